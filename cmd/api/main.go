@@ -19,7 +19,15 @@ func main() {
 	// Load and validate configuration
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatal("Failed to load configuration:", err)
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// "api migrate" applies pending migrations and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := app.Migrate(cfg); err != nil {
+			log.Fatal("Migration failed:", err)
+		}
+		return
 	}
 
 	// Initialize and start application
@@ -29,7 +37,9 @@ func main() {
 	}
 
 	// Start server with graceful shutdown
-	if err := application.Run(); err != nil {
+	err = application.Run()
+	application.Cleanup()
+	if err != nil {
 		log.Fatal("Application failed:", err)
 	}
 
@@ -3,27 +3,70 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/breach"
+	"user-auth-app/internal/breaker"
 	"user-auth-app/internal/cache"
 	"user-auth-app/internal/config"
 	"user-auth-app/internal/email"
 	"user-auth-app/internal/handler"
+	"user-auth-app/internal/hashing"
 	"user-auth-app/internal/messaging"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/oauth"
+	"user-auth-app/internal/ratelimit"
 	"user-auth-app/internal/repository"
 	"user-auth-app/internal/server"
 	"user-auth-app/internal/service"
+	"user-auth-app/internal/signing"
+	"user-auth-app/internal/validator"
+	"user-auth-app/internal/webhook"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog"
 )
 
+// poolStatsInterval is how often database pool gauges are refreshed
+const poolStatsInterval = 15 * time.Second
+
+// bootstrapAdminEmail is the bootstrap admin's email when none is
+// configured
+const bootstrapAdminEmail = "admin@localhost"
+
 // App represents the application with all dependencies
 type App struct {
 	config *config.Config
 	server *server.Server
 	pool   *pgxpool.Pool
-	logger *zerolog.Logger
+	// replicaPool is nil without a read replica
+	replicaPool *pgxpool.Pool
+	syslogSink  *audit.SyslogSink
+	webhooks    *webhook.Dispatcher
+	logger      *zerolog.Logger
+
+	// profileReads is nil unless profile reads are audited
+	profileReads *audit.AsyncSink
+
+	// stopPoolStats stops the pool statistics watcher
+	stopPoolStats context.CancelFunc
+
+	// stopKeyReload stops watching for signing key reloads
+	stopKeyReload context.CancelFunc
+
+	// stopCacheWarm abandons cache warming if it is still running
+	stopCacheWarm context.CancelFunc
+
+	// stopSessionPurge stops deleting expired sessions
+	stopSessionPurge context.CancelFunc
 }
 
 // New creates a new application instance with all dependencies
@@ -31,59 +74,270 @@ func New(cfg *config.Config) (*App, error) {
 	logger := cfg.Logger()
 
 	// Initialize database connection pool
-	pool, err := initDatabase(cfg.DBURL, logger)
+	pool, err := initDatabase(cfg, cfg.DBURL, repository.PoolPrimary, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
+	if cfg.AutoMigrate {
+		if err := repository.Migrate(pool, logger); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	breakerSettings := breaker.Settings{
+		FailureThreshold: uint32(cfg.BreakerFailureThreshold),
+		OpenTimeout:      cfg.BreakerOpenTimeout,
+	}
+
 	// Initialize cache service
-	cacheService := cache.NewRedisCache(cfg.RedisURL, logger, cfg.CacheTTL)
+	cacheService := cache.NewRedisCache(cfg.RedisURL, logger, cfg.CacheTTL, cfg.CacheMaxEntries, breakerSettings)
+
+	// Initialize email service
+	emailService, err := email.NewEmailService(&cfg.Email, logger)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Email service initialization failed, continuing without email")
+		// Don't return error, email is optional
+	}
+
+	// Initialize repositories. Transient errors are retried, and queries
+	// fail fast while the database is failing; health checks use the pool
+	// directly. The breaker sees each statement once, after its retries.
+	retryPolicy := repository.DefaultRetryPolicy
+	retryPolicy.MaxAttempts = cfg.DBRetryMaxAttempts
+	db := repository.WithCircuitBreaker(
+		repository.WithRetry(pool, retryPolicy),
+		breaker.New("postgres", breakerSettings, logger),
+	)
+	replicaPool, replica := initReplica(cfg, retryPolicy, breakerSettings, logger)
+	userRepo := repository.NewUserRepository(db, replica, repository.LockoutPolicy{
+		MaxAttempts: int32(cfg.MaxFailedLogins),
+		Duration:    cfg.LockoutDuration,
+	})
+	auditRepo := repository.NewAuditRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	deadLetterRepo := repository.NewDeadLetterRepository(db)
+	_ = repository.NewTxManager(pool) // Transaction manager available if needed
 
 	// Initialize message broker
-	broker, err := messaging.NewNATSBroker(cfg.NatsURL, logger)
+	broker, err := messaging.NewNATSBroker(cfg.NatsURL, cfg.NatsSubscriberBuffer, cfg.NatsReconnectBufferBytes, deadLetterRepo, logger)
 	if err != nil {
 		logger.Warn().Err(err).Msg("NATS broker unavailable, async operations disabled")
 		// Don't return error, broker is optional
 	}
 
-	// Initialize email service
-	emailService, err := email.NewFromEnv(logger)
+	// Keep in-memory cache entries coherent across instances
+	if broker.IsAvailable() {
+		invalidating, err := cache.WithInvalidation(cacheService, broker, logger)
+		if err != nil {
+			logger.Warn().Err(err).Msg("Cache invalidation across instances disabled")
+		} else {
+			cacheService = invalidating
+		}
+	}
+
+	// Initialize audit sinks
+	auditSink, syslogSink := initAuditSink(cfg, auditRepo, broker, logger)
+
+	// Initialize breached password checker
+	var pwnedChecker breach.PwnedChecker = breach.NopChecker{}
+	if cfg.PasswordBreachCheck {
+		pwnedChecker = breach.NewHIBPChecker(cfg.HIBPURL)
+	}
+
+	// Initialize password hasher
+	hasher, err := hashing.New(cfg.PasswordHashAlgorithm, cfg.BcryptCost, hashing.Argon2Params{
+		Memory:      uint32(cfg.Argon2Memory),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+	})
 	if err != nil {
-		logger.Warn().Err(err).Msg("Email service initialization failed, continuing without email")
-		// Don't return error, email is optional
+		return nil, fmt.Errorf("failed to initialize password hasher: %w", err)
 	}
 
-	// Initialize repositories
-	userRepo := repository.NewUserRepository(pool)
-	_ = repository.NewTxManager(pool) // Transaction manager available if needed
+	// Initialize JWT signing keys
+	signingKeys, err := initSigningKeys(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize signing keys: %w", err)
+	}
+
+	// Deliver user events to webhooks in the background
+	webhooks := webhook.NewDispatcher(webhookRepo, cfg.WebhookTimeout, cfg.WebhookMaxAttempts, cfg.WebhookDeliveryRetention, cfg.WebhookAllowedNetworks, logger)
 
 	// Initialize services
-	authService := service.NewAuthService(
-		userRepo,
-		cacheService,
-		broker,
-		emailService,
+	authService := service.NewAuthService(service.AuthServiceConfig{
+		Repo:          userRepo,
+		Sessions:      sessionRepo,
+		Cache:         cacheService,
+		Broker:        broker,
+		Webhooks:      webhooks,
+		EmailService:  emailService,
+		AuditSink:     auditSink,
+		PwnedChecker:  pwnedChecker,
+		LoginFailures: initLoginFailures(cfg, cacheService, breakerSettings, logger),
+		Logger:        logger,
+		Keys:          signingKeys,
+		Hasher:        hasher,
+
+		JWTExpiry:          cfg.JWTExpiry,
+		SessionLifetime:    cfg.SessionLifetime,
+		RememberMeLifetime: cfg.SessionRememberMe,
+		Issuer:             cfg.JWTIssuer,
+		Audience:           cfg.JWTAudience,
+
+		EnumerationSafe:      cfg.EnumerationSafeRegistration,
+		TokenBindingMode:     cfg.TokenBindingMode,
+		Permissions:          cfg.RolePermissions,
+		MaxFailedLogins:      int32(cfg.MaxFailedLogins),
+		PasswordHistory:      cfg.PasswordHistory,
+		PasswordMaxAge:       cfg.PasswordMaxAge,
+		VerificationCooldown: cfg.VerificationResendCooldown,
+		InviteOnly:           cfg.InviteOnly,
+		InviteTTL:            cfg.InviteTTL,
+		ReservedUsernames:    cfg.ReservedUsernames,
+	})
+	// Profile reads are served from cache, so they are audited in the
+	// background rather than waiting for the sinks
+	var profileReads *audit.AsyncSink
+	var profileReadSink audit.Sink
+	if cfg.AuditProfileReads {
+		profileReads = audit.NewAsyncSink(auditSink, logger)
+		profileReadSink = profileReads
+	}
+	userService := service.NewUserService(userRepo, cacheService, logger, cfg.NegativeCacheTTL, profileReadSink, cfg.CacheWarmUsers, cfg.CacheWarmUserIDs)
+	auditService := service.NewAuditService(auditRepo, logger)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, auditSink, logger, cfg.RolePermissions)
+	sessionService := service.NewSessionService(sessionRepo, cacheService, auditSink, logger)
+	webhookService := service.NewWebhookService(webhookRepo, auditSink, logger)
+	deadLetterService := service.NewDeadLetterService(deadLetterRepo, broker, auditSink, logger)
+
+	if cfg.BootstrapAdmin {
+		bootstrapAdmin(cfg, authService, logger)
+	}
+
+	// Initialize handlers
+	validationRules := validator.Rules{
+		UsernameMinLen:    cfg.UsernameMinLen,
+		UsernameMaxLen:    cfg.UsernameMaxLen,
+		ReservedUsernames: cfg.ReservedUsernames,
+		AllowedRoles:      cfg.AllowedRoles,
+		Password: validator.PasswordPolicy{
+			MinLength:      cfg.PasswordMinLength,
+			MaxLength:      cfg.PasswordMaxLength,
+			RequireUpper:   cfg.PasswordRequireUpper,
+			RequireLower:   cfg.PasswordRequireLower,
+			RequireDigit:   cfg.PasswordRequireDigit,
+			RequireSpecial: cfg.PasswordRequireSpecial,
+			RejectCommon:   cfg.PasswordRejectCommon,
+			BreachCheck:    cfg.PasswordBreachCheck,
+		},
+	}
+	cookieAuth := middleware.CookieAuth{
+		Mode:           cfg.AuthMode,
+		Name:           cfg.AuthCookieName,
+		CSRFCookieName: cfg.CSRFCookieName,
+		Secure:         cfg.AuthCookieSecure,
+		SameSite:       sameSiteMode(cfg.AuthCookieSameSite),
+		CSRF:           cfg.CSRFProtection,
+
+		RememberMeLifetime: cfg.SessionRememberMe,
+	}
+	authHandler := handler.NewAuthHandler(
+		authService,
+		userService,
 		logger,
-		cfg.JWTSecret,
-		cfg.JWTExpiry,
+		validationRules,
+		cfg.EnumerationSafeRegistration,
+		cfg.LoginThrottleDisclosure,
+		cfg.LogValidationFailures,
+		int64(cfg.MaxRequestBodyBytes),
+		cookieAuth,
+		handler.NewIdempotencyStore(cacheService, cfg.IdempotencyTTL, logger),
 	)
-	userService := service.NewUserService(userRepo, cacheService, logger)
+	healthHandler := handler.NewHealthHandler(pool, cacheService, broker, emailService, cfg.Environment)
+	adminHandler := handler.NewAdminHandler(authService, auditService, userService, logger, validationRules, cfg.LogValidationFailures, cfg.MaxImportUsers)
 
-	// Initialize handlers
-	authHandler := handler.NewAuthHandler(authService, userService, logger, cfg.Timeout)
-	healthHandler := handler.NewHealthHandler(pool, cacheService, broker, emailService)
+	keysHandler := handler.NewKeysHandler(signingKeys, logger)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService, logger, int64(cfg.MaxRequestBodyBytes))
+	sessionHandler := handler.NewSessionHandler(sessionService, logger)
+	webhookHandler := handler.NewWebhookHandler(webhookService, logger, int64(cfg.MaxRequestBodyBytes))
+	deadLetterHandler := handler.NewDeadLetterHandler(deadLetterService, logger)
+
+	var oauthHandler *handler.OAuthHandler
+	if cfg.GoogleLoginEnabled() {
+		google := oauth.NewGoogle(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleRedirectURL)
+		oauthHandler = handler.NewOAuthHandler(authHandler, google, logger, cfg.AuthCookieSecure)
+	}
+
+	ipLimiter, userLimiter := initRateLimiters(cfg, cacheService, breakerSettings, logger)
 
 	// Initialize server
-	srv := server.NewServer(cfg, logger, authHandler, healthHandler, authService)
+	srv := server.NewServer(cfg, logger, authHandler, healthHandler, adminHandler, keysHandler, oauthHandler, apiKeyHandler, sessionHandler, webhookHandler, deadLetterHandler, authService, apiKeyService, cookieAuth, ipLimiter, userLimiter)
+
+	// Publish pool statistics for saturation monitoring
+	poolStatsCtx, stopPoolStats := context.WithCancel(context.Background())
+	go repository.WatchPoolStats(poolStatsCtx, repository.PoolPrimary, pool, poolStatsInterval)
+	if replicaPool != nil {
+		go repository.WatchPoolStats(poolStatsCtx, repository.PoolReplica, replicaPool, poolStatsInterval)
+	}
+
+	// Rotate signing keys when their files change
+	keyReloadCtx, stopKeyReload := context.WithCancel(context.Background())
+	go watchKeyReload(keyReloadCtx, cfg, signingKeys, logger)
+
+	// Delete expired sessions so the table doesn't grow without bound
+	sessionPurgeCtx, stopSessionPurge := context.WithCancel(context.Background())
+	if cfg.SessionPurgeInterval > 0 {
+		go purgeExpiredSessions(sessionPurgeCtx, sessionRepo, cfg.SessionPurgeInterval, logger)
+	}
+
+	// Preload the user cache in the background, so startup and readiness
+	// don't wait for it
+	cacheWarmCtx, stopCacheWarm := context.WithTimeout(context.Background(), cfg.CacheWarmTimeout)
+	go func() {
+		defer stopCacheWarm()
+		if err := userService.WarmCache(cacheWarmCtx); err != nil {
+			logger.Warn().Err(err).Msg("Failed to warm user cache")
+		}
+	}()
 
 	return &App{
-		config: cfg,
-		server: srv,
-		pool:   pool,
-		logger: logger,
+		config:     cfg,
+		server:     srv,
+		pool:       pool,
+		syslogSink: syslogSink,
+		webhooks:   webhooks,
+
+		profileReads: profileReads,
+
+		replicaPool: replicaPool,
+		logger:      logger,
+
+		stopPoolStats: stopPoolStats,
+		stopKeyReload: stopKeyReload,
+		stopCacheWarm: stopCacheWarm,
+
+		stopSessionPurge: stopSessionPurge,
 	}, nil
 }
 
+// Migrate applies pending database migrations without starting the
+// server
+func Migrate(cfg *config.Config) error {
+	logger := cfg.Logger()
+
+	pool, err := initDatabase(cfg, cfg.DBURL, repository.PoolPrimary, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer pool.Close()
+
+	return repository.Migrate(pool, logger)
+}
+
 // Run starts the application
 func (a *App) Run() error {
 	a.logger.Info().
@@ -96,15 +350,307 @@ func (a *App) Run() error {
 
 // Cleanup performs cleanup operations
 func (a *App) Cleanup() {
+	// Profile reads are recorded to the other sinks, so they go first
+	if a.profileReads != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := a.profileReads.Close(ctx); err != nil {
+			a.logger.Warn().Err(err).Msg("Failed to flush profile read audit events")
+		}
+	}
+
+	if a.syslogSink != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := a.syslogSink.Close(ctx); err != nil {
+			a.logger.Warn().Err(err).Msg("Failed to flush syslog audit sink")
+		}
+	}
+
+	// Stop webhook delivery while the database is still there to record
+	// the attempts in progress
+	if a.webhooks != nil {
+		a.webhooks.Close()
+	}
+
+	if a.stopPoolStats != nil {
+		a.stopPoolStats()
+	}
+
+	if a.stopKeyReload != nil {
+		a.stopKeyReload()
+	}
+
+	if a.stopCacheWarm != nil {
+		a.stopCacheWarm()
+	}
+
+	if a.stopSessionPurge != nil {
+		a.stopSessionPurge()
+	}
+
+	if a.replicaPool != nil {
+		a.replicaPool.Close()
+	}
+
 	if a.pool != nil {
 		a.pool.Close()
 		a.logger.Info().Msg("Database connection closed")
 	}
 }
 
+// initAuditSink builds the audit sinks selected by configuration. Sinks
+// that cannot be initialized are skipped with a warning.
+func initAuditSink(cfg *config.Config, auditRepo repository.AuditRepository, broker messaging.Broker, logger *zerolog.Logger) (audit.Sink, *audit.SyslogSink) {
+	var (
+		sinks      []audit.Sink
+		syslogSink *audit.SyslogSink
+	)
+
+	for _, name := range cfg.AuditSinks {
+		switch name {
+		case "log":
+			sinks = append(sinks, audit.NewLogSink(logger))
+		case "postgres":
+			sinks = append(sinks, audit.NewPostgresSink(auditRepo))
+		case "nats":
+			sinks = append(sinks, audit.NewNATSSink(broker))
+		case "syslog":
+			sink, err := audit.NewSyslogSink(cfg.SyslogEndpoint, logger)
+			if err != nil {
+				logger.Warn().Err(err).Msg("Syslog audit sink unavailable")
+				continue
+			}
+			syslogSink = sink
+			sinks = append(sinks, sink)
+		}
+	}
+
+	logger.Info().Strs("sinks", cfg.AuditSinks).Msg("Audit sinks initialized")
+	return audit.NewMultiSink(sinks...), syslogSink
+}
+
+// bootstrapAdmin creates the first admin of a fresh deployment. A
+// generated password is logged once; later startups find the admin and
+// do nothing. Failures are logged rather than stopping the server, since
+// an existing deployment works without it.
+func bootstrapAdmin(cfg *config.Config, authService service.AuthService, logger *zerolog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	email, onlyIfEmpty := cfg.BootstrapAdminEmail, cfg.BootstrapAdminEmail == ""
+	if onlyIfEmpty {
+		email = bootstrapAdminEmail
+	}
+
+	admin, generated, err := authService.BootstrapAdmin(ctx, cfg.BootstrapAdminUsername, email, cfg.BootstrapAdminPassword, onlyIfEmpty)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to bootstrap admin user")
+		return
+	}
+	if admin.ID == 0 {
+		return
+	}
+
+	event := logger.Warn().
+		Int32("user_id", admin.ID).
+		Str("username", admin.Username).
+		Str("email", admin.Email)
+	if generated != "" {
+		event = event.Str("password", generated)
+	}
+	event.Msg("Created bootstrap admin user; the password must be changed on first login")
+}
+
+// initReplica connects to the read replica, wrapped like the primary with
+// retries and its own circuit breaker. Without a configured or reachable
+// replica it returns nils, so every query goes to the primary.
+func initReplica(cfg *config.Config, retryPolicy repository.RetryPolicy, breakerSettings breaker.Settings, logger *zerolog.Logger) (*pgxpool.Pool, repository.DB) {
+	if cfg.DBReplicaURL == "" {
+		return nil, nil
+	}
+
+	pool, err := initDatabase(cfg, cfg.DBReplicaURL, repository.PoolReplica, logger)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Read replica unavailable, reading from the primary")
+		return nil, nil
+	}
+
+	return pool, repository.WithCircuitBreaker(
+		repository.WithRetry(pool, retryPolicy),
+		breaker.New("postgres_replica", breakerSettings, logger),
+	)
+}
+
+// initLoginFailures builds the counter of failed logins per client
+// address, or returns nil if the check is disabled. Failures are shared
+// through the cache's Redis client when there is one, so an address
+// blocked by one instance is blocked by all.
+func initLoginFailures(cfg *config.Config, cacheService cache.Service, breakerSettings breaker.Settings, logger *zerolog.Logger) ratelimit.FailureCounter {
+	if cfg.LoginIPMaxFailures <= 0 {
+		return nil
+	}
+	if client := cache.RedisClient(cacheService); client != nil {
+		return ratelimit.NewRedisFailures(client, "login_ip", cfg.LoginIPMaxFailures, cfg.LoginIPFailureWindow, breakerSettings, logger)
+	}
+	logger.Warn().Msg("Redis unavailable, counting failed logins per instance")
+	return ratelimit.NewMemoryFailures(cfg.LoginIPMaxFailures, cfg.LoginIPFailureWindow)
+}
+
+// initRateLimiters builds the per-IP and per-user limiters on the
+// configured backend. The Redis backend shares the cache's client and
+// limits in memory when no Redis is configured.
+func initRateLimiters(cfg *config.Config, cacheService cache.Service, breakerSettings breaker.Settings, logger *zerolog.Logger) (ip, user ratelimit.Limiter) {
+	if cfg.RateLimitBackend == ratelimit.BackendRedis {
+		if client := cache.RedisClient(cacheService); client != nil {
+			logger.Info().Msg("Rate limiting with Redis")
+			return ratelimit.NewRedis(client, "ip", cfg.RateLimitRPS, cfg.RateLimitBurst, breakerSettings, logger),
+				ratelimit.NewRedis(client, "user", cfg.UserRateLimitRPS, cfg.UserRateLimitBurst, breakerSettings, logger)
+		}
+		logger.Warn().Msg("Redis unavailable, rate limiting per instance")
+	}
+
+	return ratelimit.NewMemory(cfg.RateLimitRPS, cfg.RateLimitBurst),
+		ratelimit.NewMemory(cfg.UserRateLimitRPS, cfg.UserRateLimitBurst)
+}
+
+// initSigningKeys builds the JWT key set for the configured signing method
+func initSigningKeys(cfg *config.Config) (*signing.KeySet, error) {
+	current, previous, err := loadSigningKeys(cfg, false)
+	if err != nil {
+		return nil, err
+	}
+	return signing.NewKeySet(current, previous...)
+}
+
+// loadSigningKeys reads the signing key and any extra verification keys
+// named by the configuration. On reload a verification key whose file was
+// deleted is left out, which retires it; at startup every file must exist.
+func loadSigningKeys(cfg *config.Config, reload bool) (*signing.Key, []*signing.Key, error) {
+	if cfg.JWTSigningMethod != "RS256" {
+		if cfg.JWTSecretFile != "" {
+			current, err := signing.LoadHMACKey(cfg.JWTSecretFile)
+			return current, nil, err
+		}
+		return signing.NewHMACKey([]byte(cfg.JWTSecret)), nil, nil
+	}
+
+	current, err := signing.LoadRSAPrivateKey(cfg.JWTPrivateKeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var previous []*signing.Key
+	for _, path := range cfg.JWTPublicKeyFiles {
+		key, err := signing.LoadRSAPublicKey(path)
+		if reload && errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		previous = append(previous, key)
+	}
+
+	return current, previous, nil
+}
+
+// watchKeyReload re-reads the signing key files on SIGHUP. A new signing
+// key becomes current, and the old one keeps verifying tokens until they
+// have expired. Verification keys whose files were deleted are dropped,
+// which is how a leaked previous key is retired.
+func watchKeyReload(ctx context.Context, cfg *config.Config, keys *signing.KeySet, logger *zerolog.Logger) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
+
+		current, previous, err := loadSigningKeys(cfg, true)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to reload signing keys, keeping the current keys")
+			continue
+		}
+
+		oldID := keys.CurrentID()
+		if err := keys.Rotate(current, cfg.JWTExpiry); err != nil {
+			logger.Error().Err(err).Msg("Failed to rotate signing key")
+			continue
+		}
+		keys.SetPrevious(previous...)
+
+		if current.ID == oldID {
+			logger.Info().Str("kid", current.ID).Msg("Signing keys reloaded, signing key unchanged")
+			continue
+		}
+		logger.Info().
+			Str("kid", current.ID).
+			Str("previous_kid", oldID).
+			Dur("grace", cfg.JWTExpiry).
+			Msg("Signing key rotated")
+	}
+}
+
+// purgeExpiredSessions deletes expired sessions at startup and every
+// interval until ctx is done
+func purgeExpiredSessions(ctx context.Context, sessions repository.SessionRepository, interval time.Duration, logger *zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purgeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		deleted, err := sessions.DeleteExpiredSessions(purgeCtx)
+		cancel()
+		switch {
+		case err != nil && ctx.Err() == nil:
+			logger.Warn().Err(err).Msg("Failed to delete expired sessions")
+		case deleted > 0:
+			logger.Info().Int64("deleted", deleted).Msg("Deleted expired sessions")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sameSiteMode converts AUTH_COOKIE_SAMESITE to its cookie attribute
+func sameSiteMode(mode string) http.SameSite {
+	switch mode {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
 // initDatabase initializes the database connection pool
-func initDatabase(dbURL string, logger *zerolog.Logger) (*pgxpool.Pool, error) {
-	pool, err := pgxpool.New(context.Background(), dbURL)
+func initDatabase(cfg *config.Config, url, name string, logger *zerolog.Logger) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	poolConfig.MaxConns = int32(cfg.DBMaxConns)
+	poolConfig.MinConns = int32(cfg.DBMinConns)
+	poolConfig.MaxConnLifetime = cfg.DBMaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.DBMaxConnIdleTime
+	if mode, ok := repository.QueryExecModes[cfg.DBQueryExecMode]; ok {
+		poolConfig.ConnConfig.DefaultQueryExecMode = mode
+	}
+	// Timestamp columns carry no zone and pgx reads them as UTC, so NOW()
+	// must produce UTC whatever the server's default time zone is
+	poolConfig.ConnConfig.RuntimeParams["timezone"] = "UTC"
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +661,11 @@ func initDatabase(dbURL string, logger *zerolog.Logger) (*pgxpool.Pool, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	logger.Info().Msg("Database connection established")
+	logger.Info().
+		Str("pool", name).
+		Stringer("query_exec_mode", poolConfig.ConnConfig.DefaultQueryExecMode).
+		Int32("max_conns", poolConfig.MaxConns).
+		Int32("min_conns", poolConfig.MinConns).
+		Msg("Database connection established")
 	return pool, nil
 }
@@ -0,0 +1,99 @@
+// Package audit implements a sink recording events in the background
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"user-auth-app/internal/domain"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	asyncBufferSize    = 1024
+	asyncRecordTimeout = 5 * time.Second
+)
+
+// queuedEvent is an event waiting to be recorded with the context it was
+// raised in, stripped of the request's cancellation
+type queuedEvent struct {
+	ctx   context.Context
+	event domain.AuditEvent
+}
+
+// AsyncSink records events to another sink from a background goroutine,
+// for events raised on paths that must not wait for the audit trail, such
+// as cached reads. Events keep the values of the context they were raised
+// in, such as the tenant.
+type AsyncSink struct {
+	sink   Sink
+	logger *zerolog.Logger
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan queuedEvent
+	done   chan struct{}
+}
+
+// NewAsyncSink creates a sink that queues events for sink
+func NewAsyncSink(sink Sink, logger *zerolog.Logger) *AsyncSink {
+	s := &AsyncSink{
+		sink:   sink,
+		logger: logger,
+		queue:  make(chan queuedEvent, asyncBufferSize),
+		done:   make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+// Record queues an event. It never blocks; if the buffer is full the
+// event is dropped and ErrSinkBufferFull is returned.
+func (s *AsyncSink) Record(ctx context.Context, event domain.AuditEvent) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return ErrSinkClosed
+	}
+
+	select {
+	case s.queue <- queuedEvent{ctx: context.WithoutCancel(ctx), event: event}:
+		return nil
+	default:
+		s.logger.Warn().Str("audit_event", event.Type).Msg("Audit buffer full, dropping event")
+		return ErrSinkBufferFull
+	}
+}
+
+// Close stops accepting events and records those still queued, waiting
+// at most until ctx is done
+func (s *AsyncSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *AsyncSink) run() {
+	defer close(s.done)
+	for queued := range s.queue {
+		ctx, cancel := context.WithTimeout(queued.ctx, asyncRecordTimeout)
+		if err := s.sink.Record(ctx, queued.event); err != nil {
+			s.logger.Warn().Err(err).Str("audit_event", queued.event.Type).Msg("Failed to record audit event")
+		}
+		cancel()
+	}
+}
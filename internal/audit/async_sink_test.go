@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"user-auth-app/internal/domain"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ctxSink records the context value under key of each event
+type ctxSink struct {
+	*MemorySink
+	values []any
+}
+
+type ctxKey struct{}
+
+func (s *ctxSink) Record(ctx context.Context, event domain.AuditEvent) error {
+	s.values = append(s.values, ctx.Value(ctxKey{}))
+	return s.MemorySink.Record(ctx, event)
+}
+
+func TestAsyncSinkRecordsAfterRequestEnds(t *testing.T) {
+	logger := zerolog.Nop()
+	inner := &ctxSink{MemorySink: NewMemorySink()}
+	sink := NewAsyncSink(inner, &logger)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "tenant-a"))
+	event := domain.AuditEvent{Type: domain.AuditProfileRead, Success: true}
+	require.NoError(t, sink.Record(ctx, event))
+	cancel()
+
+	// Close waits for queued events
+	require.NoError(t, sink.Close(context.Background()))
+	assert.Equal(t, []domain.AuditEvent{event}, inner.Events())
+	assert.Equal(t, []any{"tenant-a"}, inner.values)
+
+	assert.ErrorIs(t, sink.Record(context.Background(), event), ErrSinkClosed)
+}
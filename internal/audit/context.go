@@ -0,0 +1,24 @@
+// Package audit carries request client details through the context
+package audit
+
+import "context"
+
+type clientContextKey struct{}
+
+// Client identifies where a request came from
+type Client struct {
+	IPAddress string
+	UserAgent string
+}
+
+// WithClient returns a context carrying the request's client details
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, client)
+}
+
+// ClientFromContext returns the client details stored by WithClient, or
+// a zero Client if none are present
+func ClientFromContext(ctx context.Context) Client {
+	client, _ := ctx.Value(clientContextKey{}).(Client)
+	return client
+}
@@ -0,0 +1,24 @@
+// Package audit provides audit event sinks
+package audit
+
+import (
+	"context"
+	"errors"
+
+	"user-auth-app/internal/domain"
+)
+
+var (
+	// ErrSinkBufferFull indicates an asynchronous sink dropped an event
+	// because its buffer was full
+	ErrSinkBufferFull = errors.New("audit sink buffer full")
+	// ErrSinkClosed indicates the sink no longer accepts events
+	ErrSinkClosed = errors.New("audit sink closed")
+)
+
+// Sink defines where audit events are recorded
+type Sink interface {
+	// Record records an audit event. Sinks backed by remote services
+	// must not block the caller when the service is slow or unavailable.
+	Record(ctx context.Context, event domain.AuditEvent) error
+}
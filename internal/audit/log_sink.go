@@ -0,0 +1,41 @@
+// Package audit implements a zerolog audit sink
+package audit
+
+import (
+	"context"
+
+	"user-auth-app/internal/domain"
+
+	"github.com/rs/zerolog"
+)
+
+type logSink struct {
+	logger *zerolog.Logger
+}
+
+// NewLogSink creates a sink that writes audit events to the application log
+func NewLogSink(logger *zerolog.Logger) Sink {
+	return &logSink{logger: logger}
+}
+
+func (s *logSink) Record(ctx context.Context, event domain.AuditEvent) error {
+	logEvent := s.logger.Info()
+	if !event.Success {
+		logEvent = s.logger.Warn()
+	}
+
+	if event.UserID != nil {
+		logEvent = logEvent.Int32("user_id", *event.UserID)
+	}
+
+	logEvent.
+		Str("audit_event", event.Type).
+		Bool("success", event.Success).
+		Str("ip_address", event.IPAddress).
+		Str("user_agent", event.UserAgent).
+		Interface("details", event.Details).
+		Time("timestamp", event.Timestamp).
+		Msg("audit")
+
+	return nil
+}
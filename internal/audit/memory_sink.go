@@ -0,0 +1,50 @@
+// Package audit implements an in-memory recording audit sink
+package audit
+
+import (
+	"context"
+	"sync"
+
+	"user-auth-app/internal/domain"
+)
+
+// MemorySink records audit events in memory. It is intended for tests.
+type MemorySink struct {
+	mu     sync.Mutex
+	events []domain.AuditEvent
+	err    error
+}
+
+// NewMemorySink creates an empty in-memory sink
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+// Record stores the event, or returns the configured failure
+func (s *MemorySink) Record(ctx context.Context, event domain.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+// FailWith makes subsequent Record calls return err (nil to recover)
+func (s *MemorySink) FailWith(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Events returns a copy of the recorded events
+func (s *MemorySink) Events() []domain.AuditEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]domain.AuditEvent, len(s.events))
+	copy(events, s.events)
+	return events
+}
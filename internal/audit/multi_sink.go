@@ -0,0 +1,32 @@
+// Package audit implements fan-out to multiple audit sinks
+package audit
+
+import (
+	"context"
+	"errors"
+
+	"user-auth-app/internal/domain"
+)
+
+// MultiSink fans an event out to several sinks. A failing sink does not
+// prevent the remaining sinks from recording the event.
+type MultiSink struct {
+	sinks []Sink
+}
+
+// NewMultiSink creates a sink that records to every given sink
+func NewMultiSink(sinks ...Sink) *MultiSink {
+	return &MultiSink{sinks: sinks}
+}
+
+// Record records the event to every sink and returns the joined errors
+// of any sinks that failed
+func (m *MultiSink) Record(ctx context.Context, event domain.AuditEvent) error {
+	var errs []error
+	for _, sink := range m.sinks {
+		if err := sink.Record(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
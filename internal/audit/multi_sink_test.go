@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"user-auth-app/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiSinkFansOutToAllSinks(t *testing.T) {
+	first := NewMemorySink()
+	second := NewMemorySink()
+	sink := NewMultiSink(first, second)
+
+	event := domain.AuditEvent{Type: domain.AuditLoginSuccess, Success: true}
+	require.NoError(t, sink.Record(context.Background(), event))
+
+	assert.Equal(t, []domain.AuditEvent{event}, first.Events())
+	assert.Equal(t, []domain.AuditEvent{event}, second.Events())
+}
+
+func TestMultiSinkContinuesAfterFailure(t *testing.T) {
+	errDown := errors.New("sink down")
+
+	failing := NewMemorySink()
+	failing.FailWith(errDown)
+	healthy := NewMemorySink()
+	sink := NewMultiSink(failing, healthy)
+
+	event := domain.AuditEvent{Type: domain.AuditLoginFailure}
+	err := sink.Record(context.Background(), event)
+
+	assert.ErrorIs(t, err, errDown)
+	assert.Empty(t, failing.Events())
+	assert.Equal(t, []domain.AuditEvent{event}, healthy.Events())
+}
+
+func TestMultiSinkJoinsAllErrors(t *testing.T) {
+	errA := errors.New("sink a down")
+	errB := errors.New("sink b down")
+
+	a := NewMemorySink()
+	a.FailWith(errA)
+	b := NewMemorySink()
+	b.FailWith(errB)
+
+	err := NewMultiSink(a, b).Record(context.Background(), domain.AuditEvent{})
+
+	assert.ErrorIs(t, err, errA)
+	assert.ErrorIs(t, err, errB)
+}
+
+func TestMultiSinkWithNoSinks(t *testing.T) {
+	assert.NoError(t, NewMultiSink().Record(context.Background(), domain.AuditEvent{}))
+}
@@ -0,0 +1,29 @@
+// Package audit implements a NATS audit sink
+package audit
+
+import (
+	"context"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/messaging"
+)
+
+// auditSubjectPrefix is prepended to the event type to form the subject
+const auditSubjectPrefix = "audit."
+
+type natsSink struct {
+	broker messaging.Broker
+}
+
+// NewNATSSink creates a sink that publishes audit events to the message
+// broker on "audit.<event type>" subjects
+func NewNATSSink(broker messaging.Broker) Sink {
+	return &natsSink{broker: broker}
+}
+
+func (s *natsSink) Record(ctx context.Context, event domain.AuditEvent) error {
+	if s.broker == nil || !s.broker.IsAvailable() {
+		return messaging.ErrBrokerUnavailable
+	}
+	return s.broker.PublishJSON(auditSubjectPrefix+event.Type, event)
+}
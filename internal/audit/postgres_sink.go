@@ -0,0 +1,22 @@
+// Package audit implements a Postgres audit sink
+package audit
+
+import (
+	"context"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+)
+
+type postgresSink struct {
+	repo repository.AuditRepository
+}
+
+// NewPostgresSink creates a sink that persists audit events to the database
+func NewPostgresSink(repo repository.AuditRepository) Sink {
+	return &postgresSink{repo: repo}
+}
+
+func (s *postgresSink) Record(ctx context.Context, event domain.AuditEvent) error {
+	return s.repo.RecordAuditEvent(ctx, event)
+}
@@ -0,0 +1,216 @@
+// Package audit implements an RFC 5424 syslog audit sink
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"user-auth-app/internal/domain"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	syslogAppName        = "user-auth-app"
+	syslogFacility       = 10 // authpriv
+	syslogSeverityInfo   = 6
+	syslogSeverityWarn   = 4
+	syslogBufferSize     = 1024
+	syslogMaxAttempts    = 3
+	syslogDialTimeout    = 5 * time.Second
+	syslogWriteTimeout   = 5 * time.Second
+	syslogInitialBackoff = 100 * time.Millisecond
+)
+
+// SyslogSink forwards audit events to a remote syslog server (RFC 5424)
+// with JSON payloads. Events are buffered and written by a background
+// goroutine so an unavailable SIEM never blocks the auth path.
+type SyslogSink struct {
+	network  string
+	addr     string
+	hostname string
+	logger   *zerolog.Logger
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan []byte
+	done   chan struct{}
+	conn   net.Conn
+}
+
+// NewSyslogSink creates a syslog sink for an endpoint such as
+// "udp://siem.internal:514" or "tcp://siem.internal:601"
+func NewSyslogSink(endpoint string, logger *zerolog.Logger) (*SyslogSink, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse syslog endpoint: %w", err)
+	}
+	if u.Scheme != "udp" && u.Scheme != "tcp" {
+		return nil, fmt.Errorf("unsupported syslog network %q (must be udp or tcp)", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("syslog endpoint %q has no host", endpoint)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	s := &SyslogSink{
+		network:  u.Scheme,
+		addr:     u.Host,
+		hostname: hostname,
+		logger:   logger,
+		queue:    make(chan []byte, syslogBufferSize),
+		done:     make(chan struct{}),
+	}
+
+	go s.run()
+
+	logger.Info().Str("endpoint", endpoint).Msg("Syslog audit sink initialized")
+	return s, nil
+}
+
+// Record queues an event for delivery. It never blocks; if the buffer is
+// full the event is dropped and ErrSinkBufferFull is returned.
+func (s *SyslogSink) Record(ctx context.Context, event domain.AuditEvent) error {
+	msg, err := s.format(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return ErrSinkClosed
+	}
+
+	select {
+	case s.queue <- msg:
+		return nil
+	default:
+		s.logger.Warn().Str("audit_event", event.Type).Msg("Syslog audit buffer full, dropping event")
+		return ErrSinkBufferFull
+	}
+}
+
+// Close stops accepting events and flushes the buffer, waiting at most
+// until ctx is done
+func (s *SyslogSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// format renders an event as an RFC 5424 message with a JSON body
+func (s *SyslogSink) format(event domain.AuditEvent) ([]byte, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("marshal audit event: %w", err)
+	}
+
+	severity := syslogSeverityInfo
+	if !event.Success {
+		severity = syslogSeverityWarn
+	}
+
+	timestamp := event.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		syslogFacility*8+severity,
+		timestamp.UTC().Format(time.RFC3339Nano),
+		s.hostname,
+		syslogAppName,
+		os.Getpid(),
+		msgID(event.Type),
+		payload,
+	)
+
+	return []byte(msg), nil
+}
+
+// run delivers queued messages until the queue is closed
+func (s *SyslogSink) run() {
+	defer close(s.done)
+	defer func() {
+		if s.conn != nil {
+			s.conn.Close()
+		}
+	}()
+
+	for msg := range s.queue {
+		backoff := syslogInitialBackoff
+		for attempt := 1; attempt <= syslogMaxAttempts; attempt++ {
+			err := s.write(msg)
+			if err == nil {
+				break
+			}
+
+			if attempt == syslogMaxAttempts {
+				s.logger.Error().Err(err).Str("addr", s.addr).Msg("Failed to deliver audit event to syslog, dropping")
+				break
+			}
+
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+// write sends a single message, (re)connecting as needed
+func (s *SyslogSink) write(msg []byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.addr, syslogDialTimeout)
+		if err != nil {
+			return fmt.Errorf("dial syslog: %w", err)
+		}
+		s.conn = conn
+	}
+
+	// TCP transport uses octet-counting framing (RFC 6587)
+	frame := msg
+	if s.network == "tcp" {
+		frame = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+	if _, err := s.conn.Write(frame); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("write syslog: %w", err)
+	}
+
+	return nil
+}
+
+// msgID converts an event type to a valid RFC 5424 MSGID
+func msgID(eventType string) string {
+	if eventType == "" {
+		return "-"
+	}
+	if len(eventType) > 32 {
+		return eventType[:32]
+	}
+	return eventType
+}
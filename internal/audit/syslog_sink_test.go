@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseSyslog splits an RFC 5424 message into its header fields and the
+// JSON event it carries
+func parseSyslog(t *testing.T, msg string) ([]string, domain.AuditEvent) {
+	t.Helper()
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	fields := strings.SplitN(msg, " ", 8)
+	require.Len(t, fields, 8, msg)
+
+	var event domain.AuditEvent
+	require.NoError(t, json.Unmarshal([]byte(fields[7]), &event), msg)
+	return fields[:7], event
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	logger := zerolog.Nop()
+	sink, err := NewSyslogSink("udp://"+listener.LocalAddr().String(), &logger)
+	require.NoError(t, err)
+
+	userID := int32(7)
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	events := []domain.AuditEvent{
+		{Type: domain.AuditLoginSuccess, UserID: &userID, Success: true, IPAddress: "203.0.113.7", Timestamp: timestamp},
+		{Type: domain.AuditLoginFailure, Success: false, Details: map[string]string{"reason": "invalid_password"}, Timestamp: timestamp},
+	}
+	for _, event := range events {
+		require.NoError(t, sink.Record(context.Background(), event))
+	}
+
+	// Close flushes the queued events before returning
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, sink.Close(ctx))
+	assert.ErrorIs(t, sink.Record(context.Background(), events[0]), ErrSinkClosed)
+	require.NoError(t, sink.Close(ctx), "closing twice is harmless")
+
+	require.NoError(t, listener.SetReadDeadline(time.Now().Add(5*time.Second)))
+	wantPriority := []string{"<86>1", "<84>1"} // authpriv.info, authpriv.warning
+	buf := make([]byte, 64*1024)
+	for i, want := range events {
+		n, _, err := listener.ReadFrom(buf)
+		require.NoError(t, err)
+
+		// One datagram per event, without octet counting
+		header, got := parseSyslog(t, string(buf[:n]))
+		assert.Equal(t, wantPriority[i], header[0])
+		assert.Equal(t, "2024-05-01T12:00:00Z", header[1])
+		assert.Equal(t, syslogAppName, header[3])
+		assert.Equal(t, want.Type, header[5])
+		assert.Equal(t, "-", header[6], "no structured data")
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestSyslogSinkTCPFraming(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 2)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Octet counting: "<length> <message>" back to back (RFC 6587)
+		r := bufio.NewReader(conn)
+		for {
+			length, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+			if err != nil {
+				received <- fmt.Sprintf("bad frame length %q", length)
+				return
+			}
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}()
+
+	logger := zerolog.Nop()
+	sink, err := NewSyslogSink("tcp://"+listener.Addr().String(), &logger)
+	require.NoError(t, err)
+
+	events := []domain.AuditEvent{
+		{Type: domain.AuditUserRegistered, Success: true, Timestamp: time.Now().UTC()},
+		{Type: domain.AuditPasswordChange, Success: true, Timestamp: time.Now().UTC()},
+	}
+	for _, event := range events {
+		require.NoError(t, sink.Record(context.Background(), event))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, sink.Close(ctx))
+
+	for _, want := range events {
+		select {
+		case msg := <-received:
+			_, got := parseSyslog(t, msg)
+			assert.Equal(t, want.Type, got.Type)
+		case <-time.After(5 * time.Second):
+			t.Fatal("event not received")
+		}
+	}
+}
+
+func TestNewSyslogSinkRejectsEndpoint(t *testing.T) {
+	logger := zerolog.Nop()
+	for _, endpoint := range []string{"http://siem:514", "udp://", "://siem"} {
+		_, err := NewSyslogSink(endpoint, &logger)
+		assert.Error(t, err, endpoint)
+	}
+}
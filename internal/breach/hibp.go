@@ -0,0 +1,75 @@
+// Package breach implements a HaveIBeenPwned range API checker
+package breach
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultHIBPURL is the public Pwned Passwords API
+	DefaultHIBPURL = "https://api.pwnedpasswords.com"
+
+	hibpTimeout   = 3 * time.Second
+	hibpUserAgent = "user-auth-app"
+)
+
+type hibpChecker struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHIBPChecker creates a checker backed by the Pwned Passwords range
+// API. Only the first five hex characters of the password's SHA-1 hash
+// are sent (k-anonymity); the match happens locally.
+func NewHIBPChecker(baseURL string) PwnedChecker {
+	return &hibpChecker{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: hibpTimeout},
+	}
+}
+
+func (c *hibpChecker) IsPwned(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return false, fmt.Errorf("create pwned passwords request: %w", err)
+	}
+	req.Header.Set("User-Agent", hibpUserAgent)
+	// Padding hides the real response size from observers
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("query pwned passwords: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("pwned passwords returned status %d", resp.StatusCode)
+	}
+
+	// Each line is SUFFIX:COUNT; padding entries have a count of 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		hashSuffix, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(hashSuffix, suffix) {
+			continue
+		}
+		return count != "0", nil
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("read pwned passwords response: %w", err)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,64 @@
+package breach
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SHA-1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+const (
+	passwordPrefix = "5BAA6"
+	passwordSuffix = "1E4C9B93F3F0682250B6CF8331B7EE68FD8"
+)
+
+func newRangeServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only the five-character prefix may leave the process
+		assert.Equal(t, "/range/"+passwordPrefix, r.URL.Path)
+		assert.Equal(t, "true", r.Header.Get("Add-Padding"))
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHIBPCheckerPwned(t *testing.T) {
+	srv := newRangeServer(t, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n"+passwordSuffix+":9659365\r\n")
+
+	pwned, err := NewHIBPChecker(srv.URL).IsPwned(context.Background(), "password")
+	require.NoError(t, err)
+	assert.True(t, pwned)
+}
+
+func TestHIBPCheckerNotPwned(t *testing.T) {
+	srv := newRangeServer(t, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n")
+
+	pwned, err := NewHIBPChecker(srv.URL).IsPwned(context.Background(), "password")
+	require.NoError(t, err)
+	assert.False(t, pwned)
+}
+
+func TestHIBPCheckerIgnoresPadding(t *testing.T) {
+	srv := newRangeServer(t, passwordSuffix+":0\r\n")
+
+	pwned, err := NewHIBPChecker(srv.URL).IsPwned(context.Background(), "password")
+	require.NoError(t, err)
+	assert.False(t, pwned)
+}
+
+func TestHIBPCheckerUnavailable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := NewHIBPChecker(srv.URL).IsPwned(context.Background(), "password")
+	assert.Error(t, err)
+}
@@ -0,0 +1,20 @@
+// Package breach checks passwords against known data breaches
+package breach
+
+import "context"
+
+// PwnedChecker reports whether a password has appeared in a known breach
+type PwnedChecker interface {
+	// IsPwned returns true if the password is known to be compromised.
+	// An error means the check could not be performed.
+	IsPwned(ctx context.Context, password string) (bool, error)
+}
+
+// NopChecker never reports a password as pwned. It is used when breach
+// checking is disabled and in tests.
+type NopChecker struct{}
+
+// IsPwned always returns false
+func (NopChecker) IsPwned(ctx context.Context, password string) (bool, error) {
+	return false, nil
+}
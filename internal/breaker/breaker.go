@@ -0,0 +1,71 @@
+// Package breaker provides circuit breakers for calls to external
+// dependencies, with their state exported as a Prometheus gauge
+package breaker
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+	"github.com/sony/gobreaker"
+)
+
+var breakerState = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "circuit_breaker_state",
+		Help: "Circuit breaker state (0 closed, 1 half-open, 2 open)",
+	},
+	[]string{"name"},
+)
+
+// Settings controls when a breaker opens and how long it stays open
+type Settings struct {
+	// FailureThreshold is the number of consecutive failures that opens
+	// the breaker
+	FailureThreshold uint32
+	// OpenTimeout is how long the breaker fails fast before letting a
+	// single trial request through
+	OpenTimeout time.Duration
+}
+
+// DefaultSettings are used when none are configured
+var DefaultSettings = Settings{
+	FailureThreshold: 5,
+	OpenTimeout:      30 * time.Second,
+}
+
+// New creates a breaker named after the dependency it guards. Callers ask
+// Allow before each call and report whether it succeeded; while the breaker
+// is open Allow returns gobreaker.ErrOpenState.
+func New(name string, settings Settings, logger *zerolog.Logger) *gobreaker.TwoStepCircuitBreaker {
+	if settings.FailureThreshold == 0 {
+		settings.FailureThreshold = DefaultSettings.FailureThreshold
+	}
+	if settings.OpenTimeout <= 0 {
+		settings.OpenTimeout = DefaultSettings.OpenTimeout
+	}
+
+	breakerState.WithLabelValues(name).Set(float64(gobreaker.StateClosed))
+
+	return gobreaker.NewTwoStepCircuitBreaker(gobreaker.Settings{
+		Name:        name,
+		MaxRequests: 1,
+		Timeout:     settings.OpenTimeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= settings.FailureThreshold
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			breakerState.WithLabelValues(name).Set(float64(to))
+
+			event := logger.Info()
+			if to == gobreaker.StateOpen {
+				event = logger.Warn().Dur("open_timeout", settings.OpenTimeout)
+			}
+			event.Str("breaker", name).
+				Str("from", from.String()).
+				Str("to", to.String()).
+				Msg("Circuit breaker state changed")
+		},
+	})
+}
@@ -0,0 +1,305 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/breaker"
+
+	"github.com/rs/zerolog"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis is a minimal RESP server supporting the commands the cache
+// uses. It can be stopped and restarted on the same address to simulate an
+// outage.
+type fakeRedis struct {
+	t    *testing.T
+	addr string
+
+	mu       sync.Mutex
+	data     map[string]string
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	f := &fakeRedis{t: t, data: make(map[string]string)}
+	f.start("127.0.0.1:0")
+	t.Cleanup(f.stop)
+	return f
+}
+
+func (f *fakeRedis) start(addr string) {
+	ln, err := net.Listen("tcp", addr)
+	require.NoError(f.t, err)
+
+	f.mu.Lock()
+	f.addr = ln.Addr().String()
+	f.listener = ln
+	f.conns = make(map[net.Conn]struct{})
+	f.mu.Unlock()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns[conn] = struct{}{}
+			f.mu.Unlock()
+			go f.serve(conn)
+		}
+	}()
+}
+
+func (f *fakeRedis) stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.listener == nil {
+		return
+	}
+	f.listener.Close()
+	f.listener = nil
+	for conn := range f.conns {
+		conn.Close()
+	}
+}
+
+func (f *fakeRedis) restart() {
+	f.start(f.addr)
+}
+
+func (f *fakeRedis) get(key string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.data[key]
+	return v, ok
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, f.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		v, ok := f.data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	case "SET":
+		_, exists := f.data[args[1]]
+		for _, opt := range args[3:] {
+			if strings.EqualFold(opt, "NX") && exists {
+				return "$-1\r\n"
+			}
+		}
+		f.data[args[1]] = args[2]
+		return "+OK\r\n"
+	case "DEL", "EXISTS":
+		n := 0
+		for _, key := range args[1:] {
+			if _, ok := f.data[key]; ok {
+				n++
+				if strings.EqualFold(args[0], "DEL") {
+					delete(f.data, key)
+				}
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+// readCommand reads a RESP array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, errors.New("expected array")
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// openTimeout is kept short so tests can wait for the breaker to let a
+// trial request through
+const openTimeout = 100 * time.Millisecond
+
+func newBreakerCache(t *testing.T, addr string) *redisCache {
+	t.Helper()
+	logger := zerolog.Nop()
+	settings := breaker.Settings{FailureThreshold: 1, OpenTimeout: openTimeout}
+	c := NewRedisCache("redis://"+addr+"?max_retries=-1&dial_timeout=200ms", &logger, time.Minute, 100, settings).(*redisCache)
+	t.Cleanup(func() { c.redis.Close() })
+	return c
+}
+
+func TestFallsBackToMemoryWhenRedisGoesDown(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedis(t)
+	c := newBreakerCache(t, server.addr)
+
+	require.NoError(t, c.Set(ctx, "user:1", "cached", 0))
+	_, ok := server.get("user:1")
+	assert.True(t, ok, "value should be written to Redis")
+	require.NoError(t, c.Ping(ctx))
+
+	server.stop()
+
+	// The first failing call opens the breaker and is served from memory
+	require.NoError(t, c.Set(ctx, "user:1", "during outage", 0))
+	assert.Equal(t, gobreaker.StateOpen, c.breaker.State())
+
+	var got string
+	require.NoError(t, c.Get(ctx, "user:1", &got))
+	assert.Equal(t, "during outage", got)
+
+	stored, err := c.SetNX(ctx, "lock", "1", 0)
+	require.NoError(t, err)
+	assert.True(t, stored)
+	require.NoError(t, c.Delete(ctx, "lock"))
+
+	assert.ErrorIs(t, c.Ping(ctx), ErrCacheUnavailable)
+}
+
+func TestRecoversWhenRedisComesBack(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedis(t)
+	c := newBreakerCache(t, server.addr)
+
+	require.NoError(t, c.Set(ctx, "user:1", "before", 0))
+	server.stop()
+
+	// Invalidated while Redis was down
+	require.NoError(t, c.Delete(ctx, "user:1"))
+	var got string
+	assert.ErrorIs(t, c.Get(ctx, "user:1", &got), ErrCacheMiss)
+
+	server.restart()
+
+	// The breaker is still open, so Redis is not used yet
+	require.NoError(t, c.Set(ctx, "user:2", "memory", 0))
+	_, ok := server.get("user:2")
+	assert.False(t, ok)
+
+	time.Sleep(2 * openTimeout)
+	require.NoError(t, c.Ping(ctx))
+	assert.Equal(t, gobreaker.StateClosed, c.breaker.State())
+
+	// The outage's writes and deletes were purged from Redis so it does not
+	// serve the value from before the outage
+	_, ok = server.get("user:1")
+	assert.False(t, ok, "stale key should be deleted on recovery")
+	assert.ErrorIs(t, c.Get(ctx, "user:1", &got), ErrCacheMiss)
+
+	require.NoError(t, c.Set(ctx, "user:3", "redis", 0))
+	_, ok = server.get("user:3")
+	assert.True(t, ok)
+}
+
+func TestStartsInMemoryWhenRedisIsDown(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedis(t)
+	addr := server.addr
+	server.stop()
+
+	c := newBreakerCache(t, addr)
+	assert.ErrorIs(t, c.Ping(ctx), ErrCacheUnavailable)
+
+	require.NoError(t, c.Set(ctx, "user:1", "memory", 0))
+	var got string
+	require.NoError(t, c.Get(ctx, "user:1", &got))
+	assert.Equal(t, "memory", got)
+
+	server.restart()
+	time.Sleep(2 * openTimeout)
+
+	require.NoError(t, c.Ping(ctx))
+	_, ok := server.get("user:1")
+	assert.False(t, ok)
+}
+
+func TestBreakerWaitsForFailureThreshold(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedis(t)
+	logger := zerolog.Nop()
+	settings := breaker.Settings{FailureThreshold: 3, OpenTimeout: time.Hour}
+	c := NewRedisCache("redis://"+server.addr+"?max_retries=-1&dial_timeout=200ms", &logger, time.Minute, 100, settings).(*redisCache)
+	t.Cleanup(func() { c.redis.Close() })
+
+	server.stop()
+	for i := 0; i < 2; i++ {
+		require.NoError(t, c.Set(ctx, "user:1", "memory", 0))
+		assert.Equal(t, gobreaker.StateClosed, c.breaker.State())
+	}
+
+	require.NoError(t, c.Set(ctx, "user:1", "memory", 0))
+	assert.Equal(t, gobreaker.StateOpen, c.breaker.State())
+}
+
+func TestCanceledContextDoesNotOpenBreaker(t *testing.T) {
+	server := newFakeRedis(t)
+	c := newBreakerCache(t, server.addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var got string
+	require.Error(t, c.Get(ctx, "user:1", &got))
+	assert.Equal(t, gobreaker.StateClosed, c.breaker.State())
+	require.NoError(t, c.Ping(context.Background()))
+}
@@ -22,6 +22,11 @@ type Service interface {
 	// Set stores a value in cache with the given TTL
 	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
 
+	// SetNX stores a value only if the key does not already exist and
+	// reports whether the value was stored. Cache warming uses this so it
+	// never overwrites a fresher value written by a live request.
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+
 	// Delete removes a key from cache
 	Delete(ctx context.Context, key string) error
 
@@ -0,0 +1,120 @@
+// Package cache implements invalidation of in-memory entries across
+// instances
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"user-auth-app/internal/messaging"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+)
+
+// InvalidationSubject is the broker subject deleted keys are announced on
+const InvalidationSubject = "cache.invalidate"
+
+var invalidations = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cache_invalidations_total",
+		Help: "Cache keys announced to or evicted for other instances, by result (published, publish_failed, evicted)",
+	},
+	[]string{"result"},
+)
+
+// invalidation announces a deleted key. Origin identifies the instance
+// that deleted it, which already evicted its own entry.
+type invalidation struct {
+	Origin string `json:"origin"`
+	Key    string `json:"key"`
+}
+
+// localEvicter is implemented by caches that hold entries in process
+// memory
+type localEvicter interface {
+	evictLocal(key string)
+}
+
+// invalidatingCache announces deleted keys so other instances drop them
+// from memory
+type invalidatingCache struct {
+	Service
+	local  localEvicter
+	broker messaging.Broker
+	origin string
+	logger *zerolog.Logger
+}
+
+// WithInvalidation returns c extended to announce every deleted key on
+// broker and to evict keys deleted by other instances from its memory.
+// Redis is shared by all instances, but values cached in memory, without
+// Redis or during an outage, are not, so another instance would keep
+// serving a changed user until the entry expires. Caches that keep nothing
+// in memory are returned unchanged.
+func WithInvalidation(c Service, broker messaging.Broker, logger *zerolog.Logger) (Service, error) {
+	local, ok := c.(localEvicter)
+	if !ok {
+		return c, nil
+	}
+
+	origin, err := newOrigin()
+	if err != nil {
+		return nil, fmt.Errorf("generate cache origin: %w", err)
+	}
+
+	ic := &invalidatingCache{
+		Service: c,
+		local:   local,
+		broker:  broker,
+		origin:  origin,
+		logger:  logger,
+	}
+	if err := broker.Subscribe(InvalidationSubject, ic.handle); err != nil {
+		return nil, fmt.Errorf("subscribe to cache invalidations: %w", err)
+	}
+	return ic, nil
+}
+
+// Delete removes key here and announces it to the other instances, even
+// if the local delete failed, since their entries are stale either way
+func (c *invalidatingCache) Delete(ctx context.Context, key string) error {
+	err := c.Service.Delete(ctx, key)
+
+	if pubErr := c.broker.PublishJSON(InvalidationSubject, invalidation{Origin: c.origin, Key: key}); pubErr != nil {
+		invalidations.WithLabelValues("publish_failed").Inc()
+		c.logger.Warn().Err(pubErr).Str("key", key).Msg("Failed to announce cache invalidation")
+	} else {
+		invalidations.WithLabelValues("published").Inc()
+	}
+
+	return err
+}
+
+// handle evicts a key another instance deleted
+func (c *invalidatingCache) handle(data []byte) error {
+	var msg invalidation
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("decode cache invalidation: %w", err)
+	}
+	if msg.Origin == c.origin {
+		return nil
+	}
+
+	c.local.evictLocal(msg.Key)
+	invalidations.WithLabelValues("evicted").Inc()
+	return nil
+}
+
+// newOrigin returns a random instance ID
+func newOrigin() (string, error) {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
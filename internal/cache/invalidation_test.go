@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"user-auth-app/internal/messaging"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// busBroker delivers published messages synchronously to every
+// subscriber, like NATS fanning out to all instances
+type busBroker struct {
+	messaging.Broker
+	mu       sync.Mutex
+	handlers map[string][]func([]byte) error
+}
+
+func (b *busBroker) Subscribe(subject string, handler func([]byte) error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.handlers == nil {
+		b.handlers = make(map[string][]func([]byte) error)
+	}
+	b.handlers[subject] = append(b.handlers[subject], handler)
+	return nil
+}
+
+func (b *busBroker) PublishJSON(subject string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	handlers := b.handlers[subject]
+	b.mu.Unlock()
+	for _, handler := range handlers {
+		if err := handler(payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newInvalidatingCache(t *testing.T, broker messaging.Broker) Service {
+	t.Helper()
+	logger := zerolog.Nop()
+	c, err := WithInvalidation(newMemoryCache(t), broker, &logger)
+	require.NoError(t, err)
+	return c
+}
+
+func TestDeleteEvictsOtherInstances(t *testing.T) {
+	ctx := context.Background()
+	broker := &busBroker{}
+	a := newInvalidatingCache(t, broker)
+	b := newInvalidatingCache(t, broker)
+
+	require.NoError(t, a.Set(ctx, "user:1", "alice", 0))
+	require.NoError(t, b.Set(ctx, "user:1", "alice", 0))
+	require.NoError(t, b.Set(ctx, "user:2", "bob", 0))
+
+	require.NoError(t, a.Delete(ctx, "user:1"))
+
+	var got string
+	assert.ErrorIs(t, a.Get(ctx, "user:1", &got), ErrCacheMiss)
+	assert.ErrorIs(t, b.Get(ctx, "user:1", &got), ErrCacheMiss)
+	require.NoError(t, b.Get(ctx, "user:2", &got), "other keys are kept")
+	assert.Equal(t, "bob", got)
+}
+
+func TestInvalidationIgnoresOwnAnnouncements(t *testing.T) {
+	ctx := context.Background()
+	broker := &busBroker{}
+	c := newInvalidatingCache(t, broker).(*invalidatingCache)
+
+	require.NoError(t, c.Set(ctx, "user:1", "alice", 0))
+	payload, err := json.Marshal(invalidation{Origin: c.origin, Key: "user:1"})
+	require.NoError(t, err)
+	require.NoError(t, c.handle(payload))
+
+	var got string
+	assert.NoError(t, c.Get(ctx, "user:1", &got))
+
+	assert.Error(t, c.handle([]byte("not json")))
+}
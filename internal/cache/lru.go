@@ -0,0 +1,159 @@
+// Package cache implements a bounded in-memory store for the fallback cache
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	memoryEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cache_memory_entries",
+		Help: "Entries held in the in-memory fallback cache",
+	})
+	memoryEvictions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_memory_evictions_total",
+			Help: "Entries dropped from the in-memory fallback cache, by reason (capacity, expired)",
+		},
+		[]string{"reason"},
+	)
+)
+
+type cacheEntry struct {
+	value      interface{}
+	expiration time.Time
+}
+
+// lruItem is the value of a list element
+type lruItem struct {
+	key   string
+	entry cacheEntry
+}
+
+// lru holds at most maxEntries entries, dropping the least recently used
+// one to make room. Expired entries are dropped when they are next looked
+// up or when they reach the back of the list.
+type lru struct {
+	maxEntries int
+
+	mu    sync.Mutex
+	order *list.List // front is the most recently used
+	items map[string]*list.Element
+}
+
+func newLRU(maxEntries int) *lru {
+	return &lru{
+		maxEntries: max(maxEntries, 1),
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// get returns the live entry for key, marking it as recently used
+func (l *lru) get(key string) (cacheEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.items[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	item := elem.Value.(*lruItem)
+	if time.Now().After(item.entry.expiration) {
+		l.remove(elem)
+		memoryEvictions.WithLabelValues("expired").Inc()
+		return cacheEntry{}, false
+	}
+
+	l.order.MoveToFront(elem)
+	return item.entry, true
+}
+
+// set stores entry under key, replacing any previous entry
+func (l *lru) set(key string, entry cacheEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.items[key]; ok {
+		elem.Value.(*lruItem).entry = entry
+		l.order.MoveToFront(elem)
+		return
+	}
+	l.add(key, entry)
+}
+
+// setNX stores entry only if key is absent or expired and reports whether
+// it was stored
+func (l *lru) setNX(key string, entry cacheEntry) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.items[key]; ok {
+		item := elem.Value.(*lruItem)
+		if time.Now().Before(item.entry.expiration) {
+			return false
+		}
+		item.entry = entry
+		l.order.MoveToFront(elem)
+		return true
+	}
+	l.add(key, entry)
+	return true
+}
+
+// delete drops key if present
+func (l *lru) delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.items[key]; ok {
+		l.remove(elem)
+	}
+}
+
+// clear drops every entry
+func (l *lru) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	memoryEntries.Sub(float64(len(l.items)))
+	l.order.Init()
+	clear(l.items)
+}
+
+// len returns the number of entries, including expired ones not yet
+// dropped
+func (l *lru) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.items)
+}
+
+// add inserts a new entry, making room first. It must be called with mu
+// held.
+func (l *lru) add(key string, entry cacheEntry) {
+	for len(l.items) >= l.maxEntries {
+		oldest := l.order.Back()
+		reason := "capacity"
+		if time.Now().After(oldest.Value.(*lruItem).entry.expiration) {
+			reason = "expired"
+		}
+		l.remove(oldest)
+		memoryEvictions.WithLabelValues(reason).Inc()
+	}
+
+	l.items[key] = l.order.PushFront(&lruItem{key: key, entry: entry})
+	memoryEntries.Inc()
+}
+
+// remove must be called with mu held
+func (l *lru) remove(elem *list.Element) {
+	l.order.Remove(elem)
+	delete(l.items, elem.Value.(*lruItem).key)
+	memoryEntries.Dec()
+}
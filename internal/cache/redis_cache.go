@@ -4,34 +4,55 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"user-auth-app/internal/breaker"
+
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
+	"github.com/sony/gobreaker"
 )
 
-type cacheEntry struct {
-	value      interface{}
-	expiration time.Time
-}
+const (
+	redisPurgeTimeout = 2 * time.Second
+	// maxStaleKeys bounds the keys remembered during an outage
+	maxStaleKeys = 10000
+)
 
+// redisCache stores values in Redis and falls back to a bounded in-memory
+// LRU when Redis is not configured or stops responding. Redis calls go through
+// a circuit breaker; while it is open every operation is served from memory
+// without waiting on Redis.
 type redisCache struct {
-	redis       *redis.Client
-	fallback    sync.Map
-	logger      *zerolog.Logger
-	defaultTTL  time.Duration
-	useRedis    bool
-	cleanupOnce sync.Once
+	redis      *redis.Client
+	breaker    *gobreaker.TwoStepCircuitBreaker
+	fallback   *lru
+	logger     *zerolog.Logger
+	defaultTTL time.Duration
+
+	// purgeMu serializes purges. mu guards the fields below it.
+	purgeMu sync.Mutex
+	mu      sync.Mutex
+	// degraded is set once an operation has been served from memory
+	// although Redis is configured
+	degraded bool
+	// staleKeys are keys written or deleted in memory while degraded.
+	// They are deleted from Redis before it is used again so it cannot
+	// serve values that changed during the outage.
+	staleKeys     map[string]struct{}
+	staleOverflow bool
 }
 
-// NewRedisCache creates a new Redis cache service with in-memory fallback
-func NewRedisCache(redisURL string, logger *zerolog.Logger, defaultTTL time.Duration) Service {
+// NewRedisCache creates a new Redis cache service with an in-memory
+// fallback holding at most maxEntries entries
+func NewRedisCache(redisURL string, logger *zerolog.Logger, defaultTTL time.Duration, maxEntries int, breakerSettings breaker.Settings) Service {
 	cache := &redisCache{
+		fallback:   newLRU(maxEntries),
 		logger:     logger,
 		defaultTTL: defaultTTL,
-		useRedis:   false,
 	}
 
 	if redisURL != "" {
@@ -41,29 +62,43 @@ func NewRedisCache(redisURL string, logger *zerolog.Logger, defaultTTL time.Dura
 			return cache
 		}
 
-		rdb := redis.NewClient(opts)
+		// Keep the client even if Redis is down now so it is picked up
+		// once it comes back
+		cache.redis = redis.NewClient(opts)
+		cache.breaker = breaker.New("redis", breakerSettings, logger)
+
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		if err := rdb.Ping(ctx).Err(); err != nil {
-			logger.Warn().Err(err).Msg("Redis unavailable, using in-memory cache")
+		if err := cache.Ping(ctx); err != nil {
+			logger.Warn().Err(err).Msg("Redis unavailable, using in-memory cache until it recovers")
 			return cache
 		}
 
-		cache.redis = rdb
-		cache.useRedis = true
 		logger.Info().Msg("Redis cache initialized")
 	}
 
-	// Start cleanup goroutine for in-memory cache
-	cache.startCleanup()
-
 	return cache
 }
 
+// RedisClient returns the Redis client behind cache so other components
+// can share its connections, or nil if cache doesn't use Redis
+func RedisClient(cache Service) *redis.Client {
+	if c, ok := cache.(*invalidatingCache); ok {
+		cache = c.Service
+	}
+	if c, ok := cache.(*redisCache); ok {
+		return c.redis
+	}
+	return nil
+}
+
 func (c *redisCache) Get(ctx context.Context, key string, dest interface{}) error {
-	if c.useRedis {
-		return c.getFromRedis(ctx, key, dest)
+	if done, ok := c.allowRedis(ctx); ok {
+		err := c.getFromRedis(ctx, key, dest)
+		if !c.failed(ctx, done, err) {
+			return err
+		}
 	}
 	return c.getFromMemory(key, dest)
 }
@@ -73,35 +108,204 @@ func (c *redisCache) Set(ctx context.Context, key string, value interface{}, ttl
 		ttl = c.defaultTTL
 	}
 
-	if c.useRedis {
-		return c.setToRedis(ctx, key, value, ttl)
+	if done, ok := c.allowRedis(ctx); ok {
+		err := c.setToRedis(ctx, key, value, ttl)
+		if !c.failed(ctx, done, err) {
+			return err
+		}
 	}
+	c.markStale(key)
 	return c.setToMemory(key, value, ttl)
 }
 
+func (c *redisCache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	if ttl == 0 {
+		ttl = c.defaultTTL
+	}
+
+	if done, ok := c.allowRedis(ctx); ok {
+		stored, err := c.setNXToRedis(ctx, key, value, ttl)
+		if !c.failed(ctx, done, err) {
+			return stored, err
+		}
+	}
+	c.markStale(key)
+	return c.setNXToMemory(key, value, ttl), nil
+}
+
 func (c *redisCache) Delete(ctx context.Context, key string) error {
-	if c.useRedis {
-		return c.redis.Del(ctx, key).Err()
+	if done, ok := c.allowRedis(ctx); ok {
+		err := c.redis.Del(ctx, key).Err()
+		if err != nil {
+			err = c.redisError("del", key, err)
+		}
+		if !c.failed(ctx, done, err) {
+			return err
+		}
 	}
-	c.fallback.Delete(key)
+	c.markStale(key)
+	c.fallback.delete(key)
 	return nil
 }
 
 func (c *redisCache) Exists(ctx context.Context, key string) (bool, error) {
-	if c.useRedis {
+	if done, ok := c.allowRedis(ctx); ok {
 		result, err := c.redis.Exists(ctx, key).Result()
-		return result > 0, err
+		if err != nil {
+			err = c.redisError("exists", key, err)
+		}
+		if !c.failed(ctx, done, err) {
+			return result > 0, err
+		}
 	}
 
-	_, ok := c.fallback.Load(key)
+	_, ok := c.fallback.get(key)
 	return ok, nil
 }
 
+// evictLocal drops key from memory only, for keys another instance
+// deleted. Redis is shared, so its copy is already gone.
+func (c *redisCache) evictLocal(key string) {
+	c.fallback.delete(key)
+}
+
+// Ping reports ErrCacheUnavailable while Redis is configured but failing,
+// so health checks show the cache as degraded
 func (c *redisCache) Ping(ctx context.Context) error {
-	if c.useRedis {
-		return c.redis.Ping(ctx).Err()
+	if c.redis == nil {
+		return nil
 	}
-	return nil
+
+	done, ok := c.allowRedis(ctx)
+	if !ok {
+		return ErrCacheUnavailable
+	}
+
+	err := c.redis.Ping(ctx).Err()
+	if err != nil {
+		err = c.redisError("ping", "", err)
+	}
+	c.failed(ctx, done, err)
+	return err
+}
+
+// allowRedis reports whether an operation should go to Redis and, if so,
+// returns the callback for its outcome. After an outage the keys changed
+// in memory are first deleted from Redis.
+func (c *redisCache) allowRedis(ctx context.Context) (func(bool), bool) {
+	if c.redis == nil {
+		return nil, false
+	}
+
+	done, err := c.breaker.Allow()
+	if err != nil {
+		return nil, false
+	}
+
+	if err := c.purgeStaleKeys(); err != nil {
+		c.logger.Debug().Err(err).Msg("Failed to purge stale keys from Redis")
+		done(ctx.Err() != nil)
+		return nil, false
+	}
+
+	return done, true
+}
+
+// failed reports the outcome of a Redis operation to the breaker and
+// whether the operation should fall back to memory. Cache misses, encoding
+// errors and the caller's own timeouts don't count as Redis failures.
+func (c *redisCache) failed(ctx context.Context, done func(bool), err error) bool {
+	if !errors.Is(err, ErrCacheUnavailable) || ctx.Err() != nil {
+		done(true)
+		return false
+	}
+
+	done(false)
+	return true
+}
+
+// redisError logs a Redis error and wraps it in ErrCacheUnavailable
+func (c *redisCache) redisError(op, key string, err error) error {
+	c.logger.Error().Err(err).Str("key", key).Msgf("Redis %s failed", op)
+	return fmt.Errorf("redis %s: %w: %w", op, ErrCacheUnavailable, err)
+}
+
+// purgeStaleKeys deletes the keys changed in memory while degraded from
+// Redis and then drops the in-memory entries, which would be stale by the
+// next outage
+func (c *redisCache) purgeStaleKeys() error {
+	c.mu.Lock()
+	degraded := c.degraded
+	c.mu.Unlock()
+	if !degraded {
+		return nil
+	}
+
+	c.purgeMu.Lock()
+	defer c.purgeMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisPurgeTimeout)
+	defer cancel()
+
+	for {
+		c.mu.Lock()
+		if !c.degraded {
+			c.mu.Unlock()
+			return nil
+		}
+		keys := make([]string, 0, len(c.staleKeys))
+		for key := range c.staleKeys {
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 {
+			overflow := c.staleOverflow
+			c.degraded = false
+			c.staleOverflow = false
+			c.mu.Unlock()
+
+			c.fallback.clear()
+			if overflow {
+				c.logger.Warn().Int("max_stale_keys", maxStaleKeys).Msg("Too many keys changed during Redis outage, some cached values may be stale until they expire")
+			}
+			return nil
+		}
+		c.staleKeys = nil
+		c.mu.Unlock()
+
+		if err := c.redis.Del(ctx, keys...).Err(); err != nil {
+			// Put the keys back for the next attempt
+			c.mu.Lock()
+			for _, key := range keys {
+				c.addStaleKey(key)
+			}
+			c.mu.Unlock()
+			return fmt.Errorf("redis del: %w", err)
+		}
+	}
+}
+
+// markStale records a key changed in memory although Redis is configured
+func (c *redisCache) markStale(key string) {
+	if c.redis == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.degraded = true
+	c.addStaleKey(key)
+}
+
+// addStaleKey must be called with mu held
+func (c *redisCache) addStaleKey(key string) {
+	if c.staleKeys == nil {
+		c.staleKeys = make(map[string]struct{})
+	}
+	if len(c.staleKeys) >= maxStaleKeys {
+		c.staleOverflow = true
+		return
+	}
+	c.staleKeys[key] = struct{}{}
 }
 
 // getFromRedis retrieves value from Redis
@@ -111,8 +315,7 @@ func (c *redisCache) getFromRedis(ctx context.Context, key string, dest interfac
 		return ErrCacheMiss
 	}
 	if err != nil {
-		c.logger.Error().Err(err).Str("key", key).Msg("Redis get failed")
-		return fmt.Errorf("redis get: %w", err)
+		return c.redisError("get", key, err)
 	}
 
 	if err := json.Unmarshal([]byte(val), dest); err != nil {
@@ -130,29 +333,31 @@ func (c *redisCache) setToRedis(ctx context.Context, key string, value interface
 	}
 
 	if err := c.redis.Set(ctx, key, data, ttl).Err(); err != nil {
-		c.logger.Error().Err(err).Str("key", key).Msg("Redis set failed")
-		return fmt.Errorf("redis set: %w", err)
+		return c.redisError("set", key, err)
 	}
 
 	return nil
 }
 
-// getFromMemory retrieves value from in-memory cache
-func (c *redisCache) getFromMemory(key string, dest interface{}) error {
-	val, ok := c.fallback.Load(key)
-	if !ok {
-		return ErrCacheMiss
+// setNXToRedis stores value in Redis only if the key is absent
+func (c *redisCache) setNXToRedis(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("marshal cache value: %w", err)
 	}
 
-	entry, ok := val.(cacheEntry)
-	if !ok {
-		c.fallback.Delete(key)
-		return ErrCacheMiss
+	stored, err := c.redis.SetNX(ctx, key, data, ttl).Result()
+	if err != nil {
+		return false, c.redisError("setnx", key, err)
 	}
 
-	// Check expiration
-	if time.Now().After(entry.expiration) {
-		c.fallback.Delete(key)
+	return stored, nil
+}
+
+// getFromMemory retrieves value from in-memory cache
+func (c *redisCache) getFromMemory(key string, dest interface{}) error {
+	entry, ok := c.fallback.get(key)
+	if !ok {
 		return ErrCacheMiss
 	}
 
@@ -171,31 +376,12 @@ func (c *redisCache) getFromMemory(key string, dest interface{}) error {
 
 // setToMemory stores value in in-memory cache
 func (c *redisCache) setToMemory(key string, value interface{}, ttl time.Duration) error {
-	entry := cacheEntry{
-		value:      value,
-		expiration: time.Now().Add(ttl),
-	}
-	c.fallback.Store(key, entry)
+	c.fallback.set(key, cacheEntry{value: value, expiration: time.Now().Add(ttl)})
 	return nil
 }
 
-// startCleanup starts a goroutine to clean up expired entries
-func (c *redisCache) startCleanup() {
-	c.cleanupOnce.Do(func() {
-		go func() {
-			ticker := time.NewTicker(1 * time.Minute)
-			defer ticker.Stop()
-
-			for range ticker.C {
-				now := time.Now()
-				c.fallback.Range(func(key, value interface{}) bool {
-					entry, ok := value.(cacheEntry)
-					if !ok || now.After(entry.expiration) {
-						c.fallback.Delete(key)
-					}
-					return true
-				})
-			}
-		}()
-	})
+// setNXToMemory stores value in in-memory cache only if the key is absent
+// or expired
+func (c *redisCache) setNXToMemory(key string, value interface{}, ttl time.Duration) bool {
+	return c.fallback.setNX(key, cacheEntry{value: value, expiration: time.Now().Add(ttl)})
 }
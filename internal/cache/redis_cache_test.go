@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/breaker"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMemoryCache(t *testing.T) Service {
+	t.Helper()
+	logger := zerolog.Nop()
+	return NewRedisCache("", &logger, time.Minute, 100, breaker.DefaultSettings)
+}
+
+func TestSetNXDoesNotOverwriteFresherValue(t *testing.T) {
+	ctx := context.Background()
+	c := newMemoryCache(t)
+
+	// A live request populates the key first
+	require.NoError(t, c.Set(ctx, "user:1", "fresh", 0))
+
+	// Cache warming arrives later with stale data
+	stored, err := c.SetNX(ctx, "user:1", "stale", 0)
+	require.NoError(t, err)
+	assert.False(t, stored)
+
+	var got string
+	require.NoError(t, c.Get(ctx, "user:1", &got))
+	assert.Equal(t, "fresh", got)
+}
+
+func TestSetNXReplacesExpiredEntry(t *testing.T) {
+	ctx := context.Background()
+	c := newMemoryCache(t)
+
+	require.NoError(t, c.Set(ctx, "user:1", "old", time.Nanosecond))
+	time.Sleep(time.Millisecond)
+
+	stored, err := c.SetNX(ctx, "user:1", "new", 0)
+	require.NoError(t, err)
+	assert.True(t, stored)
+
+	var got string
+	require.NoError(t, c.Get(ctx, "user:1", &got))
+	assert.Equal(t, "new", got)
+}
+
+func TestSetNXRaceWithLiveWrites(t *testing.T) {
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		c := newMemoryCache(t)
+
+		// Goroutines report errors here, since require must not be
+		// called off the test goroutine
+		var wg sync.WaitGroup
+		var warmed bool
+		errs := make(chan error, 2)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- c.Set(ctx, "user:1", "live", 0)
+		}()
+		go func() {
+			defer wg.Done()
+			stored, err := c.SetNX(ctx, "user:1", "warm", 0)
+			warmed = stored
+			errs <- err
+		}()
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		// Whatever the interleaving, the live write must win
+		var got string
+		require.NoError(t, c.Get(ctx, "user:1", &got))
+		assert.Equal(t, "live", got, "warmed=%v", warmed)
+	}
+}
+
+func TestSetNXOnlyOneWinner(t *testing.T) {
+	ctx := context.Background()
+	c := newMemoryCache(t)
+
+	const attempts = 50
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		winners int
+	)
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stored, err := c.SetNX(ctx, "user:1", "warm", 0)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if stored {
+				mu.Lock()
+				winners++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, winners)
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	c := NewRedisCache("", &logger, time.Minute, 2, breaker.DefaultSettings)
+
+	require.NoError(t, c.Set(ctx, "user:1", "one", 0))
+	require.NoError(t, c.Set(ctx, "user:2", "two", 0))
+
+	// Reading user:1 makes user:2 the least recently used
+	var got string
+	require.NoError(t, c.Get(ctx, "user:1", &got))
+	require.NoError(t, c.Set(ctx, "user:3", "three", 0))
+
+	assert.ErrorIs(t, c.Get(ctx, "user:2", &got), ErrCacheMiss)
+	require.NoError(t, c.Get(ctx, "user:1", &got))
+	assert.Equal(t, "one", got)
+	require.NoError(t, c.Get(ctx, "user:3", &got))
+	assert.Equal(t, "three", got)
+	assert.Equal(t, 2, c.(*redisCache).fallback.len())
+}
+
+func TestMemoryCacheDropsExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	c := newMemoryCache(t)
+
+	require.NoError(t, c.Set(ctx, "user:1", "old", time.Nanosecond))
+	time.Sleep(time.Millisecond)
+
+	exists, err := c.Exists(ctx, "user:1")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.Zero(t, c.(*redisCache).fallback.len())
+}
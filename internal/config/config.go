@@ -2,23 +2,108 @@
 package config
 
 import (
+	"compress/gzip"
 	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/email"
+	"user-auth-app/internal/hashing"
+	"user-auth-app/internal/ratelimit"
+	"user-auth-app/internal/repository"
+	"user-auth-app/internal/validator"
+
 	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// MaxCacheWarmUsers bounds how many users are preloaded into the cache
+// at startup
+const MaxCacheWarmUsers = 10000
+
 // Config holds all application configuration
 type Config struct {
 	// Database
-	DBURL string
+	DBURL             string
+	DBMaxConns        int
+	DBMinConns        int
+	DBMaxConnLifetime time.Duration
+	DBMaxConnIdleTime time.Duration
+	// DBReplicaURL is an optional read replica serving the admin user
+	// list and search
+	DBReplicaURL string
+	// DBQueryExecMode is a key of repository.QueryExecModes; empty keeps
+	// the connection string's default_query_exec_mode or pgx's default
+	DBQueryExecMode string
+	// DBRetryMaxAttempts bounds tries of a statement that fails with a
+	// transient error; 1 disables retries
+	DBRetryMaxAttempts int
+	// AutoMigrate applies pending migrations on startup
+	AutoMigrate bool
 
 	// Authentication
-	JWTSecret string
-	JWTExpiry time.Duration
+	JWTSecret        string
+	JWTExpiry        time.Duration
+	JWTIssuer        string
+	JWTAudience      string
+	TokenBindingMode string
+
+	// SessionLifetime is how long a login's session lasts without a
+	// refresh, each refresh extending it that long again; SessionRememberMe
+	// replaces it for logins with remember_me. Tokens never outlive their
+	// session.
+	SessionLifetime   time.Duration
+	SessionRememberMe time.Duration
+	// SessionPurgeInterval is how often expired sessions are deleted; 0
+	// keeps them
+	SessionPurgeInterval time.Duration
+
+	// RolePermissions maps roles to the permissions embedded in their
+	// tokens. ROLE_PERMISSIONS overrides the defaults role by role.
+	RolePermissions domain.RolePermissions
+	// AllowedRoles are the roles users can be given. The public register
+	// endpoint never accepts admin, whatever the list says.
+	AllowedRoles []string
+
+	// Token transport: AuthMode is header, cookie or both. Cookie auth
+	// sets an HttpOnly token cookie on login. CSRFProtection adds the
+	// double-submit CSRF check and defaults to on when cookies are used.
+	AuthMode           string
+	AuthCookieName     string
+	CSRFCookieName     string
+	AuthCookieSecure   bool
+	AuthCookieSameSite string
+	CSRFProtection     bool
+
+	// Sign in with Google is enabled when GoogleClientID is set. The
+	// redirect URL must point at /api/v1/auth/google/callback and match
+	// the one registered with Google.
+	GoogleClientID     string
+	GoogleClientSecret string
+	GoogleRedirectURL  string
+
+	// JWT signing: HS256 signs with JWTSecret (or the secret in
+	// JWTSecretFile); RS256 signs with the private key and publishes public
+	// keys at /.well-known/jwks.json. JWTPublicKeyFiles are retired RS256
+	// keys still accepted for verification. Key files are re-read on SIGHUP.
+	JWTSigningMethod  string
+	JWTSecretFile     string
+	JWTPrivateKeyFile string
+	JWTPublicKeyFiles []string
+
+	// Password hashing
+	PasswordHashAlgorithm string
+	BcryptCost            int
+	Argon2Memory          int
+	Argon2Iterations      int
+	Argon2Parallelism     int
 
 	// Server
 	Port           string
@@ -27,45 +112,383 @@ type Config struct {
 	Environment    string
 	AllowedOrigins []string
 
-	// Rate Limiting
-	RateLimitRPS   int
-	RateLimitBurst int
+	// TrustedProxies are the networks of the reverse proxies in front of
+	// the server. Only they are believed about the client's address in
+	// X-Forwarded-For and X-Real-IP; with none, the headers are ignored.
+	TrustedProxies []netip.Prefix
+	// AdminAllowedNetworks and AdminDeniedNetworks restrict the admin
+	// routes to client networks; a denied network wins. With neither set
+	// the admin routes are reachable from anywhere.
+	AdminAllowedNetworks []netip.Prefix
+	AdminDeniedNetworks  []netip.Prefix
+
+	// Connection timeouts of the HTTP listeners. ReadHeaderTimeout bounds
+	// how long a client may take to send its headers (Slowloris);
+	// WriteTimeout must leave room for handlers to finish within Timeout.
+	HTTPReadHeaderTimeout time.Duration
+	HTTPReadTimeout       time.Duration
+	HTTPWriteTimeout      time.Duration
+	HTTPIdleTimeout       time.Duration
+
+	// AdminAddr, when set, moves /metrics, health checks and pprof off the
+	// public listener to a second one at this address
+	AdminAddr string
+	// EnablePprof mounts the pprof handlers under /debug/pprof, on the
+	// admin listener when there is one
+	EnablePprof bool
+
+	// SwaggerUI serves Swagger UI for /openapi.json at /docs
+	SwaggerUI bool
+
+	// Credentials for scraping /metrics: a bearer token and/or a basic
+	// auth username and password. With none set the endpoint is open,
+	// which in production is only allowed on the admin listener.
+	MetricsToken    string
+	MetricsUsername string
+	MetricsPassword string
+
+	// MaxRequestBodyBytes caps JSON request bodies
+	MaxRequestBodyBytes int
+	// RequireJSONContentType rejects JSON request bodies not declared as
+	// application/json with 415
+	RequireJSONContentType bool
+
+	// Responses of at least CompressionMinBytes are compressed at
+	// CompressionLevel (1-9) for clients that accept it
+	CompressionLevel    int
+	CompressionMinBytes int
+
+	// LogValidationFailures logs rejected requests (field names and
+	// failure codes only) at info for abuse detection
+	LogValidationFailures bool
+
+	// IdempotencyTTL is how long a registration response is kept for
+	// replay to retries with the same Idempotency-Key
+	IdempotencyTTL time.Duration
+
+	// MaxImportUsers caps the users in one POST /admin/users/import
+	MaxImportUsers int
+
+	// LogRequestBodies adds JSON request bodies, with passwords and
+	// tokens redacted, to access logs. Development only.
+	LogRequestBodies bool
+
+	// TenantBaseDomain enables resolving the tenant from the request's
+	// subdomain (acme.TenantBaseDomain is tenant "acme"). The X-Tenant-ID
+	// header works either way.
+	TenantBaseDomain string
+
+	// Rate Limiting. RateLimit* applies per client IP to every request;
+	// UserRateLimit* applies per user to authenticated requests.
+	// RateLimitBackend "redis" shares limits between instances, "memory"
+	// limits each instance separately.
+	RateLimitRPS       int
+	RateLimitBurst     int
+	UserRateLimitRPS   int
+	UserRateLimitBurst int
+	RateLimitBackend   string
+
+	// Account Lockout; zero MaxFailedLogins disables it
+	MaxFailedLogins int
+	LockoutDuration time.Duration
+	// LoginThrottleDisclosure tells clients how many login attempts remain
+	// and when a locked account unlocks
+	LoginThrottleDisclosure bool
+	// LoginIPMaxFailures is how many distinct accounts one address may fail
+	// to log in to within LoginIPFailureWindow before its logins are
+	// refused. Zero disables the check.
+	LoginIPMaxFailures   int
+	LoginIPFailureWindow time.Duration
+
+	// Validation
+	UsernameMinLen    int
+	UsernameMaxLen    int
+	PasswordMinLength int
+	PasswordMaxLength int
+
+	// ReservedUsernames can't be registered or given to users, whatever
+	// their case
+	ReservedUsernames []string
+
+	// Password Policy
+	PasswordRequireUpper   bool
+	PasswordRequireLower   bool
+	PasswordRequireDigit   bool
+	PasswordRequireSpecial bool
+	PasswordRejectCommon   bool
+	PasswordBreachCheck    bool
+	HIBPURL                string
+	// PasswordHistory is how many recent passwords, including the current
+	// one, a changed password must not match; 0 disables history
+	PasswordHistory int
+	// PasswordMaxAge is how long a password stays valid before logins
+	// require changing it; 0 disables expiry
+	PasswordMaxAge time.Duration
+
+	// Registration
+	EnumerationSafeRegistration bool
+	// VerificationResendCooldown is how long after a verification resend
+	// further requests for the same email are ignored
+	VerificationResendCooldown time.Duration
+	// InviteOnly requires an unused invite code to register
+	InviteOnly bool
+	// InviteTTL is how long an invite code can be used after it's created
+	InviteTTL time.Duration
+
+	// Bootstrap Admin. Without an email the admin is only created while
+	// there are no users.
+	BootstrapAdmin         bool
+	BootstrapAdminEmail    string
+	BootstrapAdminUsername string
+	BootstrapAdminPassword string
 
 	// External Services
-	RedisURL string
-	NatsURL  string
-	CacheTTL time.Duration
+	RedisURL             string
+	NatsURL              string
+	NatsSubscriberBuffer int
+	// NatsReconnectBufferBytes bounds the messages held for NATS while it
+	// is unreachable
+	NatsReconnectBufferBytes int
+	CacheTTL                 time.Duration
+	// CacheMaxEntries bounds the in-memory fallback cache; the least
+	// recently used entries are evicted beyond it
+	CacheMaxEntries int
+	// NegativeCacheTTL is how long a lookup of a missing user is cached;
+	// 0 disables negative caching
+	NegativeCacheTTL time.Duration
+	// CacheWarmUsers is how many of the most recently active users are
+	// preloaded into the cache at startup, unless CacheWarmUserIDs names
+	// the users to preload; 0 with no IDs disables warming. Warming gives
+	// up after CacheWarmTimeout.
+	CacheWarmUsers   int
+	CacheWarmUserIDs []int32
+	CacheWarmTimeout time.Duration
+
+	// Circuit breakers around the database and Redis: a breaker opens after
+	// BreakerFailureThreshold consecutive failures and fails fast for
+	// BreakerOpenTimeout before letting a trial request through
+	BreakerFailureThreshold int
+	BreakerOpenTimeout      time.Duration
+
+	// Audit
+	AuditSinks     []string
+	SyslogEndpoint string
+	// AuditProfileReads records who read whose profile
+	AuditProfileReads bool
+
+	// Webhooks. Each delivery request times out after WebhookTimeout and
+	// is attempted up to WebhookMaxAttempts times.
+	WebhookTimeout     time.Duration
+	WebhookMaxAttempts int
+	// WebhookAllowedNetworks are internal networks webhooks may be
+	// delivered to. Loopback, private and link-local addresses are refused
+	// unless listed.
+	WebhookAllowedNetworks []netip.Prefix
+	// WebhookDeliveryRetention is how long delivery attempts are kept; 0
+	// keeps them forever
+	WebhookDeliveryRetention time.Duration
+
+	// Email configures the mailer. It's read like every other setting, so
+	// it can come from the config file too.
+	Email email.Config
 }
 
-// Load loads configuration from environment variables
+// Load loads configuration from environment variables and, optionally,
+// from the YAML or JSON file named by CONFIG_FILE. Precedence is
+// environment variable > config file > built-in default.
+// Parse and validation problems are aggregated into a single error.
 func Load() (*Config, error) {
+	env := &envReader{}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		values, err := loadConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		env.file = values
+	}
+
 	cfg := &Config{
-		DBURL:          getEnv("DB_URL", ""),
-		JWTSecret:      getEnv("JWT_SECRET", ""),
-		Port:           getEnv("PORT", "8080"),
-		LogLevel:       getEnv("LOG_LEVEL", "info"),
-		Environment:    getEnv("ENVIRONMENT", "development"),
-		Timeout:        getEnvAsDuration("TIMEOUT_SECONDS", 30*time.Second),
-		RateLimitRPS:   getEnvAsInt("RATE_LIMIT_RPS", 10),
-		RateLimitBurst: getEnvAsInt("RATE_LIMIT_BURST", 20),
-		JWTExpiry:      getEnvAsDuration("JWT_EXPIRY_HOURS", 24*time.Hour),
-		RedisURL:       getEnv("REDIS_URL", "redis://localhost:6379"),
-		NatsURL:        getEnv("NATS_URL", "nats://localhost:4222"),
-		CacheTTL:       getEnvAsDuration("CACHE_TTL_MINUTES", 5*time.Minute),
+		DBURL:          env.String("DB_URL", ""),
+		DBReplicaURL:   env.String("DB_REPLICA_URL", ""),
+		JWTSecret:      env.String("JWT_SECRET", ""),
+		Port:           env.String("PORT", "8080"),
+		LogLevel:       env.String("LOG_LEVEL", "info"),
+		Environment:    env.String("ENVIRONMENT", "development"),
+		Timeout:        env.Duration("TIMEOUT_SECONDS", 30*time.Second),
+		RateLimitRPS:   env.Int("RATE_LIMIT_RPS", 10),
+		RateLimitBurst: env.Int("RATE_LIMIT_BURST", 20),
+		JWTExpiry:      env.Duration("JWT_EXPIRY_HOURS", 24*time.Hour),
+		JWTIssuer:      env.String("JWT_ISSUER", "user-auth-app"),
+		JWTAudience:    env.String("JWT_AUDIENCE", "user-auth-app"),
+		RedisURL:       env.String("REDIS_URL", "redis://localhost:6379"),
+		NatsURL:        env.String("NATS_URL", "nats://localhost:4222"),
+		CacheTTL:       env.Duration("CACHE_TTL_MINUTES", 5*time.Minute),
+
+		CacheMaxEntries: env.Int("CACHE_MAX_ENTRIES", 10000),
+
+		NegativeCacheTTL: env.Duration("NEGATIVE_CACHE_TTL_SECONDS", 30*time.Second),
+
+		CacheWarmUsers:   env.Int("CACHE_WARM_USERS", 0),
+		CacheWarmUserIDs: env.IDs("CACHE_WARM_USER_IDS"),
+		CacheWarmTimeout: env.Duration("CACHE_WARM_TIMEOUT_SECONDS", 30*time.Second),
+
+		UserRateLimitRPS:   env.Int("USER_RATE_LIMIT_RPS", 10),
+		UserRateLimitBurst: env.Int("USER_RATE_LIMIT_BURST", 20),
+		RateLimitBackend:   env.String("RATE_LIMIT_BACKEND", ratelimit.BackendMemory),
+
+		SyslogEndpoint:    env.String("SYSLOG_ENDPOINT", ""),
+		AuditProfileReads: env.Bool("AUDIT_PROFILE_READS", false),
+		UsernameMinLen:    env.Int("USERNAME_MIN_LEN", domain.DefaultUsernameMinLen),
+		UsernameMaxLen:    env.Int("USERNAME_MAX_LEN", domain.DefaultUsernameMaxLen),
+
+		MaxFailedLogins: env.Int("MAX_FAILED_LOGINS", 5),
+		LockoutDuration: env.Duration("LOCKOUT_DURATION_MINUTES", 15*time.Minute),
+
+		LoginThrottleDisclosure: env.Bool("LOGIN_THROTTLE_DISCLOSURE", false),
+		LoginIPMaxFailures:      env.Int("LOGIN_IP_MAX_FAILURES", 20),
+		LoginIPFailureWindow:    env.Duration("LOGIN_IP_FAILURE_WINDOW_MINUTES", 15*time.Minute),
+
+		EnumerationSafeRegistration: env.Bool("ENUMERATION_SAFE_REGISTRATION", false),
+		VerificationResendCooldown:  env.Duration("VERIFICATION_RESEND_COOLDOWN_SECONDS", 5*time.Minute),
+		InviteOnly:                  env.Bool("INVITE_ONLY", false),
+		InviteTTL:                   env.Duration("INVITE_TTL_HOURS", 7*24*time.Hour),
+
+		BootstrapAdmin:         env.Bool("BOOTSTRAP_ADMIN", true),
+		BootstrapAdminEmail:    env.String("BOOTSTRAP_ADMIN_EMAIL", ""),
+		BootstrapAdminUsername: env.String("BOOTSTRAP_ADMIN_USERNAME", "admin"),
+		BootstrapAdminPassword: env.String("BOOTSTRAP_ADMIN_PASSWORD", ""),
+
+		LogValidationFailures:  env.Bool("LOG_VALIDATION_FAILURES", false),
+		LogRequestBodies:       env.Bool("LOG_REQUEST_BODIES", false),
+		IdempotencyTTL:         env.Duration("IDEMPOTENCY_TTL_HOURS", 24*time.Hour),
+		TenantBaseDomain:       strings.ToLower(env.String("TENANT_BASE_DOMAIN", "")),
+		MaxRequestBodyBytes:    env.Int("MAX_REQUEST_BODY_BYTES", 1<<20),
+		RequireJSONContentType: env.Bool("REQUIRE_JSON_CONTENT_TYPE", true),
+		MaxImportUsers:         env.Int("MAX_IMPORT_USERS", 1000),
+		CompressionLevel:       env.Int("COMPRESSION_LEVEL", 5),
+		CompressionMinBytes:    env.Int("COMPRESSION_MIN_BYTES", 1024),
+
+		DBMaxConns:        env.Int("DB_MAX_CONNS", 10),
+		DBMinConns:        env.Int("DB_MIN_CONNS", 0),
+		DBMaxConnLifetime: env.Duration("DB_MAX_CONN_LIFETIME_MINUTES", time.Hour),
+		DBMaxConnIdleTime: env.Duration("DB_MAX_CONN_IDLE_TIME_MINUTES", 30*time.Minute),
+
+		DBRetryMaxAttempts: env.Int("DB_RETRY_MAX_ATTEMPTS", 3),
+		DBQueryExecMode:    env.String("DB_QUERY_EXEC_MODE", ""),
+		AutoMigrate:        env.Bool("AUTO_MIGRATE", false),
+
+		HTTPReadHeaderTimeout: env.Duration("HTTP_READ_HEADER_TIMEOUT_SECONDS", 5*time.Second),
+		HTTPReadTimeout:       env.Duration("HTTP_READ_TIMEOUT_SECONDS", 15*time.Second),
+		HTTPWriteTimeout:      env.Duration("HTTP_WRITE_TIMEOUT_SECONDS", 35*time.Second),
+		HTTPIdleTimeout:       env.Duration("HTTP_IDLE_TIMEOUT_SECONDS", 60*time.Second),
+
+		AdminAddr:   env.String("ADMIN_ADDR", ""),
+		EnablePprof: env.Bool("ENABLE_PPROF", false),
+		SwaggerUI:   env.Bool("SWAGGER_UI", false),
+
+		MetricsToken:    env.String("METRICS_TOKEN", ""),
+		MetricsUsername: env.String("METRICS_USERNAME", ""),
+		MetricsPassword: env.String("METRICS_PASSWORD", ""),
+
+		TokenBindingMode: env.String("TOKEN_BINDING_MODE", "none"),
+
+		SessionLifetime:      env.Duration("SESSION_LIFETIME_HOURS", 24*time.Hour),
+		SessionRememberMe:    env.Duration("SESSION_REMEMBER_ME_DAYS", 30*24*time.Hour),
+		SessionPurgeInterval: env.Duration("SESSION_PURGE_INTERVAL_MINUTES", time.Hour),
+
+		AuthMode:           strings.ToLower(env.String("AUTH_MODE", "header")),
+		AuthCookieName:     env.String("AUTH_COOKIE_NAME", "access_token"),
+		CSRFCookieName:     env.String("CSRF_COOKIE_NAME", "csrf_token"),
+		AuthCookieSecure:   env.Bool("AUTH_COOKIE_SECURE", true),
+		AuthCookieSameSite: strings.ToLower(env.String("AUTH_COOKIE_SAMESITE", "lax")),
+
+		GoogleClientID:     env.String("GOOGLE_CLIENT_ID", ""),
+		GoogleClientSecret: env.String("GOOGLE_CLIENT_SECRET", ""),
+		GoogleRedirectURL:  env.String("GOOGLE_REDIRECT_URL", ""),
+
+		JWTSigningMethod:  strings.ToUpper(env.String("JWT_SIGNING_METHOD", "HS256")),
+		JWTSecretFile:     env.String("JWT_SECRET_FILE", ""),
+		JWTPrivateKeyFile: env.String("JWT_PRIVATE_KEY_FILE", ""),
+
+		PasswordHashAlgorithm: env.String("PASSWORD_HASH_ALGORITHM", hashing.AlgorithmBcrypt),
+		BcryptCost:            env.Int("BCRYPT_COST", bcrypt.DefaultCost),
+		Argon2Memory:          env.Int("ARGON2_MEMORY_KB", int(hashing.DefaultArgon2Params.Memory)),
+		Argon2Iterations:      env.Int("ARGON2_ITERATIONS", int(hashing.DefaultArgon2Params.Iterations)),
+		Argon2Parallelism:     env.Int("ARGON2_PARALLELISM", int(hashing.DefaultArgon2Params.Parallelism)),
+
+		PasswordMinLength: env.Int("PASSWORD_MIN_LENGTH", domain.DefaultPasswordMinLen),
+		PasswordMaxLength: env.Int("PASSWORD_MAX_LENGTH", domain.DefaultPasswordMaxLen),
+
+		PasswordRequireUpper:   env.Bool("PASSWORD_REQUIRE_UPPER", true),
+		PasswordRequireLower:   env.Bool("PASSWORD_REQUIRE_LOWER", true),
+		PasswordRequireDigit:   env.Bool("PASSWORD_REQUIRE_DIGIT", true),
+		PasswordRequireSpecial: env.Bool("PASSWORD_REQUIRE_SPECIAL", true),
+		PasswordRejectCommon:   env.Bool("PASSWORD_REJECT_COMMON", true),
+		PasswordBreachCheck:    env.Bool("PASSWORD_BREACH_CHECK", false),
+		HIBPURL:                env.String("HIBP_URL", "https://api.pwnedpasswords.com"),
+		PasswordHistory:        env.Int("PASSWORD_HISTORY", 0),
+		PasswordMaxAge:         env.Duration("PASSWORD_MAX_AGE_DAYS", 0),
+
+		NatsSubscriberBuffer:     env.Int("NATS_SUBSCRIBER_BUFFER", 256),
+		NatsReconnectBufferBytes: env.Int("NATS_RECONNECT_BUFFER_BYTES", 8<<20),
+
+		BreakerFailureThreshold: env.Int("CIRCUIT_BREAKER_FAILURE_THRESHOLD", 5),
+		BreakerOpenTimeout:      env.Duration("CIRCUIT_BREAKER_OPEN_SECONDS", 30*time.Second),
+
+		WebhookTimeout:     env.Duration("WEBHOOK_TIMEOUT_SECONDS", 10*time.Second),
+		WebhookMaxAttempts: env.Int("WEBHOOK_MAX_ATTEMPTS", 5),
+
+		WebhookDeliveryRetention: env.Duration("WEBHOOK_DELIVERY_RETENTION_DAYS", 30*24*time.Hour),
 	}
 
 	// Ensure port has colon prefix
 	if !strings.HasPrefix(cfg.Port, ":") {
 		cfg.Port = ":" + cfg.Port
 	}
+	if cfg.AdminAddr != "" && !strings.Contains(cfg.AdminAddr, ":") {
+		cfg.AdminAddr = ":" + cfg.AdminAddr
+	}
 
 	// Parse allowed origins
-	originsStr := getEnv("ALLOWED_ORIGINS", "*")
-	cfg.AllowedOrigins = parseAllowedOrigins(originsStr)
+	originsStr := env.String("ALLOWED_ORIGINS", "*")
+	cfg.AllowedOrigins = parseList(originsStr)
+
+	cfg.TrustedProxies = env.Networks("TRUSTED_PROXIES")
+	cfg.AdminAllowedNetworks = env.Networks("ADMIN_ALLOWED_CIDRS")
+	cfg.AdminDeniedNetworks = env.Networks("ADMIN_DENIED_CIDRS")
+	cfg.WebhookAllowedNetworks = env.Networks("WEBHOOK_ALLOWED_CIDRS")
 
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
-		return nil, err
+	cfg.CSRFProtection = env.Bool("CSRF_PROTECTION", cfg.AuthMode != "header")
+
+	// Parse audit sinks
+	cfg.AuditSinks = parseList(env.String("AUDIT_SINKS", "log,postgres"))
+	cfg.JWTPublicKeyFiles = parseList(env.String("JWT_PUBLIC_KEY_FILES", ""))
+	cfg.RolePermissions = env.RolePermissions("ROLE_PERMISSIONS", domain.DefaultRolePermissions())
+	cfg.AllowedRoles = parseList(env.String("ALLOWED_ROLES", strings.Join(domain.DefaultRoles, ",")))
+	cfg.ReservedUsernames = parseList(env.String("RESERVED_USERNAMES", strings.Join(domain.DefaultReservedUsernames, ",")))
+
+	// Email settings; the mailer validates them itself, since it's optional
+	cfg.Email = email.Config{
+		Provider:    env.String("EMAIL_PROVIDER", "ses"),
+		FromAddress: env.String("EMAIL_FROM_ADDRESS", "noreply@yourdomain.com"),
+		FromName:    env.String("EMAIL_FROM_NAME", "Your App"),
+
+		AWSRegion:          env.String("AWS_REGION", "us-east-1"),
+		AWSAccessKeyID:     env.String("AWS_ACCESS_KEY_ID", ""),
+		AWSSecretAccessKey: env.String("AWS_SECRET_ACCESS_KEY", ""),
+
+		SMTPHost:     env.String("SMTP_HOST", "localhost"),
+		SMTPPort:     env.Int("SMTP_PORT", 1025),
+		SMTPUsername: env.String("SMTP_USERNAME", ""),
+		SMTPPassword: env.String("SMTP_PASSWORD", ""),
+		SMTPUseTLS:   env.Bool("SMTP_USE_TLS", false),
+	}
+
+	// Validate configuration, reporting parse errors alongside validation errors
+	problems := append(env.errors, cfg.problems()...)
+	if len(problems) > 0 {
+		return nil, validationError(problems)
 	}
 
 	return cfg, nil
@@ -73,26 +496,124 @@ func Load() (*Config, error) {
 
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return validationError(problems)
+	}
+	return nil
+}
+
+// problems returns every configuration problem found
+func (c *Config) problems() []string {
 	var errors []string
 
 	if c.DBURL == "" {
 		errors = append(errors, "DB_URL is required")
+	} else if err := validateURL(c.DBURL, "postgres", "postgresql"); err != nil {
+		errors = append(errors, "DB_URL "+err.Error())
+	}
+
+	if c.DBMaxConns < 1 {
+		errors = append(errors, "DB_MAX_CONNS must be at least 1")
+	}
+
+	if c.DBMinConns < 0 || c.DBMinConns > c.DBMaxConns {
+		errors = append(errors, "DB_MIN_CONNS must be between 0 and DB_MAX_CONNS")
+	}
+
+	if c.DBMaxConnLifetime <= 0 {
+		errors = append(errors, "DB_MAX_CONN_LIFETIME_MINUTES must be positive")
+	}
+
+	if c.DBMaxConnIdleTime <= 0 {
+		errors = append(errors, "DB_MAX_CONN_IDLE_TIME_MINUTES must be positive")
+	}
+
+	if _, ok := repository.QueryExecModes[c.DBQueryExecMode]; c.DBQueryExecMode != "" && !ok {
+		errors = append(errors, "DB_QUERY_EXEC_MODE must be one of: cache_statement, cache_describe, describe_exec, exec, simple_protocol")
+	}
+
+	if c.DBRetryMaxAttempts < 1 || c.DBRetryMaxAttempts > 10 {
+		errors = append(errors, "DB_RETRY_MAX_ATTEMPTS must be between 1 and 10")
+	}
+
+	switch c.JWTSigningMethod {
+	case "HS256":
+		// A JWT_SECRET_FILE is checked when the signing keys are loaded
+		if c.JWTSecretFile == "" {
+			if c.JWTSecret == "" {
+				errors = append(errors, "JWT_SECRET or JWT_SECRET_FILE is required")
+			} else if len(c.JWTSecret) < 32 {
+				errors = append(errors, "JWT_SECRET must be at least 32 bytes long")
+			}
+		}
+	case "RS256":
+		if c.JWTPrivateKeyFile == "" {
+			errors = append(errors, "JWT_PRIVATE_KEY_FILE is required when JWT_SIGNING_METHOD is RS256")
+		}
+	default:
+		errors = append(errors, "JWT_SIGNING_METHOD must be one of: HS256, RS256")
 	}
 
-	if c.JWTSecret == "" {
-		errors = append(errors, "JWT_SECRET is required")
-	} else if len(c.JWTSecret) < 32 {
-		errors = append(errors, "JWT_SECRET must be at least 32 characters long")
+	if err := validatePort(c.Port); err != nil {
+		errors = append(errors, "PORT "+err.Error())
+	}
+
+	if c.AdminAddr != "" {
+		if err := validatePort(c.AdminAddr); err != nil {
+			errors = append(errors, "ADMIN_ADDR "+err.Error())
+		} else if c.AdminAddr == c.Port {
+			errors = append(errors, "ADMIN_ADDR must differ from PORT")
+		}
+	}
+
+	if c.MaxRequestBodyBytes < 1024 {
+		errors = append(errors, "MAX_REQUEST_BODY_BYTES must be at least 1024")
+	}
+
+	if c.CompressionLevel < gzip.BestSpeed || c.CompressionLevel > gzip.BestCompression {
+		errors = append(errors, fmt.Sprintf("COMPRESSION_LEVEL must be between %d and %d", gzip.BestSpeed, gzip.BestCompression))
+	}
+
+	if c.CompressionMinBytes < 0 {
+		errors = append(errors, "COMPRESSION_MIN_BYTES must not be negative")
 	}
 
 	if c.Timeout < time.Second {
 		errors = append(errors, "TIMEOUT_SECONDS must be at least 1 second")
 	}
 
+	if c.HTTPReadHeaderTimeout <= 0 || c.HTTPReadTimeout <= 0 || c.HTTPIdleTimeout <= 0 {
+		errors = append(errors, "HTTP_READ_HEADER_TIMEOUT_SECONDS, HTTP_READ_TIMEOUT_SECONDS and HTTP_IDLE_TIMEOUT_SECONDS must be positive")
+	} else if c.HTTPReadHeaderTimeout > c.HTTPReadTimeout {
+		errors = append(errors, "HTTP_READ_HEADER_TIMEOUT_SECONDS must not exceed HTTP_READ_TIMEOUT_SECONDS")
+	}
+
+	// A shorter write timeout would drop the 504 a timed-out handler sends
+	if c.HTTPWriteTimeout <= c.Timeout {
+		errors = append(errors, "HTTP_WRITE_TIMEOUT_SECONDS must be longer than TIMEOUT_SECONDS")
+	}
+
 	if c.JWTExpiry < time.Minute {
 		errors = append(errors, "JWT_EXPIRY_HOURS must be at least 1 minute")
 	}
 
+	if c.SessionLifetime < time.Minute {
+		errors = append(errors, "SESSION_LIFETIME_HOURS must be at least 1 minute")
+	}
+	if c.SessionRememberMe < c.SessionLifetime {
+		errors = append(errors, "SESSION_REMEMBER_ME_DAYS must not be shorter than SESSION_LIFETIME_HOURS")
+	}
+	if c.SessionPurgeInterval < 0 {
+		errors = append(errors, "SESSION_PURGE_INTERVAL_MINUTES must not be negative")
+	}
+
+	if c.JWTIssuer == "" {
+		errors = append(errors, "JWT_ISSUER must not be empty")
+	}
+	if c.JWTAudience == "" {
+		errors = append(errors, "JWT_AUDIENCE must not be empty")
+	}
+
 	if c.RateLimitRPS < 1 {
 		errors = append(errors, "RATE_LIMIT_RPS must be at least 1")
 	}
@@ -101,18 +622,306 @@ func (c *Config) Validate() error {
 		errors = append(errors, "RATE_LIMIT_BURST must be >= RATE_LIMIT_RPS")
 	}
 
+	if c.UserRateLimitRPS < 1 {
+		errors = append(errors, "USER_RATE_LIMIT_RPS must be at least 1")
+	}
+
+	if c.UserRateLimitBurst < c.UserRateLimitRPS {
+		errors = append(errors, "USER_RATE_LIMIT_BURST must be >= USER_RATE_LIMIT_RPS")
+	}
+
+	if c.RateLimitBackend != ratelimit.BackendMemory && c.RateLimitBackend != ratelimit.BackendRedis {
+		errors = append(errors, "RATE_LIMIT_BACKEND must be one of: memory, redis")
+	}
+
+	if c.MaxFailedLogins < 0 {
+		errors = append(errors, "MAX_FAILED_LOGINS must not be negative")
+	}
+
+	if c.LockoutDuration <= 0 {
+		errors = append(errors, "LOCKOUT_DURATION_MINUTES must be positive")
+	}
+
+	if c.LoginIPMaxFailures < 0 {
+		errors = append(errors, "LOGIN_IP_MAX_FAILURES must not be negative")
+	}
+
+	if c.LoginIPMaxFailures > 0 && c.LoginIPFailureWindow <= 0 {
+		errors = append(errors, "LOGIN_IP_FAILURE_WINDOW_MINUTES must be positive")
+	}
+
+	if c.BootstrapAdminEmail != "" && !validator.IsEmail(validator.NormalizeEmail(c.BootstrapAdminEmail)) {
+		errors = append(errors, "BOOTSTRAP_ADMIN_EMAIL must be a valid email address")
+	}
+
+	if c.BootstrapAdminPassword != "" && len(c.BootstrapAdminPassword) < c.PasswordMinLength {
+		errors = append(errors, "BOOTSTRAP_ADMIN_PASSWORD must be at least PASSWORD_MIN_LENGTH characters")
+	}
+
+	// Attempt counts are only reported for existing accounts, which would
+	// reopen the leak enumeration-safe registration closes
+	if c.LoginThrottleDisclosure && c.EnumerationSafeRegistration {
+		errors = append(errors, "LOGIN_THROTTLE_DISCLOSURE reveals which accounts exist and can't be combined with ENUMERATION_SAFE_REGISTRATION")
+	}
+
+	if c.UsernameMinLen < 1 {
+		errors = append(errors, "USERNAME_MIN_LEN must be at least 1")
+	}
+
+	if c.UsernameMaxLen < c.UsernameMinLen {
+		errors = append(errors, "USERNAME_MAX_LEN must be >= USERNAME_MIN_LEN")
+	} else if c.UsernameMaxLen > domain.UsernameMaxLenLimit {
+		errors = append(errors, fmt.Sprintf("USERNAME_MAX_LEN must be at most %d (the database limit)", domain.UsernameMaxLenLimit))
+	}
+
+	if c.PasswordMinLength < 1 {
+		errors = append(errors, "PASSWORD_MIN_LENGTH must be at least 1")
+	}
+
+	if c.PasswordMaxLength < c.PasswordMinLength {
+		errors = append(errors, "PASSWORD_MAX_LENGTH must be >= PASSWORD_MIN_LENGTH")
+	} else if c.PasswordMaxLength > domain.PasswordMaxLenLimit {
+		errors = append(errors, fmt.Sprintf("PASSWORD_MAX_LENGTH must be at most %d (the bcrypt limit)", domain.PasswordMaxLenLimit))
+	}
+
+	if c.PasswordHistory < 0 {
+		errors = append(errors, "PASSWORD_HISTORY must not be negative")
+	}
+
+	if c.VerificationResendCooldown < time.Second {
+		errors = append(errors, "VERIFICATION_RESEND_COOLDOWN_SECONDS must be at least 1")
+	}
+
+	if c.InviteTTL <= 0 {
+		errors = append(errors, "INVITE_TTL_HOURS must be positive")
+	}
+
+	if c.PasswordMaxAge < 0 {
+		errors = append(errors, "PASSWORD_MAX_AGE_DAYS must not be negative")
+	}
+
+	if c.PasswordBreachCheck {
+		if err := validateURL(c.HIBPURL, "http", "https"); err != nil {
+			errors = append(errors, "HIBP_URL "+err.Error())
+		}
+	}
+
+	if c.CacheTTL <= 0 {
+		errors = append(errors, "CACHE_TTL_MINUTES must be positive")
+	}
+
+	if c.CacheMaxEntries < 1 {
+		errors = append(errors, "CACHE_MAX_ENTRIES must be at least 1")
+	}
+
+	if c.CacheWarmUsers < 0 || c.CacheWarmUsers > MaxCacheWarmUsers {
+		errors = append(errors, fmt.Sprintf("CACHE_WARM_USERS must be between 0 and %d", MaxCacheWarmUsers))
+	}
+
+	if len(c.CacheWarmUserIDs) > MaxCacheWarmUsers {
+		errors = append(errors, fmt.Sprintf("CACHE_WARM_USER_IDS may list at most %d users", MaxCacheWarmUsers))
+	}
+
+	if c.CacheWarmTimeout <= 0 {
+		errors = append(errors, "CACHE_WARM_TIMEOUT_SECONDS must be positive")
+	}
+
+	if c.WebhookTimeout <= 0 {
+		errors = append(errors, "WEBHOOK_TIMEOUT_SECONDS must be positive")
+	}
+
+	if c.WebhookMaxAttempts < 1 {
+		errors = append(errors, "WEBHOOK_MAX_ATTEMPTS must be at least 1")
+	}
+
+	if c.WebhookDeliveryRetention < 0 {
+		errors = append(errors, "WEBHOOK_DELIVERY_RETENTION_DAYS must not be negative")
+	}
+
+	if c.NegativeCacheTTL < 0 {
+		errors = append(errors, "NEGATIVE_CACHE_TTL_SECONDS must not be negative")
+	}
+
+	if c.IdempotencyTTL <= 0 {
+		errors = append(errors, "IDEMPOTENCY_TTL_HOURS must be positive")
+	}
+
+	if c.MaxImportUsers < 1 || c.MaxImportUsers > 10000 {
+		errors = append(errors, "MAX_IMPORT_USERS must be between 1 and 10000")
+	}
+
+	if c.BreakerFailureThreshold < 1 {
+		errors = append(errors, "CIRCUIT_BREAKER_FAILURE_THRESHOLD must be at least 1")
+	}
+
+	if c.BreakerOpenTimeout < time.Second {
+		errors = append(errors, "CIRCUIT_BREAKER_OPEN_SECONDS must be at least 1 second")
+	}
+
+	if c.RedisURL == "" {
+		errors = append(errors, "REDIS_URL must not be empty")
+	} else if err := validateURL(c.RedisURL, "redis", "rediss", "unix"); err != nil {
+		errors = append(errors, "REDIS_URL "+err.Error())
+	}
+
+	if c.NatsURL == "" {
+		errors = append(errors, "NATS_URL must not be empty")
+	} else {
+		for _, natsURL := range strings.Split(c.NatsURL, ",") {
+			if err := validateURL(strings.TrimSpace(natsURL), "nats", "tls", "ws", "wss"); err != nil {
+				errors = append(errors, "NATS_URL "+err.Error())
+			}
+		}
+	}
+
+	if c.NatsSubscriberBuffer < 1 {
+		errors = append(errors, "NATS_SUBSCRIBER_BUFFER must be at least 1")
+	}
+
+	if c.NatsReconnectBufferBytes < 1 {
+		errors = append(errors, "NATS_RECONNECT_BUFFER_BYTES must be at least 1")
+	}
+
+	validSinks := map[string]bool{"log": true, "postgres": true, "nats": true, "syslog": true}
+	for _, sink := range c.AuditSinks {
+		if !validSinks[sink] {
+			errors = append(errors, fmt.Sprintf("AUDIT_SINKS contains unknown sink %q (must be log, postgres, nats or syslog)", sink))
+		}
+		if sink == "syslog" && c.SyslogEndpoint == "" {
+			errors = append(errors, "SYSLOG_ENDPOINT is required when AUDIT_SINKS includes syslog")
+		}
+	}
+
+	if c.SyslogEndpoint != "" {
+		if err := validateURL(c.SyslogEndpoint, "udp", "tcp"); err != nil {
+			errors = append(errors, "SYSLOG_ENDPOINT "+err.Error())
+		}
+	}
+
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		errors = append(errors, fmt.Sprintf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+
+	if c.PasswordHashAlgorithm != hashing.AlgorithmBcrypt && c.PasswordHashAlgorithm != hashing.AlgorithmArgon2id {
+		errors = append(errors, "PASSWORD_HASH_ALGORITHM must be one of: bcrypt, argon2id")
+	}
+
+	if c.Argon2Iterations < 1 {
+		errors = append(errors, "ARGON2_ITERATIONS must be at least 1")
+	}
+
+	if c.Argon2Parallelism < 1 || c.Argon2Parallelism > 255 {
+		errors = append(errors, "ARGON2_PARALLELISM must be between 1 and 255")
+	}
+
+	// Argon2 requires at least 8 KiB per lane
+	if c.Argon2Memory < 8*c.Argon2Parallelism {
+		errors = append(errors, "ARGON2_MEMORY_KB must be at least 8 times ARGON2_PARALLELISM")
+	}
+
+	validBindings := map[string]bool{"none": true, "ip": true, "user_agent": true}
+	if !validBindings[c.TokenBindingMode] {
+		errors = append(errors, "TOKEN_BINDING_MODE must be one of: none, ip, user_agent")
+	}
+
+	for role, permissions := range c.RolePermissions {
+		for _, permission := range permissions {
+			if !slices.Contains(domain.Permissions, permission) {
+				errors = append(errors, fmt.Sprintf("ROLE_PERMISSIONS grants %s the unknown permission %q (must be one of: %s)",
+					role, permission, strings.Join(domain.Permissions, ", ")))
+			}
+		}
+	}
+
+	// Users created without a role get the default one
+	if !slices.Contains(c.AllowedRoles, domain.RoleUser) {
+		errors = append(errors, fmt.Sprintf("ALLOWED_ROLES must include %s", domain.RoleUser))
+	}
+
+	validAuthModes := map[string]bool{"header": true, "cookie": true, "both": true}
+	if !validAuthModes[c.AuthMode] {
+		errors = append(errors, "AUTH_MODE must be one of: header, cookie, both")
+	}
+
+	validSameSite := map[string]bool{"lax": true, "strict": true, "none": true}
+	if !validSameSite[c.AuthCookieSameSite] {
+		errors = append(errors, "AUTH_COOKIE_SAMESITE must be one of: lax, strict, none")
+	} else if c.AuthCookieSameSite == "none" && !c.AuthCookieSecure {
+		errors = append(errors, "AUTH_COOKIE_SAMESITE=none requires AUTH_COOKIE_SECURE=true")
+	}
+
+	if c.AuthCookieName == "" || c.CSRFCookieName == "" || c.AuthCookieName == c.CSRFCookieName {
+		errors = append(errors, "AUTH_COOKIE_NAME and CSRF_COOKIE_NAME must be set and differ")
+	}
+
+	if (c.MetricsUsername == "") != (c.MetricsPassword == "") {
+		errors = append(errors, "METRICS_USERNAME and METRICS_PASSWORD must be set together")
+	}
+
+	if c.IsProduction() && c.EnablePprof && c.AdminAddr == "" {
+		errors = append(errors, "ENABLE_PPROF requires ADMIN_ADDR in production")
+	}
+
+	if c.IsProduction() && !c.MetricsAuthEnabled() && c.AdminAddr == "" {
+		errors = append(errors, "METRICS_TOKEN or METRICS_USERNAME/METRICS_PASSWORD is required in production unless ADMIN_ADDR is set")
+	}
+
+	if c.GoogleLoginEnabled() && (c.GoogleClientSecret == "" || c.GoogleRedirectURL == "") {
+		errors = append(errors, "GOOGLE_CLIENT_SECRET and GOOGLE_REDIRECT_URL are required when GOOGLE_CLIENT_ID is set")
+	}
+
 	validEnvs := map[string]bool{"development": true, "staging": true, "production": true}
 	if !validEnvs[c.Environment] {
 		errors = append(errors, "ENVIRONMENT must be one of: development, staging, production")
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("configuration validation failed:\n  - %s", strings.Join(errors, "\n  - "))
+	if strings.ContainsAny(c.TenantBaseDomain, ":/") || strings.HasPrefix(c.TenantBaseDomain, ".") {
+		errors = append(errors, "TENANT_BASE_DOMAIN must be a bare domain such as auth.example.com")
+	}
+
+	if c.LogRequestBodies && !c.IsDevelopment() {
+		errors = append(errors, "LOG_REQUEST_BODIES is only allowed when ENVIRONMENT=development")
+	}
+
+	return errors
+}
+
+// validationError formats configuration problems as a single error
+func validationError(problems []string) error {
+	return fmt.Errorf("configuration validation failed:\n  - %s", strings.Join(problems, "\n  - "))
+}
+
+// validatePort checks that the listen address is of the form [host]:port
+func validatePort(addr string) error {
+	_, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("is not a valid listen address: %q", addr)
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("must be a number between 1 and 65535, got %q", portStr)
 	}
 
 	return nil
 }
 
+// validateURL checks that rawURL parses and uses one of the given schemes
+func validateURL(rawURL string, schemes ...string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("is not a valid URL: %v", err)
+	}
+
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("must use one of the schemes %s, got %q", strings.Join(schemes, ", "), u.Scheme)
+}
+
 // Logger creates a configured logger instance
 func (c *Config) Logger() *zerolog.Logger {
 	output := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
@@ -138,33 +947,82 @@ func (c *Config) IsProduction() bool {
 	return c.Environment == "production"
 }
 
-// getEnv gets an environment variable or returns default
-func getEnv(key, defaultValue string) string {
+// MetricsAuthEnabled returns true if /metrics requires credentials
+func (c *Config) MetricsAuthEnabled() bool {
+	return c.MetricsToken != "" || c.MetricsUsername != ""
+}
+
+// GoogleLoginEnabled returns true if sign in with Google is configured
+func (c *Config) GoogleLoginEnabled() bool {
+	return c.GoogleClientID != ""
+}
+
+// envReader reads typed values from the environment, falling back to
+// values from the config file, and collects parse errors instead of
+// silently falling back to defaults
+type envReader struct {
+	file   map[string]string
+	errors []string
+}
+
+// lookup returns the environment value for key, then the file value
+func (e *envReader) lookup(key string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
-	return defaultValue
+	return e.file[key]
 }
 
-// getEnvAsInt gets an environment variable as int or returns default
-func getEnvAsInt(key string, defaultValue int) int {
-	valueStr := os.Getenv(key)
-	if value, err := strconv.Atoi(valueStr); err == nil {
+// String gets an environment variable or returns default
+func (e *envReader) String(key, defaultValue string) string {
+	if value := e.lookup(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
 
-// getEnvAsDuration gets an environment variable as duration or returns default
-func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
-	valueStr := os.Getenv(key)
+// Int gets an environment variable as int or returns default
+func (e *envReader) Int(key string, defaultValue int) int {
+	valueStr := e.lookup(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		e.errors = append(e.errors, fmt.Sprintf("%s must be an integer, got %q", key, valueStr))
+		return defaultValue
+	}
+	return value
+}
+
+// Bool gets an environment variable as bool or returns default
+func (e *envReader) Bool(key string, defaultValue bool) bool {
+	valueStr := e.lookup(key)
 	if valueStr == "" {
 		return defaultValue
 	}
 
-	// Try parsing as integer (seconds/hours depending on key)
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		e.errors = append(e.errors, fmt.Sprintf("%s must be a boolean, got %q", key, valueStr))
+		return defaultValue
+	}
+	return value
+}
+
+// Duration gets an environment variable as duration or returns default
+func (e *envReader) Duration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := e.lookup(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	// Try parsing as integer (seconds/hours/days depending on key)
 	if value, err := strconv.Atoi(valueStr); err == nil {
-		if strings.Contains(key, "HOURS") {
+		if strings.Contains(key, "DAYS") {
+			return time.Duration(value) * 24 * time.Hour
+		} else if strings.Contains(key, "HOURS") {
 			return time.Duration(value) * time.Hour
 		} else if strings.Contains(key, "MINUTES") {
 			return time.Duration(value) * time.Minute
@@ -177,15 +1035,73 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 		return duration
 	}
 
+	e.errors = append(e.errors, fmt.Sprintf("%s must be an integer or a duration (e.g. 30s), got %q", key, valueStr))
 	return defaultValue
 }
 
-// parseAllowedOrigins parses comma-separated origins
-func parseAllowedOrigins(originsStr string) []string {
-	origins := strings.Split(originsStr, ",")
-	result := make([]string, 0, len(origins))
-	for _, origin := range origins {
-		if trimmed := strings.TrimSpace(origin); trimmed != "" {
+// RolePermissions parses role=permission,permission entries separated by
+// semicolons, e.g. "admin=users:list,users:write;support=users:list".
+// Listed roles replace their default permissions; "role=" grants none.
+func (e *envReader) RolePermissions(key string, defaults domain.RolePermissions) domain.RolePermissions {
+	valueStr := e.lookup(key)
+	if valueStr == "" {
+		return defaults
+	}
+
+	for _, entry := range strings.Split(valueStr, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		role, permissions, ok := strings.Cut(entry, "=")
+		role = strings.TrimSpace(role)
+		if !ok || role == "" {
+			e.errors = append(e.errors, fmt.Sprintf("%s entries must look like role=permission,permission, got %q", key, entry))
+			continue
+		}
+		defaults[role] = parseList(permissions)
+	}
+	return defaults
+}
+
+// Networks parses a comma-separated list of CIDR networks. A bare IP
+// address stands for itself alone.
+func (e *envReader) Networks(key string) []netip.Prefix {
+	var networks []netip.Prefix
+	for _, entry := range parseList(e.lookup(key)) {
+		network, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				e.errors = append(e.errors, fmt.Sprintf("%s entries must be CIDR networks or IP addresses, got %q", key, entry))
+				continue
+			}
+			network = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		networks = append(networks, network.Masked())
+	}
+	return networks
+}
+
+// IDs parses a comma-separated list of positive IDs
+func (e *envReader) IDs(key string) []int32 {
+	var ids []int32
+	for _, entry := range parseList(e.lookup(key)) {
+		id, err := strconv.ParseInt(entry, 10, 32)
+		if err != nil || id < 1 {
+			e.errors = append(e.errors, fmt.Sprintf("%s entries must be positive integers, got %q", key, entry))
+			continue
+		}
+		ids = append(ids, int32(id))
+	}
+	return ids
+}
+
+// parseList parses a comma-separated list, dropping empty entries
+func parseList(listStr string) []string {
+	items := strings.Split(listStr, ",")
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
 			result = append(result, trimmed)
 		}
 	}
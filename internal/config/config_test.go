@@ -0,0 +1,82 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validConfig loads the defaults along with the required settings
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+	setRequiredEnv(t)
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Empty(t, cfg.problems())
+	return cfg
+}
+
+func TestProblems(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   string
+	}{
+		{"missing database", func(c *Config) { c.DBURL = "" }, "DB_URL is required"},
+		{"database URL scheme", func(c *Config) { c.DBURL = "mysql://db/app" }, "DB_URL"},
+		{"short secret", func(c *Config) { c.JWTSecret = "too-short" }, "JWT_SECRET must be at least 32 bytes long"},
+		{"RS256 without key", func(c *Config) { c.JWTSigningMethod = "RS256" }, "JWT_PRIVATE_KEY_FILE is required when JWT_SIGNING_METHOD is RS256"},
+		{"port", func(c *Config) { c.Port = "http" }, "PORT"},
+		{"admin address on API port", func(c *Config) { c.AdminAddr = c.Port }, "ADMIN_ADDR must differ from PORT"},
+		{"request timeout", func(c *Config) { c.Timeout = 0 }, "TIMEOUT_SECONDS must be at least 1 second"},
+		{"write timeout", func(c *Config) { c.HTTPWriteTimeout = c.Timeout }, "HTTP_WRITE_TIMEOUT_SECONDS must be longer than TIMEOUT_SECONDS"},
+		{"rate limit", func(c *Config) { c.RateLimitRPS = 0 }, "RATE_LIMIT_RPS must be at least 1"},
+		{"rate limit burst", func(c *Config) { c.RateLimitBurst = c.RateLimitRPS - 1 }, "RATE_LIMIT_BURST must be >= RATE_LIMIT_RPS"},
+		{"rate limit backend", func(c *Config) { c.RateLimitBackend = "memcached" }, "RATE_LIMIT_BACKEND must be one of: memory, redis"},
+		{"empty redis URL", func(c *Config) { c.RedisURL = "" }, "REDIS_URL must not be empty"},
+		{"redis URL scheme", func(c *Config) { c.RedisURL = "http://cache:6379" }, "REDIS_URL"},
+		{"one bad NATS URL", func(c *Config) { c.NatsURL = "nats://a:4222, http://b:4222" }, "NATS_URL"},
+		{"session lifetime", func(c *Config) { c.SessionLifetime = time.Second }, "SESSION_LIFETIME_HOURS must be at least 1 minute"},
+		{"remember-me shorter than session", func(c *Config) { c.SessionRememberMe = c.SessionLifetime / 2 }, "SESSION_REMEMBER_ME_DAYS must not be shorter than SESSION_LIFETIME_HOURS"},
+		{"session purge interval", func(c *Config) { c.SessionPurgeInterval = -time.Minute }, "SESSION_PURGE_INTERVAL_MINUTES must not be negative"},
+		{"webhook retention", func(c *Config) { c.WebhookDeliveryRetention = -time.Hour }, "WEBHOOK_DELIVERY_RETENTION_DAYS must not be negative"},
+		{"webhook attempts", func(c *Config) { c.WebhookMaxAttempts = 0 }, "WEBHOOK_MAX_ATTEMPTS must be at least 1"},
+		{"invite TTL", func(c *Config) { c.InviteTTL = 0 }, "INVITE_TTL_HOURS must be positive"},
+		{"failed logins", func(c *Config) { c.MaxFailedLogins = -1 }, "MAX_FAILED_LOGINS must not be negative"},
+		{"lockout", func(c *Config) { c.LockoutDuration = 0 }, "LOCKOUT_DURATION_MINUTES must be positive"},
+		{"cache warm users", func(c *Config) { c.CacheWarmUsers = MaxCacheWarmUsers + 1 }, "CACHE_WARM_USERS must be between 0 and"},
+		{"syslog without endpoint", func(c *Config) { c.AuditSinks = []string{"syslog"} }, "SYSLOG_ENDPOINT is required when AUDIT_SINKS includes syslog"},
+		{"unknown audit sink", func(c *Config) { c.AuditSinks = []string{"kafka"} }, `AUDIT_SINKS contains unknown sink "kafka"`},
+		{"token binding", func(c *Config) { c.TokenBindingMode = "cookie" }, "TOKEN_BINDING_MODE must be one of: none, ip, user_agent"},
+		{"throttle disclosure with enumeration-safe registration", func(c *Config) {
+			c.LoginThrottleDisclosure, c.EnumerationSafeRegistration = true, true
+		}, "LOGIN_THROTTLE_DISCLOSURE reveals which accounts exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(t)
+			tt.modify(cfg)
+
+			problems := cfg.problems()
+			require.NotEmpty(t, problems)
+			assert.Contains(t, problems[0], tt.want, "all problems: %v", problems)
+			assert.ErrorContains(t, cfg.Validate(), tt.want)
+		})
+	}
+}
+
+func TestLoadAggregatesProblems(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("RATE_LIMIT_RPS", "ten")
+	t.Setenv("REDIS_URL", "http://cache:6379")
+	t.Setenv("SESSION_PURGE_INTERVAL_MINUTES", "-1")
+
+	_, err := Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `RATE_LIMIT_RPS must be an integer, got "ten"`)
+	assert.Contains(t, err.Error(), "REDIS_URL")
+	assert.Contains(t, err.Error(), "SESSION_PURGE_INTERVAL_MINUTES must not be negative")
+}
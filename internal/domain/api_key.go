@@ -0,0 +1,27 @@
+// Package domain contains API key models
+package domain
+
+import "time"
+
+// APIKey is a long-lived credential for service-to-service callers. It
+// acts as its owner, limited to Scopes. The key itself is never stored.
+type APIKey struct {
+	ID     int32    `json:"id"`
+	UserID int32    `json:"user_id"`
+	Name   string   `json:"name"`
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
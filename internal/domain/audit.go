@@ -0,0 +1,38 @@
+// Package domain contains audit event models
+package domain
+
+import "time"
+
+// Audit event types
+const (
+	AuditUserRegistered     = "user.registered"
+	AuditUserCreated        = "user.created"
+	AuditUserDeactivated    = "user.deactivated"
+	AuditUsersDeactivated   = "users.deactivated"
+	AuditRoleChanged        = "user.role_changed"
+	AuditLoginSuccess       = "login.success"
+	AuditLoginFailure       = "login.failure"
+	AuditPasswordChange     = "password.change"
+	AuditLogout             = "logout"
+	AuditAccountLinked      = "account.linked"
+	AuditAPIKeyCreated      = "api_key.created"
+	AuditAPIKeyRevoked      = "api_key.revoked"
+	AuditSessionRevoked     = "session.revoked"
+	AuditWebhookCreated     = "webhook.created"
+	AuditWebhookDeleted     = "webhook.deleted"
+	AuditProfileRead        = "user.profile_read"
+	AuditInviteCreated      = "invite.created"
+	AuditDeadLetterReplayed = "dead_letter.replayed"
+)
+
+// AuditEvent represents a security-relevant action for the audit trail
+type AuditEvent struct {
+	ID        int32             `json:"id,omitempty"`
+	Type      string            `json:"type"`
+	UserID    *int32            `json:"user_id,omitempty"`
+	Success   bool              `json:"success"`
+	IPAddress string            `json:"ip_address,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
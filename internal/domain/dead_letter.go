@@ -0,0 +1,16 @@
+// Package domain contains dead letter models
+package domain
+
+import "time"
+
+// DeadLetter is a message its subscriber failed to process on every
+// attempt. It is kept until an admin replays it by republishing Data to
+// Subject. Dead letters belong to no tenant.
+type DeadLetter struct {
+	ID       int32     `json:"id,omitempty"`
+	Subject  string    `json:"subject"`
+	Data     []byte    `json:"data"`
+	Attempts int32     `json:"attempts"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
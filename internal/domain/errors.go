@@ -5,24 +5,130 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 )
 
-// Common domain errors
+// Generic error kinds. HTTPStatusCode maps each kind to a status code, and
+// the specific errors below wrap one of them, so errors.Is(err, ErrNotFound)
+// also matches ErrUserNotFound. A new error only needs to wrap the right
+// kind to get a consistent status in every handler.
 var (
-	ErrNotFound           = errors.New("resource not found")
-	ErrUnauthorized       = errors.New("unauthorized")
-	ErrForbidden          = errors.New("forbidden")
-	ErrValidation         = errors.New("validation failed")
-	ErrInternal           = errors.New("internal server error")
-	ErrDuplicateEmail     = errors.New("email already exists")
-	ErrDuplicateUsername  = errors.New("username already exists")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrInvalidToken       = errors.New("invalid token")
-	ErrExpiredToken       = errors.New("token expired")
-	ErrUserNotFound       = errors.New("user not found")
-	ErrPasswordTooWeak    = errors.New("password too weak")
+	ErrNotFound     = errors.New("resource not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrValidation   = errors.New("validation failed")
+	ErrDuplicate    = errors.New("resource already exists")
+	// ErrLocked means the account is temporarily locked, e.g. after too
+	// many failed logins
+	ErrLocked = errors.New("account locked")
+	// ErrTooManyRequests means the client must slow down before trying
+	// again
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrInternal        = errors.New("internal server error")
+	ErrTimeout         = errors.New("operation timed out")
+	ErrCanceled        = errors.New("operation canceled")
+	// ErrUnavailable means a dependency is failing and requests to it are
+	// being rejected until it recovers
+	ErrUnavailable = errors.New("service temporarily unavailable")
 )
 
+// Specific domain errors
+var (
+	ErrDuplicateEmail     = newKindError(ErrDuplicate, "email already exists")
+	ErrDuplicateUsername  = newKindError(ErrDuplicate, "username already exists")
+	ErrInvalidCredentials = newKindError(ErrUnauthorized, "invalid credentials")
+	ErrInvalidToken       = newKindError(ErrUnauthorized, "invalid token")
+	ErrExpiredToken       = newKindError(ErrUnauthorized, "token expired")
+	ErrUserNotFound       = newKindError(ErrNotFound, "user not found")
+	ErrPasswordTooWeak    = newKindError(ErrValidation, "password too weak")
+
+	// ErrLoginBlocked means logins from the client's address failed for
+	// too many accounts recently
+	ErrLoginBlocked = newKindError(ErrTooManyRequests, "too many failed logins from this address")
+
+	// ErrAccountLinkRequired means a social login matched the email of an
+	// existing account that can't be linked automatically
+	ErrAccountLinkRequired = newKindError(ErrDuplicate, "account exists with another sign-in method")
+	// ErrUnverifiedIdentity means the provider hasn't verified the email
+	ErrUnverifiedIdentity = newKindError(ErrForbidden, "provider email not verified")
+
+	// ErrNotImported marks users in an atomic import that were rolled back
+	// or never attempted because another user failed
+	ErrNotImported = errors.New("not imported because another user in the batch failed")
+
+	ErrAPIKeyNotFound = newKindError(ErrNotFound, "api key not found")
+	ErrInvalidAPIKey  = newKindError(ErrUnauthorized, "invalid api key")
+
+	ErrSessionNotFound = newKindError(ErrNotFound, "session not found")
+
+	ErrWebhookNotFound = newKindError(ErrNotFound, "webhook not found")
+
+	ErrDeadLetterNotFound = newKindError(ErrNotFound, "dead letter not found")
+
+	// Invite errors refuse registration while it is invite-only
+	ErrInviteRequired = newKindError(ErrForbidden, "invite code required")
+	ErrInviteInvalid  = newKindError(ErrForbidden, "invite code invalid")
+	ErrInviteExpired  = newKindError(ErrForbidden, "invite code expired")
+	ErrInviteUsed     = newKindError(ErrForbidden, "invite code already used")
+)
+
+// kindError is a specific error that also matches its generic kind
+type kindError struct {
+	kind    error
+	message string
+}
+
+func newKindError(kind error, message string) error {
+	return &kindError{kind: kind, message: message}
+}
+
+func (e *kindError) Error() string {
+	return e.message
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// LoginThrottleError is returned by failed logins of existing accounts. It
+// wraps ErrInvalidCredentials, or ErrLocked once the account is locked, and
+// tells how close the account is to being locked or when it unlocks. It
+// also wraps ErrLoginBlocked for logins from a blocked address, whatever
+// the account, telling when the address may try again.
+type LoginThrottleError struct {
+	Err error
+	// AttemptsRemaining is how many more failures lock the account
+	AttemptsRemaining int
+	// LockedUntil is when a locked account can log in again
+	LockedUntil time.Time
+}
+
+func (e *LoginThrottleError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoginThrottleError) Unwrap() error {
+	return e.Err
+}
+
+// errorStatuses maps error kinds to HTTP status codes. The first kind err
+// matches wins.
+var errorStatuses = []struct {
+	kind   error
+	status int
+}{
+	{ErrNotFound, http.StatusNotFound},
+	{ErrUnauthorized, http.StatusUnauthorized},
+	{ErrForbidden, http.StatusForbidden},
+	{ErrValidation, http.StatusBadRequest},
+	{ErrDuplicate, http.StatusConflict},
+	{ErrLocked, http.StatusLocked},
+	{ErrTooManyRequests, http.StatusTooManyRequests},
+	{ErrTimeout, http.StatusGatewayTimeout},
+	{ErrCanceled, http.StatusServiceUnavailable},
+	{ErrUnavailable, http.StatusServiceUnavailable},
+}
+
 // AppError represents an application-specific error with additional context
 type AppError struct {
 	Err        error
@@ -66,7 +172,9 @@ func NewValidationError(fields map[string]string) *AppError {
 	}
 }
 
-// HTTPStatusCode returns the appropriate HTTP status code for an error
+// HTTPStatusCode returns the HTTP status code for an error. It is the one
+// place statuses are decided; handlers must not pick their own for domain
+// errors.
 func HTTPStatusCode(err error) int {
 	if err == nil {
 		return http.StatusOK
@@ -77,21 +185,26 @@ func HTTPStatusCode(err error) int {
 		return appErr.StatusCode
 	}
 
-	switch {
-	case errors.Is(err, ErrNotFound), errors.Is(err, ErrUserNotFound):
-		return http.StatusNotFound
-	case errors.Is(err, ErrUnauthorized), errors.Is(err, ErrInvalidCredentials),
-		errors.Is(err, ErrInvalidToken), errors.Is(err, ErrExpiredToken):
-		return http.StatusUnauthorized
-	case errors.Is(err, ErrForbidden):
-		return http.StatusForbidden
-	case errors.Is(err, ErrValidation), errors.Is(err, ErrPasswordTooWeak):
-		return http.StatusBadRequest
-	case errors.Is(err, ErrDuplicateEmail), errors.Is(err, ErrDuplicateUsername):
-		return http.StatusConflict
-	default:
-		return http.StatusInternalServerError
+	for _, e := range errorStatuses {
+		if errors.Is(err, e.kind) {
+			return e.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// UnavailableRetryAfter is how long clients are asked to wait before
+// retrying a request that failed because a dependency was unavailable
+const UnavailableRetryAfter = 5 * time.Second
+
+// RetryAfter returns how long a client should wait before retrying a
+// request that failed with err, or zero if retrying won't help or the
+// error carries its own timing (see LoginThrottleError)
+func RetryAfter(err error) time.Duration {
+	if errors.Is(err, ErrUnavailable) {
+		return UnavailableRetryAfter
 	}
+	return 0
 }
 
 // ErrorMessage returns a user-friendly error message
@@ -105,24 +218,65 @@ func ErrorMessage(err error) string {
 		return appErr.Message
 	}
 
-	// Return safe messages for known errors
+	// Return safe messages for known errors. Specific errors come before
+	// the kinds they wrap.
 	switch {
-	case errors.Is(err, ErrNotFound), errors.Is(err, ErrUserNotFound):
-		return "Resource not found"
-	case errors.Is(err, ErrUnauthorized), errors.Is(err, ErrInvalidCredentials):
+	case errors.Is(err, ErrAPIKeyNotFound):
+		return "API key not found"
+	case errors.Is(err, ErrInvalidAPIKey):
+		return "Invalid or revoked API key"
+	case errors.Is(err, ErrSessionNotFound):
+		return "Session not found"
+	case errors.Is(err, ErrWebhookNotFound):
+		return "Webhook not found"
+	case errors.Is(err, ErrDeadLetterNotFound):
+		return "Dead letter not found"
+	case errors.Is(err, ErrInvalidCredentials):
 		return "Invalid credentials"
+	case errors.Is(err, ErrLoginBlocked):
+		return "Too many failed logins from your network, please try again later"
 	case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrExpiredToken):
 		return "Invalid or expired token"
-	case errors.Is(err, ErrForbidden):
-		return "Access denied"
-	case errors.Is(err, ErrValidation):
-		return "Validation failed"
 	case errors.Is(err, ErrDuplicateEmail):
 		return "Email already exists"
 	case errors.Is(err, ErrDuplicateUsername):
 		return "Username already exists"
+	case errors.Is(err, ErrAccountLinkRequired):
+		return "An account with this email already exists; sign in with your password"
+	case errors.Is(err, ErrUnverifiedIdentity):
+		return "Your email address is not verified with the sign-in provider"
+	case errors.Is(err, ErrInviteRequired):
+		return "Registration is by invitation only; an invite code is required"
+	case errors.Is(err, ErrInviteInvalid):
+		return "Invite code is not valid"
+	case errors.Is(err, ErrInviteExpired):
+		return "Invite code has expired"
+	case errors.Is(err, ErrInviteUsed):
+		return "Invite code has already been used"
 	case errors.Is(err, ErrPasswordTooWeak):
 		return "Password does not meet requirements"
+	case errors.Is(err, ErrNotImported):
+		return "Not imported because another user in the batch failed"
+	case errors.Is(err, ErrNotFound):
+		return "Resource not found"
+	case errors.Is(err, ErrUnauthorized):
+		return "Unauthorized"
+	case errors.Is(err, ErrForbidden):
+		return "Access denied"
+	case errors.Is(err, ErrValidation):
+		return "Validation failed"
+	case errors.Is(err, ErrDuplicate):
+		return "Resource already exists"
+	case errors.Is(err, ErrLocked):
+		return "Account is temporarily locked, please try again later"
+	case errors.Is(err, ErrTooManyRequests):
+		return "Too many requests, please try again later"
+	case errors.Is(err, ErrTimeout):
+		return "The request timed out, please try again"
+	case errors.Is(err, ErrCanceled):
+		return "The request was canceled"
+	case errors.Is(err, ErrUnavailable):
+		return "The service is temporarily unavailable, please try again later"
 	default:
 		return "An error occurred"
 	}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		err        error
+		kind       error
+		wantStatus int
+		wantMsg    string
+	}{
+		{ErrUserNotFound, ErrNotFound, http.StatusNotFound, "Resource not found"},
+		{ErrAPIKeyNotFound, ErrNotFound, http.StatusNotFound, "API key not found"},
+		{ErrSessionNotFound, ErrNotFound, http.StatusNotFound, "Session not found"},
+		{ErrDeadLetterNotFound, ErrNotFound, http.StatusNotFound, "Dead letter not found"},
+		{ErrInvalidCredentials, ErrUnauthorized, http.StatusUnauthorized, "Invalid credentials"},
+		{ErrExpiredToken, ErrUnauthorized, http.StatusUnauthorized, "Invalid or expired token"},
+		{ErrInvalidAPIKey, ErrUnauthorized, http.StatusUnauthorized, "Invalid or revoked API key"},
+		{ErrDuplicateEmail, ErrDuplicate, http.StatusConflict, "Email already exists"},
+		{ErrDuplicateUsername, ErrDuplicate, http.StatusConflict, "Username already exists"},
+		{ErrAccountLinkRequired, ErrDuplicate, http.StatusConflict, "An account with this email already exists; sign in with your password"},
+		{ErrUnverifiedIdentity, ErrForbidden, http.StatusForbidden, "Your email address is not verified with the sign-in provider"},
+		{ErrInviteExpired, ErrForbidden, http.StatusForbidden, "Invite code has expired"},
+		{ErrInviteUsed, ErrForbidden, http.StatusForbidden, "Invite code has already been used"},
+		{ErrPasswordTooWeak, ErrValidation, http.StatusBadRequest, "Password does not meet requirements"},
+		{ErrLocked, ErrLocked, http.StatusLocked, "Account is temporarily locked, please try again later"},
+		{ErrLoginBlocked, ErrTooManyRequests, http.StatusTooManyRequests, "Too many failed logins from your network, please try again later"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			wrapped := fmt.Errorf("login: %w", tt.err)
+
+			assert.ErrorIs(t, wrapped, tt.err)
+			assert.ErrorIs(t, wrapped, tt.kind)
+			assert.Equal(t, tt.wantStatus, HTTPStatusCode(wrapped))
+			assert.Equal(t, tt.wantMsg, ErrorMessage(wrapped))
+		})
+	}
+}
+
+func TestErrorKindsStayDistinct(t *testing.T) {
+	assert.NotErrorIs(t, ErrDuplicateEmail, ErrDuplicateUsername)
+	assert.NotErrorIs(t, ErrUserNotFound, ErrAPIKeyNotFound)
+	assert.NotErrorIs(t, ErrNotFound, ErrUserNotFound)
+	assert.Equal(t, http.StatusInternalServerError, HTTPStatusCode(fmt.Errorf("boom")))
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, UnavailableRetryAfter, RetryAfter(fmt.Errorf("get user: %w", ErrUnavailable)))
+	assert.Zero(t, RetryAfter(fmt.Errorf("get user: %w", ErrTimeout)))
+	assert.Zero(t, RetryAfter(ErrInternal))
+}
@@ -0,0 +1,18 @@
+// Package domain contains invite models
+package domain
+
+import "time"
+
+// Invite lets one person register while registration is invite-only. Only
+// a hash of its code is stored; the code itself is shown once, when the
+// invite is created.
+type Invite struct {
+	ID        int32  `json:"id"`
+	TenantID  string `json:"tenant_id"`
+	CreatedBy *int32 `json:"created_by,omitempty"`
+
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	UsedBy    *int32     `json:"used_by,omitempty"`
+}
@@ -0,0 +1,82 @@
+// Package domain contains role permissions
+package domain
+
+import (
+	"slices"
+
+	"user-auth-app/internal/tenant"
+)
+
+// Built-in roles. Users get RoleUser unless another role is given.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// DefaultRoles are the roles users can be given unless configured
+// otherwise
+var DefaultRoles = []string{RoleUser, "moderator", RoleAdmin}
+
+// Permissions guard individual operations, so access can be granted
+// without creating a role per action
+const (
+	// PermissionUsersList allows listing every user
+	PermissionUsersList = "users:list"
+	// PermissionUsersWrite allows creating, importing and changing the
+	// roles of users
+	PermissionUsersWrite = "users:write"
+	// PermissionAuditRead allows reading the audit log
+	PermissionAuditRead = "audit:read"
+	// PermissionKeysManage allows listing the signing keys, which every
+	// tenant shares. It is one of the GlobalPermissions.
+	PermissionKeysManage = "keys:manage"
+	// PermissionWebhooksManage allows managing webhooks and reading their
+	// delivery log
+	PermissionWebhooksManage = "webhooks:manage"
+	// PermissionDeadLettersManage allows inspecting and replaying
+	// messages that failed processing, across tenants. It is one of the
+	// GlobalPermissions.
+	PermissionDeadLettersManage = "dead_letters:manage"
+)
+
+// Permissions lists every known permission
+var Permissions = []string{PermissionUsersList, PermissionUsersWrite, PermissionAuditRead, PermissionKeysManage, PermissionWebhooksManage, PermissionDeadLettersManage}
+
+// GlobalPermissions act on state shared by every tenant. Every tenant has
+// its own admins, so no role gets them by default, and a role granting
+// them only does so in the default tenant, where the operators are.
+var GlobalPermissions = []string{PermissionKeysManage, PermissionDeadLettersManage}
+
+// RolePermissions maps a role to the permissions it grants
+type RolePermissions map[string][]string
+
+// DefaultRolePermissions grants admins everything but GlobalPermissions
+// and other roles nothing, matching the access the roles had before
+// permissions existed
+func DefaultRolePermissions() RolePermissions {
+	admin := slices.DeleteFunc(slices.Clone(Permissions), func(permission string) bool {
+		return slices.Contains(GlobalPermissions, permission)
+	})
+	return RolePermissions{
+		RoleAdmin: admin,
+	}
+}
+
+// For returns the permissions granted to role
+func (p RolePermissions) For(role string) []string {
+	return p[role]
+}
+
+// ForTenant returns the permissions granted to role in the tenant
+// tenantID. Outside the default tenant GlobalPermissions are left out, so
+// a tenant's admin can't reach them by giving a user the role that has
+// them.
+func (p RolePermissions) ForTenant(tenantID, role string) []string {
+	permissions := p.For(role)
+	if tenantID == "" || tenantID == tenant.Default {
+		return permissions
+	}
+	return slices.DeleteFunc(slices.Clone(permissions), func(permission string) bool {
+		return slices.Contains(GlobalPermissions, permission)
+	})
+}
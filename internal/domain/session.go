@@ -0,0 +1,17 @@
+// Package domain contains session models
+package domain
+
+import "time"
+
+// Session is a login on one device. Refreshing a token keeps its session
+// alive; revoking the session invalidates every token issued for it.
+type Session struct {
+	ID        string `json:"id"`
+	UserID    int32  `json:"user_id"`
+	IPAddress string `json:"ip_address,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -1,12 +1,110 @@
 // Package domain
 package domain
 
-import "github.com/jackc/pgx/v5/pgtype"
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Username length bounds. UsernameMaxLenLimit mirrors the
+// check_username_length constraint on users.username, so configured
+// limits can never exceed what the database accepts.
+const (
+	DefaultUsernameMinLen = 3
+	DefaultUsernameMaxLen = 50
+	UsernameMaxLenLimit   = 64
+)
+
+// DefaultReservedUsernames can't be taken unless configured otherwise,
+// since users holding them could pass for staff or the system
+var DefaultReservedUsernames = []string{
+	"admin", "administrator", "root", "superuser", "system", "support",
+	"help", "security", "staff", "moderator", "abuse", "postmaster",
+	"webmaster", "hostmaster", "noreply", "no_reply",
+}
+
+// Password length bounds. PasswordMaxLenLimit is bcrypt's input limit;
+// longer passwords are rejected by the hasher.
+const (
+	DefaultPasswordMinLen = 8
+	DefaultPasswordMaxLen = 72
+	PasswordMaxLenLimit   = 72
+)
 
 type User struct {
 	ID        int32            `json:"id"`
+	TenantID  string           `json:"tenant_id"`
 	Username  string           `json:"username"`
 	Email     string           `json:"email"`
 	Role      string           `json:"role"`
 	CreatedAt pgtype.Timestamp `json:"created_at"`
+
+	// MustChangePassword is set for admin-provisioned accounts until the
+	// user replaces their temporary password
+	MustChangePassword bool `json:"must_change_password"`
+
+	// EmailVerified and Provider are only loaded by lookups that need
+	// them (login by email). Provider is empty for password-only accounts.
+	EmailVerified bool   `json:"email_verified"`
+	Provider      string `json:"provider,omitempty"`
+
+	// TokenVersion is embedded in issued tokens. Bumping it, on a password
+	// change or deactivation, revokes every token issued before.
+	TokenVersion int32 `json:"-"`
+
+	// FailedLogins and LockedUntil are only loaded by login lookups.
+	// LockedUntil is zero for accounts that were never locked.
+	FailedLogins int32     `json:"-"`
+	LockedUntil  time.Time `json:"-"`
+
+	// PasswordChangedAt is when the user last chose a password, only
+	// loaded by login lookups
+	PasswordChangedAt time.Time `json:"-"`
+}
+
+// UserImport is one user in a bulk import
+type UserImport struct {
+	Username string
+	Email    string
+	Role     string
+
+	// PasswordHash is an existing bcrypt or Argon2id hash to keep. Without
+	// one the user has no usable password until they set one through
+	// EventUserPasswordSetup.
+	PasswordHash string
+}
+
+// UserImportResult is the outcome of importing one user
+type UserImportResult struct {
+	User User
+
+	// PasswordSetupQueued is set when the user was created without a
+	// password and EventUserPasswordSetup was published for them
+	PasswordSetupQueued bool
+
+	Err error
+}
+
+// UserDeactivationResult is the outcome of deactivating one user in bulk.
+// Err is ErrUserNotFound if no active user had the ID.
+type UserDeactivationResult struct {
+	UserID int32
+	Err    error
+}
+
+// ExternalIdentity is a user as reported by a social login provider
+type ExternalIdentity struct {
+	Provider      string
+	ProviderID    string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// UserCollectionStats summarizes the active user collection. It changes
+// whenever a user is added, updated or removed.
+type UserCollectionStats struct {
+	Total       int64
+	LastUpdated time.Time
 }
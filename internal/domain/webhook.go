@@ -0,0 +1,54 @@
+// Package domain contains webhook models
+package domain
+
+import "time"
+
+// User events, published to NATS under these subjects and delivered to
+// webhooks subscribed to them
+const (
+	EventUserRegistered      = "user.registered"
+	EventUserPasswordChanged = "user.password_changed"
+)
+
+// EventUserVerify asks for a verification email to be sent to an
+// unverified account. It is published to NATS only; the mailer consuming
+// it issues the verification link.
+const EventUserVerify = "user.verify"
+
+// EventUserPasswordSetup asks for an email letting a user created without
+// a password, such as an imported one, choose one. It is published to
+// NATS only; the mailer consuming it issues the password reset link.
+const EventUserPasswordSetup = "user.password_setup"
+
+// WebhookEvents lists the events webhooks can subscribe to
+var WebhookEvents = []string{EventUserRegistered, EventUserPasswordChanged}
+
+// Webhook is an HTTP endpoint notified of a tenant's user events. Each
+// payload is signed with Secret so the receiver can verify it came from
+// this service.
+type Webhook struct {
+	ID       int32    `json:"id"`
+	TenantID string   `json:"tenant_id"`
+	URL      string   `json:"url"`
+	Secret   string   `json:"-"`
+	Events   []string `json:"events"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery records one attempt to deliver an event to a webhook.
+// Retries of an event share its DeliveryID.
+type WebhookDelivery struct {
+	ID         int64  `json:"id"`
+	WebhookID  int32  `json:"webhook_id"`
+	DeliveryID string `json:"delivery_id"`
+	Event      string `json:"event"`
+	Attempt    int32  `json:"attempt"`
+	// StatusCode is the receiver's response status; zero if no response
+	// arrived
+	StatusCode int32  `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Succeeded  bool   `json:"succeeded"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -13,6 +13,7 @@ type Service interface {
 	SendVerificationEmail(ctx context.Context, to, verificationToken string) error
 	SendPasswordChangedEmail(ctx context.Context, to, username string) error
 	SendLoginAlertEmail(ctx context.Context, to, username, ipAddress, location string) error
+	SendRegistrationAttemptEmail(ctx context.Context, to string) error
 	SendEmail(ctx context.Context, msg *Message) error
 	IsAvailable() bool
 }
@@ -40,11 +41,12 @@ type Attachment struct {
 type EmailTemplate string
 
 const (
-	TemplateWelcome         EmailTemplate = "welcome"
-	TemplatePasswordReset   EmailTemplate = "password_reset"
-	TemplateVerification    EmailTemplate = "verification"
-	TemplatePasswordChanged EmailTemplate = "password_changed"
-	TemplateLoginAlert      EmailTemplate = "login_alert"
+	TemplateWelcome             EmailTemplate = "welcome"
+	TemplatePasswordReset       EmailTemplate = "password_reset"
+	TemplateVerification        EmailTemplate = "verification"
+	TemplatePasswordChanged     EmailTemplate = "password_changed"
+	TemplateLoginAlert          EmailTemplate = "login_alert"
+	TemplateRegistrationAttempt EmailTemplate = "registration_attempt"
 )
 
 // Config holds email service configuration
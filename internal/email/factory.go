@@ -22,9 +22,3 @@ func NewEmailService(cfg *Config, logger *zerolog.Logger) (Service, error) {
 		return nil, fmt.Errorf("unsupported email provider: %s", cfg.Provider)
 	}
 }
-
-// NewFromEnv creates an email service from environment variables
-func NewFromEnv(logger *zerolog.Logger) (Service, error) {
-	cfg := ConfigFromEnv()
-	return NewEmailService(cfg, logger)
-}
@@ -218,3 +218,14 @@ func (s *sesService) SendLoginAlertEmail(ctx context.Context, to, username, ipAd
 		HTMLBody: htmlBody,
 	})
 }
+
+func (s *sesService) SendRegistrationAttemptEmail(ctx context.Context, to string) error {
+	subject, body, htmlBody := s.templates.RenderRegistrationAttempt()
+
+	return s.SendEmail(ctx, &Message{
+		To:       []string{to},
+		Subject:  subject,
+		Body:     body,
+		HTMLBody: htmlBody,
+	})
+}
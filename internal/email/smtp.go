@@ -27,12 +27,6 @@ func NewSMTPService(cfg *Config, logger *zerolog.Logger) (Service, error) {
 	// Test SMTP connection
 	addr := fmt.Sprintf("%s:%d", cfg.SMTPHost, cfg.SMTPPort)
 
-	var auth smtp.Auth
-	fmt.Printf("auth", auth)
-	if cfg.SMTPUsername != "" {
-		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
-	}
-
 	// Try to connect
 	available := true
 	if cfg.SMTPUseTLS {
@@ -189,3 +183,14 @@ func (s *smtpService) SendLoginAlertEmail(ctx context.Context, to, username, ipA
 		HTMLBody: htmlBody,
 	})
 }
+
+func (s *smtpService) SendRegistrationAttemptEmail(ctx context.Context, to string) error {
+	subject, body, htmlBody := s.templates.RenderRegistrationAttempt()
+
+	return s.SendEmail(ctx, &Message{
+		To:       []string{to},
+		Subject:  subject,
+		Body:     body,
+		HTMLBody: htmlBody,
+	})
+}
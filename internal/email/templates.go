@@ -303,6 +303,57 @@ The Team`, username, time.Now().Format("January 2, 2006 at 3:04 PM"), ipAddress,
 	return subject, text, html
 }
 
+// RenderRegistrationAttempt generates a notice that someone tried to
+// register with an email address that already has an account
+func (tm *TemplateManager) RenderRegistrationAttempt() (subject, text, html string) {
+	subject = "Someone Tried to Register With Your Email"
+
+	text = fmt.Sprintf(`Hi,
+
+Someone tried to create a new account using this email address on %s, but you already have an account with us.
+
+If this was you, you can simply log in. If you forgot your password, use the password reset option on the login page.
+
+If this wasn't you, you can safely ignore this email. Your account has not been changed.
+
+Best regards,
+The Team`, time.Now().Format("January 2, 2006 at 3:04 PM"))
+
+	html = fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: #2196F3; color: white; padding: 30px; text-align: center; border-radius: 10px 10px 0 0; }
+        .content { background: #f9f9f9; padding: 30px; border-radius: 0 0 10px 10px; }
+        .alert { background: #e3f2fd; border-left: 4px solid #2196F3; padding: 15px; margin: 20px 0; }
+        .footer { text-align: center; margin-top: 30px; color: #666; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>📬 Registration Attempt</h1>
+        </div>
+        <div class="content">
+            <p>Someone tried to create a new account using this email address on %s, but you already have an account with us.</p>
+            <p>If this was you, you can simply log in. If you forgot your password, use the password reset option on the login page.</p>
+            <div class="alert">
+                <strong>ℹ️ Note:</strong> If this wasn't you, you can safely ignore this email. Your account has not been changed.
+            </div>
+        </div>
+        <div class="footer">
+            <p>© %d Our Platform. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>`, time.Now().Format("January 2, 2006 at 3:04 PM"), time.Now().Year())
+
+	return subject, text, html
+}
+
 // EscapeHTML safely escapes HTML content
 func EscapeHTML(s string) string {
 	return strings.ReplaceAll(
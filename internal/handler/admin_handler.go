@@ -0,0 +1,299 @@
+// Package handler implements admin HTTP handlers
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/service"
+	"user-auth-app/internal/validator"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+// Page sizes of the admin listings; clients may ask for up to the max
+const (
+	defaultAuditLimit = 50
+	MaxAuditLimit     = 500
+
+	defaultUserLimit = 50
+	MaxUserLimit     = 200
+
+	// User search terms are bounded: shorter ones can't use the trigram
+	// indexes and would match most users anyway
+	minUserSearchLen = 3
+	maxUserSearchLen = 100
+)
+
+type AdminHandler struct {
+	authService  service.AuthService
+	auditService service.AuditService
+	userService  service.UserService
+	logger       *zerolog.Logger
+	rules        validator.Rules
+	validation   validationReporter
+
+	// maxImportUsers caps the users in one bulk import
+	maxImportUsers int
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(
+	authService service.AuthService,
+	auditService service.AuditService,
+	userService service.UserService,
+	logger *zerolog.Logger,
+	rules validator.Rules,
+	logValidationFailures bool,
+	maxImportUsers int,
+) *AdminHandler {
+	return &AdminHandler{
+		authService:  authService,
+		auditService: auditService,
+		userService:  userService,
+		logger:       logger,
+		rules:        rules,
+		validation:   validationReporter{logger: logger, verbose: logValidationFailures},
+
+		maxImportUsers: maxImportUsers,
+	}
+}
+
+// CreateUser provisions a user with a temporary password. The user must
+// change it on first login before any other endpoint is available.
+func (h *AdminHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req dto.CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Invalid request body",
+		})
+		return
+	}
+
+	v := validator.NewWithRules(h.rules)
+	v.ValidateUsername("username", req.Username)
+	v.ValidateEmail("email", req.Email)
+	v.ValidatePassword("password", req.Password)
+	v.ValidateRole("role", req.Role)
+
+	if !v.Valid() {
+		h.validation.respond(w, r, v.Errors())
+		return
+	}
+
+	user, err := h.authService.CreateUser(ctx, req.Username, req.Email, req.Password, req.Role)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	respond(w, r, http.StatusCreated, dto.ToUserResponse(user))
+}
+
+// UpdateUserRole changes a user's role, revoking their tokens so they log
+// in again with the new role
+func (h *AdminHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		respondError(w, r, h.logger, domain.ErrUnauthorized)
+		return
+	}
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Invalid user ID",
+		})
+		return
+	}
+
+	var req dto.UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Invalid request body",
+		})
+		return
+	}
+
+	v := validator.NewWithRules(h.rules)
+	v.ValidateRequired("role", req.Role)
+	v.ValidateRole("role", req.Role)
+
+	if !v.Valid() {
+		h.validation.respond(w, r, v.Errors())
+		return
+	}
+
+	if err := h.authService.UpdateUserRole(ctx, claims.UserID, int32(userID), req.Role); err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeactivateUser deactivates a user, revoking their tokens and API keys
+func (h *AdminHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Invalid user ID",
+		})
+		return
+	}
+
+	if err := h.authService.DeactivateUser(ctx, int32(userID)); err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListUsers returns a page of active users. It supports conditional GET:
+// the response carries a weak ETag over the whole collection, and a
+// matching If-None-Match gets 304 Not Modified without loading the page.
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, offset, ok := parsePage(w, r, defaultUserLimit, MaxUserLimit)
+	if !ok {
+		return
+	}
+
+	stats, err := h.userService.GetCollectionStats(ctx)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	etag := collectionETag(stats)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	users, err := h.userService.ListUsers(ctx, limit, offset)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, dto.ToUsersResponse(users, stats.Total))
+}
+
+// SearchUsers returns a page of active users whose username or email
+// contains the q query parameter, case-insensitively
+func (h *AdminHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if n := utf8.RuneCountInString(q); n < minUserSearchLen || n > maxUserSearchLen {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: fmt.Sprintf("q must be %d-%d characters", minUserSearchLen, maxUserSearchLen),
+		})
+		return
+	}
+
+	limit, offset, ok := parsePage(w, r, defaultUserLimit, MaxUserLimit)
+	if !ok {
+		return
+	}
+
+	users, total, err := h.userService.SearchUsers(ctx, q, limit, offset)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, dto.ToUserSearchResponse(users, total, limit, offset))
+}
+
+// parsePage reads the limit and offset query parameters, responding with
+// 400 and returning false if either is invalid
+func parsePage(w http.ResponseWriter, r *http.Request, defaultLimit, maxLimit int) (limit, offset int, ok bool) {
+	query := r.URL.Query()
+
+	limit = defaultLimit
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxLimit {
+			respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+				Error: "limit must be between 1 and " + strconv.Itoa(maxLimit),
+			})
+			return 0, 0, false
+		}
+		limit = n
+	}
+
+	if raw := query.Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+				Error: "offset must be a non-negative integer",
+			})
+			return 0, 0, false
+		}
+		offset = n
+	}
+
+	return limit, offset, true
+}
+
+// ListAuditEvents returns recent audit events, optionally filtered by
+// the user_id query parameter
+func (h *AdminHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+
+	var userID *int32
+	if raw := query.Get("user_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil {
+			respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+				Error: "Invalid user ID",
+			})
+			return
+		}
+		id32 := int32(id)
+		userID = &id32
+	}
+
+	limit := defaultAuditLimit
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > MaxAuditLimit {
+			respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+				Error: "limit must be between 1 and " + strconv.Itoa(MaxAuditLimit),
+			})
+			return
+		}
+		limit = n
+	}
+
+	events, err := h.auditService.ListEvents(ctx, userID, limit)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, dto.ToAuditEventsResponse(events))
+}
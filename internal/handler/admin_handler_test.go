@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/service"
+	"user-auth-app/internal/validator"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserService serves a fixed collection. Methods not overridden
+// panic via the nil embedded interface.
+type fakeUserService struct {
+	service.UserService
+	users     []domain.User
+	stats     domain.UserCollectionStats
+	listCalls int
+	searches  []string
+}
+
+func (s *fakeUserService) ListUsers(ctx context.Context, limit, offset int) ([]domain.User, error) {
+	s.listCalls++
+	return s.users, nil
+}
+
+func (s *fakeUserService) SearchUsers(ctx context.Context, query string, limit, offset int) ([]domain.User, int64, error) {
+	s.searches = append(s.searches, fmt.Sprintf("%s/%d/%d", query, limit, offset))
+	return s.users, int64(len(s.users)), nil
+}
+
+func (s *fakeUserService) GetCollectionStats(ctx context.Context) (domain.UserCollectionStats, error) {
+	return s.stats, nil
+}
+
+func newTestAdminHandler(users service.UserService) *AdminHandler {
+	logger := zerolog.Nop()
+	return NewAdminHandler(nil, nil, users, &logger, validator.DefaultRules(), false, 0)
+}
+
+func listUsers(h *AdminHandler, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rec := httptest.NewRecorder()
+	h.ListUsers(rec, req)
+	return rec
+}
+
+func TestListUsersConditionalGet(t *testing.T) {
+	lastUpdated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	users := &fakeUserService{
+		users: []domain.User{{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}},
+		stats: domain.UserCollectionStats{Total: 2, LastUpdated: lastUpdated},
+	}
+	h := newTestAdminHandler(users)
+
+	first := listUsers(h, "")
+	assert.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	t.Run("unchanged", func(t *testing.T) {
+		calls := users.listCalls
+		rec := listUsers(h, etag)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+		assert.Equal(t, calls, users.listCalls, "page should not be loaded")
+	})
+
+	t.Run("row updated", func(t *testing.T) {
+		users.stats = domain.UserCollectionStats{Total: 2, LastUpdated: lastUpdated.Add(time.Second)}
+		rec := listUsers(h, etag)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	})
+
+	t.Run("row deleted without newer update", func(t *testing.T) {
+		users.stats = domain.UserCollectionStats{Total: 1, LastUpdated: lastUpdated}
+		rec := listUsers(h, etag)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	})
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `W/"2-100"`
+
+	assert.True(t, etagMatches(`W/"2-100"`, etag))
+	assert.True(t, etagMatches(`"2-100"`, etag))
+	assert.True(t, etagMatches(`W/"1-1", W/"2-100"`, etag))
+	assert.True(t, etagMatches(`*`, etag))
+	assert.False(t, etagMatches(``, etag))
+	assert.False(t, etagMatches(`W/"2-101"`, etag))
+}
+
+// deactivatingAuthService deactivates the users it knows
+type deactivatingAuthService struct {
+	service.AuthService
+	active map[int32]bool
+}
+
+func (s deactivatingAuthService) DeactivateUser(ctx context.Context, userID int32) error {
+	if !s.active[userID] {
+		return fmt.Errorf("deactivate user: %w", domain.ErrUserNotFound)
+	}
+	s.active[userID] = false
+	return nil
+}
+
+func TestDeactivateUser(t *testing.T) {
+	logger := zerolog.Nop()
+	h := NewAdminHandler(deactivatingAuthService{active: map[int32]bool{7: true}}, nil, nil, &logger, validator.DefaultRules(), false, 0)
+
+	deactivate := func(id string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/admin/users/"+id, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		h.DeactivateUser(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, deactivate("7"))
+	assert.Equal(t, http.StatusNotFound, deactivate("7"), "already deactivated")
+	assert.Equal(t, http.StatusBadRequest, deactivate("seven"))
+}
+
+// roleAuthService records role changes of the users it knows
+type roleAuthService struct {
+	service.AuthService
+	roles map[int32]string
+}
+
+func (s roleAuthService) UpdateUserRole(ctx context.Context, adminID, userID int32, role string) error {
+	if _, ok := s.roles[userID]; !ok {
+		return fmt.Errorf("update role: %w", domain.ErrUserNotFound)
+	}
+	s.roles[userID] = role
+	return nil
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	logger := zerolog.Nop()
+	roles := map[int32]string{7: domain.RoleUser}
+	h := NewAdminHandler(roleAuthService{roles: roles}, nil, nil, &logger, validator.DefaultRules(), false, 0)
+
+	update := func(id, body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/users/"+id+"/role", strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+		req = req.WithContext(context.WithValue(ctx, middleware.UserContextKey, &service.TokenClaims{UserID: 1}))
+		rec := httptest.NewRecorder()
+		h.UpdateUserRole(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, update("7", `{"role":"moderator"}`))
+	assert.Equal(t, "moderator", roles[7])
+
+	assert.Equal(t, http.StatusBadRequest, update("7", `{"role":"superuser"}`), "not an allowed role")
+	assert.Equal(t, http.StatusBadRequest, update("7", `{}`), "role is required")
+	assert.Equal(t, http.StatusBadRequest, update("seven", `{"role":"admin"}`))
+	assert.Equal(t, http.StatusNotFound, update("8", `{"role":"admin"}`))
+	assert.Equal(t, "moderator", roles[7])
+}
+
+func TestSearchUsers(t *testing.T) {
+	users := &fakeUserService{users: []domain.User{{ID: 1, Username: "alice", Email: "alice@example.com"}}}
+	h := newTestAdminHandler(users)
+
+	search := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users/search?"+query, nil)
+		rec := httptest.NewRecorder()
+		h.SearchUsers(rec, req)
+		return rec
+	}
+
+	rec := search("q=+ali+&limit=10&offset=20")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp dto.UserSearchResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, dto.UserSearchResponse{
+		Users:  []dto.UserResponse{{ID: 1, Username: "alice", Email: "alice@example.com"}},
+		Total:  1,
+		Limit:  10,
+		Offset: 20,
+	}, resp)
+	assert.Equal(t, []string{"ali/10/20"}, users.searches, "the term is trimmed")
+
+	assert.Equal(t, http.StatusOK, search("q=alice").Code)
+	assert.Equal(t, "alice/50/0", users.searches[1], "default page")
+
+	for _, query := range []string{"", "q=al", "q=" + strings.Repeat("a", 101), "q=alice&limit=0", "q=alice&limit=201", "q=alice&offset=-1"} {
+		assert.Equal(t, http.StatusBadRequest, search(query).Code, query)
+	}
+	assert.Len(t, users.searches, 2)
+}
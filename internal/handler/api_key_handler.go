@@ -0,0 +1,108 @@
+// Package handler implements the API key management endpoints
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+type APIKeyHandler struct {
+	apiKeys service.APIKeyService
+	logger  *zerolog.Logger
+
+	// maxBodyBytes caps request bodies read with decodeJSON
+	maxBodyBytes int64
+}
+
+// NewAPIKeyHandler creates a handler for users to manage their API keys
+func NewAPIKeyHandler(apiKeys service.APIKeyService, logger *zerolog.Logger, maxBodyBytes int64) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeys:      apiKeys,
+		logger:       logger,
+		maxBodyBytes: maxBodyBytes,
+	}
+}
+
+// CreateAPIKey creates an API key for the authenticated user. The key is
+// in this response only; it can't be retrieved later.
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, r, h.logger, domain.ErrUnauthorized)
+		return
+	}
+
+	var req dto.CreateAPIKeyRequest
+	if err := decodeJSON(w, r, &req, h.maxBodyBytes); err != nil {
+		respondDecodeError(w, r, err)
+		return
+	}
+
+	key, plaintext, err := h.apiKeys.GenerateAPIKey(ctx, claims.UserID, req.Name, req.Scopes)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	// The key is a credential; keep it out of caches
+	w.Header().Set("Cache-Control", "no-store")
+	respond(w, r, http.StatusCreated, dto.CreateAPIKeyResponse{
+		APIKeyResponse: dto.ToAPIKeyResponse(key),
+		Key:            plaintext,
+	})
+}
+
+// ListAPIKeys lists the authenticated user's active API keys
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, r, h.logger, domain.ErrUnauthorized)
+		return
+	}
+
+	keys, err := h.apiKeys.ListAPIKeys(ctx, claims.UserID)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, dto.ToAPIKeysResponse(keys))
+}
+
+// RevokeAPIKey revokes one of the authenticated user's API keys
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, r, h.logger, domain.ErrUnauthorized)
+		return
+	}
+
+	keyID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Invalid API key ID",
+		})
+		return
+	}
+
+	if err := h.apiKeys.RevokeAPIKey(ctx, claims.UserID, int32(keyID)); err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
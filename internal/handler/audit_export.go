@@ -0,0 +1,243 @@
+// Package handler implements the streaming audit log export
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+)
+
+const (
+	// MaxAuditExportRange caps the time range of one export
+	MaxAuditExportRange = 90 * 24 * time.Hour
+	// auditExportTimeout bounds one export. The route runs without the
+	// API's request timeout, which would buffer the whole response.
+	auditExportTimeout = 5 * time.Minute
+	// auditExportFlushEvery is how many events are written between flushes
+	auditExportFlushEvery = 100
+)
+
+// Export formats
+const (
+	auditFormatCSV    = "csv"
+	auditFormatNDJSON = "ndjson"
+)
+
+// auditCSVColumns is the header row of CSV exports
+var auditCSVColumns = []string{"id", "timestamp", "type", "user_id", "success", "ip_address", "user_agent", "details"}
+
+// ExportAuditEvents streams the audit events from the from query parameter
+// up to but excluding to, oldest first, as CSV or, with ?format=ndjson,
+// one JSON object per line. Events are written as they are read from the
+// database and flushed regularly, so the export is never held in memory.
+// If the export fails after it started, the connection is aborted so the
+// client can't mistake a truncated file for a complete one.
+func (h *AdminHandler) ExportAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	from, err := parseExportTime(query.Get("from"))
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "from must be an RFC 3339 timestamp or a YYYY-MM-DD date",
+		})
+		return
+	}
+	to, err := parseExportTime(query.Get("to"))
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "to must be an RFC 3339 timestamp or a YYYY-MM-DD date",
+		})
+		return
+	}
+	if !from.Before(to) {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "from must be before to",
+		})
+		return
+	}
+	if to.Sub(from) > MaxAuditExportRange {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: fmt.Sprintf("The range may span at most %d days", int(MaxAuditExportRange.Hours()/24)),
+		})
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = auditFormatCSV
+	}
+	if format != auditFormatCSV && format != auditFormatNDJSON {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "format must be csv or ndjson",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), auditExportTimeout)
+	defer cancel()
+
+	// The listener's write timeout is sized for ordinary responses
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(auditExportTimeout)); err != nil {
+		h.logger.Debug().Err(err).Msg("Can't extend write deadline for audit export")
+	}
+
+	export := newAuditExport(w, format)
+	err = h.auditService.ExportEvents(ctx, from, to, func(event domain.AuditEvent) error {
+		if err := export.write(event); err != nil {
+			return err
+		}
+		if export.count%auditExportFlushEvery == 0 {
+			return export.flush(rc)
+		}
+		return nil
+	})
+	if err != nil {
+		if export.count == 0 {
+			respondError(w, r, h.logger, err)
+			return
+		}
+		h.logger.Warn().Err(err).Int("events", export.count).Msg("Audit export aborted")
+		panic(http.ErrAbortHandler)
+	}
+
+	// An empty range still gets the CSV header row
+	err = export.start()
+	if err == nil {
+		err = export.flush(rc)
+	}
+	if err != nil {
+		h.logger.Warn().Err(err).Int("events", export.count).Msg("Audit export aborted")
+		return
+	}
+
+	h.logger.Info().
+		Time("from", from).
+		Time("to", to).
+		Str("format", format).
+		Int("events", export.count).
+		Msg("Audit events exported")
+}
+
+// parseExportTime parses an RFC 3339 timestamp or a date, taken as
+// midnight UTC
+func parseExportTime(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
+
+// auditExport writes events in one format, sending the response headers
+// with the first event
+type auditExport struct {
+	w       http.ResponseWriter
+	format  string
+	buf     *bufio.Writer
+	csv     *csv.Writer
+	json    *json.Encoder
+	started bool
+	count   int
+}
+
+func newAuditExport(w http.ResponseWriter, format string) *auditExport {
+	buf := bufio.NewWriter(w)
+	return &auditExport{
+		w:      w,
+		format: format,
+		buf:    buf,
+		csv:    csv.NewWriter(buf),
+		json:   json.NewEncoder(buf),
+	}
+}
+
+// start sends the headers and, for CSV, the header row once
+func (e *auditExport) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+
+	contentType, ext := "text/csv; charset=utf-8", "csv"
+	if e.format == auditFormatNDJSON {
+		contentType, ext = "application/x-ndjson", "ndjson"
+	}
+	e.w.Header().Set("Content-Type", contentType)
+	e.w.Header().Set("Content-Disposition", `attachment; filename="audit-events.`+ext+`"`)
+	e.w.Header().Set("Cache-Control", "no-store")
+	e.w.WriteHeader(http.StatusOK)
+
+	if e.format == auditFormatCSV {
+		return e.csv.Write(auditCSVColumns)
+	}
+	return nil
+}
+
+func (e *auditExport) write(event domain.AuditEvent) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	e.count++
+
+	if e.format == auditFormatNDJSON {
+		return e.json.Encode(dto.ToAuditEventResponse(event))
+	}
+
+	var userID, details string
+	if event.UserID != nil {
+		userID = strconv.FormatInt(int64(*event.UserID), 10)
+	}
+	if len(event.Details) > 0 {
+		// Keys are sorted, so identical events export identically
+		raw, err := json.Marshal(event.Details)
+		if err != nil {
+			return fmt.Errorf("encode audit details: %w", err)
+		}
+		details = string(raw)
+	}
+
+	return e.csv.Write([]string{
+		strconv.FormatInt(int64(event.ID), 10),
+		event.Timestamp.UTC().Format(time.RFC3339),
+		event.Type,
+		userID,
+		strconv.FormatBool(event.Success),
+		csvSafe(event.IPAddress),
+		csvSafe(event.UserAgent),
+		csvSafe(details),
+	})
+}
+
+// flush sends everything written so far to the client
+func (e *auditExport) flush(rc *http.ResponseController) error {
+	e.csv.Flush()
+	if err := e.csv.Error(); err != nil {
+		return err
+	}
+	if err := e.buf.Flush(); err != nil {
+		return err
+	}
+	if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// csvSafe keeps client-supplied values, such as user agents, from being
+// run as formulas when an export is opened in a spreadsheet
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/service"
+	"user-auth-app/internal/validator"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportAuditService streams a fixed list of events, failing with err
+// after failAfter of them if err is set. Methods not overridden panic via
+// the nil embedded interface.
+type exportAuditService struct {
+	service.AuditService
+	events    []domain.AuditEvent
+	err       error
+	failAfter int
+	from, to  time.Time
+}
+
+func (s *exportAuditService) ExportEvents(ctx context.Context, from, to time.Time, fn func(domain.AuditEvent) error) error {
+	s.from, s.to = from, to
+	for i, event := range s.events {
+		if s.err != nil && i == s.failAfter {
+			return s.err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	if s.err != nil && s.failAfter >= len(s.events) {
+		return s.err
+	}
+	return nil
+}
+
+func exportAudit(audit service.AuditService, query string) *httptest.ResponseRecorder {
+	logger := zerolog.Nop()
+	h := NewAdminHandler(nil, audit, nil, &logger, validator.DefaultRules(), false, 0)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit/export?"+query, nil)
+	rec := httptest.NewRecorder()
+	h.ExportAuditEvents(rec, req)
+	return rec
+}
+
+func exportEvents() []domain.AuditEvent {
+	userID := int32(42)
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return []domain.AuditEvent{
+		{ID: 1, Type: domain.AuditLoginSuccess, UserID: &userID, Success: true, IPAddress: "203.0.113.5", UserAgent: "curl/8.0", Timestamp: at},
+		{ID: 2, Type: domain.AuditLoginFailure, Details: map[string]string{"reason": "invalid_password"}, UserAgent: "=HYPERLINK(\"http://evil\")", Timestamp: at.Add(time.Minute)},
+	}
+}
+
+func TestExportAuditEventsCSV(t *testing.T) {
+	audit := &exportAuditService{events: exportEvents()}
+	rec := exportAudit(audit, "from=2024-03-01&to=2024-03-02T00:00:00Z")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "attachment")
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), audit.from)
+	assert.Equal(t, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), audit.to)
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		auditCSVColumns,
+		{"1", "2024-03-01T12:00:00Z", "login.success", "42", "true", "203.0.113.5", "curl/8.0", ""},
+		{"2", "2024-03-01T12:01:00Z", "login.failure", "", "false", "", "'=HYPERLINK(\"http://evil\")", `{"reason":"invalid_password"}`},
+	}, records)
+}
+
+func TestExportAuditEventsNDJSON(t *testing.T) {
+	rec := exportAudit(&exportAuditService{events: exportEvents()}, "from=2024-03-01&to=2024-03-02&format=ndjson")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	var event map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, "login.failure", event["type"])
+	assert.Equal(t, "2024-03-01T12:01:00Z", event["timestamp"])
+}
+
+func TestExportAuditEventsEmptyRange(t *testing.T) {
+	rec := exportAudit(&exportAuditService{}, "from=2024-03-01&to=2024-03-02")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, strings.Join(auditCSVColumns, ",")+"\n", rec.Body.String())
+}
+
+func TestExportAuditEventsRejectsBadRanges(t *testing.T) {
+	tests := map[string]string{
+		"missing from":   "to=2024-03-02",
+		"malformed to":   "from=2024-03-01&to=tomorrow",
+		"reversed":       "from=2024-03-02&to=2024-03-01",
+		"empty":          "from=2024-03-01&to=2024-03-01",
+		"too long":       "from=2024-01-01&to=2024-06-01",
+		"unknown format": "from=2024-03-01&to=2024-03-02&format=xlsx",
+	}
+
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			audit := &exportAuditService{}
+			rec := exportAudit(audit, query)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.True(t, audit.from.IsZero(), "nothing is exported")
+		})
+	}
+}
+
+func TestExportAuditEventsFailure(t *testing.T) {
+	t.Run("before the first event", func(t *testing.T) {
+		audit := &exportAuditService{err: fmt.Errorf("export audit logs: %w", domain.ErrUnavailable)}
+		rec := exportAudit(audit, "from=2024-03-01&to=2024-03-02")
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Type"), "application/json")
+	})
+
+	t.Run("mid-stream", func(t *testing.T) {
+		audit := &exportAuditService{events: exportEvents(), err: domain.ErrUnavailable, failAfter: 1}
+
+		// The response already started, so the connection is aborted
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			exportAudit(audit, "from=2024-03-01&to=2024-03-02")
+		})
+	})
+}
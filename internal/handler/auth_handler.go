@@ -2,14 +2,18 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"user-auth-app/internal/domain"
 	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/middleware"
 	"user-auth-app/internal/service"
 	"user-auth-app/internal/validator"
 
@@ -17,11 +21,36 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// maxBatchUsers caps the number of IDs in a batch request, such as a
+// profile lookup or a bulk deactivation
+const maxBatchUsers = 100
+
 type AuthHandler struct {
 	authService service.AuthService
 	userService service.UserService
 	logger      *zerolog.Logger
-	timeout     time.Duration
+	rules       validator.Rules
+
+	// passwordPolicy is static per deploy, so it is built once
+	passwordPolicy dto.PasswordPolicyResponse
+
+	// enumerationSafe returns the same response for new and existing emails
+	enumerationSafe bool
+	// discloseThrottling tells clients how many login attempts remain and
+	// when a locked account unlocks
+	discloseThrottling bool
+
+	validation validationReporter
+
+	// maxBodyBytes caps request bodies read with decodeJSON
+	maxBodyBytes int64
+
+	// cookies decides whether issued tokens are also set as cookies
+	cookies middleware.CookieAuth
+
+	// idempotency replays registrations retried with the same
+	// Idempotency-Key; nil disables it
+	idempotency *IdempotencyStore
 }
 
 // NewAuthHandler creates a new authentication handler
@@ -29,114 +58,259 @@ func NewAuthHandler(
 	authService service.AuthService,
 	userService service.UserService,
 	logger *zerolog.Logger,
-	timeout time.Duration,
+	rules validator.Rules,
+	enumerationSafe bool,
+	discloseThrottling bool,
+	logValidationFailures bool,
+	maxBodyBytes int64,
+	cookies middleware.CookieAuth,
+	idempotency *IdempotencyStore,
 ) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
 		userService: userService,
 		logger:      logger,
-		timeout:     timeout,
+		rules:       rules,
+
+		passwordPolicy:     dto.ToPasswordPolicyResponse(rules.Password),
+		enumerationSafe:    enumerationSafe,
+		discloseThrottling: discloseThrottling,
+
+		validation:   validationReporter{logger: logger, verbose: logValidationFailures},
+		maxBodyBytes: maxBodyBytes,
+		cookies:      cookies,
+		idempotency:  idempotency,
 	}
 }
 
-// Register handles user registration
+// Register handles user registration. A retry carrying the same
+// Idempotency-Key gets the original response instead of registering again.
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
-	defer cancel()
+	h.idempotency.serve(w, r, "register", h.maxBodyBytes, h.register)
+}
+
+func (h *AuthHandler) register(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
 	var req dto.RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondJSON(w, http.StatusBadRequest, dto.ErrorResponse{
-			Error: "Invalid request body",
-		})
+	if err := decodeJSON(w, r, &req, h.maxBodyBytes); err != nil {
+		respondDecodeError(w, r, err)
 		return
 	}
 
 	// Validate input
-	v := validator.New()
+	v := validator.NewWithRules(h.rules)
 	v.ValidateUsername("username", req.Username)
 	v.ValidateEmail("email", req.Email)
 	v.ValidatePassword("password", req.Password)
-	v.ValidateRole("role", req.Role)
+	v.ValidateSelfAssignedRole("role", req.Role)
 
 	if !v.Valid() {
-		respondValidationError(w, v.Errors())
+		h.validation.respond(w, r, v.Errors())
 		return
 	}
 
 	// Register user
-	user, err := h.authService.Register(ctx, req.Username, req.Email, req.Password, req.Role)
+	user, err := h.authService.Register(ctx, req.Username, req.Email, req.Password, req.Role, req.InviteCode)
 	if err != nil {
-		respondError(w, h.logger, err)
+		respondError(w, r, h.logger, err)
 		return
 	}
 
-	respondJSON(w, http.StatusCreated, dto.ToUserResponse(user))
+	// Don't reveal whether the email was already registered
+	if h.enumerationSafe {
+		respond(w, r, http.StatusAccepted, dto.MessageResponse{
+			Message: "Registration received. Please check your email to continue.",
+		})
+		return
+	}
+
+	respond(w, r, http.StatusCreated, dto.ToUserResponse(user))
+}
+
+// ResendVerification asks for the verification email of an unverified
+// account to be sent again. The response is the same whether or not the
+// email belongs to such an account.
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req dto.ResendVerificationRequest
+	if err := decodeJSON(w, r, &req, h.maxBodyBytes); err != nil {
+		respondDecodeError(w, r, err)
+		return
+	}
+
+	v := validator.NewWithRules(h.rules)
+	v.ValidateEmail("email", req.Email)
+
+	if !v.Valid() {
+		h.validation.respond(w, r, v.Errors())
+		return
+	}
+
+	if err := h.authService.ResendVerification(ctx, req.Email); err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	respond(w, r, http.StatusAccepted, dto.MessageResponse{
+		Message: "If the email belongs to an unverified account, a verification email is on its way.",
+	})
 }
 
 // Login handles user login
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
-	defer cancel()
+	ctx := r.Context()
 
 	var req dto.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondJSON(w, http.StatusBadRequest, dto.ErrorResponse{
-			Error: "Invalid request body",
-		})
+	if err := decodeJSON(w, r, &req, h.maxBodyBytes); err != nil {
+		respondDecodeError(w, r, err)
 		return
 	}
 
 	// Validate input
+	identifier := strings.TrimSpace(req.LoginIdentifier())
 	v := validator.New()
-	v.ValidateRequired("email", req.Email)
+	v.ValidateRequired("identifier", identifier)
 	v.ValidateRequired("password", req.Password)
 
 	if !v.Valid() {
-		respondValidationError(w, v.Errors())
+		h.validation.respond(w, r, v.Errors())
 		return
 	}
 
-	// Authenticate user
-	token, expiresAt, err := h.authService.Login(ctx, req.Email, req.Password)
+	// Authenticate user by email or username
+	user, token, expiresAt, err := h.authService.Login(ctx, identifier, req.Password, req.RememberMe)
 	if err != nil {
-		respondError(w, h.logger, err)
+		h.respondLoginError(w, r, err)
 		return
 	}
 
-	// Get user profile for response
-	claims, _ := h.authService.ValidateToken(ctx, token)
-	user := dto.UserResponse{
-		ID:    claims.UserID,
-		Email: claims.Email,
-		Role:  claims.Role,
-	}
+	h.respondLogin(w, r, user, token, expiresAt, req.RememberMe)
+}
 
+// respondLogin sends the response for a newly issued session token,
+// including the user's profile so clients needn't fetch it separately
+func (h *AuthHandler) respondLogin(w http.ResponseWriter, r *http.Request, user domain.User, token string, expiresAt time.Time, rememberMe bool) {
 	response := dto.LoginResponse{
 		Token:     token,
-		ExpiresAt: expiresAt,
-		User:      user,
+		ExpiresAt: expiresAt.UTC(),
+		User:      dto.ToUserResponse(user),
+
+		// Such users get a token scoped to changing the password
+		PasswordChangeRequired: user.MustChangePassword,
+	}
+	if !h.issueTokenCookies(w, r, &response, rememberMe && !user.MustChangePassword) {
+		return
 	}
 
-	respondJSON(w, http.StatusOK, response)
+	respond(w, r, http.StatusOK, response)
+}
+
+// respondLoginError sends the response for a failed login. When
+// throttling may be disclosed, failures of existing accounts also say how
+// many attempts remain (X-RateLimit-Remaining) or, once locked, when to
+// retry (Retry-After). A blocked address always gets Retry-After, since
+// it says nothing about any account.
+func (h *AuthHandler) respondLoginError(w http.ResponseWriter, r *http.Request, err error) {
+	var throttle *domain.LoginThrottleError
+	if !errors.As(err, &throttle) {
+		respondError(w, r, h.logger, err)
+		return
+	}
+	if !h.discloseThrottling {
+		if errors.Is(err, domain.ErrLoginBlocked) {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(throttle.LockedUntil)))
+		}
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	response := dto.LoginFailureResponse{Error: domain.ErrorMessage(err)}
+	if throttle.LockedUntil.IsZero() {
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(throttle.AttemptsRemaining))
+		response.AttemptsRemaining = &throttle.AttemptsRemaining
+	} else {
+		retryAfter := retryAfterSeconds(throttle.LockedUntil)
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		response.RetryAfterSeconds = retryAfter
+	}
+
+	respond(w, r, domain.HTTPStatusCode(err), response)
+}
+
+// retryAfterSeconds returns the Retry-After value for until, rounded up
+// so clients retrying on time don't arrive early
+func retryAfterSeconds(until time.Time) int {
+	return int(math.Ceil(time.Until(until).Seconds()))
+}
+
+// ChangePassword changes the authenticated user's password. It is the
+// only endpoint available to tokens with a pending password change.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, r, h.logger, domain.ErrUnauthorized)
+		return
+	}
+
+	var req dto.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Invalid request body",
+		})
+		return
+	}
+
+	v := validator.NewWithRules(h.rules)
+	v.ValidateRequired("current_password", req.CurrentPassword)
+	v.ValidatePassword("new_password", req.NewPassword)
+
+	if !v.Valid() {
+		h.validation.respond(w, r, v.Errors())
+		return
+	}
+
+	if err := h.authService.ChangePassword(ctx, claims.UserID, req.CurrentPassword, req.NewPassword); err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, dto.MessageResponse{
+		Message: "Password changed. Please log in again.",
+	})
+}
+
+// PasswordPolicy returns the active password requirements so clients can
+// display and pre-validate them
+func (h *AuthHandler) PasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	respond(w, r, http.StatusOK, h.passwordPolicy)
 }
 
 // GetProfile retrieves a user's profile
 func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
-	defer cancel()
+	ctx := r.Context()
+
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok || claims == nil {
+		respondError(w, r, h.logger, domain.ErrUnauthorized)
+		return
+	}
 
 	userIDStr := chi.URLParam(r, "id")
 	userID64, err := strconv.ParseInt(userIDStr, 10, 32)
 	if err != nil {
-		respondJSON(w, http.StatusBadRequest, dto.ErrorResponse{
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
 			Error: "Invalid user ID",
 		})
 		return
 	}
 
 	if userID64 < math.MinInt32 || userID64 > math.MaxInt32 {
-		respondJSON(w, http.StatusBadRequest, dto.ErrorResponse{
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
 			Error: "User ID out of range",
 		})
 		return
@@ -145,58 +319,108 @@ func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	userID := int32(userID64)
 
 	// Get user profile using user service
-	user, err := h.userService.GetProfile(ctx, userID)
+	user, err := h.userService.GetProfile(ctx, claims.UserID, userID)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, dto.ToUserResponse(user))
+}
+
+// GetCurrentUser returns the profile of the user the request was
+// authenticated as, so clients don't need to know their own ID
+func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok || claims == nil {
+		respondError(w, r, h.logger, domain.ErrUnauthorized)
+		return
+	}
+
+	user, err := h.userService.GetProfile(ctx, claims.UserID, claims.UserID)
 	if err != nil {
-		respondError(w, h.logger, err)
+		respondError(w, r, h.logger, err)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, dto.ToUserResponse(user))
+	respond(w, r, http.StatusOK, dto.ToUserResponse(user))
+}
+
+// GetProfiles returns several user profiles in one request. The body is a
+// JSON array of user IDs; IDs that don't exist are left out of the result.
+func (h *AuthHandler) GetProfiles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var ids []int32
+	if err := json.NewDecoder(r.Body).Decode(&ids); err != nil {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Request body must be a JSON array of user IDs",
+		})
+		return
+	}
+
+	if len(ids) == 0 || len(ids) > maxBatchUsers {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: fmt.Sprintf("Between 1 and %d user IDs are required", maxBatchUsers),
+		})
+		return
+	}
+
+	users, err := h.userService.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, dto.ToUsersResponse(users, int64(len(users))))
 }
 
 // RefreshToken refreshes an access token
 func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
-	defer cancel()
+	ctx := r.Context()
 
-	// Get token from Authorization header
-	tokenString := extractToken(r)
+	// Get the token sent with the request, which may have expired
+	tokenString, _ := middleware.GetTokenFromContext(r.Context())
 	if tokenString == "" {
-		respondJSON(w, http.StatusUnauthorized, dto.ErrorResponse{
+		respond(w, r, http.StatusUnauthorized, dto.ErrorResponse{
 			Error: "Missing authorization token",
 		})
 		return
 	}
 
 	// Refresh token
-	newToken, expiresAt, err := h.authService.RefreshToken(ctx, tokenString)
+	user, newToken, expiresAt, rememberMe, err := h.authService.RefreshToken(ctx, tokenString)
 	if err != nil {
-		respondError(w, h.logger, err)
+		respondError(w, r, h.logger, err)
 		return
 	}
 
-	// Get user claims
-	claims, _ := h.authService.ValidateToken(ctx, newToken)
-	user := dto.UserResponse{
-		ID:    claims.UserID,
-		Email: claims.Email,
-		Role:  claims.Role,
-	}
+	h.respondLogin(w, r, user, newToken, expiresAt, rememberMe)
+}
 
-	response := dto.LoginResponse{
-		Token:     newToken,
-		ExpiresAt: expiresAt,
-		User:      user,
+// issueTokenCookies sets the auth and CSRF cookies when cookie auth is
+// enabled. Persistent cookies expire with the remember-me session, which
+// outlives the token, so the browser keeps a token it can refresh; others
+// are session cookies. In cookie-only mode the token is left out of the body so page
+// scripts never see it. It reports false if it already sent an error.
+func (h *AuthHandler) issueTokenCookies(w http.ResponseWriter, r *http.Request, response *dto.LoginResponse, persistent bool) bool {
+	if !h.cookies.UsesCookies() {
+		return true
 	}
 
-	respondJSON(w, http.StatusOK, response)
-}
+	var expiresAt time.Time
+	if persistent {
+		expiresAt = time.Now().Add(h.cookies.RememberMeLifetime)
+	}
+	if err := h.cookies.SetTokenCookies(w, response.Token, expiresAt); err != nil {
+		respondError(w, r, h.logger, err)
+		return false
+	}
 
-// extractToken extracts JWT token from Authorization header
-func extractToken(r *http.Request) string {
-	bearerToken := r.Header.Get("Authorization")
-	if len(bearerToken) > 7 && bearerToken[:7] == "Bearer " {
-		return bearerToken[7:]
+	if h.cookies.Mode == middleware.AuthModeCookie {
+		response.Token = ""
 	}
-	return ""
+	return true
 }
\ No newline at end of file
@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/service"
+	"user-auth-app/internal/validator"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStrictRequestDecoding checks that Register and Login reject bodies
+// before reaching the service, which is nil here
+func TestStrictRequestDecoding(t *testing.T) {
+	logger := zerolog.Nop()
+	h := NewAuthHandler(nil, nil, &logger, validator.DefaultRules(), false, false, false, DefaultMaxBodyBytes, middleware.CookieAuth{}, nil)
+
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		body      string
+		wantField string
+		wantError string
+	}{
+		{
+			name:      "register with misspelled field",
+			handler:   h.Register,
+			body:      `{"username":"alice","email":"alice@example.com","passwrd":"Secure-Pass-123"}`,
+			wantField: "passwrd",
+			wantError: `Request body contains unknown field "passwrd"`,
+		},
+		{
+			name:      "register with wrong type",
+			handler:   h.Register,
+			body:      `{"username":"alice","email":"alice@example.com","password":12345678}`,
+			wantField: "password",
+			wantError: `Field "password" must be a string`,
+		},
+		{
+			name:      "login with extra field",
+			handler:   h.Login,
+			body:      `{"identifier":"alice","password":"Secure-Pass-123","remember":true}`,
+			wantField: "remember",
+			wantError: `Request body contains unknown field "remember"`,
+		},
+		{
+			name:      "login with wrong type",
+			handler:   h.Login,
+			body:      `{"identifier":["alice"],"password":"Secure-Pass-123"}`,
+			wantField: "identifier",
+			wantError: `Field "identifier" must be a string`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			tt.handler(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var resp dto.ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, tt.wantError, resp.Error)
+			assert.Contains(t, resp.Fields, tt.wantField)
+		})
+	}
+}
+
+// profileUserService serves GetProfile from a map
+type profileUserService struct {
+	service.UserService
+	users map[int32]domain.User
+}
+
+func (s profileUserService) GetProfile(ctx context.Context, readerID, userID int32) (domain.User, error) {
+	user, ok := s.users[userID]
+	if !ok {
+		return domain.User{}, domain.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func TestGetCurrentUser(t *testing.T) {
+	logger := zerolog.Nop()
+	users := profileUserService{users: map[int32]domain.User{
+		7: {ID: 7, TenantID: "acme", Username: "alice", Email: "alice@example.com", Role: "user", EmailVerified: true, Provider: "google", TokenVersion: 3},
+	}}
+	h := NewAuthHandler(nil, users, &logger, validator.DefaultRules(), false, false, false, DefaultMaxBodyBytes, middleware.CookieAuth{}, nil)
+
+	getMe := func(claims *service.TokenClaims) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+		if claims != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, claims))
+		}
+		rec := httptest.NewRecorder()
+		h.GetCurrentUser(rec, req)
+		return rec
+	}
+
+	t.Run("resolves the user from the token", func(t *testing.T) {
+		rec := getMe(&service.TokenClaims{UserID: 7})
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var user dto.UserResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&user))
+		assert.Equal(t, int32(7), user.ID)
+		assert.Equal(t, "alice", user.Username)
+	})
+
+	t.Run("only public fields are exposed", func(t *testing.T) {
+		rec := getMe(&service.TokenClaims{UserID: 7})
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var fields map[string]json.RawMessage
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&fields))
+		assert.ElementsMatch(t, []string{"id", "username", "email", "role", "created_at"}, slices.Collect(maps.Keys(fields)),
+			"internal fields such as tenant_id and provider must not leak")
+	})
+
+	t.Run("missing claims", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, getMe(nil).Code)
+	})
+
+	t.Run("deleted user", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, getMe(&service.TokenClaims{UserID: 8}).Code)
+	})
+}
+
+// loginAuthService logs in a fixed user
+type loginAuthService struct {
+	service.AuthService
+	user domain.User
+}
+
+func (s loginAuthService) Login(ctx context.Context, identifier, password string, rememberMe bool) (domain.User, string, time.Time, error) {
+	return s.user, "signed-token", time.Now().Add(time.Hour), nil
+}
+
+func TestLoginReturnsUser(t *testing.T) {
+	logger := zerolog.Nop()
+	auth := loginAuthService{user: domain.User{ID: 7, Username: "alice", Email: "alice@example.com", Role: "admin"}}
+	h := NewAuthHandler(auth, nil, &logger, validator.DefaultRules(), false, false, false, DefaultMaxBodyBytes, middleware.CookieAuth{}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"identifier":"alice","password":"Secure-Pass-123"}`))
+	rec := httptest.NewRecorder()
+	h.Login(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp map[string]json.RawMessage
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.JSONEq(t, `"signed-token"`, string(resp["token"]))
+	assert.JSONEq(t, `{"id":7,"username":"alice","email":"alice@example.com","role":"admin","created_at":"0001-01-01T00:00:00Z"}`, string(resp["user"]))
+}
+
+func TestLoginTimestampsAreUTC(t *testing.T) {
+	logger := zerolog.Nop()
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 60*60))
+	auth := loginAuthService{user: domain.User{ID: 7, Username: "alice", CreatedAt: pgtype.Timestamp{Time: createdAt, Valid: true}}}
+	h := NewAuthHandler(auth, nil, &logger, validator.DefaultRules(), false, false, false, DefaultMaxBodyBytes, middleware.CookieAuth{}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"identifier":"alice","password":"Secure-Pass-123"}`))
+	rec := httptest.NewRecorder()
+	h.Login(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		ExpiresAt string `json:"expires_at"`
+		User      struct {
+			CreatedAt string `json:"created_at"`
+		} `json:"user"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "2024-01-02T02:04:05Z", resp.User.CreatedAt)
+	assert.True(t, strings.HasSuffix(resp.ExpiresAt, "Z"), resp.ExpiresAt)
+	_, err := time.Parse(time.RFC3339, resp.ExpiresAt)
+	assert.NoError(t, err)
+}
+
+func TestLoginRememberMeCookie(t *testing.T) {
+	logger := zerolog.Nop()
+	auth := loginAuthService{user: domain.User{ID: 7, Username: "alice"}}
+	cookies := middleware.CookieAuth{Mode: middleware.AuthModeCookie, Name: "access_token", CSRFCookieName: "csrf_token", CSRF: true, RememberMeLifetime: 30 * 24 * time.Hour}
+	h := NewAuthHandler(auth, nil, &logger, validator.DefaultRules(), false, false, false, DefaultMaxBodyBytes, cookies, nil)
+
+	for body, persistent := range map[string]bool{
+		`{"identifier":"alice","password":"Secure-Pass-123"}`:                     false,
+		`{"identifier":"alice","password":"Secure-Pass-123","remember_me":false}`: false,
+		`{"identifier":"alice","password":"Secure-Pass-123","remember_me":true}`:  true,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.Login(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		// Without remember-me the browser forgets the login when it closes
+		resp := rec.Result()
+		require.Len(t, resp.Cookies(), 2)
+		for _, cookie := range resp.Cookies() {
+			assert.Equal(t, persistent, !cookie.Expires.IsZero(), "%s in %s", cookie.Name, body)
+			if persistent {
+				// The cookies outlive the token, as the session does
+				assert.WithinDuration(t, time.Now().Add(cookies.RememberMeLifetime), cookie.Expires, time.Minute)
+			}
+		}
+	}
+}
+
+// refreshAuthService refreshes any token for a fixed user
+type refreshAuthService struct {
+	service.AuthService
+	user       domain.User
+	rememberMe bool
+}
+
+func (s refreshAuthService) RefreshToken(ctx context.Context, token string) (domain.User, string, time.Time, bool, error) {
+	if token != "expired-token" {
+		return domain.User{}, "", time.Time{}, false, domain.ErrInvalidToken
+	}
+	return s.user, "refreshed-token", time.Now().Add(time.Hour), s.rememberMe, nil
+}
+
+func TestRefreshTokenAfterExpiry(t *testing.T) {
+	logger := zerolog.Nop()
+	auth := refreshAuthService{user: domain.User{ID: 7, Username: "alice", Email: "alice@example.com", Role: "user"}, rememberMe: true}
+	cookies := middleware.CookieAuth{Mode: middleware.AuthModeBoth, Name: "access_token", CSRFCookieName: "csrf_token", RememberMeLifetime: 30 * 24 * time.Hour}
+	h := NewAuthHandler(auth, nil, &logger, validator.DefaultRules(), false, false, false, DefaultMaxBodyBytes, cookies, nil)
+	refresh := middleware.RefreshAuth(&logger, cookies)(http.HandlerFunc(h.RefreshToken))
+
+	// The expired access token is not rejected before the service sees it
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: "expired-token"})
+	rec := httptest.NewRecorder()
+	refresh.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp map[string]json.RawMessage
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.JSONEq(t, `"refreshed-token"`, string(resp["token"]))
+	assert.JSONEq(t, `{"id":7,"username":"alice","email":"alice@example.com","role":"user","created_at":"0001-01-01T00:00:00Z"}`, string(resp["user"]))
+
+	// A remember-me cookie expires with the session, not the token
+	require.Len(t, rec.Result().Cookies(), 1)
+	cookie := rec.Result().Cookies()[0]
+	assert.Equal(t, "refreshed-token", cookie.Value)
+	assert.WithinDuration(t, time.Now().Add(cookies.RememberMeLifetime), cookie.Expires, time.Minute)
+
+	// Once the session is gone the user has to log in again
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", nil)
+	req.Header.Set("Authorization", "Bearer other-token")
+	rec = httptest.NewRecorder()
+	refresh.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestRegisterEnumerationSafeResponse(t *testing.T) {
+	logger := zerolog.Nop()
+	h := NewAuthHandler(&countingAuthService{}, nil, &logger, validator.DefaultRules(), true, false, false, DefaultMaxBodyBytes, middleware.CookieAuth{}, nil)
+
+	rec := register(h, "", registerBody)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Contains(t, rec.Body.String(), "check your email")
+	assert.NotContains(t, rec.Body.String(), "alice", "the response doesn't depend on the account")
+}
+
+func TestRegisterRejectsAdminRole(t *testing.T) {
+	auth := &countingAuthService{}
+	h := newIdempotentTestHandler(auth)
+
+	rec := register(h, "", strings.Replace(registerBody, `}`, `,"role":"admin"}`, 1))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "can only be assigned by an administrator")
+	assert.Zero(t, auth.calls)
+}
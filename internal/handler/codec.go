@@ -0,0 +1,88 @@
+// Package handler negotiates the encoding of response bodies
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Media types responses can be encoded in
+const (
+	mediaTypeJSON    = "application/json"
+	mediaTypeMsgpack = "application/msgpack"
+)
+
+// codec encodes response bodies in one media type
+type codec interface {
+	ContentType() string
+	Encode(w io.Writer, v any) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return mediaTypeJSON }
+
+func (jsonCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// msgpackCodec encodes with the DTOs' json tags, so both formats have the
+// same field names and omit the same empty fields
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string { return mediaTypeMsgpack }
+
+func (msgpackCodec) Encode(w io.Writer, v any) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(v)
+}
+
+// codecs maps the media types clients may ask for to their codec.
+// Wildcards get the default, JSON.
+var codecs = map[string]codec{
+	mediaTypeJSON:           jsonCodec{},
+	mediaTypeMsgpack:        msgpackCodec{},
+	"application/x-msgpack": msgpackCodec{},
+	"application/*":         jsonCodec{},
+	"*/*":                   jsonCodec{},
+}
+
+// negotiate picks the codec for the request's Accept header: the known
+// media type with the highest quality, the first listed on ties. Without
+// a known media type responses are JSON.
+func negotiate(r *http.Request) codec {
+	var (
+		best        codec = jsonCodec{}
+		bestQuality float64
+	)
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(accepted)
+		if err != nil {
+			continue
+		}
+		c, ok := codecs[mediaType]
+		if !ok {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = c, quality
+		}
+	}
+
+	return best
+}
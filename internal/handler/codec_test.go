@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/handler/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", mediaTypeJSON},
+		{"*/*", mediaTypeJSON},
+		{"text/html", mediaTypeJSON},
+		{"not a media type", mediaTypeJSON},
+		{"application/msgpack", mediaTypeMsgpack},
+		{"application/x-msgpack", mediaTypeMsgpack},
+		{"application/msgpack, application/json", mediaTypeMsgpack},
+		{"application/json, application/msgpack", mediaTypeJSON},
+		{"application/json;q=0.5, application/msgpack", mediaTypeMsgpack},
+		{"application/msgpack;q=0.9, */*;q=0.1", mediaTypeMsgpack},
+		{"application/msgpack;q=0", mediaTypeJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			assert.Equal(t, tt.want, negotiate(req).ContentType())
+		})
+	}
+}
+
+func TestRespondMsgpackMatchesJSON(t *testing.T) {
+	user := dto.UserResponse{ID: 1, Email: "user@example.com", Username: "user", Role: "user", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	send := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		respond(rec, req, http.StatusOK, user)
+		return rec
+	}
+
+	jsonRec := send(mediaTypeJSON)
+	msgpackRec := send(mediaTypeMsgpack)
+	assert.Equal(t, mediaTypeMsgpack, msgpackRec.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", msgpackRec.Header().Get("Vary"))
+	assert.Less(t, msgpackRec.Body.Len(), jsonRec.Body.Len())
+
+	// Both formats carry the same fields under the same names
+	var fromJSON, fromMsgpack map[string]any
+	require.NoError(t, json.Unmarshal(jsonRec.Body.Bytes(), &fromJSON))
+	require.NoError(t, msgpack.Unmarshal(msgpackRec.Body.Bytes(), &fromMsgpack))
+	assert.Equal(t, len(fromJSON), len(fromMsgpack))
+	for field := range fromJSON {
+		assert.Contains(t, fromMsgpack, field)
+	}
+	assert.Equal(t, "user@example.com", fromMsgpack["email"])
+}
@@ -0,0 +1,74 @@
+// Package handler implements the dead letter admin endpoints
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultDeadLetterLimit = 50
+	MaxDeadLetterLimit     = 200
+)
+
+type DeadLetterHandler struct {
+	deadLetters service.DeadLetterService
+	logger      *zerolog.Logger
+}
+
+// NewDeadLetterHandler creates a handler for admins to inspect and replay
+// dead letters
+func NewDeadLetterHandler(deadLetters service.DeadLetterService, logger *zerolog.Logger) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		deadLetters: deadLetters,
+		logger:      logger,
+	}
+}
+
+// ListDeadLetters returns the dead letters waiting to be replayed, newest
+// first
+func (h *DeadLetterHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit := defaultDeadLetterLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > MaxDeadLetterLimit {
+			respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+				Error: "limit must be between 1 and " + strconv.Itoa(MaxDeadLetterLimit),
+			})
+			return
+		}
+		limit = n
+	}
+
+	deadLetters, err := h.deadLetters.ListDeadLetters(r.Context(), limit)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, dto.ToDeadLettersResponse(deadLetters))
+}
+
+// ReplayDeadLetter republishes a dead letter to its subject and removes it
+func (h *DeadLetterHandler) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Invalid dead letter ID",
+		})
+		return
+	}
+
+	if err := h.deadLetters.ReplayDeadLetter(r.Context(), int32(id)); err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,67 @@
+// Package dto contains API key data transfer objects
+package dto
+
+import (
+	"time"
+
+	"user-auth-app/internal/domain"
+)
+
+// CreateAPIKeyRequest represents a request to create an API key
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKeyResponse describes an API key without the key itself
+type APIKeyResponse struct {
+	ID         int32      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreateAPIKeyResponse includes the key, which is only ever returned here
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// APIKeysResponse represents the caller's active API keys
+type APIKeysResponse struct {
+	Keys []APIKeyResponse `json:"keys"`
+}
+
+// ToAPIKeyResponse converts a domain API key to a response
+func ToAPIKeyResponse(key domain.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		CreatedAt:  key.CreatedAt.UTC(),
+		LastUsedAt: utcPtr(key.LastUsedAt),
+	}
+}
+
+// ToAPIKeysResponse converts domain API keys to a response
+func ToAPIKeysResponse(keys []domain.APIKey) APIKeysResponse {
+	resp := APIKeysResponse{
+		Keys: make([]APIKeyResponse, len(keys)),
+	}
+	for i, key := range keys {
+		resp.Keys[i] = ToAPIKeyResponse(key)
+	}
+	return resp
+}
+
+// utcPtr converts an optional timestamp to UTC
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
@@ -0,0 +1,50 @@
+// Package dto contains audit data transfer objects
+package dto
+
+import (
+	"time"
+
+	"user-auth-app/internal/domain"
+)
+
+// AuditEventResponse represents an audit event in responses
+type AuditEventResponse struct {
+	ID        int32             `json:"id"`
+	Type      string            `json:"type"`
+	UserID    *int32            `json:"user_id"`
+	Success   bool              `json:"success"`
+	IPAddress string            `json:"ip_address,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// AuditEventsResponse represents a list of audit events
+type AuditEventsResponse struct {
+	Events []AuditEventResponse `json:"events"`
+}
+
+// ToAuditEventResponse converts a domain audit event to a response
+func ToAuditEventResponse(event domain.AuditEvent) AuditEventResponse {
+	return AuditEventResponse{
+		ID:        event.ID,
+		Type:      event.Type,
+		UserID:    event.UserID,
+		Success:   event.Success,
+		IPAddress: event.IPAddress,
+		UserAgent: event.UserAgent,
+		Details:   event.Details,
+		Timestamp: event.Timestamp.UTC(),
+	}
+}
+
+// ToAuditEventsResponse converts domain audit events to a response
+func ToAuditEventsResponse(events []domain.AuditEvent) AuditEventsResponse {
+	resp := AuditEventsResponse{
+		Events: make([]AuditEventResponse, len(events)),
+	}
+	for i, event := range events {
+		resp.Events[i] = ToAuditEventResponse(event)
+	}
+	return resp
+}
@@ -1,10 +1,13 @@
-// Package dto defines data transfer objects
+// Package dto defines data transfer objects. Timestamps are converted to
+// UTC, so they always encode as RFC 3339 with a Z suffix whatever the
+// server's time zone.
 package dto
 
 import (
 	"time"
 
 	"user-auth-app/internal/domain"
+	"user-auth-app/internal/validator"
 )
 
 // RegisterRequest represents a user registration request
@@ -13,19 +16,65 @@ type RegisterRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Role     string `json:"role,omitempty"`
+	// InviteCode is required while registration is invite-only
+	InviteCode string `json:"invite_code,omitempty"`
 }
 
-// LoginRequest represents a login request
+// LoginRequest represents a login request. Identifier is an email or a
+// username; Email is still accepted from older clients.
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Identifier string `json:"identifier"`
+	Email      string `json:"email,omitempty"`
+	Password   string `json:"password"`
+	// RememberMe keeps the session for SESSION_REMEMBER_ME_DAYS instead
+	// of SESSION_LIFETIME_HOURS, and the auth cookie across browser
+	// restarts
+	RememberMe bool `json:"remember_me,omitempty"`
+}
+
+// LoginIdentifier returns the identifier, falling back to the legacy
+// email field
+func (r LoginRequest) LoginIdentifier() string {
+	if r.Identifier != "" {
+		return r.Identifier
+	}
+	return r.Email
 }
 
 // LoginResponse represents a successful login response
 type LoginResponse struct {
-	Token     string       `json:"token"`
+	Token     string       `json:"token,omitempty"`
 	ExpiresAt time.Time    `json:"expires_at"`
 	User      UserResponse `json:"user"`
+
+	// PasswordChangeRequired means the token only allows changing the
+	// password
+	PasswordChangeRequired bool `json:"password_change_required,omitempty"`
+}
+
+// ResendVerificationRequest asks for a verification email to be resent
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// ChangePasswordRequest represents a password change request
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// CreateUserRequest represents an admin request to provision a user with
+// a temporary password
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// UpdateRoleRequest represents an admin request to change a user's role
+type UpdateRoleRequest struct {
+	Role string `json:"role"`
 }
 
 // UserResponse represents user data in responses
@@ -35,6 +84,8 @@ type UserResponse struct {
 	Email     string    `json:"email"`
 	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
+
+	MustChangePassword bool `json:"must_change_password,omitempty"`
 }
 
 // ToUserResponse converts domain.User to UserResponse
@@ -44,12 +95,86 @@ func ToUserResponse(user domain.User) UserResponse {
 		Username:  user.Username,
 		Email:     user.Email,
 		Role:      user.Role,
-		CreatedAt: user.CreatedAt.Time,
+		CreatedAt: user.CreatedAt.Time.UTC(),
+
+		MustChangePassword: user.MustChangePassword,
 	}
 }
 
+// UsersResponse represents a page of users
+type UsersResponse struct {
+	Users []UserResponse `json:"users"`
+	Total int64          `json:"total"`
+}
+
+// ToUsersResponse converts a page of domain users to a UsersResponse
+func ToUsersResponse(users []domain.User, total int64) UsersResponse {
+	resp := UsersResponse{
+		Users: make([]UserResponse, len(users)),
+		Total: total,
+	}
+	for i, user := range users {
+		resp.Users[i] = ToUserResponse(user)
+	}
+	return resp
+}
+
+// UserSearchResponse represents a page of users matching a search
+type UserSearchResponse struct {
+	Users  []UserResponse `json:"users"`
+	Total  int64          `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+// ToUserSearchResponse converts a page of search results to a
+// UserSearchResponse
+func ToUserSearchResponse(users []domain.User, total int64, limit, offset int) UserSearchResponse {
+	return UserSearchResponse{
+		Users:  ToUsersResponse(users, total).Users,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+}
+
+// PasswordPolicyResponse describes the active password requirements
+type PasswordPolicyResponse struct {
+	MinLength       int      `json:"min_length"`
+	MaxLength       int      `json:"max_length"`
+	RequiredClasses []string `json:"required_classes"`
+	RejectCommon    bool     `json:"reject_common"`
+	BreachCheck     bool     `json:"breach_check"`
+}
+
+// ToPasswordPolicyResponse converts a validator.PasswordPolicy to a response
+func ToPasswordPolicyResponse(policy validator.PasswordPolicy) PasswordPolicyResponse {
+	return PasswordPolicyResponse{
+		MinLength:       policy.MinLength,
+		MaxLength:       policy.MaxLength,
+		RequiredClasses: policy.RequiredClasses(),
+		RejectCommon:    policy.RejectCommon,
+		BreachCheck:     policy.BreachCheck,
+	}
+}
+
+// MessageResponse represents a generic informational response
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error  string            `json:"error"`
 	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// LoginFailureResponse is the error response of a failed login when
+// throttling details may be disclosed
+type LoginFailureResponse struct {
+	Error string `json:"error"`
+	// AttemptsRemaining is how many more failures lock the account
+	AttemptsRemaining *int `json:"attempts_remaining,omitempty"`
+	// RetryAfterSeconds is how long a locked account stays locked
+	RetryAfterSeconds int `json:"retry_after_seconds,omitempty"`
 }
\ No newline at end of file
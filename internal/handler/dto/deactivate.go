@@ -0,0 +1,26 @@
+// Package dto contains bulk user deactivation data transfer objects
+package dto
+
+// DeactivateUsersRequest lists the users to deactivate
+type DeactivateUsersRequest struct {
+	UserIDs []int32 `json:"user_ids"`
+}
+
+// Deactivation result statuses
+const (
+	DeactivationStatusDeactivated = "deactivated"
+	DeactivationStatusNotFound    = "not_found"
+)
+
+// DeactivateUserResult is the outcome for one user ID
+type DeactivateUserResult struct {
+	UserID int32  `json:"user_id"`
+	Status string `json:"status"`
+}
+
+// DeactivateUsersResponse reports a bulk deactivation
+type DeactivateUsersResponse struct {
+	Deactivated int                    `json:"deactivated"`
+	NotFound    int                    `json:"not_found"`
+	Results     []DeactivateUserResult `json:"results"`
+}
@@ -0,0 +1,56 @@
+// Package dto contains dead letter data transfer objects
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"user-auth-app/internal/domain"
+)
+
+// DeadLetterResponse describes a message its subscriber failed to
+// process. A JSON message is returned as is in Data; anything else is
+// base64-encoded in DataBase64.
+type DeadLetterResponse struct {
+	ID         int32           `json:"id"`
+	Subject    string          `json:"subject"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	DataBase64 []byte          `json:"data_base64,omitempty"`
+	Attempts   int32           `json:"attempts"`
+	Error      string          `json:"error"`
+	FailedAt   time.Time       `json:"failed_at"`
+}
+
+// DeadLettersResponse represents the dead letters waiting to be replayed,
+// newest first
+type DeadLettersResponse struct {
+	DeadLetters []DeadLetterResponse `json:"dead_letters"`
+}
+
+// ToDeadLetterResponse converts a domain dead letter to a response
+func ToDeadLetterResponse(dl domain.DeadLetter) DeadLetterResponse {
+	resp := DeadLetterResponse{
+		ID:       dl.ID,
+		Subject:  dl.Subject,
+		Attempts: dl.Attempts,
+		Error:    dl.Error,
+		FailedAt: dl.FailedAt.UTC(),
+	}
+	if json.Valid(dl.Data) {
+		resp.Data = dl.Data
+	} else {
+		resp.DataBase64 = dl.Data
+	}
+	return resp
+}
+
+// ToDeadLettersResponse converts domain dead letters to a response
+func ToDeadLettersResponse(deadLetters []domain.DeadLetter) DeadLettersResponse {
+	resp := DeadLettersResponse{
+		DeadLetters: make([]DeadLetterResponse, len(deadLetters)),
+	}
+	for i, dl := range deadLetters {
+		resp.DeadLetters[i] = ToDeadLetterResponse(dl)
+	}
+	return resp
+}
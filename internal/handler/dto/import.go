@@ -0,0 +1,47 @@
+// Package dto contains bulk user import data transfer objects
+package dto
+
+// ImportUserRequest is one user in a bulk import, given as a JSON array
+// element or a CSV row
+type ImportUserRequest struct {
+	Username     string `json:"username"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	PasswordHash string `json:"password_hash"`
+}
+
+// Import result statuses
+const (
+	ImportStatusCreated     = "created"
+	ImportStatusFailed      = "failed"
+	ImportStatusNotImported = "not_imported"
+)
+
+// Password setup statuses of users imported without a password hash
+const (
+	PasswordSetupQueued = "queued"
+	PasswordSetupFailed = "failed"
+)
+
+// ImportUserResult is the outcome for one user. Row is the user's 1-based
+// position in the request.
+type ImportUserResult struct {
+	Row    int           `json:"row"`
+	Status string        `json:"status"`
+	User   *UserResponse `json:"user,omitempty"`
+
+	// PasswordSetup is set for users created without a password hash,
+	// who choose a password through the password setup email
+	PasswordSetup string `json:"password_setup,omitempty"`
+
+	Error  string            `json:"error,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// ImportUsersResponse reports a bulk import
+type ImportUsersResponse struct {
+	Mode    string             `json:"mode"`
+	Created int                `json:"created"`
+	Failed  int                `json:"failed"`
+	Results []ImportUserResult `json:"results"`
+}
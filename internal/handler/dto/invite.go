@@ -0,0 +1,13 @@
+// Package dto contains invite data transfer objects
+package dto
+
+import "time"
+
+// CreateInviteResponse includes the invite code, which is only ever
+// returned here
+type CreateInviteResponse struct {
+	ID        int32     `json:"id"`
+	Code      string    `json:"code"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,40 @@
+// Package dto contains signing key data transfer objects
+package dto
+
+import (
+	"time"
+
+	"user-auth-app/internal/signing"
+)
+
+// SigningKeyResponse describes a JWT key accepted by the server
+type SigningKeyResponse struct {
+	ID        string     `json:"kid"`
+	Algorithm string     `json:"alg"`
+	Current   bool       `json:"current"`
+	RetiresAt *time.Time `json:"retires_at,omitempty"`
+}
+
+// SigningKeysResponse represents the list of accepted keys
+type SigningKeysResponse struct {
+	Keys []SigningKeyResponse `json:"keys"`
+}
+
+// ToSigningKeysResponse converts key descriptions to a response
+func ToSigningKeysResponse(keys []signing.KeyInfo) SigningKeysResponse {
+	resp := SigningKeysResponse{
+		Keys: make([]SigningKeyResponse, len(keys)),
+	}
+	for i, key := range keys {
+		resp.Keys[i] = SigningKeyResponse{
+			ID:        key.ID,
+			Algorithm: key.Algorithm,
+			Current:   key.Current,
+		}
+		if !key.RetiresAt.IsZero() {
+			retiresAt := key.RetiresAt.UTC()
+			resp.Keys[i].RetiresAt = &retiresAt
+		}
+	}
+	return resp
+}
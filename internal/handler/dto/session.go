@@ -0,0 +1,45 @@
+// Package dto contains session data transfer objects
+package dto
+
+import (
+	"time"
+
+	"user-auth-app/internal/domain"
+)
+
+// SessionResponse describes one of the caller's sessions
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current marks the session of the token making the request
+	Current bool `json:"current"`
+}
+
+// SessionsResponse represents the caller's active sessions
+type SessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+// ToSessionsResponse converts domain sessions to a response, marking the
+// one with currentID as current
+func ToSessionsResponse(sessions []domain.Session, currentID string) SessionsResponse {
+	resp := SessionsResponse{
+		Sessions: make([]SessionResponse, len(sessions)),
+	}
+	for i, session := range sessions {
+		resp.Sessions[i] = SessionResponse{
+			ID:         session.ID,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt.UTC(),
+			LastUsedAt: session.LastUsedAt.UTC(),
+			ExpiresAt:  session.ExpiresAt.UTC(),
+			Current:    currentID != "" && session.ID == currentID,
+		}
+	}
+	return resp
+}
@@ -0,0 +1,92 @@
+// Package dto contains webhook data transfer objects
+package dto
+
+import (
+	"time"
+
+	"user-auth-app/internal/domain"
+)
+
+// CreateWebhookRequest represents a request to create a webhook
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// WebhookResponse describes a webhook without its signing secret
+type WebhookResponse struct {
+	ID        int32     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateWebhookResponse includes the signing secret, which is only ever
+// returned here
+type CreateWebhookResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+// WebhooksResponse represents the tenant's webhooks
+type WebhooksResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+// WebhookDeliveryResponse describes one delivery attempt
+type WebhookDeliveryResponse struct {
+	DeliveryID string    `json:"delivery_id"`
+	Event      string    `json:"event"`
+	Attempt    int32     `json:"attempt"`
+	StatusCode int32     `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Succeeded  bool      `json:"succeeded"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookDeliveriesResponse represents a webhook's recent delivery
+// attempts, newest first
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+}
+
+// ToWebhookResponse converts a domain webhook to a response
+func ToWebhookResponse(webhook domain.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Events:    webhook.Events,
+		CreatedAt: webhook.CreatedAt.UTC(),
+	}
+}
+
+// ToWebhooksResponse converts domain webhooks to a response
+func ToWebhooksResponse(webhooks []domain.Webhook) WebhooksResponse {
+	resp := WebhooksResponse{
+		Webhooks: make([]WebhookResponse, len(webhooks)),
+	}
+	for i, webhook := range webhooks {
+		resp.Webhooks[i] = ToWebhookResponse(webhook)
+	}
+	return resp
+}
+
+// ToWebhookDeliveriesResponse converts domain delivery attempts to a
+// response
+func ToWebhookDeliveriesResponse(deliveries []domain.WebhookDelivery) WebhookDeliveriesResponse {
+	resp := WebhookDeliveriesResponse{
+		Deliveries: make([]WebhookDeliveryResponse, len(deliveries)),
+	}
+	for i, delivery := range deliveries {
+		resp.Deliveries[i] = WebhookDeliveryResponse{
+			DeliveryID: delivery.DeliveryID,
+			Event:      delivery.Event,
+			Attempt:    delivery.Attempt,
+			StatusCode: delivery.StatusCode,
+			Error:      delivery.Error,
+			Succeeded:  delivery.Succeeded,
+			CreatedAt:  delivery.CreatedAt.UTC(),
+		}
+	}
+	return resp
+}
@@ -0,0 +1,33 @@
+// Package handler implements conditional GET helpers
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"user-auth-app/internal/domain"
+)
+
+// collectionETag builds a weak ETag for the user collection. The count
+// is included so deleting a row changes the tag even when the latest
+// update time of the remaining rows does not.
+func collectionETag(stats domain.UserCollectionStats) string {
+	return fmt.Sprintf(`W/"%d-%d"`, stats.Total, stats.LastUpdated.UnixNano())
+}
+
+// etagMatches reports whether an If-None-Match header matches etag using
+// weak comparison
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"time"
 
+	"user-auth-app/internal/buildinfo"
 	"user-auth-app/internal/cache"
 	"user-auth-app/internal/email"
 	"user-auth-app/internal/messaging"
@@ -19,6 +20,7 @@ type HealthHandler struct {
 	cache        cache.Service
 	broker       messaging.Broker
 	emailService email.Service
+	environment  string
 }
 
 type HealthResponse struct {
@@ -28,13 +30,20 @@ type HealthResponse struct {
 	Version   string            `json:"version,omitempty"`
 }
 
+// VersionResponse describes the running build
+type VersionResponse struct {
+	buildinfo.Info
+	Environment string `json:"environment"`
+}
+
 // NewHealthHandler creates a new health handler
-func NewHealthHandler(pool *pgxpool.Pool, cache cache.Service, broker messaging.Broker, emailService email.Service) *HealthHandler {
+func NewHealthHandler(pool *pgxpool.Pool, cache cache.Service, broker messaging.Broker, emailService email.Service, environment string) *HealthHandler {
 	return &HealthHandler{
 		pool:         pool,
 		cache:        cache,
 		broker:       broker,
 		emailService: emailService,
+		environment:  environment,
 	}
 }
 
@@ -71,8 +80,11 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 
 	// Check message broker
 	if h.broker != nil {
-		if h.broker.IsAvailable() {
+		if h.broker.IsConnected() {
 			services["messaging"] = "healthy"
+		} else if h.broker.IsAvailable() {
+			// Messages are buffered until the connection is back
+			services["messaging"] = "degraded"
 		} else {
 			services["messaging"] = "unavailable"
 		}
@@ -97,7 +109,7 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 		Status:    status,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Services:  services,
-		Version:   "1.0.0", // Update with actual version
+		Version:   buildinfo.Version,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -134,3 +146,17 @@ func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
 func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
+
+// Version reports which build is running, to confirm what each
+// environment has deployed
+// @Summary Build information
+// @Tags system
+// @Produce json
+// @Success 200 {object} VersionResponse
+// @Router /version [get]
+func (h *HealthHandler) Version(w http.ResponseWriter, r *http.Request) {
+	respond(w, r, http.StatusOK, VersionResponse{
+		Info:        buildinfo.Get(),
+		Environment: h.environment,
+	})
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"user-auth-app/internal/buildinfo"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion(t *testing.T) {
+	restore := buildinfo.Commit
+	buildinfo.Commit = "abc123"
+	defer func() { buildinfo.Commit = restore }()
+
+	h := NewHealthHandler(nil, nil, nil, nil, "staging")
+	rec := httptest.NewRecorder()
+	h.Version(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, "abc123", body["commit"])
+	assert.Equal(t, runtime.Version(), body["go_version"])
+	assert.Equal(t, "staging", body["environment"])
+	assert.Equal(t, buildinfo.Version, body["version"])
+	assert.Contains(t, body, "build_time")
+}
@@ -0,0 +1,188 @@
+// Package handler implements Idempotency-Key support for retried requests
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"user-auth-app/internal/cache"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/tenant"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// IdempotencyKeyHeader names the client-chosen key for a request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// idempotencyReplayedHeader marks responses served from the store
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLen bounds client keys; a UUID is 36 characters
+	maxIdempotencyKeyLen = 255
+	// idempotencyLockTTL bounds how long a request in flight holds its
+	// key, so a crashed request doesn't block retries for the full TTL
+	idempotencyLockTTL = time.Minute
+)
+
+// idempotencyRecord is what the store keeps per key: a lock while the
+// first request runs, then its response
+type idempotencyRecord struct {
+	RequestHash string `json:"request_hash"`
+	Done        bool   `json:"done"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// IdempotencyStore replays the first response to a request carrying an
+// Idempotency-Key header when the client retries it
+type IdempotencyStore struct {
+	cache  cache.Service
+	ttl    time.Duration
+	logger *zerolog.Logger
+}
+
+// NewIdempotencyStore creates a store that keeps responses for ttl
+func NewIdempotencyStore(cache cache.Service, ttl time.Duration, logger *zerolog.Logger) *IdempotencyStore {
+	return &IdempotencyStore{
+		cache:  cache,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// serve runs next at most once per Idempotency-Key. A retry with the same
+// key and body gets the stored response; the same key with a different
+// body, or while the first request is still running, gets 409. Requests
+// without a key, and all requests when the store is unavailable, run
+// normally. Server errors aren't stored, so they can be retried.
+func (s *IdempotencyStore) serve(w http.ResponseWriter, r *http.Request, scope string, maxBodyBytes int64, next http.HandlerFunc) {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if s == nil || key == "" {
+		next(w, r)
+		return
+	}
+	if len(key) > maxIdempotencyKeyLen {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Idempotency-Key must be at most 255 characters",
+		})
+		return
+	}
+
+	// The body is needed to fingerprint the request, so read it up front
+	// and hand the handler a copy
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = DefaultMaxBodyBytes
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		respondDecodeError(w, r, classifyDecodeError(err, maxBodyBytes))
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Keys are chosen by clients, so tenants can't share them
+	ctx := r.Context()
+	storeKey := "idempotency:" + tenant.ID(ctx) + ":" + scope + ":" + hashHex([]byte(key))
+	// The key salts the fingerprint, since bodies can contain passwords
+	requestHash := hashHex(append([]byte(key+"\x00"), body...))
+
+	locked, err := s.cache.SetNX(ctx, storeKey, idempotencyRecord{RequestHash: requestHash}, idempotencyLockTTL)
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("Idempotency store unavailable, processing request without it")
+		next(w, r)
+		return
+	}
+
+	if !locked {
+		s.replay(ctx, w, r, storeKey, requestHash)
+		return
+	}
+
+	rec := &capturingWriter{ResponseWriter: w, status: http.StatusOK}
+	next(rec, r)
+
+	// Let the client retry server errors, and unlock the key for them
+	if rec.status >= http.StatusInternalServerError {
+		if err := s.cache.Delete(context.WithoutCancel(ctx), storeKey); err != nil {
+			s.logger.Warn().Err(err).Msg("Failed to release idempotency key")
+		}
+		return
+	}
+
+	record := idempotencyRecord{
+		RequestHash: requestHash,
+		Done:        true,
+		Status:      rec.status,
+		ContentType: rec.Header().Get("Content-Type"),
+		Body:        rec.body.Bytes(),
+	}
+	if err := s.cache.Set(context.WithoutCancel(ctx), storeKey, record, s.ttl); err != nil {
+		s.logger.Warn().Err(err).Msg("Failed to store idempotent response")
+	}
+}
+
+// replay answers a request whose key is already in the store
+func (s *IdempotencyStore) replay(ctx context.Context, w http.ResponseWriter, r *http.Request, storeKey, requestHash string) {
+	var record idempotencyRecord
+	if err := s.cache.Get(ctx, storeKey, &record); err != nil {
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			s.logger.Warn().Err(err).Msg("Failed to load idempotent response")
+		}
+		// Most likely the first request failed and released the key in
+		// the meantime; have the client retry
+		record = idempotencyRecord{RequestHash: requestHash}
+	}
+
+	switch {
+	case record.RequestHash != requestHash:
+		respond(w, r, http.StatusConflict, dto.ErrorResponse{
+			Error: "Idempotency-Key was already used with a different request",
+		})
+	case !record.Done:
+		respond(w, r, http.StatusConflict, dto.ErrorResponse{
+			Error: "A request with this Idempotency-Key is being processed; retry shortly",
+		})
+	default:
+		if record.ContentType != "" {
+			w.Header().Set("Content-Type", record.ContentType)
+		}
+		w.Header().Set(idempotencyReplayedHeader, "true")
+		w.WriteHeader(record.Status)
+		w.Write(record.Body)
+	}
+}
+
+// capturingWriter copies the status and body it passes through
+type capturingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *capturingWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func hashHex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
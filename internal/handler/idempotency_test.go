@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/breaker"
+	"user-auth-app/internal/cache"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/service"
+	"user-auth-app/internal/validator"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingAuthService registers users and counts the calls. Methods not
+// overridden panic via the nil embedded interface.
+type countingAuthService struct {
+	service.AuthService
+	calls int
+	err   error
+}
+
+func (s *countingAuthService) Register(ctx context.Context, username, email, password, role, inviteCode string) (domain.User, error) {
+	s.calls++
+	if s.err != nil {
+		return domain.User{}, s.err
+	}
+	return domain.User{ID: int32(s.calls), Username: username, Email: email, Role: "user"}, nil
+}
+
+func newIdempotentTestHandler(auth service.AuthService) *AuthHandler {
+	logger := zerolog.Nop()
+	store := NewIdempotencyStore(cache.NewRedisCache("", &logger, time.Minute, 100, breaker.DefaultSettings), time.Hour, &logger)
+	return NewAuthHandler(auth, nil, &logger, validator.DefaultRules(), false, false, false, DefaultMaxBodyBytes, middleware.CookieAuth{}, store)
+}
+
+func register(h *AuthHandler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/register", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	h.Register(rec, req)
+	return rec
+}
+
+const registerBody = `{"username":"alice","email":"alice@example.com","password":"Secure-Pass-123"}`
+
+func TestRegisterIdempotencyReplay(t *testing.T) {
+	auth := &countingAuthService{}
+	h := newIdempotentTestHandler(auth)
+
+	first := register(h, "key-1", registerBody)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	retry := register(h, "key-1", registerBody)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get(idempotencyReplayedHeader))
+	assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+	assert.Equal(t, 1, auth.calls, "retry must not register again")
+
+	other := register(h, "key-2", registerBody)
+	assert.Equal(t, http.StatusCreated, other.Code)
+	assert.Equal(t, 2, auth.calls)
+}
+
+func TestRegisterIdempotencyKeyReusedWithDifferentBody(t *testing.T) {
+	auth := &countingAuthService{}
+	h := newIdempotentTestHandler(auth)
+
+	register(h, "key-1", registerBody)
+	rec := register(h, "key-1", strings.Replace(registerBody, "alice", "mallory", 2))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, 1, auth.calls)
+}
+
+func TestRegisterIdempotencyStoresClientErrors(t *testing.T) {
+	auth := &countingAuthService{err: domain.ErrDuplicateEmail}
+	h := newIdempotentTestHandler(auth)
+
+	assert.Equal(t, http.StatusConflict, register(h, "key-1", registerBody).Code)
+	assert.Equal(t, http.StatusConflict, register(h, "key-1", registerBody).Code)
+	assert.Equal(t, 1, auth.calls)
+}
+
+func TestRegisterIdempotencyRetriesServerErrors(t *testing.T) {
+	auth := &countingAuthService{err: domain.ErrTimeout}
+	h := newIdempotentTestHandler(auth)
+
+	assert.Equal(t, http.StatusGatewayTimeout, register(h, "key-1", registerBody).Code)
+
+	auth.err = nil
+	assert.Equal(t, http.StatusCreated, register(h, "key-1", registerBody).Code)
+	assert.Equal(t, 2, auth.calls)
+}
+
+func TestRegisterWithoutIdempotencyKey(t *testing.T) {
+	auth := &countingAuthService{}
+	h := newIdempotentTestHandler(auth)
+
+	register(h, "", registerBody)
+	register(h, "", registerBody)
+	assert.Equal(t, 2, auth.calls)
+}
+
+func TestRegisterIdempotencyKeyTooLong(t *testing.T) {
+	auth := &countingAuthService{}
+	h := newIdempotentTestHandler(auth)
+
+	rec := register(h, strings.Repeat("k", maxIdempotencyKeyLen+1), registerBody)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, 0, auth.calls)
+}
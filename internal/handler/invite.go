@@ -0,0 +1,38 @@
+// Package handler implements invites to register while registration is
+// invite-only
+package handler
+
+import (
+	"net/http"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/middleware"
+)
+
+// CreateInvite creates a single-use invite code. The code is in this
+// response only; it can't be retrieved later.
+func (h *AdminHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		respondError(w, r, h.logger, domain.ErrUnauthorized)
+		return
+	}
+
+	invite, code, err := h.authService.CreateInvite(ctx, claims.UserID)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	// The code is a credential; keep it out of caches
+	w.Header().Set("Cache-Control", "no-store")
+	respond(w, r, http.StatusCreated, dto.CreateInviteResponse{
+		ID:        invite.ID,
+		Code:      code,
+		CreatedAt: invite.CreatedAt,
+		ExpiresAt: invite.ExpiresAt,
+	})
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/service"
+	"user-auth-app/internal/validator"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inviteAuthService creates invites on behalf of whoever asks
+type inviteAuthService struct {
+	service.AuthService
+	createdBy int32
+}
+
+func (s *inviteAuthService) CreateInvite(ctx context.Context, createdBy int32) (domain.Invite, string, error) {
+	s.createdBy = createdBy
+	now := time.Now().UTC().Truncate(time.Second)
+	return domain.Invite{ID: 4, CreatedBy: &createdBy, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}, "inv_code", nil
+}
+
+func TestCreateInvite(t *testing.T) {
+	auth := &inviteAuthService{}
+	logger := zerolog.Nop()
+	h := NewAdminHandler(auth, nil, nil, &logger, validator.DefaultRules(), false, 0)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/invites", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &service.TokenClaims{UserID: 9}))
+	rec := httptest.NewRecorder()
+	h.CreateInvite(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, int32(9), auth.createdBy)
+
+	var response dto.CreateInviteResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, int32(4), response.ID)
+	assert.Equal(t, "inv_code", response.Code)
+	assert.Equal(t, time.Hour, response.ExpiresAt.Sub(response.CreatedAt))
+}
+
+func TestCreateInviteRequiresClaims(t *testing.T) {
+	auth := &inviteAuthService{}
+	logger := zerolog.Nop()
+	h := NewAdminHandler(auth, nil, nil, &logger, validator.DefaultRules(), false, 0)
+
+	rec := httptest.NewRecorder()
+	h.CreateInvite(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/invites", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,36 @@
+// Package handler implements the JWKS and signing key admin endpoints
+package handler
+
+import (
+	"net/http"
+
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/signing"
+
+	"github.com/rs/zerolog"
+)
+
+type KeysHandler struct {
+	keys   *signing.KeySet
+	logger *zerolog.Logger
+}
+
+// NewKeysHandler creates a handler that publishes and lists the token
+// verification keys
+func NewKeysHandler(keys *signing.KeySet, logger *zerolog.Logger) *KeysHandler {
+	return &KeysHandler{keys: keys, logger: logger}
+}
+
+// JWKS serves the public keys in JWKS format so gateways and other
+// services can verify tokens. Symmetric (HS256) keys are never published;
+// with HS256 the key set is empty.
+func (h *KeysHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	// Short enough that verifiers pick up a new key soon after rotation
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respond(w, r, http.StatusOK, h.keys.JWKS())
+}
+
+// ListKeys lists the keys whose tokens are accepted, signing key first
+func (h *KeysHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	respond(w, r, http.StatusOK, dto.ToSigningKeysResponse(h.keys.Keys()))
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/service"
+	"user-auth-app/internal/validator"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingLoginAuthService fails every login with err
+type failingLoginAuthService struct {
+	service.AuthService
+	err error
+}
+
+func (s failingLoginAuthService) Login(ctx context.Context, identifier, password string, rememberMe bool) (domain.User, string, time.Time, error) {
+	return domain.User{}, "", time.Time{}, s.err
+}
+
+func failLogin(t *testing.T, err error, disclose bool) (*httptest.ResponseRecorder, dto.LoginFailureResponse) {
+	t.Helper()
+	logger := zerolog.Nop()
+	h := NewAuthHandler(failingLoginAuthService{err: err}, nil, &logger, validator.DefaultRules(), false, disclose, false, DefaultMaxBodyBytes, middleware.CookieAuth{}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(`{"identifier":"alice","password":"wrong"}`))
+	rec := httptest.NewRecorder()
+	h.Login(rec, req)
+
+	var resp dto.LoginFailureResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec, resp
+}
+
+func TestLoginDisclosesRemainingAttempts(t *testing.T) {
+	rec, resp := failLogin(t, &domain.LoginThrottleError{Err: domain.ErrInvalidCredentials, AttemptsRemaining: 2}, true)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.Empty(t, rec.Header().Get("Retry-After"))
+	assert.Equal(t, "Invalid credentials", resp.Error)
+	require.NotNil(t, resp.AttemptsRemaining)
+	assert.Equal(t, 2, *resp.AttemptsRemaining)
+}
+
+func TestLoginDisclosesLockout(t *testing.T) {
+	lockedUntil := time.Now().Add(10 * time.Minute)
+	rec, resp := failLogin(t, &domain.LoginThrottleError{Err: domain.ErrLocked, LockedUntil: lockedUntil}, true)
+
+	assert.Equal(t, http.StatusLocked, rec.Code)
+	assert.Empty(t, rec.Header().Get("X-RateLimit-Remaining"))
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.InDelta(t, 600, retryAfter, 2)
+	assert.Equal(t, retryAfter, resp.RetryAfterSeconds)
+	assert.Nil(t, resp.AttemptsRemaining)
+}
+
+func TestLoginThrottlingIsOpaqueByDefault(t *testing.T) {
+	for _, err := range []error{
+		&domain.LoginThrottleError{Err: domain.ErrInvalidCredentials, AttemptsRemaining: 2},
+		&domain.LoginThrottleError{Err: domain.ErrLocked, LockedUntil: time.Now().Add(time.Minute)},
+	} {
+		rec, resp := failLogin(t, err, false)
+
+		assert.Equal(t, domain.HTTPStatusCode(err), rec.Code)
+		assert.Empty(t, rec.Header().Get("X-RateLimit-Remaining"))
+		assert.Empty(t, rec.Header().Get("Retry-After"))
+		assert.Nil(t, resp.AttemptsRemaining)
+		assert.Zero(t, resp.RetryAfterSeconds)
+	}
+}
+
+func TestLoginUnknownAccountDisclosesNothing(t *testing.T) {
+	rec, resp := failLogin(t, domain.ErrInvalidCredentials, true)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, rec.Header().Get("X-RateLimit-Remaining"))
+	assert.Nil(t, resp.AttemptsRemaining)
+}
+
+func TestLoginBlockedAddressAlwaysGetsRetryAfter(t *testing.T) {
+	err := &domain.LoginThrottleError{Err: domain.ErrLoginBlocked, LockedUntil: time.Now().Add(5 * time.Minute)}
+
+	for _, disclose := range []bool{false, true} {
+		rec, resp := failLogin(t, err, disclose)
+
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		assert.InDelta(t, 300, retryAfter, 2)
+		assert.Equal(t, "Too many failed logins from your network, please try again later", resp.Error)
+	}
+}
+
+func TestLoginDatabaseOutage(t *testing.T) {
+	t.Run("unreachable", func(t *testing.T) {
+		rec, resp := failLogin(t, fmt.Errorf("login failed: get user by email: %w", domain.ErrUnavailable), false)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "5", rec.Header().Get("Retry-After"))
+		assert.NotEqual(t, "An internal error occurred", resp.Error)
+	})
+
+	t.Run("timed out", func(t *testing.T) {
+		rec, _ := failLogin(t, fmt.Errorf("login failed: get user by email: %w", domain.ErrTimeout), false)
+
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+		assert.Empty(t, rec.Header().Get("Retry-After"))
+	})
+}
@@ -0,0 +1,15 @@
+// Package handler defines HTTP handler metrics
+package handler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var validationFailures = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "validation_failures_total",
+		Help: "Total number of request validation failures by endpoint, field and failure code",
+	},
+	[]string{"endpoint", "field", "code"},
+)
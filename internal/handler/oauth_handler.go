@@ -0,0 +1,156 @@
+// Package handler implements the social login endpoints
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/oauth"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	oauthStateCookie    = "oauth_state"
+	oauthVerifierCookie = "oauth_verifier"
+	// oauthFlowTTL bounds how long the user has to finish the consent page
+	oauthFlowTTL = 10 * time.Minute
+)
+
+// IdentityProvider runs an OAuth2 authorization code flow with PKCE
+type IdentityProvider interface {
+	AuthCodeURL(state, verifier string) string
+	Identity(ctx context.Context, code, verifier string) (domain.ExternalIdentity, error)
+}
+
+type OAuthHandler struct {
+	auth   *AuthHandler
+	google IdentityProvider
+	logger *zerolog.Logger
+	secure bool
+}
+
+// NewOAuthHandler creates a handler for signing in with Google. Sessions
+// are issued the same way as password logins through auth.
+func NewOAuthHandler(auth *AuthHandler, google IdentityProvider, logger *zerolog.Logger, secureCookies bool) *OAuthHandler {
+	return &OAuthHandler{
+		auth:   auth,
+		google: google,
+		logger: logger,
+		secure: secureCookies,
+	}
+}
+
+// GoogleStart redirects to Google's consent page. The state and PKCE
+// verifier are kept in short-lived cookies bound to this browser.
+func (h *OAuthHandler) GoogleStart(w http.ResponseWriter, r *http.Request) {
+	state, err := randomToken()
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	h.setFlowCookie(w, oauthStateCookie, state, oauthFlowTTL)
+	h.setFlowCookie(w, oauthVerifierCookie, verifier, oauthFlowTTL)
+
+	http.Redirect(w, r, h.google.AuthCodeURL(state, verifier), http.StatusFound)
+}
+
+// GoogleCallback completes the flow started by GoogleStart and signs the
+// user in
+func (h *OAuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+
+	// The flow cookies are single use whatever the outcome
+	state, _ := r.Cookie(oauthStateCookie)
+	verifier, _ := r.Cookie(oauthVerifierCookie)
+	h.setFlowCookie(w, oauthStateCookie, "", -1)
+	h.setFlowCookie(w, oauthVerifierCookie, "", -1)
+
+	if reason := query.Get("error"); reason != "" {
+		h.logger.Info().Str("reason", reason).Msg("Google sign-in not completed")
+		respond(w, r, http.StatusUnauthorized, dto.ErrorResponse{
+			Error: "Sign-in with Google was cancelled or denied",
+		})
+		return
+	}
+
+	if state == nil || verifier == nil || query.Get("state") == "" ||
+		subtle.ConstantTimeCompare([]byte(state.Value), []byte(query.Get("state"))) != 1 {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Invalid or expired sign-in request, please try again",
+		})
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Missing authorization code",
+		})
+		return
+	}
+
+	identity, err := h.google.Identity(ctx, code, verifier.Value)
+	if err != nil {
+		if errors.Is(err, oauth.ErrExchangeFailed) {
+			h.logger.Warn().Err(err).Msg("Google sign-in exchange failed")
+			respond(w, r, http.StatusBadGateway, dto.ErrorResponse{
+				Error: "Could not complete sign-in with Google",
+			})
+			return
+		}
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	user, token, expiresAt, err := h.auth.authService.LoginWithProvider(ctx, identity)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	h.auth.respondLogin(w, r, user, token, expiresAt, false)
+}
+
+// setFlowCookie sets or, with a negative maxAge, clears a flow cookie.
+// Lax is required: the callback is a top-level navigation from Google.
+func (h *OAuthHandler) setFlowCookie(w http.ResponseWriter, name, value string, maxAge time.Duration) {
+	seconds := int(maxAge.Seconds())
+	if maxAge < 0 {
+		seconds = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/api/v1/auth/google",
+		MaxAge:   seconds,
+		Secure:   h.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// randomToken returns 32 random bytes, base64url encoded. That is also a
+// valid PKCE verifier.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
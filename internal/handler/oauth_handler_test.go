@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/validator"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubProvider records whether the code exchange was attempted
+type stubProvider struct {
+	exchanged bool
+}
+
+func (p *stubProvider) AuthCodeURL(state, verifier string) string {
+	return "https://accounts.example.com/auth?state=" + state
+}
+
+func (p *stubProvider) Identity(ctx context.Context, code, verifier string) (domain.ExternalIdentity, error) {
+	p.exchanged = true
+	return domain.ExternalIdentity{}, nil
+}
+
+func newTestOAuthHandler(provider IdentityProvider) *OAuthHandler {
+	logger := zerolog.Nop()
+	auth := NewAuthHandler(nil, nil, &logger, validator.DefaultRules(), false, false, false, DefaultMaxBodyBytes, middleware.CookieAuth{}, nil)
+	return NewOAuthHandler(auth, provider, &logger, true)
+}
+
+func TestGoogleStartSetsFlowCookies(t *testing.T) {
+	h := newTestOAuthHandler(&stubProvider{})
+	rec := httptest.NewRecorder()
+
+	h.GoogleStart(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/google", nil))
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+
+	cookies := map[string]*http.Cookie{}
+	for _, c := range rec.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+	if assert.Contains(t, cookies, oauthStateCookie) && assert.Contains(t, cookies, oauthVerifierCookie) {
+		assert.True(t, cookies[oauthStateCookie].HttpOnly)
+		assert.True(t, cookies[oauthStateCookie].Secure)
+		assert.Contains(t, rec.Header().Get("Location"), "state="+cookies[oauthStateCookie].Value)
+	}
+}
+
+func TestGoogleCallbackRejectsBadState(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		cookie string
+	}{
+		{"state mismatch", "?state=other&code=abc", "expected"},
+		{"missing cookie", "?state=expected&code=abc", ""},
+		{"missing state", "?code=abc", "expected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &stubProvider{}
+			h := newTestOAuthHandler(provider)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/google/callback"+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oauthStateCookie, Value: tt.cookie})
+				req.AddCookie(&http.Cookie{Name: oauthVerifierCookie, Value: "verifier"})
+			}
+			rec := httptest.NewRecorder()
+
+			h.GoogleCallback(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.False(t, provider.exchanged, "code must not be exchanged")
+		})
+	}
+}
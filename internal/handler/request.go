@@ -0,0 +1,172 @@
+// Package handler decodes JSON request bodies
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"user-auth-app/internal/handler/dto"
+)
+
+// DefaultMaxBodyBytes is the request body limit used when none is configured
+const DefaultMaxBodyBytes = 1 << 20
+
+var (
+	errBodyTooLarge  = errors.New("request body too large")
+	errUnknownField  = errors.New("unknown field")
+	errMalformedJSON = errors.New("malformed JSON")
+)
+
+// decodeError is a request decoding failure with a message safe to show
+// the client. It wraps one of errBodyTooLarge, errUnknownField or
+// errMalformedJSON.
+type decodeError struct {
+	kind    error
+	status  int
+	message string
+	field   string
+}
+
+func (e *decodeError) Error() string {
+	return e.message
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.kind
+}
+
+// decodeJSON decodes a single JSON value from the request body into dst.
+// The body is capped at maxBytes, unknown fields are rejected and trailing
+// data after the value is an error.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return classifyDecodeError(err, maxBytes)
+	}
+
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return classifyDecodeError(err, maxBytes)
+		}
+		return &decodeError{
+			kind:    errMalformedJSON,
+			status:  http.StatusBadRequest,
+			message: "Request body must contain a single JSON value",
+		}
+	}
+
+	return nil
+}
+
+func classifyDecodeError(err error, maxBytes int64) error {
+	var (
+		maxBytesErr *http.MaxBytesError
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+	)
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &decodeError{
+			kind:    errBodyTooLarge,
+			status:  http.StatusRequestEntityTooLarge,
+			message: fmt.Sprintf("Request body must not be larger than %d bytes", maxBytes),
+		}
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &decodeError{
+			kind:    errUnknownField,
+			status:  http.StatusBadRequest,
+			message: fmt.Sprintf("Request body contains unknown field %q", field),
+			field:   field,
+		}
+
+	case errors.As(err, &syntaxErr):
+		return &decodeError{
+			kind:    errMalformedJSON,
+			status:  http.StatusBadRequest,
+			message: fmt.Sprintf("Malformed JSON at position %d", syntaxErr.Offset),
+		}
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &decodeError{
+			kind:    errMalformedJSON,
+			status:  http.StatusBadRequest,
+			message: "Malformed JSON: unexpected end of body",
+		}
+
+	case errors.Is(err, io.EOF):
+		return &decodeError{
+			kind:    errMalformedJSON,
+			status:  http.StatusBadRequest,
+			message: "Request body must not be empty",
+		}
+
+	case errors.As(err, &typeErr):
+		return &decodeError{
+			kind:    errMalformedJSON,
+			status:  http.StatusBadRequest,
+			message: fmt.Sprintf("Field %q must be %s", typeErr.Field, jsonTypeName(typeErr.Type)),
+			field:   typeErr.Field,
+		}
+
+	default:
+		return &decodeError{
+			kind:    errMalformedJSON,
+			status:  http.StatusBadRequest,
+			message: "Invalid request body",
+		}
+	}
+}
+
+// jsonTypeName describes the JSON type that decodes into t
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	default:
+		return "a valid value"
+	}
+}
+
+// respondDecodeError sends the response for a decodeJSON failure
+func respondDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var decodeErr *decodeError
+	if !errors.As(err, &decodeErr) {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Invalid request body",
+		})
+		return
+	}
+
+	response := dto.ErrorResponse{Error: decodeErr.message}
+	if decodeErr.field != "" {
+		response.Fields = map[string]string{decodeErr.field: decodeErr.message}
+	}
+
+	respond(w, r, decodeErr.status, response)
+}
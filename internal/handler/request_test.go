@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type payload struct {
+		Email string `json:"email"`
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantErr    error
+		wantStatus int
+	}{
+		{"valid", `{"email":"a@example.com"}`, nil, 0},
+		{"too large", `{"email":"` + strings.Repeat("a", 100) + `"}`, errBodyTooLarge, http.StatusRequestEntityTooLarge},
+		{"unknown field", `{"email":"a@example.com","passwrd":"x"}`, errUnknownField, http.StatusBadRequest},
+		{"syntax error", `{"email":}`, errMalformedJSON, http.StatusBadRequest},
+		{"truncated", `{"email":"a@example.com"`, errMalformedJSON, http.StatusBadRequest},
+		{"empty", ``, errMalformedJSON, http.StatusBadRequest},
+		{"trailing data", `{"email":"a@example.com"} {"email":"b@example.com"}`, errMalformedJSON, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			var dst payload
+			err := decodeJSON(rec, req, &dst, 64)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				assert.Equal(t, "a@example.com", dst.Email)
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			respondDecodeError(rec, req, err)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
@@ -2,9 +2,9 @@
 package handler
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 
 	"user-auth-app/internal/domain"
 	"user-auth-app/internal/handler/dto"
@@ -13,17 +13,20 @@ import (
 	"github.com/rs/zerolog"
 )
 
-// respondJSON sends a JSON response
-func respondJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+// respond sends a response encoded in the format the request accepts,
+// JSON unless it asks for MessagePack
+func respond(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	c := negotiate(r)
+	w.Header().Set("Content-Type", c.ContentType())
+	w.Header().Add("Vary", "Accept")
 	w.WriteHeader(status)
 	if data != nil {
-		json.NewEncoder(w).Encode(data)
+		c.Encode(w, data)
 	}
 }
 
 // respondError sends an error response
-func respondError(w http.ResponseWriter, logger *zerolog.Logger, err error) {
+func respondError(w http.ResponseWriter, r *http.Request, logger *zerolog.Logger, err error) {
 	statusCode := domain.HTTPStatusCode(err)
 	message := domain.ErrorMessage(err)
 
@@ -33,6 +36,18 @@ func respondError(w http.ResponseWriter, logger *zerolog.Logger, err error) {
 		message = "An internal error occurred"
 	}
 
+	// Timeouts usually mean a slow database, so keep them visible
+	if statusCode == http.StatusGatewayTimeout {
+		logger.Warn().Err(err).Msg("Request timed out")
+	}
+
+	// Outages are transient, so tell clients and load balancers when to
+	// come back rather than reporting a bug
+	if retryAfter := domain.RetryAfter(err); retryAfter > 0 {
+		logger.Warn().Err(err).Msg("Dependency unavailable")
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+	}
+
 	var appErr *domain.AppError
 	var response dto.ErrorResponse
 
@@ -47,13 +62,18 @@ func respondError(w http.ResponseWriter, logger *zerolog.Logger, err error) {
 		}
 	}
 
-	respondJSON(w, statusCode, response)
+	respond(w, r, statusCode, response)
 }
 
-// respondValidationError sends a validation error response
-func respondValidationError(w http.ResponseWriter, errors []validator.ValidationError) {
+// respondValidationError sends a validation error response. Multiple
+// errors for the same field are joined with "; ".
+func respondValidationError(w http.ResponseWriter, r *http.Request, errors []validator.ValidationError) {
 	fields := make(map[string]string, len(errors))
 	for _, err := range errors {
+		if existing, ok := fields[err.Field]; ok {
+			fields[err.Field] = existing + "; " + err.Message
+			continue
+		}
 		fields[err.Field] = err.Message
 	}
 
@@ -62,5 +82,5 @@ func respondValidationError(w http.ResponseWriter, errors []validator.Validation
 		Fields: fields,
 	}
 
-	respondJSON(w, http.StatusBadRequest, response)
+	respond(w, r, http.StatusBadRequest, response)
 }
\ No newline at end of file
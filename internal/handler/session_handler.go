@@ -0,0 +1,66 @@
+// Package handler implements the session management endpoints
+package handler
+
+import (
+	"net/http"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+type SessionHandler struct {
+	sessions service.SessionService
+	logger   *zerolog.Logger
+}
+
+// NewSessionHandler creates a handler for users to manage their sessions
+func NewSessionHandler(sessions service.SessionService, logger *zerolog.Logger) *SessionHandler {
+	return &SessionHandler{
+		sessions: sessions,
+		logger:   logger,
+	}
+}
+
+// ListSessions lists the authenticated user's active sessions, marking
+// the one making the request
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, r, h.logger, domain.ErrUnauthorized)
+		return
+	}
+
+	sessions, err := h.sessions.ListSessions(ctx, claims.UserID)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, dto.ToSessionsResponse(sessions, claims.SessionID))
+}
+
+// RevokeSession signs the authenticated user out of one of their
+// sessions; revoking the requesting token's own session logs out
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, r, h.logger, domain.ErrUnauthorized)
+		return
+	}
+
+	if err := h.sessions.RevokeSession(ctx, claims.UserID, chi.URLParam(r, "id"), claims.SessionID); err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSessionService serves a fixed set of sessions
+type fakeSessionService struct {
+	sessions map[string]domain.Session
+}
+
+func (s fakeSessionService) ListSessions(ctx context.Context, userID int32) ([]domain.Session, error) {
+	var sessions []domain.Session
+	for _, id := range []string{"laptop", "phone"} {
+		if session, ok := s.sessions[id]; ok && session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (s fakeSessionService) RevokeSession(ctx context.Context, userID int32, sessionID, currentSessionID string) error {
+	if session, ok := s.sessions[sessionID]; !ok || session.UserID != userID {
+		return domain.ErrSessionNotFound
+	}
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+func TestSessionEndpoints(t *testing.T) {
+	logger := zerolog.Nop()
+	now := time.Now()
+	h := NewSessionHandler(fakeSessionService{sessions: map[string]domain.Session{
+		"laptop": {ID: "laptop", UserID: 1, IPAddress: "203.0.113.7", UserAgent: "Firefox", CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)},
+		"phone":  {ID: "phone", UserID: 1, UserAgent: "Safari", CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)},
+	}}, &logger)
+	claims := &service.TokenClaims{UserID: 1, Scope: service.ScopeFull, SessionID: "phone"}
+
+	request := func(method, id string) *http.Request {
+		req := httptest.NewRequest(method, "/api/v1/users/me/sessions/"+id, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+		return req.WithContext(context.WithValue(ctx, middleware.UserContextKey, claims))
+	}
+
+	list := func() dto.SessionsResponse {
+		rec := httptest.NewRecorder()
+		h.ListSessions(rec, request(http.MethodGet, ""))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp dto.SessionsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := list()
+	require.Len(t, resp.Sessions, 2)
+	assert.Equal(t, "laptop", resp.Sessions[0].ID)
+	assert.Equal(t, "Firefox", resp.Sessions[0].UserAgent)
+	assert.False(t, resp.Sessions[0].Current)
+	assert.True(t, resp.Sessions[1].Current, "the requesting token's session is current")
+
+	revoke := func(id string) int {
+		rec := httptest.NewRecorder()
+		h.RevokeSession(rec, request(http.MethodDelete, id))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, revoke("laptop"))
+	assert.Equal(t, http.StatusNotFound, revoke("laptop"), "already revoked")
+	assert.Len(t, list().Sessions, 1)
+}
@@ -0,0 +1,61 @@
+// Package handler implements bulk user deactivation
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/middleware"
+)
+
+// DeactivateUsers deactivates up to maxBatchUsers users in one
+// transaction, revoking their tokens, sessions and API keys. IDs without
+// an active user are reported as not_found and don't stop the others.
+func (h *AdminHandler) DeactivateUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		respondError(w, r, h.logger, domain.ErrUnauthorized)
+		return
+	}
+
+	var req dto.DeactivateUsersRequest
+	if err := decodeJSON(w, r, &req, 0); err != nil {
+		respondDecodeError(w, r, err)
+		return
+	}
+
+	if len(req.UserIDs) == 0 || len(req.UserIDs) > maxBatchUsers {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: fmt.Sprintf("Between 1 and %d user IDs are required", maxBatchUsers),
+		})
+		return
+	}
+
+	results, err := h.authService.DeactivateUsers(ctx, claims.UserID, req.UserIDs)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	response := dto.DeactivateUsersResponse{
+		Results: make([]dto.DeactivateUserResult, len(results)),
+	}
+	for i, result := range results {
+		response.Results[i] = dto.DeactivateUserResult{
+			UserID: result.UserID,
+			Status: dto.DeactivationStatusDeactivated,
+		}
+		if result.Err != nil {
+			response.Results[i].Status = dto.DeactivationStatusNotFound
+			response.NotFound++
+			continue
+		}
+		response.Deactivated++
+	}
+
+	respond(w, r, http.StatusOK, response)
+}
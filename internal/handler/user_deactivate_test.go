@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/service"
+	"user-auth-app/internal/validator"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bulkDeactivateAuthService deactivates the users it knows, recording the
+// admin who asked
+type bulkDeactivateAuthService struct {
+	service.AuthService
+	active  map[int32]bool
+	adminID int32
+}
+
+func (s *bulkDeactivateAuthService) DeactivateUsers(ctx context.Context, adminID int32, userIDs []int32) ([]domain.UserDeactivationResult, error) {
+	s.adminID = adminID
+	results := make([]domain.UserDeactivationResult, len(userIDs))
+	for i, id := range userIDs {
+		results[i].UserID = id
+		if !s.active[id] {
+			results[i].Err = domain.ErrUserNotFound
+			continue
+		}
+		s.active[id] = false
+	}
+	return results, nil
+}
+
+func deactivateUsers(auth service.AuthService, body string) *httptest.ResponseRecorder {
+	logger := zerolog.Nop()
+	h := NewAdminHandler(auth, nil, nil, &logger, validator.DefaultRules(), false, 0)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/deactivate", strings.NewReader(body))
+	claims := &service.TokenClaims{UserID: 9}
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, claims))
+	rec := httptest.NewRecorder()
+	h.DeactivateUsers(rec, req)
+	return rec
+}
+
+func TestDeactivateUsers(t *testing.T) {
+	auth := &bulkDeactivateAuthService{active: map[int32]bool{1: true, 2: true}}
+	rec := deactivateUsers(auth, `{"user_ids": [1, 3, 2]}`)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int32(9), auth.adminID)
+
+	var response dto.DeactivateUsersResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, dto.DeactivateUsersResponse{
+		Deactivated: 2,
+		NotFound:    1,
+		Results: []dto.DeactivateUserResult{
+			{UserID: 1, Status: dto.DeactivationStatusDeactivated},
+			{UserID: 3, Status: dto.DeactivationStatusNotFound},
+			{UserID: 2, Status: dto.DeactivationStatusDeactivated},
+		},
+	}, response)
+}
+
+func TestDeactivateUsersRejectsBadBatches(t *testing.T) {
+	ids := make([]string, maxBatchUsers+1)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i + 1)
+	}
+
+	tests := map[string]string{
+		"empty":     `{"user_ids": []}`,
+		"missing":   `{}`,
+		"too many":  `{"user_ids": [` + strings.Join(ids, ",") + `]}`,
+		"malformed": `{"user_ids": ["one"]}`,
+		"unknown":   `{"ids": [1]}`,
+	}
+
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			auth := &bulkDeactivateAuthService{active: map[int32]bool{1: true}}
+			rec := deactivateUsers(auth, body)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.True(t, auth.active[1], "nothing is deactivated")
+		})
+	}
+}
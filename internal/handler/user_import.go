@@ -0,0 +1,342 @@
+// Package handler implements bulk user import
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/validator"
+)
+
+// DefaultMaxImportUsers caps a bulk import when no limit is configured
+const DefaultMaxImportUsers = 1000
+
+// maxImportRowBytes bounds the request body at this many bytes per user
+const maxImportRowBytes = 1024
+
+// Import modes
+const (
+	importModeAtomic     = "atomic"
+	importModeBestEffort = "best_effort"
+)
+
+// importColumns are the CSV columns an import may use
+var importColumns = map[string]bool{
+	"username":      true,
+	"email":         true,
+	"role":          true,
+	"password_hash": true,
+}
+
+// ImportUsers creates users in bulk from a JSON array or a CSV file
+// (Content-Type: text/csv) with a header row. By default the import is
+// atomic: every user is created or none are. With ?mode=best_effort each
+// user succeeds or fails on its own. The response has a result per user.
+// Users without a password hash get no password; the response reports
+// whether they were sent a password setup email.
+func (h *AdminHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = importModeAtomic
+	}
+	if mode != importModeAtomic && mode != importModeBestEffort {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "mode must be atomic or best_effort",
+		})
+		return
+	}
+
+	rows, err := readUserImport(w, r, h.maxImportUsers)
+	if err != nil {
+		respondDecodeError(w, r, err)
+		return
+	}
+	if len(rows) == 0 {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "No users to import",
+		})
+		return
+	}
+
+	results := make([]dto.ImportUserResult, len(rows))
+	users := make([]domain.UserImport, 0, len(rows))
+	positions := make([]int, 0, len(rows))
+	for i, row := range rows {
+		results[i].Row = i + 1
+
+		v := validator.NewWithRules(h.rules)
+		v.ValidateUsername("username", row.Username)
+		v.ValidateEmail("email", row.Email)
+		v.ValidateRole("role", row.Role)
+		if !v.Valid() {
+			results[i].Status = dto.ImportStatusFailed
+			results[i].Error = "validation failed"
+			results[i].Fields = importFieldErrors(v.Errors())
+			continue
+		}
+
+		users = append(users, domain.UserImport{
+			Username:     row.Username,
+			Email:        row.Email,
+			Role:         row.Role,
+			PasswordHash: row.PasswordHash,
+		})
+		positions = append(positions, i)
+	}
+
+	atomic := mode == importModeAtomic
+	if atomic && len(users) < len(rows) {
+		for _, i := range positions {
+			results[i].Status = dto.ImportStatusNotImported
+			results[i].Error = domain.ErrorMessage(domain.ErrNotImported)
+		}
+		respond(w, r, http.StatusBadRequest, importResponse(mode, results))
+		return
+	}
+
+	statusCode := http.StatusCreated
+	if !atomic {
+		statusCode = http.StatusOK
+	}
+
+	if len(users) > 0 {
+		ctx := r.Context()
+
+		imported, err := h.authService.ImportUsers(ctx, users, atomic)
+		if err != nil {
+			respondError(w, r, h.logger, err)
+			return
+		}
+
+		for j, result := range imported {
+			i := positions[j]
+			if result.Err == nil {
+				user := dto.ToUserResponse(result.User)
+				results[i].Status = dto.ImportStatusCreated
+				results[i].User = &user
+				if users[j].PasswordHash == "" {
+					results[i].PasswordSetup = dto.PasswordSetupFailed
+					if result.PasswordSetupQueued {
+						results[i].PasswordSetup = dto.PasswordSetupQueued
+					}
+				}
+				continue
+			}
+
+			results[i].Status = dto.ImportStatusFailed
+			if errors.Is(result.Err, domain.ErrNotImported) {
+				results[i].Status = dto.ImportStatusNotImported
+			} else if atomic {
+				// The user that failed the batch decides the status code
+				statusCode = domain.HTTPStatusCode(result.Err)
+			}
+
+			results[i].Error = domain.ErrorMessage(result.Err)
+			if domain.HTTPStatusCode(result.Err) >= http.StatusInternalServerError {
+				results[i].Error = "An internal error occurred"
+			}
+			var appErr *domain.AppError
+			if errors.As(result.Err, &appErr) {
+				results[i].Fields = appErr.Fields
+			}
+		}
+	}
+
+	respond(w, r, statusCode, importResponse(mode, results))
+}
+
+func importResponse(mode string, results []dto.ImportUserResult) dto.ImportUsersResponse {
+	response := dto.ImportUsersResponse{Mode: mode, Results: results}
+	for _, result := range results {
+		if result.Status == dto.ImportStatusCreated {
+			response.Created++
+		} else {
+			response.Failed++
+		}
+	}
+	return response
+}
+
+// importFieldErrors maps each invalid field to its messages
+func importFieldErrors(errs []validator.ValidationError) map[string]string {
+	fields := make(map[string]string, len(errs))
+	for _, err := range errs {
+		if existing, ok := fields[err.Field]; ok {
+			fields[err.Field] = existing + "; " + err.Message
+			continue
+		}
+		fields[err.Field] = err.Message
+	}
+	return fields
+}
+
+// readUserImport streams the users out of the request body, stopping as
+// soon as there are more than maxUsers
+func readUserImport(w http.ResponseWriter, r *http.Request, maxUsers int) ([]dto.ImportUserRequest, error) {
+	if maxUsers <= 0 {
+		maxUsers = DefaultMaxImportUsers
+	}
+	maxBytes := int64(maxUsers) * maxImportRowBytes
+	body := http.MaxBytesReader(w, r.Body, maxBytes)
+
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, unsupportedImportType()
+		}
+		mediaType = parsed
+	}
+
+	switch mediaType {
+	case "application/json":
+		return readJSONImport(body, maxUsers, maxBytes)
+	case "text/csv":
+		return readCSVImport(body, maxUsers, maxBytes)
+	default:
+		return nil, unsupportedImportType()
+	}
+}
+
+func readJSONImport(body io.Reader, maxUsers int, maxBytes int64) ([]dto.ImportUserRequest, error) {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		if err != nil {
+			return nil, classifyDecodeError(err, maxBytes)
+		}
+		return nil, &decodeError{
+			kind:    errMalformedJSON,
+			status:  http.StatusBadRequest,
+			message: "Request body must be a JSON array of users",
+		}
+	}
+
+	var rows []dto.ImportUserRequest
+	for dec.More() {
+		if len(rows) == maxUsers {
+			return nil, tooManyImportUsers(maxUsers)
+		}
+
+		var row dto.ImportUserRequest
+		if err := dec.Decode(&row); err != nil {
+			return nil, classifyDecodeError(err, maxBytes)
+		}
+		rows = append(rows, row)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, classifyDecodeError(err, maxBytes)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, &decodeError{
+			kind:    errMalformedJSON,
+			status:  http.StatusBadRequest,
+			message: "Request body must contain a single JSON value",
+		}
+	}
+
+	return rows, nil
+}
+
+func readCSVImport(body io.Reader, maxUsers int, maxBytes int64) ([]dto.ImportUserRequest, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, csvError(err, maxBytes)
+	}
+
+	columns := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !importColumns[name] || seen[name] {
+			return nil, &decodeError{
+				kind:    errUnknownField,
+				status:  http.StatusBadRequest,
+				message: fmt.Sprintf("CSV header contains unknown or repeated column %q", name),
+				field:   name,
+			}
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+
+	var rows []dto.ImportUserRequest
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, csvError(err, maxBytes)
+		}
+		if len(rows) == maxUsers {
+			return nil, tooManyImportUsers(maxUsers)
+		}
+
+		var row dto.ImportUserRequest
+		for i, value := range record {
+			switch columns[i] {
+			case "username":
+				row.Username = value
+			case "email":
+				row.Email = value
+			case "role":
+				row.Role = value
+			case "password_hash":
+				row.PasswordHash = value
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func csvError(err error, maxBytes int64) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return classifyDecodeError(err, maxBytes)
+	}
+
+	message := "Malformed CSV"
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		message = fmt.Sprintf("Malformed CSV on line %d: %v", parseErr.Line, parseErr.Err)
+	}
+	return &decodeError{
+		kind:    errMalformedJSON,
+		status:  http.StatusBadRequest,
+		message: message,
+	}
+}
+
+func tooManyImportUsers(maxUsers int) error {
+	return &decodeError{
+		kind:    errBodyTooLarge,
+		status:  http.StatusRequestEntityTooLarge,
+		message: fmt.Sprintf("An import may contain at most %d users", maxUsers),
+	}
+}
+
+func unsupportedImportType() error {
+	return &decodeError{
+		kind:    errMalformedJSON,
+		status:  http.StatusUnsupportedMediaType,
+		message: "Content-Type must be application/json or text/csv",
+	}
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/service"
+	"user-auth-app/internal/validator"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// importAuthService records imported users and fails those whose email
+// is in failEmails
+type importAuthService struct {
+	service.AuthService
+	imported   []domain.UserImport
+	atomic     bool
+	failEmails map[string]error
+}
+
+func (s *importAuthService) ImportUsers(ctx context.Context, users []domain.UserImport, atomic bool) ([]domain.UserImportResult, error) {
+	s.imported = users
+	s.atomic = atomic
+
+	results := make([]domain.UserImportResult, len(users))
+	failed := false
+	for i, user := range users {
+		if err, ok := s.failEmails[user.Email]; ok {
+			results[i].Err = err
+			failed = true
+			continue
+		}
+		results[i].User = domain.User{ID: int32(i + 1), Username: user.Username, Email: user.Email}
+		results[i].PasswordSetupQueued = user.PasswordHash == ""
+	}
+	if atomic && failed {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = domain.UserImportResult{Err: domain.ErrNotImported}
+			}
+		}
+	}
+	return results, nil
+}
+
+func importUsers(t *testing.T, auth service.AuthService, maxUsers int, query, contentType, body string) (*httptest.ResponseRecorder, dto.ImportUsersResponse) {
+	t.Helper()
+	logger := zerolog.Nop()
+	h := NewAdminHandler(auth, nil, nil, &logger, validator.DefaultRules(), false, maxUsers)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/import"+query, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.ImportUsers(rec, req)
+
+	var response dto.ImportUsersResponse
+	if rec.Code < 300 || strings.Contains(rec.Body.String(), `"results"`) {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	}
+	return rec, response
+}
+
+func TestImportUsersJSON(t *testing.T) {
+	auth := &importAuthService{}
+	rec, response := importUsers(t, auth, 10, "", "application/json", `[
+		{"username": "alice", "email": "alice@example.com"},
+		{"username": "bob", "email": "bob@example.com", "role": "admin", "password_hash": "$2a$10$abc"}
+	]`)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.True(t, auth.atomic)
+	require.Len(t, auth.imported, 2)
+	assert.Equal(t, "$2a$10$abc", auth.imported[1].PasswordHash)
+
+	assert.Equal(t, "atomic", response.Mode)
+	assert.Equal(t, 2, response.Created)
+	require.Len(t, response.Results, 2)
+	assert.Equal(t, 1, response.Results[0].Row)
+	assert.Equal(t, dto.ImportStatusCreated, response.Results[0].Status)
+	assert.Equal(t, dto.PasswordSetupQueued, response.Results[0].PasswordSetup)
+	assert.Empty(t, response.Results[1].PasswordSetup, "bob keeps his password")
+	assert.Equal(t, "alice", response.Results[0].User.Username)
+	assert.NotContains(t, rec.Body.String(), "password\":", "no credentials are returned")
+}
+
+func TestImportUsersCSV(t *testing.T) {
+	auth := &importAuthService{}
+	csv := "Email,username,role\nalice@example.com,alice,user\nbob@example.com,bob,\n"
+	rec, response := importUsers(t, auth, 10, "", "text/csv; charset=utf-8", csv)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	require.Len(t, auth.imported, 2)
+	assert.Equal(t, domain.UserImport{Username: "alice", Email: "alice@example.com", Role: "user"}, auth.imported[0])
+	assert.Equal(t, "bob", auth.imported[1].Username)
+	assert.Equal(t, 2, response.Created)
+
+	t.Run("unknown column", func(t *testing.T) {
+		rec, _ := importUsers(t, &importAuthService{}, 10, "", "text/csv", "username,email,password\nalice,alice@example.com,secret\n")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("wrong field count", func(t *testing.T) {
+		rec, _ := importUsers(t, &importAuthService{}, 10, "", "text/csv", "username,email\nalice\n")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "line 2")
+	})
+}
+
+func TestImportUsersAtomicFailures(t *testing.T) {
+	body := `[
+		{"username": "alice", "email": "alice@example.com"},
+		{"username": "bob", "email": "not-an-email"}
+	]`
+
+	t.Run("validation failure imports nothing", func(t *testing.T) {
+		auth := &importAuthService{}
+		rec, response := importUsers(t, auth, 10, "", "", body)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Nil(t, auth.imported, "service should not be called")
+		assert.Equal(t, 0, response.Created)
+		assert.Equal(t, dto.ImportStatusNotImported, response.Results[0].Status)
+		assert.Equal(t, dto.ImportStatusFailed, response.Results[1].Status)
+		assert.Contains(t, response.Results[1].Fields, "email")
+	})
+
+	t.Run("duplicate rolls back", func(t *testing.T) {
+		auth := &importAuthService{failEmails: map[string]error{"bob@example.com": domain.ErrDuplicateEmail}}
+		rec, response := importUsers(t, auth, 10, "", "", strings.Replace(body, "not-an-email", "bob@example.com", 1))
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, dto.ImportStatusNotImported, response.Results[0].Status)
+		assert.Empty(t, response.Results[0].PasswordSetup)
+		assert.Equal(t, dto.ImportStatusFailed, response.Results[1].Status)
+		assert.Equal(t, "Email already exists", response.Results[1].Error)
+	})
+}
+
+func TestImportUsersBestEffort(t *testing.T) {
+	auth := &importAuthService{failEmails: map[string]error{"carol@example.com": domain.ErrDuplicateEmail}}
+	rec, response := importUsers(t, auth, 10, "?mode=best_effort", "", `[
+		{"username": "alice", "email": "alice@example.com"},
+		{"username": "bob", "email": "not-an-email"},
+		{"username": "carol", "email": "carol@example.com"}
+	]`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, auth.atomic)
+	require.Len(t, auth.imported, 2, "invalid rows are not sent to the service")
+	assert.Equal(t, 1, response.Created)
+	assert.Equal(t, 2, response.Failed)
+	assert.Equal(t, dto.ImportStatusCreated, response.Results[0].Status)
+	assert.Equal(t, dto.ImportStatusFailed, response.Results[1].Status)
+	assert.Equal(t, 3, response.Results[2].Row)
+	assert.Equal(t, dto.ImportStatusFailed, response.Results[2].Status)
+}
+
+func TestImportUsersRejectsBadRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"too many users", "", "", `[{"username":"a1","email":"a1@example.com"},{"username":"a2","email":"a2@example.com"},{"username":"a3","email":"a3@example.com"}]`, http.StatusRequestEntityTooLarge},
+		{"too many csv rows", "", "text/csv", "username,email\na1,a1@example.com\na2,a2@example.com\na3,a3@example.com\n", http.StatusRequestEntityTooLarge},
+		{"not an array", "", "", `{"username":"alice"}`, http.StatusBadRequest},
+		{"unknown field", "", "", `[{"username":"alice","password":"secret"}]`, http.StatusBadRequest},
+		{"empty", "", "", `[]`, http.StatusBadRequest},
+		{"unsupported type", "", "application/xml", `<users/>`, http.StatusUnsupportedMediaType},
+		{"unknown mode", "?mode=some", "", `[]`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := &importAuthService{}
+			rec, _ := importUsers(t, auth, 2, tt.query, tt.contentType, tt.body)
+			assert.Equal(t, tt.want, rec.Code)
+			assert.Nil(t, auth.imported)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Package handler reports request validation failures
+package handler
+
+import (
+	"net/http"
+
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/validator"
+
+	"github.com/rs/zerolog"
+)
+
+// validationReporter turns validation failures into an abuse-detection
+// signal. Only endpoint, field names and failure codes are recorded; the
+// submitted values (passwords, emails) never are.
+type validationReporter struct {
+	logger *zerolog.Logger
+
+	// verbose logs each failure at info instead of debug
+	verbose bool
+}
+
+// respond records the failures and sends the validation error response
+func (v validationReporter) respond(w http.ResponseWriter, r *http.Request, errs []validator.ValidationError) {
+	v.report(r, errs)
+	respondValidationError(w, r, errs)
+}
+
+func (v validationReporter) report(r *http.Request, errs []validator.ValidationError) {
+	endpoint := middleware.RoutePattern(r)
+	for _, err := range errs {
+		validationFailures.WithLabelValues(endpoint, err.Field, err.Code).Inc()
+	}
+
+	if v.logger == nil {
+		return
+	}
+
+	level := zerolog.DebugLevel
+	if v.verbose {
+		level = zerolog.InfoLevel
+	}
+
+	failures := make([]string, 0, len(errs))
+	for _, err := range errs {
+		failures = append(failures, err.Field+":"+err.Code)
+	}
+
+	v.logger.WithLevel(level).
+		Str("endpoint", endpoint).
+		Str("ip", audit.ClientFromContext(r.Context()).IPAddress).
+		Strs("failures", failures).
+		Msg("Request validation failed")
+}
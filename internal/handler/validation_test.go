@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/validator"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationFailuresNeverLogValues(t *testing.T) {
+	const (
+		username = "x!"
+		email    = "probe-target@@example.com"
+		password = "hunter2secret"
+	)
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	h := NewAuthHandler(nil, nil, &logger, validator.DefaultRules(), false, false, true, DefaultMaxBodyBytes, middleware.CookieAuth{}, nil)
+
+	r := chi.NewRouter()
+	r.Post("/api/v1/register", h.Register)
+
+	before := testutil.ToFloat64(validationFailures.WithLabelValues("/api/v1/register", "email", validator.CodeInvalidFormat))
+
+	body := `{"username":"` + username + `","email":"` + email + `","password":"` + password + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/register", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	logged := buf.String()
+	assert.Contains(t, logged, `"endpoint":"/api/v1/register"`)
+	assert.Contains(t, logged, "email:"+validator.CodeInvalidFormat)
+	assert.Contains(t, logged, "password:"+validator.CodeMissingUpper)
+	for _, value := range []string{username, email, password} {
+		assert.NotContains(t, logged, value)
+	}
+
+	after := testutil.ToFloat64(validationFailures.WithLabelValues("/api/v1/register", "email", validator.CodeInvalidFormat))
+	assert.Equal(t, before+1, after)
+}
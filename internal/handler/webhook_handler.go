@@ -0,0 +1,127 @@
+// Package handler implements the webhook management endpoints
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"user-auth-app/internal/handler/dto"
+	"user-auth-app/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	MaxDeliveryLimit     = 200
+)
+
+type WebhookHandler struct {
+	webhooks service.WebhookService
+	logger   *zerolog.Logger
+
+	// maxBodyBytes caps request bodies read with decodeJSON
+	maxBodyBytes int64
+}
+
+// NewWebhookHandler creates a handler for admins to manage webhooks
+func NewWebhookHandler(webhooks service.WebhookService, logger *zerolog.Logger, maxBodyBytes int64) *WebhookHandler {
+	return &WebhookHandler{
+		webhooks:     webhooks,
+		logger:       logger,
+		maxBodyBytes: maxBodyBytes,
+	}
+}
+
+// CreateWebhook subscribes an endpoint to events. The signing secret is
+// in this response only; it can't be retrieved later.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req dto.CreateWebhookRequest
+	if err := decodeJSON(w, r, &req, h.maxBodyBytes); err != nil {
+		respondDecodeError(w, r, err)
+		return
+	}
+
+	webhook, secret, err := h.webhooks.CreateWebhook(ctx, req.URL, req.Events)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	// The secret is a credential; keep it out of caches
+	w.Header().Set("Cache-Control", "no-store")
+	respond(w, r, http.StatusCreated, dto.CreateWebhookResponse{
+		WebhookResponse: dto.ToWebhookResponse(webhook),
+		Secret:          secret,
+	})
+}
+
+// ListWebhooks lists the tenant's webhooks
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.webhooks.ListWebhooks(r.Context())
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, dto.ToWebhooksResponse(webhooks))
+}
+
+// DeleteWebhook deletes a webhook along with its delivery log
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := parseWebhookID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhooks.DeleteWebhook(r.Context(), webhookID); err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries returns a webhook's recent delivery attempts
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := parseWebhookID(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultDeliveryLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > MaxDeliveryLimit {
+			respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+				Error: "limit must be between 1 and " + strconv.Itoa(MaxDeliveryLimit),
+			})
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.webhooks.ListDeliveries(r.Context(), webhookID, limit)
+	if err != nil {
+		respondError(w, r, h.logger, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, dto.ToWebhookDeliveriesResponse(deliveries))
+}
+
+// parseWebhookID reads the webhook ID from the path, responding with 400
+// if it isn't one
+func parseWebhookID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Invalid webhook ID",
+		})
+		return 0, false
+	}
+	return int32(id), true
+}
@@ -0,0 +1,95 @@
+package hashing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2Params keep tests fast; production uses DefaultArgon2Params
+var testArgon2Params = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestArgon2idHashAndCompare(t *testing.T) {
+	h := NewArgon2idHasher(testArgon2Params)
+
+	hash, err := h.Hash("Correct-Horse-9")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"), hash)
+
+	assert.NoError(t, h.Compare(hash, "Correct-Horse-9"))
+	assert.ErrorIs(t, h.Compare(hash, "wrong-password"), ErrMismatch)
+
+	other, err := h.Hash("Correct-Horse-9")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "salt must be random")
+}
+
+func TestArgon2idRejectsMalformedHashes(t *testing.T) {
+	h := NewArgon2idHasher(testArgon2Params)
+
+	tests := []string{
+		"",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=0$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$",
+		"$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5",
+	}
+
+	for _, hash := range tests {
+		assert.Error(t, h.Compare(hash, "password"), hash)
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	weak := NewArgon2idHasher(testArgon2Params)
+	hash, err := weak.Hash("password")
+	require.NoError(t, err)
+
+	assert.False(t, weak.NeedsRehash(hash))
+	assert.True(t, NewArgon2idHasher(Argon2Params{Memory: 128, Iterations: 1, Parallelism: 1}).NeedsRehash(hash))
+	assert.True(t, NewArgon2idHasher(Argon2Params{Memory: 64, Iterations: 2, Parallelism: 1}).NeedsRehash(hash))
+}
+
+func TestDetect(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	assert.Equal(t, AlgorithmBcrypt, Detect(string(bcryptHash)))
+	assert.Equal(t, AlgorithmArgon2id, Detect("$argon2id$v=19$m=64,t=1,p=1$c2FsdA$a2V5"))
+	assert.Equal(t, "", Detect("plaintext"))
+}
+
+func TestNewVerifiesEitherAlgorithm(t *testing.T) {
+	bcryptHash, err := NewBcryptHasher(bcrypt.MinCost).Hash("password")
+	require.NoError(t, err)
+	argon2Hash, err := NewArgon2idHasher(testArgon2Params).Hash("password")
+	require.NoError(t, err)
+
+	for _, algorithm := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+		t.Run(algorithm, func(t *testing.T) {
+			h, err := New(algorithm, bcrypt.MinCost, testArgon2Params)
+			require.NoError(t, err)
+
+			assert.NoError(t, h.Compare(bcryptHash, "password"))
+			assert.NoError(t, h.Compare(argon2Hash, "password"))
+			assert.ErrorIs(t, h.Compare(bcryptHash, "wrong"), ErrMismatch)
+			assert.ErrorIs(t, h.Compare(argon2Hash, "wrong"), ErrMismatch)
+			assert.ErrorIs(t, h.Compare("plaintext", "password"), ErrUnknownAlgorithm)
+
+			hash, err := h.Hash("password")
+			require.NoError(t, err)
+			assert.Equal(t, algorithm, Detect(hash))
+
+			// Hashes from the other algorithm are migrated on login
+			assert.Equal(t, algorithm != AlgorithmBcrypt, h.NeedsRehash(bcryptHash))
+			assert.Equal(t, algorithm != AlgorithmArgon2id, h.NeedsRehash(argon2Hash))
+		})
+	}
+
+	_, err = New("md5", bcrypt.MinCost, testArgon2Params)
+	assert.Error(t, err)
+}
@@ -0,0 +1,100 @@
+// Package hashing hashes and verifies user passwords
+package hashing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Supported password hashing algorithms
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var (
+	// ErrMismatch is returned when a password doesn't match its hash
+	ErrMismatch = errors.New("password does not match hash")
+
+	// ErrUnknownAlgorithm is returned for hashes in an unrecognized format
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+)
+
+// PasswordHasher hashes passwords and verifies them against stored hashes
+type PasswordHasher interface {
+	// Hash returns an encoded hash that carries its own parameters
+	Hash(password string) (string, error)
+
+	// Compare returns nil if password matches hash and ErrMismatch if not
+	Compare(hash, password string) error
+
+	// NeedsRehash reports whether hash was produced with a different
+	// algorithm or weaker parameters than the hasher is configured for
+	NeedsRehash(hash string) bool
+}
+
+// New creates a hasher that produces hashes with the given algorithm and
+// verifies hashes from any supported algorithm, detected from the stored
+// hash's prefix. Switching algorithms therefore keeps existing hashes
+// working; they are upgraded as users log in.
+func New(algorithm string, bcryptCost int, argon2Params Argon2Params) (PasswordHasher, error) {
+	bcryptHasher := NewBcryptHasher(bcryptCost)
+	argon2Hasher := NewArgon2idHasher(argon2Params)
+
+	var primary PasswordHasher
+	switch algorithm {
+	case AlgorithmBcrypt:
+		primary = bcryptHasher
+	case AlgorithmArgon2id:
+		primary = argon2Hasher
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", algorithm)
+	}
+
+	return &detectingHasher{
+		algorithm: algorithm,
+		primary:   primary,
+		byAlgorithm: map[string]PasswordHasher{
+			AlgorithmBcrypt:   bcryptHasher,
+			AlgorithmArgon2id: argon2Hasher,
+		},
+	}, nil
+}
+
+type detectingHasher struct {
+	algorithm   string
+	primary     PasswordHasher
+	byAlgorithm map[string]PasswordHasher
+}
+
+func (h *detectingHasher) Hash(password string) (string, error) {
+	return h.primary.Hash(password)
+}
+
+func (h *detectingHasher) Compare(hash, password string) error {
+	hasher, ok := h.byAlgorithm[Detect(hash)]
+	if !ok {
+		return ErrUnknownAlgorithm
+	}
+	return hasher.Compare(hash, password)
+}
+
+func (h *detectingHasher) NeedsRehash(hash string) bool {
+	if Detect(hash) != h.algorithm {
+		return true
+	}
+	return h.primary.NeedsRehash(hash)
+}
+
+// Detect returns the algorithm that produced hash, or "" if unknown
+func Detect(hash string) string {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return AlgorithmArgon2id
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return AlgorithmBcrypt
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,131 @@
+// Package messaging implements bounded message dispatch to subscribers
+package messaging
+
+import (
+	"sync/atomic"
+	"time"
+
+	"user-auth-app/internal/domain"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// maxDeliveryAttempts is how often a handler gets a message before it
+	// is dead-lettered
+	maxDeliveryAttempts = 3
+	// retryBackoff is the pause before the second attempt, growing
+	// linearly after that
+	retryBackoff = 100 * time.Millisecond
+)
+
+// DeadLetterSuffix is appended to a subject to name the subject its
+// dead letters are published to, e.g. user.registered.dlq
+const DeadLetterSuffix = ".dlq"
+
+// DeadLetter describes a message its handler failed to process on every
+// attempt. Republishing Data to Subject replays it.
+type DeadLetter = domain.DeadLetter
+
+// dispatcher decouples the NATS callback from a subscriber's handler with
+// a bounded queue. When the handler can't keep up, new messages are
+// dropped instead of buffered, so a slow subscriber can't grow memory
+// without limit or stall the connection's other subscriptions.
+type dispatcher struct {
+	subject string
+	handler func([]byte) error
+	logger  *zerolog.Logger
+	// deadLetter receives messages that failed every attempt; nil only
+	// logs them
+	deadLetter func(DeadLetter)
+
+	queue   chan []byte
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+func newDispatcher(subject string, bufferSize int, handler func([]byte) error, deadLetter func(DeadLetter), logger *zerolog.Logger) *dispatcher {
+	d := &dispatcher{
+		subject:    subject,
+		handler:    handler,
+		logger:     logger,
+		deadLetter: deadLetter,
+		queue:      make(chan []byte, bufferSize),
+		done:       make(chan struct{}),
+	}
+
+	go d.run()
+
+	return d
+}
+
+// offer queues a message without blocking. It returns false if the
+// message was dropped because the queue is full.
+func (d *dispatcher) offer(data []byte) bool {
+	select {
+	case d.queue <- data:
+		return true
+	default:
+	}
+
+	if d.dropped.Add(1) == 1 {
+		d.logger.Warn().Str("subject", d.subject).Msg("Subscriber falling behind, dropping messages")
+	}
+	messagesDropped.WithLabelValues(d.subject).Inc()
+	return false
+}
+
+// stop ends delivery; queued messages are discarded
+func (d *dispatcher) stop() {
+	close(d.done)
+}
+
+func (d *dispatcher) run() {
+	for {
+		select {
+		case <-d.done:
+			return
+		case data := <-d.queue:
+			d.deliver(data)
+		}
+	}
+}
+
+// deliver hands a message to the handler, retrying failures with backoff
+// and dead-lettering the message once every attempt has failed
+func (d *dispatcher) deliver(data []byte) {
+	var err error
+	for attempt := 1; attempt <= maxDeliveryAttempts; attempt++ {
+		if err = d.handler(data); err == nil {
+			return
+		}
+		d.logger.Warn().
+			Err(err).
+			Str("subject", d.subject).
+			Int("attempt", attempt).
+			Msg("Message handler error")
+
+		if attempt < maxDeliveryAttempts {
+			select {
+			case <-d.done:
+				return
+			case <-time.After(time.Duration(attempt) * retryBackoff):
+			}
+		}
+	}
+
+	deadLetters.WithLabelValues(d.subject).Inc()
+	d.logger.Error().
+		Err(err).
+		Str("subject", d.subject).
+		Msg("Message failed every attempt, dead-lettering it")
+	if d.deadLetter != nil {
+		d.deadLetter(DeadLetter{
+			Subject:  d.subject,
+			Data:     data,
+			Attempts: maxDeliveryAttempts,
+			Error:    err.Error(),
+			FailedAt: time.Now().UTC(),
+		})
+	}
+}
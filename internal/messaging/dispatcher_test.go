@@ -0,0 +1,119 @@
+package messaging
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcherDropsWhenSubscriberIsSlow(t *testing.T) {
+	logger := zerolog.Nop()
+	release := make(chan struct{})
+	var handled atomic.Int64
+
+	const bufferSize = 8
+	d := newDispatcher("test.slow", bufferSize, func([]byte) error {
+		<-release
+		handled.Add(1)
+		return nil
+	}, nil, &logger)
+	defer d.stop()
+
+	// The handler blocks on the first message; everything beyond the
+	// in-flight message and a full buffer must be dropped, not queued
+	const published = 10000
+	accepted := 0
+	for i := 0; i < published; i++ {
+		if d.offer(make([]byte, 1024)) {
+			accepted++
+		}
+		assert.LessOrEqual(t, len(d.queue), bufferSize)
+	}
+
+	assert.LessOrEqual(t, accepted, bufferSize+1)
+	assert.Equal(t, uint64(published-accepted), d.dropped.Load())
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return handled.Load() == int64(accepted)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDispatcherDeliversInOrder(t *testing.T) {
+	logger := zerolog.Nop()
+	received := make(chan byte, 16)
+
+	d := newDispatcher("test.order", 16, func(data []byte) error {
+		received <- data[0]
+		return nil
+	}, nil, &logger)
+	defer d.stop()
+
+	for i := byte(0); i < 10; i++ {
+		assert.True(t, d.offer([]byte{i}))
+	}
+
+	for i := byte(0); i < 10; i++ {
+		select {
+		case got := <-received:
+			assert.Equal(t, i, got)
+		case <-time.After(time.Second):
+			t.Fatal("message not delivered")
+		}
+	}
+}
+
+func TestDispatcherDeadLettersAfterRetries(t *testing.T) {
+	logger := zerolog.Nop()
+	var attempts atomic.Int64
+	dead := make(chan DeadLetter, 1)
+
+	d := newDispatcher("test.fail", 4, func([]byte) error {
+		attempts.Add(1)
+		return errors.New("smtp unavailable")
+	}, func(dl DeadLetter) { dead <- dl }, &logger)
+	defer d.stop()
+
+	assert.True(t, d.offer([]byte("payload")))
+
+	select {
+	case dl := <-dead:
+		assert.Equal(t, "test.fail", dl.Subject)
+		assert.Equal(t, []byte("payload"), dl.Data)
+		assert.Equal(t, int32(maxDeliveryAttempts), dl.Attempts)
+		assert.Equal(t, "smtp unavailable", dl.Error)
+		assert.False(t, dl.FailedAt.IsZero())
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not dead-lettered")
+	}
+	assert.Equal(t, int64(maxDeliveryAttempts), attempts.Load())
+}
+
+func TestDispatcherRetriesBeforeDeadLettering(t *testing.T) {
+	logger := zerolog.Nop()
+	var attempts atomic.Int64
+	handled := make(chan struct{})
+	dead := make(chan DeadLetter, 1)
+
+	d := newDispatcher("test.flaky", 4, func([]byte) error {
+		if attempts.Add(1) == 1 {
+			return errors.New("temporary failure")
+		}
+		close(handled)
+		return nil
+	}, func(dl DeadLetter) { dead <- dl }, &logger)
+	defer d.stop()
+
+	assert.True(t, d.offer([]byte("payload")))
+
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("message not retried")
+	}
+	assert.Empty(t, dead)
+}
@@ -1,11 +1,19 @@
 // Package messaging provides message broker abstraction
 package messaging
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 // ErrBrokerUnavailable indicates the message broker is unavailable
 var ErrBrokerUnavailable = errors.New("message broker unavailable")
 
+// DeadLetterStore keeps dead letters until an admin replays them
+type DeadLetterStore interface {
+	SaveDeadLetter(ctx context.Context, dl DeadLetter) error
+}
+
 // Broker defines the message broker interface
 type Broker interface {
 	// Publish publishes a message to a subject
@@ -20,6 +28,10 @@ type Broker interface {
 	// Close closes the broker connection
 	Close() error
 
-	// IsAvailable checks if the broker is available
+	// IsAvailable reports whether published messages will be delivered,
+	// right away or once a dropped connection is back
 	IsAvailable() bool
+
+	// IsConnected reports whether the broker is connected right now
+	IsConnected() bool
 }
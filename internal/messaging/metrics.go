@@ -0,0 +1,54 @@
+// Package messaging defines broker metrics
+package messaging
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	messagesDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nats_messages_dropped_total",
+			Help: "Total number of messages dropped because a subscriber could not keep up",
+		},
+		[]string{"subject"},
+	)
+
+	deadLetters = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nats_dead_letters_total",
+			Help: "Total number of messages dead-lettered after their handler failed every attempt",
+		},
+		[]string{"subject"},
+	)
+
+	publishFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nats_publish_failures_total",
+			Help: "Total number of messages that could not be published or buffered",
+		},
+		[]string{"subject"},
+	)
+
+	natsConnected = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nats_connected",
+			Help: "Whether the NATS connection is up (1) or down (0)",
+		},
+	)
+
+	natsBufferedMessages = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nats_buffered_messages",
+			Help: "Messages published while disconnected, waiting to be sent on reconnect",
+		},
+	)
+
+	slowConsumerEvents = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nats_slow_consumer_events_total",
+			Help: "Total number of slow consumer errors reported by the NATS client",
+		},
+	)
+)
@@ -2,24 +2,61 @@
 package messaging
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/rs/zerolog"
 )
 
+const (
+	// reconnectWait is the pause between attempts to reach NATS
+	reconnectWait = 2 * time.Second
+	// deadLetterSaveTimeout bounds storing a dead letter
+	deadLetterSaveTimeout = 5 * time.Second
+)
+
 type natsBroker struct {
 	conn      *nats.Conn
 	logger    *zerolog.Logger
 	available bool
+
+	// buffered counts messages published while disconnected, which the
+	// client holds until it reconnects
+	buffered atomic.Int64
+
+	// deadLetters keeps messages that failed every attempt; nil only
+	// publishes them
+	deadLetters DeadLetterStore
+
+	// bufferSize bounds each subscription's queue of undelivered messages
+	bufferSize  int
+	mu          sync.Mutex
+	dispatchers []*dispatcher
 }
 
-// NewNATSBroker creates a new NATS broker
-func NewNATSBroker(natsURL string, logger *zerolog.Logger) (Broker, error) {
+// NewNATSBroker creates a new NATS broker. Each subscription buffers at
+// most bufferSize messages for its handler; beyond that messages are
+// dropped and counted.
+//
+// The broker keeps trying to reach NATS, including when it is down at
+// startup. Messages published meanwhile are held, up to
+// reconnectBufferBytes, and sent once the connection is back; publishing
+// beyond that fails.
+//
+// Messages a subscriber fails to process are saved to deadLetters, if
+// given, where admins can inspect and replay them.
+func NewNATSBroker(natsURL string, bufferSize, reconnectBufferBytes int, deadLetters DeadLetterStore, logger *zerolog.Logger) (Broker, error) {
 	broker := &natsBroker{
-		logger:    logger,
-		available: false,
+		logger:      logger,
+		available:   false,
+		bufferSize:  bufferSize,
+		deadLetters: deadLetters,
 	}
 
 	if natsURL == "" {
@@ -29,14 +66,31 @@ func NewNATSBroker(natsURL string, logger *zerolog.Logger) (Broker, error) {
 
 	conn, err := nats.Connect(natsURL,
 		nats.MaxReconnects(-1),
-		nats.ReconnectWait(2),
+		nats.ReconnectWait(reconnectWait),
+		nats.RetryOnFailedConnect(true),
+		nats.ReconnectBufSize(reconnectBufferBytes),
+		nats.ConnectHandler(func(nc *nats.Conn) {
+			broker.connected("NATS connected")
+		}),
 		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
-			if err != nil {
-				logger.Warn().Err(err).Msg("NATS disconnected")
-			}
+			natsConnected.Set(0)
+			logger.Warn().Err(err).Msg("NATS disconnected, buffering published messages")
 		}),
 		nats.ReconnectHandler(func(nc *nats.Conn) {
-			logger.Info().Msg("NATS reconnected")
+			broker.connected("NATS reconnected")
+		}),
+		nats.ClosedHandler(func(nc *nats.Conn) {
+			natsConnected.Set(0)
+		}),
+		nats.ErrorHandler(func(nc *nats.Conn, sub *nats.Subscription, err error) {
+			if errors.Is(err, nats.ErrSlowConsumer) {
+				slowConsumerEvents.Inc()
+			}
+			event := logger.Warn().Err(err)
+			if sub != nil {
+				event = event.Str("subject", sub.Subject)
+			}
+			event.Msg("NATS async error")
 		}),
 	)
 
@@ -47,20 +101,42 @@ func NewNATSBroker(natsURL string, logger *zerolog.Logger) (Broker, error) {
 
 	broker.conn = conn
 	broker.available = true
-	logger.Info().Str("url", natsURL).Msg("NATS broker initialized")
+	if conn.IsConnected() {
+		natsConnected.Set(1)
+		logger.Info().Str("url", natsURL).Msg("NATS broker initialized")
+	} else {
+		logger.Warn().Str("url", natsURL).Msg("NATS unavailable, buffering published messages until it connects")
+	}
 
 	return broker, nil
 }
 
+// connected records that the connection is up and that the client has
+// sent the messages it held while disconnected
+func (b *natsBroker) connected(msg string) {
+	natsConnected.Set(1)
+	flushed := b.buffered.Swap(0)
+	natsBufferedMessages.Sub(float64(flushed))
+	b.logger.Info().Int64("flushed_messages", flushed).Msg(msg)
+}
+
 func (b *natsBroker) Publish(subject string, data []byte) error {
 	if !b.available {
 		return ErrBrokerUnavailable
 	}
 
+	// While disconnected the client buffers the message, or fails once
+	// its buffer is full
+	connected := b.conn.IsConnected()
 	if err := b.conn.Publish(subject, data); err != nil {
+		publishFailures.WithLabelValues(subject).Inc()
 		b.logger.Error().Err(err).Str("subject", subject).Msg("Failed to publish message")
 		return fmt.Errorf("publish to %s: %w", subject, err)
 	}
+	if !connected {
+		b.buffered.Add(1)
+		natsBufferedMessages.Inc()
+	}
 
 	b.logger.Debug().Str("subject", subject).Int("bytes", len(data)).Msg("Message published")
 	return nil
@@ -84,32 +160,71 @@ func (b *natsBroker) Subscribe(subject string, handler func([]byte) error) error
 		return ErrBrokerUnavailable
 	}
 
+	// The NATS callback only enqueues; the handler runs on the
+	// dispatcher's goroutine so it can never block the connection
+	d := newDispatcher(subject, b.bufferSize, handler, b.publishDeadLetter, b.logger)
+
 	_, err := b.conn.Subscribe(subject, func(msg *nats.Msg) {
-		if err := handler(msg.Data); err != nil {
-			b.logger.Error().
-				Err(err).
-				Str("subject", subject).
-				Msg("Message handler error")
-		}
+		d.offer(msg.Data)
 	})
 
 	if err != nil {
+		d.stop()
 		return fmt.Errorf("subscribe to %s: %w", subject, err)
 	}
 
+	b.mu.Lock()
+	b.dispatchers = append(b.dispatchers, d)
+	b.mu.Unlock()
+
 	b.logger.Info().Str("subject", subject).Msg("Subscribed to subject")
 	return nil
 }
 
+// publishDeadLetter saves a message that failed processing for admins to
+// inspect and replay, and publishes it to the subject's dead-letter
+// subject for anyone watching. The message is only lost if both fail.
+func (b *natsBroker) publishDeadLetter(dl DeadLetter) {
+	saved := false
+	if b.deadLetters != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), deadLetterSaveTimeout)
+		defer cancel()
+		if err := b.deadLetters.SaveDeadLetter(ctx, dl); err != nil {
+			b.logger.Error().Err(err).Str("subject", dl.Subject).Msg("Failed to save dead letter")
+		} else {
+			saved = true
+		}
+	}
+
+	if err := b.PublishJSON(dl.Subject+DeadLetterSuffix, dl); err != nil && !saved {
+		b.logger.Error().Err(err).Str("subject", dl.Subject).Msg("Failed to publish dead letter, message lost")
+	}
+}
+
 func (b *natsBroker) Close() error {
 	if b.conn != nil {
+		if pending := b.buffered.Load(); pending > 0 && !b.conn.IsConnected() {
+			b.logger.Warn().Int64("messages", pending).Msg("Closing NATS while disconnected, buffered messages are lost")
+		}
 		b.conn.Close()
 		b.available = false
 		b.logger.Info().Msg("NATS connection closed")
 	}
+
+	b.mu.Lock()
+	for _, d := range b.dispatchers {
+		d.stop()
+	}
+	b.dispatchers = nil
+	b.mu.Unlock()
+
 	return nil
 }
 
 func (b *natsBroker) IsAvailable() bool {
 	return b.available && b.conn != nil && !b.conn.IsClosed()
+}
+
+func (b *natsBroker) IsConnected() bool {
+	return b.IsAvailable() && b.conn.IsConnected()
 }
\ No newline at end of file
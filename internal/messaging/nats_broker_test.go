@@ -0,0 +1,62 @@
+package messaging
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNATSBrokerBuffersWhileUnreachable(t *testing.T) {
+	// An address nothing listens on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	url := "nats://" + ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	logger := zerolog.Nop()
+	broker, err := NewNATSBroker(url, 8, 1024, nil, &logger)
+	require.NoError(t, err, "an unreachable server isn't fatal")
+	t.Cleanup(func() { broker.Close() })
+
+	assert.True(t, broker.IsAvailable(), "messages can still be published")
+	assert.False(t, broker.IsConnected())
+
+	require.NoError(t, broker.PublishJSON("user.registered", map[string]int{"user_id": 1}))
+	assert.Equal(t, int64(1), broker.(*natsBroker).buffered.Load())
+
+	// Once the buffer is full publishing fails instead of growing it
+	// without bound
+	require.NoError(t, broker.Publish("user.registered", make([]byte, 2048)))
+	assert.Error(t, broker.Publish("user.registered", []byte("{}")))
+	assert.Equal(t, int64(2), broker.(*natsBroker).buffered.Load())
+
+	require.NoError(t, broker.Close())
+	assert.False(t, broker.IsAvailable())
+}
+
+// deadLetterStore records saved dead letters
+type deadLetterStore struct {
+	saved []DeadLetter
+}
+
+func (s *deadLetterStore) SaveDeadLetter(ctx context.Context, dl DeadLetter) error {
+	s.saved = append(s.saved, dl)
+	return nil
+}
+
+func TestNATSBrokerSavesDeadLetters(t *testing.T) {
+	logger := zerolog.Nop()
+	store := &deadLetterStore{}
+	broker := &natsBroker{logger: &logger, deadLetters: store}
+
+	// Saved even though it can't be published while NATS is unavailable
+	broker.publishDeadLetter(DeadLetter{Subject: "user.verify", Data: []byte(`{"user_id":1}`), Attempts: 3})
+
+	require.Len(t, store.saved, 1)
+	assert.Equal(t, "user.verify", store.saved[0].Subject)
+	assert.Equal(t, []byte(`{"user_id":1}`), store.saved[0].Data)
+}
@@ -0,0 +1,96 @@
+// Package middleware implements API key authentication
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/service"
+
+	"github.com/rs/zerolog"
+)
+
+// APIKeyHeader carries API keys
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyMiddleware authenticates requests with the key in the X-API-Key
+// header and adds the owner's claims to context. The claims carry
+// service.ScopeAPIKey, so routes must opt in with RequireScope.
+func APIKeyMiddleware(apiKeys service.APIKeyService, logger *zerolog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(APIKeyHeader)
+			if key == "" {
+				respondUnauthorized(w, "Missing API key")
+				return
+			}
+
+			claims, err := apiKeys.AuthenticateAPIKey(r.Context(), key)
+			if errors.Is(err, domain.ErrInvalidAPIKey) {
+				logger.Warn().Str("path", r.URL.Path).Msg("Invalid API key")
+				respondUnauthorized(w, "Invalid or revoked API key")
+				return
+			}
+			if err != nil {
+				logger.Error().Err(err).Msg("API key authentication failed")
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(domain.HTTPStatusCode(err))
+				json.NewEncoder(w).Encode(map[string]string{"error": "Authentication unavailable"})
+				return
+			}
+
+			ctx, ok := bindTenant(r.Context(), claims)
+			if !ok {
+				logger.Warn().Int32("api_key_id", claims.APIKeyID).Msg("API key used for another tenant")
+				respondForbidden(w, "API key is not valid for this tenant")
+				return
+			}
+
+			ctx = context.WithValue(ctx, UserContextKey, claims)
+			ctx = withUserLogger(ctx, claims.UserID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AuthOrAPIKey lets a route accept either credential: requests with an
+// X-API-Key header go through apiKeyAuth, all others through jwtAuth
+func AuthOrAPIKey(apiKeyAuth, jwtAuth func(next http.Handler) http.Handler) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withAPIKey := apiKeyAuth(next)
+		withJWT := jwtAuth(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(APIKeyHeader) != "" {
+				withAPIKey.ServeHTTP(w, r)
+				return
+			}
+			withJWT.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAPIKeyScope creates middleware that requires requests made with
+// an API key to have been granted scope. Other requests pass through.
+func RequireAPIKeyScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserContextKey).(*service.TokenClaims)
+			if !ok {
+				respondUnauthorized(w, "Unauthorized")
+				return
+			}
+
+			if claims.Scope == service.ScopeAPIKey && !slices.Contains(claims.APIKeyScopes, scope) {
+				respondForbidden(w, "API key lacks the "+scope+" scope")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/service"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubAPIKeyService accepts a single key
+type stubAPIKeyService struct {
+	service.APIKeyService
+	key    string
+	claims *service.TokenClaims
+}
+
+func (s stubAPIKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*service.TokenClaims, error) {
+	if key != s.key {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	return s.claims, nil
+}
+
+func TestAuthOrAPIKey(t *testing.T) {
+	logger := zerolog.Nop()
+	apiKeys := stubAPIKeyService{
+		key: "uak_good",
+		claims: &service.TokenClaims{
+			UserID:       9,
+			Role:         "user",
+			Scope:        service.ScopeAPIKey,
+			APIKeyScopes: []string{service.APIKeyScopeUsersRead},
+		},
+	}
+	jwt := stubAuthService{claims: &service.TokenClaims{UserID: 1, Role: "user", Scope: service.ScopeFull}}
+
+	var seen *service.TokenClaims
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = GetUserFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	auth := AuthOrAPIKey(APIKeyMiddleware(apiKeys, &logger), AuthMiddleware(jwt, &logger, CookieAuth{}))
+
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		wantCode int
+		wantUser int32
+	}{
+		{"api key", APIKeyHeader, "uak_good", http.StatusOK, 9},
+		{"bad api key", APIKeyHeader, "uak_bad", http.StatusUnauthorized, 0},
+		{"bearer token", "Authorization", "Bearer token", http.StatusOK, 1},
+		{"no credentials", "", "", http.StatusUnauthorized, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = nil
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+
+			auth(ok).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantUser != 0 && assert.NotNil(t, seen) {
+				assert.Equal(t, tt.wantUser, seen.UserID)
+			}
+		})
+	}
+}
+
+func TestRequireAPIKeyScope(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		claims *service.TokenClaims
+		want   int
+	}{
+		{"key with scope", &service.TokenClaims{Scope: service.ScopeAPIKey, APIKeyScopes: []string{service.APIKeyScopeAuditRead}}, http.StatusOK},
+		{"key without scope", &service.TokenClaims{Scope: service.ScopeAPIKey, APIKeyScopes: []string{service.APIKeyScopeUsersRead}}, http.StatusForbidden},
+		{"jwt", &service.TokenClaims{Scope: service.ScopeFull}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit", nil)
+			req = req.WithContext(context.WithValue(req.Context(), UserContextKey, tt.claims))
+			rec := httptest.NewRecorder()
+
+			RequireAPIKeyScope(service.APIKeyScopeAuditRead)(ok).ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestRequireScopeRejectsAPIKeysByDefault(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/api-keys", nil)
+	req = req.WithContext(context.WithValue(req.Context(), UserContextKey, &service.TokenClaims{Scope: service.ScopeAPIKey}))
+	rec := httptest.NewRecorder()
+
+	RequireScope(service.ScopeFull)(ok).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
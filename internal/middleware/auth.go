@@ -4,9 +4,13 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
+	"user-auth-app/internal/domain"
 	"user-auth-app/internal/service"
 
 	"github.com/rs/zerolog"
@@ -15,46 +19,124 @@ import (
 type contextKey string
 
 const (
-	UserContextKey contextKey = "user"
+	UserContextKey  contextKey = "user"
+	TokenContextKey contextKey = "token"
 )
 
-// AuthMiddleware validates JWT tokens and adds user claims to context
-func AuthMiddleware(authService service.AuthService, logger *zerolog.Logger) func(next http.Handler) http.Handler {
+// Machine-readable error codes for rejected tokens. Clients should refresh
+// on token_expired, which works while the session is active, and log in
+// again on invalid_token.
+const (
+	TokenErrorExpired = "token_expired"
+	TokenErrorInvalid = "invalid_token"
+)
+
+// maxAuthorizationHeader bounds the Authorization header. Real tokens are
+// far shorter; anything longer is rejected before it is parsed.
+const maxAuthorizationHeader = 8 << 10
+
+// AuthMiddleware validates JWT tokens and adds user claims to context.
+// Tokens are read from the Authorization header, the auth cookie or both,
+// depending on cookies.Mode. CSRF checks for cookie auth are done by the
+// CSRF middleware.
+func AuthMiddleware(authService service.AuthService, logger *zerolog.Logger, cookies CookieAuth) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Extract token from Authorization header
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				logger.Warn().Str("path", r.URL.Path).Msg("Missing authorization header")
-				respondUnauthorized(w, "Missing authorization token")
+			tokenString, ok := requestToken(w, r, logger, cookies)
+			if !ok {
 				return
 			}
 
-			// Parse Bearer token
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				logger.Warn().Str("header", authHeader).Msg("Invalid authorization header format")
-				respondUnauthorized(w, "Invalid authorization header format")
+			// Validate token
+			claims, err := authService.ValidateToken(r.Context(), tokenString)
+			if errors.Is(err, domain.ErrExpiredToken) {
+				// Routine for clients that refresh on demand
+				logger.Debug().Str("path", r.URL.Path).Msg("Expired token")
+				respondTokenError(w, TokenErrorExpired, "The access token expired")
+				return
+			}
+			if errors.Is(err, domain.ErrUnauthorized) {
+				logger.Warn().Err(err).Msg("Token validation failed")
+				respondTokenError(w, TokenErrorInvalid, "The access token is invalid")
+				return
+			}
+			if err != nil {
+				// Checking for revocation needs the database; don't log
+				// clients out because it is down
+				logger.Error().Err(err).Msg("Token validation unavailable")
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(domain.HTTPStatusCode(err))
+				json.NewEncoder(w).Encode(map[string]string{"error": "Authentication unavailable"})
 				return
 			}
 
-			tokenString := parts[1]
+			ctx, ok := bindTenant(r.Context(), claims)
+			if !ok {
+				logger.Warn().Int32("user_id", claims.UserID).Msg("Token used for another tenant")
+				respondForbidden(w, "Token is not valid for this tenant")
+				return
+			}
 
-			// Validate token
-			claims, err := authService.ValidateToken(r.Context(), tokenString)
-			if err != nil {
-				logger.Warn().Err(err).Msg("Token validation failed")
-				respondUnauthorized(w, "Invalid or expired token")
+			// Add claims and the raw token to context
+			ctx = context.WithValue(ctx, UserContextKey, claims)
+			ctx = withUserLogger(ctx, claims.UserID)
+			ctx = context.WithValue(ctx, TokenContextKey, tokenString)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RefreshAuth reads the token as AuthMiddleware does and adds it to the
+// context unchecked. The refresh handler validates it itself, since an
+// expired token can still be refreshed while its session is active.
+func RefreshAuth(logger *zerolog.Logger, cookies CookieAuth) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString, ok := requestToken(w, r, logger, cookies)
+			if !ok {
 				return
 			}
 
-			// Add claims to context
-			ctx := context.WithValue(r.Context(), UserContextKey, claims)
+			ctx := context.WithValue(r.Context(), TokenContextKey, tokenString)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// requestToken reads the token from the Authorization header or the auth
+// cookie, depending on cookies.Mode. It reports false if it already sent
+// an error.
+func requestToken(w http.ResponseWriter, r *http.Request, logger *zerolog.Logger, cookies CookieAuth) (string, bool) {
+	var tokenString string
+
+	authHeader := r.Header.Get("Authorization")
+	switch {
+	case authHeader != "" && cookies.usesHeader():
+		token, problem := parseBearer(authHeader)
+		if problem != "" {
+			// The header itself isn't logged: it may hold a credential
+			// and is attacker-sized
+			logger.Warn().Str("path", r.URL.Path).Str("problem", problem).Msg("Malformed authorization header")
+			respondInvalidRequest(w, problem)
+			return "", false
+		}
+		tokenString = token
+
+	case cookies.UsesCookies():
+		if cookie, err := r.Cookie(cookies.Name); err == nil && cookie.Value != "" {
+			tokenString = cookie.Value
+		}
+	}
+
+	if tokenString == "" {
+		logger.Warn().Str("path", r.URL.Path).Msg("Missing authorization token")
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		respondUnauthorized(w, "Missing authorization token")
+		return "", false
+	}
+	return tokenString, true
+}
+
 // RequireRole creates middleware that checks for specific roles
 func RequireRole(roles ...string) func(next http.Handler) http.Handler {
 	roleMap := make(map[string]bool, len(roles))
@@ -80,18 +162,112 @@ func RequireRole(roles ...string) func(next http.Handler) http.Handler {
 	}
 }
 
+// RequirePermission creates middleware that only admits tokens whose
+// permissions include permission
+func RequirePermission(permission string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserContextKey).(*service.TokenClaims)
+			if !ok {
+				respondUnauthorized(w, "Unauthorized")
+				return
+			}
+
+			if !slices.Contains(claims.Permissions, permission) {
+				respondForbidden(w, "Missing the "+permission+" permission")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScope creates middleware that only admits tokens carrying one of
+// the given scopes. Restricted tokens (e.g. a pending password change) are
+// rejected with a message telling the client what to do.
+func RequireScope(scopes ...string) func(next http.Handler) http.Handler {
+	scopeMap := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		scopeMap[scope] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserContextKey).(*service.TokenClaims)
+			if !ok {
+				respondUnauthorized(w, "Unauthorized")
+				return
+			}
+
+			if !scopeMap[claims.Scope] {
+				if claims.Scope == service.ScopePasswordChange {
+					respondForbidden(w, "Password change required")
+					return
+				}
+				respondForbidden(w, "Insufficient token scope")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // GetUserFromContext extracts user claims from context
 func GetUserFromContext(ctx context.Context) (*service.TokenClaims, bool) {
 	claims, ok := ctx.Value(UserContextKey).(*service.TokenClaims)
 	return claims, ok
 }
 
+// GetTokenFromContext returns the raw token the request was authenticated
+// with, whether it came from the header or a cookie
+func GetTokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(TokenContextKey).(string)
+	return token, ok
+}
+
 func respondUnauthorized(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusUnauthorized)
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
 
+// parseBearer extracts the token from an Authorization header. The scheme
+// is matched case-insensitively (RFC 7235). A non-empty problem describes
+// why the header was rejected.
+func parseBearer(header string) (token, problem string) {
+	if len(header) > maxAuthorizationHeader {
+		return "", "Authorization header too long"
+	}
+	scheme, token, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", "Authorization scheme must be Bearer"
+	}
+	token = strings.TrimSpace(token)
+	if !found || token == "" {
+		return "", "Missing bearer token"
+	}
+	if strings.ContainsAny(token, " \t") {
+		return "", "Invalid authorization header format"
+	}
+	return token, ""
+}
+
+// respondInvalidRequest rejects a malformed Authorization header. Unlike
+// respondTokenError, no token was checked, so clients shouldn't refresh.
+func respondInvalidRequest(w http.ResponseWriter, description string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_request", error_description=%q`, description))
+	respondUnauthorized(w, description)
+}
+
+// respondTokenError rejects a bearer token as described in RFC 6750,
+// with the code repeated in the body for clients that don't parse headers
+func respondTokenError(w http.ResponseWriter, code, description string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, description))
+	respondUnauthorized(w, code)
+}
+
 func respondForbidden(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusForbidden)
@@ -0,0 +1,292 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/breach"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+	"user-auth-app/internal/service"
+	"user-auth-app/internal/signing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAuthService answers ValidateToken with a fixed result. Other methods
+// panic via the nil embedded interface.
+type stubAuthService struct {
+	service.AuthService
+	claims *service.TokenClaims
+	err    error
+}
+
+func (s stubAuthService) ValidateToken(ctx context.Context, token string) (*service.TokenClaims, error) {
+	return s.claims, s.err
+}
+
+func TestAuthMiddlewareTokenErrors(t *testing.T) {
+	logger := zerolog.Nop()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name          string
+		err           error
+		wantCode      string
+		wantChallenge string
+	}{
+		{"expired", domain.ErrExpiredToken, TokenErrorExpired, `Bearer error="invalid_token", error_description="The access token expired"`},
+		{"invalid", domain.ErrInvalidToken, TokenErrorInvalid, `Bearer error="invalid_token", error_description="The access token is invalid"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+			req.Header.Set("Authorization", "Bearer token")
+			rec := httptest.NewRecorder()
+
+			AuthMiddleware(stubAuthService{err: tt.err}, &logger, CookieAuth{})(ok).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Equal(t, tt.wantChallenge, rec.Header().Get("WWW-Authenticate"))
+
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+			assert.Equal(t, tt.wantCode, body["error"])
+		})
+	}
+
+	t.Run("revocation check unavailable", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		rec := httptest.NewRecorder()
+
+		AuthMiddleware(stubAuthService{err: fmt.Errorf("check token version: %w", domain.ErrUnavailable)}, &logger, CookieAuth{})(ok).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Empty(t, rec.Header().Get("WWW-Authenticate"), "clients must not discard the token")
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+		rec := httptest.NewRecorder()
+
+		AuthMiddleware(stubAuthService{}, &logger, CookieAuth{})(ok).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		rec := httptest.NewRecorder()
+
+		AuthMiddleware(stubAuthService{claims: &service.TokenClaims{UserID: 1}}, &logger, CookieAuth{})(ok).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestRefreshAuthLeavesValidationToHandler(t *testing.T) {
+	logger := zerolog.Nop()
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := GetTokenFromContext(r.Context())
+		w.Write([]byte(token))
+	})
+
+	// The token is passed on as is, even though it has expired
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", nil)
+	req.Header.Set("Authorization", "Bearer expired-token")
+	rec := httptest.NewRecorder()
+	RefreshAuth(&logger, CookieAuth{})(echo).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "expired-token", rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", nil)
+	rec = httptest.NewRecorder()
+	RefreshAuth(&logger, CookieAuth{})(echo).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+}
+
+// versionRepo reports version 0 for every user's tokens. Other methods
+// panic via the nil embedded interface.
+type versionRepo struct {
+	repository.UserRepository
+	checked []int32
+}
+
+func (r *versionRepo) GetTokenVersion(ctx context.Context, userID int32) (int32, error) {
+	r.checked = append(r.checked, userID)
+	return 0, nil
+}
+
+// JSON numbers in tokens used to be read back as float64 and converted;
+// the user ID in the request context must be exactly the one signed
+func TestAuthMiddlewareInjectsExactUserID(t *testing.T) {
+	logger := zerolog.Nop()
+	keys, err := signing.NewKeySet(signing.NewHMACKey([]byte("test-secret-key-min-32-characters-long")))
+	require.NoError(t, err)
+	repo := &versionRepo{}
+	authService := service.NewAuthService(service.AuthServiceConfig{
+		Repo:         repo,
+		PwnedChecker: breach.NopChecker{},
+		Logger:       &logger,
+		Keys:         keys,
+
+		JWTExpiry:          time.Hour,
+		SessionLifetime:    24 * time.Hour,
+		RememberMeLifetime: 24 * time.Hour,
+		Issuer:             "auth",
+		Audience:           "api",
+		TokenBindingMode:   service.TokenBindingNone,
+	})
+
+	// Signed as a generic map, as another issuer sharing the key would
+	signed, err := keys.Sign(jwt.MapClaims{
+		"user_id": math.MaxInt32,
+		"iss":     "auth",
+		"aud":     "api",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+
+	var got int32
+	handler := AuthMiddleware(authService, &logger, CookieAuth{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := GetUserFromContext(r.Context())
+		require.True(t, ok)
+		got = claims.UserID
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+	req.Header.Set("Authorization", "Bearer "+signed)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int32(math.MaxInt32), got)
+	assert.Equal(t, []int32{math.MaxInt32}, repo.checked, "the revocation check sees the same ID")
+}
+
+func TestAuthMiddlewareMalformedHeader(t *testing.T) {
+	logger := zerolog.Nop()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	auth := AuthMiddleware(stubAuthService{claims: &service.TokenClaims{UserID: 1}}, &logger, CookieAuth{})
+
+	tests := []struct {
+		name    string
+		header  string
+		wantErr string
+	}{
+		{"no scheme", "token", "Authorization scheme must be Bearer"},
+		{"other scheme", "Basic dXNlcjpwYXNz", "Authorization scheme must be Bearer"},
+		{"scheme only", "Bearer", "Missing bearer token"},
+		{"empty token", "Bearer   ", "Missing bearer token"},
+		{"extra parts", "Bearer token extra", "Invalid authorization header format"},
+		{"too long", "Bearer " + strings.Repeat("a", maxAuthorizationHeader), "Authorization header too long"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+			req.Header.Set("Authorization", tt.header)
+			rec := httptest.NewRecorder()
+
+			auth(ok).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Equal(t, fmt.Sprintf(`Bearer error="invalid_request", error_description=%q`, tt.wantErr), rec.Header().Get("WWW-Authenticate"))
+
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+			assert.Equal(t, tt.wantErr, body["error"])
+		})
+	}
+
+	for _, header := range []string{"bearer token", "BEARER token", "Bearer  token "} {
+		t.Run("accepts "+header, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+			req.Header.Set("Authorization", header)
+			rec := httptest.NewRecorder()
+
+			auth(ok).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		claims *service.TokenClaims
+		want   int
+	}{
+		{"full scope", &service.TokenClaims{UserID: 1, Scope: service.ScopeFull}, http.StatusOK},
+		{"pending password change", &service.TokenClaims{UserID: 1, Scope: service.ScopePasswordChange}, http.StatusForbidden},
+		{"unknown scope", &service.TokenClaims{UserID: 1, Scope: "other"}, http.StatusForbidden},
+		{"no claims", nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+			if tt.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), UserContextKey, tt.claims))
+			}
+			rec := httptest.NewRecorder()
+
+			RequireScope(service.ScopeFull)(ok).ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		claims *service.TokenClaims
+		want   int
+	}{
+		{"granted", &service.TokenClaims{UserID: 1, Role: "support", Permissions: []string{domain.PermissionAuditRead, domain.PermissionUsersList}}, http.StatusOK},
+		{"other permissions only", &service.TokenClaims{UserID: 1, Role: "admin", Permissions: []string{domain.PermissionAuditRead}}, http.StatusForbidden},
+		{"no permissions", &service.TokenClaims{UserID: 1, Role: "user"}, http.StatusForbidden},
+		{"no claims", nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+			if tt.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), UserContextKey, tt.claims))
+			}
+			rec := httptest.NewRecorder()
+
+			RequirePermission(domain.PermissionUsersList)(ok).ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
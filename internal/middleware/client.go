@@ -0,0 +1,126 @@
+// Package middleware provides client identification middleware
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"user-auth-app/internal/audit"
+)
+
+type peerKey struct{}
+
+// RealIP sets r.RemoteAddr to ClientIP, without a port, so everything
+// after it, such as rate limiting and the audit log, sees the same client
+// address. The connection's own address is kept for IPFilter.
+func RealIP(trustedProxies []netip.Prefix) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(context.WithValue(r.Context(), peerKey{}, r.RemoteAddr))
+			r.RemoteAddr = ClientIP(r, trustedProxies)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the address of the client that sent r as a string
+// (see ClientAddr). X-Forwarded-For and X-Real-IP are only honored when
+// the connection comes from a trusted proxy, so clients can't choose
+// their own address. If the address can't be resolved, the connection's
+// host is returned.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	if addr := ClientAddr(r, trustedProxies); addr.IsValid() {
+		return addr.String()
+	}
+	remote := peerAddr(r)
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return host
+	}
+	return remote
+}
+
+// ClientAddr returns the address of the client that sent r. The
+// connection's address is the client unless it belongs to a trusted
+// proxy; then X-Forwarded-For is read from the right, skipping trusted
+// proxies, up to the first address a trusted proxy vouched for. A
+// malformed entry in that part of the header returns the zero Addr, as
+// does an unparsable connection address.
+func ClientAddr(r *http.Request, trustedProxies []netip.Prefix) netip.Addr {
+	addr := parseAddr(peerAddr(r))
+	if !addr.IsValid() || !containsAddr(trustedProxies, addr) {
+		return addr
+	}
+
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	if len(hops) == 0 {
+		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+			return parseAddr(realIP)
+		}
+		return addr
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr = parseAddr(hops[i])
+		if !addr.IsValid() || !containsAddr(trustedProxies, addr) {
+			return addr
+		}
+	}
+	// Every hop is a trusted proxy; the first one is as far as we can see
+	return addr
+}
+
+// peerAddr returns the address of the connection r came in on, before
+// RealIP replaced it
+func peerAddr(r *http.Request) string {
+	if remote, ok := r.Context().Value(peerKey{}).(string); ok {
+		return remote
+	}
+	return r.RemoteAddr
+}
+
+// parseAddr parses an IP address, with or without a port. IPv4 addresses
+// mapped into IPv6 are unmapped so they match IPv4 networks.
+func parseAddr(raw string) netip.Addr {
+	raw = strings.TrimSpace(raw)
+	if addrPort, err := netip.ParseAddrPort(raw); err == nil {
+		return addrPort.Addr().Unmap()
+	}
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+// containsAddr reports whether one of the networks contains addr
+func containsAddr(networks []netip.Prefix, addr netip.Addr) bool {
+	for _, network := range networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientInfo stores the client IP address and user agent in the request
+// context for audit logging. It must run after RealIP.
+func ClientInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+
+		ctx := audit.WithClient(r.Context(), audit.Client{
+			IPAddress: ip,
+			UserAgent: r.UserAgent(),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"user-auth-app/internal/audit"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientAddr(t *testing.T) {
+	proxies := networks("10.0.0.0/8", "fd00::/8")
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{"direct IPv4", "203.0.113.7:4321", nil, "", "203.0.113.7"},
+		{"direct IPv6", "[2001:db8::7]:4321", nil, "", "2001:db8::7"},
+		{"IPv4-mapped IPv6", "[::ffff:203.0.113.7]:4321", nil, "", "203.0.113.7"},
+		{"untrusted peer can't forward", "203.0.113.7:4321", []string{"10.1.2.3"}, "", "203.0.113.7"},
+		{"untrusted peer can't set X-Real-IP", "203.0.113.7:4321", nil, "10.1.2.3", "203.0.113.7"},
+		{"through a proxy", "10.0.0.2:80", []string{"198.51.100.4"}, "", "198.51.100.4"},
+		{"through an IPv6 proxy", "[fd00::2]:80", []string{"2001:db8::9"}, "", "2001:db8::9"},
+		{"through a proxy chain", "10.0.0.2:80", []string{"198.51.100.4, 10.0.0.3"}, "", "198.51.100.4"},
+		{"spoofed entries left of the client", "10.0.0.2:80", []string{"10.9.9.9, 198.51.100.4"}, "", "198.51.100.4"},
+		{"spoofed header repeated", "10.0.0.2:80", []string{"10.9.9.9", "198.51.100.4"}, "", "198.51.100.4"},
+		{"X-Real-IP from a proxy", "10.0.0.2:80", nil, "198.51.100.4", "198.51.100.4"},
+		{"only proxies", "10.0.0.2:80", []string{"10.0.0.4, 10.0.0.3"}, "", "10.0.0.4"},
+		{"garbage from the client", "10.0.0.2:80", []string{"not-an-ip"}, "", "invalid IP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			assert.Equal(t, tt.want, ClientAddr(req, proxies).String())
+		})
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	proxies := networks("10.0.0.0/8")
+
+	tests := []struct {
+		name       string
+		proxies    []netip.Prefix
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"port is dropped", proxies, "203.0.113.7:4321", "", "203.0.113.7"},
+		{"IPv6 port is dropped", proxies, "[2001:db8::7]:4321", "", "2001:db8::7"},
+		{"spoofed header", proxies, "203.0.113.7:4321", "198.51.100.4", "203.0.113.7"},
+		{"headers ignored without trusted proxies", nil, "10.0.0.2:80", "198.51.100.4", "10.0.0.2"},
+		{"through a proxy", proxies, "10.0.0.2:80", "198.51.100.4", "198.51.100.4"},
+		{"garbage through a proxy", proxies, "10.0.0.2:80", "not-an-ip", "10.0.0.2"},
+		{"unparsable peer", proxies, "pipe", "", "pipe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+
+			// Rate limiting and the audit log see the same address
+			var remoteAddr, audited string
+			handler := RealIP(tt.proxies)(ClientInfo(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				remoteAddr = r.RemoteAddr
+				audited = audit.ClientFromContext(r.Context()).IPAddress
+			})))
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.want, remoteAddr)
+			assert.Equal(t, tt.want, audited)
+		})
+	}
+}
@@ -0,0 +1,254 @@
+// Package middleware provides response compression
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Content encodings responses can be compressed with
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// incompressibleTypes are content types that are compressed already, or
+// close enough that compressing them again wastes CPU
+var incompressibleTypes = []string{
+	"image/",
+	"audio/",
+	"video/",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zip",
+	"application/zstd",
+	"application/octet-stream",
+}
+
+// Compress compresses responses of at least minSize bytes with gzip or
+// deflate, whichever the request's Accept-Encoding prefers, at the given
+// compression level (1-9). Smaller responses, responses that already set
+// Content-Encoding and incompressible content types are sent as is.
+//
+// Compress holds back the status until minSize bytes are written or the
+// handler returns, so it must sit inside any middleware that records the
+// status.
+func Compress(level, minSize int) func(next http.Handler) http.Handler {
+	pools := map[string]*sync.Pool{
+		encodingGzip: {New: func() any {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		}},
+		encodingDeflate: {New: func() any {
+			w, _ := zlib.NewWriterLevel(io.Discard, level)
+			return w
+		}},
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				pool:           pools[encoding],
+				minSize:        minSize,
+			}
+			defer cw.close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// acceptedEncoding returns the supported encoding with the highest
+// quality in an Accept-Encoding header, preferring gzip on ties, or ""
+// if the client accepts neither
+func acceptedEncoding(header string) string {
+	var (
+		best        string
+		bestQuality float64
+	)
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != encodingGzip && name != encodingDeflate && name != "*" {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		if name == "*" {
+			name = encodingGzip
+		}
+		if quality > bestQuality || (quality == bestQuality && name == encodingGzip) {
+			best, bestQuality = name, quality
+		}
+	}
+
+	if bestQuality == 0 {
+		return ""
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it knows whether
+// the response is worth compressing, then either compresses everything
+// or passes it through
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	pool     *sync.Pool
+	minSize  int
+
+	status  int
+	buf     []byte
+	decided bool
+	// encoder is set once the response is being compressed
+	encoder compressor
+}
+
+// compressor is implemented by the gzip and zlib writers
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = code
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < cw.minSize {
+			return len(b), nil
+		}
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends what has been written so far, so streaming responses keep
+// working; a response flushed before reaching minSize isn't compressed
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide()
+	}
+	if cw.encoder != nil {
+		cw.encoder.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide sends the status and headers, compressing the response if it
+// has reached minSize and its type is worth compressing, followed by the
+// buffered body
+func (cw *compressWriter) decide() error {
+	cw.decided = true
+
+	header := cw.Header()
+	if len(cw.buf) >= cw.minSize && header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) {
+		if header.Get("Content-Type") == "" {
+			// Sniff from the uncompressed body, as net/http would
+			header.Set("Content-Type", http.DetectContentType(cw.buf))
+		}
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+
+		cw.encoder = cw.pool.Get().(compressor)
+		cw.encoder.Reset(cw.ResponseWriter)
+	}
+
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close sends a response that stayed under minSize and finishes a
+// compressed one
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			// Nothing written; leave the default response to net/http
+			return
+		}
+		cw.decide()
+	}
+
+	if cw.encoder != nil {
+		cw.encoder.Close()
+		cw.pool.Put(cw.encoder)
+		cw.encoder = nil
+	}
+}
+
+// compressible reports whether responses of contentType are worth
+// compressing
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// Unset or unparseable; sniffed types are mostly text
+		return true
+	}
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return false
+		}
+	}
+	return true
+}
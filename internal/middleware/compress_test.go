@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                          "",
+		"identity":                  "",
+		"br":                        "",
+		"gzip":                      encodingGzip,
+		"deflate":                   encodingDeflate,
+		"deflate, gzip":             encodingGzip,
+		"gzip;q=0.5, deflate":       encodingDeflate,
+		"GZIP":                      encodingGzip,
+		"*":                         encodingGzip,
+		"gzip;q=0":                  "",
+		"gzip;q=0, deflate;q=0.1":   encodingDeflate,
+		"br;q=1.0, gzip;q=0.8, *;q": encodingGzip,
+	}
+
+	for header, want := range tests {
+		assert.Equal(t, want, acceptedEncoding(header), "Accept-Encoding: %q", header)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := `{"users":[` + strings.Repeat(`{"username":"johndoe"},`, 100) + `{}]}`
+
+	serve := func(acceptEncoding string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		Compress(5, 1024)(h).ServeHTTP(rec, req)
+		return rec
+	}
+	jsonBody := func(status int, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", "123")
+			w.WriteHeader(status)
+			// Several writes, the first under the threshold
+			io.WriteString(w, body[:len(body)/2])
+			io.WriteString(w, body[len(body)/2:])
+		}
+	}
+
+	t.Run("large responses are gzipped", func(t *testing.T) {
+		rec := serve("gzip, deflate", jsonBody(http.StatusCreated, large))
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+		assert.Empty(t, rec.Header().Get("Content-Length"))
+		assert.Less(t, rec.Body.Len(), len(large))
+
+		zr, err := gzip.NewReader(rec.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		assert.Equal(t, large, string(body))
+	})
+
+	t.Run("deflate when preferred", func(t *testing.T) {
+		rec := serve("gzip;q=0.5, deflate", jsonBody(http.StatusOK, large))
+
+		assert.Equal(t, "deflate", rec.Header().Get("Content-Encoding"))
+		zr, err := zlib.NewReader(rec.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		assert.Equal(t, large, string(body))
+	})
+
+	t.Run("small responses are sent as is", func(t *testing.T) {
+		rec := serve("gzip", jsonBody(http.StatusNotFound, `{"error":"User not found"}`))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+		assert.Equal(t, `{"error":"User not found"}`, rec.Body.String())
+	})
+
+	t.Run("clients that don't accept compression", func(t *testing.T) {
+		rec := serve("", jsonBody(http.StatusOK, large))
+
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, rec.Body.String())
+	})
+
+	t.Run("compressed content is left alone", func(t *testing.T) {
+		for _, h := range []http.HandlerFunc{
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				io.WriteString(w, large)
+			},
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "br")
+				io.WriteString(w, large)
+			},
+		} {
+			rec := serve("gzip, br", h)
+			assert.NotEqual(t, "gzip", rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, large, rec.Body.String())
+		}
+	})
+
+	t.Run("status reaches outer middleware", func(t *testing.T) {
+		var status int
+		outer := func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+				next.ServeHTTP(ww, r)
+				status = ww.Status()
+			})
+		}
+
+		for _, body := range []string{large, `{}`} {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			outer(Compress(5, 1024)(jsonBody(http.StatusAccepted, body))).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusAccepted, status)
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+// Package middleware enforces request body content types
+package middleware
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// RequireJSON rejects requests whose body isn't declared as
+// application/json with 415 Unsupported Media Type. A charset parameter is
+// allowed as long as it is UTF-8, the only encoding JSON permits. With
+// enabled false requests pass through, for clients that predate the check.
+func RequireJSON(enabled bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isJSONContentType(r.Header.Get("Content-Type")) {
+				respondUnsupportedMediaType(w, "Content-Type must be application/json")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isJSONContentType reports whether contentType is application/json with
+// no charset or a UTF-8 one
+func isJSONContentType(contentType string) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "application/json" {
+		return false
+	}
+	charset, ok := params["charset"]
+	return !ok || strings.EqualFold(charset, "utf-8")
+}
+
+func respondUnsupportedMediaType(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnsupportedMediaType)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,65 @@
+// Package middleware implements cookie-based token transport
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// Where AuthMiddleware accepts tokens from
+const (
+	// AuthModeHeader only accepts Authorization: Bearer
+	AuthModeHeader = "header"
+	// AuthModeCookie only accepts the auth cookie
+	AuthModeCookie = "cookie"
+	// AuthModeBoth prefers the header and falls back to the cookie
+	AuthModeBoth = "both"
+)
+
+// CookieAuth configures how tokens travel between browser and server
+type CookieAuth struct {
+	Mode           string
+	Name           string
+	CSRFCookieName string
+	Secure         bool
+	SameSite       http.SameSite
+
+	// CSRF enables the double-submit check (see CSRF)
+	CSRF bool
+
+	// RememberMeLifetime is how long persistent cookies last. It matches
+	// the lifetime of a remember-me session, which each refresh extends
+	// along with the cookies.
+	RememberMeLifetime time.Duration
+}
+
+// UsesCookies reports whether tokens are accepted from cookies
+func (c CookieAuth) UsesCookies() bool {
+	return c.Mode == AuthModeCookie || c.Mode == AuthModeBoth
+}
+
+// usesHeader reports whether tokens are accepted from the Authorization
+// header
+func (c CookieAuth) usesHeader() bool {
+	return c.Mode != AuthModeCookie
+}
+
+// SetTokenCookies stores a token in an HttpOnly cookie. With CSRF
+// protection it also rotates the CSRF token, so one issued before login
+// can't be replayed with the new session. A zero expiresAt sets session
+// cookies, which the browser drops when it closes.
+func (c CookieAuth) SetTokenCookies(w http.ResponseWriter, token string, expiresAt time.Time) error {
+	http.SetCookie(w, &http.Cookie{
+		Name:     c.Name,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		Secure:   c.Secure,
+		HttpOnly: true,
+		SameSite: c.SameSite,
+	})
+	if c.CSRF {
+		return c.setCSRFCookie(w, expiresAt)
+	}
+	return nil
+}
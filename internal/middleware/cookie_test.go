@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/service"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenAuthService accepts exactly one token
+type tokenAuthService struct {
+	service.AuthService
+	token string
+}
+
+func (s tokenAuthService) ValidateToken(ctx context.Context, token string) (*service.TokenClaims, error) {
+	if token != s.token {
+		return nil, assert.AnError
+	}
+	return &service.TokenClaims{UserID: 1, Scope: service.ScopeFull}, nil
+}
+
+func testCookieAuth(mode string) CookieAuth {
+	return CookieAuth{
+		Mode:           mode,
+		Name:           "access_token",
+		CSRFCookieName: "csrf_token",
+		Secure:         true,
+		SameSite:       http.SameSiteLaxMode,
+		CSRF:           true,
+	}
+}
+
+func TestSetTokenCookies(t *testing.T) {
+	rec := httptest.NewRecorder()
+	expiresAt := time.Now().Add(time.Hour)
+
+	require.NoError(t, testCookieAuth(AuthModeCookie).SetTokenCookies(rec, "jwt", expiresAt))
+
+	cookies := map[string]*http.Cookie{}
+	for _, c := range rec.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+
+	token := cookies["access_token"]
+	require.NotNil(t, token)
+	assert.Equal(t, "jwt", token.Value)
+	assert.True(t, token.HttpOnly)
+	assert.True(t, token.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, token.SameSite)
+	assert.Equal(t, expiresAt.Unix(), token.Expires.Unix())
+
+	csrf := cookies["csrf_token"]
+	require.NotNil(t, csrf)
+	assert.NotEmpty(t, csrf.Value)
+	assert.False(t, csrf.HttpOnly, "scripts must be able to read the CSRF token")
+
+	// Without CSRF protection only the token cookie is set
+	auth := testCookieAuth(AuthModeCookie)
+	auth.CSRF = false
+	rec = httptest.NewRecorder()
+	require.NoError(t, auth.SetTokenCookies(rec, "jwt", expiresAt))
+	assert.Len(t, rec.Result().Cookies(), 1)
+}
+
+func TestAuthMiddlewareCookies(t *testing.T) {
+	logger := zerolog.Nop()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := GetTokenFromContext(r.Context())
+		w.Write([]byte(token))
+	})
+
+	tests := []struct {
+		name   string
+		mode   string
+		header string
+		cookie string
+		want   int
+	}{
+		{"cookie mode reads cookie", AuthModeCookie, "", "good", http.StatusOK},
+		{"cookie mode ignores header", AuthModeCookie, "Bearer good", "", http.StatusUnauthorized},
+		{"header mode ignores cookie", AuthModeHeader, "", "good", http.StatusUnauthorized},
+		{"both prefers header", AuthModeBoth, "Bearer good", "bad", http.StatusOK},
+		{"both falls back to cookie", AuthModeBoth, "", "good", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "access_token", Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+
+			AuthMiddleware(tokenAuthService{token: "good"}, &logger, testCookieAuth(tt.mode))(ok).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			if tt.want == http.StatusOK {
+				assert.Equal(t, "good", rec.Body.String())
+			}
+		})
+	}
+}
@@ -10,11 +10,19 @@ func CORS(allowedOrigins []string) func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
 
-			// Check if origin is allowed
+			// Check if origin is allowed. Only origins listed by name may
+			// send cookies; a wildcard never grants credentials.
 			allowed := false
+			credentials := false
 			for _, o := range allowedOrigins {
-				if o == "*" || o == origin {
+				if o == "*" {
+					// Keep looking: the origin may also be listed by name
 					allowed = true
+					continue
+				}
+				if o == origin {
+					allowed = true
+					credentials = true
 					break
 				}
 			}
@@ -22,8 +30,11 @@ func CORS(allowedOrigins []string) func(next http.Handler) http.Handler {
 			if allowed {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+CSRFHeader+", "+TenantHeader)
 				w.Header().Set("Access-Control-Max-Age", "3600")
+				if credentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
 			}
 
 			// Handle preflight
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name            string
+		allowed         []string
+		origin          string
+		wantAllowed     bool
+		wantCredentials bool
+	}{
+		{"named origin", []string{"https://app.example.com"}, "https://app.example.com", true, true},
+		{"unlisted origin", []string{"https://app.example.com"}, "https://evil.example.com", false, false},
+		{"wildcard", []string{"*"}, "https://any.example.com", true, false},
+		{"named origin after wildcard", []string{"*", "https://app.example.com"}, "https://app.example.com", true, true},
+		{"named origin before wildcard", []string{"https://app.example.com", "*"}, "https://app.example.com", true, true},
+		{"other origin with wildcard", []string{"*", "https://app.example.com"}, "https://any.example.com", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CORS(tt.allowed)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if tt.wantAllowed {
+				assert.Equal(t, tt.origin, rec.Header().Get("Access-Control-Allow-Origin"))
+			} else {
+				assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+			}
+			if tt.wantCredentials {
+				assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+			} else {
+				assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	called := false
+	handler := CORS([]string{"https://app.example.com"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/login", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.False(t, called, "preflight requests don't reach the handler")
+	assert.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), CSRFHeader)
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSRF(t *testing.T) {
+	logger := zerolog.Nop()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		mode   string
+		method string
+		header string
+		cookie string
+		csrf   string
+		echo   string
+		want   int
+	}{
+		{"safe method", AuthModeCookie, http.MethodGet, "", "jwt", "", "", http.StatusOK},
+		{"no CSRF header", AuthModeCookie, http.MethodPost, "", "jwt", "abc", "", http.StatusForbidden},
+		{"no CSRF cookie", AuthModeCookie, http.MethodDelete, "", "jwt", "", "abc", http.StatusForbidden},
+		{"wrong CSRF header", AuthModeCookie, http.MethodPut, "", "jwt", "abc", "xyz", http.StatusForbidden},
+		{"matching CSRF header", AuthModeCookie, http.MethodPatch, "", "jwt", "abc", "abc", http.StatusOK},
+		{"bearer token is exempt", AuthModeBoth, http.MethodPost, "Bearer jwt", "jwt", "", "", http.StatusOK},
+		{"header ignored in cookie mode", AuthModeCookie, http.MethodPost, "Bearer jwt", "jwt", "", "", http.StatusForbidden},
+		{"no auth cookie is exempt", AuthModeCookie, http.MethodPost, "", "", "", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/auth/refresh", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "access_token", Value: tt.cookie})
+			}
+			if tt.csrf != "" {
+				req.AddCookie(&http.Cookie{Name: "csrf_token", Value: tt.csrf})
+			}
+			if tt.echo != "" {
+				req.Header.Set(CSRFHeader, tt.echo)
+			}
+			rec := httptest.NewRecorder()
+
+			CSRF(testCookieAuth(tt.mode), &logger)(ok).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestCSRFIssuesMissingCookie(t *testing.T) {
+	logger := zerolog.Nop()
+	handler := CSRF(testCookieAuth(AuthModeCookie), &logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/password-policy", nil))
+
+	cookies := rec.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, "csrf_token", cookies[0].Name)
+		assert.NotEmpty(t, cookies[0].Value)
+	}
+
+	// An existing cookie is left alone
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/password-policy", nil)
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "abc"})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Empty(t, rec.Result().Cookies())
+}
@@ -0,0 +1,58 @@
+// Package middleware implements network restrictions for sensitive routes
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/rs/zerolog"
+)
+
+// IPFilter restricts routes to client networks. Deny wins over Allow; an
+// empty Allow admits every network not denied. The client's address is
+// resolved with ClientAddr, so only TrustedProxies can speak for it.
+type IPFilter struct {
+	Allow          []netip.Prefix
+	Deny           []netip.Prefix
+	TrustedProxies []netip.Prefix
+}
+
+// Enabled reports whether any network is allowed or denied
+func (f IPFilter) Enabled() bool {
+	return len(f.Allow) > 0 || len(f.Deny) > 0
+}
+
+// Allows reports whether requests from addr are admitted. An invalid
+// address is never admitted by an enabled filter.
+func (f IPFilter) Allows(addr netip.Addr) bool {
+	if !f.Enabled() {
+		return true
+	}
+	if !addr.IsValid() || containsAddr(f.Deny, addr) {
+		return false
+	}
+	return len(f.Allow) == 0 || containsAddr(f.Allow, addr)
+}
+
+// RequireIP rejects requests from clients the filter doesn't allow with
+// 403. It passes everything through when the filter is not Enabled.
+func RequireIP(filter IPFilter, logger *zerolog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !filter.Enabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr := ClientAddr(r, filter.TrustedProxies)
+			if !filter.Allows(addr) {
+				logger.Warn().
+					Str("client_ip", addr.String()).
+					Str("path", r.URL.Path).
+					Msg("Request from disallowed network")
+				respondForbidden(w, "Access from this network is not allowed")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
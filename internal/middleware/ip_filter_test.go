@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func networks(cidrs ...string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, len(cidrs))
+	for i, cidr := range cidrs {
+		prefixes[i] = netip.MustParsePrefix(cidr)
+	}
+	return prefixes
+}
+
+func TestRequireIP(t *testing.T) {
+	filter := IPFilter{
+		Allow:          networks("198.51.100.0/24", "2001:db8:1::/48"),
+		Deny:           networks("198.51.100.66/32"),
+		TrustedProxies: networks("10.0.0.0/8"),
+	}
+
+	tests := []struct {
+		name       string
+		filter     IPFilter
+		remoteAddr string
+		forwarded  string
+		want       int
+	}{
+		{"allowed IPv4", filter, "198.51.100.4:1234", "", http.StatusOK},
+		{"allowed IPv6", filter, "[2001:db8:1::5]:1234", "", http.StatusOK},
+		{"other IPv4", filter, "203.0.113.7:1234", "", http.StatusForbidden},
+		{"other IPv6", filter, "[2001:db8:2::5]:1234", "", http.StatusForbidden},
+		{"denied within an allowed network", filter, "198.51.100.66:1234", "", http.StatusForbidden},
+		{"allowed through a proxy", filter, "10.0.0.2:80", "198.51.100.4", http.StatusOK},
+		{"spoofed through a proxy", filter, "10.0.0.2:80", "198.51.100.4, 203.0.113.7", http.StatusForbidden},
+		{"spoofed without a proxy", filter, "203.0.113.7:1234", "198.51.100.4", http.StatusForbidden},
+		{"the proxy itself isn't allowed", filter, "10.0.0.2:80", "", http.StatusForbidden},
+		{"deny only", IPFilter{Deny: networks("203.0.113.0/24")}, "203.0.113.7:1234", "", http.StatusForbidden},
+		{"deny only, other network", IPFilter{Deny: networks("203.0.113.0/24")}, "198.51.100.4:1234", "", http.StatusOK},
+		{"disabled", IPFilter{}, "203.0.113.7:1234", "", http.StatusOK},
+	}
+
+	logger := zerolog.Nop()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			rec := httptest.NewRecorder()
+
+			// Behind RealIP, as in the server
+			RealIP(tt.filter.TrustedProxies)(RequireIP(tt.filter, &logger)(ok)).ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
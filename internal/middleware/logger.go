@@ -2,9 +2,21 @@
 package middleware
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
+	"user-auth-app/internal/audit"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog"
 )
 
@@ -12,37 +24,93 @@ type responseWriter struct {
 	http.ResponseWriter
 	statusCode   int
 	bytesWritten int
+	wroteHeader  bool
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
+	if !rw.wroteHeader {
+		rw.statusCode = code
+		rw.wroteHeader = true
+	}
 	rw.ResponseWriter.WriteHeader(code)
 }
 
 func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
 	n, err := rw.ResponseWriter.Write(b)
 	rw.bytesWritten += n
 	return n, err
 }
 
-// Logger creates a logging middleware
-func Logger(logger *zerolog.Logger) func(next http.Handler) http.Handler {
+// Flush implements http.Flusher so streaming responses (SSE) keep working
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker so websocket upgrades keep working
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("underlying ResponseWriter does not implement http.Hijacker")
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// maxLoggedBodyBytes caps how much of a request body is captured for
+// logging
+const maxLoggedBodyBytes = 4 << 10
+
+// redactedValue replaces sensitive values in logged request bodies
+const redactedValue = "[REDACTED]"
+
+// sensitiveFieldMarkers match JSON keys whose values are never logged,
+// e.g. password, new_password, token and refresh_token
+var sensitiveFieldMarkers = []string{"password", "token", "secret"}
+
+// Logger creates an access log middleware. Each request is logged with its
+// method, route pattern, status, duration, client IP and request ID, so it
+// must run after RequestID and ClientInfo. With logBodies the JSON request
+// body is logged too, with sensitive fields redacted; that is meant for
+// local debugging only.
+//
+// It also stores a request-scoped logger carrying the request ID in the
+// context (see zerolog.Ctx), which authentication extends with the user
+// ID, so lines logged while handling the request can be correlated.
+func Logger(logger *zerolog.Logger, logBodies bool) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
-			
+
 			// Wrap response writer to capture status code
 			rw := &responseWriter{
 				ResponseWriter: w,
 				statusCode:     http.StatusOK,
 			}
 
+			// Copy whatever the handler reads, leaving the body intact
+			var body *cappedBuffer
+			if logBodies && r.Body != nil && r.Body != http.NoBody {
+				body = &cappedBuffer{limit: maxLoggedBodyBytes}
+				r.Body = teeReadCloser{Reader: io.TeeReader(r.Body, body), Closer: r.Body}
+			}
+
+			requestLogger := logger.With().Str("request_id", chimiddleware.GetReqID(r.Context())).Logger()
+			r = r.WithContext(requestLogger.WithContext(r.Context()))
+
 			// Call next handler
 			next.ServeHTTP(rw, r)
 
 			// Log request details
 			duration := time.Since(start)
-			
+
 			logEvent := logger.Info()
 			if rw.statusCode >= 500 {
 				logEvent = logger.Error()
@@ -51,14 +119,131 @@ func Logger(logger *zerolog.Logger) func(next http.Handler) http.Handler {
 			}
 
 			logEvent.
+				Str("request_id", chimiddleware.GetReqID(r.Context())).
 				Str("method", r.Method).
-				Str("path", r.URL.Path).
-				Str("remote_addr", r.RemoteAddr).
+				Str("path", logPath(r)).
+				Str("client_ip", audit.ClientFromContext(r.Context()).IPAddress).
 				Str("user_agent", r.UserAgent()).
 				Int("status", rw.statusCode).
 				Int("bytes", rw.bytesWritten).
-				Dur("duration", duration).
-				Msg("request completed")
+				Dur("duration", duration)
+
+			if body != nil && body.Len() > 0 {
+				logEvent.RawJSON("body", redactBody(body))
+			}
+
+			logEvent.Msg("request completed")
 		})
 	}
-}
\ No newline at end of file
+}
+
+// withUserLogger adds the authenticated user's ID to the request-scoped
+// logger, if there is one
+func withUserLogger(ctx context.Context, userID int32) context.Context {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		return ctx
+	}
+	userLogger := logger.With().Int32("user_id", userID).Logger()
+	return userLogger.WithContext(ctx)
+}
+
+// UnknownRoute is the RoutePattern of requests that matched no route
+const UnknownRoute = "unknown"
+
+// RoutePattern returns the matched chi route pattern (e.g.
+// /api/v1/users/{id}), which keeps metric label cardinality bounded, or
+// UnknownRoute if nothing matched
+func RoutePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return UnknownRoute
+}
+
+// logPath returns the matched route pattern so IDs in URLs don't end up
+// in logs, or the raw path if nothing matched
+func logPath(r *http.Request) string {
+	if pattern := RoutePattern(r); pattern != UnknownRoute {
+		return pattern
+	}
+	return r.URL.Path
+}
+
+// redactBody returns the captured body as JSON with sensitive fields
+// replaced. Bodies that aren't complete JSON can't be redacted reliably,
+// so only a placeholder is logged for them.
+func redactBody(body *cappedBuffer) []byte {
+	var v interface{}
+	if body.truncated || json.Unmarshal(body.Bytes(), &v) != nil {
+		placeholder, _ := json.Marshal(fmt.Sprintf("[unlogged body, %d bytes]", body.total))
+		return placeholder
+	}
+
+	redacted, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return []byte(`"[unlogged body]"`)
+	}
+	return redacted
+}
+
+// redactValue replaces the values of sensitive keys at any depth
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if isSensitiveField(key) {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = redactValue(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func isSensitiveField(key string) bool {
+	key = strings.ToLower(key)
+	for _, marker := range sensitiveFieldMarkers {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// cappedBuffer keeps the first limit bytes written to it and counts the
+// rest
+type cappedBuffer struct {
+	bytes.Buffer
+	limit     int
+	total     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.total += len(p)
+	if room := b.limit - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+// teeReadCloser reads through a TeeReader while closing the original body
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveLogged sends req through Logger and a chi route that echoes the
+// body, returning the handler's view of the body and the log line
+func serveLogged(t *testing.T, logBodies bool, req *http.Request) (string, map[string]interface{}) {
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+
+	var handlerBody string
+	r := chi.NewRouter()
+	r.Use(chimiddleware.RequestID)
+	r.Use(ClientInfo)
+	r.Use(Logger(&logger, logBodies))
+	r.Post("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		handlerBody = string(b)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+	return handlerBody, entry
+}
+
+func TestLoggerFields(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/users/42", strings.NewReader(`{}`))
+	req.RemoteAddr = "203.0.113.7:5000"
+
+	_, entry := serveLogged(t, false, req)
+
+	assert.Equal(t, "POST", entry["method"])
+	assert.Equal(t, "/users/{id}", entry["path"])
+	assert.Equal(t, float64(http.StatusCreated), entry["status"])
+	assert.Equal(t, "203.0.113.7", entry["client_ip"])
+	assert.NotEmpty(t, entry["request_id"])
+	assert.Contains(t, entry, "duration")
+	assert.NotContains(t, entry, "body")
+}
+
+func TestLoggerRedactsBody(t *testing.T) {
+	body := `{"email":"a@example.com","password":"Secret-123","nested":{"refresh_token":"abc"},"items":[{"new_password":"x"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/users/1", strings.NewReader(body))
+
+	handlerBody, entry := serveLogged(t, true, req)
+
+	assert.Equal(t, body, handlerBody, "handler must see the whole body")
+
+	logged, _ := json.Marshal(entry["body"])
+	assert.JSONEq(t, `{"email":"a@example.com","password":"[REDACTED]","nested":{"refresh_token":"[REDACTED]"},"items":[{"new_password":"[REDACTED]"}]}`, string(logged))
+	assert.NotContains(t, string(logged), "Secret-123")
+}
+
+func TestLoggerSkipsUnparsableBody(t *testing.T) {
+	tests := map[string]string{
+		"not json":  "password=Secret-123",
+		"truncated": `{"password":"Secret-123","pad":"` + strings.Repeat("x", maxLoggedBodyBytes) + `"}`,
+	}
+
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users/1", strings.NewReader(body))
+
+			handlerBody, entry := serveLogged(t, true, req)
+
+			assert.Equal(t, body, handlerBody)
+			assert.NotContains(t, entry["body"], "Secret-123")
+			assert.Contains(t, entry["body"], "unlogged body")
+		})
+	}
+}
+
+func TestLoggerStoresRequestLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+
+	r := chi.NewRouter()
+	r.Use(chimiddleware.RequestID)
+	r.Use(Logger(&logger, false))
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		ctx := withUserLogger(r.Context(), 7)
+		zerolog.Ctx(ctx).Info().Msg("handled")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, lines, 2)
+	var handled, access map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &handled))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &access))
+
+	assert.Equal(t, "handled", handled["message"])
+	assert.Equal(t, float64(7), handled["user_id"])
+	assert.NotEmpty(t, handled["request_id"])
+	assert.Equal(t, access["request_id"], handled["request_id"])
+}
+
+func TestRoutePattern(t *testing.T) {
+	var pattern string
+	r := chi.NewRouter()
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		pattern = RoutePattern(r)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	assert.Equal(t, "/users/{id}", pattern)
+
+	assert.Equal(t, UnknownRoute, RoutePattern(httptest.NewRequest(http.MethodGet, "/users/42", nil)))
+}
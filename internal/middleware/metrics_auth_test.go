@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireMetricsAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	both := MetricsAuth{Token: "scrape-token", Username: "prometheus", Password: "s3cret"}
+
+	tests := []struct {
+		name          string
+		auth          MetricsAuth
+		setup         func(r *http.Request)
+		want          int
+		wantChallenge string
+	}{
+		{"open when unconfigured", MetricsAuth{}, func(r *http.Request) {}, http.StatusOK, ""},
+		{"missing credentials", both, func(r *http.Request) {}, http.StatusUnauthorized, `Basic realm="metrics", charset="UTF-8"`},
+		{"valid token", both, func(r *http.Request) { r.Header.Set("Authorization", "Bearer scrape-token") }, http.StatusOK, ""},
+		{"wrong token", both, func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized, `Basic realm="metrics", charset="UTF-8"`},
+		{"valid basic auth", both, func(r *http.Request) { r.SetBasicAuth("prometheus", "s3cret") }, http.StatusOK, ""},
+		{"wrong password", both, func(r *http.Request) { r.SetBasicAuth("prometheus", "guess") }, http.StatusUnauthorized, `Basic realm="metrics", charset="UTF-8"`},
+		{"basic auth not configured", MetricsAuth{Token: "scrape-token"}, func(r *http.Request) { r.SetBasicAuth("prometheus", "scrape-token") }, http.StatusUnauthorized, `Bearer realm="metrics"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			tt.setup(req)
+			rec := httptest.NewRecorder()
+
+			RequireMetricsAuth(tt.auth)(ok).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			assert.Equal(t, tt.wantChallenge, rec.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
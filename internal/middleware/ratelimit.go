@@ -2,56 +2,34 @@ package middleware
 
 import (
 	"net/http"
-	"sync"
-	"time"
+	"strconv"
 
-	"golang.org/x/time/rate"
+	"user-auth-app/internal/ratelimit"
 )
 
-type rateLimiter struct {
-	limiter  *rate.Limiter
-	lastSeen time.Time
-}
-
-// RateLimiter creates a simple IP-based rate limiter
-// rps = requests per second, burst = max burst size
-func RateLimiter(rps int, burst int) func(next http.Handler) http.Handler {
-	var (
-		mu      sync.Mutex
-		clients = make(map[string]*rateLimiter)
-	)
-
-	// Cleanup old entries every minute
-	go func() {
-		ticker := time.NewTicker(time.Minute)
-		defer ticker.Stop()
-		for range ticker.C {
-			mu.Lock()
-			for ip, rl := range clients {
-				if time.Since(rl.lastSeen) > 3*time.Minute {
-					delete(clients, ip)
-				}
-			}
-			mu.Unlock()
-		}
-	}()
-
+// RateLimiter limits each client IP address with limiter
+func RateLimiter(limiter ratelimit.Limiter) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := r.RemoteAddr
-
-			mu.Lock()
-			rl, exists := clients[ip]
-			if !exists {
-				rl = &rateLimiter{
-					limiter: rate.NewLimiter(rate.Limit(rps), burst),
-				}
-				clients[ip] = rl
+			if !limiter.Allow(r.Context(), r.RemoteAddr) {
+				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
 			}
-			rl.lastSeen = time.Now()
-			mu.Unlock()
 
-			if !rl.limiter.Allow() {
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UserRateLimiter limits each authenticated user with limiter, whatever
+// addresses their requests come from. It must run after the
+// authentication middleware; requests without claims pass through. Share
+// one limiter between route groups so a user has a single budget.
+func UserRateLimiter(limiter ratelimit.Limiter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := GetUserFromContext(r.Context())
+			if ok && !limiter.Allow(r.Context(), strconv.FormatInt(int64(claims.UserID), 10)) {
 				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
 				return
 			}
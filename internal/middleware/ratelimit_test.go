@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"user-auth-app/internal/ratelimit"
+	"user-auth-app/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRateLimiterKeysOnUser(t *testing.T) {
+	h := UserRateLimiter(ratelimit.NewMemory(1, 2))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(userID int32, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+		req.RemoteAddr = remoteAddr
+		if userID != 0 {
+			req = req.WithContext(context.WithValue(req.Context(), UserContextKey, &service.TokenClaims{UserID: userID}))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Changing address doesn't reset a user's budget
+	assert.Equal(t, http.StatusOK, request(1, "203.0.113.1:1000"))
+	assert.Equal(t, http.StatusOK, request(1, "203.0.113.2:1000"))
+	assert.Equal(t, http.StatusTooManyRequests, request(1, "203.0.113.3:1000"))
+
+	// Other users behind the same address have their own
+	assert.Equal(t, http.StatusOK, request(2, "203.0.113.1:1000"))
+
+	// Unauthenticated requests are left to the per-IP limiter
+	for range 3 {
+		assert.Equal(t, http.StatusOK, request(0, "203.0.113.1:1000"))
+	}
+}
@@ -15,6 +15,12 @@ func Recovery(logger *zerolog.Logger) func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
+					// A handler aborting a response it already started,
+					// e.g. a failed stream; net/http closes the connection
+					if err == http.ErrAbortHandler {
+						panic(err)
+					}
+
 					// Log the panic with stack trace
 					logger.Error().
 						Interface("panic", err).
@@ -0,0 +1,97 @@
+// Package middleware resolves the tenant a request is for
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"user-auth-app/internal/service"
+	"user-auth-app/internal/tenant"
+)
+
+// TenantHeader names the tenant a request is for
+const TenantHeader = "X-Tenant-ID"
+
+// Tenant resolves the tenant from the X-Tenant-ID header or, when
+// baseDomain is set, the subdomain of the request host (acme.example.com
+// for base domain example.com is tenant "acme"), and stores it in the
+// request context. Requests naming neither use tenant.Default. Malformed
+// tenant IDs, or a header and subdomain that disagree, get 400.
+//
+// The resolved tenant only selects where unauthenticated requests such
+// as registration and login look users up; authenticated requests are
+// bound to the tenant in their credentials.
+func Tenant(baseDomain string) func(next http.Handler) http.Handler {
+	suffix := ""
+	if baseDomain != "" {
+		suffix = "." + strings.ToLower(strings.TrimPrefix(baseDomain, "."))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fromHeader := strings.ToLower(strings.TrimSpace(r.Header.Get(TenantHeader)))
+			fromHost := subdomain(r.Host, suffix)
+
+			id := fromHeader
+			if id == "" {
+				id = fromHost
+			}
+			if id == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !tenant.Valid(id) {
+				respondBadRequest(w, "Invalid tenant ID")
+				return
+			}
+			if fromHeader != "" && fromHost != "" && fromHeader != fromHost {
+				respondBadRequest(w, "X-Tenant-ID does not match the request host")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(tenant.WithID(r.Context(), id)))
+		})
+	}
+}
+
+// subdomain returns the part of host before suffix, or "" if host is not
+// a subdomain of it
+func subdomain(host, suffix string) string {
+	if suffix == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	if !strings.HasSuffix(host, suffix) {
+		return ""
+	}
+	return strings.TrimSuffix(host, suffix)
+}
+
+// bindTenant scopes an authenticated request to the tenant its
+// credentials belong to. It returns false if the request explicitly named
+// a different tenant.
+func bindTenant(ctx context.Context, claims *service.TokenClaims) (context.Context, bool) {
+	id := claims.TenantID
+	if id == "" {
+		id = tenant.Default
+	}
+
+	if requested, ok := tenant.FromContext(ctx); ok && requested != id {
+		return ctx, false
+	}
+	return tenant.WithID(ctx, id), true
+}
+
+func respondBadRequest(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"user-auth-app/internal/service"
+	"user-auth-app/internal/tenant"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantResolution(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		header     string
+		wantCode   int
+		wantTenant string
+	}{
+		{"header", "auth.example.com", "acme", http.StatusOK, "acme"},
+		{"header is case-insensitive", "auth.example.com", "ACME", http.StatusOK, "acme"},
+		{"subdomain", "acme.auth.example.com:8080", "", http.StatusOK, "acme"},
+		{"header matching subdomain", "acme.auth.example.com", "acme", http.StatusOK, "acme"},
+		{"neither", "auth.example.com", "", http.StatusOK, tenant.Default},
+		{"other domain", "acme.example.org", "", http.StatusOK, tenant.Default},
+		{"malformed", "auth.example.com", "acme_corp", http.StatusBadRequest, ""},
+		{"nested subdomain", "a.b.auth.example.com", "", http.StatusBadRequest, ""},
+		{"header and subdomain disagree", "acme.auth.example.com", "globex", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = tenant.ID(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/login", nil)
+			req.Host = tt.host
+			if tt.header != "" {
+				req.Header.Set(TenantHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			Tenant("auth.example.com")(next).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantTenant, got)
+		})
+	}
+}
+
+func TestAuthMiddlewareBindsTokenTenant(t *testing.T) {
+	logger := zerolog.Nop()
+	auth := stubAuthService{claims: &service.TokenClaims{UserID: 1, TenantID: "acme", Scope: service.ScopeFull}}
+
+	var got string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = tenant.ID(r.Context())
+	})
+	handler := Tenant("")(AuthMiddleware(auth, &logger, CookieAuth{})(next))
+
+	t.Run("no tenant named", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "acme", got, "the token's tenant scopes the request")
+	})
+
+	t.Run("other tenant named", func(t *testing.T) {
+		got = ""
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set(TenantHeader, "globex")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, got)
+	})
+}
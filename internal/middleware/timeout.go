@@ -0,0 +1,36 @@
+// Package middleware provides request timeouts
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Timeout gives each request timeout to complete. The request's context
+// is cancelled at the deadline, and if the handler hasn't responded by
+// then the client gets 503 with a JSON error instead. Responses are
+// buffered until the handler returns, so don't use it on streaming routes.
+func Timeout(timeout time.Duration) func(next http.Handler) http.Handler {
+	body, _ := json.Marshal(map[string]string{"error": "Request timed out"})
+
+	return func(next http.Handler) http.Handler {
+		h := http.TimeoutHandler(next, timeout, string(body))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(timeoutResponseWriter{w}, r)
+		})
+	}
+}
+
+// timeoutResponseWriter labels the body http.TimeoutHandler writes on
+// timeout as JSON. Handler responses pass through with their own headers.
+type timeoutResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w timeoutResponseWriter) WriteHeader(statusCode int) {
+	if statusCode == http.StatusServiceUnavailable && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
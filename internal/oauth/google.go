@@ -0,0 +1,101 @@
+// Package oauth implements sign-in through external OAuth2 providers
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"user-auth-app/internal/domain"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// ProviderGoogle is the provider name stored on users who signed in with
+// Google
+const ProviderGoogle = "google"
+
+const (
+	googleUserInfoURL = "https://openidconnect.googleapis.com/v1/userinfo"
+	userInfoTimeout   = 10 * time.Second
+	maxUserInfoBytes  = 1 << 20
+)
+
+// ErrExchangeFailed is returned when the provider rejects the
+// authorization code or its user info cannot be read
+var ErrExchangeFailed = errors.New("oauth exchange failed")
+
+// Google runs the OpenID Connect authorization code flow against Google
+type Google struct {
+	config      *oauth2.Config
+	userInfoURL string
+	client      *http.Client
+}
+
+// NewGoogle creates a Google provider for the given OAuth client
+func NewGoogle(clientID, clientSecret, redirectURL string) *Google {
+	return &Google{
+		config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Endpoint:     google.Endpoint,
+			Scopes:       []string{"openid", "email", "profile"},
+		},
+		userInfoURL: googleUserInfoURL,
+		client:      &http.Client{Timeout: userInfoTimeout},
+	}
+}
+
+// AuthCodeURL returns the consent page URL. The verifier is the PKCE
+// secret that must be presented again in Identity.
+func (g *Google) AuthCodeURL(state, verifier string) string {
+	return g.config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+}
+
+// Identity exchanges an authorization code for the signed-in Google
+// account's identity
+func (g *Google) Identity(ctx context.Context, code, verifier string) (domain.ExternalIdentity, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, g.client)
+
+	token, err := g.config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return domain.ExternalIdentity{}, fmt.Errorf("%w: %v", ErrExchangeFailed, err)
+	}
+
+	resp, err := g.config.Client(ctx, token).Get(g.userInfoURL)
+	if err != nil {
+		return domain.ExternalIdentity{}, fmt.Errorf("%w: fetch user info: %v", ErrExchangeFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return domain.ExternalIdentity{}, fmt.Errorf("%w: user info returned %d", ErrExchangeFailed, resp.StatusCode)
+	}
+
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxUserInfoBytes)).Decode(&info); err != nil {
+		return domain.ExternalIdentity{}, fmt.Errorf("%w: decode user info: %v", ErrExchangeFailed, err)
+	}
+	if info.Sub == "" || info.Email == "" {
+		return domain.ExternalIdentity{}, fmt.Errorf("%w: user info missing sub or email", ErrExchangeFailed)
+	}
+
+	return domain.ExternalIdentity{
+		Provider:      ProviderGoogle,
+		ProviderID:    info.Sub,
+		Email:         info.Email,
+		EmailVerified: info.EmailVerified,
+		Name:          info.Name,
+	}, nil
+}
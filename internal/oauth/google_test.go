@@ -0,0 +1,86 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// newTestGoogle points a provider at a fake token and userinfo server
+func newTestGoogle(t *testing.T, userInfo string) *Google {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.PostForm.Get("code") != "good-code" || r.PostForm.Get("code_verifier") != "verifier" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"access","token_type":"Bearer","expires_in":3600}`))
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(userInfo))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	g := NewGoogle("client", "secret", "http://localhost/api/v1/auth/google/callback")
+	g.config.Endpoint = oauth2.Endpoint{AuthURL: srv.URL + "/auth", TokenURL: srv.URL + "/token"}
+	g.userInfoURL = srv.URL + "/userinfo"
+	return g
+}
+
+func TestGoogleAuthCodeURLUsesPKCE(t *testing.T) {
+	g := NewGoogle("client", "secret", "http://localhost/callback")
+
+	u, err := url.Parse(g.AuthCodeURL("state", "verifier"))
+	require.NoError(t, err)
+
+	q := u.Query()
+	assert.Equal(t, "state", q.Get("state"))
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	assert.Equal(t, oauth2.S256ChallengeFromVerifier("verifier"), q.Get("code_challenge"))
+	assert.Contains(t, q.Get("scope"), "email")
+}
+
+func TestGoogleIdentity(t *testing.T) {
+	g := newTestGoogle(t, `{"sub":"42","email":"alice@example.com","email_verified":true,"name":"Alice"}`)
+
+	identity, err := g.Identity(context.Background(), "good-code", "verifier")
+	require.NoError(t, err)
+	assert.Equal(t, ProviderGoogle, identity.Provider)
+	assert.Equal(t, "42", identity.ProviderID)
+	assert.Equal(t, "alice@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+}
+
+func TestGoogleIdentityErrors(t *testing.T) {
+	t.Run("rejected code", func(t *testing.T) {
+		g := newTestGoogle(t, `{}`)
+		_, err := g.Identity(context.Background(), "bad-code", "verifier")
+		assert.ErrorIs(t, err, ErrExchangeFailed)
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		g := newTestGoogle(t, `{}`)
+		_, err := g.Identity(context.Background(), "good-code", "other")
+		assert.ErrorIs(t, err, ErrExchangeFailed)
+	})
+
+	t.Run("missing email", func(t *testing.T) {
+		g := newTestGoogle(t, `{"sub":"42"}`)
+		_, err := g.Identity(context.Background(), "good-code", "verifier")
+		assert.ErrorIs(t, err, ErrExchangeFailed)
+	})
+}
@@ -0,0 +1,402 @@
+// Package openapi builds OpenAPI 3 documents from a table of operations,
+// deriving request and response schemas from the json tags of the types
+// handlers encode, so the documented shapes can't drift from the real ones
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the OpenAPI version of built documents
+const Version = "3.0.3"
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// SecurityScheme is a way of authenticating requests
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Parameter is a path, query or header parameter of an operation
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// PathParam documents a required path parameter
+func PathParam(name, description string, schema *Schema) Parameter {
+	return Parameter{Name: name, In: "path", Description: description, Required: true, Schema: schema}
+}
+
+// QueryParam documents an optional query parameter
+func QueryParam(name, description string, schema *Schema) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// Schema is the subset of JSON Schema the generated documents use
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+}
+
+// String returns a string schema
+func String() *Schema { return &Schema{Type: "string"} }
+
+// Integer returns an integer schema
+func Integer() *Schema { return &Schema{Type: "integer"} }
+
+// IntegerRange returns an integer schema bounded by min and max
+func IntegerRange(min, max int) *Schema {
+	return &Schema{Type: "integer", Minimum: &min, Maximum: &max}
+}
+
+// Enum returns a string schema allowing only values
+func Enum(values ...string) *Schema { return &Schema{Type: "string", Enum: values} }
+
+// Response is one possible response of an operation. Body is a value of
+// the type the handler encodes, or nil for responses without a body.
+type Response struct {
+	Status      int
+	Description string
+	Body        any
+}
+
+// Operation documents one route. Request is a value of the type the
+// handler decodes from the body, or nil. Security names the schemes that
+// are accepted; operations without any are public.
+type Operation struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tag         string
+	Security    []string
+	Parameters  []Parameter
+	Request     any
+	// RequestTypes lists the media types of the request body; JSON by
+	// default
+	RequestTypes []string
+	Responses    []Response
+}
+
+// Spec describes a whole API
+type Spec struct {
+	Info            Info
+	Servers         []string
+	SecuritySchemes map[string]SecurityScheme
+	Operations      []Operation
+	// ResponseTypes are the media types every response body can be
+	// encoded in
+	ResponseTypes []string
+}
+
+type document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Servers    []server                        `json:"servers,omitempty"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components components                      `json:"components"`
+}
+
+type server struct {
+	URL string `json:"url"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Build renders spec as an OpenAPI JSON document
+func Build(spec Spec) ([]byte, error) {
+	b := &builder{schemas: make(map[string]*Schema), types: make(map[string]reflect.Type)}
+
+	doc := document{
+		OpenAPI: Version,
+		Info:    spec.Info,
+		Paths:   make(map[string]map[string]operation),
+		Components: components{
+			Schemas:         b.schemas,
+			SecuritySchemes: spec.SecuritySchemes,
+		},
+	}
+	for _, url := range spec.Servers {
+		doc.Servers = append(doc.Servers, server{URL: url})
+	}
+
+	responseTypes := spec.ResponseTypes
+	if len(responseTypes) == 0 {
+		responseTypes = []string{"application/json"}
+	}
+
+	for _, op := range spec.Operations {
+		method := strings.ToLower(op.Method)
+		if doc.Paths[op.Path] == nil {
+			doc.Paths[op.Path] = make(map[string]operation)
+		}
+		if _, exists := doc.Paths[op.Path][method]; exists {
+			return nil, fmt.Errorf("duplicate operation %s %s", op.Method, op.Path)
+		}
+
+		out := operation{
+			Summary:     op.Summary,
+			Description: op.Description,
+			OperationID: operationID(op.Method, op.Path),
+			Parameters:  op.Parameters,
+			Responses:   make(map[string]response),
+		}
+		if op.Tag != "" {
+			out.Tags = []string{op.Tag}
+		}
+		for _, name := range op.Security {
+			if _, ok := spec.SecuritySchemes[name]; !ok {
+				return nil, fmt.Errorf("%s %s: unknown security scheme %q", op.Method, op.Path, name)
+			}
+			out.Security = append(out.Security, map[string][]string{name: {}})
+		}
+
+		if op.Request != nil {
+			schema, err := b.schema(reflect.TypeOf(op.Request))
+			if err != nil {
+				return nil, fmt.Errorf("%s %s request: %w", op.Method, op.Path, err)
+			}
+			requestTypes := op.RequestTypes
+			if len(requestTypes) == 0 {
+				requestTypes = []string{"application/json"}
+			}
+			out.RequestBody = &requestBody{Required: true, Content: content(requestTypes, schema)}
+		}
+
+		if len(op.Responses) == 0 {
+			return nil, fmt.Errorf("%s %s: no responses", op.Method, op.Path)
+		}
+		for _, resp := range op.Responses {
+			description := resp.Description
+			if description == "" {
+				description = http.StatusText(resp.Status)
+			}
+			r := response{Description: description}
+			if resp.Body != nil {
+				schema, err := b.schema(reflect.TypeOf(resp.Body))
+				if err != nil {
+					return nil, fmt.Errorf("%s %s response %d: %w", op.Method, op.Path, resp.Status, err)
+				}
+				r.Content = content(responseTypes, schema)
+			}
+			out.Responses[strconv.Itoa(resp.Status)] = r
+		}
+
+		doc.Paths[op.Path][method] = out
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func content(mediaTypes []string, schema *Schema) map[string]mediaType {
+	c := make(map[string]mediaType, len(mediaTypes))
+	for _, t := range mediaTypes {
+		c[t] = mediaType{Schema: schema}
+	}
+	return c
+}
+
+// operationID derives a stable ID such as getApiV1UsersId from the route
+func operationID(method, path string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '-' || r == '.' || r == '_'
+	}) {
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
+
+// builder collects the named schemas referenced by a document
+type builder struct {
+	schemas map[string]*Schema
+	// types detects two different types sharing a name
+	types map[string]reflect.Type
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the schema of t, registering named structs as components
+// and referencing them
+func (b *builder) schema(t reflect.Type) (*Schema, error) {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s, err := b.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		if s.Ref != "" {
+			// Siblings of $ref are ignored, so nullable refs are left as is
+			return s, nil
+		}
+		s.Nullable = true
+		return s, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := b.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key of %s must be a string", t)
+		}
+		values, err := b.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		return b.ref(t)
+	case reflect.Interface:
+		// Any JSON value
+		return &Schema{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+// ref registers the named struct t and references it
+func (b *builder) ref(t reflect.Type) (*Schema, error) {
+	name := t.Name()
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+
+	if existing, ok := b.types[name]; ok {
+		if existing != t {
+			return nil, fmt.Errorf("schema name %s used by both %s and %s", name, existing, t)
+		}
+		return ref, nil
+	}
+	// Registered before building so recursive types terminate
+	b.types[name] = t
+
+	object, err := b.object(t)
+	if err != nil {
+		return nil, err
+	}
+	b.schemas[name] = object
+	return ref, nil
+}
+
+// object builds the schema of struct t the way encoding/json encodes it.
+// Fields without omitempty are always present and so required.
+func (b *builder) object(t reflect.Type) (*Schema, error) {
+	object := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// Embedded structs without a name are flattened into the parent
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded, err := b.object(field.Type)
+			if err != nil {
+				return nil, err
+			}
+			for property, schema := range embedded.Properties {
+				object.Properties[property] = schema
+			}
+			object.Required = append(object.Required, embedded.Required...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema, err := b.schema(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t, field.Name, err)
+		}
+		object.Properties[name] = schema
+		if !strings.Contains(options, "omitempty") {
+			object.Required = append(object.Required, name)
+		}
+	}
+
+	sort.Strings(object.Required)
+	return object, nil
+}
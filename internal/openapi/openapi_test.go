@@ -0,0 +1,89 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type base struct {
+	ID int32 `json:"id"`
+}
+
+type widget struct {
+	base
+	Name      string            `json:"name"`
+	Tags      []string          `json:"tags,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	UpdatedAt *time.Time        `json:"updated_at,omitempty"`
+	Parent    *widget           `json:"parent,omitempty"`
+	Ignored   string            `json:"-"`
+	internal  string
+}
+
+func build(t *testing.T, ops ...Operation) map[string]any {
+	t.Helper()
+	raw, err := Build(Spec{Info: Info{Title: "test", Version: "1"}, Operations: ops})
+	require.NoError(t, err)
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(raw, &doc))
+	return doc
+}
+
+func TestSchemasFollowJSONTags(t *testing.T) {
+	doc := build(t, Operation{
+		Method: http.MethodPost, Path: "/widgets",
+		Request:   []widget{},
+		Responses: []Response{{Status: http.StatusCreated, Body: widget{}}, {Status: http.StatusNoContent}},
+	})
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	w := schemas["widget"].(map[string]any)
+
+	properties := w["properties"].(map[string]any)
+	assert.ElementsMatch(t, []string{"id", "name", "tags", "labels", "updated_at", "parent"}, keys(properties))
+	assert.ElementsMatch(t, []any{"id", "name"}, w["required"], "omitempty fields are optional")
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time", "nullable": true}, properties["updated_at"])
+	assert.Equal(t, "#/components/schemas/widget", properties["parent"].(map[string]any)["$ref"])
+
+	op := doc["paths"].(map[string]any)["/widgets"].(map[string]any)["post"].(map[string]any)
+	assert.Equal(t, "postWidgets", op["operationId"])
+	request := op["requestBody"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+	assert.Equal(t, "array", request["type"])
+
+	responses := op["responses"].(map[string]any)
+	assert.Equal(t, "No Content", responses["204"].(map[string]any)["description"])
+	assert.NotContains(t, responses["204"], "content")
+}
+
+func TestBuildRejectsMistakes(t *testing.T) {
+	ok := []Response{{Status: http.StatusOK}}
+
+	_, err := Build(Spec{Operations: []Operation{
+		{Method: http.MethodGet, Path: "/a", Responses: ok},
+		{Method: http.MethodGet, Path: "/a", Responses: ok},
+	}})
+	assert.ErrorContains(t, err, "duplicate operation")
+
+	_, err = Build(Spec{Operations: []Operation{{Method: http.MethodGet, Path: "/a", Security: []string{"bearerAuth"}, Responses: ok}}})
+	assert.ErrorContains(t, err, "unknown security scheme")
+
+	_, err = Build(Spec{Operations: []Operation{{Method: http.MethodGet, Path: "/a"}}})
+	assert.ErrorContains(t, err, "no responses")
+
+	_, err = Build(Spec{Operations: []Operation{{Method: http.MethodGet, Path: "/a", Responses: []Response{{Status: http.StatusOK, Body: make(chan int)}}}}})
+	assert.ErrorContains(t, err, "unsupported type")
+}
+
+func keys(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
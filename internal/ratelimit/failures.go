@@ -0,0 +1,113 @@
+// Package ratelimit counts failures per client over a sliding window
+package ratelimit
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// FailureCounter blocks clients that failed for too many distinct
+// subjects, such as accounts, within a sliding window. Old failures age
+// out one by one, so a client is never blocked for longer than the window.
+type FailureCounter interface {
+	// Blocked returns how long the client identified by key must wait
+	// before trying again, or zero if it isn't blocked
+	Blocked(ctx context.Context, key string) time.Duration
+	// Fail records a failure of key for subject and reports whether key
+	// is blocked now. Repeated failures for one subject count once.
+	Fail(ctx context.Context, key, subject string) bool
+}
+
+// memoryFailures holds the time of each client's latest failure per
+// subject
+type memoryFailures struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	clients map[string]map[string]time.Time
+}
+
+// NewMemoryFailures creates a counter blocking clients once they failed
+// for limit subjects within window. Failures are counted per instance.
+func NewMemoryFailures(limit int, window time.Duration) FailureCounter {
+	f := &memoryFailures{
+		limit:   max(limit, 1),
+		window:  window,
+		clients: make(map[string]map[string]time.Time),
+	}
+
+	// Forget clients whose failures have all aged out
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			f.mu.Lock()
+			for key := range f.clients {
+				f.prune(key, time.Now())
+			}
+			f.mu.Unlock()
+		}
+	}()
+
+	return f
+}
+
+func (f *memoryFailures) Blocked(ctx context.Context, key string) time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.wait(key, time.Now())
+}
+
+func (f *memoryFailures) Fail(ctx context.Context, key, subject string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	f.prune(key, now)
+	failures, ok := f.clients[key]
+	if !ok {
+		failures = make(map[string]time.Time)
+		f.clients[key] = failures
+	}
+	failures[subject] = now
+	return f.wait(key, now) > 0
+}
+
+// wait returns how long until fewer than limit failures of key are
+// within the window. It must be called with mu held.
+func (f *memoryFailures) wait(key string, now time.Time) time.Duration {
+	f.prune(key, now)
+	failures := f.clients[key]
+	if len(failures) < f.limit {
+		return 0
+	}
+
+	times := make([]time.Time, 0, len(failures))
+	for _, at := range failures {
+		times = append(times, at)
+	}
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+
+	// The client is unblocked once this failure ages out
+	return max(times[len(times)-f.limit].Add(f.window).Sub(now), time.Millisecond)
+}
+
+// prune drops failures of key older than the window. It must be called
+// with mu held.
+func (f *memoryFailures) prune(key string, now time.Time) {
+	failures, ok := f.clients[key]
+	if !ok {
+		return
+	}
+	for subject, at := range failures {
+		if now.Sub(at) >= f.window {
+			delete(failures, subject)
+		}
+	}
+	if len(failures) == 0 {
+		delete(f.clients, key)
+	}
+}
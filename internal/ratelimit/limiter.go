@@ -0,0 +1,81 @@
+// Package ratelimit provides token bucket rate limiters keyed by client,
+// held in memory or in Redis
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Backends
+const (
+	// BackendMemory keeps buckets per instance
+	BackendMemory = "memory"
+	// BackendRedis shares buckets between instances
+	BackendRedis = "redis"
+)
+
+// Limiter decides whether a client may make a request now
+type Limiter interface {
+	// Allow takes a token from the bucket of the client identified by key
+	Allow(ctx context.Context, key string) bool
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// memoryLimiter holds a bucket per client key, forgetting clients that
+// have been idle for a few minutes
+type memoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	rps     int
+	burst   int
+}
+
+// NewMemory creates a limiter allowing each client rps requests per
+// second with bursts of up to burst. Limits apply per instance.
+func NewMemory(rps, burst int) Limiter {
+	l := &memoryLimiter{
+		buckets: make(map[string]*bucket),
+		rps:     rps,
+		burst:   burst,
+	}
+
+	// Cleanup old entries every minute
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			l.mu.Lock()
+			for key, b := range l.buckets {
+				if time.Since(b.lastSeen) > 3*time.Minute {
+					delete(l.buckets, key)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}()
+
+	return l
+}
+
+func (l *memoryLimiter) Allow(ctx context.Context, key string) bool {
+	l.mu.Lock()
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{
+			limiter: rate.NewLimiter(rate.Limit(l.rps), l.burst),
+		}
+		l.buckets[key] = b
+	}
+	b.lastSeen = time.Now()
+	l.mu.Unlock()
+
+	return b.limiter.Allow()
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/breaker"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiterKeepsBucketPerKey(t *testing.T) {
+	l := NewMemory(1, 2)
+	ctx := context.Background()
+
+	assert.True(t, l.Allow(ctx, "a"))
+	assert.True(t, l.Allow(ctx, "a"))
+	assert.False(t, l.Allow(ctx, "a"), "burst is used up")
+
+	assert.True(t, l.Allow(ctx, "b"), "other clients have their own bucket")
+}
+
+func TestRedisLimiterFallsBackToMemory(t *testing.T) {
+	// An address nothing listens on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	client := redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	t.Cleanup(func() { client.Close() })
+
+	logger := zerolog.Nop()
+	l := NewRedis(client, "test", 1, 2, breaker.Settings{FailureThreshold: 1, OpenTimeout: time.Minute}, &logger)
+	ctx := context.Background()
+
+	// Requests are still limited, by the instance, both while Redis fails
+	// and once the breaker has opened
+	assert.True(t, l.Allow(ctx, "a"))
+	assert.True(t, l.Allow(ctx, "a"))
+	assert.False(t, l.Allow(ctx, "a"))
+	assert.True(t, l.Allow(ctx, "b"))
+}
+
+func TestMemoryFailuresCountDistinctSubjects(t *testing.T) {
+	f := NewMemoryFailures(3, time.Minute)
+	ctx := context.Background()
+
+	assert.False(t, f.Fail(ctx, "a", "alice"))
+	assert.False(t, f.Fail(ctx, "a", "alice"), "repeated failures for one subject count once")
+	assert.False(t, f.Fail(ctx, "a", "bob"))
+	assert.Zero(t, f.Blocked(ctx, "a"))
+
+	assert.True(t, f.Fail(ctx, "a", "carol"))
+	wait := f.Blocked(ctx, "a")
+	assert.Greater(t, wait, 59*time.Second)
+	assert.LessOrEqual(t, wait, time.Minute)
+
+	assert.Zero(t, f.Blocked(ctx, "b"), "other clients aren't blocked")
+}
+
+func TestMemoryFailuresSlideOut(t *testing.T) {
+	f := NewMemoryFailures(2, 200*time.Millisecond)
+	ctx := context.Background()
+
+	f.Fail(ctx, "a", "alice")
+	time.Sleep(100 * time.Millisecond)
+	require.True(t, f.Fail(ctx, "a", "bob"))
+
+	// Unblocked once alice's failure ages out, not a full window after
+	// the latest failure
+	wait := f.Blocked(ctx, "a")
+	assert.Greater(t, wait, time.Duration(0))
+	assert.Less(t, wait, 150*time.Millisecond)
+
+	time.Sleep(wait)
+	assert.Zero(t, f.Blocked(ctx, "a"))
+	assert.True(t, f.Fail(ctx, "a", "carol"), "bob's failure still counts")
+}
+
+func TestRedisFailuresFallBackToMemory(t *testing.T) {
+	// An address nothing listens on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	client := redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	t.Cleanup(func() { client.Close() })
+
+	logger := zerolog.Nop()
+	f := NewRedisFailures(client, "test", 2, time.Minute, breaker.Settings{FailureThreshold: 1, OpenTimeout: time.Minute}, &logger)
+	ctx := context.Background()
+
+	// Counted in memory, both before and after the breaker opens
+	assert.False(t, f.Fail(ctx, "a", "alice"))
+	assert.True(t, f.Fail(ctx, "a", "bob"))
+	assert.Greater(t, f.Blocked(ctx, "a"), time.Duration(0))
+}
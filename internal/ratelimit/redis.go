@@ -0,0 +1,96 @@
+// Package ratelimit implements the Redis-backed limiter
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"user-auth-app/internal/breaker"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/sony/gobreaker"
+)
+
+// redisTimeout bounds the Redis round trip added to each request
+const redisTimeout = 250 * time.Millisecond
+
+// tokenBucket refills and takes from a bucket atomically. Buckets are
+// hashes of the remaining tokens and the time they were counted, and
+// expire once they would be full again. Time comes from Redis, so
+// instances with skewed clocks share buckets correctly.
+//
+// KEYS[1] is the bucket; ARGV is the refill rate per second and the burst.
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000000)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return allowed
+`)
+
+// redisLimiter enforces limits across instances. While Redis fails or its
+// breaker is open, requests are limited per instance instead.
+type redisLimiter struct {
+	client   *redis.Client
+	breaker  *gobreaker.TwoStepCircuitBreaker
+	prefix   string
+	rps      int
+	burst    int
+	fallback Limiter
+	logger   *zerolog.Logger
+}
+
+// NewRedis creates a limiter whose buckets live in Redis, so every
+// instance draws from the same budget. name distinguishes the buckets of
+// different limiters.
+func NewRedis(client *redis.Client, name string, rps, burst int, breakerSettings breaker.Settings, logger *zerolog.Logger) Limiter {
+	return &redisLimiter{
+		client:   client,
+		breaker:  breaker.New("redis_ratelimit_"+name, breakerSettings, logger),
+		prefix:   "ratelimit:" + name + ":",
+		rps:      rps,
+		burst:    burst,
+		fallback: NewMemory(rps, burst),
+		logger:   logger,
+	}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string) bool {
+	done, err := l.breaker.Allow()
+	if err != nil {
+		return l.fallback.Allow(ctx, key)
+	}
+
+	redisCtx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	allowed, err := tokenBucket.Run(redisCtx, l.client, []string{l.prefix + key}, l.rps, l.burst).Int()
+	if err != nil {
+		// The caller giving up isn't a Redis failure
+		done(ctx.Err() != nil)
+		l.logger.Warn().Err(err).Msg("Redis rate limiter failed, limiting in memory")
+		return l.fallback.Allow(ctx, key)
+	}
+
+	done(true)
+	return allowed == 1
+}
@@ -0,0 +1,128 @@
+// Package ratelimit implements the Redis-backed failure counter
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"user-auth-app/internal/breaker"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/sony/gobreaker"
+)
+
+// Each client's failures are a sorted set of subjects scored by the time,
+// in milliseconds, of their latest failure. Entries older than the window
+// are dropped on every call and the set expires with its newest entry.
+// Time comes from Redis, so instances with skewed clocks agree.
+//
+// KEYS[1] is the set; ARGV is the subject, the window and the limit.
+var recordFailure = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local window = tonumber(ARGV[2])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+redis.call('ZADD', KEYS[1], now, ARGV[1])
+redis.call('PEXPIRE', KEYS[1], window)
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[3]) then
+	return 1
+end
+return 0
+`)
+
+// blockedFor returns the milliseconds until fewer than the limit of
+// failures are within the window, or 0 if that's already the case.
+//
+// KEYS[1] is the set; ARGV is the window and the limit.
+var blockedFor = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count < limit then
+	return 0
+end
+local oldest = redis.call('ZRANGE', KEYS[1], count - limit, count - limit, 'WITHSCORES')
+return math.max(tonumber(oldest[2]) + window - now, 1)
+`)
+
+// redisFailures shares failure counts between instances. While Redis
+// fails or its breaker is open, failures are counted per instance instead.
+type redisFailures struct {
+	client   *redis.Client
+	breaker  *gobreaker.TwoStepCircuitBreaker
+	prefix   string
+	limit    int
+	window   time.Duration
+	fallback FailureCounter
+	logger   *zerolog.Logger
+}
+
+// NewRedisFailures creates a counter whose failures live in Redis, so a
+// client is blocked on every instance at once. name distinguishes the
+// failures of different counters.
+func NewRedisFailures(client *redis.Client, name string, limit int, window time.Duration, breakerSettings breaker.Settings, logger *zerolog.Logger) FailureCounter {
+	return &redisFailures{
+		client:   client,
+		breaker:  breaker.New("redis_failures_"+name, breakerSettings, logger),
+		prefix:   "failures:" + name + ":",
+		limit:    max(limit, 1),
+		window:   window,
+		fallback: NewMemoryFailures(limit, window),
+		logger:   logger,
+	}
+}
+
+func (f *redisFailures) Blocked(ctx context.Context, key string) time.Duration {
+	done, err := f.breaker.Allow()
+	if err != nil {
+		return f.fallback.Blocked(ctx, key)
+	}
+
+	redisCtx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	wait, err := blockedFor.Run(redisCtx, f.client, []string{f.prefix + key}, f.window.Milliseconds(), f.limit).Int64()
+	if err != nil {
+		// The caller giving up isn't a Redis failure
+		done(ctx.Err() != nil)
+		f.logger.Warn().Err(err).Msg("Redis failure counter failed, counting in memory")
+		return f.fallback.Blocked(ctx, key)
+	}
+
+	done(true)
+	return time.Duration(wait) * time.Millisecond
+}
+
+func (f *redisFailures) Fail(ctx context.Context, key, subject string) bool {
+	done, err := f.breaker.Allow()
+	if err != nil {
+		return f.fallback.Fail(ctx, key, subject)
+	}
+
+	redisCtx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	blocked, err := recordFailure.Run(redisCtx, f.client, []string{f.prefix + key}, hashSubject(subject), f.window.Milliseconds(), f.limit).Int()
+	if err != nil {
+		done(ctx.Err() != nil)
+		f.logger.Warn().Err(err).Msg("Redis failure counter failed, counting in memory")
+		return f.fallback.Fail(ctx, key, subject)
+	}
+
+	done(true)
+	return blocked == 1
+}
+
+// hashSubject keeps subjects, such as email addresses, out of Redis
+func hashSubject(subject string) string {
+	sum := sha256.Sum256([]byte(subject))
+	return hex.EncodeToString(sum[:16])
+}
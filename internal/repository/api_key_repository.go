@@ -0,0 +1,176 @@
+// Package repository implements API key data access
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository/sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type apiKeyRepository struct {
+	db *sqlc.Queries
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(pool DB) APIKeyRepository {
+	return &apiKeyRepository{
+		db: sqlc.New(pool),
+	}
+}
+
+func (r *apiKeyRepository) CreateAPIKey(ctx context.Context, key domain.APIKey, keyHash string) (domain.APIKey, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	row, err := r.db.CreateAPIKey(ctx, sqlc.CreateAPIKeyParams{
+		UserID:  key.UserID,
+		Name:    key.Name,
+		Prefix:  key.Prefix,
+		KeyHash: keyHash,
+		Scopes:  scopes,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("create_api_key", queryStatus(err)).Inc()
+		if transient := transientError(err, "create api key"); transient != nil {
+			return domain.APIKey{}, transient
+		}
+		return domain.APIKey{}, fmt.Errorf("create api key failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("create_api_key", "success").Inc()
+
+	return apiKeyToDomain(row.ID, row.UserID, row.Name, row.Prefix, row.Scopes, row.CreatedAt, row.LastUsedAt), nil
+}
+
+func (r *apiKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (domain.APIKey, domain.User, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	row, err := r.db.GetAPIKeyByHash(ctx, keyHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			dbQueryTotal.WithLabelValues("get_api_key_by_hash", "not_found").Inc()
+			return domain.APIKey{}, domain.User{}, domain.ErrAPIKeyNotFound
+		}
+		dbQueryTotal.WithLabelValues("get_api_key_by_hash", queryStatus(err)).Inc()
+		if transient := transientError(err, "get api key"); transient != nil {
+			return domain.APIKey{}, domain.User{}, transient
+		}
+		return domain.APIKey{}, domain.User{}, fmt.Errorf("get api key failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("get_api_key_by_hash", "success").Inc()
+
+	key := apiKeyToDomain(row.ID, row.UserID, row.Name, row.Prefix, row.Scopes, row.CreatedAt, row.LastUsedAt)
+	owner := domain.User{
+		ID:       row.UserID,
+		TenantID: row.TenantID,
+		Email:    row.Email,
+		Role:     row.Role,
+	}
+	return key, owner, nil
+}
+
+func (r *apiKeyRepository) ListAPIKeys(ctx context.Context, userID int32) ([]domain.APIKey, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	rows, err := r.db.ListAPIKeysByUser(ctx, userID)
+	if err != nil {
+		dbQueryTotal.WithLabelValues("list_api_keys", queryStatus(err)).Inc()
+		if transient := transientError(err, "list api keys"); transient != nil {
+			return nil, transient
+		}
+		return nil, fmt.Errorf("list api keys failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("list_api_keys", "success").Inc()
+
+	keys := make([]domain.APIKey, len(rows))
+	for i, row := range rows {
+		keys[i] = apiKeyToDomain(row.ID, row.UserID, row.Name, row.Prefix, row.Scopes, row.CreatedAt, row.LastUsedAt)
+	}
+
+	return keys, nil
+}
+
+func (r *apiKeyRepository) RevokeAPIKey(ctx context.Context, userID, keyID int32) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	revoked, err := r.db.RevokeAPIKey(ctx, sqlc.RevokeAPIKeyParams{
+		ID:     keyID,
+		UserID: userID,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("revoke_api_key", queryStatus(err)).Inc()
+		if transient := transientError(err, "revoke api key"); transient != nil {
+			return transient
+		}
+		return fmt.Errorf("revoke api key failed: %w", err)
+	}
+
+	if revoked == 0 {
+		dbQueryTotal.WithLabelValues("revoke_api_key", "not_found").Inc()
+		return domain.ErrAPIKeyNotFound
+	}
+
+	dbQueryTotal.WithLabelValues("revoke_api_key", "success").Inc()
+	return nil
+}
+
+func (r *apiKeyRepository) TouchAPIKey(ctx context.Context, keyID int32) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	if err := r.db.TouchAPIKey(ctx, keyID); err != nil {
+		dbQueryTotal.WithLabelValues("touch_api_key", queryStatus(err)).Inc()
+		if transient := transientError(err, "touch api key"); transient != nil {
+			return transient
+		}
+		return fmt.Errorf("touch api key failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("touch_api_key", "success").Inc()
+	return nil
+}
+
+// apiKeyToDomain converts the columns shared by the API key query rows
+func apiKeyToDomain(id, userID int32, name, prefix string, scopes []string, createdAt, lastUsedAt pgtype.Timestamp) domain.APIKey {
+	key := domain.APIKey{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		CreatedAt: createdAt.Time,
+	}
+	if lastUsedAt.Valid {
+		lastUsed := lastUsedAt.Time
+		key.LastUsedAt = &lastUsed
+	}
+	return key
+}
@@ -0,0 +1,198 @@
+// Package repository implements audit log data access
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository/sqlc"
+	"user-auth-app/internal/tenant"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// exportAuditLogs selects the events of a time range, oldest first. It is
+// run directly rather than through sqlc, whose generated code collects
+// every row before returning.
+const exportAuditLogs = `SELECT id, user_id, action, resource, details, ip_address, user_agent, created_at
+FROM audit_logs
+WHERE created_at >= $1 AND created_at < $2 AND tenant_id = $3
+ORDER BY created_at, id`
+
+type auditRepository struct {
+	db   *sqlc.Queries
+	pool DB
+}
+
+// NewAuditRepository creates a new audit log repository
+func NewAuditRepository(pool DB) AuditRepository {
+	return &auditRepository{
+		db:   sqlc.New(pool),
+		pool: pool,
+	}
+}
+
+func (r *auditRepository) RecordAuditEvent(ctx context.Context, event domain.AuditEvent) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	details := map[string]interface{}{
+		"success": event.Success,
+	}
+	for k, v := range event.Details {
+		details[k] = v
+	}
+
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("marshal audit details: %w", err)
+	}
+
+	params := sqlc.CreateAuditLogParams{
+		Action:    event.Type,
+		Resource:  pgtype.Text{String: "auth", Valid: true},
+		Details:   detailsJSON,
+		IpAddress: pgtype.Text{String: event.IPAddress, Valid: event.IPAddress != ""},
+		UserAgent: pgtype.Text{String: event.UserAgent, Valid: event.UserAgent != ""},
+		TenantID:  tenant.ID(ctx),
+	}
+	if event.UserID != nil {
+		params.UserID = pgtype.Int4{Int32: *event.UserID, Valid: true}
+	}
+
+	if err := r.db.CreateAuditLog(ctx, params); err != nil {
+		dbQueryTotal.WithLabelValues("create_audit_log", queryStatus(err)).Inc()
+		if transient := transientError(err, "create audit log"); transient != nil {
+			return transient
+		}
+		return fmt.Errorf("create audit log failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("create_audit_log", "success").Inc()
+	return nil
+}
+
+// ListAuditEvents returns the most recent audit events of the tenant in
+// the context, newest first, optionally restricted to a single user
+func (r *auditRepository) ListAuditEvents(ctx context.Context, userID *int32, limit int) ([]domain.AuditEvent, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	params := sqlc.ListAuditLogsParams{
+		TenantID: tenant.ID(ctx),
+		RowLimit: int32(limit),
+	}
+	if userID != nil {
+		params.UserID = pgtype.Int4{Int32: *userID, Valid: true}
+	}
+
+	rows, err := r.db.ListAuditLogs(ctx, params)
+	if err != nil {
+		dbQueryTotal.WithLabelValues("list_audit_logs", queryStatus(err)).Inc()
+		if transient := transientError(err, "list audit logs"); transient != nil {
+			return nil, transient
+		}
+		return nil, fmt.Errorf("list audit logs failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("list_audit_logs", "success").Inc()
+
+	events := make([]domain.AuditEvent, len(rows))
+	for i, row := range rows {
+		events[i] = auditLogToDomain(row)
+	}
+
+	return events, nil
+}
+
+// ExportAuditEvents passes each event of the tenant in the context from
+// from up to but excluding to to fn, oldest first, as rows arrive from the database. It stops at the
+// first error fn returns.
+func (r *auditRepository) ExportAuditEvents(ctx context.Context, from, to time.Time, fn func(domain.AuditEvent) error) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	// created_at has no time zone and is written in UTC
+	rows, err := r.pool.Query(ctx, exportAuditLogs, from.UTC(), to.UTC(), tenant.ID(ctx))
+	if err != nil {
+		return r.exportError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row sqlc.AuditLog
+		if err := rows.Scan(
+			&row.ID,
+			&row.UserID,
+			&row.Action,
+			&row.Resource,
+			&row.Details,
+			&row.IpAddress,
+			&row.UserAgent,
+			&row.CreatedAt,
+		); err != nil {
+			return r.exportError(err)
+		}
+		if err := fn(auditLogToDomain(row)); err != nil {
+			dbQueryTotal.WithLabelValues("export_audit_logs", "aborted").Inc()
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return r.exportError(err)
+	}
+
+	dbQueryTotal.WithLabelValues("export_audit_logs", "success").Inc()
+	return nil
+}
+
+func (r *auditRepository) exportError(err error) error {
+	dbQueryTotal.WithLabelValues("export_audit_logs", queryStatus(err)).Inc()
+	if transient := transientError(err, "export audit logs"); transient != nil {
+		return transient
+	}
+	return fmt.Errorf("export audit logs failed: %w", err)
+}
+
+// auditLogToDomain converts a stored audit log row back into an event,
+// splitting the success flag out of the JSON details
+func auditLogToDomain(row sqlc.AuditLog) domain.AuditEvent {
+	event := domain.AuditEvent{
+		ID:        row.ID,
+		Type:      row.Action,
+		IPAddress: row.IpAddress.String,
+		UserAgent: row.UserAgent.String,
+		Timestamp: row.CreatedAt.Time,
+	}
+	if row.UserID.Valid {
+		userID := row.UserID.Int32
+		event.UserID = &userID
+	}
+
+	var details map[string]interface{}
+	if err := json.Unmarshal(row.Details, &details); err != nil {
+		return event
+	}
+
+	for k, v := range details {
+		if k == "success" {
+			event.Success, _ = v.(bool)
+			continue
+		}
+		if event.Details == nil {
+			event.Details = make(map[string]string)
+		}
+		event.Details[k] = fmt.Sprint(v)
+	}
+
+	return event
+}
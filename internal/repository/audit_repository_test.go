@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository/sqlc"
+	"user-auth-app/internal/tenant"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// emptyRows is a query result without rows
+type emptyRows struct {
+	pgx.Rows
+}
+
+func (emptyRows) Close()     {}
+func (emptyRows) Err() error { return nil }
+func (emptyRows) Next() bool { return false }
+
+// queryArgsPool records the arguments of every query, returning no rows
+type queryArgsPool struct {
+	argsPool
+}
+
+func (p *queryArgsPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	p.args = append(p.args, args)
+	return emptyRows{}, nil
+}
+
+func TestAuditEventsAreScopedToContextTenant(t *testing.T) {
+	pool := &queryArgsPool{}
+	repo := NewAuditRepository(pool)
+
+	acme := tenant.WithID(context.Background(), "acme")
+	require.NoError(t, repo.RecordAuditEvent(acme, domain.AuditEvent{Type: domain.AuditLoginSuccess}))
+	_, err := repo.ListAuditEvents(acme, nil, 10)
+	require.NoError(t, err)
+	require.NoError(t, repo.ExportAuditEvents(acme, time.Now().Add(-time.Hour), time.Now(), func(domain.AuditEvent) error { return nil }))
+
+	// No tenant in the context means the default tenant
+	require.NoError(t, repo.RecordAuditEvent(context.Background(), domain.AuditEvent{Type: domain.AuditLoginSuccess}))
+
+	require.Len(t, pool.args, 4)
+	assert.Equal(t, "acme", pool.args[0][6], "recorded in the tenant")
+	assert.Equal(t, "acme", pool.args[1][0], "listed for the tenant only")
+	assert.Equal(t, "acme", pool.args[2][2], "exported for the tenant only")
+	assert.Equal(t, tenant.Default, pool.args[3][6])
+}
+
+func TestAuditLogToDomain(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	event := auditLogToDomain(sqlc.AuditLog{
+		ID:        7,
+		UserID:    pgtype.Int4{Int32: 42, Valid: true},
+		Action:    domain.AuditLoginFailure,
+		Details:   []byte(`{"success":false,"reason":"invalid_password"}`),
+		IpAddress: pgtype.Text{String: "203.0.113.5", Valid: true},
+		UserAgent: pgtype.Text{String: "curl/8.0", Valid: true},
+		CreatedAt: pgtype.Timestamp{Time: createdAt, Valid: true},
+	})
+
+	if assert.NotNil(t, event.UserID) {
+		assert.Equal(t, int32(42), *event.UserID)
+	}
+	assert.Equal(t, int32(7), event.ID)
+	assert.Equal(t, domain.AuditLoginFailure, event.Type)
+	assert.False(t, event.Success)
+	assert.Equal(t, map[string]string{"reason": "invalid_password"}, event.Details)
+	assert.Equal(t, "203.0.113.5", event.IPAddress)
+	assert.Equal(t, "curl/8.0", event.UserAgent)
+	assert.Equal(t, createdAt, event.Timestamp)
+}
+
+func TestAuditLogToDomainWithoutUser(t *testing.T) {
+	event := auditLogToDomain(sqlc.AuditLog{
+		Action:  domain.AuditLoginFailure,
+		Details: []byte(`{"success":false}`),
+	})
+
+	assert.Nil(t, event.UserID)
+	assert.Nil(t, event.Details)
+}
@@ -0,0 +1,136 @@
+// Package repository guards database access with a circuit breaker
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"user-auth-app/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sony/gobreaker"
+)
+
+type breakerDB struct {
+	db      DB
+	breaker *gobreaker.TwoStepCircuitBreaker
+}
+
+// WithCircuitBreaker wraps db so that once the database keeps failing,
+// queries fail fast with domain.ErrUnavailable instead of waiting on a
+// connection. Statements inside a transaction are not guarded; Begin is.
+func WithCircuitBreaker(db DB, breaker *gobreaker.TwoStepCircuitBreaker) DB {
+	return &breakerDB{db: db, breaker: breaker}
+}
+
+func (b *breakerDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	done, err := b.allow()
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	tag, err := b.db.Exec(ctx, sql, args...)
+	done(!isDBFailure(err))
+	return tag, err
+}
+
+func (b *breakerDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	done, err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := b.db.Query(ctx, sql, args...)
+	if err != nil {
+		done(!isDBFailure(err))
+		return nil, err
+	}
+	return &breakerRows{Rows: rows, done: done}, nil
+}
+
+func (b *breakerDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	done, err := b.allow()
+	if err != nil {
+		return errRow{err: err}
+	}
+	return &breakerRow{row: b.db.QueryRow(ctx, sql, args...), done: done}
+}
+
+func (b *breakerDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	done, err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := b.db.Begin(ctx)
+	done(!isDBFailure(err))
+	return tx, err
+}
+
+func (b *breakerDB) allow() (func(success bool), error) {
+	done, err := b.breaker.Allow()
+	if err != nil {
+		return nil, fmt.Errorf("database %s: %w: %w", b.breaker.Name(), domain.ErrUnavailable, err)
+	}
+	return done, nil
+}
+
+// breakerRow reports the query outcome when the row is scanned, which is
+// when pgx surfaces QueryRow errors
+type breakerRow struct {
+	row  pgx.Row
+	done func(success bool)
+}
+
+func (r *breakerRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	r.done(!isDBFailure(err))
+	return err
+}
+
+// breakerRows reports the query outcome when the rows are closed
+type breakerRows struct {
+	pgx.Rows
+	done   func(success bool)
+	closed bool
+}
+
+func (r *breakerRows) Close() {
+	r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		r.done(!isDBFailure(r.Rows.Err()))
+	}
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...interface{}) error {
+	return r.err
+}
+
+// isDBFailure reports whether err means the database is unhealthy.
+// Missing rows, constraint violations and canceled requests don't count.
+func isDBFailure(err error) bool {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Connection exceptions, insufficient resources and operator
+		// intervention (e.g. shutdown)
+		switch pgErr.Code[:2] {
+		case "08", "53", "57":
+			return true
+		default:
+			return false
+		}
+	}
+
+	return true
+}
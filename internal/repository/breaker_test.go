@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/breaker"
+	"user-auth-app/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingPool fails every query with err and counts the queries it sees
+type failingPool struct {
+	err     error
+	queries int
+}
+
+func (p *failingPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	p.queries++
+	return pgconn.CommandTag{}, p.err
+}
+
+func (p *failingPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	p.queries++
+	return nil, p.err
+}
+
+func (p *failingPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	p.queries++
+	return errRow{err: p.err}
+}
+
+func (p *failingPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	p.queries++
+	return nil, p.err
+}
+
+func newBreakerRepository(pool DB) *userRepository {
+	logger := zerolog.Nop()
+	cb := breaker.New("postgres_test", breaker.Settings{FailureThreshold: 3, OpenTimeout: time.Hour}, &logger)
+	return newUserRepository(WithCircuitBreaker(pool, cb), nil, LockoutPolicy{})
+}
+
+func TestCircuitBreakerFailsFast(t *testing.T) {
+	ctx := context.Background()
+	pool := &failingPool{err: errors.New("dial tcp: connection refused")}
+	repo := newBreakerRepository(pool)
+
+	for i := 0; i < 3; i++ {
+		_, err := repo.GetUserByID(ctx, 1)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, domain.ErrUnavailable)
+	}
+	require.Equal(t, 3, pool.queries)
+
+	// The breaker is open: the database is no longer called
+	_, err := repo.GetUserByID(ctx, 1)
+	assert.ErrorIs(t, err, domain.ErrUnavailable)
+	assert.ErrorIs(t, repo.ResetFailedLogins(ctx, 1), domain.ErrUnavailable)
+	err = repo.WithTx(ctx, func(UserRepository) error { return nil })
+	assert.ErrorIs(t, err, domain.ErrUnavailable)
+
+	assert.Equal(t, 3, pool.queries)
+	assert.Equal(t, 503, domain.HTTPStatusCode(err))
+}
+
+func TestCircuitBreakerIgnoresQueryErrors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"no rows", pgx.ErrNoRows},
+		{"unique violation", &pgconn.PgError{Code: "23505", ConstraintName: "users_tenant_email_key"}},
+		{"canceled", context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &failingPool{err: tt.err}
+			repo := newBreakerRepository(pool)
+
+			for i := 0; i < 5; i++ {
+				_, err := repo.GetUserByID(ctx, 1)
+				require.Error(t, err)
+				assert.NotErrorIs(t, err, domain.ErrUnavailable)
+			}
+			assert.Equal(t, 5, pool.queries)
+		})
+	}
+}
+
+func TestIsDBFailure(t *testing.T) {
+	assert.True(t, isDBFailure(context.DeadlineExceeded))
+	assert.True(t, isDBFailure(&pgconn.PgError{Code: "57P01"}))
+	assert.True(t, isDBFailure(&pgconn.PgError{Code: "53300"}))
+	assert.False(t, isDBFailure(&pgconn.PgError{Code: "23503"}))
+	assert.False(t, isDBFailure(nil))
+}
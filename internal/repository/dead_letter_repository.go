@@ -0,0 +1,135 @@
+// Package repository implements dead letter data access. Dead letters
+// belong to no tenant.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository/sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type deadLetterRepository struct {
+	db *sqlc.Queries
+}
+
+// NewDeadLetterRepository creates a new dead letter repository
+func NewDeadLetterRepository(pool DB) DeadLetterRepository {
+	return &deadLetterRepository{
+		db: sqlc.New(pool),
+	}
+}
+
+func (r *deadLetterRepository) SaveDeadLetter(ctx context.Context, dl domain.DeadLetter) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	err := r.db.CreateDeadLetter(ctx, sqlc.CreateDeadLetterParams{
+		Subject:  dl.Subject,
+		Data:     dl.Data,
+		Attempts: dl.Attempts,
+		Error:    dl.Error,
+		FailedAt: pgtype.Timestamp{Time: dl.FailedAt.UTC(), Valid: true},
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("create_dead_letter", queryStatus(err)).Inc()
+		if transient := transientError(err, "save dead letter"); transient != nil {
+			return transient
+		}
+		return fmt.Errorf("save dead letter failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("create_dead_letter", "success").Inc()
+	return nil
+}
+
+func (r *deadLetterRepository) ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	rows, err := r.db.ListDeadLetters(ctx, int32(limit))
+	if err != nil {
+		dbQueryTotal.WithLabelValues("list_dead_letters", queryStatus(err)).Inc()
+		if transient := transientError(err, "list dead letters"); transient != nil {
+			return nil, transient
+		}
+		return nil, fmt.Errorf("list dead letters failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("list_dead_letters", "success").Inc()
+
+	deadLetters := make([]domain.DeadLetter, len(rows))
+	for i, row := range rows {
+		deadLetters[i] = deadLetterToDomain(row)
+	}
+	return deadLetters, nil
+}
+
+func (r *deadLetterRepository) GetDeadLetter(ctx context.Context, id int32) (domain.DeadLetter, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	row, err := r.db.GetDeadLetter(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+		dbQueryTotal.WithLabelValues("get_dead_letter", "not_found").Inc()
+		return domain.DeadLetter{}, domain.ErrDeadLetterNotFound
+	}
+	if err != nil {
+		dbQueryTotal.WithLabelValues("get_dead_letter", queryStatus(err)).Inc()
+		if transient := transientError(err, "get dead letter"); transient != nil {
+			return domain.DeadLetter{}, transient
+		}
+		return domain.DeadLetter{}, fmt.Errorf("get dead letter failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("get_dead_letter", "success").Inc()
+	return deadLetterToDomain(row), nil
+}
+
+func (r *deadLetterRepository) DeleteDeadLetter(ctx context.Context, id int32) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	deleted, err := r.db.DeleteDeadLetter(ctx, id)
+	if err != nil {
+		dbQueryTotal.WithLabelValues("delete_dead_letter", queryStatus(err)).Inc()
+		if transient := transientError(err, "delete dead letter"); transient != nil {
+			return transient
+		}
+		return fmt.Errorf("delete dead letter failed: %w", err)
+	}
+
+	if deleted == 0 {
+		dbQueryTotal.WithLabelValues("delete_dead_letter", "not_found").Inc()
+		return domain.ErrDeadLetterNotFound
+	}
+
+	dbQueryTotal.WithLabelValues("delete_dead_letter", "success").Inc()
+	return nil
+}
+
+func deadLetterToDomain(row sqlc.DeadLetter) domain.DeadLetter {
+	return domain.DeadLetter{
+		ID:       row.ID,
+		Subject:  row.Subject,
+		Data:     row.Data,
+		Attempts: row.Attempts,
+		Error:    row.Error,
+		FailedAt: row.FailedAt.Time,
+	}
+}
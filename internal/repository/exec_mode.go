@@ -0,0 +1,20 @@
+// Package repository configures how queries are sent to Postgres
+package repository
+
+import "github.com/jackc/pgx/v5"
+
+// QueryExecModes maps DB_QUERY_EXEC_MODE values, the names pgx accepts as
+// default_query_exec_mode in a connection string, to pgx modes.
+//
+// sqlc passes each query's fixed SQL text to pgx, so with cache_statement
+// every query is prepared once per connection and later runs skip parsing
+// and planning. Poolers that don't pin server connections, like PgBouncer
+// in transaction mode, can't keep prepared statements; use exec or
+// simple_protocol behind them.
+var QueryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
@@ -3,21 +3,154 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"user-auth-app/internal/domain"
 
 	"github.com/jackc/pgx/v5"
 )
 
-// UserRepository defines methods for user data access
+// UserRepository defines methods for user data access. Every method only
+// sees users of the tenant in the context.
 type UserRepository interface {
 	CreateUser(ctx context.Context, user domain.User, passwordHash string) (domain.User, error)
 	GetUserByEmail(ctx context.Context, email string) (domain.User, string, error)
 	GetUserByID(ctx context.Context, id int32) (domain.User, error)
-	GetUserByUsername(ctx context.Context, username string) (domain.User, error)
+	GetUserByUsername(ctx context.Context, username string) (domain.User, string, error)
+	// GetUserByProvider finds the user linked to an external identity
+	GetUserByProvider(ctx context.Context, provider, providerID string) (domain.User, error)
+	CreateOAuthUser(ctx context.Context, user domain.User, identity domain.ExternalIdentity) (domain.User, error)
+	LinkProvider(ctx context.Context, userID int32, identity domain.ExternalIdentity) error
+	// GetUsersByIDs returns the active users among ids in a single query,
+	// ordered by ID. Unknown IDs are skipped.
+	GetUsersByIDs(ctx context.Context, ids []int32) ([]domain.User, error)
 	UpdateUser(ctx context.Context, user domain.User) error
 	DeleteUser(ctx context.Context, id int32) error
 	ListUsers(ctx context.Context, limit, offset int) ([]domain.User, error)
+	// ListUsersToWarm returns up to limit active users of any tenant to
+	// preload into the cache: those among ids or, when ids is empty, the
+	// most recently logged in
+	ListUsersToWarm(ctx context.Context, ids []int32, limit int) ([]domain.User, error)
+	// SearchUsers returns a page of active users whose username or email
+	// contains query, case-insensitively, ordered by username, along with
+	// the total number of matches
+	SearchUsers(ctx context.Context, query string, limit, offset int) ([]domain.User, int64, error)
+	GetCollectionStats(ctx context.Context) (domain.UserCollectionStats, error)
+	UpdatePassword(ctx context.Context, userID int32, passwordHash string) error
+	// ChangePassword sets a new password hash and revokes the user's
+	// existing tokens
+	ChangePassword(ctx context.Context, userID int32, passwordHash string) error
+	// GetPasswordHistory returns up to limit of the user's previous
+	// password hashes, most recent first
+	GetPasswordHistory(ctx context.Context, userID int32, limit int) ([]string, error)
+	// AddPasswordHistory records a previous password hash and keeps only
+	// the keep most recent ones
+	AddPasswordHistory(ctx context.Context, userID int32, passwordHash string, keep int) error
+	// UpdateUserRole changes the role of an active user and revokes their
+	// tokens, returning the role they had, or domain.ErrUserNotFound if no
+	// active user has the ID
+	UpdateUserRole(ctx context.Context, userID int32, role string) (string, error)
+	// DeactivateUser deactivates a user and revokes their tokens,
+	// returning domain.ErrUserNotFound if no active user has the ID
+	DeactivateUser(ctx context.Context, userID int32) error
+	// GetTokenVersion returns the version tokens must carry to be valid
+	GetTokenVersion(ctx context.Context, userID int32) (int32, error)
+	GetPasswordHash(ctx context.Context, userID int32) (string, error)
+	IncrementFailedLogins(ctx context.Context, userID int32) (count int32, lockedUntil time.Time, err error)
+	ResetFailedLogins(ctx context.Context, userID int32) error
+
+	// CreateInvite stores an invite under the hash of its code
+	CreateInvite(ctx context.Context, invite domain.Invite, codeHash string) (domain.Invite, error)
+	// ConsumeInvite marks the invite with the code hash as used by the
+	// user, returning domain.ErrInviteInvalid, domain.ErrInviteExpired or
+	// domain.ErrInviteUsed if it can't be used
+	ConsumeInvite(ctx context.Context, codeHash string, userID int32) error
+	// LockInvite locks the invite with the code hash for the rest of the
+	// transaction, returning the same errors as ConsumeInvite if it can't
+	// be used
+	LockInvite(ctx context.Context, codeHash string) error
+
+	// WithTx runs fn against a transaction-scoped repository, committing
+	// on success and rolling back on error
+	WithTx(ctx context.Context, fn func(UserRepository) error) error
+}
+
+// AuditRepository defines methods for audit log persistence
+type AuditRepository interface {
+	RecordAuditEvent(ctx context.Context, event domain.AuditEvent) error
+	ListAuditEvents(ctx context.Context, userID *int32, limit int) ([]domain.AuditEvent, error)
+	// ExportAuditEvents streams the context tenant's events from from up
+	// to but excluding to into fn, oldest first, stopping at the first error
+	ExportAuditEvents(ctx context.Context, from, to time.Time, fn func(domain.AuditEvent) error) error
+}
+
+// APIKeyRepository defines methods for API key persistence. Keys are
+// stored and looked up by hash only.
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key domain.APIKey, keyHash string) (domain.APIKey, error)
+	// GetAPIKeyByHash returns an unrevoked key with its owner's ID,
+	// tenant, email and role. Keys of deactivated users are not found.
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (domain.APIKey, domain.User, error)
+	ListAPIKeys(ctx context.Context, userID int32) ([]domain.APIKey, error)
+	// RevokeAPIKey revokes one of the user's keys, returning
+	// domain.ErrAPIKeyNotFound if the user has no such active key
+	RevokeAPIKey(ctx context.Context, userID, keyID int32) error
+	// TouchAPIKey records that a key was just used
+	TouchAPIKey(ctx context.Context, keyID int32) error
+}
+
+// SessionRepository defines methods for session persistence. Sessions
+// are keyed by user ID, which is unique across tenants.
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session domain.Session) (domain.Session, error)
+	// SessionExists reports whether the user has an unexpired session
+	// with the ID
+	SessionExists(ctx context.Context, userID int32, sessionID string) (bool, error)
+	// ListSessions returns the user's unexpired sessions, most recently
+	// used first
+	ListSessions(ctx context.Context, userID int32) ([]domain.Session, error)
+	// TouchSession records that a session was just used and extends it to
+	// expiresAt, returning when it now expires, or
+	// domain.ErrSessionNotFound if it has expired or was revoked
+	TouchSession(ctx context.Context, userID int32, sessionID string, expiresAt time.Time) (time.Time, error)
+	// DeleteSession revokes one of the user's sessions, returning
+	// domain.ErrSessionNotFound if the user has no such session
+	DeleteSession(ctx context.Context, userID int32, sessionID string) error
+	DeleteUserSessions(ctx context.Context, userID int32) error
+	// DeleteExpiredSessions removes every expired session and returns how
+	// many there were
+	DeleteExpiredSessions(ctx context.Context) (int64, error)
+}
+
+// WebhookRepository defines methods for webhook persistence. Webhooks
+// are scoped to the tenant in the context.
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook domain.Webhook) (domain.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	// ListWebhooksForEvent returns the webhooks subscribed to event
+	ListWebhooksForEvent(ctx context.Context, event string) ([]domain.Webhook, error)
+	// DeleteWebhook returns domain.ErrWebhookNotFound if the tenant has no
+	// such webhook
+	DeleteWebhook(ctx context.Context, webhookID int32) error
+	RecordWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+	// ListWebhookDeliveries returns up to limit delivery attempts of the
+	// webhook, newest first
+	ListWebhookDeliveries(ctx context.Context, webhookID int32, limit int) ([]domain.WebhookDelivery, error)
+	// PurgeWebhookDeliveries deletes the delivery attempts of every tenant
+	// made before before, returning how many were deleted
+	PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+// DeadLetterRepository stores messages whose subscriber failed every
+// attempt, until they are replayed. Dead letters belong to no tenant.
+type DeadLetterRepository interface {
+	SaveDeadLetter(ctx context.Context, dl domain.DeadLetter) error
+	// ListDeadLetters returns up to limit dead letters, newest first
+	ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error)
+	// GetDeadLetter and DeleteDeadLetter return
+	// domain.ErrDeadLetterNotFound if there is no such dead letter
+	GetDeadLetter(ctx context.Context, id int32) (domain.DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, id int32) error
 }
 
 // TxManager handles database transactions
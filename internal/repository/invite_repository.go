@@ -0,0 +1,141 @@
+// Package repository implements invite data access. Invites live on the
+// user repository so registration can consume one in the transaction that
+// creates the user.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository/sqlc"
+	"user-auth-app/internal/tenant"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// CreateInvite stores an invite of the tenant in the context under the
+// hash of its code
+func (r *userRepository) CreateInvite(ctx context.Context, invite domain.Invite, codeHash string) (domain.Invite, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	params := sqlc.CreateInviteParams{
+		TenantID:  tenant.ID(ctx),
+		CodeHash:  codeHash,
+		ExpiresAt: pgtype.Timestamp{Time: invite.ExpiresAt.UTC(), Valid: true},
+	}
+	if invite.CreatedBy != nil {
+		params.CreatedBy = pgtype.Int4{Int32: *invite.CreatedBy, Valid: true}
+	}
+
+	row, err := r.db.CreateInvite(ctx, params)
+	if err != nil {
+		dbQueryTotal.WithLabelValues("create_invite", queryStatus(err)).Inc()
+		return domain.Invite{}, r.handleError(err, "create invite")
+	}
+
+	dbQueryTotal.WithLabelValues("create_invite", "success").Inc()
+	return inviteToDomain(row.ID, row.TenantID, row.CreatedBy, row.CreatedAt, row.ExpiresAt, row.UsedAt, row.UsedBy), nil
+}
+
+// ConsumeInvite marks the invite with the code hash as used by userID. If
+// the invite can't be used it returns domain.ErrInviteInvalid,
+// domain.ErrInviteExpired or domain.ErrInviteUsed.
+func (r *userRepository) ConsumeInvite(ctx context.Context, codeHash string, userID int32) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	rows, err := r.db.ConsumeInvite(ctx, sqlc.ConsumeInviteParams{
+		TenantID: tenant.ID(ctx),
+		CodeHash: codeHash,
+		UsedBy:   pgtype.Int4{Int32: userID, Valid: true},
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("consume_invite", queryStatus(err)).Inc()
+		return r.handleError(err, "consume invite")
+	}
+	if rows == 1 {
+		dbQueryTotal.WithLabelValues("consume_invite", "success").Inc()
+		return nil
+	}
+
+	// Nothing was consumed; look the invite up to tell the caller why
+	row, err := r.db.GetInviteByHash(ctx, sqlc.GetInviteByHashParams{
+		TenantID: tenant.ID(ctx),
+		CodeHash: codeHash,
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows):
+		err = domain.ErrInviteInvalid
+	case err != nil:
+		dbQueryTotal.WithLabelValues("consume_invite", queryStatus(err)).Inc()
+		return r.handleError(err, "consume invite")
+	case row.UsedAt.Valid:
+		err = domain.ErrInviteUsed
+	default:
+		err = domain.ErrInviteExpired
+	}
+
+	dbQueryTotal.WithLabelValues("consume_invite", "rejected").Inc()
+	return err
+}
+
+// LockInvite locks the invite with the code hash until the transaction
+// ends. It returns domain.ErrInviteInvalid, domain.ErrInviteExpired or
+// domain.ErrInviteUsed if the invite can't be used, so registration can
+// refuse a caller before looking at the username or email.
+func (r *userRepository) LockInvite(ctx context.Context, codeHash string) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	row, err := r.db.LockInviteByHash(ctx, sqlc.LockInviteByHashParams{
+		TenantID: tenant.ID(ctx),
+		CodeHash: codeHash,
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows):
+		err = domain.ErrInviteInvalid
+	case err != nil:
+		dbQueryTotal.WithLabelValues("lock_invite", queryStatus(err)).Inc()
+		return r.handleError(err, "lock invite")
+	case row.UsedAt.Valid:
+		err = domain.ErrInviteUsed
+	case !row.ExpiresAt.Time.After(time.Now()):
+		err = domain.ErrInviteExpired
+	default:
+		dbQueryTotal.WithLabelValues("lock_invite", "success").Inc()
+		return nil
+	}
+
+	dbQueryTotal.WithLabelValues("lock_invite", "rejected").Inc()
+	return err
+}
+
+func inviteToDomain(id int32, tenantID string, createdBy pgtype.Int4, createdAt, expiresAt, usedAt pgtype.Timestamp, usedBy pgtype.Int4) domain.Invite {
+	invite := domain.Invite{
+		ID:        id,
+		TenantID:  tenantID,
+		CreatedAt: createdAt.Time,
+		ExpiresAt: expiresAt.Time,
+	}
+	if createdBy.Valid {
+		invite.CreatedBy = &createdBy.Int32
+	}
+	if usedAt.Valid {
+		invite.UsedAt = &usedAt.Time
+	}
+	if usedBy.Valid {
+		invite.UsedBy = &usedBy.Int32
+	}
+	return invite
+}
@@ -0,0 +1,91 @@
+// Package repository applies database migrations
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"user-auth-app/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/rs/zerolog"
+)
+
+// Migrate applies the pending embedded migrations, logging each applied
+// version. Versions are tracked in schema_migrations like the migrate CLI
+// does, so either can be used on the same database. A Postgres advisory
+// lock is held while migrating: replicas starting together wait for each
+// other, and the later ones find nothing left to apply.
+func Migrate(pool *pgxpool.Pool, logger *zerolog.Logger) error {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return fmt.Errorf("load migrations: %w", err)
+	}
+
+	// Closing the database/sql handle leaves the pool open
+	driver, err := pgxmigrate.WithInstance(stdlib.OpenDBFromPool(pool), &pgxmigrate.Config{})
+	if err != nil {
+		return fmt.Errorf("open migration driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "pgx5", driver)
+	if err != nil {
+		return fmt.Errorf("init migrations: %w", err)
+	}
+	defer m.Close()
+
+	before, err := schemaVersion(m)
+	if err != nil {
+		return err
+	}
+
+	err = m.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		logger.Info().Uint("version", before).Msg("Database schema is up to date")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("apply migrations: %w", err)
+	}
+
+	after, err := schemaVersion(m)
+	if err != nil {
+		return err
+	}
+	logAppliedMigrations(src, before, after, logger)
+	return nil
+}
+
+// schemaVersion returns the database's migration version, 0 before the
+// first migration. A dirty version, left by a migration that failed
+// halfway, needs fixing by hand.
+func schemaVersion(m *migrate.Migrate) (uint, error) {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("migration %d failed partway; repair the schema and run make migrate-force version=%d", version, version-1)
+	}
+	return version, nil
+}
+
+// logAppliedMigrations logs every migration after before up to after
+func logAppliedMigrations(src source.Driver, before, after uint, logger *zerolog.Logger) {
+	next := src.First
+	if before > 0 {
+		next = func() (uint, error) { return src.Next(before) }
+	}
+
+	for version, err := next(); err == nil && version <= after; version, err = src.Next(version) {
+		logger.Info().Uint("version", version).Msg("Applied migration")
+	}
+}
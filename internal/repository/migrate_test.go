@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"user-auth-app/migrations"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrationsAreComplete(t *testing.T) {
+	src, err := iofs.New(migrations.FS, ".")
+	require.NoError(t, err)
+	defer src.Close()
+
+	// Versions are numbered without gaps and each can be rolled back
+	expected := uint(1)
+	version, err := src.First()
+	for ; err == nil; version, err = src.Next(version) {
+		assert.Equal(t, expected, version)
+		expected++
+
+		up, _, upErr := src.ReadUp(version)
+		if assert.NoError(t, upErr, "up migration %d", version) {
+			up.Close()
+		}
+		down, _, downErr := src.ReadDown(version)
+		if assert.NoError(t, downErr, "down migration %d", version) {
+			down.Close()
+		}
+	}
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.Greater(t, expected, uint(1), "no migrations embedded")
+}
+
+func TestLogAppliedMigrations(t *testing.T) {
+	src, err := iofs.New(migrations.FS, ".")
+	require.NoError(t, err)
+	defer src.Close()
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+
+	logAppliedMigrations(src, 0, 2, &logger)
+	logAppliedMigrations(src, 11, 13, &logger)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	for i, version := range []string{"1", "2", "12", "13"} {
+		assert.Contains(t, lines[i], `"version":`+version+`,`)
+	}
+}
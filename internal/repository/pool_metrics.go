@@ -0,0 +1,133 @@
+// Package repository exports connection pool statistics
+package repository
+
+import (
+	"context"
+	"time"
+
+	"user-auth-app/internal/repository/sqlc"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	dbPoolTotalConns = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_pool_total_conns",
+			Help: "Total number of connections currently in the pool",
+		},
+		[]string{"pool"},
+	)
+
+	dbPoolIdleConns = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_pool_idle_conns",
+			Help: "Number of idle connections in the pool",
+		},
+		[]string{"pool"},
+	)
+
+	dbPoolAcquiredConns = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_pool_acquired_conns",
+			Help: "Number of connections currently checked out of the pool",
+		},
+		[]string{"pool"},
+	)
+
+	// Every refresh token belongs to a session, so unexpired sessions
+	// count the refresh tokens still usable across all instances
+	authActiveRefreshTokens = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "auth_active_refresh_tokens",
+			Help: "Number of sessions whose refresh token has not yet expired",
+		},
+	)
+
+	deadLettersPending = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dead_letters_pending",
+			Help: "Number of dead-lettered messages waiting to be replayed",
+		},
+		[]string{"subject"},
+	)
+)
+
+// Pool names used as the pool label of the pool gauges
+const (
+	PoolPrimary = "primary"
+	PoolReplica = "replica"
+)
+
+// WatchPoolStats publishes the statistics of the pool called name every
+// interval until ctx is done. Acquired connections close to the pool's
+// max means saturation. The primary pool also refreshes the gauges
+// counted from its tables: active refresh tokens and pending dead letters.
+func WatchPoolStats(ctx context.Context, name string, pool *pgxpool.Pool, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		recordPoolStats(name, pool.Stat())
+		if name == PoolPrimary {
+			recordActiveSessions(ctx, pool, interval)
+			recordDeadLetters(ctx, pool, interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func recordPoolStats(name string, stat *pgxpool.Stat) {
+	dbPoolTotalConns.WithLabelValues(name).Set(float64(stat.TotalConns()))
+	dbPoolIdleConns.WithLabelValues(name).Set(float64(stat.IdleConns()))
+	dbPoolAcquiredConns.WithLabelValues(name).Set(float64(stat.AcquiredConns()))
+}
+
+// recordActiveSessions sets the active refresh token gauge from the
+// sessions table. A failed count leaves the last value in place.
+func recordActiveSessions(ctx context.Context, db sqlc.DBTX, timeout time.Duration) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	count, err := sqlc.New(db).CountActiveSessions(ctx)
+	dbQueryTotal.WithLabelValues("count_active_sessions", queryStatus(err)).Inc()
+	if err != nil {
+		return
+	}
+	authActiveRefreshTokens.Set(float64(count))
+}
+
+// recordDeadLetters sets the pending dead letter gauge from the
+// dead_letters table, dropping subjects with none left. A failed count
+// leaves the last values in place.
+func recordDeadLetters(ctx context.Context, db sqlc.DBTX, timeout time.Duration) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	rows, err := sqlc.New(db).CountDeadLettersBySubject(ctx)
+	dbQueryTotal.WithLabelValues("count_dead_letters", queryStatus(err)).Inc()
+	if err != nil {
+		return
+	}
+	deadLettersPending.Reset()
+	for _, row := range rows {
+		deadLettersPending.WithLabelValues(row.Subject).Set(float64(row.Pending))
+	}
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// countPool answers every row query with count
+type countPool struct {
+	count int64
+}
+
+func (p countPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (p countPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return nil, errors.New("unexpected query")
+}
+
+func (p countPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return countRow(p)
+}
+
+func (p countPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	return nil, errors.New("unexpected transaction")
+}
+
+type countRow struct {
+	count int64
+}
+
+func (r countRow) Scan(dest ...interface{}) error {
+	*dest[0].(*int64) = r.count
+	return nil
+}
+
+func TestRecordActiveSessionsSetsGauge(t *testing.T) {
+	recordActiveSessions(context.Background(), countPool{count: 42}, time.Second)
+	assert.Equal(t, float64(42), testutil.ToFloat64(authActiveRefreshTokens))
+
+	// A failed count keeps the last known value
+	recordActiveSessions(context.Background(), &failingPool{err: errors.New("connection refused")}, time.Second)
+	assert.Equal(t, float64(42), testutil.ToFloat64(authActiveRefreshTokens))
+}
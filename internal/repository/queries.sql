@@ -1,76 +1,217 @@
 -- User queries
 
 -- name: CreateUser :one
-INSERT INTO users (username, email, password_hash, role)
-VALUES ($1, $2, $3, $4)
-RETURNING id, username, email, role, created_at, updated_at, is_active, email_verified;
+INSERT INTO users (tenant_id, username, email, password_hash, role, must_change_password)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, tenant_id, username, email, role, created_at, updated_at, is_active, email_verified, must_change_password;
+
+-- name: CreateOAuthUser :one
+-- Social-login users start without a password and with the email the
+-- provider verified.
+INSERT INTO users (tenant_id, username, email, password_hash, role, email_verified, provider, provider_id)
+VALUES ($1, $2, $3, '', 'user', TRUE, $4, $5)
+RETURNING id, tenant_id, username, email, role, created_at;
 
 -- name: GetUserByEmail :one
-SELECT id, username, email, password_hash, role, created_at, updated_at, last_login, is_active, email_verified
+SELECT id, tenant_id, username, email, password_hash, role, created_at, updated_at, last_login, is_active, email_verified, must_change_password, provider, token_version, failed_login_attempts, locked_until, password_changed_at
+FROM users
+WHERE tenant_id = $1 AND lower(email) = lower($2) AND is_active = TRUE;
+
+-- name: GetUserPasswordHash :one
+SELECT password_hash
 FROM users
-WHERE email = $1 AND is_active = TRUE;
+WHERE tenant_id = $1 AND id = $2 AND is_active = TRUE;
 
 -- name: GetUserByID :one
-SELECT id, username, email, role, created_at, updated_at, last_login, is_active, email_verified
+SELECT id, tenant_id, username, email, role, created_at, updated_at, last_login, is_active, email_verified, token_version
 FROM users
-WHERE id = $1 AND is_active = TRUE;
+WHERE tenant_id = $1 AND id = $2 AND is_active = TRUE;
+
+-- name: GetUserByProvider :one
+SELECT id, tenant_id, username, email, role, created_at, must_change_password, token_version
+FROM users
+WHERE tenant_id = $1 AND provider = $2 AND provider_id = $3 AND is_active = TRUE;
+
+-- name: GetUsersByIDs :many
+SELECT id, tenant_id, username, email, role, created_at, updated_at, last_login, is_active, email_verified
+FROM users
+WHERE tenant_id = @tenant_id AND id = ANY(@ids::int[]) AND is_active = TRUE
+ORDER BY id;
 
 -- name: GetUserByUsername :one
-SELECT id, username, email, role, created_at, updated_at, last_login, is_active, email_verified
+SELECT id, tenant_id, username, email, password_hash, role, created_at, updated_at, last_login, is_active, email_verified, must_change_password, token_version, failed_login_attempts, locked_until, password_changed_at
 FROM users
-WHERE username = $1 AND is_active = TRUE;
+WHERE tenant_id = $1 AND lower(username) = lower($2) AND is_active = TRUE;
+
+-- name: GetTokenVersion :one
+SELECT token_version
+FROM users
+WHERE tenant_id = $1 AND id = $2 AND is_active = TRUE;
 
 -- name: UpdateUserLastLogin :exec
 UPDATE users
 SET last_login = NOW()
-WHERE id = $1;
+WHERE tenant_id = $1 AND id = $2;
 
 -- name: UpdateUserEmail :exec
 UPDATE users
 SET email = $1
-WHERE id = $2;
+WHERE tenant_id = $2 AND id = $3;
 
 -- name: UpdateUserPassword :exec
 UPDATE users
 SET password_hash = $1
-WHERE id = $2;
+WHERE tenant_id = $2 AND id = $3;
+
+-- name: ChangeUserPassword :exec
+-- Sets a user-chosen password, clears any forced change and restarts the
+-- password's age. Bumping the token version revokes the user's existing
+-- tokens.
+UPDATE users
+SET password_hash = $1, must_change_password = FALSE, token_version = token_version + 1, password_changed_at = NOW()
+WHERE tenant_id = $2 AND id = $3;
+
+-- name: IncrementFailedLogins :one
+-- Atomically increments the counter and starts a lockout once the
+-- threshold is reached; row locking makes concurrent attempts serialize.
+UPDATE users
+SET failed_login_attempts = failed_login_attempts + 1,
+    locked_until = CASE
+        WHEN failed_login_attempts + 1 >= @max_attempts::int
+            THEN NOW() + make_interval(secs => @lockout_seconds::float8)
+        ELSE locked_until
+    END
+WHERE tenant_id = @tenant_id AND id = @id
+RETURNING failed_login_attempts, locked_until;
+
+-- name: LinkUserProvider :exec
+UPDATE users
+SET provider = $1, provider_id = $2
+WHERE tenant_id = $3 AND id = $4;
+
+-- name: ResetFailedLogins :exec
+UPDATE users
+SET failed_login_attempts = 0, locked_until = NULL
+WHERE tenant_id = $1 AND id = $2;
 
 -- name: VerifyUserEmail :exec
 UPDATE users
 SET email_verified = TRUE
-WHERE id = $1;
+WHERE tenant_id = $1 AND id = $2;
 
--- name: DeactivateUser :exec
+-- name: UpdateUserRole :one
+-- Also revokes the user's existing tokens, which carry the old role, and
+-- returns that role.
 UPDATE users
-SET is_active = FALSE
-WHERE id = $1;
+SET role = $1, token_version = users.token_version + 1
+FROM users previous
+WHERE users.tenant_id = $2 AND users.id = $3 AND users.is_active = TRUE AND previous.id = users.id
+RETURNING previous.role;
+
+-- name: DeactivateUser :execrows
+-- Also revokes the user's existing tokens.
+UPDATE users
+SET is_active = FALSE, token_version = token_version + 1
+WHERE tenant_id = $1 AND id = $2 AND is_active = TRUE;
 
 -- name: ListUsers :many
-SELECT id, username, email, role, created_at, updated_at, last_login, is_active, email_verified
+SELECT id, tenant_id, username, email, role, created_at, updated_at, last_login, is_active, email_verified
 FROM users
-WHERE is_active = TRUE
+WHERE tenant_id = $1 AND is_active = TRUE
 ORDER BY created_at DESC
-LIMIT $1 OFFSET $2;
+LIMIT $2 OFFSET $3;
+
+-- name: ListUsersToWarm :many
+-- ids picks the users; when empty, the most recently logged in users of
+-- all tenants are returned instead.
+SELECT id, tenant_id, username, email, role, created_at, updated_at, last_login, is_active, email_verified, token_version
+FROM users
+WHERE is_active = TRUE
+  AND (id = ANY(@ids::int[]) OR (cardinality(@ids::int[]) = 0 AND last_login IS NOT NULL))
+ORDER BY last_login DESC NULLS LAST, id
+LIMIT @max_users;
+
+-- name: SearchUsers :many
+-- pattern is an ILIKE pattern; wildcards in user input must be escaped.
+SELECT id, tenant_id, username, email, role, created_at
+FROM users
+WHERE tenant_id = sqlc.arg(tenant_id) AND is_active = TRUE
+  AND (username ILIKE sqlc.arg(pattern) OR email ILIKE sqlc.arg(pattern))
+ORDER BY username
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountSearchUsers :one
+SELECT COUNT(*)
+FROM users
+WHERE tenant_id = sqlc.arg(tenant_id) AND is_active = TRUE
+  AND (username ILIKE sqlc.arg(pattern) OR email ILIKE sqlc.arg(pattern));
 
 -- name: CountUsers :one
-SELECT COUNT(*) FROM users WHERE is_active = TRUE;
+SELECT COUNT(*) FROM users WHERE tenant_id = $1 AND is_active = TRUE;
+
+-- name: GetUserCollectionStats :one
+-- Count and latest change of active users, used to build the list ETag.
+SELECT COUNT(*)::bigint AS total, MAX(updated_at)::timestamp AS last_updated
+FROM users
+WHERE tenant_id = $1 AND is_active = TRUE;
+
+-- Password history queries
+
+-- name: AddPasswordHistory :exec
+INSERT INTO password_history (user_id, password_hash)
+VALUES ($1, $2);
+
+-- name: ListPasswordHistory :many
+SELECT password_hash
+FROM password_history
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+
+-- name: PrunePasswordHistory :exec
+-- Keeps the user's keep most recent entries.
+DELETE FROM password_history
+WHERE user_id = @user_id AND id NOT IN (
+    SELECT id FROM password_history
+    WHERE user_id = @user_id
+    ORDER BY created_at DESC, id DESC
+    LIMIT @keep
+);
 
 -- Session queries
 
 -- name: CreateSession :one
-INSERT INTO sessions (id, user_id, token_hash, expires_at, ip_address, user_agent)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, user_id, expires_at, created_at;
+INSERT INTO sessions (id, user_id, ip_address, user_agent, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, ip_address, user_agent, created_at, last_used_at, expires_at;
+
+-- name: SessionExists :one
+SELECT EXISTS (
+    SELECT 1 FROM sessions
+    WHERE id = $1 AND user_id = $2 AND expires_at > NOW()
+);
 
--- name: GetSession :one
-SELECT id, user_id, token_hash, expires_at, created_at, ip_address, user_agent
+-- name: ListSessionsByUser :many
+SELECT id, user_id, ip_address, user_agent, created_at, last_used_at, expires_at
 FROM sessions
-WHERE id = $1 AND expires_at > NOW();
+WHERE user_id = $1 AND expires_at > NOW()
+ORDER BY last_used_at DESC;
+
+-- name: CountActiveSessions :one
+-- Sessions that have not yet expired, one per refresh token.
+SELECT COUNT(*) FROM sessions WHERE expires_at > NOW();
+
+-- name: TouchSession :one
+-- Refreshing a token records the use and extends the session.
+UPDATE sessions
+SET last_used_at = NOW(), expires_at = $3
+WHERE id = $1 AND user_id = $2 AND expires_at > NOW()
+RETURNING expires_at;
 
--- name: DeleteSession :exec
-DELETE FROM sessions WHERE id = $1;
+-- name: DeleteSession :execrows
+DELETE FROM sessions WHERE id = $1 AND user_id = $2;
 
--- name: DeleteExpiredSessions :exec
+-- name: DeleteExpiredSessions :execrows
 DELETE FROM sessions WHERE expires_at <= NOW();
 
 -- name: DeleteUserSessions :exec
@@ -79,12 +220,144 @@ DELETE FROM sessions WHERE user_id = $1;
 -- Audit log queries
 
 -- name: CreateAuditLog :exec
-INSERT INTO audit_logs (user_id, action, resource, details, ip_address)
-VALUES ($1, $2, $3, $4, $5);
+INSERT INTO audit_logs (user_id, action, resource, details, ip_address, user_agent, tenant_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
 
 -- name: GetUserAuditLogs :many
-SELECT id, user_id, action, resource, details, ip_address, created_at
+SELECT id, user_id, action, resource, details, ip_address, user_agent, created_at, tenant_id
 FROM audit_logs
 WHERE user_id = $1
 ORDER BY created_at DESC
-LIMIT $2 OFFSET $3;
\ No newline at end of file
+LIMIT $2 OFFSET $3;
+
+-- name: ListAuditLogs :many
+SELECT id, user_id, action, resource, details, ip_address, user_agent, created_at, tenant_id
+FROM audit_logs
+WHERE tenant_id = @tenant_id
+  AND (sqlc.narg('user_id')::int IS NULL OR user_id = sqlc.narg('user_id'))
+ORDER BY created_at DESC, id DESC
+LIMIT @row_limit;
+
+-- API key queries
+
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, name, prefix, scopes, created_at, last_used_at, revoked_at;
+
+-- name: GetAPIKeyByHash :one
+-- Only unrevoked keys of active users authenticate. Keys are global; the
+-- owner's tenant scopes what the key can reach.
+SELECT k.id, k.user_id, k.name, k.prefix, k.scopes, k.created_at, k.last_used_at, u.tenant_id, u.email, u.role
+FROM api_keys k
+JOIN users u ON u.id = k.user_id
+WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND u.is_active = TRUE;
+
+-- name: ListAPIKeysByUser :many
+SELECT id, user_id, name, prefix, scopes, created_at, last_used_at, revoked_at
+FROM api_keys
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC, id DESC;
+
+-- name: RevokeAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: TouchAPIKey :exec
+-- Records use at most once a minute so busy keys don't write on every
+-- request.
+UPDATE api_keys
+SET last_used_at = NOW()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+
+-- Webhook queries
+
+-- name: CreateWebhook :one
+INSERT INTO webhooks (tenant_id, url, secret, events)
+VALUES ($1, $2, $3, $4)
+RETURNING id, tenant_id, url, secret, events, created_at;
+
+-- name: ListWebhooks :many
+SELECT id, tenant_id, url, secret, events, created_at
+FROM webhooks
+WHERE tenant_id = $1
+ORDER BY created_at DESC, id DESC;
+
+-- name: ListWebhooksForEvent :many
+SELECT id, tenant_id, url, secret, events, created_at
+FROM webhooks
+WHERE tenant_id = $1 AND @event::text = ANY(events);
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks WHERE id = $1 AND tenant_id = $2;
+
+-- name: DeleteWebhookDeliveriesBefore :execrows
+-- Purges delivery attempts older than the retention period.
+DELETE FROM webhook_deliveries WHERE created_at < $1;
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (webhook_id, delivery_id, event, attempt, status_code, error, succeeded)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListWebhookDeliveries :many
+-- Newest attempts first. Joining the webhook keeps other tenants' logs
+-- out of reach.
+SELECT d.id, d.webhook_id, d.delivery_id, d.event, d.attempt, d.status_code, d.error, d.succeeded, d.created_at
+FROM webhook_deliveries d
+JOIN webhooks w ON w.id = d.webhook_id
+WHERE d.webhook_id = $1 AND w.tenant_id = $2
+ORDER BY d.created_at DESC, d.id DESC
+LIMIT $3;
+
+-- Invite queries
+
+-- name: CreateInvite :one
+INSERT INTO invites (tenant_id, code_hash, created_by, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING id, tenant_id, created_by, created_at, expires_at, used_at, used_by;
+
+-- name: ConsumeInvite :execrows
+-- Marks an unused, unexpired invite as used by the user it let register.
+UPDATE invites
+SET used_at = NOW(), used_by = $3
+WHERE tenant_id = $1 AND code_hash = $2 AND used_at IS NULL AND expires_at > NOW();
+
+-- name: GetInviteByHash :one
+SELECT id, tenant_id, created_by, created_at, expires_at, used_at, used_by
+FROM invites
+WHERE tenant_id = $1 AND code_hash = $2;
+
+-- name: LockInviteByHash :one
+-- Locks the invite until the transaction ends, so registration can check
+-- it before creating the user.
+SELECT id, tenant_id, created_by, created_at, expires_at, used_at, used_by
+FROM invites
+WHERE tenant_id = $1 AND code_hash = $2
+FOR UPDATE;
+
+-- Dead letter queries
+
+-- name: CreateDeadLetter :exec
+INSERT INTO dead_letters (subject, data, attempts, error, failed_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ListDeadLetters :many
+SELECT id, subject, data, attempts, error, failed_at
+FROM dead_letters
+ORDER BY failed_at DESC, id DESC
+LIMIT $1;
+
+-- name: GetDeadLetter :one
+SELECT id, subject, data, attempts, error, failed_at
+FROM dead_letters
+WHERE id = $1;
+
+-- name: DeleteDeadLetter :execrows
+DELETE FROM dead_letters WHERE id = $1;
+
+-- name: CountDeadLettersBySubject :many
+-- Dead letters waiting to be replayed, per subject.
+SELECT subject, COUNT(*) AS pending
+FROM dead_letters
+GROUP BY subject;
@@ -0,0 +1,207 @@
+// Package repository retries transient database errors with backoff
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var dbRetriesTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "db_retries_total",
+		Help: "Total number of database statements retried after a transient error",
+	},
+	[]string{"reason"},
+)
+
+// RetryPolicy controls how often a statement that failed with a transient
+// error is retried. The wait before retry n is a random duration between
+// half and all of BaseDelay*2^(n-1), capped at MaxDelay.
+type RetryPolicy struct {
+	// MaxAttempts counts the first try; 1 disables retries
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is used when no policy is configured
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   50 * time.Millisecond,
+	MaxDelay:    time.Second,
+}
+
+type retryDB struct {
+	db     DB
+	policy RetryPolicy
+}
+
+// WithRetry wraps db so that statements failing with a transient error are
+// retried with exponential backoff and jitter. Serialization failures and
+// deadlocks are retried for any statement, since Postgres rolled it back.
+// Lost connections are retried only for reads, or when pgx knows nothing
+// reached the server. Retries stop early rather than outlive the request
+// deadline. Statements inside a transaction are not retried; Begin is.
+func WithRetry(db DB, policy RetryPolicy) DB {
+	return &retryDB{db: db, policy: policy}
+}
+
+func (r *retryDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := r.do(ctx, isReadOnly(sql), func() error {
+		var err error
+		tag, err = r.db.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+// Query retries failures to start the query. Errors while reading rows
+// surface through Rows.Err and are not retried.
+func (r *retryDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := r.do(ctx, isReadOnly(sql), func() error {
+		var err error
+		rows, err = r.db.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow defers the query to Scan, which is where pgx reports its errors
+func (r *retryDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return &retryRow{db: r, ctx: ctx, sql: sql, args: args}
+}
+
+func (r *retryDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	var tx pgx.Tx
+	err := r.do(ctx, true, func() error {
+		var err error
+		tx, err = r.db.Begin(ctx)
+		return err
+	})
+	return tx, err
+}
+
+// do runs fn until it succeeds, fails with an error that isn't worth
+// retrying, runs out of attempts or would wait past the context deadline.
+// It returns fn's last error.
+func (r *retryDB) do(ctx context.Context, idempotent bool, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		reason := retryReason(err, idempotent)
+		if reason == "" || attempt >= r.policy.MaxAttempts {
+			return err
+		}
+
+		delay := r.policy.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		dbRetriesTotal.WithLabelValues(reason).Inc()
+	}
+}
+
+// backoff returns the wait before retrying after the given attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+type retryRow struct {
+	db   *retryDB
+	ctx  context.Context
+	sql  string
+	args []interface{}
+}
+
+func (r *retryRow) Scan(dest ...interface{}) error {
+	return r.db.do(r.ctx, isReadOnly(r.sql), func() error {
+		return r.db.db.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	})
+}
+
+// retryReason returns why err is worth retrying, or "" if it isn't.
+// Constraint violations and other query errors are never retried.
+func retryReason(err error, idempotent bool) string {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ""
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == "40001":
+			return "serialization_failure"
+		case pgErr.Code == "40P01":
+			return "deadlock"
+		case idempotent && (strings.HasPrefix(pgErr.Code, "08") || pgErr.Code == "57P01"):
+			// Connection exception or admin_shutdown
+			return "connection"
+		default:
+			return ""
+		}
+	}
+
+	// Nothing was sent, e.g. the pool couldn't connect
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.SafeToRetry(err) {
+		return "connection"
+	}
+
+	// The connection dropped mid-statement, which may have run
+	var netErr net.Error
+	if idempotent && (errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
+		return "connection"
+	}
+
+	return ""
+}
+
+// isReadOnly reports whether sql is a plain SELECT, skipping the comment
+// lines sqlc puts before each query
+func isReadOnly(sql string) bool {
+	for {
+		sql = strings.TrimSpace(sql)
+		if !strings.HasPrefix(sql, "--") {
+			break
+		}
+		end := strings.IndexByte(sql, '\n')
+		if end < 0 {
+			return false
+		}
+		sql = sql[end+1:]
+	}
+	return len(sql) >= 6 && strings.EqualFold(sql[:6], "select")
+}
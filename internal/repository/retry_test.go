@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyPool fails queries with errs in order, then succeeds
+type flakyPool struct {
+	errs    []error
+	queries int
+}
+
+func (p *flakyPool) next() error {
+	p.queries++
+	if len(p.errs) == 0 {
+		return nil
+	}
+	err := p.errs[0]
+	p.errs = p.errs[1:]
+	return err
+}
+
+func (p *flakyPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, p.next()
+}
+
+func (p *flakyPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return nil, p.next()
+}
+
+func (p *flakyPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if err := p.next(); err != nil {
+		return errRow{err: err}
+	}
+	return fakeRow{}
+}
+
+func (p *flakyPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	return nil, p.next()
+}
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func newRetryRepository(pool DB, policy RetryPolicy) *userRepository {
+	return newUserRepository(WithRetry(pool, policy), nil, LockoutPolicy{})
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	ctx := context.Background()
+	connReset := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+
+	tests := []struct {
+		name        string
+		errs        []error
+		run         func(*userRepository) error
+		wantQueries int
+		wantErr     bool
+	}{
+		{
+			name:        "serialization failure on write",
+			errs:        []error{&pgconn.PgError{Code: "40001"}},
+			run:         func(r *userRepository) error { return r.ResetFailedLogins(ctx, 1) },
+			wantQueries: 2,
+		},
+		{
+			name:        "deadlock on write",
+			errs:        []error{&pgconn.PgError{Code: "40P01"}, &pgconn.PgError{Code: "40P01"}},
+			run:         func(r *userRepository) error { return r.ResetFailedLogins(ctx, 1) },
+			wantQueries: 3,
+		},
+		{
+			name:        "connection reset on read",
+			errs:        []error{connReset},
+			run:         func(r *userRepository) error { _, err := r.GetPasswordHash(ctx, 1); return err },
+			wantQueries: 2,
+		},
+		{
+			name:        "connection reset on write is not retried",
+			errs:        []error{connReset},
+			run:         func(r *userRepository) error { return r.ResetFailedLogins(ctx, 1) },
+			wantQueries: 1,
+			wantErr:     true,
+		},
+		{
+			name:        "gives up after max attempts",
+			errs:        []error{&pgconn.PgError{Code: "40001"}, &pgconn.PgError{Code: "40001"}, &pgconn.PgError{Code: "40001"}},
+			run:         func(r *userRepository) error { return r.ResetFailedLogins(ctx, 1) },
+			wantQueries: 3,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &flakyPool{errs: tt.errs}
+			err := tt.run(newRetryRepository(pool, testRetryPolicy))
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantQueries, pool.queries)
+		})
+	}
+}
+
+func TestRetrySkipsQueryErrors(t *testing.T) {
+	ctx := context.Background()
+
+	pool := &flakyPool{errs: []error{&pgconn.PgError{Code: "23505", ConstraintName: "users_tenant_email_lower_key"}}}
+	_, err := newRetryRepository(pool, testRetryPolicy).CreateUser(ctx, domain.User{Email: "a@example.com"}, "hash")
+	assert.ErrorIs(t, err, domain.ErrDuplicateEmail)
+	assert.Equal(t, 1, pool.queries)
+
+	pool = &flakyPool{errs: []error{pgx.ErrNoRows}}
+	_, err = newRetryRepository(pool, testRetryPolicy).GetUserByID(ctx, 1)
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	assert.Equal(t, 1, pool.queries)
+}
+
+func TestRetryDisabled(t *testing.T) {
+	pool := &flakyPool{errs: []error{&pgconn.PgError{Code: "40001"}}}
+	policy := testRetryPolicy
+	policy.MaxAttempts = 1
+
+	err := newRetryRepository(pool, policy).ResetFailedLogins(context.Background(), 1)
+	assert.Error(t, err)
+	assert.Equal(t, 1, pool.queries)
+}
+
+func TestRetryStopsAtDeadline(t *testing.T) {
+	pool := &flakyPool{errs: []error{&pgconn.PgError{Code: "40001"}}}
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := newRetryRepository(pool, policy).ResetFailedLogins(ctx, 1)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, 1, pool.queries)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 5: time.Second, 70: time.Second} {
+		for i := 0; i < 20; i++ {
+			delay := policy.backoff(attempt)
+			assert.GreaterOrEqual(t, delay, want/2)
+			assert.LessOrEqual(t, delay, want)
+		}
+	}
+}
+
+func TestIsReadOnly(t *testing.T) {
+	assert.True(t, isReadOnly("-- name: GetUserByID :one\nSELECT id FROM users WHERE id = $1\n"))
+	assert.True(t, isReadOnly("select 1"))
+	assert.False(t, isReadOnly("-- name: ResetFailedLogins :exec\nUPDATE users SET failed_login_attempts = 0"))
+	assert.False(t, isReadOnly("INSERT INTO users DEFAULT VALUES"))
+	assert.False(t, isReadOnly("-- only a comment"))
+}
@@ -0,0 +1,202 @@
+// Package repository implements session data access
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository/sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type sessionRepository struct {
+	db *sqlc.Queries
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(pool DB) SessionRepository {
+	return &sessionRepository{
+		db: sqlc.New(pool),
+	}
+}
+
+func (r *sessionRepository) CreateSession(ctx context.Context, session domain.Session) (domain.Session, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	row, err := r.db.CreateSession(ctx, sqlc.CreateSessionParams{
+		ID:        session.ID,
+		UserID:    session.UserID,
+		IpAddress: pgtype.Text{String: session.IPAddress, Valid: session.IPAddress != ""},
+		UserAgent: pgtype.Text{String: session.UserAgent, Valid: session.UserAgent != ""},
+		ExpiresAt: pgtype.Timestamp{Time: session.ExpiresAt.UTC(), Valid: true},
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("create_session", queryStatus(err)).Inc()
+		if transient := transientError(err, "create session"); transient != nil {
+			return domain.Session{}, transient
+		}
+		return domain.Session{}, fmt.Errorf("create session failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("create_session", "success").Inc()
+
+	return sessionToDomain(row), nil
+}
+
+func (r *sessionRepository) SessionExists(ctx context.Context, userID int32, sessionID string) (bool, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	exists, err := r.db.SessionExists(ctx, sqlc.SessionExistsParams{
+		ID:     sessionID,
+		UserID: userID,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("session_exists", queryStatus(err)).Inc()
+		if transient := transientError(err, "check session"); transient != nil {
+			return false, transient
+		}
+		return false, fmt.Errorf("check session failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("session_exists", "success").Inc()
+	return exists, nil
+}
+
+func (r *sessionRepository) ListSessions(ctx context.Context, userID int32) ([]domain.Session, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	rows, err := r.db.ListSessionsByUser(ctx, userID)
+	if err != nil {
+		dbQueryTotal.WithLabelValues("list_sessions", queryStatus(err)).Inc()
+		if transient := transientError(err, "list sessions"); transient != nil {
+			return nil, transient
+		}
+		return nil, fmt.Errorf("list sessions failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("list_sessions", "success").Inc()
+
+	sessions := make([]domain.Session, len(rows))
+	for i, row := range rows {
+		sessions[i] = sessionToDomain(row)
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) TouchSession(ctx context.Context, userID int32, sessionID string, expiresAt time.Time) (time.Time, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	extended, err := r.db.TouchSession(ctx, sqlc.TouchSessionParams{
+		ID:        sessionID,
+		UserID:    userID,
+		ExpiresAt: pgtype.Timestamp{Time: expiresAt.UTC(), Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			dbQueryTotal.WithLabelValues("touch_session", "not_found").Inc()
+			return time.Time{}, domain.ErrSessionNotFound
+		}
+		dbQueryTotal.WithLabelValues("touch_session", queryStatus(err)).Inc()
+		if transient := transientError(err, "touch session"); transient != nil {
+			return time.Time{}, transient
+		}
+		return time.Time{}, fmt.Errorf("touch session failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("touch_session", "success").Inc()
+	return extended.Time, nil
+}
+
+func (r *sessionRepository) DeleteSession(ctx context.Context, userID int32, sessionID string) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	deleted, err := r.db.DeleteSession(ctx, sqlc.DeleteSessionParams{
+		ID:     sessionID,
+		UserID: userID,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("delete_session", queryStatus(err)).Inc()
+		if transient := transientError(err, "delete session"); transient != nil {
+			return transient
+		}
+		return fmt.Errorf("delete session failed: %w", err)
+	}
+
+	if deleted == 0 {
+		dbQueryTotal.WithLabelValues("delete_session", "not_found").Inc()
+		return domain.ErrSessionNotFound
+	}
+
+	dbQueryTotal.WithLabelValues("delete_session", "success").Inc()
+	return nil
+}
+
+func (r *sessionRepository) DeleteUserSessions(ctx context.Context, userID int32) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	if err := r.db.DeleteUserSessions(ctx, userID); err != nil {
+		dbQueryTotal.WithLabelValues("delete_user_sessions", queryStatus(err)).Inc()
+		if transient := transientError(err, "delete user sessions"); transient != nil {
+			return transient
+		}
+		return fmt.Errorf("delete user sessions failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("delete_user_sessions", "success").Inc()
+	return nil
+}
+
+func (r *sessionRepository) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	deleted, err := r.db.DeleteExpiredSessions(ctx)
+	if err != nil {
+		dbQueryTotal.WithLabelValues("delete_expired_sessions", queryStatus(err)).Inc()
+		if transient := transientError(err, "delete expired sessions"); transient != nil {
+			return 0, transient
+		}
+		return 0, fmt.Errorf("delete expired sessions failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("delete_expired_sessions", "success").Inc()
+	return deleted, nil
+}
+
+// sessionToDomain converts a session row
+func sessionToDomain(row sqlc.Session) domain.Session {
+	return domain.Session{
+		ID:         row.ID,
+		UserID:     row.UserID,
+		IPAddress:  row.IpAddress.String,
+		UserAgent:  row.UserAgent.String,
+		CreatedAt:  row.CreatedAt.Time,
+		LastUsedAt: row.LastUsedAt.Time,
+		ExpiresAt:  row.ExpiresAt.Time,
+	}
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tagPool answers every Exec with tag
+type tagPool struct {
+	ctxPool
+	tag string
+}
+
+func (p *tagPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.NewCommandTag(p.tag), nil
+}
+
+func TestDeleteExpiredSessionsReturnsCount(t *testing.T) {
+	repo := NewSessionRepository(&tagPool{tag: "DELETE 3"})
+
+	deleted, err := repo.DeleteExpiredSessions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+}
@@ -8,6 +8,18 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ApiKey struct {
+	ID         int32            `json:"id"`
+	UserID     int32            `json:"user_id"`
+	Name       string           `json:"name"`
+	Prefix     string           `json:"prefix"`
+	KeyHash    string           `json:"key_hash"`
+	Scopes     []string         `json:"scopes"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	LastUsedAt pgtype.Timestamp `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamp `json:"revoked_at"`
+}
+
 type AuditLog struct {
 	ID        int32            `json:"id"`
 	UserID    pgtype.Int4      `json:"user_id"`
@@ -15,28 +27,86 @@ type AuditLog struct {
 	Resource  pgtype.Text      `json:"resource"`
 	Details   []byte           `json:"details"`
 	IpAddress pgtype.Text      `json:"ip_address"`
+	UserAgent pgtype.Text      `json:"user_agent"`
 	CreatedAt pgtype.Timestamp `json:"created_at"`
+	TenantID  string           `json:"tenant_id"`
 }
 
-type Session struct {
-	ID        string           `json:"id"`
-	UserID    int32            `json:"user_id"`
-	TokenHash string           `json:"token_hash"`
-	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+type DeadLetter struct {
+	ID       int32            `json:"id"`
+	Subject  string           `json:"subject"`
+	Data     []byte           `json:"data"`
+	Attempts int32            `json:"attempts"`
+	Error    string           `json:"error"`
+	FailedAt pgtype.Timestamp `json:"failed_at"`
+}
+
+type Invite struct {
+	ID        int32            `json:"id"`
+	TenantID  string           `json:"tenant_id"`
+	CodeHash  string           `json:"code_hash"`
+	CreatedBy pgtype.Int4      `json:"created_by"`
 	CreatedAt pgtype.Timestamp `json:"created_at"`
-	IpAddress pgtype.Text      `json:"ip_address"`
-	UserAgent pgtype.Text      `json:"user_agent"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	UsedAt    pgtype.Timestamp `json:"used_at"`
+	UsedBy    pgtype.Int4      `json:"used_by"`
+}
+
+type PasswordHistory struct {
+	ID           int32            `json:"id"`
+	UserID       int32            `json:"user_id"`
+	PasswordHash string           `json:"password_hash"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+}
+
+type Session struct {
+	ID         string           `json:"id"`
+	UserID     int32            `json:"user_id"`
+	IpAddress  pgtype.Text      `json:"ip_address"`
+	UserAgent  pgtype.Text      `json:"user_agent"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	LastUsedAt pgtype.Timestamp `json:"last_used_at"`
+	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
 }
 
 type User struct {
-	ID            int32            `json:"id"`
-	Username      string           `json:"username"`
-	Email         string           `json:"email"`
-	PasswordHash  string           `json:"password_hash"`
-	Role          string           `json:"role"`
-	CreatedAt     pgtype.Timestamp `json:"created_at"`
-	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
-	LastLogin     pgtype.Timestamp `json:"last_login"`
-	IsActive      bool             `json:"is_active"`
-	EmailVerified bool             `json:"email_verified"`
+	ID                  int32            `json:"id"`
+	Username            string           `json:"username"`
+	Email               string           `json:"email"`
+	PasswordHash        string           `json:"password_hash"`
+	Role                string           `json:"role"`
+	CreatedAt           pgtype.Timestamp `json:"created_at"`
+	UpdatedAt           pgtype.Timestamp `json:"updated_at"`
+	LastLogin           pgtype.Timestamp `json:"last_login"`
+	IsActive            bool             `json:"is_active"`
+	EmailVerified       bool             `json:"email_verified"`
+	FailedLoginAttempts int32            `json:"failed_login_attempts"`
+	LockedUntil         pgtype.Timestamp `json:"locked_until"`
+	MustChangePassword  bool             `json:"must_change_password"`
+	Provider            pgtype.Text      `json:"provider"`
+	ProviderID          pgtype.Text      `json:"provider_id"`
+	TenantID            string           `json:"tenant_id"`
+	TokenVersion        int32            `json:"token_version"`
+	PasswordChangedAt   pgtype.Timestamp `json:"password_changed_at"`
+}
+
+type Webhook struct {
+	ID        int32            `json:"id"`
+	TenantID  string           `json:"tenant_id"`
+	Url       string           `json:"url"`
+	Secret    string           `json:"secret"`
+	Events    []string         `json:"events"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID         int64            `json:"id"`
+	WebhookID  int32            `json:"webhook_id"`
+	DeliveryID string           `json:"delivery_id"`
+	Event      string           `json:"event"`
+	Attempt    int32            `json:"attempt"`
+	StatusCode pgtype.Int4      `json:"status_code"`
+	Error      pgtype.Text      `json:"error"`
+	Succeeded  bool             `json:"succeeded"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
 }
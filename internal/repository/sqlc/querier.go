@@ -6,30 +6,106 @@ package sqlc
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
-	CountUsers(ctx context.Context) (int64, error)
+	// Password history queries
+	AddPasswordHistory(ctx context.Context, arg AddPasswordHistoryParams) error
+	// Sets a user-chosen password and clears any forced change. Bumping the
+	// token version revokes the user's existing tokens.
+	ChangeUserPassword(ctx context.Context, arg ChangeUserPasswordParams) error
+	// Marks an unused, unexpired invite as used by the user it let register.
+	ConsumeInvite(ctx context.Context, arg ConsumeInviteParams) (int64, error)
+	// Sessions that have not yet expired, one per refresh token.
+	CountActiveSessions(ctx context.Context) (int64, error)
+	// Dead letters waiting to be replayed, per subject.
+	CountDeadLettersBySubject(ctx context.Context) ([]CountDeadLettersBySubjectRow, error)
+	CountSearchUsers(ctx context.Context, arg CountSearchUsersParams) (int64, error)
+	CountUsers(ctx context.Context, tenantID string) (int64, error)
+	// API key queries
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (CreateAPIKeyRow, error)
 	// Audit log queries
 	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) error
+	// Dead letter queries
+	CreateDeadLetter(ctx context.Context, arg CreateDeadLetterParams) error
+	// Invite queries
+	CreateInvite(ctx context.Context, arg CreateInviteParams) (CreateInviteRow, error)
+	// Social-login users start without a password and with the email the
+	// provider verified.
+	CreateOAuthUser(ctx context.Context, arg CreateOAuthUserParams) (CreateOAuthUserRow, error)
 	// Session queries
-	CreateSession(ctx context.Context, arg CreateSessionParams) (CreateSessionRow, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	// User queries
 	CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error)
-	DeactivateUser(ctx context.Context, id int32) error
-	DeleteExpiredSessions(ctx context.Context) error
-	DeleteSession(ctx context.Context, id string) error
+	// Webhook queries
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) error
+	// Also revokes the user's existing tokens.
+	DeactivateUser(ctx context.Context, arg DeactivateUserParams) (int64, error)
+	DeleteDeadLetter(ctx context.Context, id int32) (int64, error)
+	DeleteExpiredSessions(ctx context.Context) (int64, error)
+	DeleteSession(ctx context.Context, arg DeleteSessionParams) (int64, error)
 	DeleteUserSessions(ctx context.Context, userID int32) error
-	GetSession(ctx context.Context, id string) (Session, error)
+	DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) (int64, error)
+	// Purges delivery attempts older than the retention period.
+	DeleteWebhookDeliveriesBefore(ctx context.Context, createdAt pgtype.Timestamp) (int64, error)
+	// Only unrevoked keys of active users authenticate. Keys are global; the
+	// owner's tenant scopes what the key can reach.
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (GetAPIKeyByHashRow, error)
+	GetDeadLetter(ctx context.Context, id int32) (DeadLetter, error)
+	GetInviteByHash(ctx context.Context, arg GetInviteByHashParams) (GetInviteByHashRow, error)
+	GetTokenVersion(ctx context.Context, arg GetTokenVersionParams) (int32, error)
 	GetUserAuditLogs(ctx context.Context, arg GetUserAuditLogsParams) ([]AuditLog, error)
-	GetUserByEmail(ctx context.Context, email string) (User, error)
-	GetUserByID(ctx context.Context, id int32) (GetUserByIDRow, error)
-	GetUserByUsername(ctx context.Context, username string) (GetUserByUsernameRow, error)
+	GetUserByEmail(ctx context.Context, arg GetUserByEmailParams) (User, error)
+	GetUserByID(ctx context.Context, arg GetUserByIDParams) (GetUserByIDRow, error)
+	GetUserByProvider(ctx context.Context, arg GetUserByProviderParams) (GetUserByProviderRow, error)
+	GetUserByUsername(ctx context.Context, arg GetUserByUsernameParams) (GetUserByUsernameRow, error)
+	// Count and latest change of active users, used to build the list ETag.
+	GetUserCollectionStats(ctx context.Context, tenantID string) (GetUserCollectionStatsRow, error)
+	GetUserPasswordHash(ctx context.Context, arg GetUserPasswordHashParams) (string, error)
+	GetUsersByIDs(ctx context.Context, arg GetUsersByIDsParams) ([]GetUsersByIDsRow, error)
+	// Atomically increments the counter and starts a lockout once the
+	// threshold is reached; row locking makes concurrent attempts serialize.
+	IncrementFailedLogins(ctx context.Context, arg IncrementFailedLoginsParams) (IncrementFailedLoginsRow, error)
+	LinkUserProvider(ctx context.Context, arg LinkUserProviderParams) error
+	ListAPIKeysByUser(ctx context.Context, userID int32) ([]ListAPIKeysByUserRow, error)
+	ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error)
+	ListDeadLetters(ctx context.Context, limit int32) ([]DeadLetter, error)
+	ListPasswordHistory(ctx context.Context, arg ListPasswordHistoryParams) ([]string, error)
+	ListSessionsByUser(ctx context.Context, userID int32) ([]Session, error)
 	ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error)
+	// ids picks the users; when empty, the most recently logged in users of
+	// all tenants are returned instead.
+	ListUsersToWarm(ctx context.Context, arg ListUsersToWarmParams) ([]ListUsersToWarmRow, error)
+	// Newest attempts first. Joining the webhook keeps other tenants' logs
+	// out of reach.
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhooks(ctx context.Context, tenantID string) ([]Webhook, error)
+	ListWebhooksForEvent(ctx context.Context, arg ListWebhooksForEventParams) ([]Webhook, error)
+	// Locks the invite until the transaction ends, so registration can check
+	// it before creating the user.
+	LockInviteByHash(ctx context.Context, arg LockInviteByHashParams) (LockInviteByHashRow, error)
+	// Keeps the user's keep most recent entries.
+	PrunePasswordHistory(ctx context.Context, arg PrunePasswordHistoryParams) error
+	ResetFailedLogins(ctx context.Context, arg ResetFailedLoginsParams) error
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (int64, error)
+	// pattern is an ILIKE pattern; wildcards in user input must be escaped.
+	SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error)
+	SessionExists(ctx context.Context, arg SessionExistsParams) (bool, error)
+	// Records use at most once a minute so busy keys don't write on every
+	// request.
+	TouchAPIKey(ctx context.Context, id int32) error
+	// Refreshing a token records the use; the session keeps its expiry.
+	TouchSession(ctx context.Context, arg TouchSessionParams) (pgtype.Timestamp, error)
 	UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) error
-	UpdateUserLastLogin(ctx context.Context, id int32) error
+	UpdateUserLastLogin(ctx context.Context, arg UpdateUserLastLoginParams) error
 	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
-	VerifyUserEmail(ctx context.Context, id int32) error
+	// Also revokes the user's existing tokens, which carry the old role, and
+	// returns that role.
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (string, error)
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) error
 }
 
 var _ Querier = (*Queries)(nil)
@@ -11,21 +11,191 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const addPasswordHistory = `-- name: AddPasswordHistory :exec
+
+INSERT INTO password_history (user_id, password_hash)
+VALUES ($1, $2)
+`
+
+type AddPasswordHistoryParams struct {
+	UserID       int32  `json:"user_id"`
+	PasswordHash string `json:"password_hash"`
+}
+
+// Password history queries
+func (q *Queries) AddPasswordHistory(ctx context.Context, arg AddPasswordHistoryParams) error {
+	_, err := q.db.Exec(ctx, addPasswordHistory, arg.UserID, arg.PasswordHash)
+	return err
+}
+
+const changeUserPassword = `-- name: ChangeUserPassword :exec
+UPDATE users
+SET password_hash = $1, must_change_password = FALSE, token_version = token_version + 1, password_changed_at = NOW()
+WHERE tenant_id = $2 AND id = $3
+`
+
+type ChangeUserPasswordParams struct {
+	PasswordHash string `json:"password_hash"`
+	TenantID     string `json:"tenant_id"`
+	ID           int32  `json:"id"`
+}
+
+// Sets a user-chosen password, clears any forced change and restarts the
+// password's age. Bumping the token version revokes the user's existing
+// tokens.
+func (q *Queries) ChangeUserPassword(ctx context.Context, arg ChangeUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, changeUserPassword, arg.PasswordHash, arg.TenantID, arg.ID)
+	return err
+}
+
+const consumeInvite = `-- name: ConsumeInvite :execrows
+UPDATE invites
+SET used_at = NOW(), used_by = $3
+WHERE tenant_id = $1 AND code_hash = $2 AND used_at IS NULL AND expires_at > NOW()
+`
+
+type ConsumeInviteParams struct {
+	TenantID string      `json:"tenant_id"`
+	CodeHash string      `json:"code_hash"`
+	UsedBy   pgtype.Int4 `json:"used_by"`
+}
+
+// Marks an unused, unexpired invite as used by the user it let register.
+func (q *Queries) ConsumeInvite(ctx context.Context, arg ConsumeInviteParams) (int64, error) {
+	result, err := q.db.Exec(ctx, consumeInvite, arg.TenantID, arg.CodeHash, arg.UsedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const countActiveSessions = `-- name: CountActiveSessions :one
+SELECT COUNT(*) FROM sessions WHERE expires_at > NOW()
+`
+
+// Sessions that have not yet expired, one per refresh token.
+func (q *Queries) CountActiveSessions(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countActiveSessions)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countDeadLettersBySubject = `-- name: CountDeadLettersBySubject :many
+SELECT subject, COUNT(*) AS pending
+FROM dead_letters
+GROUP BY subject
+`
+
+type CountDeadLettersBySubjectRow struct {
+	Subject string `json:"subject"`
+	Pending int64  `json:"pending"`
+}
+
+// Dead letters waiting to be replayed, per subject.
+func (q *Queries) CountDeadLettersBySubject(ctx context.Context) ([]CountDeadLettersBySubjectRow, error) {
+	rows, err := q.db.Query(ctx, countDeadLettersBySubject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountDeadLettersBySubjectRow
+	for rows.Next() {
+		var i CountDeadLettersBySubjectRow
+		if err := rows.Scan(&i.Subject, &i.Pending); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countSearchUsers = `-- name: CountSearchUsers :one
+SELECT COUNT(*)
+FROM users
+WHERE tenant_id = $1 AND is_active = TRUE
+  AND (username ILIKE $2 OR email ILIKE $2)
+`
+
+type CountSearchUsersParams struct {
+	TenantID string `json:"tenant_id"`
+	Pattern  string `json:"pattern"`
+}
+
+func (q *Queries) CountSearchUsers(ctx context.Context, arg CountSearchUsersParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countSearchUsers, arg.TenantID, arg.Pattern)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countUsers = `-- name: CountUsers :one
-SELECT COUNT(*) FROM users WHERE is_active = TRUE
+SELECT COUNT(*) FROM users WHERE tenant_id = $1 AND is_active = TRUE
 `
 
-func (q *Queries) CountUsers(ctx context.Context) (int64, error) {
-	row := q.db.QueryRow(ctx, countUsers)
+func (q *Queries) CountUsers(ctx context.Context, tenantID string) (int64, error) {
+	row := q.db.QueryRow(ctx, countUsers, tenantID)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
-const createAuditLog = `-- name: CreateAuditLog :exec
+const createAPIKey = `-- name: CreateAPIKey :one
 
-INSERT INTO audit_logs (user_id, action, resource, details, ip_address)
+INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes)
 VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, name, prefix, scopes, created_at, last_used_at, revoked_at
+`
+
+type CreateAPIKeyParams struct {
+	UserID  int32    `json:"user_id"`
+	Name    string   `json:"name"`
+	Prefix  string   `json:"prefix"`
+	KeyHash string   `json:"key_hash"`
+	Scopes  []string `json:"scopes"`
+}
+
+type CreateAPIKeyRow struct {
+	ID         int32            `json:"id"`
+	UserID     int32            `json:"user_id"`
+	Name       string           `json:"name"`
+	Prefix     string           `json:"prefix"`
+	Scopes     []string         `json:"scopes"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	LastUsedAt pgtype.Timestamp `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamp `json:"revoked_at"`
+}
+
+// API key queries
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (CreateAPIKeyRow, error) {
+	row := q.db.QueryRow(ctx, createAPIKey,
+		arg.UserID,
+		arg.Name,
+		arg.Prefix,
+		arg.KeyHash,
+		arg.Scopes,
+	)
+	var i CreateAPIKeyRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Prefix,
+		&i.Scopes,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const createAuditLog = `-- name: CreateAuditLog :exec
+
+INSERT INTO audit_logs (user_id, action, resource, details, ip_address, user_agent, tenant_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 `
 
 type CreateAuditLogParams struct {
@@ -34,6 +204,8 @@ type CreateAuditLogParams struct {
 	Resource  pgtype.Text `json:"resource"`
 	Details   []byte      `json:"details"`
 	IpAddress pgtype.Text `json:"ip_address"`
+	UserAgent pgtype.Text `json:"user_agent"`
+	TenantID  string      `json:"tenant_id"`
 }
 
 // Audit log queries
@@ -44,89 +216,208 @@ func (q *Queries) CreateAuditLog(ctx context.Context, arg CreateAuditLogParams)
 		arg.Resource,
 		arg.Details,
 		arg.IpAddress,
+		arg.UserAgent,
+		arg.TenantID,
+	)
+	return err
+}
+
+const createDeadLetter = `-- name: CreateDeadLetter :exec
+
+INSERT INTO dead_letters (subject, data, attempts, error, failed_at)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateDeadLetterParams struct {
+	Subject  string           `json:"subject"`
+	Data     []byte           `json:"data"`
+	Attempts int32            `json:"attempts"`
+	Error    string           `json:"error"`
+	FailedAt pgtype.Timestamp `json:"failed_at"`
+}
+
+// Dead letter queries
+func (q *Queries) CreateDeadLetter(ctx context.Context, arg CreateDeadLetterParams) error {
+	_, err := q.db.Exec(ctx, createDeadLetter,
+		arg.Subject,
+		arg.Data,
+		arg.Attempts,
+		arg.Error,
+		arg.FailedAt,
 	)
 	return err
 }
 
+const createInvite = `-- name: CreateInvite :one
+
+INSERT INTO invites (tenant_id, code_hash, created_by, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING id, tenant_id, created_by, created_at, expires_at, used_at, used_by
+`
+
+type CreateInviteParams struct {
+	TenantID  string           `json:"tenant_id"`
+	CodeHash  string           `json:"code_hash"`
+	CreatedBy pgtype.Int4      `json:"created_by"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+}
+
+type CreateInviteRow struct {
+	ID        int32            `json:"id"`
+	TenantID  string           `json:"tenant_id"`
+	CreatedBy pgtype.Int4      `json:"created_by"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	UsedAt    pgtype.Timestamp `json:"used_at"`
+	UsedBy    pgtype.Int4      `json:"used_by"`
+}
+
+// Invite queries
+func (q *Queries) CreateInvite(ctx context.Context, arg CreateInviteParams) (CreateInviteRow, error) {
+	row := q.db.QueryRow(ctx, createInvite,
+		arg.TenantID,
+		arg.CodeHash,
+		arg.CreatedBy,
+		arg.ExpiresAt,
+	)
+	var i CreateInviteRow
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.UsedBy,
+	)
+	return i, err
+}
+
+const createOAuthUser = `-- name: CreateOAuthUser :one
+INSERT INTO users (tenant_id, username, email, password_hash, role, email_verified, provider, provider_id)
+VALUES ($1, $2, $3, '', 'user', TRUE, $4, $5)
+RETURNING id, tenant_id, username, email, role, created_at
+`
+
+type CreateOAuthUserParams struct {
+	TenantID   string      `json:"tenant_id"`
+	Username   string      `json:"username"`
+	Email      string      `json:"email"`
+	Provider   pgtype.Text `json:"provider"`
+	ProviderID pgtype.Text `json:"provider_id"`
+}
+
+type CreateOAuthUserRow struct {
+	ID        int32            `json:"id"`
+	TenantID  string           `json:"tenant_id"`
+	Username  string           `json:"username"`
+	Email     string           `json:"email"`
+	Role      string           `json:"role"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+// Social-login users start without a password and with the email the
+// provider verified.
+func (q *Queries) CreateOAuthUser(ctx context.Context, arg CreateOAuthUserParams) (CreateOAuthUserRow, error) {
+	row := q.db.QueryRow(ctx, createOAuthUser,
+		arg.TenantID,
+		arg.Username,
+		arg.Email,
+		arg.Provider,
+		arg.ProviderID,
+	)
+	var i CreateOAuthUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Username,
+		&i.Email,
+		&i.Role,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createSession = `-- name: CreateSession :one
 
-INSERT INTO sessions (id, user_id, token_hash, expires_at, ip_address, user_agent)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, user_id, expires_at, created_at
+INSERT INTO sessions (id, user_id, ip_address, user_agent, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, ip_address, user_agent, created_at, last_used_at, expires_at
 `
 
 type CreateSessionParams struct {
 	ID        string           `json:"id"`
 	UserID    int32            `json:"user_id"`
-	TokenHash string           `json:"token_hash"`
-	ExpiresAt pgtype.Timestamp `json:"expires_at"`
 	IpAddress pgtype.Text      `json:"ip_address"`
 	UserAgent pgtype.Text      `json:"user_agent"`
-}
-
-type CreateSessionRow struct {
-	ID        string           `json:"id"`
-	UserID    int32            `json:"user_id"`
 	ExpiresAt pgtype.Timestamp `json:"expires_at"`
-	CreatedAt pgtype.Timestamp `json:"created_at"`
 }
 
 // Session queries
-func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (CreateSessionRow, error) {
+func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
 	row := q.db.QueryRow(ctx, createSession,
 		arg.ID,
 		arg.UserID,
-		arg.TokenHash,
-		arg.ExpiresAt,
 		arg.IpAddress,
 		arg.UserAgent,
+		arg.ExpiresAt,
 	)
-	var i CreateSessionRow
+	var i Session
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
-		&i.ExpiresAt,
+		&i.IpAddress,
+		&i.UserAgent,
 		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.ExpiresAt,
 	)
 	return i, err
 }
 
 const createUser = `-- name: CreateUser :one
 
-INSERT INTO users (username, email, password_hash, role)
-VALUES ($1, $2, $3, $4)
-RETURNING id, username, email, role, created_at, updated_at, is_active, email_verified
+INSERT INTO users (tenant_id, username, email, password_hash, role, must_change_password)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, tenant_id, username, email, role, created_at, updated_at, is_active, email_verified, must_change_password
 `
 
 type CreateUserParams struct {
-	Username     string `json:"username"`
-	Email        string `json:"email"`
-	PasswordHash string `json:"password_hash"`
-	Role         string `json:"role"`
+	TenantID           string `json:"tenant_id"`
+	Username           string `json:"username"`
+	Email              string `json:"email"`
+	PasswordHash       string `json:"password_hash"`
+	Role               string `json:"role"`
+	MustChangePassword bool   `json:"must_change_password"`
 }
 
 type CreateUserRow struct {
-	ID            int32            `json:"id"`
-	Username      string           `json:"username"`
-	Email         string           `json:"email"`
-	Role          string           `json:"role"`
-	CreatedAt     pgtype.Timestamp `json:"created_at"`
-	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
-	IsActive      bool             `json:"is_active"`
-	EmailVerified bool             `json:"email_verified"`
+	ID                 int32            `json:"id"`
+	TenantID           string           `json:"tenant_id"`
+	Username           string           `json:"username"`
+	Email              string           `json:"email"`
+	Role               string           `json:"role"`
+	CreatedAt          pgtype.Timestamp `json:"created_at"`
+	UpdatedAt          pgtype.Timestamp `json:"updated_at"`
+	IsActive           bool             `json:"is_active"`
+	EmailVerified      bool             `json:"email_verified"`
+	MustChangePassword bool             `json:"must_change_password"`
 }
 
 // User queries
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
 	row := q.db.QueryRow(ctx, createUser,
+		arg.TenantID,
 		arg.Username,
 		arg.Email,
 		arg.PasswordHash,
 		arg.Role,
+		arg.MustChangePassword,
 	)
 	var i CreateUserRow
 	err := row.Scan(
 		&i.ID,
+		&i.TenantID,
 		&i.Username,
 		&i.Email,
 		&i.Role,
@@ -134,37 +425,132 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		&i.UpdatedAt,
 		&i.IsActive,
 		&i.EmailVerified,
+		&i.MustChangePassword,
 	)
 	return i, err
 }
 
-const deactivateUser = `-- name: DeactivateUser :exec
-UPDATE users
-SET is_active = FALSE
-WHERE id = $1
+const createWebhook = `-- name: CreateWebhook :one
+
+INSERT INTO webhooks (tenant_id, url, secret, events)
+VALUES ($1, $2, $3, $4)
+RETURNING id, tenant_id, url, secret, events, created_at
+`
+
+type CreateWebhookParams struct {
+	TenantID string   `json:"tenant_id"`
+	Url      string   `json:"url"`
+	Secret   string   `json:"secret"`
+	Events   []string `json:"events"`
+}
+
+// Webhook queries
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRow(ctx, createWebhook,
+		arg.TenantID,
+		arg.Url,
+		arg.Secret,
+		arg.Events,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (webhook_id, delivery_id, event, attempt, status_code, error, succeeded)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 `
 
-func (q *Queries) DeactivateUser(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, deactivateUser, id)
+type CreateWebhookDeliveryParams struct {
+	WebhookID  int32       `json:"webhook_id"`
+	DeliveryID string      `json:"delivery_id"`
+	Event      string      `json:"event"`
+	Attempt    int32       `json:"attempt"`
+	StatusCode pgtype.Int4 `json:"status_code"`
+	Error      pgtype.Text `json:"error"`
+	Succeeded  bool        `json:"succeeded"`
+}
+
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, createWebhookDelivery,
+		arg.WebhookID,
+		arg.DeliveryID,
+		arg.Event,
+		arg.Attempt,
+		arg.StatusCode,
+		arg.Error,
+		arg.Succeeded,
+	)
 	return err
 }
 
-const deleteExpiredSessions = `-- name: DeleteExpiredSessions :exec
+const deactivateUser = `-- name: DeactivateUser :execrows
+UPDATE users
+SET is_active = FALSE, token_version = token_version + 1
+WHERE tenant_id = $1 AND id = $2 AND is_active = TRUE
+`
+
+type DeactivateUserParams struct {
+	TenantID string `json:"tenant_id"`
+	ID       int32  `json:"id"`
+}
+
+// Also revokes the user's existing tokens.
+func (q *Queries) DeactivateUser(ctx context.Context, arg DeactivateUserParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deactivateUser, arg.TenantID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteDeadLetter = `-- name: DeleteDeadLetter :execrows
+DELETE FROM dead_letters WHERE id = $1
+`
+
+func (q *Queries) DeleteDeadLetter(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteDeadLetter, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteExpiredSessions = `-- name: DeleteExpiredSessions :execrows
 DELETE FROM sessions WHERE expires_at <= NOW()
 `
 
-func (q *Queries) DeleteExpiredSessions(ctx context.Context) error {
-	_, err := q.db.Exec(ctx, deleteExpiredSessions)
-	return err
+func (q *Queries) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredSessions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const deleteSession = `-- name: DeleteSession :exec
-DELETE FROM sessions WHERE id = $1
+const deleteSession = `-- name: DeleteSession :execrows
+DELETE FROM sessions WHERE id = $1 AND user_id = $2
 `
 
-func (q *Queries) DeleteSession(ctx context.Context, id string) error {
-	_, err := q.db.Exec(ctx, deleteSession, id)
-	return err
+type DeleteSessionParams struct {
+	ID     string `json:"id"`
+	UserID int32  `json:"user_id"`
+}
+
+func (q *Queries) DeleteSession(ctx context.Context, arg DeleteSessionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSession, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const deleteUserSessions = `-- name: DeleteUserSessions :exec
@@ -176,29 +562,134 @@ func (q *Queries) DeleteUserSessions(ctx context.Context, userID int32) error {
 	return err
 }
 
-const getSession = `-- name: GetSession :one
-SELECT id, user_id, token_hash, expires_at, created_at, ip_address, user_agent
-FROM sessions
-WHERE id = $1 AND expires_at > NOW()
+const deleteWebhook = `-- name: DeleteWebhook :execrows
+DELETE FROM webhooks WHERE id = $1 AND tenant_id = $2
 `
 
-func (q *Queries) GetSession(ctx context.Context, id string) (Session, error) {
-	row := q.db.QueryRow(ctx, getSession, id)
-	var i Session
+type DeleteWebhookParams struct {
+	ID       int32  `json:"id"`
+	TenantID string `json:"tenant_id"`
+}
+
+func (q *Queries) DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebhook, arg.ID, arg.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteWebhookDeliveriesBefore = `-- name: DeleteWebhookDeliveriesBefore :execrows
+DELETE FROM webhook_deliveries WHERE created_at < $1
+`
+
+// Purges delivery attempts older than the retention period.
+func (q *Queries) DeleteWebhookDeliveriesBefore(ctx context.Context, createdAt pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebhookDeliveriesBefore, createdAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getAPIKeyByHash = `-- name: GetAPIKeyByHash :one
+SELECT k.id, k.user_id, k.name, k.prefix, k.scopes, k.created_at, k.last_used_at, u.tenant_id, u.email, u.role
+FROM api_keys k
+JOIN users u ON u.id = k.user_id
+WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND u.is_active = TRUE
+`
+
+type GetAPIKeyByHashRow struct {
+	ID         int32            `json:"id"`
+	UserID     int32            `json:"user_id"`
+	Name       string           `json:"name"`
+	Prefix     string           `json:"prefix"`
+	Scopes     []string         `json:"scopes"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	LastUsedAt pgtype.Timestamp `json:"last_used_at"`
+	TenantID   string           `json:"tenant_id"`
+	Email      string           `json:"email"`
+	Role       string           `json:"role"`
+}
+
+// Only unrevoked keys of active users authenticate. Keys are global; the
+// owner's tenant scopes what the key can reach.
+func (q *Queries) GetAPIKeyByHash(ctx context.Context, keyHash string) (GetAPIKeyByHashRow, error) {
+	row := q.db.QueryRow(ctx, getAPIKeyByHash, keyHash)
+	var i GetAPIKeyByHashRow
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
-		&i.TokenHash,
-		&i.ExpiresAt,
+		&i.Name,
+		&i.Prefix,
+		&i.Scopes,
 		&i.CreatedAt,
-		&i.IpAddress,
-		&i.UserAgent,
+		&i.LastUsedAt,
+		&i.TenantID,
+		&i.Email,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getDeadLetter = `-- name: GetDeadLetter :one
+SELECT id, subject, data, attempts, error, failed_at
+FROM dead_letters
+WHERE id = $1
+`
+
+func (q *Queries) GetDeadLetter(ctx context.Context, id int32) (DeadLetter, error) {
+	row := q.db.QueryRow(ctx, getDeadLetter, id)
+	var i DeadLetter
+	err := row.Scan(
+		&i.ID,
+		&i.Subject,
+		&i.Data,
+		&i.Attempts,
+		&i.Error,
+		&i.FailedAt,
+	)
+	return i, err
+}
+
+const getInviteByHash = `-- name: GetInviteByHash :one
+SELECT id, tenant_id, created_by, created_at, expires_at, used_at, used_by
+FROM invites
+WHERE tenant_id = $1 AND code_hash = $2
+`
+
+type GetInviteByHashParams struct {
+	TenantID string `json:"tenant_id"`
+	CodeHash string `json:"code_hash"`
+}
+
+type GetInviteByHashRow struct {
+	ID        int32            `json:"id"`
+	TenantID  string           `json:"tenant_id"`
+	CreatedBy pgtype.Int4      `json:"created_by"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	UsedAt    pgtype.Timestamp `json:"used_at"`
+	UsedBy    pgtype.Int4      `json:"used_by"`
+}
+
+func (q *Queries) GetInviteByHash(ctx context.Context, arg GetInviteByHashParams) (GetInviteByHashRow, error) {
+	row := q.db.QueryRow(ctx, getInviteByHash, arg.TenantID, arg.CodeHash)
+	var i GetInviteByHashRow
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.UsedBy,
 	)
 	return i, err
 }
 
 const getUserAuditLogs = `-- name: GetUserAuditLogs :many
-SELECT id, user_id, action, resource, details, ip_address, created_at
+SELECT id, user_id, action, resource, details, ip_address, user_agent, created_at, tenant_id
 FROM audit_logs
 WHERE user_id = $1
 ORDER BY created_at DESC
@@ -227,7 +718,9 @@ func (q *Queries) GetUserAuditLogs(ctx context.Context, arg GetUserAuditLogsPara
 			&i.Resource,
 			&i.Details,
 			&i.IpAddress,
+			&i.UserAgent,
 			&i.CreatedAt,
+			&i.TenantID,
 		); err != nil {
 			return nil, err
 		}
@@ -239,19 +732,43 @@ func (q *Queries) GetUserAuditLogs(ctx context.Context, arg GetUserAuditLogsPara
 	return items, nil
 }
 
-const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, password_hash, role, created_at, updated_at, last_login, is_active, email_verified
+const getTokenVersion = `-- name: GetTokenVersion :one
+SELECT token_version
 FROM users
-WHERE email = $1 AND is_active = TRUE
+WHERE tenant_id = $1 AND id = $2 AND is_active = TRUE
 `
 
-func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
-	row := q.db.QueryRow(ctx, getUserByEmail, email)
-	var i User
-	err := row.Scan(
-		&i.ID,
-		&i.Username,
-		&i.Email,
+type GetTokenVersionParams struct {
+	TenantID string `json:"tenant_id"`
+	ID       int32  `json:"id"`
+}
+
+func (q *Queries) GetTokenVersion(ctx context.Context, arg GetTokenVersionParams) (int32, error) {
+	row := q.db.QueryRow(ctx, getTokenVersion, arg.TenantID, arg.ID)
+	var token_version int32
+	err := row.Scan(&token_version)
+	return token_version, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, tenant_id, username, email, password_hash, role, created_at, updated_at, last_login, is_active, email_verified, must_change_password, provider, token_version, failed_login_attempts, locked_until, password_changed_at
+FROM users
+WHERE tenant_id = $1 AND lower(email) = lower($2) AND is_active = TRUE
+`
+
+type GetUserByEmailParams struct {
+	TenantID string `json:"tenant_id"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) GetUserByEmail(ctx context.Context, arg GetUserByEmailParams) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, arg.TenantID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Username,
+		&i.Email,
 		&i.PasswordHash,
 		&i.Role,
 		&i.CreatedAt,
@@ -259,18 +776,30 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.LastLogin,
 		&i.IsActive,
 		&i.EmailVerified,
+		&i.MustChangePassword,
+		&i.Provider,
+		&i.TokenVersion,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.PasswordChangedAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, username, email, role, created_at, updated_at, last_login, is_active, email_verified
+SELECT id, tenant_id, username, email, role, created_at, updated_at, last_login, is_active, email_verified, token_version
 FROM users
-WHERE id = $1 AND is_active = TRUE
+WHERE tenant_id = $1 AND id = $2 AND is_active = TRUE
 `
 
+type GetUserByIDParams struct {
+	TenantID string `json:"tenant_id"`
+	ID       int32  `json:"id"`
+}
+
 type GetUserByIDRow struct {
 	ID            int32            `json:"id"`
+	TenantID      string           `json:"tenant_id"`
 	Username      string           `json:"username"`
 	Email         string           `json:"email"`
 	Role          string           `json:"role"`
@@ -279,13 +808,15 @@ type GetUserByIDRow struct {
 	LastLogin     pgtype.Timestamp `json:"last_login"`
 	IsActive      bool             `json:"is_active"`
 	EmailVerified bool             `json:"email_verified"`
+	TokenVersion  int32            `json:"token_version"`
 }
 
-func (q *Queries) GetUserByID(ctx context.Context, id int32) (GetUserByIDRow, error) {
-	row := q.db.QueryRow(ctx, getUserByID, id)
+func (q *Queries) GetUserByID(ctx context.Context, arg GetUserByIDParams) (GetUserByIDRow, error) {
+	row := q.db.QueryRow(ctx, getUserByID, arg.TenantID, arg.ID)
 	var i GetUserByIDRow
 	err := row.Scan(
 		&i.ID,
+		&i.TenantID,
 		&i.Username,
 		&i.Email,
 		&i.Role,
@@ -294,60 +825,459 @@ func (q *Queries) GetUserByID(ctx context.Context, id int32) (GetUserByIDRow, er
 		&i.LastLogin,
 		&i.IsActive,
 		&i.EmailVerified,
+		&i.TokenVersion,
+	)
+	return i, err
+}
+
+const getUserByProvider = `-- name: GetUserByProvider :one
+SELECT id, tenant_id, username, email, role, created_at, must_change_password, token_version
+FROM users
+WHERE tenant_id = $1 AND provider = $2 AND provider_id = $3 AND is_active = TRUE
+`
+
+type GetUserByProviderParams struct {
+	TenantID   string      `json:"tenant_id"`
+	Provider   pgtype.Text `json:"provider"`
+	ProviderID pgtype.Text `json:"provider_id"`
+}
+
+type GetUserByProviderRow struct {
+	ID                 int32            `json:"id"`
+	TenantID           string           `json:"tenant_id"`
+	Username           string           `json:"username"`
+	Email              string           `json:"email"`
+	Role               string           `json:"role"`
+	CreatedAt          pgtype.Timestamp `json:"created_at"`
+	MustChangePassword bool             `json:"must_change_password"`
+	TokenVersion       int32            `json:"token_version"`
+}
+
+func (q *Queries) GetUserByProvider(ctx context.Context, arg GetUserByProviderParams) (GetUserByProviderRow, error) {
+	row := q.db.QueryRow(ctx, getUserByProvider, arg.TenantID, arg.Provider, arg.ProviderID)
+	var i GetUserByProviderRow
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Username,
+		&i.Email,
+		&i.Role,
+		&i.CreatedAt,
+		&i.MustChangePassword,
+		&i.TokenVersion,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, username, email, role, created_at, updated_at, last_login, is_active, email_verified
+SELECT id, tenant_id, username, email, password_hash, role, created_at, updated_at, last_login, is_active, email_verified, must_change_password, token_version, failed_login_attempts, locked_until, password_changed_at
 FROM users
-WHERE username = $1 AND is_active = TRUE
+WHERE tenant_id = $1 AND lower(username) = lower($2) AND is_active = TRUE
 `
 
+type GetUserByUsernameParams struct {
+	TenantID string `json:"tenant_id"`
+	Username string `json:"username"`
+}
+
 type GetUserByUsernameRow struct {
-	ID            int32            `json:"id"`
-	Username      string           `json:"username"`
-	Email         string           `json:"email"`
-	Role          string           `json:"role"`
-	CreatedAt     pgtype.Timestamp `json:"created_at"`
-	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
-	LastLogin     pgtype.Timestamp `json:"last_login"`
-	IsActive      bool             `json:"is_active"`
-	EmailVerified bool             `json:"email_verified"`
+	ID                  int32            `json:"id"`
+	TenantID            string           `json:"tenant_id"`
+	Username            string           `json:"username"`
+	Email               string           `json:"email"`
+	PasswordHash        string           `json:"password_hash"`
+	Role                string           `json:"role"`
+	CreatedAt           pgtype.Timestamp `json:"created_at"`
+	UpdatedAt           pgtype.Timestamp `json:"updated_at"`
+	LastLogin           pgtype.Timestamp `json:"last_login"`
+	IsActive            bool             `json:"is_active"`
+	EmailVerified       bool             `json:"email_verified"`
+	MustChangePassword  bool             `json:"must_change_password"`
+	TokenVersion        int32            `json:"token_version"`
+	FailedLoginAttempts int32            `json:"failed_login_attempts"`
+	LockedUntil         pgtype.Timestamp `json:"locked_until"`
+	PasswordChangedAt   pgtype.Timestamp `json:"password_changed_at"`
 }
 
-func (q *Queries) GetUserByUsername(ctx context.Context, username string) (GetUserByUsernameRow, error) {
-	row := q.db.QueryRow(ctx, getUserByUsername, username)
+func (q *Queries) GetUserByUsername(ctx context.Context, arg GetUserByUsernameParams) (GetUserByUsernameRow, error) {
+	row := q.db.QueryRow(ctx, getUserByUsername, arg.TenantID, arg.Username)
 	var i GetUserByUsernameRow
 	err := row.Scan(
 		&i.ID,
+		&i.TenantID,
 		&i.Username,
 		&i.Email,
+		&i.PasswordHash,
 		&i.Role,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastLogin,
 		&i.IsActive,
 		&i.EmailVerified,
+		&i.MustChangePassword,
+		&i.TokenVersion,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.PasswordChangedAt,
 	)
 	return i, err
 }
 
+const getUserCollectionStats = `-- name: GetUserCollectionStats :one
+SELECT COUNT(*)::bigint AS total, MAX(updated_at)::timestamp AS last_updated
+FROM users
+WHERE tenant_id = $1 AND is_active = TRUE
+`
+
+type GetUserCollectionStatsRow struct {
+	Total       int64            `json:"total"`
+	LastUpdated pgtype.Timestamp `json:"last_updated"`
+}
+
+// Count and latest change of active users, used to build the list ETag.
+func (q *Queries) GetUserCollectionStats(ctx context.Context, tenantID string) (GetUserCollectionStatsRow, error) {
+	row := q.db.QueryRow(ctx, getUserCollectionStats, tenantID)
+	var i GetUserCollectionStatsRow
+	err := row.Scan(&i.Total, &i.LastUpdated)
+	return i, err
+}
+
+const getUserPasswordHash = `-- name: GetUserPasswordHash :one
+SELECT password_hash
+FROM users
+WHERE tenant_id = $1 AND id = $2 AND is_active = TRUE
+`
+
+type GetUserPasswordHashParams struct {
+	TenantID string `json:"tenant_id"`
+	ID       int32  `json:"id"`
+}
+
+func (q *Queries) GetUserPasswordHash(ctx context.Context, arg GetUserPasswordHashParams) (string, error) {
+	row := q.db.QueryRow(ctx, getUserPasswordHash, arg.TenantID, arg.ID)
+	var password_hash string
+	err := row.Scan(&password_hash)
+	return password_hash, err
+}
+
+const getUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT id, tenant_id, username, email, role, created_at, updated_at, last_login, is_active, email_verified
+FROM users
+WHERE tenant_id = $1 AND id = ANY($2::int[]) AND is_active = TRUE
+ORDER BY id
+`
+
+type GetUsersByIDsParams struct {
+	TenantID string  `json:"tenant_id"`
+	Ids      []int32 `json:"ids"`
+}
+
+type GetUsersByIDsRow struct {
+	ID            int32            `json:"id"`
+	TenantID      string           `json:"tenant_id"`
+	Username      string           `json:"username"`
+	Email         string           `json:"email"`
+	Role          string           `json:"role"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
+	LastLogin     pgtype.Timestamp `json:"last_login"`
+	IsActive      bool             `json:"is_active"`
+	EmailVerified bool             `json:"email_verified"`
+}
+
+func (q *Queries) GetUsersByIDs(ctx context.Context, arg GetUsersByIDsParams) ([]GetUsersByIDsRow, error) {
+	rows, err := q.db.Query(ctx, getUsersByIDs, arg.TenantID, arg.Ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsersByIDsRow
+	for rows.Next() {
+		var i GetUsersByIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.Username,
+			&i.Email,
+			&i.Role,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastLogin,
+			&i.IsActive,
+			&i.EmailVerified,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const incrementFailedLogins = `-- name: IncrementFailedLogins :one
+UPDATE users
+SET failed_login_attempts = failed_login_attempts + 1,
+    locked_until = CASE
+        WHEN failed_login_attempts + 1 >= $1::int
+            THEN NOW() + make_interval(secs => $2::float8)
+        ELSE locked_until
+    END
+WHERE tenant_id = $3 AND id = $4
+RETURNING failed_login_attempts, locked_until
+`
+
+type IncrementFailedLoginsParams struct {
+	MaxAttempts    int32   `json:"max_attempts"`
+	LockoutSeconds float64 `json:"lockout_seconds"`
+	TenantID       string  `json:"tenant_id"`
+	ID             int32   `json:"id"`
+}
+
+type IncrementFailedLoginsRow struct {
+	FailedLoginAttempts int32            `json:"failed_login_attempts"`
+	LockedUntil         pgtype.Timestamp `json:"locked_until"`
+}
+
+// Atomically increments the counter and starts a lockout once the
+// threshold is reached; row locking makes concurrent attempts serialize.
+func (q *Queries) IncrementFailedLogins(ctx context.Context, arg IncrementFailedLoginsParams) (IncrementFailedLoginsRow, error) {
+	row := q.db.QueryRow(ctx, incrementFailedLogins, arg.MaxAttempts, arg.LockoutSeconds, arg.TenantID, arg.ID)
+	var i IncrementFailedLoginsRow
+	err := row.Scan(&i.FailedLoginAttempts, &i.LockedUntil)
+	return i, err
+}
+
+const linkUserProvider = `-- name: LinkUserProvider :exec
+UPDATE users
+SET provider = $1, provider_id = $2
+WHERE tenant_id = $3 AND id = $4
+`
+
+type LinkUserProviderParams struct {
+	Provider   pgtype.Text `json:"provider"`
+	ProviderID pgtype.Text `json:"provider_id"`
+	TenantID   string      `json:"tenant_id"`
+	ID         int32       `json:"id"`
+}
+
+func (q *Queries) LinkUserProvider(ctx context.Context, arg LinkUserProviderParams) error {
+	_, err := q.db.Exec(ctx, linkUserProvider, arg.Provider, arg.ProviderID, arg.TenantID, arg.ID)
+	return err
+}
+
+const listAPIKeysByUser = `-- name: ListAPIKeysByUser :many
+SELECT id, user_id, name, prefix, scopes, created_at, last_used_at, revoked_at
+FROM api_keys
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC, id DESC
+`
+
+type ListAPIKeysByUserRow struct {
+	ID         int32            `json:"id"`
+	UserID     int32            `json:"user_id"`
+	Name       string           `json:"name"`
+	Prefix     string           `json:"prefix"`
+	Scopes     []string         `json:"scopes"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	LastUsedAt pgtype.Timestamp `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamp `json:"revoked_at"`
+}
+
+func (q *Queries) ListAPIKeysByUser(ctx context.Context, userID int32) ([]ListAPIKeysByUserRow, error) {
+	rows, err := q.db.Query(ctx, listAPIKeysByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAPIKeysByUserRow
+	for rows.Next() {
+		var i ListAPIKeysByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Prefix,
+			&i.Scopes,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAuditLogs = `-- name: ListAuditLogs :many
+SELECT id, user_id, action, resource, details, ip_address, user_agent, created_at, tenant_id
+FROM audit_logs
+WHERE tenant_id = $1
+  AND ($2::int IS NULL OR user_id = $2)
+ORDER BY created_at DESC, id DESC
+LIMIT $3
+`
+
+type ListAuditLogsParams struct {
+	TenantID string      `json:"tenant_id"`
+	UserID   pgtype.Int4 `json:"user_id"`
+	RowLimit int32       `json:"row_limit"`
+}
+
+func (q *Queries) ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error) {
+	rows, err := q.db.Query(ctx, listAuditLogs, arg.TenantID, arg.UserID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditLog
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Action,
+			&i.Resource,
+			&i.Details,
+			&i.IpAddress,
+			&i.UserAgent,
+			&i.CreatedAt,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeadLetters = `-- name: ListDeadLetters :many
+SELECT id, subject, data, attempts, error, failed_at
+FROM dead_letters
+ORDER BY failed_at DESC, id DESC
+LIMIT $1
+`
+
+func (q *Queries) ListDeadLetters(ctx context.Context, limit int32) ([]DeadLetter, error) {
+	rows, err := q.db.Query(ctx, listDeadLetters, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeadLetter
+	for rows.Next() {
+		var i DeadLetter
+		if err := rows.Scan(
+			&i.ID,
+			&i.Subject,
+			&i.Data,
+			&i.Attempts,
+			&i.Error,
+			&i.FailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPasswordHistory = `-- name: ListPasswordHistory :many
+SELECT password_hash
+FROM password_history
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2
+`
+
+type ListPasswordHistoryParams struct {
+	UserID int32 `json:"user_id"`
+	Limit  int32 `json:"limit"`
+}
+
+func (q *Queries) ListPasswordHistory(ctx context.Context, arg ListPasswordHistoryParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listPasswordHistory, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var password_hash string
+		if err := rows.Scan(&password_hash); err != nil {
+			return nil, err
+		}
+		items = append(items, password_hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSessionsByUser = `-- name: ListSessionsByUser :many
+SELECT id, user_id, ip_address, user_agent, created_at, last_used_at, expires_at
+FROM sessions
+WHERE user_id = $1 AND expires_at > NOW()
+ORDER BY last_used_at DESC
+`
+
+func (q *Queries) ListSessionsByUser(ctx context.Context, userID int32) ([]Session, error) {
+	rows, err := q.db.Query(ctx, listSessionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Session
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.IpAddress,
+			&i.UserAgent,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUsers = `-- name: ListUsers :many
-SELECT id, username, email, role, created_at, updated_at, last_login, is_active, email_verified
+SELECT id, tenant_id, username, email, role, created_at, updated_at, last_login, is_active, email_verified
 FROM users
-WHERE is_active = TRUE
+WHERE tenant_id = $1 AND is_active = TRUE
 ORDER BY created_at DESC
-LIMIT $1 OFFSET $2
+LIMIT $2 OFFSET $3
 `
 
 type ListUsersParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	TenantID string `json:"tenant_id"`
+	Limit    int32  `json:"limit"`
+	Offset   int32  `json:"offset"`
 }
 
 type ListUsersRow struct {
 	ID            int32            `json:"id"`
+	TenantID      string           `json:"tenant_id"`
 	Username      string           `json:"username"`
 	Email         string           `json:"email"`
 	Role          string           `json:"role"`
@@ -359,7 +1289,7 @@ type ListUsersRow struct {
 }
 
 func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
-	rows, err := q.db.Query(ctx, listUsers, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listUsers, arg.TenantID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -369,6 +1299,68 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUse
 		var i ListUsersRow
 		if err := rows.Scan(
 			&i.ID,
+			&i.TenantID,
+			&i.Username,
+			&i.Email,
+			&i.Role,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastLogin,
+			&i.IsActive,
+			&i.EmailVerified,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersToWarm = `-- name: ListUsersToWarm :many
+SELECT id, tenant_id, username, email, role, created_at, updated_at, last_login, is_active, email_verified, token_version
+FROM users
+WHERE is_active = TRUE
+  AND (id = ANY($1::int[]) OR (cardinality($1::int[]) = 0 AND last_login IS NOT NULL))
+ORDER BY last_login DESC NULLS LAST, id
+LIMIT $2
+`
+
+type ListUsersToWarmParams struct {
+	Ids      []int32 `json:"ids"`
+	MaxUsers int32   `json:"max_users"`
+}
+
+type ListUsersToWarmRow struct {
+	ID            int32            `json:"id"`
+	TenantID      string           `json:"tenant_id"`
+	Username      string           `json:"username"`
+	Email         string           `json:"email"`
+	Role          string           `json:"role"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
+	LastLogin     pgtype.Timestamp `json:"last_login"`
+	IsActive      bool             `json:"is_active"`
+	EmailVerified bool             `json:"email_verified"`
+	TokenVersion  int32            `json:"token_version"`
+}
+
+// ids picks the users; when empty, the most recently logged in users of
+// all tenants are returned instead.
+func (q *Queries) ListUsersToWarm(ctx context.Context, arg ListUsersToWarmParams) ([]ListUsersToWarmRow, error) {
+	rows, err := q.db.Query(ctx, listUsersToWarm, arg.Ids, arg.MaxUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersToWarmRow
+	for rows.Next() {
+		var i ListUsersToWarmRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
 			&i.Username,
 			&i.Email,
 			&i.Role,
@@ -377,6 +1369,7 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUse
 			&i.LastLogin,
 			&i.IsActive,
 			&i.EmailVerified,
+			&i.TokenVersion,
 		); err != nil {
 			return nil, err
 		}
@@ -388,56 +1381,416 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUse
 	return items, nil
 }
 
+const listWebhookDeliveries = `-- name: ListWebhookDeliveries :many
+SELECT d.id, d.webhook_id, d.delivery_id, d.event, d.attempt, d.status_code, d.error, d.succeeded, d.created_at
+FROM webhook_deliveries d
+JOIN webhooks w ON w.id = d.webhook_id
+WHERE d.webhook_id = $1 AND w.tenant_id = $2
+ORDER BY d.created_at DESC, d.id DESC
+LIMIT $3
+`
+
+type ListWebhookDeliveriesParams struct {
+	WebhookID int32  `json:"webhook_id"`
+	TenantID  string `json:"tenant_id"`
+	Limit     int32  `json:"limit"`
+}
+
+// Newest attempts first. Joining the webhook keeps other tenants' logs
+// out of reach.
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, listWebhookDeliveries, arg.WebhookID, arg.TenantID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.DeliveryID,
+			&i.Event,
+			&i.Attempt,
+			&i.StatusCode,
+			&i.Error,
+			&i.Succeeded,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhooks = `-- name: ListWebhooks :many
+SELECT id, tenant_id, url, secret, events, created_at
+FROM webhooks
+WHERE tenant_id = $1
+ORDER BY created_at DESC, id DESC
+`
+
+func (q *Queries) ListWebhooks(ctx context.Context, tenantID string) ([]Webhook, error) {
+	rows, err := q.db.Query(ctx, listWebhooks, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webhook
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.Url,
+			&i.Secret,
+			&i.Events,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhooksForEvent = `-- name: ListWebhooksForEvent :many
+SELECT id, tenant_id, url, secret, events, created_at
+FROM webhooks
+WHERE tenant_id = $1 AND $2::text = ANY(events)
+`
+
+type ListWebhooksForEventParams struct {
+	TenantID string `json:"tenant_id"`
+	Event    string `json:"event"`
+}
+
+func (q *Queries) ListWebhooksForEvent(ctx context.Context, arg ListWebhooksForEventParams) ([]Webhook, error) {
+	rows, err := q.db.Query(ctx, listWebhooksForEvent, arg.TenantID, arg.Event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webhook
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.Url,
+			&i.Secret,
+			&i.Events,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockInviteByHash = `-- name: LockInviteByHash :one
+SELECT id, tenant_id, created_by, created_at, expires_at, used_at, used_by
+FROM invites
+WHERE tenant_id = $1 AND code_hash = $2
+FOR UPDATE
+`
+
+type LockInviteByHashParams struct {
+	TenantID string `json:"tenant_id"`
+	CodeHash string `json:"code_hash"`
+}
+
+type LockInviteByHashRow struct {
+	ID        int32            `json:"id"`
+	TenantID  string           `json:"tenant_id"`
+	CreatedBy pgtype.Int4      `json:"created_by"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	UsedAt    pgtype.Timestamp `json:"used_at"`
+	UsedBy    pgtype.Int4      `json:"used_by"`
+}
+
+// Locks the invite until the transaction ends, so registration can check
+// it before creating the user.
+func (q *Queries) LockInviteByHash(ctx context.Context, arg LockInviteByHashParams) (LockInviteByHashRow, error) {
+	row := q.db.QueryRow(ctx, lockInviteByHash, arg.TenantID, arg.CodeHash)
+	var i LockInviteByHashRow
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.UsedBy,
+	)
+	return i, err
+}
+
+const prunePasswordHistory = `-- name: PrunePasswordHistory :exec
+DELETE FROM password_history
+WHERE user_id = $1 AND id NOT IN (
+    SELECT id FROM password_history
+    WHERE user_id = $1
+    ORDER BY created_at DESC, id DESC
+    LIMIT $2
+)
+`
+
+type PrunePasswordHistoryParams struct {
+	UserID int32 `json:"user_id"`
+	Keep   int32 `json:"keep"`
+}
+
+// Keeps the user's keep most recent entries.
+func (q *Queries) PrunePasswordHistory(ctx context.Context, arg PrunePasswordHistoryParams) error {
+	_, err := q.db.Exec(ctx, prunePasswordHistory, arg.UserID, arg.Keep)
+	return err
+}
+
+const resetFailedLogins = `-- name: ResetFailedLogins :exec
+UPDATE users
+SET failed_login_attempts = 0, locked_until = NULL
+WHERE tenant_id = $1 AND id = $2
+`
+
+type ResetFailedLoginsParams struct {
+	TenantID string `json:"tenant_id"`
+	ID       int32  `json:"id"`
+}
+
+func (q *Queries) ResetFailedLogins(ctx context.Context, arg ResetFailedLoginsParams) error {
+	_, err := q.db.Exec(ctx, resetFailedLogins, arg.TenantID, arg.ID)
+	return err
+}
+
+const revokeAPIKey = `-- name: RevokeAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeAPIKeyParams struct {
+	ID     int32 `json:"id"`
+	UserID int32 `json:"user_id"`
+}
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeAPIKey, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const searchUsers = `-- name: SearchUsers :many
+SELECT id, tenant_id, username, email, role, created_at
+FROM users
+WHERE tenant_id = $1 AND is_active = TRUE
+  AND (username ILIKE $2 OR email ILIKE $2)
+ORDER BY username
+LIMIT $3 OFFSET $4
+`
+
+type SearchUsersParams struct {
+	TenantID string `json:"tenant_id"`
+	Pattern  string `json:"pattern"`
+	Limit    int32  `json:"limit"`
+	Offset   int32  `json:"offset"`
+}
+
+type SearchUsersRow struct {
+	ID        int32            `json:"id"`
+	TenantID  string           `json:"tenant_id"`
+	Username  string           `json:"username"`
+	Email     string           `json:"email"`
+	Role      string           `json:"role"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+// pattern is an ILIKE pattern; wildcards in user input must be escaped.
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error) {
+	rows, err := q.db.Query(ctx, searchUsers,
+		arg.TenantID,
+		arg.Pattern,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchUsersRow
+	for rows.Next() {
+		var i SearchUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.Username,
+			&i.Email,
+			&i.Role,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sessionExists = `-- name: SessionExists :one
+SELECT EXISTS (
+    SELECT 1 FROM sessions
+    WHERE id = $1 AND user_id = $2 AND expires_at > NOW()
+)
+`
+
+type SessionExistsParams struct {
+	ID     string `json:"id"`
+	UserID int32  `json:"user_id"`
+}
+
+func (q *Queries) SessionExists(ctx context.Context, arg SessionExistsParams) (bool, error) {
+	row := q.db.QueryRow(ctx, sessionExists, arg.ID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const touchAPIKey = `-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = NOW()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+`
+
+// Records use at most once a minute so busy keys don't write on every
+// request.
+func (q *Queries) TouchAPIKey(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, touchAPIKey, id)
+	return err
+}
+
+const touchSession = `-- name: TouchSession :one
+UPDATE sessions
+SET last_used_at = NOW(), expires_at = $3
+WHERE id = $1 AND user_id = $2 AND expires_at > NOW()
+RETURNING expires_at
+`
+
+type TouchSessionParams struct {
+	ID        string           `json:"id"`
+	UserID    int32            `json:"user_id"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+}
+
+// Refreshing a token records the use and extends the session.
+func (q *Queries) TouchSession(ctx context.Context, arg TouchSessionParams) (pgtype.Timestamp, error) {
+	row := q.db.QueryRow(ctx, touchSession, arg.ID, arg.UserID, arg.ExpiresAt)
+	var expires_at pgtype.Timestamp
+	err := row.Scan(&expires_at)
+	return expires_at, err
+}
+
 const updateUserEmail = `-- name: UpdateUserEmail :exec
 UPDATE users
 SET email = $1
-WHERE id = $2
+WHERE tenant_id = $2 AND id = $3
 `
 
 type UpdateUserEmailParams struct {
-	Email string `json:"email"`
-	ID    int32  `json:"id"`
+	Email    string `json:"email"`
+	TenantID string `json:"tenant_id"`
+	ID       int32  `json:"id"`
 }
 
 func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) error {
-	_, err := q.db.Exec(ctx, updateUserEmail, arg.Email, arg.ID)
+	_, err := q.db.Exec(ctx, updateUserEmail, arg.Email, arg.TenantID, arg.ID)
 	return err
 }
 
 const updateUserLastLogin = `-- name: UpdateUserLastLogin :exec
 UPDATE users
 SET last_login = NOW()
-WHERE id = $1
+WHERE tenant_id = $1 AND id = $2
 `
 
-func (q *Queries) UpdateUserLastLogin(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, updateUserLastLogin, id)
+type UpdateUserLastLoginParams struct {
+	TenantID string `json:"tenant_id"`
+	ID       int32  `json:"id"`
+}
+
+func (q *Queries) UpdateUserLastLogin(ctx context.Context, arg UpdateUserLastLoginParams) error {
+	_, err := q.db.Exec(ctx, updateUserLastLogin, arg.TenantID, arg.ID)
 	return err
 }
 
 const updateUserPassword = `-- name: UpdateUserPassword :exec
 UPDATE users
 SET password_hash = $1
-WHERE id = $2
+WHERE tenant_id = $2 AND id = $3
 `
 
 type UpdateUserPasswordParams struct {
 	PasswordHash string `json:"password_hash"`
+	TenantID     string `json:"tenant_id"`
 	ID           int32  `json:"id"`
 }
 
 func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
-	_, err := q.db.Exec(ctx, updateUserPassword, arg.PasswordHash, arg.ID)
+	_, err := q.db.Exec(ctx, updateUserPassword, arg.PasswordHash, arg.TenantID, arg.ID)
 	return err
 }
 
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET role = $1, token_version = users.token_version + 1
+FROM users previous
+WHERE users.tenant_id = $2 AND users.id = $3 AND users.is_active = TRUE AND previous.id = users.id
+RETURNING previous.role
+`
+
+type UpdateUserRoleParams struct {
+	Role     string `json:"role"`
+	TenantID string `json:"tenant_id"`
+	ID       int32  `json:"id"`
+}
+
+// Also revokes the user's existing tokens, which carry the old role, and
+// returns that role.
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (string, error) {
+	row := q.db.QueryRow(ctx, updateUserRole, arg.Role, arg.TenantID, arg.ID)
+	var role string
+	err := row.Scan(&role)
+	return role, err
+}
+
 const verifyUserEmail = `-- name: VerifyUserEmail :exec
 UPDATE users
 SET email_verified = TRUE
-WHERE id = $1
+WHERE tenant_id = $1 AND id = $2
 `
 
-func (q *Queries) VerifyUserEmail(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, verifyUserEmail, id)
+type VerifyUserEmailParams struct {
+	TenantID string `json:"tenant_id"`
+	ID       int32  `json:"id"`
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) error {
+	_, err := q.db.Exec(ctx, verifyUserEmail, arg.TenantID, arg.ID)
 	return err
 }
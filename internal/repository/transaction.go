@@ -9,6 +9,11 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// txBeginner starts database transactions (satisfied by *pgxpool.Pool)
+type txBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
 type txManager struct {
 	pool *pgxpool.Pool
 }
@@ -22,7 +27,15 @@ func NewTxManager(pool *pgxpool.Pool) TxManager {
 // If the function returns an error, the transaction is rolled back
 // Otherwise, the transaction is committed
 func (tm *txManager) WithTransaction(ctx context.Context, fn func(context.Context, pgx.Tx) error) error {
-	tx, err := tm.pool.Begin(ctx)
+	return withTransaction(ctx, tm.pool, func(tx pgx.Tx) error {
+		return fn(ctx, tx)
+	})
+}
+
+// withTransaction begins a transaction, runs fn and commits, rolling back
+// if fn returns an error or panics
+func withTransaction(ctx context.Context, db txBeginner, fn func(pgx.Tx) error) error {
+	tx, err := db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -40,7 +53,7 @@ func (tm *txManager) WithTransaction(ctx context.Context, fn func(context.Contex
 	}()
 
 	// Execute the function within the transaction
-	if err := fn(ctx, tx); err != nil {
+	if err := fn(tx); err != nil {
 		// Rollback on error
 		if rbErr := tx.Rollback(ctx); rbErr != nil {
 			return fmt.Errorf("transaction error: %w, rollback error: %v", err, rbErr)
@@ -1,4 +1,5 @@
-// Package repository implements data access layer
+// Package repository implements data access layer. User queries are
+// scoped to the tenant in the context (tenant.Default if none).
 package repository
 
 import (
@@ -6,25 +7,32 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"strings"
 	"time"
 
 	"user-auth-app/internal/domain"
 	"user-auth-app/internal/repository/sqlc"
+	"user-auth-app/internal/tenant"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+// dbQueryBuckets resolve the sub-millisecond lookups by primary key as
+// well as slow queries of several seconds
+var dbQueryBuckets = []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
 var (
 	dbQueryDuration = promauto.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:    "db_query_duration_seconds",
 			Help:    "Database query latency in seconds",
-			Buckets: prometheus.DefBuckets,
+			Buckets: dbQueryBuckets,
 		},
 	)
 
@@ -37,15 +45,63 @@ var (
 	)
 )
 
+// DB is the subset of *pgxpool.Pool used by repositories. It is satisfied
+// by a pool and by WithCircuitBreaker.
+type DB interface {
+	sqlc.DBTX
+	txBeginner
+}
+
+// LockoutPolicy controls when repeated failed logins lock an account
+type LockoutPolicy struct {
+	MaxAttempts int32
+	Duration    time.Duration
+}
+
 type userRepository struct {
 	db *sqlc.Queries
+	// replica serves listings and searches, which tolerate replication
+	// lag. Lookups whose results are cached stay on db, so the cache never
+	// holds a lagging copy. It is db when no replica is configured.
+	replica *sqlc.Queries
+	pool    DB // nil when the repository is scoped to a transaction
+	lockout LockoutPolicy
 }
 
-// NewUserRepository creates a new user repository
-func NewUserRepository(pool *pgxpool.Pool) UserRepository {
+// NewUserRepository creates a new user repository. Reads that tolerate
+// replication lag go to replica; with a nil replica everything uses pool.
+func NewUserRepository(pool, replica DB, lockout LockoutPolicy) UserRepository {
+	return newUserRepository(pool, replica, lockout)
+}
+
+func newUserRepository(pool, replica DB, lockout LockoutPolicy) *userRepository {
+	db := sqlc.New(pool)
+	reads := db
+	if replica != nil {
+		reads = sqlc.New(replica)
+	}
 	return &userRepository{
-		db: sqlc.New(pool),
+		db:      db,
+		replica: reads,
+		pool:    pool,
+		lockout: lockout,
+	}
+}
+
+// WithTx runs fn with a repository whose queries all execute in a single
+// transaction. The transaction commits if fn returns nil and rolls back
+// otherwise. Calling WithTx on a transaction-scoped repository reuses the
+// existing transaction.
+func (r *userRepository) WithTx(ctx context.Context, fn func(UserRepository) error) error {
+	if r.pool == nil {
+		return fn(r)
 	}
+
+	return withTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		// Reads see the transaction's writes
+		txQueries := r.db.WithTx(tx)
+		return fn(&userRepository{db: txQueries, replica: txQueries, lockout: r.lockout})
+	})
 }
 
 func (r *userRepository) CreateUser(ctx context.Context, user domain.User, passwordHash string) (domain.User, error) {
@@ -55,13 +111,16 @@ func (r *userRepository) CreateUser(ctx context.Context, user domain.User, passw
 	}()
 
 	created, err := r.db.CreateUser(ctx, sqlc.CreateUserParams{
+		TenantID:     tenant.ID(ctx),
 		Username:     user.Username,
 		Email:        user.Email,
 		PasswordHash: passwordHash,
 		Role:         user.Role,
+
+		MustChangePassword: user.MustChangePassword,
 	})
 	if err != nil {
-		dbQueryTotal.WithLabelValues("create_user", "error").Inc()
+		dbQueryTotal.WithLabelValues("create_user", queryStatus(err)).Inc()
 		return domain.User{}, r.handleError(err, "create user")
 	}
 
@@ -69,10 +128,48 @@ func (r *userRepository) CreateUser(ctx context.Context, user domain.User, passw
 
 	return domain.User{
 		ID:        created.ID,
+		TenantID:  created.TenantID,
+		Username:  created.Username,
+		Email:     created.Email,
+		Role:      created.Role,
+		CreatedAt: created.CreatedAt,
+
+		MustChangePassword: created.MustChangePassword,
+	}, nil
+}
+
+// CreateOAuthUser creates a passwordless user linked to an external
+// identity. The provider has verified the email.
+func (r *userRepository) CreateOAuthUser(ctx context.Context, user domain.User, identity domain.ExternalIdentity) (domain.User, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	created, err := r.db.CreateOAuthUser(ctx, sqlc.CreateOAuthUserParams{
+		TenantID:   tenant.ID(ctx),
+		Username:   user.Username,
+		Email:      user.Email,
+		Provider:   pgtype.Text{String: identity.Provider, Valid: true},
+		ProviderID: pgtype.Text{String: identity.ProviderID, Valid: true},
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("create_oauth_user", queryStatus(err)).Inc()
+		return domain.User{}, r.handleError(err, "create oauth user")
+	}
+
+	dbQueryTotal.WithLabelValues("create_oauth_user", "success").Inc()
+
+	return domain.User{
+		ID:        created.ID,
+		TenantID:  created.TenantID,
 		Username:  created.Username,
 		Email:     created.Email,
 		Role:      created.Role,
 		CreatedAt: created.CreatedAt,
+
+		EmailVerified: true,
+		Provider:      identity.Provider,
 	}, nil
 }
 
@@ -82,13 +179,16 @@ func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (doma
 		dbQueryDuration.Observe(time.Since(start).Seconds())
 	}()
 
-	u, err := r.db.GetUserByEmail(ctx, email)
+	u, err := r.db.GetUserByEmail(ctx, sqlc.GetUserByEmailParams{
+		TenantID: tenant.ID(ctx),
+		Email:    email,
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
 			dbQueryTotal.WithLabelValues("get_user_by_email", "not_found").Inc()
 			return domain.User{}, "", domain.ErrUserNotFound
 		}
-		dbQueryTotal.WithLabelValues("get_user_by_email", "error").Inc()
+		dbQueryTotal.WithLabelValues("get_user_by_email", queryStatus(err)).Inc()
 		return domain.User{}, "", r.handleError(err, "get user by email")
 	}
 
@@ -96,10 +196,21 @@ func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (doma
 
 	return domain.User{
 		ID:        u.ID,
+		TenantID:  u.TenantID,
 		Username:  u.Username,
 		Email:     u.Email,
 		Role:      u.Role,
 		CreatedAt: u.CreatedAt,
+
+		MustChangePassword: u.MustChangePassword,
+		EmailVerified:      u.EmailVerified,
+		Provider:           u.Provider.String,
+		TokenVersion:       u.TokenVersion,
+
+		FailedLogins: u.FailedLoginAttempts,
+		LockedUntil:  u.LockedUntil.Time,
+
+		PasswordChangedAt: u.PasswordChangedAt.Time,
 	}, u.PasswordHash, nil
 }
 
@@ -109,13 +220,18 @@ func (r *userRepository) GetUserByID(ctx context.Context, id int32) (domain.User
 		dbQueryDuration.Observe(time.Since(start).Seconds())
 	}()
 
-	u, err := r.db.GetUserByID(ctx, id)
+	// Read from the primary: lookups by ID fill the user cache and back
+	// token refresh, where a lagging copy would outlive the lag
+	u, err := r.db.GetUserByID(ctx, sqlc.GetUserByIDParams{
+		TenantID: tenant.ID(ctx),
+		ID:       id,
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
 			dbQueryTotal.WithLabelValues("get_user_by_id", "not_found").Inc()
 			return domain.User{}, domain.ErrUserNotFound
 		}
-		dbQueryTotal.WithLabelValues("get_user_by_id", "error").Inc()
+		dbQueryTotal.WithLabelValues("get_user_by_id", queryStatus(err)).Inc()
 		return domain.User{}, r.handleError(err, "get user by id")
 	}
 
@@ -123,16 +239,122 @@ func (r *userRepository) GetUserByID(ctx context.Context, id int32) (domain.User
 
 	return domain.User{
 		ID:        u.ID,
+		TenantID:  u.TenantID,
 		Username:  u.Username,
 		Email:     u.Email,
 		Role:      u.Role,
 		CreatedAt: u.CreatedAt,
+
+		TokenVersion: u.TokenVersion,
 	}, nil
 }
 
-func (r *userRepository) GetUserByUsername(ctx context.Context, username string) (domain.User, error) {
-	// Implementation placeholder - add to sqlc queries
-	return domain.User{}, fmt.Errorf("not implemented")
+func (r *userRepository) GetUserByProvider(ctx context.Context, provider, providerID string) (domain.User, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	u, err := r.db.GetUserByProvider(ctx, sqlc.GetUserByProviderParams{
+		TenantID:   tenant.ID(ctx),
+		Provider:   pgtype.Text{String: provider, Valid: true},
+		ProviderID: pgtype.Text{String: providerID, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			dbQueryTotal.WithLabelValues("get_user_by_provider", "not_found").Inc()
+			return domain.User{}, domain.ErrUserNotFound
+		}
+		dbQueryTotal.WithLabelValues("get_user_by_provider", queryStatus(err)).Inc()
+		return domain.User{}, r.handleError(err, "get user by provider")
+	}
+
+	dbQueryTotal.WithLabelValues("get_user_by_provider", "success").Inc()
+
+	return domain.User{
+		ID:        u.ID,
+		TenantID:  u.TenantID,
+		Username:  u.Username,
+		Email:     u.Email,
+		Role:      u.Role,
+		CreatedAt: u.CreatedAt,
+
+		MustChangePassword: u.MustChangePassword,
+		Provider:           provider,
+		TokenVersion:       u.TokenVersion,
+	}, nil
+}
+
+func (r *userRepository) GetUserByUsername(ctx context.Context, username string) (domain.User, string, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	u, err := r.db.GetUserByUsername(ctx, sqlc.GetUserByUsernameParams{
+		TenantID: tenant.ID(ctx),
+		Username: username,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			dbQueryTotal.WithLabelValues("get_user_by_username", "not_found").Inc()
+			return domain.User{}, "", domain.ErrUserNotFound
+		}
+		dbQueryTotal.WithLabelValues("get_user_by_username", queryStatus(err)).Inc()
+		return domain.User{}, "", r.handleError(err, "get user by username")
+	}
+
+	dbQueryTotal.WithLabelValues("get_user_by_username", "success").Inc()
+
+	return domain.User{
+		ID:        u.ID,
+		TenantID:  u.TenantID,
+		Username:  u.Username,
+		Email:     u.Email,
+		Role:      u.Role,
+		CreatedAt: u.CreatedAt,
+
+		MustChangePassword: u.MustChangePassword,
+		TokenVersion:       u.TokenVersion,
+
+		FailedLogins: u.FailedLoginAttempts,
+		LockedUntil:  u.LockedUntil.Time,
+
+		PasswordChangedAt: u.PasswordChangedAt.Time,
+	}, u.PasswordHash, nil
+}
+
+func (r *userRepository) GetUsersByIDs(ctx context.Context, ids []int32) ([]domain.User, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	// Read from the primary: batch lookups fill the user cache
+	rows, err := r.db.GetUsersByIDs(ctx, sqlc.GetUsersByIDsParams{
+		TenantID: tenant.ID(ctx),
+		Ids:      ids,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("get_users_by_ids", queryStatus(err)).Inc()
+		return nil, r.handleError(err, "get users by ids")
+	}
+
+	dbQueryTotal.WithLabelValues("get_users_by_ids", "success").Inc()
+
+	users := make([]domain.User, len(rows))
+	for i, u := range rows {
+		users[i] = domain.User{
+			ID:        u.ID,
+			TenantID:  u.TenantID,
+			Username:  u.Username,
+			Email:     u.Email,
+			Role:      u.Role,
+			CreatedAt: u.CreatedAt,
+		}
+	}
+
+	return users, nil
 }
 
 func (r *userRepository) UpdateUser(ctx context.Context, user domain.User) error {
@@ -146,12 +368,423 @@ func (r *userRepository) DeleteUser(ctx context.Context, id int32) error {
 }
 
 func (r *userRepository) ListUsers(ctx context.Context, limit, offset int) ([]domain.User, error) {
-	// Implementation placeholder - add to sqlc queries
-	return nil, fmt.Errorf("not implemented")
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	rows, err := r.replica.ListUsers(ctx, sqlc.ListUsersParams{
+		TenantID: tenant.ID(ctx),
+		Limit:    int32(limit),
+		Offset:   int32(offset),
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("list_users", queryStatus(err)).Inc()
+		return nil, r.handleError(err, "list users")
+	}
+
+	dbQueryTotal.WithLabelValues("list_users", "success").Inc()
+
+	users := make([]domain.User, len(rows))
+	for i, u := range rows {
+		users[i] = domain.User{
+			ID:        u.ID,
+			TenantID:  u.TenantID,
+			Username:  u.Username,
+			Email:     u.Email,
+			Role:      u.Role,
+			CreatedAt: u.CreatedAt,
+		}
+	}
+
+	return users, nil
+}
+
+func (r *userRepository) ListUsersToWarm(ctx context.Context, ids []int32, limit int) ([]domain.User, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	// A nil slice is sent as NULL, which matches nothing
+	if ids == nil {
+		ids = []int32{}
+	}
+	// Read from the primary, so the cache isn't warmed with stale users
+	rows, err := r.db.ListUsersToWarm(ctx, sqlc.ListUsersToWarmParams{
+		Ids:      ids,
+		MaxUsers: int32(limit),
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("list_users_to_warm", queryStatus(err)).Inc()
+		return nil, r.handleError(err, "list users to warm")
+	}
+
+	dbQueryTotal.WithLabelValues("list_users_to_warm", "success").Inc()
+
+	users := make([]domain.User, len(rows))
+	for i, u := range rows {
+		users[i] = domain.User{
+			ID:        u.ID,
+			TenantID:  u.TenantID,
+			Username:  u.Username,
+			Email:     u.Email,
+			Role:      u.Role,
+			CreatedAt: u.CreatedAt,
+
+			TokenVersion: u.TokenVersion,
+		}
+	}
+
+	return users, nil
+}
+
+func (r *userRepository) SearchUsers(ctx context.Context, query string, limit, offset int) ([]domain.User, int64, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	pattern := containsPattern(query)
+	total, err := r.replica.CountSearchUsers(ctx, sqlc.CountSearchUsersParams{
+		TenantID: tenant.ID(ctx),
+		Pattern:  pattern,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("search_users", queryStatus(err)).Inc()
+		return nil, 0, r.handleError(err, "count user search")
+	}
+
+	rows, err := r.replica.SearchUsers(ctx, sqlc.SearchUsersParams{
+		TenantID: tenant.ID(ctx),
+		Pattern:  pattern,
+		Limit:    int32(limit),
+		Offset:   int32(offset),
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("search_users", queryStatus(err)).Inc()
+		return nil, 0, r.handleError(err, "search users")
+	}
+
+	dbQueryTotal.WithLabelValues("search_users", "success").Inc()
+
+	users := make([]domain.User, len(rows))
+	for i, u := range rows {
+		users[i] = domain.User{
+			ID:        u.ID,
+			TenantID:  u.TenantID,
+			Username:  u.Username,
+			Email:     u.Email,
+			Role:      u.Role,
+			CreatedAt: u.CreatedAt,
+		}
+	}
+
+	return users, total, nil
+}
+
+// containsPattern builds an ILIKE pattern matching values that contain
+// s, escaping the wildcards and escape character it may hold
+func containsPattern(s string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + escaped + "%"
+}
+
+// GetCollectionStats returns the count and latest update time of the
+// tenant's active users
+func (r *userRepository) GetCollectionStats(ctx context.Context) (domain.UserCollectionStats, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	row, err := r.replica.GetUserCollectionStats(ctx, tenant.ID(ctx))
+	if err != nil {
+		dbQueryTotal.WithLabelValues("get_user_collection_stats", queryStatus(err)).Inc()
+		return domain.UserCollectionStats{}, r.handleError(err, "get user collection stats")
+	}
+
+	dbQueryTotal.WithLabelValues("get_user_collection_stats", "success").Inc()
+
+	return domain.UserCollectionStats{
+		Total:       row.Total,
+		LastUpdated: row.LastUpdated.Time,
+	}, nil
+}
+
+// UpdatePassword replaces the user's password hash
+func (r *userRepository) UpdatePassword(ctx context.Context, userID int32, passwordHash string) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	err := r.db.UpdateUserPassword(ctx, sqlc.UpdateUserPasswordParams{
+		PasswordHash: passwordHash,
+		TenantID:     tenant.ID(ctx),
+		ID:           userID,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("update_password", queryStatus(err)).Inc()
+		return r.handleError(err, "update password")
+	}
+
+	dbQueryTotal.WithLabelValues("update_password", "success").Inc()
+	return nil
+}
+
+// ChangePassword sets a user-chosen password hash, clears any forced
+// password change and revokes the user's tokens. Use UpdatePassword to
+// replace the hash of the same password (e.g. rehashing), which must do
+// neither.
+func (r *userRepository) ChangePassword(ctx context.Context, userID int32, passwordHash string) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	err := r.db.ChangeUserPassword(ctx, sqlc.ChangeUserPasswordParams{
+		PasswordHash: passwordHash,
+		TenantID:     tenant.ID(ctx),
+		ID:           userID,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("change_password", queryStatus(err)).Inc()
+		return r.handleError(err, "change password")
+	}
+
+	dbQueryTotal.WithLabelValues("change_password", "success").Inc()
+	return nil
+}
+
+// GetPasswordHistory returns up to limit of the user's previous password
+// hashes, most recent first
+func (r *userRepository) GetPasswordHistory(ctx context.Context, userID int32, limit int) ([]string, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	hashes, err := r.db.ListPasswordHistory(ctx, sqlc.ListPasswordHistoryParams{
+		UserID: userID,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("get_password_history", queryStatus(err)).Inc()
+		return nil, r.handleError(err, "get password history")
+	}
+
+	dbQueryTotal.WithLabelValues("get_password_history", "success").Inc()
+	return hashes, nil
+}
+
+// AddPasswordHistory records a previous password hash of the user and
+// prunes all but the keep most recent entries
+func (r *userRepository) AddPasswordHistory(ctx context.Context, userID int32, passwordHash string, keep int) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	err := r.db.AddPasswordHistory(ctx, sqlc.AddPasswordHistoryParams{
+		UserID:       userID,
+		PasswordHash: passwordHash,
+	})
+	if err == nil {
+		err = r.db.PrunePasswordHistory(ctx, sqlc.PrunePasswordHistoryParams{
+			UserID: userID,
+			Keep:   int32(keep),
+		})
+	}
+	if err != nil {
+		dbQueryTotal.WithLabelValues("add_password_history", queryStatus(err)).Inc()
+		return r.handleError(err, "add password history")
+	}
+
+	dbQueryTotal.WithLabelValues("add_password_history", "success").Inc()
+	return nil
+}
+
+// LinkProvider attaches an external identity to an existing user
+func (r *userRepository) LinkProvider(ctx context.Context, userID int32, identity domain.ExternalIdentity) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	err := r.db.LinkUserProvider(ctx, sqlc.LinkUserProviderParams{
+		Provider:   pgtype.Text{String: identity.Provider, Valid: true},
+		ProviderID: pgtype.Text{String: identity.ProviderID, Valid: true},
+		TenantID:   tenant.ID(ctx),
+		ID:         userID,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("link_user_provider", queryStatus(err)).Inc()
+		return r.handleError(err, "link user provider")
+	}
+
+	dbQueryTotal.WithLabelValues("link_user_provider", "success").Inc()
+	return nil
+}
+
+// GetPasswordHash returns the stored password hash of an active user
+func (r *userRepository) GetPasswordHash(ctx context.Context, userID int32) (string, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	hash, err := r.db.GetUserPasswordHash(ctx, sqlc.GetUserPasswordHashParams{
+		TenantID: tenant.ID(ctx),
+		ID:       userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			dbQueryTotal.WithLabelValues("get_password_hash", "not_found").Inc()
+			return "", domain.ErrUserNotFound
+		}
+		dbQueryTotal.WithLabelValues("get_password_hash", queryStatus(err)).Inc()
+		return "", r.handleError(err, "get password hash")
+	}
+
+	dbQueryTotal.WithLabelValues("get_password_hash", "success").Inc()
+	return hash, nil
+}
+
+// IncrementFailedLogins atomically increments the user's failed login
+// counter and, once it reaches the lockout threshold, locks the account.
+// lockedUntil is zero if the account is not locked.
+func (r *userRepository) IncrementFailedLogins(ctx context.Context, userID int32) (int32, time.Time, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	row, err := r.db.IncrementFailedLogins(ctx, sqlc.IncrementFailedLoginsParams{
+		MaxAttempts:    r.lockout.MaxAttempts,
+		LockoutSeconds: r.lockout.Duration.Seconds(),
+		TenantID:       tenant.ID(ctx),
+		ID:             userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			dbQueryTotal.WithLabelValues("increment_failed_logins", "not_found").Inc()
+			return 0, time.Time{}, domain.ErrUserNotFound
+		}
+		dbQueryTotal.WithLabelValues("increment_failed_logins", queryStatus(err)).Inc()
+		return 0, time.Time{}, r.handleError(err, "increment failed logins")
+	}
+
+	dbQueryTotal.WithLabelValues("increment_failed_logins", "success").Inc()
+
+	var lockedUntil time.Time
+	if row.LockedUntil.Valid {
+		lockedUntil = row.LockedUntil.Time
+	}
+
+	return row.FailedLoginAttempts, lockedUntil, nil
+}
+
+// ResetFailedLogins clears the failed login counter and any lockout
+func (r *userRepository) ResetFailedLogins(ctx context.Context, userID int32) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	err := r.db.ResetFailedLogins(ctx, sqlc.ResetFailedLoginsParams{
+		TenantID: tenant.ID(ctx),
+		ID:       userID,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("reset_failed_logins", queryStatus(err)).Inc()
+		return r.handleError(err, "reset failed logins")
+	}
+
+	dbQueryTotal.WithLabelValues("reset_failed_logins", "success").Inc()
+	return nil
+}
+
+// GetTokenVersion returns the token version of an active user
+func (r *userRepository) GetTokenVersion(ctx context.Context, userID int32) (int32, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	version, err := r.db.GetTokenVersion(ctx, sqlc.GetTokenVersionParams{
+		TenantID: tenant.ID(ctx),
+		ID:       userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			dbQueryTotal.WithLabelValues("get_token_version", "not_found").Inc()
+			return 0, domain.ErrUserNotFound
+		}
+		dbQueryTotal.WithLabelValues("get_token_version", queryStatus(err)).Inc()
+		return 0, r.handleError(err, "get token version")
+	}
+
+	dbQueryTotal.WithLabelValues("get_token_version", "success").Inc()
+	return version, nil
+}
+
+// UpdateUserRole changes the role of an active user and revokes their
+// tokens, returning the role they had
+func (r *userRepository) UpdateUserRole(ctx context.Context, userID int32, role string) (string, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	previous, err := r.db.UpdateUserRole(ctx, sqlc.UpdateUserRoleParams{
+		Role:     role,
+		TenantID: tenant.ID(ctx),
+		ID:       userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			dbQueryTotal.WithLabelValues("update_role", "not_found").Inc()
+			return "", domain.ErrUserNotFound
+		}
+		dbQueryTotal.WithLabelValues("update_role", queryStatus(err)).Inc()
+		return "", r.handleError(err, "update role")
+	}
+
+	dbQueryTotal.WithLabelValues("update_role", "success").Inc()
+	return previous, nil
+}
+
+// DeactivateUser deactivates an active user and revokes their tokens
+func (r *userRepository) DeactivateUser(ctx context.Context, userID int32) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	rows, err := r.db.DeactivateUser(ctx, sqlc.DeactivateUserParams{
+		TenantID: tenant.ID(ctx),
+		ID:       userID,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("deactivate_user", queryStatus(err)).Inc()
+		return r.handleError(err, "deactivate user")
+	}
+	if rows == 0 {
+		dbQueryTotal.WithLabelValues("deactivate_user", "not_found").Inc()
+		return domain.ErrUserNotFound
+	}
+
+	dbQueryTotal.WithLabelValues("deactivate_user", "success").Inc()
+	return nil
 }
 
 // handleError converts database errors to domain errors
 func (r *userRepository) handleError(err error, operation string) error {
+	if transient := transientError(err, operation); transient != nil {
+		return transient
+	}
+
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
 		switch pgErr.Code {
@@ -172,3 +805,60 @@ func (r *userRepository) handleError(err error, operation string) error {
 
 	return fmt.Errorf("%s failed: %w", operation, err)
 }
+
+// transientError maps a query aborted by its context to domain.ErrTimeout
+// or domain.ErrCanceled, and a query that couldn't reach the database to
+// domain.ErrUnavailable, so handlers can answer 504/503 instead of a
+// generic 500. It returns nil for any other error, such as a failed query.
+func transientError(err error, operation string) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%s: %w: %w", operation, domain.ErrTimeout, err)
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("%s: %w: %w", operation, domain.ErrCanceled, err)
+	case isConnectionError(err):
+		return fmt.Errorf("%s: %w: %w", operation, domain.ErrUnavailable, err)
+	default:
+		return nil
+	}
+}
+
+// isConnectionError reports whether err means the database couldn't be
+// reached or the connection was lost, as opposed to the query failing.
+// Context errors are checked first by callers, since a deadline can also
+// surface as a connection error.
+func isConnectionError(err error) bool {
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "53300", // too_many_connections
+			"57P01", // admin_shutdown
+			"57P02", // crash_shutdown
+			"57P03": // cannot_connect_now
+			return true
+		}
+		return strings.HasPrefix(pgErr.Code, "08") // connection_exception
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// queryStatus is the dbQueryTotal status label for a failed query
+func queryStatus(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, domain.ErrUnavailable), isConnectionError(err):
+		return "unavailable"
+	default:
+		return "error"
+	}
+}
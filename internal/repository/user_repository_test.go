@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/tenant"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRow satisfies pgx.Row, leaving scan targets untouched
+type fakeRow struct{}
+
+func (fakeRow) Scan(dest ...interface{}) error { return nil }
+
+// fakeTx records how a transaction was finished and which queries ran in it
+type fakeTx struct {
+	pgx.Tx
+	queries    int
+	committed  bool
+	rolledBack bool
+}
+
+func (t *fakeTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	t.queries++
+	return pgconn.CommandTag{}, nil
+}
+
+func (t *fakeTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	t.queries++
+	return fakeRow{}
+}
+
+func (t *fakeTx) Commit(ctx context.Context) error {
+	t.committed = true
+	return nil
+}
+
+func (t *fakeTx) Rollback(ctx context.Context) error {
+	t.rolledBack = true
+	return nil
+}
+
+// fakePool hands out a single fakeTx and fails queries run outside it
+type fakePool struct {
+	tx *fakeTx
+}
+
+func (p *fakePool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errors.New("query ran outside transaction")
+}
+
+func (p *fakePool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return nil, errors.New("query ran outside transaction")
+}
+
+func (p *fakePool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	panic("query ran outside transaction")
+}
+
+func (p *fakePool) Begin(ctx context.Context) (pgx.Tx, error) {
+	return p.tx, nil
+}
+
+func TestWithTxRollsBackOnError(t *testing.T) {
+	pool := &fakePool{tx: &fakeTx{}}
+	repo := newUserRepository(pool, nil, LockoutPolicy{})
+	errAudit := errors.New("audit insert failed")
+
+	err := repo.WithTx(context.Background(), func(tx UserRepository) error {
+		if _, err := tx.CreateUser(context.Background(), domain.User{Username: "alice"}, "hash"); err != nil {
+			return err
+		}
+		return errAudit
+	})
+
+	assert.ErrorIs(t, err, errAudit)
+	assert.Equal(t, 1, pool.tx.queries, "CreateUser should run inside the transaction")
+	assert.True(t, pool.tx.rolledBack)
+	assert.False(t, pool.tx.committed)
+}
+
+func TestWithTxCommitsOnSuccess(t *testing.T) {
+	pool := &fakePool{tx: &fakeTx{}}
+	repo := newUserRepository(pool, nil, LockoutPolicy{})
+
+	err := repo.WithTx(context.Background(), func(tx UserRepository) error {
+		_, err := tx.CreateUser(context.Background(), domain.User{Username: "alice"}, "hash")
+		return err
+	})
+
+	require.NoError(t, err)
+	assert.True(t, pool.tx.committed)
+	assert.False(t, pool.tx.rolledBack)
+}
+
+func TestWithTxRollsBackOnPanic(t *testing.T) {
+	pool := &fakePool{tx: &fakeTx{}}
+	repo := newUserRepository(pool, nil, LockoutPolicy{})
+
+	assert.Panics(t, func() {
+		_ = repo.WithTx(context.Background(), func(tx UserRepository) error {
+			panic("boom")
+		})
+	})
+	assert.True(t, pool.tx.rolledBack)
+	assert.False(t, pool.tx.committed)
+}
+
+// ctxRow fails the scan with the context's error, like pgx does when a
+// query's context is done
+type ctxRow struct {
+	ctx context.Context
+}
+
+func (r ctxRow) Scan(dest ...interface{}) error {
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ctxPool fails every query whose context is already done
+type ctxPool struct{}
+
+func (ctxPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, ctx.Err()
+}
+
+func (ctxPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return nil, ctx.Err()
+}
+
+func (ctxPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return ctxRow{ctx: ctx}
+}
+
+func (ctxPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	return nil, ctx.Err()
+}
+
+func histogramSamples(t *testing.T, h prometheus.Histogram) uint64 {
+	var m dto.Metric
+	require.NoError(t, h.Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestQueriesHonorDoneContext(t *testing.T) {
+	repo := newUserRepository(ctxPool{}, nil, LockoutPolicy{})
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		wantErr    error
+		wantStatus int
+		wantLabel  string
+	}{
+		{"deadline exceeded", expired, domain.ErrTimeout, http.StatusGatewayTimeout, "timeout"},
+		{"canceled", canceled, domain.ErrCanceled, http.StatusServiceUnavailable, "canceled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := histogramSamples(t, dbQueryDuration)
+			count := testutil.ToFloat64(dbQueryTotal.WithLabelValues("get_user_by_email", tt.wantLabel))
+
+			_, _, err := repo.GetUserByEmail(tt.ctx, "user@example.com")
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, tt.ctx.Err())
+			assert.Equal(t, tt.wantStatus, domain.HTTPStatusCode(err))
+
+			// The aborted query's duration is still observed
+			assert.Equal(t, samples+1, histogramSamples(t, dbQueryDuration))
+			assert.Equal(t, count+1, testutil.ToFloat64(dbQueryTotal.WithLabelValues("get_user_by_email", tt.wantLabel)))
+		})
+	}
+}
+
+// errPool fails every query with err
+type errPool struct {
+	ctxPool
+	err error
+}
+
+func (p errPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return errRow{err: p.err}
+}
+
+func TestUnreachableDatabaseIsUnavailable(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantErr    error
+		wantStatus int
+		wantLabel  string
+	}{
+		{"can't connect", &pgconn.ConnectError{Config: &pgconn.Config{}}, domain.ErrUnavailable, http.StatusServiceUnavailable, "unavailable"},
+		{"server shutting down", &pgconn.PgError{Code: "57P01"}, domain.ErrUnavailable, http.StatusServiceUnavailable, "unavailable"},
+		{"connection lost", &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, domain.ErrUnavailable, http.StatusServiceUnavailable, "unavailable"},
+		{"connection closed", io.ErrUnexpectedEOF, domain.ErrUnavailable, http.StatusServiceUnavailable, "unavailable"},
+		{"deadline while connecting", fmt.Errorf("dial: %w", context.DeadlineExceeded), domain.ErrTimeout, http.StatusGatewayTimeout, "timeout"},
+		{"query error", &pgconn.PgError{Code: "42P01"}, nil, http.StatusInternalServerError, "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newUserRepository(errPool{err: tt.err}, nil, LockoutPolicy{})
+			count := testutil.ToFloat64(dbQueryTotal.WithLabelValues("get_user_by_id", tt.wantLabel))
+
+			_, err := repo.GetUserByID(context.Background(), 1)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			assert.Equal(t, tt.wantStatus, domain.HTTPStatusCode(err))
+			assert.Equal(t, count+1, testutil.ToFloat64(dbQueryTotal.WithLabelValues("get_user_by_id", tt.wantLabel)))
+		})
+	}
+}
+
+// argsPool records the arguments of every query
+type argsPool struct {
+	ctxPool
+	args [][]interface{}
+}
+
+func (p *argsPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	p.args = append(p.args, args)
+	return pgconn.CommandTag{}, nil
+}
+
+func (p *argsPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	p.args = append(p.args, args)
+	return fakeRow{}
+}
+
+func TestQueriesAreScopedToContextTenant(t *testing.T) {
+	pool := &argsPool{}
+	repo := newUserRepository(pool, nil, LockoutPolicy{})
+
+	acme := tenant.WithID(context.Background(), "acme")
+	_, err := repo.GetUserByID(acme, 1)
+	require.NoError(t, err)
+	_, _, err = repo.GetUserByEmail(acme, "user@example.com")
+	require.NoError(t, err)
+	require.NoError(t, repo.ResetFailedLogins(acme, 1))
+
+	// No tenant in the context means the default tenant
+	_, err = repo.GetUserByID(context.Background(), 1)
+	require.NoError(t, err)
+
+	require.Len(t, pool.args, 4)
+	for _, args := range pool.args[:3] {
+		assert.Equal(t, "acme", args[0])
+	}
+	assert.Equal(t, tenant.Default, pool.args[3][0])
+}
+
+func TestContainsPatternEscapesWildcards(t *testing.T) {
+	assert.Equal(t, "%alice%", containsPattern("alice"))
+	assert.Equal(t, `%100\%\_off%`, containsPattern("100%_off"))
+	assert.Equal(t, `%a\\b%`, containsPattern(`a\b`))
+}
+
+// replicaPool counts the queries sent to a pool
+type replicaPool struct {
+	fakePool
+	queries int
+}
+
+func (p *replicaPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	p.queries++
+	return fakeRow{}
+}
+
+func (p *replicaPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	p.queries++
+	return nil, errors.New("replica queried")
+}
+
+func TestReadsUseReplica(t *testing.T) {
+	ctx := context.Background()
+	primary := &replicaPool{fakePool: fakePool{tx: &fakeTx{}}}
+	replica := &replicaPool{}
+	repo := newUserRepository(primary, replica, LockoutPolicy{})
+
+	// Listings tolerate replication lag
+	_, err := repo.GetCollectionStats(ctx)
+	require.NoError(t, err)
+	_, err = repo.ListUsers(ctx, 10, 0)
+	assert.EqualError(t, errors.Unwrap(err), "replica queried")
+	assert.Equal(t, 2, replica.queries)
+	assert.Equal(t, 0, primary.queries)
+
+	// Lookups that fill the cache read the primary, so a lagging copy is
+	// never cached
+	_, err = repo.GetUserByID(ctx, 1)
+	require.NoError(t, err)
+	_, _ = repo.GetUsersByIDs(ctx, []int32{1})
+	_, _ = repo.ListUsersToWarm(ctx, nil, 10)
+	assert.Equal(t, 3, primary.queries)
+	assert.Equal(t, 2, replica.queries)
+
+	// Writes go to the primary
+	assert.Error(t, repo.UpdatePassword(ctx, 1, "hash"))
+	assert.Equal(t, 2, replica.queries)
+
+	// Reads in a transaction see its writes
+	require.NoError(t, repo.WithTx(ctx, func(tx UserRepository) error {
+		_, err := tx.GetUserByID(ctx, 1)
+		return err
+	}))
+	assert.Equal(t, 1, primary.tx.queries)
+	assert.Equal(t, 2, replica.queries)
+}
@@ -0,0 +1,224 @@
+// Package repository implements webhook data access, scoped to the tenant
+// in the context
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository/sqlc"
+	"user-auth-app/internal/tenant"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type webhookRepository struct {
+	db *sqlc.Queries
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(pool DB) WebhookRepository {
+	return &webhookRepository{
+		db: sqlc.New(pool),
+	}
+}
+
+func (r *webhookRepository) CreateWebhook(ctx context.Context, webhook domain.Webhook) (domain.Webhook, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	row, err := r.db.CreateWebhook(ctx, sqlc.CreateWebhookParams{
+		TenantID: tenant.ID(ctx),
+		Url:      webhook.URL,
+		Secret:   webhook.Secret,
+		Events:   webhook.Events,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("create_webhook", queryStatus(err)).Inc()
+		if transient := transientError(err, "create webhook"); transient != nil {
+			return domain.Webhook{}, transient
+		}
+		return domain.Webhook{}, fmt.Errorf("create webhook failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("create_webhook", "success").Inc()
+	return webhookToDomain(row), nil
+}
+
+func (r *webhookRepository) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	rows, err := r.db.ListWebhooks(ctx, tenant.ID(ctx))
+	if err != nil {
+		dbQueryTotal.WithLabelValues("list_webhooks", queryStatus(err)).Inc()
+		if transient := transientError(err, "list webhooks"); transient != nil {
+			return nil, transient
+		}
+		return nil, fmt.Errorf("list webhooks failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("list_webhooks", "success").Inc()
+	return webhooksToDomain(rows), nil
+}
+
+func (r *webhookRepository) ListWebhooksForEvent(ctx context.Context, event string) ([]domain.Webhook, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	rows, err := r.db.ListWebhooksForEvent(ctx, sqlc.ListWebhooksForEventParams{
+		TenantID: tenant.ID(ctx),
+		Event:    event,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("list_webhooks_for_event", queryStatus(err)).Inc()
+		if transient := transientError(err, "list webhooks for event"); transient != nil {
+			return nil, transient
+		}
+		return nil, fmt.Errorf("list webhooks for event failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("list_webhooks_for_event", "success").Inc()
+	return webhooksToDomain(rows), nil
+}
+
+func (r *webhookRepository) DeleteWebhook(ctx context.Context, webhookID int32) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	deleted, err := r.db.DeleteWebhook(ctx, sqlc.DeleteWebhookParams{
+		ID:       webhookID,
+		TenantID: tenant.ID(ctx),
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("delete_webhook", queryStatus(err)).Inc()
+		if transient := transientError(err, "delete webhook"); transient != nil {
+			return transient
+		}
+		return fmt.Errorf("delete webhook failed: %w", err)
+	}
+
+	if deleted == 0 {
+		dbQueryTotal.WithLabelValues("delete_webhook", "not_found").Inc()
+		return domain.ErrWebhookNotFound
+	}
+
+	dbQueryTotal.WithLabelValues("delete_webhook", "success").Inc()
+	return nil
+}
+
+func (r *webhookRepository) PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	// created_at has no time zone and is written in UTC
+	deleted, err := r.db.DeleteWebhookDeliveriesBefore(ctx, pgtype.Timestamp{Time: before.UTC(), Valid: true})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("purge_webhook_deliveries", queryStatus(err)).Inc()
+		if transient := transientError(err, "purge webhook deliveries"); transient != nil {
+			return 0, transient
+		}
+		return 0, fmt.Errorf("purge webhook deliveries failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("purge_webhook_deliveries", "success").Inc()
+	return deleted, nil
+}
+
+func (r *webhookRepository) RecordWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	err := r.db.CreateWebhookDelivery(ctx, sqlc.CreateWebhookDeliveryParams{
+		WebhookID:  delivery.WebhookID,
+		DeliveryID: delivery.DeliveryID,
+		Event:      delivery.Event,
+		Attempt:    delivery.Attempt,
+		StatusCode: pgtype.Int4{Int32: delivery.StatusCode, Valid: delivery.StatusCode != 0},
+		Error:      pgtype.Text{String: delivery.Error, Valid: delivery.Error != ""},
+		Succeeded:  delivery.Succeeded,
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("record_webhook_delivery", queryStatus(err)).Inc()
+		if transient := transientError(err, "record webhook delivery"); transient != nil {
+			return transient
+		}
+		return fmt.Errorf("record webhook delivery failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("record_webhook_delivery", "success").Inc()
+	return nil
+}
+
+func (r *webhookRepository) ListWebhookDeliveries(ctx context.Context, webhookID int32, limit int) ([]domain.WebhookDelivery, error) {
+	start := time.Now()
+	defer func() {
+		dbQueryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	rows, err := r.db.ListWebhookDeliveries(ctx, sqlc.ListWebhookDeliveriesParams{
+		WebhookID: webhookID,
+		TenantID:  tenant.ID(ctx),
+		Limit:     int32(limit),
+	})
+	if err != nil {
+		dbQueryTotal.WithLabelValues("list_webhook_deliveries", queryStatus(err)).Inc()
+		if transient := transientError(err, "list webhook deliveries"); transient != nil {
+			return nil, transient
+		}
+		return nil, fmt.Errorf("list webhook deliveries failed: %w", err)
+	}
+
+	dbQueryTotal.WithLabelValues("list_webhook_deliveries", "success").Inc()
+
+	deliveries := make([]domain.WebhookDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i] = domain.WebhookDelivery{
+			ID:         row.ID,
+			WebhookID:  row.WebhookID,
+			DeliveryID: row.DeliveryID,
+			Event:      row.Event,
+			Attempt:    row.Attempt,
+			StatusCode: row.StatusCode.Int32,
+			Error:      row.Error.String,
+			Succeeded:  row.Succeeded,
+			CreatedAt:  row.CreatedAt.Time,
+		}
+	}
+	return deliveries, nil
+}
+
+// webhookToDomain converts a webhook row
+func webhookToDomain(row sqlc.Webhook) domain.Webhook {
+	return domain.Webhook{
+		ID:        row.ID,
+		TenantID:  row.TenantID,
+		URL:       row.Url,
+		Secret:    row.Secret,
+		Events:    row.Events,
+		CreatedAt: row.CreatedAt.Time,
+	}
+}
+
+// webhooksToDomain converts webhook rows
+func webhooksToDomain(rows []sqlc.Webhook) []domain.Webhook {
+	webhooks := make([]domain.Webhook, len(rows))
+	for i, row := range rows {
+		webhooks[i] = webhookToDomain(row)
+	}
+	return webhooks
+}
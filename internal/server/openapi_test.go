@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"user-auth-app/internal/config"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	s := newTestServer(&config.Config{Port: ":8080", AdminAddr: ":9090", Environment: "development", RateLimitRPS: 100, RateLimitBurst: 100})
+	routes := s.setupRoutes()
+
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var doc struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+
+	documented := make(map[string]bool)
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	served := make(map[string]bool)
+	require.NoError(t, chi.Walk(routes.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if route == "/openapi.json" {
+			return nil
+		}
+		served[method+" "+route] = true
+		return nil
+	}))
+
+	for route := range served {
+		assert.True(t, documented[route], "%s is not in the OpenAPI document", route)
+	}
+	for route := range documented {
+		assert.True(t, served[route], "%s is documented but not served", route)
+	}
+}
+
+func TestSwaggerUIIsOptional(t *testing.T) {
+	cfg := config.Config{Port: ":8080", Environment: "development", RateLimitRPS: 100, RateLimitBurst: 100}
+	assert.Equal(t, http.StatusNotFound, status(newTestServer(&cfg).setupRoutes(), "/docs"))
+
+	cfg.SwaggerUI = true
+	assert.Equal(t, http.StatusOK, status(newTestServer(&cfg).setupRoutes(), "/docs"))
+}
@@ -3,6 +3,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,8 +12,10 @@ import (
 	"time"
 
 	"user-auth-app/internal/config"
+	"user-auth-app/internal/domain"
 	"user-auth-app/internal/handler"
 	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/ratelimit"
 	"user-auth-app/internal/service"
 
 	"github.com/go-chi/chi/v5"
@@ -23,6 +26,11 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// requestDurationBuckets span cached reads of a millisecond to requests
+// running into the write timeout. Logins land in the middle because of
+// password hashing.
+var requestDurationBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
 var (
 	requestsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -32,23 +40,43 @@ var (
 		[]string{"path", "method", "status"},
 	)
 
+	requestErrorsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_request_errors_total",
+			Help: "Total number of HTTP requests that returned a 4xx or 5xx status",
+		},
+		[]string{"path", "method", "status_class"},
+	)
+
 	requestDuration = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "http_request_duration_seconds",
 			Help:    "HTTP request latency in seconds",
-			Buckets: prometheus.DefBuckets,
+			Buckets: requestDurationBuckets,
 		},
 		[]string{"path", "method"},
 	)
 )
 
 type Server struct {
-	httpServer    *http.Server
-	config        *config.Config
-	logger        *zerolog.Logger
-	authHandler   *handler.AuthHandler
-	healthHandler *handler.HealthHandler
-	authService   service.AuthService
+	httpServer        *http.Server
+	adminServer       *http.Server // nil unless config.AdminAddr is set
+	config            *config.Config
+	logger            *zerolog.Logger
+	authHandler       *handler.AuthHandler
+	healthHandler     *handler.HealthHandler
+	adminHandler      *handler.AdminHandler
+	keysHandler       *handler.KeysHandler
+	oauthHandler      *handler.OAuthHandler
+	apiKeyHandler     *handler.APIKeyHandler
+	sessionHandler    *handler.SessionHandler
+	webhookHandler    *handler.WebhookHandler
+	deadLetterHandler *handler.DeadLetterHandler
+	authService       service.AuthService
+	apiKeyService     service.APIKeyService
+	cookieAuth        middleware.CookieAuth
+	ipLimiter         ratelimit.Limiter
+	userLimiter       ratelimit.Limiter
 }
 
 // NewServer creates a new HTTP server
@@ -57,39 +85,66 @@ func NewServer(
 	logger *zerolog.Logger,
 	authHandler *handler.AuthHandler,
 	healthHandler *handler.HealthHandler,
+	adminHandler *handler.AdminHandler,
+	keysHandler *handler.KeysHandler,
+	oauthHandler *handler.OAuthHandler,
+	apiKeyHandler *handler.APIKeyHandler,
+	sessionHandler *handler.SessionHandler,
+	webhookHandler *handler.WebhookHandler,
+	deadLetterHandler *handler.DeadLetterHandler,
 	authService service.AuthService,
+	apiKeyService service.APIKeyService,
+	cookieAuth middleware.CookieAuth,
+	ipLimiter ratelimit.Limiter,
+	userLimiter ratelimit.Limiter,
 ) *Server {
 	return &Server{
-		config:        cfg,
-		logger:        logger,
-		authHandler:   authHandler,
-		healthHandler: healthHandler,
-		authService:   authService,
+		config:            cfg,
+		logger:            logger,
+		authHandler:       authHandler,
+		healthHandler:     healthHandler,
+		adminHandler:      adminHandler,
+		keysHandler:       keysHandler,
+		oauthHandler:      oauthHandler,
+		apiKeyHandler:     apiKeyHandler,
+		sessionHandler:    sessionHandler,
+		webhookHandler:    webhookHandler,
+		deadLetterHandler: deadLetterHandler,
+		authService:       authService,
+		apiKeyService:     apiKeyService,
+		cookieAuth:        cookieAuth,
+		ipLimiter:         ipLimiter,
+		userLimiter:       userLimiter,
 	}
 }
 
-// Start starts the HTTP server with graceful shutdown
+// Start starts the HTTP server, and the admin server if configured, with
+// graceful shutdown
 func (s *Server) Start() error {
-	router := s.setupRoutes()
-
-	s.httpServer = &http.Server{
-		Addr:         s.config.Port,
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	s.httpServer = s.newHTTPServer(s.config.Port, s.setupRoutes())
+	servers := []*http.Server{s.httpServer}
+
+	if s.config.AdminAddr != "" {
+		s.adminServer = s.newHTTPServer(s.config.AdminAddr, s.setupAdminRoutes())
+		// No write timeout: CPU profiles and traces stream for as long as
+		// the caller asks
+		s.adminServer.WriteTimeout = 0
+		servers = append(servers, s.adminServer)
 	}
 
-	// Start server in goroutine
-	serverErrors := make(chan error, 1)
-	go func() {
-		s.logger.Info().
-			Str("address", s.httpServer.Addr).
-			Str("environment", s.config.Environment).
-			Msg("Starting HTTP server")
+	// Start servers in goroutines
+	serverErrors := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			s.logger.Info().
+				Str("address", srv.Addr).
+				Str("environment", s.config.Environment).
+				Bool("admin", srv == s.adminServer).
+				Msg("Starting HTTP server")
 
-		serverErrors <- s.httpServer.ListenAndServe()
-	}()
+			serverErrors <- srv.ListenAndServe()
+		}()
+	}
 
 	// Wait for interrupt signal or server error
 	shutdown := make(chan os.Signal, 1)
@@ -98,6 +153,10 @@ func (s *Server) Start() error {
 	select {
 	case err := <-serverErrors:
 		if err != nil && err != http.ErrServerClosed {
+			// Don't leave the other listener running
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			s.shutdown(ctx, servers)
 			return fmt.Errorf("server error: %w", err)
 		}
 	case sig := <-shutdown:
@@ -107,11 +166,7 @@ func (s *Server) Start() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
-		if err := s.httpServer.Shutdown(ctx); err != nil {
-			s.logger.Error().Err(err).Msg("Server forced to shutdown")
-			if err := s.httpServer.Close(); err != nil {
-				return fmt.Errorf("error closing server: %w", err)
-			}
+		if err := s.shutdown(ctx, servers); err != nil {
 			return fmt.Errorf("graceful shutdown failed: %w", err)
 		}
 
@@ -121,44 +176,218 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// newHTTPServer creates a server with the configured connection timeouts
+func (s *Server) newHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: s.config.HTTPReadHeaderTimeout,
+		ReadTimeout:       s.config.HTTPReadTimeout,
+		WriteTimeout:      s.config.HTTPWriteTimeout,
+		IdleTimeout:       s.config.HTTPIdleTimeout,
+	}
+}
+
+// shutdown stops servers in order, the public one first so the admin
+// endpoints stay up while requests drain. A server that doesn't stop in
+// time is closed.
+func (s *Server) shutdown(ctx context.Context, servers []*http.Server) error {
+	var errs []error
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			s.logger.Error().Err(err).Str("address", srv.Addr).Msg("Server forced to shutdown")
+			errs = append(errs, err)
+			if err := srv.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("error closing server: %w", err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // setupRoutes configures all routes and middleware
 func (s *Server) setupRoutes() http.Handler {
 	r := chi.NewRouter()
 
 	// Global middleware (order matters)
 	r.Use(middleware.Recovery(s.logger))
-	r.Use(middleware.Logger(s.logger))
-	r.Use(middleware.CORS(s.config.AllowedOrigins))
 	r.Use(chimiddleware.RequestID)
-	r.Use(chimiddleware.RealIP)
-	r.Use(middleware.RateLimiter(s.config.RateLimitRPS, s.config.RateLimitBurst))
+	r.Use(middleware.RealIP(s.config.TrustedProxies))
+	r.Use(middleware.ClientInfo)
+	r.Use(middleware.Tenant(s.config.TenantBaseDomain))
+	r.Use(middleware.Logger(s.logger, s.config.LogRequestBodies))
+	r.Use(middleware.CORS(s.config.AllowedOrigins))
+	if s.cookieAuth.CSRF {
+		r.Use(middleware.CSRF(s.cookieAuth, s.logger))
+	}
+	r.Use(middleware.RateLimiter(s.ipLimiter))
 	r.Use(s.metricsMiddleware())
+	// Inside the metrics middleware, which sees the status once
+	// compression has decided how to send the response
+	r.Use(middleware.Compress(s.config.CompressionLevel, s.config.CompressionMinBytes))
 
-	// Health check routes (no auth required)
-	r.Get("/health", s.healthHandler.Health)
-	r.Get("/ready", s.healthHandler.Readiness)
-	r.Get("/live", s.healthHandler.Liveness)
-	r.Get("/metrics", promhttp.Handler().ServeHTTP)
+	// Health check routes (no auth required), unless they are served on
+	// the admin listener
+	if s.config.AdminAddr == "" {
+		r.Get("/health", s.healthHandler.Health)
+		r.Get("/ready", s.healthHandler.Readiness)
+		r.Get("/live", s.healthHandler.Liveness)
+		r.Get("/version", s.healthHandler.Version)
+		r.With(s.metricsAuth()).Get("/metrics", promhttp.Handler().ServeHTTP)
+
+		// Profiles reveal as much as metrics, so they take the same
+		// credentials
+		if s.config.EnablePprof {
+			r.With(s.metricsAuth()).Mount("/debug", chimiddleware.Profiler())
+		}
+	}
+
+	// Token verification keys for other services
+	r.Get("/.well-known/jwks.json", s.keysHandler.JWKS)
+
+	// API description for client teams
+	if serveSpec, err := s.openAPIHandler(); err != nil {
+		s.logger.Error().Err(err).Msg("Failed to build OpenAPI document")
+	} else {
+		r.Get("/openapi.json", serveSpec)
+		if s.config.SwaggerUI {
+			r.Get("/docs", swaggerUI)
+		}
+	}
+
+	jwtAuth := middleware.AuthMiddleware(s.authService, s.logger, s.cookieAuth)
+	apiKeyOrJWT := middleware.AuthOrAPIKey(middleware.APIKeyMiddleware(s.apiKeyService, s.logger), jwtAuth)
+
+	// One limiter for all authenticated routes, so each user has a single
+	// budget
+	userLimit := middleware.UserRateLimiter(s.userLimiter)
+
+	// Admin routes are only reachable from the configured networks,
+	// checked before the credentials
+	adminIPs := middleware.RequireIP(middleware.IPFilter{
+		Allow:          s.config.AdminAllowedNetworks,
+		Deny:           s.config.AdminDeniedNetworks,
+		TrustedProxies: s.config.TrustedProxies,
+	}, s.logger)
+
+	// The audit export streams its response, so it is routed outside
+	// /api/v1, whose Timeout middleware buffers responses. The handler
+	// bounds its own run time.
+	r.With(
+		adminIPs,
+		apiKeyOrJWT,
+		userLimit,
+		middleware.RequireScope(service.ScopeFull, service.ScopeAPIKey),
+		middleware.RequireAPIKeyScope(service.APIKeyScopeAuditRead),
+		middleware.RequirePermission(domain.PermissionAuditRead),
+	).Get("/api/v1/admin/audit/export", s.adminHandler.ExportAuditEvents)
 
 	// API routes
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(middleware.Timeout(s.config.Timeout))
+
+		// Routes whose body is JSON must say so
+		requireJSON := middleware.RequireJSON(s.config.RequireJSONContentType)
+
 		// Public routes
-		r.Post("/register", s.authHandler.Register)
-		r.Post("/login", s.authHandler.Login)
+		r.With(requireJSON).Post("/register", s.authHandler.Register)
+		r.With(requireJSON).Post("/login", s.authHandler.Login)
+		r.With(requireJSON).Post("/verify/resend", s.authHandler.ResendVerification)
+		r.Get("/auth/password-policy", s.authHandler.PasswordPolicy)
+
+		// Refresh checks the token itself, accepting an expired one while
+		// its session is active
+		r.With(middleware.RefreshAuth(s.logger, s.cookieAuth)).Post("/auth/refresh", s.authHandler.RefreshToken)
+
+		// Social login, only when a provider is configured
+		if s.oauthHandler != nil {
+			r.Get("/auth/google", s.oauthHandler.GoogleStart)
+			r.Get("/auth/google/callback", s.oauthHandler.GoogleCallback)
+		}
+
+		// Routes that also accept an API key with the matching scope
+		r.Group(func(r chi.Router) {
+			r.Use(apiKeyOrJWT)
+			r.Use(userLimit)
+			r.Use(middleware.RequireScope(service.ScopeFull, service.ScopeAPIKey))
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireAPIKeyScope(service.APIKeyScopeUsersRead))
+				r.Get("/users/{id}", s.authHandler.GetProfile)
+				r.With(requireJSON).Post("/users/batch", s.authHandler.GetProfiles)
+			})
+		})
 
 		// Protected routes
 		r.Group(func(r chi.Router) {
 			// Require authentication
-			r.Use(middleware.AuthMiddleware(s.authService, s.logger))
+			r.Use(jwtAuth)
+			r.Use(userLimit)
+
+			// Available to every token, including those with a pending
+			// password change
+			r.With(requireJSON).Post("/auth/change-password", s.authHandler.ChangePassword)
+
+			// Everything else requires a full-scope token
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireScope(service.ScopeFull))
 
-			// User routes
-			r.Get("/users/{id}", s.authHandler.GetProfile)
-			r.Post("/auth/refresh", s.authHandler.RefreshToken)
+				// User routes
+				r.Get("/users/me", s.authHandler.GetCurrentUser)
 
-			// Admin routes
+				// API key management; keys can't manage keys
+				r.With(requireJSON).Post("/api-keys", s.apiKeyHandler.CreateAPIKey)
+				r.Get("/api-keys", s.apiKeyHandler.ListAPIKeys)
+				r.Delete("/api-keys/{id}", s.apiKeyHandler.RevokeAPIKey)
+
+				// Sessions of the signed-in user
+				r.Get("/users/me/sessions", s.sessionHandler.ListSessions)
+				r.Delete("/users/me/sessions/{id}", s.sessionHandler.RevokeSession)
+			})
+		})
+
+		// Admin routes
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(adminIPs)
+
+			// The audit log also accepts an API key with the audit:read
+			// scope
 			r.Group(func(r chi.Router) {
-				r.Use(middleware.RequireRole("admin"))
-				// Add admin-only routes here
+				r.Use(apiKeyOrJWT)
+				r.Use(userLimit)
+				r.Use(middleware.RequireScope(service.ScopeFull, service.ScopeAPIKey))
+				r.Use(middleware.RequireAPIKeyScope(service.APIKeyScopeAuditRead))
+				r.Use(middleware.RequirePermission(domain.PermissionAuditRead))
+				r.Get("/audit", s.adminHandler.ListAuditEvents)
+			})
+
+			// Everything else takes a full-scope token, each route guarded
+			// by the permission it needs
+			r.Group(func(r chi.Router) {
+				r.Use(jwtAuth)
+				r.Use(userLimit)
+				r.Use(middleware.RequireScope(service.ScopeFull))
+
+				usersList := middleware.RequirePermission(domain.PermissionUsersList)
+				usersWrite := middleware.RequirePermission(domain.PermissionUsersWrite)
+				keysManage := middleware.RequirePermission(domain.PermissionKeysManage)
+				webhooksManage := middleware.RequirePermission(domain.PermissionWebhooksManage)
+				deadLettersManage := middleware.RequirePermission(domain.PermissionDeadLettersManage)
+				r.With(usersList).Get("/users", s.adminHandler.ListUsers)
+				r.With(usersList).Get("/users/search", s.adminHandler.SearchUsers)
+				r.With(usersWrite, requireJSON).Post("/users", s.adminHandler.CreateUser)
+				r.With(usersWrite).Post("/users/import", s.adminHandler.ImportUsers)
+				r.With(usersWrite, requireJSON).Post("/users/deactivate", s.adminHandler.DeactivateUsers)
+				r.With(usersWrite, requireJSON).Put("/users/{id}/role", s.adminHandler.UpdateUserRole)
+				r.With(usersWrite).Delete("/users/{id}", s.adminHandler.DeactivateUser)
+				r.With(usersWrite).Post("/invites", s.adminHandler.CreateInvite)
+				r.With(keysManage).Get("/keys", s.keysHandler.ListKeys)
+				r.With(webhooksManage, requireJSON).Post("/webhooks", s.webhookHandler.CreateWebhook)
+				r.With(webhooksManage).Get("/webhooks", s.webhookHandler.ListWebhooks)
+				r.With(webhooksManage).Delete("/webhooks/{id}", s.webhookHandler.DeleteWebhook)
+				r.With(webhooksManage).Get("/webhooks/{id}/deliveries", s.webhookHandler.ListDeliveries)
+				r.With(deadLettersManage).Get("/dead-letters", s.deadLetterHandler.ListDeadLetters)
+				r.With(deadLettersManage).Post("/dead-letters/{id}/replay", s.deadLetterHandler.ReplayDeadLetter)
 			})
 		})
 	})
@@ -173,12 +402,38 @@ func (s *Server) setupRoutes() http.Handler {
 	return r
 }
 
+// setupAdminRoutes configures the admin listener: health checks, metrics
+// and, with EnablePprof, pprof. It is meant for the internal network only.
+func (s *Server) setupAdminRoutes() http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.Recovery(s.logger))
+
+	r.Get("/health", s.healthHandler.Health)
+	r.Get("/readyz", s.healthHandler.Readiness)
+	r.Get("/livez", s.healthHandler.Liveness)
+	r.Get("/version", s.healthHandler.Version)
+	r.With(s.metricsAuth()).Get("/metrics", promhttp.Handler().ServeHTTP)
+	if s.config.EnablePprof {
+		r.Mount("/debug", chimiddleware.Profiler())
+	}
+
+	return r
+}
+
+// metricsAuth guards /metrics with the configured credentials, if any
+func (s *Server) metricsAuth() func(next http.Handler) http.Handler {
+	return middleware.RequireMetricsAuth(middleware.MetricsAuth{
+		Token:    s.config.MetricsToken,
+		Username: s.config.MetricsUsername,
+		Password: s.config.MetricsPassword,
+	})
+}
+
 // metricsMiddleware records Prometheus metrics
 func (s *Server) metricsMiddleware() func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
-			path := r.URL.Path
 			method := r.Method
 
 			// Wrap response writer to capture status
@@ -189,10 +444,24 @@ func (s *Server) metricsMiddleware() func(next http.Handler) http.Handler {
 
 			// Record metrics
 			duration := time.Since(start).Seconds()
-			status := fmt.Sprintf("%d", ww.Status())
+			code := ww.Status()
+			if code == 0 {
+				// Handler wrote nothing; net/http sends 200
+				code = http.StatusOK
+			}
+			status := fmt.Sprintf("%d", code)
+			path := middleware.RoutePattern(r)
 
 			requestDuration.WithLabelValues(path, method).Observe(duration)
 			requestsTotal.WithLabelValues(path, method, status).Inc()
+			if code >= 400 {
+				requestErrorsTotal.WithLabelValues(path, method, statusClass(code)).Inc()
+			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// statusClass buckets a status code into 1xx/2xx/3xx/4xx/5xx
+func statusClass(code int) string {
+	return fmt.Sprintf("%dxx", code/100)
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/config"
+	"user-auth-app/internal/handler"
+	"user-auth-app/internal/middleware"
+	"user-auth-app/internal/ratelimit"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(cfg *config.Config) *Server {
+	logger := zerolog.Nop()
+	return NewServer(cfg, &logger, nil, handler.NewHealthHandler(nil, nil, nil, nil, cfg.Environment),
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, middleware.CookieAuth{},
+		ratelimit.NewMemory(cfg.RateLimitRPS, cfg.RateLimitBurst), ratelimit.NewMemory(cfg.UserRateLimitRPS, cfg.UserRateLimitBurst))
+}
+
+func status(h http.Handler, path string) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code
+}
+
+func TestOpsRoutesFollowAdminAddr(t *testing.T) {
+	t.Run("single port", func(t *testing.T) {
+		s := newTestServer(&config.Config{Port: ":8080", Environment: "development", RateLimitRPS: 100, RateLimitBurst: 100})
+		routes := s.setupRoutes()
+
+		assert.Equal(t, http.StatusOK, status(routes, "/live"))
+		assert.Equal(t, http.StatusOK, status(routes, "/metrics"))
+		assert.Equal(t, http.StatusNotFound, status(routes, "/debug/pprof/"), "pprof is off by default")
+	})
+
+	t.Run("admin listener", func(t *testing.T) {
+		s := newTestServer(&config.Config{Port: ":8080", AdminAddr: ":9090", EnablePprof: true, Environment: "development", RateLimitRPS: 100, RateLimitBurst: 100, MetricsToken: "scrape"})
+		routes := s.setupRoutes()
+		admin := s.setupAdminRoutes()
+
+		for _, path := range []string{"/live", "/metrics", "/health", "/debug/pprof/"} {
+			assert.Equal(t, http.StatusNotFound, status(routes, path), "public listener must not serve %s", path)
+		}
+
+		assert.Equal(t, http.StatusOK, status(admin, "/livez"))
+		assert.Equal(t, http.StatusOK, status(admin, "/debug/pprof/"))
+		assert.Equal(t, http.StatusUnauthorized, status(admin, "/metrics"), "metrics credentials still apply")
+	})
+}
+
+func TestPprofIsGated(t *testing.T) {
+	base := config.Config{Port: ":8080", Environment: "development", RateLimitRPS: 100, RateLimitBurst: 100}
+
+	t.Run("disabled on admin listener", func(t *testing.T) {
+		cfg := base
+		cfg.AdminAddr = ":9090"
+		assert.Equal(t, http.StatusNotFound, status(newTestServer(&cfg).setupAdminRoutes(), "/debug/pprof/"))
+	})
+
+	t.Run("enabled without admin listener", func(t *testing.T) {
+		cfg := base
+		cfg.EnablePprof = true
+		cfg.MetricsToken = "scrape"
+		routes := newTestServer(&cfg).setupRoutes()
+
+		assert.Equal(t, http.StatusUnauthorized, status(routes, "/debug/pprof/"), "metrics credentials apply")
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+		req.Header.Set("Authorization", "Bearer scrape")
+		routes.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestAdminRoutesFollowIPFilter(t *testing.T) {
+	s := newTestServer(&config.Config{
+		Port: ":8080", Environment: "development", RateLimitRPS: 100, RateLimitBurst: 100, Timeout: time.Minute,
+		AdminAllowedNetworks: []netip.Prefix{netip.MustParsePrefix("198.51.100.0/24")},
+		TrustedProxies:       []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	})
+	routes := s.setupRoutes()
+
+	request := func(path, remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Disallowed networks are turned away before their credentials are
+	// looked at
+	for _, path := range []string{"/api/v1/admin/users", "/api/v1/admin/audit", "/api/v1/admin/audit/export"} {
+		assert.Equal(t, http.StatusForbidden, request(path, "203.0.113.7:1234", ""), path)
+		assert.Equal(t, http.StatusForbidden, request(path, "203.0.113.7:1234", "198.51.100.4"), "spoofed %s", path)
+		assert.Equal(t, http.StatusUnauthorized, request(path, "198.51.100.4:1234", ""), path)
+		assert.Equal(t, http.StatusUnauthorized, request(path, "10.0.0.2:80", "198.51.100.4"), "proxied %s", path)
+	}
+
+	// Other routes are open to every network
+	assert.Equal(t, http.StatusUnauthorized, request("/api/v1/users/me", "203.0.113.7:1234", ""))
+}
+
+func TestSlowHeadersAreCutOff(t *testing.T) {
+	s := newTestServer(&config.Config{
+		Port:                  ":8080",
+		Environment:           "development",
+		RateLimitRPS:          100,
+		RateLimitBurst:        100,
+		HTTPReadHeaderTimeout: 100 * time.Millisecond,
+		HTTPReadTimeout:       time.Second,
+		HTTPWriteTimeout:      time.Second,
+		HTTPIdleTimeout:       time.Second,
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := s.newHTTPServer(ln.Addr().String(), s.setupRoutes())
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Start a request but never finish the headers
+	_, err = io.WriteString(conn, "GET /live HTTP/1.1\r\nHost: localhost\r\n")
+	require.NoError(t, err)
+
+	start := time.Now()
+	require.NoError(t, conn.SetReadDeadline(start.Add(2*time.Second)))
+	_, err = io.ReadAll(conn)
+	require.NoError(t, err, "server should close the connection before the client gives up")
+	assert.Less(t, time.Since(start), time.Second)
+}
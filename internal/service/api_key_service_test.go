@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"user-auth-app/internal/domain"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memAPIKeyRepo keeps API keys in memory, keyed by hash
+type memAPIKeyRepo struct {
+	keys    map[string]domain.APIKey
+	owner   domain.User
+	touched []int32
+}
+
+func (r *memAPIKeyRepo) CreateAPIKey(ctx context.Context, key domain.APIKey, keyHash string) (domain.APIKey, error) {
+	key.ID = int32(len(r.keys) + 1)
+	r.keys[keyHash] = key
+	return key, nil
+}
+
+func (r *memAPIKeyRepo) GetAPIKeyByHash(ctx context.Context, keyHash string) (domain.APIKey, domain.User, error) {
+	key, ok := r.keys[keyHash]
+	if !ok {
+		return domain.APIKey{}, domain.User{}, domain.ErrAPIKeyNotFound
+	}
+	return key, r.owner, nil
+}
+
+func (r *memAPIKeyRepo) ListAPIKeys(ctx context.Context, userID int32) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *memAPIKeyRepo) RevokeAPIKey(ctx context.Context, userID, keyID int32) error {
+	for hash, key := range r.keys {
+		if key.ID == keyID && key.UserID == userID {
+			delete(r.keys, hash)
+			return nil
+		}
+	}
+	return domain.ErrAPIKeyNotFound
+}
+
+func (r *memAPIKeyRepo) TouchAPIKey(ctx context.Context, keyID int32) error {
+	r.touched = append(r.touched, keyID)
+	return nil
+}
+
+func newAPIKeyTestService() (*apiKeyService, *memAPIKeyRepo) {
+	logger := zerolog.Nop()
+	repo := &memAPIKeyRepo{
+		keys:  make(map[string]domain.APIKey),
+		owner: domain.User{ID: 5, Email: "svc@example.com", Role: "admin"},
+	}
+	return &apiKeyService{repo: repo, logger: &logger}, repo
+}
+
+func TestGenerateAndAuthenticateAPIKey(t *testing.T) {
+	s, repo := newAPIKeyTestService()
+	ctx := context.Background()
+
+	key, plaintext, err := s.GenerateAPIKey(ctx, 5, " billing sync ", []string{APIKeyScopeUsersRead, APIKeyScopeUsersRead})
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(plaintext, apiKeyPrefix))
+	assert.Equal(t, plaintext[:apiKeyDisplayLen], key.Prefix)
+	assert.Equal(t, "billing sync", key.Name)
+	assert.Equal(t, []string{APIKeyScopeUsersRead}, key.Scopes)
+	assert.NotContains(t, repo.keys, plaintext, "the key itself must not be stored")
+
+	claims, err := s.AuthenticateAPIKey(ctx, plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, int32(5), claims.UserID)
+	assert.Equal(t, "admin", claims.Role)
+	assert.Equal(t, ScopeAPIKey, claims.Scope)
+	assert.Equal(t, key.ID, claims.APIKeyID)
+	assert.Equal(t, []string{APIKeyScopeUsersRead}, claims.APIKeyScopes)
+	assert.Equal(t, []int32{key.ID}, repo.touched)
+
+	require.NoError(t, s.RevokeAPIKey(ctx, 5, key.ID))
+	_, err = s.AuthenticateAPIKey(ctx, plaintext)
+	assert.ErrorIs(t, err, domain.ErrInvalidAPIKey)
+}
+
+func TestAuthenticateAPIKeyRejectsUnknownKeys(t *testing.T) {
+	s, _ := newAPIKeyTestService()
+
+	for _, key := range []string{"", "not-a-key", apiKeyPrefix + "unknown"} {
+		_, err := s.AuthenticateAPIKey(context.Background(), key)
+		assert.ErrorIs(t, err, domain.ErrInvalidAPIKey, key)
+	}
+}
+
+func TestGenerateAPIKeyValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		scopes []string
+		field  string
+	}{
+		{"missing name", "  ", []string{APIKeyScopeUsersRead}, "name"},
+		{"name too long", strings.Repeat("k", apiKeyNameMaxLen+1), []string{APIKeyScopeUsersRead}, "name"},
+		{"no scopes", "sync", nil, "scopes"},
+		{"unknown scope", "sync", []string{"users:write"}, "scopes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newAPIKeyTestService()
+
+			_, _, err := s.GenerateAPIKey(context.Background(), 5, tt.key, tt.scopes)
+
+			var appErr *domain.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Contains(t, appErr.Fields, tt.field)
+			assert.Empty(t, repo.keys)
+		})
+	}
+}
+
+func TestRevokeAPIKeyOfAnotherUser(t *testing.T) {
+	s, _ := newAPIKeyTestService()
+	ctx := context.Background()
+
+	key, _, err := s.GenerateAPIKey(ctx, 5, "sync", []string{APIKeyScopeUsersRead})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, s.RevokeAPIKey(ctx, 6, key.ID), domain.ErrAPIKeyNotFound)
+}
@@ -0,0 +1,50 @@
+// Package service implements audit trail queries
+package service
+
+import (
+	"context"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+
+	"github.com/rs/zerolog"
+)
+
+type auditService struct {
+	repo   repository.AuditRepository
+	logger *zerolog.Logger
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(repo repository.AuditRepository, logger *zerolog.Logger) AuditService {
+	return &auditService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *auditService) ListEvents(ctx context.Context, userID *int32, limit int) ([]domain.AuditEvent, error) {
+	events, err := s.repo.ListAuditEvents(ctx, userID, limit)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to list audit events")
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (s *auditService) ExportEvents(ctx context.Context, from, to time.Time, fn func(domain.AuditEvent) error) error {
+	// Errors of fn, such as a client that went away, are the caller's to
+	// report
+	var fnErr error
+	err := s.repo.ExportAuditEvents(ctx, from, to, func(event domain.AuditEvent) error {
+		fnErr = fn(event)
+		return fnErr
+	})
+	if err != nil && fnErr == nil {
+		s.logger.Error().Err(err).Time("from", from).Time("to", to).Msg("Failed to export audit events")
+	}
+
+	return err
+}
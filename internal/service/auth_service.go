@@ -7,81 +7,244 @@ import (
 	"fmt"
 	"time"
 
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/breach"
 	"user-auth-app/internal/cache"
 	"user-auth-app/internal/domain"
 	"user-auth-app/internal/email"
+	"user-auth-app/internal/hashing"
 	"user-auth-app/internal/messaging"
+	"user-auth-app/internal/ratelimit"
 	"user-auth-app/internal/repository"
+	"user-auth-app/internal/signing"
+	"user-auth-app/internal/tenant"
+	"user-auth-app/internal/validator"
+	"user-auth-app/internal/webhook"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/rs/zerolog"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// passwordChangeTokenExpiry bounds the lifetime of tokens issued to users
+// who must change their password before doing anything else
+const passwordChangeTokenExpiry = 15 * time.Minute
+
 type authService struct {
 	repo         repository.UserRepository
+	sessions     repository.SessionRepository
 	cache        cache.Service
 	broker       messaging.Broker
+	webhooks     webhook.Notifier
 	emailService email.Service
+	auditSink    audit.Sink
+	pwnedChecker breach.PwnedChecker
 	logger       *zerolog.Logger
-	jwtSecret    string
+	keys         *signing.KeySet
 	jwtExpiry    time.Duration
+	hasher       hashing.PasswordHasher
+
+	// sessionLifetime is how long a login's session lasts, or
+	// rememberMeLifetime when the user asked to be remembered. Refreshing
+	// extends the session to that long from then.
+	sessionLifetime    time.Duration
+	rememberMeLifetime time.Duration
+
+	// issuer and audience are stamped into every token and required when
+	// validating, so tokens don't carry over between environments
+	issuer   string
+	audience string
+
+	// enumerationSafe hides whether an email is already registered
+	enumerationSafe bool
+
+	// tokenBindingMode ties issued tokens to the client's IP network or
+	// user agent (see TokenBinding* constants)
+	tokenBindingMode string
+
+	// permissions resolves the permissions embedded in a user's tokens
+	permissions domain.RolePermissions
+
+	// maxFailedLogins is how many failed logins lock an account; zero
+	// disables lockout
+	maxFailedLogins int32
+
+	// passwordHistory is how many recent passwords, including the current
+	// one, a new password must not match; zero or one only rejects the
+	// current password
+	passwordHistory int
+
+	// passwordMaxAge is how long a password stays valid before the user
+	// must change it; zero disables expiry
+	passwordMaxAge time.Duration
+
+	// verificationCooldown is how long after a verification resend
+	// another one for the same email is ignored
+	verificationCooldown time.Duration
+
+	// loginFailures blocks addresses that failed to log in to too many
+	// accounts; nil disables the check
+	loginFailures ratelimit.FailureCounter
+
+	// inviteOnly requires an invite to register; invites expire after
+	// inviteTTL
+	inviteOnly bool
+	inviteTTL  time.Duration
+
+	// reservedUsernames are kept out of usernames derived for social
+	// sign-up
+	reservedUsernames []string
+}
+
+// AuthServiceConfig holds what NewAuthService needs. Sessions, Cache,
+// Broker, Webhooks, EmailService, AuditSink and LoginFailures are optional
+// and may be nil.
+type AuthServiceConfig struct {
+	Repo          repository.UserRepository
+	Sessions      repository.SessionRepository
+	Cache         cache.Service
+	Broker        messaging.Broker
+	Webhooks      webhook.Notifier
+	EmailService  email.Service
+	AuditSink     audit.Sink
+	PwnedChecker  breach.PwnedChecker
+	LoginFailures ratelimit.FailureCounter
+	Logger        *zerolog.Logger
+	Keys          *signing.KeySet
+	Hasher        hashing.PasswordHasher
+
+	JWTExpiry          time.Duration
+	SessionLifetime    time.Duration
+	RememberMeLifetime time.Duration
+	Issuer             string
+	Audience           string
+
+	EnumerationSafe      bool
+	TokenBindingMode     string
+	Permissions          domain.RolePermissions
+	MaxFailedLogins      int32
+	PasswordHistory      int
+	PasswordMaxAge       time.Duration
+	VerificationCooldown time.Duration
+	InviteOnly           bool
+	InviteTTL            time.Duration
+	ReservedUsernames    []string
 }
 
 // NewAuthService creates a new authentication service
-func NewAuthService(
-	repo repository.UserRepository,
-	cache cache.Service,
-	broker messaging.Broker,
-	emailService email.Service,
-	logger *zerolog.Logger,
-	jwtSecret string,
-	jwtExpiry time.Duration,
-) AuthService {
+func NewAuthService(cfg AuthServiceConfig) AuthService {
 	return &authService{
-		repo:         repo,
-		cache:        cache,
-		broker:       broker,
-		emailService: emailService,
-		logger:       logger,
-		jwtSecret:    jwtSecret,
-		jwtExpiry:    jwtExpiry,
+		repo:         cfg.Repo,
+		sessions:     cfg.Sessions,
+		cache:        cfg.Cache,
+		broker:       cfg.Broker,
+		webhooks:     cfg.Webhooks,
+		emailService: cfg.EmailService,
+		auditSink:    cfg.AuditSink,
+		pwnedChecker: cfg.PwnedChecker,
+		logger:       cfg.Logger,
+		keys:         cfg.Keys,
+		jwtExpiry:    cfg.JWTExpiry,
+		hasher:       cfg.Hasher,
+		issuer:       cfg.Issuer,
+		audience:     cfg.Audience,
+
+		sessionLifetime:    cfg.SessionLifetime,
+		rememberMeLifetime: cfg.RememberMeLifetime,
+
+		enumerationSafe:  cfg.EnumerationSafe,
+		tokenBindingMode: cfg.TokenBindingMode,
+		permissions:      cfg.Permissions,
+		maxFailedLogins:  cfg.MaxFailedLogins,
+		passwordHistory:  cfg.PasswordHistory,
+		passwordMaxAge:   cfg.PasswordMaxAge,
+
+		verificationCooldown: cfg.VerificationCooldown,
+		loginFailures:        cfg.LoginFailures,
+
+		inviteOnly: cfg.InviteOnly,
+		inviteTTL:  cfg.InviteTTL,
+
+		reservedUsernames: cfg.ReservedUsernames,
 	}
 }
 
-func (s *authService) Register(ctx context.Context, username, email, password, role string) (domain.User, error) {
+func (s *authService) Register(ctx context.Context, username, email, password, role, inviteCode string) (domain.User, error) {
+	logger := s.loggerFromCtx(ctx)
+	username = validator.NormalizeUsername(username)
+	email = validator.NormalizeEmail(email)
+
+	if s.inviteOnly && inviteCode == "" {
+		return domain.User{}, domain.ErrInviteRequired
+	}
+
 	// Validate password
 	if password == "" {
 		return domain.User{}, domain.ErrValidation
 	}
 
+	if s.isPwned(ctx, password) {
+		return domain.User{}, domain.NewValidationError(map[string]string{
+			"password": "has appeared in a data breach; choose a different password",
+		})
+	}
+
 	// Default role
 	if role == "" {
 		role = "user"
 	}
 
-	// Hash password
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to hash password")
-		return domain.User{}, fmt.Errorf("password hashing failed: %w", err)
-	}
-
-	// Create user
 	user := domain.User{
 		Username: username,
 		Email:    email,
 		Role:     role,
 	}
 
-	created, err := s.repo.CreateUser(ctx, user, string(hash))
+	var created domain.User
+	createUser := func(repo repository.UserRepository) error {
+		// The invite is checked, and locked against concurrent use, before
+		// anything else, so a caller without a valid one can't learn which
+		// usernames and emails are taken
+		if s.inviteOnly {
+			if err := repo.LockInvite(ctx, hashInviteCode(inviteCode)); err != nil {
+				return err
+			}
+		}
+
+		// Hash password. This always happens before the duplicate check so
+		// the response time doesn't reveal whether the email is registered.
+		hash, err := s.hasher.Hash(password)
+		if err != nil {
+			return fmt.Errorf("password hashing failed: %w", err)
+		}
+
+		if created, err = repo.CreateUser(ctx, user, hash); err != nil || !s.inviteOnly {
+			return err
+		}
+		return repo.ConsumeInvite(ctx, hashInviteCode(inviteCode), created.ID)
+	}
+
+	var err error
+	if s.inviteOnly {
+		// The invite is only used up if the user is created
+		err = s.repo.WithTx(ctx, createUser)
+	} else {
+		err = createUser(s.repo)
+	}
 	if err != nil {
 		if errors.Is(err, domain.ErrDuplicateEmail) {
+			if s.enumerationSafe {
+				s.notifyRegistrationAttempt(email)
+				return domain.User{}, nil
+			}
 			return domain.User{}, domain.ErrDuplicateEmail
 		}
-		s.logger.Error().Err(err).Msg("Failed to create user")
+		if isInviteError(err) {
+			return domain.User{}, err
+		}
+		logger.Error().Err(err).Msg("Failed to create user")
 		return domain.User{}, fmt.Errorf("user creation failed: %w", err)
 	}
+	s.invalidateUserCache(ctx, created.ID)
 
 	// Send welcome email asynchronously
 	if s.emailService != nil && s.emailService.IsAvailable() {
@@ -90,27 +253,29 @@ func (s *authService) Register(ctx context.Context, username, email, password, r
 			defer cancel()
 
 			if err := s.emailService.SendWelcomeEmail(emailCtx, created.Email, created.Username); err != nil {
-				s.logger.Error().Err(err).Str("email", created.Email).Msg("Failed to send welcome email")
+				logger.Error().Err(err).Str("email", created.Email).Msg("Failed to send welcome email")
 			} else {
-				s.logger.Info().Str("email", created.Email).Msg("Welcome email sent")
+				logger.Info().Str("email", created.Email).Msg("Welcome email sent")
 			}
 		}()
 	}
 
-	// Publish user registration event
-	if s.broker != nil && s.broker.IsAvailable() {
-		event := map[string]interface{}{
-			"user_id":   created.ID,
-			"email":     created.Email,
-			"username":  created.Username,
-			"timestamp": time.Now().UTC(),
-		}
-		if err := s.broker.PublishJSON("user.registered", event); err != nil {
-			s.logger.Error().Err(err).Msg("Failed to publish registration event")
-		}
-	}
+	s.publishEvent(ctx, domain.EventUserRegistered, map[string]interface{}{
+		"user_id":   created.ID,
+		"tenant_id": created.TenantID,
+		"email":     created.Email,
+		"username":  created.Username,
+		"timestamp": time.Now().UTC(),
+	})
 
-	s.logger.Info().
+	authRegistrations.Inc()
+	s.recordAudit(ctx, domain.AuditEvent{
+		Type:    domain.AuditUserRegistered,
+		UserID:  &created.ID,
+		Success: true,
+	})
+
+	logger.Info().
 		Int32("user_id", created.ID).
 		Str("email", email).
 		Msg("User registered successfully")
@@ -118,31 +283,97 @@ func (s *authService) Register(ctx context.Context, username, email, password, r
 	return created, nil
 }
 
-func (s *authService) Login(ctx context.Context, email, password string) (string, time.Time, error) {
-	// Get user by email
-	user, hash, err := s.repo.GetUserByEmail(ctx, email)
+func (s *authService) Login(ctx context.Context, identifier, password string, rememberMe bool) (domain.User, string, time.Time, error) {
+	logger := s.loggerFromCtx(ctx)
+
+	// Addresses guessing across accounts are refused before any lookup
+	if err := s.checkLoginAddress(ctx); err != nil {
+		return domain.User{}, "", time.Time{}, err
+	}
+
+	// Usernames can't contain '@', so anything that parses as an email is
+	// looked up by email
+	lookup, unknownReason := s.repo.GetUserByUsername, "unknown_username"
+	if email := validator.NormalizeEmail(identifier); validator.IsEmail(email) {
+		lookup, unknownReason = s.repo.GetUserByEmail, "unknown_email"
+		identifier = email
+	} else {
+		identifier = validator.NormalizeUsername(identifier)
+	}
+
+	user, hash, err := lookup(ctx, identifier)
 	if err != nil {
 		if errors.Is(err, domain.ErrUserNotFound) {
-			return "", time.Time{}, domain.ErrInvalidCredentials
+			authLoginAttempts.WithLabelValues("failure").Inc()
+			s.recordAudit(ctx, domain.AuditEvent{
+				Type:    domain.AuditLoginFailure,
+				Details: map[string]string{"reason": unknownReason},
+			})
+			s.recordAddressFailure(ctx, identifier)
+			return domain.User{}, "", time.Time{}, domain.ErrInvalidCredentials
 		}
-		s.logger.Error().Err(err).Msg("Failed to get user")
-		return "", time.Time{}, fmt.Errorf("login failed: %w", err)
+		logger.Error().Err(err).Msg("Failed to get user")
+		return domain.User{}, "", time.Time{}, fmt.Errorf("login failed: %w", err)
+	}
+
+	// Locked accounts are rejected before the password is checked, so
+	// guessing can't continue during the lockout
+	if s.isLocked(user) {
+		authLoginAttempts.WithLabelValues("failure").Inc()
+		s.recordAudit(ctx, domain.AuditEvent{
+			Type:    domain.AuditLoginFailure,
+			UserID:  &user.ID,
+			Details: map[string]string{"reason": "locked"},
+		})
+		s.recordAddressFailure(ctx, identifier)
+		return domain.User{}, "", time.Time{}, &domain.LoginThrottleError{Err: domain.ErrLocked, LockedUntil: user.LockedUntil}
 	}
 
 	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
-		s.logger.Warn().
-			Str("email", email).
+	if err := s.hasher.Compare(hash, password); err != nil {
+		logger.Warn().
+			Int32("user_id", user.ID).
 			Msg("Invalid password attempt")
-		return "", time.Time{}, domain.ErrInvalidCredentials
+		authLoginAttempts.WithLabelValues("failure").Inc()
+		s.recordAudit(ctx, domain.AuditEvent{
+			Type:    domain.AuditLoginFailure,
+			UserID:  &user.ID,
+			Details: map[string]string{"reason": "invalid_password"},
+		})
+		s.recordAddressFailure(ctx, identifier)
+		return domain.User{}, "", time.Time{}, s.recordFailedLogin(ctx, user.ID)
+	}
+
+	s.resetFailedLogins(ctx, user)
+
+	// The plaintext is only available now, so upgrade old hashes here
+	s.rehashIfNeeded(ctx, user.ID, hash, password)
+
+	// An expired password is treated like a temporary one: the login
+	// succeeds but only allows changing it
+	if s.passwordExpired(user) {
+		logger.Info().Int32("user_id", user.ID).Msg("Password expired, change required")
+		user.MustChangePassword = true
 	}
 
-	// Generate JWT token
+	// Generate JWT token. Users who must change their password get a
+	// short-lived token that only allows the change, and a session that
+	// ends with it.
 	expiresAt := time.Now().Add(s.jwtExpiry)
-	token, err := s.generateToken(user, expiresAt)
+	sessionEnd := time.Now().Add(s.sessionLifetimeFor(rememberMe))
+	if user.MustChangePassword {
+		expiresAt = time.Now().Add(passwordChangeTokenExpiry)
+		sessionEnd, rememberMe = expiresAt, false
+	}
+	sessionID, err := s.startSession(ctx, user.ID, sessionEnd)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to generate token")
-		return "", time.Time{}, fmt.Errorf("token generation failed: %w", err)
+		logger.Error().Err(err).Msg("Failed to start session")
+		return domain.User{}, "", time.Time{}, fmt.Errorf("login failed: %w", err)
+	}
+	token, err := s.generateToken(ctx, user, sessionID, rememberMe, expiresAt)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to generate token")
+		return domain.User{}, "", time.Time{}, fmt.Errorf("token generation failed: %w", err)
 	}
 
 	// Send login alert email asynchronously (optional security feature)
@@ -156,98 +387,475 @@ func (s *authService) Login(ctx context.Context, email, password string) (string
 			location := "Unknown"
 
 			if err := s.emailService.SendLoginAlertEmail(emailCtx, user.Email, user.Username, ipAddress, location); err != nil {
-				s.logger.Warn().Err(err).Msg("Failed to send login alert email")
+				logger.Warn().Err(err).Msg("Failed to send login alert email")
 			}
 		}()
 	}
 
-	s.logger.Info().
+	authLoginAttempts.WithLabelValues("success").Inc()
+	s.recordAudit(ctx, domain.AuditEvent{
+		Type:    domain.AuditLoginSuccess,
+		UserID:  &user.ID,
+		Success: true,
+	})
+
+	logger.Info().
 		Int32("user_id", user.ID).
-		Str("email", email).
+		Str("email", user.Email).
 		Msg("User logged in successfully")
 
-	return token, expiresAt, nil
+	return user, token, expiresAt, nil
 }
 
 func (s *authService) ValidateToken(ctx context.Context, tokenString string) (*TokenClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.jwtSecret), nil
-	})
+	return s.validateToken(ctx, tokenString, false)
+}
+
+// validateToken checks a token as ValidateToken does. With allowExpired
+// an expired token is still accepted if it belongs to a session, which
+// then decides on its own whether the token is still good.
+func (s *authService) validateToken(ctx context.Context, tokenString string, allowExpired bool) (*TokenClaims, error) {
+	// The key set picks the verification key by kid and checks the
+	// signing method matches it. exp is required, nbf is checked whenever
+	// present, and iss and aud must match this deployment.
+	claims := &UserClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, s.keys.Keyfunc,
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithAudience(s.audience),
+	)
 	if err != nil {
-		return nil, domain.ErrInvalidToken
+		// Claims are only checked once the signature is verified, so a
+		// forged token is never reported as merely expired. An expired
+		// token meant for another deployment is still invalid.
+		if !errors.Is(err, jwt.ErrTokenExpired) ||
+			errors.Is(err, jwt.ErrTokenInvalidIssuer) ||
+			errors.Is(err, jwt.ErrTokenInvalidAudience) ||
+			errors.Is(err, jwt.ErrTokenNotValidYet) {
+			return nil, domain.ErrInvalidToken
+		}
+		if !allowExpired || claims.SessionID == "" {
+			return nil, domain.ErrExpiredToken
+		}
 	}
 
-	if !token.Valid {
+	// IDs start at 1, so a zero ID means the claim is missing
+	if claims.UserID == 0 {
 		return nil, domain.ErrInvalidToken
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, domain.ErrInvalidToken
+	// Tokens issued before multi-tenancy belong to the default tenant
+	tenantID := claims.TenantID
+	if tenantID == "" {
+		tenantID = tenant.Default
 	}
 
-	// Extract claims
-	userID, ok := claims["user_id"].(float64)
-	if !ok {
+	// Tokens issued before scopes existed carry full access
+	scope := claims.Scope
+	if scope == "" {
+		scope = ScopeFull
+	}
+
+	// Tokens issued before permissions existed get their role's current
+	// permissions
+	permissions := claims.Permissions
+	if permissions == nil {
+		permissions = s.permissions.ForTenant(tenantID, claims.Role)
+	}
+
+	// Reject tokens presented by a different client than they were issued to
+	if !bindingMatches(s.tokenBindingMode, claims.Binding, audit.ClientFromContext(ctx)) {
+		s.logger.Warn().Int32("user_id", claims.UserID).Msg("Token binding mismatch")
 		return nil, domain.ErrInvalidToken
 	}
 
-	role, _ := claims["role"].(string)
-	email, _ := claims["email"].(string)
+	// Reject tokens revoked by a password change or deactivation. Tokens
+	// issued before versions existed count as version 0.
+	if err := s.checkTokenVersion(tenant.WithID(ctx, tenantID), claims.UserID, claims.Version); err != nil {
+		return nil, err
+	}
 
-	// Check expiration
-	if exp, ok := claims["exp"].(float64); ok {
-		if time.Now().Unix() > int64(exp) {
-			return nil, domain.ErrExpiredToken
+	// Reject tokens of revoked sessions
+	if claims.SessionID != "" {
+		if err := s.checkSession(ctx, claims.UserID, claims.SessionID); err != nil {
+			return nil, err
 		}
 	}
 
 	return &TokenClaims{
-		UserID: int32(userID),
-		Role:   role,
-		Email:  email,
+		UserID:   claims.UserID,
+		TenantID: tenantID,
+		Role:     claims.Role,
+		Email:    claims.Email,
+		Scope:    scope,
+
+		Permissions: permissions,
+		SessionID:   claims.SessionID,
+		RememberMe:  claims.RememberMe,
 	}, nil
 }
 
-func (s *authService) RefreshToken(ctx context.Context, tokenString string) (string, time.Time, error) {
-	// Validate existing token
-	claims, err := s.ValidateToken(ctx, tokenString)
-	if err != nil && !errors.Is(err, domain.ErrExpiredToken) {
-		return "", time.Time{}, err
+func (s *authService) RefreshToken(ctx context.Context, tokenString string) (domain.User, string, time.Time, bool, error) {
+	// An expired token can still be refreshed while its session is
+	// active, so the session rather than the token decides how long the
+	// user stays signed in
+	claims, err := s.validateToken(ctx, tokenString, true)
+	if err != nil {
+		return domain.User{}, "", time.Time{}, false, err
+	}
+
+	// A restricted token must not be exchanged for a full one
+	if claims.Scope != ScopeFull {
+		return domain.User{}, "", time.Time{}, false, domain.ErrForbidden
 	}
 
-	// Get user to ensure they still exist
-	user, err := s.repo.GetUserByID(ctx, claims.UserID)
+	// Refresh isn't behind the auth middleware, so the token is bound to
+	// its tenant here. A request explicitly naming another is refused.
+	if requested, ok := tenant.FromContext(ctx); ok && requested != claims.TenantID {
+		return domain.User{}, "", time.Time{}, false, domain.ErrForbidden
+	}
+
+	// Get user to ensure they still exist. The token, not the request,
+	// decides which tenant the user is looked up in.
+	user, err := s.repo.GetUserByID(tenant.WithID(ctx, claims.TenantID), claims.UserID)
 	if err != nil {
-		return "", time.Time{}, err
+		return domain.User{}, "", time.Time{}, false, err
 	}
 
-	// Generate new token
+	// Generate new token for the same session, which slides on by the
+	// lifetime chosen at login. The token expires when the session does
+	// if that comes first, since the session check would reject it from
+	// then on anyway.
 	expiresAt := time.Now().Add(s.jwtExpiry)
-	newToken, err := s.generateToken(user, expiresAt)
+	if claims.SessionID != "" && s.sessions != nil {
+		extendTo := time.Now().Add(s.sessionLifetimeFor(claims.RememberMe))
+		sessionEnd, err := s.sessions.TouchSession(ctx, claims.UserID, claims.SessionID, extendTo)
+		if err != nil {
+			if errors.Is(err, domain.ErrSessionNotFound) {
+				return domain.User{}, "", time.Time{}, false, domain.ErrInvalidToken
+			}
+			return domain.User{}, "", time.Time{}, false, fmt.Errorf("refresh session: %w", err)
+		}
+		if sessionEnd.Before(expiresAt) {
+			expiresAt = sessionEnd
+		}
+	}
+	newToken, err := s.generateToken(ctx, user, claims.SessionID, claims.RememberMe, expiresAt)
+	if err != nil {
+		return domain.User{}, "", time.Time{}, false, err
+	}
+
+	return user, newToken, expiresAt, claims.RememberMe, nil
+}
+
+func (s *authService) CreateUser(ctx context.Context, username, email, temporaryPassword, role string) (domain.User, error) {
+	username = validator.NormalizeUsername(username)
+	email = validator.NormalizeEmail(email)
+
+	if temporaryPassword == "" {
+		return domain.User{}, domain.ErrValidation
+	}
+
+	if s.isPwned(ctx, temporaryPassword) {
+		return domain.User{}, domain.NewValidationError(map[string]string{
+			"password": "has appeared in a data breach; choose a different password",
+		})
+	}
+
+	if role == "" {
+		role = "user"
+	}
+
+	hash, err := s.hasher.Hash(temporaryPassword)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to hash password")
+		return domain.User{}, fmt.Errorf("password hashing failed: %w", err)
+	}
+
+	created, err := s.repo.CreateUser(ctx, domain.User{
+		Username:           username,
+		Email:              email,
+		Role:               role,
+		MustChangePassword: true,
+	}, hash)
 	if err != nil {
-		return "", time.Time{}, err
+		if errors.Is(err, domain.ErrDuplicateEmail) || errors.Is(err, domain.ErrDuplicateUsername) {
+			return domain.User{}, err
+		}
+		s.logger.Error().Err(err).Msg("Failed to create user")
+		return domain.User{}, fmt.Errorf("user creation failed: %w", err)
+	}
+	s.invalidateUserCache(ctx, created.ID)
+
+	s.recordAudit(ctx, domain.AuditEvent{
+		Type:    domain.AuditUserCreated,
+		UserID:  &created.ID,
+		Success: true,
+	})
+
+	s.logger.Info().
+		Int32("user_id", created.ID).
+		Str("role", created.Role).
+		Msg("User created with temporary password")
+
+	return created, nil
+}
+
+func (s *authService) ChangePassword(ctx context.Context, userID int32, currentPassword, newPassword string) error {
+	hash, err := s.repo.GetPasswordHash(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.hasher.Compare(hash, currentPassword); err != nil {
+		s.recordAudit(ctx, domain.AuditEvent{
+			Type:    domain.AuditPasswordChange,
+			UserID:  &userID,
+			Details: map[string]string{"reason": "invalid_password"},
+		})
+		return domain.ErrInvalidCredentials
+	}
+
+	if newPassword == currentPassword {
+		return domain.NewValidationError(map[string]string{
+			"new_password": "must differ from the current password",
+		})
+	}
+
+	if s.isPwned(ctx, newPassword) {
+		return domain.NewValidationError(map[string]string{
+			"new_password": "has appeared in a data breach; choose a different password",
+		})
+	}
+
+	if err := s.checkPasswordHistory(ctx, userID, newPassword); err != nil {
+		return err
+	}
+
+	newHash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to hash password")
+		return fmt.Errorf("password hashing failed: %w", err)
+	}
+
+	if err := s.replacePassword(ctx, userID, hash, newHash); err != nil {
+		s.logger.Error().Err(err).Int32("user_id", userID).Msg("Failed to change password")
+		return fmt.Errorf("password change failed: %w", err)
+	}
+
+	// The cached profile still carries the old must-change flag, and the
+	// cached token version would let revoked tokens through
+	s.invalidateUserCache(ctx, userID)
+	s.invalidateTokenVersion(ctx, userID)
+	s.endSessions(ctx, userID)
+
+	s.recordAudit(ctx, domain.AuditEvent{
+		Type:    domain.AuditPasswordChange,
+		UserID:  &userID,
+		Success: true,
+	})
+	s.publishEvent(ctx, domain.EventUserPasswordChanged, map[string]interface{}{
+		"user_id":   userID,
+		"tenant_id": tenant.ID(ctx),
+		"timestamp": time.Now().UTC(),
+	})
+
+	s.logger.Info().Int32("user_id", userID).Msg("Password changed")
+	return nil
+}
+
+func (s *authService) UpdateUserRole(ctx context.Context, adminID, userID int32, role string) error {
+	previous, err := s.repo.UpdateUserRole(ctx, userID, role)
+	if err != nil {
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			s.logger.Error().Err(err).Int32("user_id", userID).Msg("Failed to update role")
+		}
+		return fmt.Errorf("update role: %w", err)
+	}
+
+	// Tokens and sessions carry the old role and its permissions; the user
+	// logs in again to get the new ones
+	s.invalidateUserCache(ctx, userID)
+	s.invalidateTokenVersion(ctx, userID)
+	s.endSessions(ctx, userID)
+
+	s.recordAudit(ctx, domain.AuditEvent{
+		Type:    domain.AuditRoleChanged,
+		UserID:  &userID,
+		Success: true,
+		Details: map[string]string{
+			"role":          role,
+			"previous_role": previous,
+			"admin_id":      fmt.Sprint(adminID),
+		},
+	})
+
+	s.logger.Info().
+		Int32("user_id", userID).
+		Int32("admin_id", adminID).
+		Str("role", role).
+		Str("previous_role", previous).
+		Msg("User role changed")
+	return nil
+}
+
+func (s *authService) DeactivateUser(ctx context.Context, userID int32) error {
+	if err := s.repo.DeactivateUser(ctx, userID); err != nil {
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			s.logger.Error().Err(err).Int32("user_id", userID).Msg("Failed to deactivate user")
+		}
+		return fmt.Errorf("deactivate user: %w", err)
 	}
 
-	return newToken, expiresAt, nil
+	s.invalidateUserCache(ctx, userID)
+	s.invalidateTokenVersion(ctx, userID)
+	s.endSessions(ctx, userID)
+
+	s.recordAudit(ctx, domain.AuditEvent{
+		Type:    domain.AuditUserDeactivated,
+		UserID:  &userID,
+		Success: true,
+	})
+
+	s.logger.Info().Int32("user_id", userID).Msg("User deactivated")
+	return nil
+}
+
+// notifyRegistrationAttempt tells the owner of an existing account that
+// someone tried to register with their email
+func (s *authService) notifyRegistrationAttempt(to string) {
+	s.logger.Info().Msg("Registration attempted with existing email")
+
+	if s.emailService == nil || !s.emailService.IsAvailable() {
+		return
+	}
+
+	go func() {
+		emailCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := s.emailService.SendRegistrationAttemptEmail(emailCtx, to); err != nil {
+			s.logger.Error().Err(err).Msg("Failed to send registration attempt email")
+		}
+	}()
+}
+
+// invalidateUserCache drops the cached profile of a user. New users need
+// it too: a lookup of their ID before they existed may have cached a "not
+// found" tombstone.
+func (s *authService) invalidateUserCache(ctx context.Context, userID int32) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Delete(ctx, userCacheKey(ctx, userID)); err != nil {
+		s.logger.Warn().Err(err).Msg("Failed to invalidate cache")
+	}
+}
+
+// rehashIfNeeded re-hashes a verified password if its stored hash uses a
+// different algorithm or lower cost than configured. Failures are logged;
+// login still succeeds.
+func (s *authService) rehashIfNeeded(ctx context.Context, userID int32, hash, password string) {
+	if !s.hasher.NeedsRehash(hash) {
+		return
+	}
+
+	newHash, err := s.hasher.Hash(password)
+	if err != nil {
+		s.logger.Warn().Err(err).Int32("user_id", userID).Msg("Failed to rehash password")
+		return
+	}
+
+	if err := s.repo.UpdatePassword(ctx, userID, newHash); err != nil {
+		s.logger.Warn().Err(err).Int32("user_id", userID).Msg("Failed to store rehashed password")
+		return
+	}
+
+	s.logger.Info().
+		Int32("user_id", userID).
+		Str("old_algorithm", hashing.Detect(hash)).
+		Str("new_algorithm", hashing.Detect(newHash)).
+		Msg("Upgraded password hash")
+}
+
+// isPwned checks password against known breaches. It fails open: if the
+// check can't be performed the password is allowed and the error logged.
+func (s *authService) isPwned(ctx context.Context, password string) bool {
+	if s.pwnedChecker == nil {
+		return false
+	}
+
+	pwned, err := s.pwnedChecker.IsPwned(ctx, password)
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("Breached password check failed, allowing password")
+		return false
+	}
+
+	return pwned
+}
+
+// publishEvent publishes a user event to NATS, if it's available, and
+// queues it for the tenant's webhooks. Neither fails the operation that
+// raised the event.
+func (s *authService) publishEvent(ctx context.Context, subject string, event map[string]interface{}) {
+	if s.broker != nil && s.broker.IsAvailable() {
+		if err := s.broker.PublishJSON(subject, event); err != nil {
+			s.loggerFromCtx(ctx).Error().Err(err).Str("subject", subject).Msg("Failed to publish event")
+		}
+	}
+	if s.webhooks != nil {
+		s.webhooks.Notify(ctx, subject, event)
+	}
+}
+
+// recordAudit records an audit event without failing the calling operation
+func (s *authService) recordAudit(ctx context.Context, event domain.AuditEvent) {
+	recordAuditEvent(ctx, s.auditSink, s.logger, event)
+}
+
+// recordAuditEvent fills in the client details and records an event,
+// logging rather than returning failures
+func recordAuditEvent(ctx context.Context, sink audit.Sink, logger *zerolog.Logger, event domain.AuditEvent) {
+	if sink == nil {
+		return
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	client := audit.ClientFromContext(ctx)
+	if event.IPAddress == "" {
+		event.IPAddress = client.IPAddress
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = client.UserAgent
+	}
+
+	if err := sink.Record(ctx, event); err != nil {
+		logger.Warn().Err(err).Str("audit_event", event.Type).Msg("Failed to record audit event")
+	}
 }
 
-// generateToken creates a JWT token for a user
-func (s *authService) generateToken(user domain.User, expiresAt time.Time) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"role":    user.Role,
-		"exp":     expiresAt.Unix(),
-		"iat":     time.Now().Unix(),
+// generateToken creates a JWT token for a user, belonging to sessionID
+// if set
+func (s *authService) generateToken(ctx context.Context, user domain.User, sessionID string, rememberMe bool, expiresAt time.Time) (string, error) {
+	claims := newUserClaims(s.issuer, s.audience, expiresAt)
+	claims.UserID = user.ID
+	claims.TenantID = user.TenantID
+	claims.Email = user.Email
+	claims.Role = user.Role
+	claims.Scope = ScopeFull
+	if user.MustChangePassword {
+		claims.Scope = ScopePasswordChange
 	}
+	claims.Permissions = s.permissions.ForTenant(user.TenantID, user.Role)
+	claims.Binding = tokenBinding(s.tokenBindingMode, audit.ClientFromContext(ctx))
+	claims.Version = user.TokenVersion
+	claims.SessionID = sessionID
+	claims.RememberMe = rememberMe
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(s.jwtSecret))
+	signedToken, err := s.keys.Sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
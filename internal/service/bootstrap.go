@@ -0,0 +1,73 @@
+// Package service implements bootstrapping the first admin
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/validator"
+)
+
+// BootstrapAdmin creates the first admin. See AuthService for the
+// semantics.
+func (s *authService) BootstrapAdmin(ctx context.Context, username, email, password string, onlyIfEmpty bool) (domain.User, string, error) {
+	username = validator.NormalizeUsername(username)
+	email = validator.NormalizeEmail(email)
+
+	if onlyIfEmpty {
+		stats, err := s.repo.GetCollectionStats(ctx)
+		if err != nil {
+			return domain.User{}, "", fmt.Errorf("count users: %w", err)
+		}
+		if stats.Total > 0 {
+			return domain.User{}, "", nil
+		}
+	} else {
+		_, _, err := s.repo.GetUserByEmail(ctx, email)
+		if err == nil {
+			return domain.User{}, "", nil
+		}
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			return domain.User{}, "", fmt.Errorf("look up bootstrap admin: %w", err)
+		}
+	}
+
+	var generated string
+	if password == "" {
+		var err error
+		if password, err = generateTemporaryPassword(); err != nil {
+			return domain.User{}, "", fmt.Errorf("generate bootstrap admin password: %w", err)
+		}
+		generated = password
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return domain.User{}, "", fmt.Errorf("hash bootstrap admin password: %w", err)
+	}
+
+	created, err := s.repo.CreateUser(ctx, domain.User{
+		Username:           username,
+		Email:              email,
+		Role:               domain.RoleAdmin,
+		MustChangePassword: true,
+	}, hash)
+	if errors.Is(err, domain.ErrDuplicateEmail) {
+		// Another instance starting at the same time created it
+		return domain.User{}, "", nil
+	}
+	if err != nil {
+		return domain.User{}, "", fmt.Errorf("create bootstrap admin: %w", err)
+	}
+
+	s.recordAudit(ctx, domain.AuditEvent{
+		Type:    domain.AuditUserCreated,
+		UserID:  &created.ID,
+		Success: true,
+		Details: map[string]string{"source": "bootstrap"},
+	})
+
+	return created, generated, nil
+}
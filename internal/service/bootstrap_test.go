@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"user-auth-app/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// countingUserRepo holds at most one user, like fakeUserRepo, and counts it
+type countingUserRepo struct {
+	*fakeUserRepo
+}
+
+func (r countingUserRepo) GetCollectionStats(ctx context.Context) (domain.UserCollectionStats, error) {
+	if r.user.Email == "" {
+		return domain.UserCollectionStats{}, nil
+	}
+	return domain.UserCollectionStats{Total: 1}, nil
+}
+
+func TestBootstrapAdminOnEmptyDatabase(t *testing.T) {
+	repo := countingUserRepo{&fakeUserRepo{}}
+	s := newLoginTestService(repo, bcrypt.MinCost)
+	ctx := context.Background()
+
+	admin, password, err := s.BootstrapAdmin(ctx, "admin", "Admin@Example.com", "", true)
+	require.NoError(t, err)
+	assert.NotZero(t, admin.ID)
+	assert.Equal(t, domain.RoleAdmin, admin.Role)
+	assert.Equal(t, "admin@example.com", admin.Email)
+	assert.True(t, admin.MustChangePassword)
+	require.NotEmpty(t, password, "a password is generated")
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(repo.hash), []byte(password)))
+
+	// Restarting doesn't create another admin or reveal a new password
+	again, password, err := s.BootstrapAdmin(ctx, "admin", "admin@example.com", "", true)
+	require.NoError(t, err)
+	assert.Zero(t, again.ID)
+	assert.Empty(t, password)
+}
+
+func TestBootstrapAdminSkipsExistingUsers(t *testing.T) {
+	repo := countingUserRepo{&fakeUserRepo{user: domain.User{ID: 1, Email: "user@example.com", Role: domain.RoleUser}}}
+	s := newLoginTestService(repo, bcrypt.MinCost)
+
+	admin, _, err := s.BootstrapAdmin(context.Background(), "admin", "admin@localhost", "", true)
+	require.NoError(t, err)
+	assert.Zero(t, admin.ID)
+	assert.Equal(t, "user@example.com", repo.user.Email)
+}
+
+func TestBootstrapAdminWithConfiguredEmail(t *testing.T) {
+	repo := countingUserRepo{&fakeUserRepo{}}
+	s := newLoginTestService(repo, bcrypt.MinCost)
+	ctx := context.Background()
+
+	admin, generated, err := s.BootstrapAdmin(ctx, "root", "root@example.com", "Configured-Pass-1", false)
+	require.NoError(t, err)
+	assert.NotZero(t, admin.ID)
+	assert.Empty(t, generated, "configured passwords aren't echoed")
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(repo.hash), []byte("Configured-Pass-1")))
+
+	admin, _, err = s.BootstrapAdmin(ctx, "root", "root@example.com", "Configured-Pass-1", false)
+	require.NoError(t, err)
+	assert.Zero(t, admin.ID, "an existing admin is left alone")
+}
@@ -0,0 +1,75 @@
+// Package service implements dead letter inspection and replay
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/messaging"
+	"user-auth-app/internal/repository"
+
+	"github.com/rs/zerolog"
+)
+
+type deadLetterService struct {
+	repo      repository.DeadLetterRepository
+	broker    messaging.Broker
+	auditSink audit.Sink
+	logger    *zerolog.Logger
+}
+
+// NewDeadLetterService creates a new dead letter service. Replays are
+// published through broker.
+func NewDeadLetterService(repo repository.DeadLetterRepository, broker messaging.Broker, auditSink audit.Sink, logger *zerolog.Logger) DeadLetterService {
+	return &deadLetterService{
+		repo:      repo,
+		broker:    broker,
+		auditSink: auditSink,
+		logger:    logger,
+	}
+}
+
+func (s *deadLetterService) ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	deadLetters, err := s.repo.ListDeadLetters(ctx, limit)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to list dead letters")
+		return nil, err
+	}
+	return deadLetters, nil
+}
+
+func (s *deadLetterService) ReplayDeadLetter(ctx context.Context, id int32) error {
+	if s.broker == nil || !s.broker.IsAvailable() {
+		return domain.ErrUnavailable
+	}
+
+	dl, err := s.repo.GetDeadLetter(ctx, id)
+	if err != nil {
+		if !errors.Is(err, domain.ErrDeadLetterNotFound) {
+			s.logger.Error().Err(err).Int32("dead_letter_id", id).Msg("Failed to get dead letter")
+		}
+		return err
+	}
+
+	// Publish first, so a failed replay leaves the dead letter in place.
+	// Another admin replaying it at the same time can deliver it twice.
+	if err := s.broker.Publish(dl.Subject, dl.Data); err != nil {
+		return fmt.Errorf("replay dead letter: %w", err)
+	}
+	if err := s.repo.DeleteDeadLetter(ctx, id); err != nil && !errors.Is(err, domain.ErrDeadLetterNotFound) {
+		s.logger.Error().Err(err).Int32("dead_letter_id", id).Msg("Dead letter replayed but not deleted")
+		return err
+	}
+
+	recordAuditEvent(ctx, s.auditSink, s.logger, domain.AuditEvent{
+		Type:    domain.AuditDeadLetterReplayed,
+		Success: true,
+		Details: map[string]string{"dead_letter_id": fmt.Sprint(id), "subject": dl.Subject},
+	})
+
+	s.logger.Info().Int32("dead_letter_id", id).Str("subject", dl.Subject).Msg("Dead letter replayed")
+	return nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/messaging"
+	"user-auth-app/internal/repository"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memDeadLetterRepo keeps dead letters by ID
+type memDeadLetterRepo struct {
+	repository.DeadLetterRepository
+	deadLetters map[int32]domain.DeadLetter
+}
+
+func (r *memDeadLetterRepo) GetDeadLetter(ctx context.Context, id int32) (domain.DeadLetter, error) {
+	dl, ok := r.deadLetters[id]
+	if !ok {
+		return domain.DeadLetter{}, domain.ErrDeadLetterNotFound
+	}
+	return dl, nil
+}
+
+func (r *memDeadLetterRepo) DeleteDeadLetter(ctx context.Context, id int32) error {
+	if _, ok := r.deadLetters[id]; !ok {
+		return domain.ErrDeadLetterNotFound
+	}
+	delete(r.deadLetters, id)
+	return nil
+}
+
+// replayBroker records raw publishes, failing them with err
+type replayBroker struct {
+	messaging.Broker
+	available bool
+	err       error
+	published map[string][]byte
+}
+
+func (b *replayBroker) IsAvailable() bool { return b.available }
+
+func (b *replayBroker) Publish(subject string, data []byte) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.published[subject] = data
+	return nil
+}
+
+func newDeadLetterTestService(broker *replayBroker) (*deadLetterService, *memDeadLetterRepo, *audit.MemorySink) {
+	logger := zerolog.Nop()
+	repo := &memDeadLetterRepo{deadLetters: map[int32]domain.DeadLetter{
+		7: {ID: 7, Subject: domain.EventUserVerify, Data: []byte(`{"user_id":1}`), Attempts: 3, Error: "smtp unavailable"},
+	}}
+	sink := audit.NewMemorySink()
+	s := NewDeadLetterService(repo, broker, sink, &logger).(*deadLetterService)
+	return s, repo, sink
+}
+
+func TestReplayDeadLetter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("republishes and removes", func(t *testing.T) {
+		broker := &replayBroker{available: true, published: map[string][]byte{}}
+		s, repo, sink := newDeadLetterTestService(broker)
+
+		require.NoError(t, s.ReplayDeadLetter(ctx, 7))
+		assert.Equal(t, []byte(`{"user_id":1}`), broker.published[domain.EventUserVerify])
+		assert.Empty(t, repo.deadLetters)
+
+		events := sink.Events()
+		require.Len(t, events, 1)
+		assert.Equal(t, domain.AuditDeadLetterReplayed, events[0].Type)
+		assert.Equal(t, map[string]string{"dead_letter_id": "7", "subject": domain.EventUserVerify}, events[0].Details)
+	})
+
+	t.Run("failed publish keeps the dead letter", func(t *testing.T) {
+		broker := &replayBroker{available: true, err: errors.New("nats: connection closed")}
+		s, repo, _ := newDeadLetterTestService(broker)
+
+		assert.Error(t, s.ReplayDeadLetter(ctx, 7))
+		assert.Contains(t, repo.deadLetters, int32(7))
+	})
+
+	t.Run("broker unavailable", func(t *testing.T) {
+		s, repo, _ := newDeadLetterTestService(&replayBroker{})
+
+		assert.ErrorIs(t, s.ReplayDeadLetter(ctx, 7), domain.ErrUnavailable)
+		assert.Contains(t, repo.deadLetters, int32(7))
+	})
+
+	t.Run("unknown dead letter", func(t *testing.T) {
+		s, _, _ := newDeadLetterTestService(&replayBroker{available: true, published: map[string][]byte{}})
+
+		assert.ErrorIs(t, s.ReplayDeadLetter(ctx, 8), domain.ErrDeadLetterNotFound)
+	})
+}
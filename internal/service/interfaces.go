@@ -10,24 +10,192 @@ import (
 
 // AuthService handles authentication operations
 type AuthService interface {
-	Register(ctx context.Context, username, email, password, role string) (domain.User, error)
-	Login(ctx context.Context, email, password string) (string, time.Time, error)
+	// Register creates a user. In enumeration-safe mode a duplicate email
+	// returns a zero User and nil error so callers respond identically.
+	// While registration is invite-only, inviteCode must name a valid
+	// invite, which is used up in the transaction creating the user;
+	// otherwise it is ignored.
+	Register(ctx context.Context, username, email, password, role, inviteCode string) (domain.User, error)
+	// Login authenticates by email or username; identifier is treated as
+	// an email if it parses as one. It returns the user along with the
+	// token so callers needn't look them up again. With rememberMe the
+	// session can be refreshed for longer; the token's lifetime is the
+	// same either way.
+	Login(ctx context.Context, identifier, password string, rememberMe bool) (domain.User, string, time.Time, error)
+	// LoginWithProvider signs in with an identity from a social login
+	// provider, creating or linking a local user as needed
+	LoginWithProvider(ctx context.Context, identity domain.ExternalIdentity) (domain.User, string, time.Time, error)
 	ValidateToken(ctx context.Context, token string) (*TokenClaims, error)
-	RefreshToken(ctx context.Context, token string) (string, time.Time, error)
+	// RefreshToken exchanges a token for a new one in the same session.
+	// It returns the user along with the token, and whether the session
+	// was started with remember me.
+	RefreshToken(ctx context.Context, token string) (domain.User, string, time.Time, bool, error)
+	// CreateUser provisions a user with a temporary password that must be
+	// changed on first login
+	CreateUser(ctx context.Context, username, email, temporaryPassword, role string) (domain.User, error)
+	// ImportUsers creates users in bulk, returning one result per user in
+	// order. Users without a password hash are created without a usable
+	// password, and domain.EventUserPasswordSetup is published so they
+	// can choose one. In atomic mode the users are created in one
+	// transaction and any failure rolls back the whole batch, leaving the
+	// others marked domain.ErrNotImported; otherwise each user succeeds or
+	// fails on its own. The error is only set when the import as a whole
+	// failed.
+	ImportUsers(ctx context.Context, users []domain.UserImport, atomic bool) ([]domain.UserImportResult, error)
+	// BootstrapAdmin creates an admin, so a fresh deployment can use the
+	// admin endpoints. With onlyIfEmpty it does nothing once any user
+	// exists; otherwise it does nothing if email is already registered,
+	// so running it on every startup is safe. Without a password one is
+	// generated and returned; either way it must be changed on first
+	// login. The user is zero when nothing was created.
+	BootstrapAdmin(ctx context.Context, username, email, password string, onlyIfEmpty bool) (domain.User, string, error)
+	// ChangePassword replaces the user's password after verifying the
+	// current one, clearing any forced password change. It revokes every
+	// token the user was issued.
+	ChangePassword(ctx context.Context, userID int32, currentPassword, newPassword string) error
+	// UpdateUserRole gives an active user a new role on behalf of adminID.
+	// It revokes every token the user was issued, so no token carries the
+	// old role.
+	UpdateUserRole(ctx context.Context, adminID, userID int32, role string) error
+	// CreateInvite creates a single-use invite to register, returning it
+	// along with its code, which is not stored and can't be shown again
+	CreateInvite(ctx context.Context, createdBy int32) (domain.Invite, string, error)
+	// DeactivateUser deactivates a user and revokes every token they were
+	// issued
+	DeactivateUser(ctx context.Context, userID int32) error
+	// DeactivateUsers deactivates users in bulk, in one transaction, on
+	// behalf of adminID. It returns one result per distinct ID, in the
+	// order given; IDs without an active user fail with
+	// domain.ErrUserNotFound without affecting the others. The error is
+	// only set when the transaction as a whole failed.
+	DeactivateUsers(ctx context.Context, adminID int32, userIDs []int32) ([]domain.UserDeactivationResult, error)
+	// ResendVerification publishes domain.EventUserVerify for the
+	// unverified account with email, at most once per cooldown per email.
+	// It returns nil whether or not such an account exists, so callers
+	// can't learn which emails are registered.
+	ResendVerification(ctx context.Context, email string) error
 }
 
 // UserService handles user operations
 type UserService interface {
-	GetProfile(ctx context.Context, userID int32) (domain.User, error)
+	// GetProfile returns the profile of userID as read by readerID. With
+	// profile read auditing on, reads of other users' profiles are audited.
+	GetProfile(ctx context.Context, readerID, userID int32) (domain.User, error)
 	GetUserByID(ctx context.Context, userID int32) (domain.User, error)
+	// GetUsersByIDs returns the users that exist among ids, in request
+	// order. Cached users are served from cache; the rest are fetched in
+	// one query.
+	GetUsersByIDs(ctx context.Context, ids []int32) ([]domain.User, error)
+	// WarmCache preloads the configured users into the cache, so their
+	// first profile reads after startup are served from it. It stops when
+	// ctx is done.
+	WarmCache(ctx context.Context) error
 	UpdateProfile(ctx context.Context, userID int32, updates map[string]interface{}) error
 	DeleteProfile(ctx context.Context, userID int32) error
 	ListUsers(ctx context.Context, limit, offset int) ([]domain.User, error)
+	// SearchUsers returns a page of active users whose username or email
+	// contains query, with the total number of matches
+	SearchUsers(ctx context.Context, query string, limit, offset int) ([]domain.User, int64, error)
+	GetCollectionStats(ctx context.Context) (domain.UserCollectionStats, error)
 }
 
+// AuditService handles audit trail queries
+type AuditService interface {
+	// ListEvents returns the most recent audit events, newest first. A nil
+	// userID returns events for all users.
+	ListEvents(ctx context.Context, userID *int32, limit int) ([]domain.AuditEvent, error)
+	// ExportEvents passes the events from from up to but excluding to to
+	// fn, oldest first, without loading them all into memory. It stops at
+	// the first error fn returns.
+	ExportEvents(ctx context.Context, from, to time.Time, fn func(domain.AuditEvent) error) error
+}
+
+// APIKeyService manages API keys and authenticates requests made with them
+type APIKeyService interface {
+	// GenerateAPIKey creates a key for the user. The returned key is the
+	// only copy; just its hash is stored.
+	GenerateAPIKey(ctx context.Context, userID int32, name string, scopes []string) (domain.APIKey, string, error)
+	ListAPIKeys(ctx context.Context, userID int32) ([]domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID int32) error
+	// AuthenticateAPIKey resolves a presented key to claims for its owner,
+	// recording when the key was last used
+	AuthenticateAPIKey(ctx context.Context, key string) (*TokenClaims, error)
+}
+
+// SessionService lets users see and revoke where they're signed in
+type SessionService interface {
+	ListSessions(ctx context.Context, userID int32) ([]domain.Session, error)
+	// RevokeSession ends one of the user's sessions, invalidating its
+	// tokens. Revoking currentSessionID, the session of the token making
+	// the request, is recorded as a logout.
+	RevokeSession(ctx context.Context, userID int32, sessionID, currentSessionID string) error
+}
+
+// WebhookService manages the webhooks of the tenant in the context
+type WebhookService interface {
+	// CreateWebhook subscribes an endpoint to events. The returned signing
+	// secret is only ever returned here.
+	CreateWebhook(ctx context.Context, url string, events []string) (domain.Webhook, string, error)
+	ListWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID int32) error
+	// ListDeliveries returns up to limit delivery attempts of a webhook,
+	// newest first
+	ListDeliveries(ctx context.Context, webhookID int32, limit int) ([]domain.WebhookDelivery, error)
+}
+
+// DeadLetterService lets admins inspect and replay messages their
+// subscriber failed to process. Dead letters belong to no tenant.
+type DeadLetterService interface {
+	// ListDeadLetters returns up to limit dead letters, newest first
+	ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error)
+	// ReplayDeadLetter republishes a dead letter to its subject and deletes
+	// it. It returns domain.ErrDeadLetterNotFound if there is no such dead
+	// letter and domain.ErrUnavailable while the broker is.
+	ReplayDeadLetter(ctx context.Context, id int32) error
+}
+
+// Token scopes
+const (
+	// ScopeFull grants access to every endpoint the user's role allows
+	ScopeFull = "full"
+	// ScopePasswordChange only allows changing the password
+	ScopePasswordChange = "password_change"
+	// ScopeAPIKey marks requests authenticated with an API key. Only
+	// routes that opt in accept it, further limited by the key's scopes.
+	ScopeAPIKey = "api_key"
+)
+
+// API key scopes, granted per key
+const (
+	// APIKeyScopeUsersRead allows reading user profiles
+	APIKeyScopeUsersRead = "users:read"
+	// APIKeyScopeAuditRead allows reading the audit log; the key's owner
+	// must also have the audit:read permission
+	APIKeyScopeAuditRead = "audit:read"
+)
+
+// APIKeyScopes lists the scopes an API key can be granted
+var APIKeyScopes = []string{APIKeyScopeUsersRead, APIKeyScopeAuditRead}
+
 // TokenClaims represents JWT token claims
 type TokenClaims struct {
-	UserID int32  `json:"user_id"`
-	Role   string `json:"role"`
-	Email  string `json:"email"`
+	UserID   int32  `json:"user_id"`
+	TenantID string `json:"tenant_id"`
+	Role     string `json:"role"`
+	Email    string `json:"email"`
+	Scope    string `json:"scope"`
+
+	// Permissions are those of the user's role when the token was issued
+	Permissions []string `json:"permissions,omitempty"`
+
+	// SessionID identifies the login the token belongs to. Tokens issued
+	// before sessions existed have none.
+	SessionID string `json:"session_id,omitempty"`
+	// RememberMe is set when the user asked to be remembered at login
+	RememberMe bool `json:"remember_me,omitempty"`
+
+	// APIKeyID and APIKeyScopes are set when the request was
+	// authenticated with an API key
+	APIKeyID     int32    `json:"api_key_id,omitempty"`
+	APIKeyScopes []string `json:"api_key_scopes,omitempty"`
 }
\ No newline at end of file
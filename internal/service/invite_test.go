@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// inviteRepo stores users and invites by code hash. WithTx works on a copy
+// and only keeps it if fn succeeds.
+type inviteRepo struct {
+	repository.UserRepository
+	users   map[string]domain.User
+	invites map[string]domain.Invite
+}
+
+func newInviteRepo() *inviteRepo {
+	return &inviteRepo{users: map[string]domain.User{}, invites: map[string]domain.Invite{}}
+}
+
+func (r *inviteRepo) CreateUser(ctx context.Context, user domain.User, hash string) (domain.User, error) {
+	if _, ok := r.users[user.Email]; ok {
+		return domain.User{}, domain.ErrDuplicateEmail
+	}
+	user.ID = int32(len(r.users) + 1)
+	r.users[user.Email] = user
+	return user, nil
+}
+
+func (r *inviteRepo) CreateInvite(ctx context.Context, invite domain.Invite, codeHash string) (domain.Invite, error) {
+	invite.ID = int32(len(r.invites) + 1)
+	invite.CreatedAt = time.Now()
+	r.invites[codeHash] = invite
+	return invite, nil
+}
+
+func (r *inviteRepo) LockInvite(ctx context.Context, codeHash string) error {
+	invite, ok := r.invites[codeHash]
+	switch {
+	case !ok:
+		return domain.ErrInviteInvalid
+	case invite.UsedAt != nil:
+		return domain.ErrInviteUsed
+	case !invite.ExpiresAt.After(time.Now()):
+		return domain.ErrInviteExpired
+	}
+	return nil
+}
+
+func (r *inviteRepo) ConsumeInvite(ctx context.Context, codeHash string, userID int32) error {
+	if err := r.LockInvite(ctx, codeHash); err != nil {
+		return err
+	}
+	invite := r.invites[codeHash]
+	now := time.Now()
+	invite.UsedAt, invite.UsedBy = &now, &userID
+	r.invites[codeHash] = invite
+	return nil
+}
+
+func (r *inviteRepo) WithTx(ctx context.Context, fn func(repository.UserRepository) error) error {
+	tx := &inviteRepo{users: maps.Clone(r.users), invites: maps.Clone(r.invites)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	r.users, r.invites = tx.users, tx.invites
+	return nil
+}
+
+func newInviteTestService(repo *inviteRepo) *authService {
+	s := newLoginTestService(repo, bcrypt.MinCost)
+	s.inviteOnly = true
+	s.inviteTTL = time.Hour
+	return s
+}
+
+func TestCreateInvite(t *testing.T) {
+	repo := newInviteRepo()
+	s := newInviteTestService(repo)
+	sink := audit.NewMemorySink()
+	s.auditSink = sink
+
+	invite, code, err := s.CreateInvite(context.Background(), 9)
+	require.NoError(t, err)
+
+	assert.Contains(t, code, invitePrefix)
+	assert.Equal(t, int32(9), *invite.CreatedBy)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), invite.ExpiresAt, time.Minute)
+	assert.Contains(t, repo.invites, hashInviteCode(code), "only the hash is stored")
+
+	events := sink.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, domain.AuditInviteCreated, events[0].Type)
+	assert.Equal(t, map[string]string{"invite_id": "1"}, events[0].Details)
+}
+
+func TestRegisterInviteOnly(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("invite required", func(t *testing.T) {
+		repo := newInviteRepo()
+		s := newInviteTestService(repo)
+
+		_, err := s.Register(ctx, "alice", "alice@example.com", "Correct-Horse-9", "", "")
+		assert.ErrorIs(t, err, domain.ErrInviteRequired)
+		assert.Empty(t, repo.users)
+	})
+
+	t.Run("invite is used up", func(t *testing.T) {
+		repo := newInviteRepo()
+		s := newInviteTestService(repo)
+		_, code, err := s.CreateInvite(ctx, 9)
+		require.NoError(t, err)
+
+		created, err := s.Register(ctx, "alice", "alice@example.com", "Correct-Horse-9", "", code)
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, *repo.invites[hashInviteCode(code)].UsedBy)
+
+		_, err = s.Register(ctx, "bob", "bob@example.com", "Correct-Horse-9", "", code)
+		assert.ErrorIs(t, err, domain.ErrInviteUsed)
+		assert.NotContains(t, repo.users, "bob@example.com", "the user should be rolled back")
+	})
+
+	t.Run("unusable invites", func(t *testing.T) {
+		repo := newInviteRepo()
+		s := newInviteTestService(repo)
+		s.inviteTTL = -time.Minute
+		_, expired, err := s.CreateInvite(ctx, 9)
+		require.NoError(t, err)
+
+		_, err = s.Register(ctx, "alice", "alice@example.com", "Correct-Horse-9", "", expired)
+		assert.ErrorIs(t, err, domain.ErrInviteExpired)
+		_, err = s.Register(ctx, "alice", "alice@example.com", "Correct-Horse-9", "", "inv_unknown")
+		assert.ErrorIs(t, err, domain.ErrInviteInvalid)
+		assert.Empty(t, repo.users)
+	})
+
+	t.Run("invite is checked before the email", func(t *testing.T) {
+		repo := newInviteRepo()
+		repo.users["alice@example.com"] = domain.User{ID: 1, Email: "alice@example.com"}
+		s := newInviteTestService(repo)
+
+		// A taken email must not be told apart from a free one without a
+		// valid invite
+		_, err := s.Register(ctx, "alice", "alice@example.com", "Correct-Horse-9", "", "inv_unknown")
+		assert.ErrorIs(t, err, domain.ErrInviteInvalid)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		assert.NotErrorIs(t, err, domain.ErrDuplicateEmail)
+	})
+
+	t.Run("open registration ignores invites", func(t *testing.T) {
+		repo := newInviteRepo()
+		s := newInviteTestService(repo)
+		s.inviteOnly = false
+
+		_, err := s.Register(ctx, "alice", "alice@example.com", "Correct-Horse-9", "", "")
+		require.NoError(t, err)
+		assert.Empty(t, repo.invites)
+	})
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// lockoutUserRepo counts failed logins like the IncrementFailedLogins query
+type lockoutUserRepo struct {
+	*fakeUserRepo
+	maxAttempts int32
+	duration    time.Duration
+}
+
+func (r *lockoutUserRepo) IncrementFailedLogins(ctx context.Context, userID int32) (int32, time.Time, error) {
+	r.user.FailedLogins++
+	if r.user.FailedLogins >= r.maxAttempts {
+		r.user.LockedUntil = time.Now().Add(r.duration)
+	}
+	return r.user.FailedLogins, r.user.LockedUntil, nil
+}
+
+func (r *lockoutUserRepo) ResetFailedLogins(ctx context.Context, userID int32) error {
+	r.user.FailedLogins, r.user.LockedUntil = 0, time.Time{}
+	return nil
+}
+
+func newLockoutTestService(t *testing.T, password string, maxAttempts int32) (*authService, *lockoutUserRepo) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repo := &lockoutUserRepo{
+		fakeUserRepo: &fakeUserRepo{
+			user: domain.User{ID: 1, Email: "user@example.com", Role: "user"},
+			hash: string(hash),
+		},
+		maxAttempts: maxAttempts,
+		duration:    15 * time.Minute,
+	}
+	s := newLoginTestService(repo, bcrypt.MinCost)
+	s.maxFailedLogins = maxAttempts
+	return s, repo
+}
+
+func TestLoginLocksAccountAfterFailedAttempts(t *testing.T) {
+	const password = "Correct-Horse-9"
+	s, repo := newLockoutTestService(t, password, 3)
+	ctx := context.Background()
+
+	for remaining := 2; remaining > 0; remaining-- {
+		_, _, _, err := s.Login(ctx, "user@example.com", "wrong", false)
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+
+		var throttle *domain.LoginThrottleError
+		require.True(t, errors.As(err, &throttle))
+		assert.Equal(t, remaining, throttle.AttemptsRemaining)
+		assert.True(t, throttle.LockedUntil.IsZero())
+	}
+
+	_, _, _, err := s.Login(ctx, "user@example.com", "wrong", false)
+	assert.ErrorIs(t, err, domain.ErrLocked)
+	var throttle *domain.LoginThrottleError
+	require.True(t, errors.As(err, &throttle))
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), throttle.LockedUntil, time.Minute)
+
+	// The right password doesn't help while the account is locked
+	_, _, _, err = s.Login(ctx, "user@example.com", password, false)
+	assert.ErrorIs(t, err, domain.ErrLocked)
+	assert.Equal(t, int32(3), repo.user.FailedLogins, "locked logins aren't counted")
+
+	// Once the lockout lifts, a successful login clears the counter
+	repo.user.LockedUntil = time.Now().Add(-time.Second)
+	_, _, _, err = s.Login(ctx, "user@example.com", password, false)
+	require.NoError(t, err)
+	assert.Zero(t, repo.user.FailedLogins)
+	assert.True(t, repo.user.LockedUntil.IsZero())
+}
+
+func TestLoginWithoutLockout(t *testing.T) {
+	const password = "Correct-Horse-9"
+	s, repo := newLockoutTestService(t, password, 0)
+	ctx := context.Background()
+
+	for range 5 {
+		_, _, _, err := s.Login(ctx, "user@example.com", "wrong", false)
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+		var throttle *domain.LoginThrottleError
+		assert.False(t, errors.As(err, &throttle))
+	}
+	assert.Zero(t, repo.user.FailedLogins, "failures aren't counted")
+
+	_, _, _, err := s.Login(ctx, "user@example.com", password, false)
+	assert.NoError(t, err)
+}
+
+func TestLoginUnknownUserHasNoThrottleDetails(t *testing.T) {
+	s, _ := newLockoutTestService(t, "Correct-Horse-9", 3)
+
+	_, _, _, err := s.Login(context.Background(), "nobody@example.com", "wrong", false)
+	assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	var throttle *domain.LoginThrottleError
+	assert.False(t, errors.As(err, &throttle))
+}
+
+func TestLoginBlocksAddressFailingAcrossAccounts(t *testing.T) {
+	const password = "Correct-Horse-9"
+	s, repo := newLockoutTestService(t, password, 10)
+	s.loginFailures = ratelimit.NewMemoryFailures(2, time.Minute)
+	attacker := audit.WithClient(context.Background(), audit.Client{IPAddress: "203.0.113.5"})
+
+	// Retrying one account counts once
+	for range 3 {
+		_, _, _, err := s.Login(attacker, "user@example.com", "wrong", false)
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	}
+	_, _, _, err := s.Login(attacker, "nobody@example.com", "wrong", false)
+	assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+
+	// The address is refused now, even with the right password, and the
+	// refusals don't count towards the account's lockout
+	_, _, _, err = s.Login(attacker, "user@example.com", password, false)
+	assert.ErrorIs(t, err, domain.ErrLoginBlocked)
+	var throttle *domain.LoginThrottleError
+	require.True(t, errors.As(err, &throttle))
+	assert.WithinDuration(t, time.Now().Add(time.Minute), throttle.LockedUntil, 5*time.Second)
+	assert.Equal(t, int32(3), repo.user.FailedLogins)
+
+	// Other addresses aren't affected
+	other := audit.WithClient(context.Background(), audit.Client{IPAddress: "198.51.100.7"})
+	_, _, _, err = s.Login(other, "user@example.com", password, false)
+	require.NoError(t, err)
+}
@@ -0,0 +1,26 @@
+// Package service resolves request-scoped loggers
+package service
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// contextLogger returns the request-scoped logger middleware stored in
+// ctx, which carries correlation fields such as request_id and user_id, or
+// fallback outside of a request
+func contextLogger(ctx context.Context, fallback *zerolog.Logger) *zerolog.Logger {
+	if logger := zerolog.Ctx(ctx); logger.GetLevel() != zerolog.Disabled {
+		return logger
+	}
+	return fallback
+}
+
+func (s *authService) loggerFromCtx(ctx context.Context) *zerolog.Logger {
+	return contextLogger(ctx, s.logger)
+}
+
+func (s *userService) loggerFromCtx(ctx context.Context) *zerolog.Logger {
+	return contextLogger(ctx, s.logger)
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextLogger(t *testing.T) {
+	var fallbackLogs, requestLogs bytes.Buffer
+	fallback := zerolog.New(&fallbackLogs)
+	requestLogger := zerolog.New(&requestLogs).With().Str("request_id", "req-1").Logger()
+
+	contextLogger(context.Background(), &fallback).Info().Msg("background")
+	contextLogger(requestLogger.WithContext(context.Background()), &fallback).Info().Msg("request")
+
+	assert.Contains(t, fallbackLogs.String(), "background")
+	assert.NotContains(t, fallbackLogs.String(), "request_id")
+	assert.Contains(t, requestLogs.String(), `"request_id":"req-1"`)
+	assert.Contains(t, requestLogs.String(), "request")
+}
@@ -0,0 +1,61 @@
+// Package service defines auth domain metrics
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	authLoginAttempts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_login_attempts_total",
+			Help: "Total number of login attempts by result",
+		},
+		[]string{"result"},
+	)
+
+	authRegistrations = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "auth_registrations_total",
+			Help: "Total number of successful user registrations",
+		},
+	)
+
+	authCacheHits = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "auth_cache_hits_total",
+			Help: "Total number of user cache hits",
+		},
+	)
+
+	authCacheMisses = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "auth_cache_misses_total",
+			Help: "Total number of user cache misses",
+		},
+	)
+
+	authCacheCoalesced = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "auth_cache_coalesced_total",
+			Help: "Total number of user cache misses served by another request's database fetch",
+		},
+	)
+
+	authVerificationResends = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_verification_resends_total",
+			Help: "Total number of verification email resend requests by result",
+		},
+		[]string{"result"},
+	)
+
+	authAPIKeyAuthentications = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_api_key_authentications_total",
+			Help: "Total number of API key authentications by result",
+		},
+		[]string{"result"},
+	)
+)
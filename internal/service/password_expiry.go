@@ -0,0 +1,17 @@
+// Package service implements password expiry
+package service
+
+import (
+	"time"
+
+	"user-auth-app/internal/domain"
+)
+
+// passwordExpired reports whether user's password is older than the
+// configured maximum age. Users without a recorded change date, such as
+// social-login accounts, never expire.
+func (s *authService) passwordExpired(user domain.User) bool {
+	return s.passwordMaxAge > 0 &&
+		!user.PasswordChangedAt.IsZero() &&
+		time.Since(user.PasswordChangedAt) > s.passwordMaxAge
+}
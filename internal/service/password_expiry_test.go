@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginWithExpiredPassword(t *testing.T) {
+	const password = "Correct-Horse-9"
+	const maxAge = 90 * 24 * time.Hour
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		maxAge         time.Duration
+		changedAt      time.Time
+		wantRestricted bool
+	}{
+		{"expired", maxAge, time.Now().Add(-maxAge - time.Hour), true},
+		{"recent", maxAge, time.Now().Add(-time.Hour), false},
+		{"expiry disabled", 0, time.Now().Add(-10 * maxAge), false},
+		{"no change date", maxAge, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeUserRepo{
+				user: domain.User{ID: 1, Email: "user@example.com", Role: "user", PasswordChangedAt: tt.changedAt},
+				hash: string(hash),
+			}
+			s := newLoginTestService(repo, bcrypt.MinCost)
+			s.passwordMaxAge = tt.maxAge
+			ctx := context.Background()
+
+			user, token, _, err := s.Login(ctx, "user@example.com", password, false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRestricted, user.MustChangePassword)
+
+			claims, err := s.ValidateToken(ctx, token)
+			require.NoError(t, err)
+			if tt.wantRestricted {
+				assert.Equal(t, ScopePasswordChange, claims.Scope)
+			} else {
+				assert.Equal(t, ScopeFull, claims.Scope)
+			}
+		})
+	}
+}
+
+func TestChangeExpiredPasswordRestoresFullAccess(t *testing.T) {
+	const password = "Correct-Horse-9"
+	const replacement = "Brand-New-Pass-2"
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repo := &expiryUserRepo{fakeUserRepo: &fakeUserRepo{
+		user: domain.User{ID: 1, Email: "user@example.com", Role: "user", PasswordChangedAt: time.Now().AddDate(-1, 0, 0)},
+		hash: string(hash),
+	}}
+	s := newLoginTestService(repo, bcrypt.MinCost)
+	s.passwordMaxAge = 90 * 24 * time.Hour
+	ctx := context.Background()
+
+	user, _, _, err := s.Login(ctx, "user@example.com", password, false)
+	require.NoError(t, err)
+	require.True(t, user.MustChangePassword)
+
+	require.NoError(t, s.ChangePassword(ctx, user.ID, password, replacement))
+
+	user, token, _, err := s.Login(ctx, "user@example.com", replacement, false)
+	require.NoError(t, err)
+	assert.False(t, user.MustChangePassword)
+	claims, err := s.ValidateToken(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, ScopeFull, claims.Scope)
+}
+
+// expiryUserRepo restarts the password's age on a change like the
+// ChangeUserPassword query
+type expiryUserRepo struct {
+	*fakeUserRepo
+}
+
+func (r *expiryUserRepo) ChangePassword(ctx context.Context, userID int32, passwordHash string) error {
+	r.user.PasswordChangedAt = time.Now()
+	return r.fakeUserRepo.ChangePassword(ctx, userID, passwordHash)
+}
@@ -0,0 +1,59 @@
+// Package service implements password history, rejecting reuse of recent
+// passwords
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+)
+
+// previousPasswords is how many previous password hashes are kept besides
+// the current one, or zero if history is disabled
+func (s *authService) previousPasswords() int {
+	return max(s.passwordHistory-1, 0)
+}
+
+// checkPasswordHistory rejects newPassword if it matches one of the user's
+// previous passwords still in the history. The current password is checked
+// separately by the caller.
+func (s *authService) checkPasswordHistory(ctx context.Context, userID int32, newPassword string) error {
+	keep := s.previousPasswords()
+	if keep == 0 {
+		return nil
+	}
+
+	hashes, err := s.repo.GetPasswordHistory(ctx, userID, keep)
+	if err != nil {
+		s.loggerFromCtx(ctx).Error().Err(err).Int32("user_id", userID).Msg("Failed to load password history")
+		return fmt.Errorf("password history lookup failed: %w", err)
+	}
+
+	for _, hash := range hashes {
+		if s.hasher.Compare(hash, newPassword) == nil {
+			return domain.NewValidationError(map[string]string{
+				"new_password": fmt.Sprintf("must not match any of your last %d passwords", s.passwordHistory),
+			})
+		}
+	}
+	return nil
+}
+
+// replacePassword changes the user's password to newHash. With history
+// enabled, oldHash is added to it in the same transaction, so the history
+// always holds exactly the passwords the user has moved away from.
+func (s *authService) replacePassword(ctx context.Context, userID int32, oldHash, newHash string) error {
+	keep := s.previousPasswords()
+	if keep == 0 || oldHash == "" {
+		return s.repo.ChangePassword(ctx, userID, newHash)
+	}
+
+	return s.repo.WithTx(ctx, func(repo repository.UserRepository) error {
+		if err := repo.AddPasswordHistory(ctx, userID, oldHash, keep); err != nil {
+			return err
+		}
+		return repo.ChangePassword(ctx, userID, newHash)
+	})
+}
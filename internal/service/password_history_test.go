@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// historyUserRepo keeps password history like the password_history
+// queries, most recent first
+type historyUserRepo struct {
+	*fakeUserRepo
+	history []string
+}
+
+func (r *historyUserRepo) GetPasswordHistory(ctx context.Context, userID int32, limit int) ([]string, error) {
+	return r.history[:min(limit, len(r.history))], nil
+}
+
+func (r *historyUserRepo) AddPasswordHistory(ctx context.Context, userID int32, passwordHash string, keep int) error {
+	r.history = append([]string{passwordHash}, r.history...)
+	r.history = r.history[:min(keep, len(r.history))]
+	return nil
+}
+
+func (r *historyUserRepo) WithTx(ctx context.Context, fn func(repository.UserRepository) error) error {
+	return fn(r)
+}
+
+func newHistoryTestService(t *testing.T, password string, history int) (*authService, *historyUserRepo) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repo := &historyUserRepo{
+		fakeUserRepo: &fakeUserRepo{
+			user: domain.User{ID: 1, Email: "user@example.com", Role: "user"},
+			hash: string(hash),
+		},
+	}
+	s := newLoginTestService(repo, bcrypt.MinCost)
+	s.passwordHistory = history
+	return s, repo
+}
+
+func TestChangePasswordRejectsRecentPassword(t *testing.T) {
+	s, repo := newHistoryTestService(t, "First-Pass-1", 3)
+	ctx := context.Background()
+
+	require.NoError(t, s.ChangePassword(ctx, 1, "First-Pass-1", "Second-Pass-2"))
+	require.NoError(t, s.ChangePassword(ctx, 1, "Second-Pass-2", "Third-Pass-3"))
+
+	for _, reused := range []string{"First-Pass-1", "Second-Pass-2"} {
+		err := s.ChangePassword(ctx, 1, "Third-Pass-3", reused)
+		var appErr *domain.AppError
+		require.ErrorAs(t, err, &appErr, reused)
+		assert.Contains(t, appErr.Fields, "new_password")
+	}
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(repo.hash), []byte("Third-Pass-3")))
+	assert.Len(t, repo.history, 2)
+}
+
+func TestChangePasswordAllowsPasswordOutsideHistory(t *testing.T) {
+	s, repo := newHistoryTestService(t, "First-Pass-1", 3)
+	ctx := context.Background()
+
+	require.NoError(t, s.ChangePassword(ctx, 1, "First-Pass-1", "Second-Pass-2"))
+	require.NoError(t, s.ChangePassword(ctx, 1, "Second-Pass-2", "Third-Pass-3"))
+	require.NoError(t, s.ChangePassword(ctx, 1, "Third-Pass-3", "Fourth-Pass-4"))
+
+	// The first password has been pruned from the history
+	assert.Len(t, repo.history, 2)
+	require.NoError(t, s.ChangePassword(ctx, 1, "Fourth-Pass-4", "First-Pass-1"))
+}
+
+func TestChangePasswordWithoutHistory(t *testing.T) {
+	s, repo := newHistoryTestService(t, "First-Pass-1", 0)
+	ctx := context.Background()
+
+	require.NoError(t, s.ChangePassword(ctx, 1, "First-Pass-1", "Second-Pass-2"))
+	require.NoError(t, s.ChangePassword(ctx, 1, "Second-Pass-2", "First-Pass-1"))
+	assert.Empty(t, repo.history)
+}
@@ -0,0 +1,166 @@
+// Package service implements login sessions
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/cache"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// sessionIDBytes is the entropy of a session ID. IDs are shown to
+	// their owner but must not be guessable by anyone else.
+	sessionIDBytes = 16
+	// sessionCacheTTL bounds how long a session is trusted without asking
+	// the database. Revoking a session deletes the entry, so this only
+	// matters when another instance's in-memory fallback cache holds it.
+	sessionCacheTTL = time.Minute
+)
+
+// sessionCacheKey is the cache key marking a session as active
+func sessionCacheKey(sessionID string) string {
+	return "session:" + sessionID
+}
+
+type sessionService struct {
+	repo      repository.SessionRepository
+	cache     cache.Service
+	auditSink audit.Sink
+	logger    *zerolog.Logger
+}
+
+// NewSessionService creates a new session service
+func NewSessionService(repo repository.SessionRepository, cache cache.Service, auditSink audit.Sink, logger *zerolog.Logger) SessionService {
+	return &sessionService{
+		repo:      repo,
+		cache:     cache,
+		auditSink: auditSink,
+		logger:    logger,
+	}
+}
+
+func (s *sessionService) ListSessions(ctx context.Context, userID int32) ([]domain.Session, error) {
+	sessions, err := s.repo.ListSessions(ctx, userID)
+	if err != nil {
+		s.logger.Error().Err(err).Int32("user_id", userID).Msg("Failed to list sessions")
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (s *sessionService) RevokeSession(ctx context.Context, userID int32, sessionID, currentSessionID string) error {
+	if err := s.repo.DeleteSession(ctx, userID, sessionID); err != nil {
+		if !errors.Is(err, domain.ErrSessionNotFound) {
+			s.logger.Error().Err(err).Int32("user_id", userID).Msg("Failed to revoke session")
+		}
+		return err
+	}
+
+	// Tokens of the session are checked against the cache first
+	if s.cache != nil {
+		if err := s.cache.Delete(ctx, sessionCacheKey(sessionID)); err != nil {
+			s.logger.Warn().Err(err).Msg("Failed to invalidate cached session")
+		}
+	}
+
+	event, message := domain.AuditSessionRevoked, "Session revoked"
+	if sessionID == currentSessionID {
+		event, message = domain.AuditLogout, "User logged out"
+	}
+	recordAuditEvent(ctx, s.auditSink, s.logger, domain.AuditEvent{
+		Type:    event,
+		UserID:  &userID,
+		Success: true,
+		Details: map[string]string{"session_id": sessionID},
+	})
+
+	s.logger.Info().Int32("user_id", userID).Msg(message)
+	return nil
+}
+
+// startSession records a login from the client in ctx, returning the ID
+// to embed in its tokens. Without a session repository tokens carry no
+// session.
+func (s *authService) startSession(ctx context.Context, userID int32, expiresAt time.Time) (string, error) {
+	if s.sessions == nil {
+		return "", nil
+	}
+
+	raw := make([]byte, sessionIDBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate session id: %w", err)
+	}
+
+	client := audit.ClientFromContext(ctx)
+	session, err := s.sessions.CreateSession(ctx, domain.Session{
+		ID:        base64.RawURLEncoding.EncodeToString(raw),
+		UserID:    userID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return "", fmt.Errorf("start session: %w", err)
+	}
+	return session.ID, nil
+}
+
+// sessionLifetimeFor returns how long a new session lasts
+func (s *authService) sessionLifetimeFor(rememberMe bool) time.Duration {
+	if rememberMe {
+		return s.rememberMeLifetime
+	}
+	return s.sessionLifetime
+}
+
+// checkSession rejects tokens whose session was revoked or has expired
+func (s *authService) checkSession(ctx context.Context, userID int32, sessionID string) error {
+	if s.sessions == nil {
+		return nil
+	}
+
+	var active bool
+	if s.cache != nil {
+		if err := s.cache.Get(ctx, sessionCacheKey(sessionID), &active); err == nil && active {
+			return nil
+		}
+	}
+
+	active, err := s.sessions.SessionExists(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("check session: %w", err)
+	}
+	if !active {
+		s.logger.Debug().Int32("user_id", userID).Msg("Token of revoked session")
+		return domain.ErrInvalidToken
+	}
+
+	if s.cache != nil {
+		if err := s.cache.Set(ctx, sessionCacheKey(sessionID), true, sessionCacheTTL); err != nil {
+			s.logger.Warn().Err(err).Msg("Failed to cache session")
+		}
+	}
+	return nil
+}
+
+// endSessions removes the user's sessions after all their tokens were
+// revoked. The token version already rejects the tokens, so failures are
+// only logged.
+func (s *authService) endSessions(ctx context.Context, userID int32) {
+	if s.sessions == nil {
+		return
+	}
+	if err := s.sessions.DeleteUserSessions(ctx, userID); err != nil {
+		s.logger.Warn().Err(err).Int32("user_id", userID).Msg("Failed to delete sessions")
+	}
+}
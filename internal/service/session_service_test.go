@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+	"user-auth-app/internal/tenant"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySessionRepo keeps sessions in a map
+type memorySessionRepo struct {
+	repository.SessionRepository
+	sessions map[string]domain.Session
+}
+
+func newMemorySessionRepo() *memorySessionRepo {
+	return &memorySessionRepo{sessions: make(map[string]domain.Session)}
+}
+
+func (r *memorySessionRepo) CreateSession(ctx context.Context, session domain.Session) (domain.Session, error) {
+	session.CreatedAt = time.Now()
+	session.LastUsedAt = session.CreatedAt
+	r.sessions[session.ID] = session
+	return session, nil
+}
+
+func (r *memorySessionRepo) active(userID int32, sessionID string) (domain.Session, bool) {
+	session, ok := r.sessions[sessionID]
+	return session, ok && session.UserID == userID && session.ExpiresAt.After(time.Now())
+}
+
+func (r *memorySessionRepo) SessionExists(ctx context.Context, userID int32, sessionID string) (bool, error) {
+	_, ok := r.active(userID, sessionID)
+	return ok, nil
+}
+
+func (r *memorySessionRepo) ListSessions(ctx context.Context, userID int32) ([]domain.Session, error) {
+	var sessions []domain.Session
+	for id := range r.sessions {
+		if session, ok := r.active(userID, id); ok {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt) })
+	return sessions, nil
+}
+
+func (r *memorySessionRepo) TouchSession(ctx context.Context, userID int32, sessionID string, expiresAt time.Time) (time.Time, error) {
+	session, ok := r.active(userID, sessionID)
+	if !ok {
+		return time.Time{}, domain.ErrSessionNotFound
+	}
+	session.LastUsedAt = time.Now()
+	session.ExpiresAt = expiresAt
+	r.sessions[sessionID] = session
+	return session.ExpiresAt, nil
+}
+
+func (r *memorySessionRepo) DeleteSession(ctx context.Context, userID int32, sessionID string) error {
+	if session, ok := r.sessions[sessionID]; !ok || session.UserID != userID {
+		return domain.ErrSessionNotFound
+	}
+	delete(r.sessions, sessionID)
+	return nil
+}
+
+func (r *memorySessionRepo) DeleteUserSessions(ctx context.Context, userID int32) error {
+	for id, session := range r.sessions {
+		if session.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+func TestSessionsFollowLogins(t *testing.T) {
+	const password = "Correct-Horse-9"
+	s, _ := newRevocationTestService(t, password)
+	repo := newMemorySessionRepo()
+	s.sessions = repo
+	logger := zerolog.Nop()
+	auditSink := audit.NewMemorySink()
+	sessions := NewSessionService(repo, s.cache, auditSink, &logger)
+
+	laptopCtx := audit.WithClient(context.Background(), audit.Client{IPAddress: "203.0.113.7", UserAgent: "Firefox"})
+	phoneCtx := audit.WithClient(context.Background(), audit.Client{IPAddress: "198.51.100.2", UserAgent: "Safari"})
+
+	_, laptop, _, err := s.Login(laptopCtx, "user@example.com", password, false)
+	require.NoError(t, err)
+	_, phone, _, err := s.Login(phoneCtx, "user@example.com", password, false)
+	require.NoError(t, err)
+
+	listed, err := sessions.ListSessions(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+
+	laptopClaims, err := s.ValidateToken(laptopCtx, laptop)
+	require.NoError(t, err)
+	require.NotEmpty(t, laptopClaims.SessionID)
+	assert.Equal(t, "203.0.113.7", repo.sessions[laptopClaims.SessionID].IPAddress)
+	assert.Equal(t, "Firefox", repo.sessions[laptopClaims.SessionID].UserAgent)
+
+	// Refreshing keeps the session
+	_, refreshed, _, _, err := s.RefreshToken(phoneCtx, phone)
+	require.NoError(t, err)
+	refreshedClaims, err := s.ValidateToken(phoneCtx, refreshed)
+	require.NoError(t, err)
+	phoneClaims, err := s.ValidateToken(phoneCtx, phone)
+	require.NoError(t, err)
+	assert.Equal(t, phoneClaims.SessionID, refreshedClaims.SessionID)
+	assert.NotEqual(t, laptopClaims.SessionID, phoneClaims.SessionID)
+
+	// Revoking the laptop's session signs only the laptop out, even though
+	// its session is cached
+	require.NoError(t, sessions.RevokeSession(context.Background(), 1, laptopClaims.SessionID, phoneClaims.SessionID))
+
+	_, err = s.ValidateToken(laptopCtx, laptop)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+	_, _, _, _, err = s.RefreshToken(laptopCtx, laptop)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+	_, err = s.ValidateToken(phoneCtx, refreshed)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, sessions.RevokeSession(context.Background(), 1, laptopClaims.SessionID, phoneClaims.SessionID), domain.ErrSessionNotFound)
+	assert.ErrorIs(t, sessions.RevokeSession(context.Background(), 2, phoneClaims.SessionID, ""), domain.ErrSessionNotFound,
+		"users can't revoke each other's sessions")
+
+	// Revoking the requesting token's own session is a logout
+	require.NoError(t, sessions.RevokeSession(context.Background(), 1, phoneClaims.SessionID, phoneClaims.SessionID))
+	events := auditSink.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, domain.AuditSessionRevoked, events[0].Type)
+	assert.Equal(t, laptopClaims.SessionID, events[0].Details["session_id"])
+	assert.Equal(t, domain.AuditLogout, events[1].Type)
+	assert.Equal(t, phoneClaims.SessionID, events[1].Details["session_id"])
+
+	_, err = s.ValidateToken(phoneCtx, refreshed)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+
+	// A password change ends every session
+	require.NoError(t, s.ChangePassword(context.Background(), 1, password, "Brand-New-Pass-2"))
+	listed, err = sessions.ListSessions(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+}
+
+func TestRememberMeSessionLifetime(t *testing.T) {
+	const password = "Correct-Horse-9"
+	s, _ := newRevocationTestService(t, password)
+	repo := newMemorySessionRepo()
+	s.sessions = repo
+	ctx := context.Background()
+
+	tests := map[bool]time.Duration{
+		false: s.sessionLifetime,
+		true:  s.rememberMeLifetime,
+	}
+	for rememberMe, lifetime := range tests {
+		_, token, expiresAt, err := s.Login(ctx, "user@example.com", password, rememberMe)
+		require.NoError(t, err)
+
+		// Only the session's lifetime depends on remember-me
+		assert.WithinDuration(t, time.Now().Add(s.jwtExpiry), expiresAt, time.Minute)
+		claims, err := s.ValidateToken(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, rememberMe, claims.RememberMe)
+		assert.WithinDuration(t, time.Now().Add(lifetime), repo.sessions[claims.SessionID].ExpiresAt, time.Minute)
+
+		// Refreshing slides the session on by the same lifetime, no
+		// further, and keeps remember-me
+		session := repo.sessions[claims.SessionID]
+		session.ExpiresAt = time.Now().Add(time.Minute)
+		repo.sessions[claims.SessionID] = session
+		user, refreshed, _, refreshedRememberMe, err := s.RefreshToken(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, claims.UserID, user.ID)
+		assert.Equal(t, rememberMe, refreshedRememberMe)
+		refreshedClaims, err := s.ValidateToken(ctx, refreshed)
+		require.NoError(t, err)
+		assert.Equal(t, rememberMe, refreshedClaims.RememberMe)
+		assert.WithinDuration(t, time.Now().Add(lifetime), repo.sessions[claims.SessionID].ExpiresAt, time.Minute)
+	}
+}
+
+func TestRefreshedTokenEndsWithSession(t *testing.T) {
+	const password = "Correct-Horse-9"
+	s, _ := newRevocationTestService(t, password)
+	repo := newMemorySessionRepo()
+	s.sessions = repo
+	s.sessionLifetime = 10 * time.Minute
+	ctx := context.Background()
+
+	_, token, expiresAt, err := s.Login(ctx, "user@example.com", password, false)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(s.jwtExpiry), expiresAt, time.Minute)
+
+	_, _, refreshedAt, _, err := s.RefreshToken(ctx, token)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), refreshedAt, time.Minute)
+}
+
+func TestRefreshExpiredTokenOfActiveSession(t *testing.T) {
+	const password = "Correct-Horse-9"
+	s, repo := newRevocationTestService(t, password)
+	sessions := newMemorySessionRepo()
+	s.sessions = sessions
+	ctx := context.Background()
+
+	_, token, _, err := s.Login(ctx, "user@example.com", password, true)
+	require.NoError(t, err)
+	claims, err := s.ValidateToken(ctx, token)
+	require.NoError(t, err)
+
+	// The access token has expired, but its session has not
+	expired, err := s.generateToken(ctx, repo.user, claims.SessionID, true, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	_, err = s.ValidateToken(ctx, expired)
+	require.ErrorIs(t, err, domain.ErrExpiredToken)
+
+	_, refreshed, _, _, err := s.RefreshToken(ctx, expired)
+	require.NoError(t, err)
+	_, err = s.ValidateToken(ctx, refreshed)
+	assert.NoError(t, err)
+
+	// Refresh isn't behind the auth middleware, so it binds the tenant
+	_, _, _, _, err = s.RefreshToken(tenant.WithID(ctx, "acme"), expired)
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+
+	// Once the session is gone the user has to log in again
+	delete(sessions.sessions, claims.SessionID)
+	_, _, _, _, err = s.RefreshToken(ctx, expired)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+
+	// An expired token without a session can't be refreshed at all
+	sessionless, err := s.generateToken(ctx, repo.user, "", false, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	_, _, _, _, err = s.RefreshToken(ctx, sessionless)
+	assert.ErrorIs(t, err, domain.ErrExpiredToken)
+}
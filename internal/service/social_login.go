@@ -0,0 +1,206 @@
+// Package service implements sign-in through external identity providers
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/validator"
+)
+
+const (
+	// socialUsernameMaxLen keeps generated usernames within the default
+	// username rules, leaving room for a uniqueness suffix
+	socialUsernameMaxLen = 40
+	// socialUsernameAttempts bounds retries when a generated username is
+	// taken
+	socialUsernameAttempts = 5
+)
+
+// LoginWithProvider signs in a user authenticated by a social login
+// provider. A known identity logs straight in. Otherwise an account with
+// the same email is linked when both sides have verified the address, and
+// a new passwordless user is created when no account uses the email,
+// unless registration is invite-only.
+func (s *authService) LoginWithProvider(ctx context.Context, identity domain.ExternalIdentity) (domain.User, string, time.Time, error) {
+	if !identity.EmailVerified {
+		authLoginAttempts.WithLabelValues("failure").Inc()
+		s.recordAudit(ctx, domain.AuditEvent{
+			Type:    domain.AuditLoginFailure,
+			Details: map[string]string{"reason": "unverified_email", "provider": identity.Provider},
+		})
+		return domain.User{}, "", time.Time{}, domain.ErrUnverifiedIdentity
+	}
+	identity.Email = validator.NormalizeEmail(identity.Email)
+
+	user, err := s.repo.GetUserByProvider(ctx, identity.Provider, identity.ProviderID)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		user, err = s.linkOrCreateUser(ctx, identity)
+	}
+	if err != nil {
+		if !errors.Is(err, domain.ErrAccountLinkRequired) && !errors.Is(err, domain.ErrInviteRequired) {
+			s.logger.Error().Err(err).Str("provider", identity.Provider).Msg("Social login failed")
+		}
+		return domain.User{}, "", time.Time{}, fmt.Errorf("social login failed: %w", err)
+	}
+
+	expiresAt := time.Now().Add(s.jwtExpiry)
+	sessionEnd := time.Now().Add(s.sessionLifetime)
+	if user.MustChangePassword {
+		expiresAt = time.Now().Add(passwordChangeTokenExpiry)
+		sessionEnd = expiresAt
+	}
+	sessionID, err := s.startSession(ctx, user.ID, sessionEnd)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to start session")
+		return domain.User{}, "", time.Time{}, fmt.Errorf("social login failed: %w", err)
+	}
+	token, err := s.generateToken(ctx, user, sessionID, false, expiresAt)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to generate token")
+		return domain.User{}, "", time.Time{}, fmt.Errorf("token generation failed: %w", err)
+	}
+
+	authLoginAttempts.WithLabelValues("success").Inc()
+	s.recordAudit(ctx, domain.AuditEvent{
+		Type:    domain.AuditLoginSuccess,
+		UserID:  &user.ID,
+		Success: true,
+		Details: map[string]string{"provider": identity.Provider},
+	})
+
+	s.logger.Info().
+		Int32("user_id", user.ID).
+		Str("provider", identity.Provider).
+		Msg("User logged in with provider")
+
+	return user, token, expiresAt, nil
+}
+
+// linkOrCreateUser attaches a new external identity to the account with
+// the same email, or creates one
+func (s *authService) linkOrCreateUser(ctx context.Context, identity domain.ExternalIdentity) (domain.User, error) {
+	existing, _, err := s.repo.GetUserByEmail(ctx, identity.Email)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return s.createSocialUser(ctx, identity)
+	}
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	// Linking lets whoever controls the provider identity into the
+	// account. Only do it when the local account has also proven it owns
+	// the email; otherwise someone who registered the address first with
+	// a password would share the real owner's account. Accounts already
+	// linked to another identity are never re-linked.
+	if existing.Provider != "" || !existing.EmailVerified {
+		authLoginAttempts.WithLabelValues("failure").Inc()
+		s.recordAudit(ctx, domain.AuditEvent{
+			Type:    domain.AuditLoginFailure,
+			UserID:  &existing.ID,
+			Details: map[string]string{"reason": "link_required", "provider": identity.Provider},
+		})
+		return domain.User{}, domain.ErrAccountLinkRequired
+	}
+
+	if err := s.repo.LinkProvider(ctx, existing.ID, identity); err != nil {
+		return domain.User{}, err
+	}
+	existing.Provider = identity.Provider
+
+	s.recordAudit(ctx, domain.AuditEvent{
+		Type:    domain.AuditAccountLinked,
+		UserID:  &existing.ID,
+		Success: true,
+		Details: map[string]string{"provider": identity.Provider},
+	})
+
+	return existing, nil
+}
+
+// createSocialUser creates a passwordless user, deriving a free username
+// from the email. Social sign-up has no invite code to give, so it is
+// refused while registration is invite-only.
+func (s *authService) createSocialUser(ctx context.Context, identity domain.ExternalIdentity) (domain.User, error) {
+	if s.inviteOnly {
+		return domain.User{}, domain.ErrInviteRequired
+	}
+
+	// A reserved name is handled like a taken one: it only ever goes out
+	// with a suffix
+	base := usernameFromEmail(identity.Email)
+	username := base
+	if validator.IsReservedUsername(base, s.reservedUsernames) {
+		suffixed, err := suffixedUsername(base)
+		if err != nil {
+			return domain.User{}, err
+		}
+		username = suffixed
+	}
+
+	for attempt := 1; ; attempt++ {
+		created, err := s.repo.CreateOAuthUser(ctx, domain.User{
+			Username: username,
+			Email:    identity.Email,
+		}, identity)
+		if err == nil {
+			s.invalidateUserCache(ctx, created.ID)
+			authRegistrations.Inc()
+			s.recordAudit(ctx, domain.AuditEvent{
+				Type:    domain.AuditUserRegistered,
+				UserID:  &created.ID,
+				Success: true,
+				Details: map[string]string{"provider": identity.Provider},
+			})
+			return created, nil
+		}
+		if !errors.Is(err, domain.ErrDuplicateUsername) || attempt == socialUsernameAttempts {
+			return domain.User{}, err
+		}
+
+		if username, err = suffixedUsername(base); err != nil {
+			return domain.User{}, err
+		}
+	}
+}
+
+// suffixedUsername appends a random four-digit suffix to base
+func suffixedUsername(base string) (string, error) {
+	suffix, err := rand.Int(rand.Reader, big.NewInt(10000))
+	if err != nil {
+		return "", fmt.Errorf("generate username suffix: %w", err)
+	}
+	return fmt.Sprintf("%s_%04d", base, suffix.Int64()), nil
+}
+
+// usernameFromEmail turns an email's local part into a valid username:
+// letters, digits and underscores only, at least the default minimum
+// length
+func usernameFromEmail(email string) string {
+	local, _, _ := strings.Cut(email, "@")
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(local) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+		if b.Len() == socialUsernameMaxLen {
+			break
+		}
+	}
+
+	username := strings.Trim(b.String(), "_")
+	for len(username) < domain.DefaultUsernameMinLen {
+		username += "_user"
+	}
+	return username
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// socialUserRepo extends fakeUserRepo with provider identities
+type socialUserRepo struct {
+	fakeUserRepo
+	identities map[string]domain.User
+	created    []domain.User
+	taken      map[string]bool
+	linkedID   int32
+}
+
+func (r *socialUserRepo) GetUserByProvider(ctx context.Context, provider, providerID string) (domain.User, error) {
+	user, ok := r.identities[provider+":"+providerID]
+	if !ok {
+		return domain.User{}, domain.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (r *socialUserRepo) CreateOAuthUser(ctx context.Context, user domain.User, identity domain.ExternalIdentity) (domain.User, error) {
+	if r.taken[user.Username] {
+		return domain.User{}, domain.ErrDuplicateUsername
+	}
+	user.ID = int32(100 + len(r.created))
+	user.Role = "user"
+	user.EmailVerified = true
+	user.Provider = identity.Provider
+	r.created = append(r.created, user)
+	return user, nil
+}
+
+// GetTokenVersion also knows users reached through an identity
+func (r *socialUserRepo) GetTokenVersion(ctx context.Context, userID int32) (int32, error) {
+	for _, user := range append(slices.Collect(maps.Values(r.identities)), r.created...) {
+		if user.ID == userID {
+			return user.TokenVersion, nil
+		}
+	}
+	return r.fakeUserRepo.GetTokenVersion(ctx, userID)
+}
+
+func (r *socialUserRepo) LinkProvider(ctx context.Context, userID int32, identity domain.ExternalIdentity) error {
+	r.linkedID = userID
+	return nil
+}
+
+func newSocialTestService(repo repository.UserRepository) *authService {
+	return newLoginTestService(repo, bcrypt.MinCost)
+}
+
+func googleIdentity(email string) domain.ExternalIdentity {
+	return domain.ExternalIdentity{
+		Provider:      "google",
+		ProviderID:    "1234567890",
+		Email:         email,
+		EmailVerified: true,
+	}
+}
+
+func TestLoginWithProviderKnownIdentity(t *testing.T) {
+	user := domain.User{ID: 7, Username: "alice", Email: "alice@example.com", Role: "user", Provider: "google"}
+	repo := &socialUserRepo{identities: map[string]domain.User{"google:1234567890": user}}
+	s := newSocialTestService(repo)
+
+	_, token, _, err := s.LoginWithProvider(context.Background(), googleIdentity("alice@example.com"))
+	require.NoError(t, err)
+
+	claims, err := s.ValidateToken(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, int32(7), claims.UserID)
+	assert.Empty(t, repo.created)
+}
+
+func TestLoginWithProviderCreatesUser(t *testing.T) {
+	repo := &socialUserRepo{taken: map[string]bool{"new_user": true}}
+	s := newSocialTestService(repo)
+
+	_, _, _, err := s.LoginWithProvider(context.Background(), googleIdentity("new.user@example.com"))
+	require.NoError(t, err)
+
+	require.Len(t, repo.created, 1)
+	assert.Equal(t, "new.user@example.com", repo.created[0].Email)
+	assert.Regexp(t, `^new_user_\d{4}$`, repo.created[0].Username, "taken username gets a suffix")
+}
+
+func TestLoginWithProviderSuffixesReservedUsername(t *testing.T) {
+	repo := &socialUserRepo{}
+	s := newSocialTestService(repo)
+	s.reservedUsernames = domain.DefaultReservedUsernames
+
+	_, _, _, err := s.LoginWithProvider(context.Background(), googleIdentity("Admin@attacker.tld"))
+	require.NoError(t, err)
+
+	require.Len(t, repo.created, 1)
+	assert.Regexp(t, `^admin_\d{4}$`, repo.created[0].Username, "reserved username gets a suffix")
+}
+
+func TestLoginWithProviderLinksVerifiedAccount(t *testing.T) {
+	repo := &socialUserRepo{fakeUserRepo: fakeUserRepo{
+		user: domain.User{ID: 3, Username: "bob", Email: "bob@example.com", Role: "user", EmailVerified: true},
+		hash: "hash",
+	}}
+	s := newSocialTestService(repo)
+
+	_, token, _, err := s.LoginWithProvider(context.Background(), googleIdentity("bob@example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), repo.linkedID)
+
+	claims, err := s.ValidateToken(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), claims.UserID)
+}
+
+func TestLoginWithProviderRefusesUnsafeLink(t *testing.T) {
+	tests := []struct {
+		name string
+		user domain.User
+	}{
+		{"unverified local email", domain.User{ID: 3, Email: "bob@example.com"}},
+		{"linked to another identity", domain.User{ID: 3, Email: "bob@example.com", EmailVerified: true, Provider: "google"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &socialUserRepo{fakeUserRepo: fakeUserRepo{user: tt.user, hash: "hash"}}
+			s := newSocialTestService(repo)
+
+			_, _, _, err := s.LoginWithProvider(context.Background(), googleIdentity("bob@example.com"))
+			assert.ErrorIs(t, err, domain.ErrAccountLinkRequired)
+			assert.Zero(t, repo.linkedID)
+			assert.Empty(t, repo.created)
+		})
+	}
+}
+
+func TestLoginWithProviderRejectsUnverifiedEmail(t *testing.T) {
+	repo := &socialUserRepo{}
+	s := newSocialTestService(repo)
+
+	identity := googleIdentity("eve@example.com")
+	identity.EmailVerified = false
+
+	_, _, _, err := s.LoginWithProvider(context.Background(), identity)
+	assert.ErrorIs(t, err, domain.ErrUnverifiedIdentity)
+	assert.Empty(t, repo.created)
+}
+
+func TestUsernameFromEmail(t *testing.T) {
+	tests := map[string]string{
+		"Jane.Doe@example.com": "jane_doe",
+		"a@example.com":        "a_user",
+		"x+tag@example.com":    "x_tag",
+	}
+	for email, want := range tests {
+		assert.Equal(t, want, usernameFromEmail(email), email)
+	}
+}
@@ -0,0 +1,67 @@
+// Package service implements token binding to client attributes
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/netip"
+
+	"user-auth-app/internal/audit"
+)
+
+// Token binding modes
+const (
+	TokenBindingNone      = "none"
+	TokenBindingIP        = "ip"
+	TokenBindingUserAgent = "user_agent"
+)
+
+// tokenBinding returns a hash of the client attribute a token is bound to
+// under mode, or "" when binding is disabled
+func tokenBinding(mode string, client audit.Client) string {
+	var value string
+	switch mode {
+	case TokenBindingIP:
+		value = ipNetwork(client.IPAddress)
+	case TokenBindingUserAgent:
+		value = client.UserAgent
+	default:
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(mode + ":" + value))
+	return hex.EncodeToString(sum[:])
+}
+
+// bindingMatches reports whether a token's binding claim matches the
+// current client
+func bindingMatches(mode, claim string, client audit.Client) bool {
+	want := tokenBinding(mode, client)
+	if want == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(claim), []byte(want)) == 1
+}
+
+// ipNetwork reduces an address to its /24 (IPv4) or /64 (IPv6) network so
+// clients moving between addresses on the same carrier network keep
+// their token
+func ipNetwork(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+
+	bits := 64
+	if addr.Is4() {
+		bits = 24
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ip
+	}
+	return prefix.String()
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/domain"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBindingTestService(mode string) *authService {
+	logger := zerolog.Nop()
+	return &authService{
+		repo:             &fakeUserRepo{user: domain.User{ID: 1}},
+		logger:           &logger,
+		keys:             testKeys(),
+		jwtExpiry:        time.Hour,
+		issuer:           testIssuer,
+		audience:         testAudience,
+		tokenBindingMode: mode,
+	}
+}
+
+func TestTokenBinding(t *testing.T) {
+	issuer := audit.Client{IPAddress: "203.0.113.10", UserAgent: "app/1.0"}
+
+	tests := []struct {
+		name    string
+		mode    string
+		client  audit.Client
+		wantErr error
+	}{
+		{"none ignores client", TokenBindingNone, audit.Client{IPAddress: "198.51.100.1", UserAgent: "other"}, nil},
+		{"ip same address", TokenBindingIP, issuer, nil},
+		{"ip same /24", TokenBindingIP, audit.Client{IPAddress: "203.0.113.250"}, nil},
+		{"ip different network", TokenBindingIP, audit.Client{IPAddress: "198.51.100.10"}, domain.ErrInvalidToken},
+		{"user agent same", TokenBindingUserAgent, audit.Client{IPAddress: "198.51.100.10", UserAgent: "app/1.0"}, nil},
+		{"user agent different", TokenBindingUserAgent, audit.Client{IPAddress: "203.0.113.10", UserAgent: "app/2.0"}, domain.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newBindingTestService(tt.mode)
+			user := domain.User{ID: 1, Email: "user@example.com", Role: "user"}
+
+			token, err := s.generateToken(audit.WithClient(context.Background(), issuer), user, "", false, time.Now().Add(time.Hour))
+			require.NoError(t, err)
+
+			claims, err := s.ValidateToken(audit.WithClient(context.Background(), tt.client), token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, claims.UserID)
+		})
+	}
+}
+
+func TestTokenBindingRejectsUnboundToken(t *testing.T) {
+	ctx := audit.WithClient(context.Background(), audit.Client{IPAddress: "203.0.113.10"})
+	user := domain.User{ID: 1, Email: "user@example.com", Role: "user"}
+
+	// Tokens issued before binding was enabled carry no binding claim
+	token, err := newBindingTestService(TokenBindingNone).generateToken(ctx, user, "", false, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	_, err = newBindingTestService(TokenBindingIP).ValidateToken(ctx, token)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+}
+
+func TestIPNetwork(t *testing.T) {
+	assert.Equal(t, "203.0.113.0/24", ipNetwork("203.0.113.77"))
+	assert.Equal(t, "203.0.113.0/24", ipNetwork("::ffff:203.0.113.77"))
+	assert.Equal(t, "2001:db8:1:2::/64", ipNetwork("2001:db8:1:2:aaaa::1"))
+	assert.Equal(t, "not-an-ip", ipNetwork("not-an-ip"))
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/tenant"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClaimsTestService(issuer, audience string) *authService {
+	logger := zerolog.Nop()
+	return &authService{
+		repo:      &fakeUserRepo{user: domain.User{ID: 1}},
+		logger:    &logger,
+		keys:      testKeys(),
+		jwtExpiry: time.Hour,
+		issuer:    issuer,
+		audience:  audience,
+	}
+}
+
+func TestTokenRegisteredClaims(t *testing.T) {
+	s := newClaimsTestService("auth.staging", "api.staging")
+	user := domain.User{ID: 1, Email: "user@example.com", Role: "user"}
+
+	signed, err := s.generateToken(context.Background(), user, "", false, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(signed, claims, s.keys.Keyfunc)
+	require.NoError(t, err)
+
+	assert.Equal(t, "auth.staging", claims["iss"])
+	assert.Equal(t, "api.staging", claims["aud"])
+	assert.Contains(t, claims, "iat")
+	assert.Contains(t, claims, "nbf")
+
+	_, err = s.ValidateToken(context.Background(), signed)
+	assert.NoError(t, err)
+}
+
+func TestValidateTokenRejectsOtherEnvironments(t *testing.T) {
+	user := domain.User{ID: 1, Email: "user@example.com", Role: "user"}
+	production := newClaimsTestService("auth.production", "api.production")
+
+	tests := []struct {
+		name   string
+		issuer *authService
+	}{
+		{"other issuer", newClaimsTestService("auth.staging", "api.production")},
+		{"other audience", newClaimsTestService("auth.production", "api.staging")},
+		{"no issuer or audience", newClaimsTestService("", "")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Same signing key, so only iss and aud tell the tokens apart
+			signed, err := tt.issuer.generateToken(context.Background(), user, "", false, time.Now().Add(time.Hour))
+			require.NoError(t, err)
+
+			_, err = production.ValidateToken(context.Background(), signed)
+			assert.ErrorIs(t, err, domain.ErrInvalidToken)
+		})
+	}
+}
+
+func TestValidateTokenRejectsNotYetValid(t *testing.T) {
+	s := newClaimsTestService("auth", "api")
+
+	signed, err := s.keys.Sign(jwt.MapClaims{
+		"user_id": 1,
+		"iss":     "auth",
+		"aud":     "api",
+		"exp":     time.Now().Add(2 * time.Hour).Unix(),
+		"nbf":     time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.ValidateToken(context.Background(), signed)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+}
+
+func TestValidateTokenDistinguishesExpired(t *testing.T) {
+	s := newClaimsTestService("auth", "api")
+	user := domain.User{ID: 1, Email: "user@example.com", Role: "user"}
+
+	expired, err := s.generateToken(context.Background(), user, "", false, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	_, err = s.ValidateToken(context.Background(), expired)
+	assert.ErrorIs(t, err, domain.ErrExpiredToken)
+
+	// An expired token with a bad signature is invalid, not expired
+	tampered := expired[:len(expired)-4] + "AAAA"
+	_, err = s.ValidateToken(context.Background(), tampered)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+
+	// So is an expired token issued for another deployment
+	other, err := newClaimsTestService("auth", "other").generateToken(context.Background(), user, "", false, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	_, err = s.ValidateToken(context.Background(), other)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+
+	// Tokens must carry an expiry
+	forever, err := s.keys.Sign(jwt.MapClaims{"user_id": 1, "iss": "auth", "aud": "api"})
+	require.NoError(t, err)
+	_, err = s.ValidateToken(context.Background(), forever)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+}
+
+func TestTokenCarriesTenant(t *testing.T) {
+	s := newClaimsTestService("auth", "api")
+
+	signed, err := s.generateToken(context.Background(), domain.User{ID: 1, TenantID: "acme"}, "", false, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	claims, err := s.ValidateToken(context.Background(), signed)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", claims.TenantID)
+
+	// Tokens issued before multi-tenancy belong to the default tenant
+	legacy, err := s.keys.Sign(jwt.MapClaims{
+		"user_id": 1,
+		"iss":     "auth",
+		"aud":     "api",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+	claims, err = s.ValidateToken(context.Background(), legacy)
+	require.NoError(t, err)
+	assert.Equal(t, tenant.Default, claims.TenantID)
+}
+
+func TestTokenCarriesRolePermissions(t *testing.T) {
+	s := newClaimsTestService("auth", "api")
+	s.permissions = domain.RolePermissions{"support": {domain.PermissionUsersList}}
+
+	signed, err := s.generateToken(context.Background(), domain.User{ID: 1, Role: "support"}, "", false, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// Later changes to the mapping don't affect issued tokens
+	s.permissions = domain.RolePermissions{"support": {domain.PermissionAuditRead}}
+	claims, err := s.ValidateToken(context.Background(), signed)
+	require.NoError(t, err)
+	assert.Equal(t, []string{domain.PermissionUsersList}, claims.Permissions)
+
+	signed, err = s.generateToken(context.Background(), domain.User{ID: 1, Role: "user"}, "", false, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	claims, err = s.ValidateToken(context.Background(), signed)
+	require.NoError(t, err)
+	assert.Empty(t, claims.Permissions)
+
+	// Tokens issued before permissions existed get the role's permissions
+	legacy, err := s.keys.Sign(jwt.MapClaims{
+		"user_id": 1,
+		"role":    "support",
+		"iss":     "auth",
+		"aud":     "api",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+	claims, err = s.ValidateToken(context.Background(), legacy)
+	require.NoError(t, err)
+	assert.Equal(t, []string{domain.PermissionAuditRead}, claims.Permissions)
+}
+
+func TestGlobalPermissionsOnlyInDefaultTenant(t *testing.T) {
+	s := newClaimsTestService("auth", "api")
+	s.permissions = domain.RolePermissions{"operator": {domain.PermissionAuditRead, domain.PermissionDeadLettersManage}}
+
+	// The default admin role can't act across tenants
+	admin := domain.DefaultRolePermissions().For(domain.RoleAdmin)
+	assert.NotContains(t, admin, domain.PermissionKeysManage)
+	assert.NotContains(t, admin, domain.PermissionDeadLettersManage)
+
+	for tenantID, want := range map[string][]string{
+		tenant.Default: {domain.PermissionAuditRead, domain.PermissionDeadLettersManage},
+		"acme":         {domain.PermissionAuditRead},
+	} {
+		signed, err := s.generateToken(context.Background(), domain.User{ID: 1, TenantID: tenantID, Role: "operator"}, "", false, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		claims, err := s.ValidateToken(context.Background(), signed)
+		require.NoError(t, err)
+		assert.Equal(t, want, claims.Permissions, tenantID)
+	}
+}
+
+func TestTokenUserIDIsTyped(t *testing.T) {
+	s := newClaimsTestService("auth", "api")
+	s.repo = &fakeUserRepo{user: domain.User{ID: math.MaxInt32}}
+
+	signed, err := s.generateToken(context.Background(), domain.User{ID: math.MaxInt32}, "", false, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	claims, err := s.ValidateToken(context.Background(), signed)
+	require.NoError(t, err)
+	assert.Equal(t, int32(math.MaxInt32), claims.UserID)
+
+	// A token without a usable user ID is rejected rather than read as 0
+	for name, userID := range map[string]any{"missing": nil, "fractional": 1.5, "string": "1", "overflow": int64(math.MaxInt32) + 1} {
+		t.Run(name, func(t *testing.T) {
+			mapClaims := jwt.MapClaims{"iss": "auth", "aud": "api", "exp": time.Now().Add(time.Hour).Unix()}
+			if userID != nil {
+				mapClaims["user_id"] = userID
+			}
+			signed, err := s.keys.Sign(mapClaims)
+			require.NoError(t, err)
+
+			_, err = s.ValidateToken(context.Background(), signed)
+			assert.ErrorIs(t, err, domain.ErrInvalidToken)
+		})
+	}
+}
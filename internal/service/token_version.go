@@ -0,0 +1,75 @@
+// Package service implements token revocation by version
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"user-auth-app/internal/domain"
+)
+
+// tokenVersionCacheTTL bounds how long a cached token version is trusted.
+// Revocation deletes the entry, so this only matters when another
+// instance's in-memory fallback cache still holds the old version.
+const tokenVersionCacheTTL = time.Minute
+
+// tokenVersionCacheKey is the cache key of a user's current token version
+func tokenVersionCacheKey(ctx context.Context, userID int32) string {
+	return "token_version:" + userCacheKey(ctx, userID)
+}
+
+// checkTokenVersion rejects tokens issued before the user's tokens were
+// last revoked, and tokens of users who no longer exist or were
+// deactivated. ctx must carry the token's tenant.
+func (s *authService) checkTokenVersion(ctx context.Context, userID, version int32) error {
+	current, err := s.currentTokenVersion(ctx, userID)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		s.logger.Debug().Int32("user_id", userID).Msg("Token of missing or deactivated user")
+		return domain.ErrInvalidToken
+	}
+	if err != nil {
+		return fmt.Errorf("check token version: %w", err)
+	}
+
+	if version != current {
+		s.logger.Debug().Int32("user_id", userID).Msg("Revoked token")
+		return domain.ErrInvalidToken
+	}
+	return nil
+}
+
+// currentTokenVersion returns the user's token version, from the cache
+// when possible since every authenticated request needs it
+func (s *authService) currentTokenVersion(ctx context.Context, userID int32) (int32, error) {
+	var version int32
+	if s.cache != nil {
+		if err := s.cache.Get(ctx, tokenVersionCacheKey(ctx, userID), &version); err == nil {
+			return version, nil
+		}
+	}
+
+	version, err := s.repo.GetTokenVersion(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if s.cache != nil {
+		if err := s.cache.Set(ctx, tokenVersionCacheKey(ctx, userID), version, tokenVersionCacheTTL); err != nil {
+			s.logger.Warn().Err(err).Msg("Failed to cache token version")
+		}
+	}
+	return version, nil
+}
+
+// invalidateTokenVersion drops the cached token version after the user's
+// tokens were revoked, so the revocation applies immediately
+func (s *authService) invalidateTokenVersion(ctx context.Context, userID int32) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Delete(ctx, tokenVersionCacheKey(ctx, userID)); err != nil {
+		s.logger.Warn().Err(err).Msg("Failed to invalidate cached token version")
+	}
+}
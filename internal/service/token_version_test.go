@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newRevocationTestService(t *testing.T, password string) (*authService, *fakeUserRepo) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repo := &fakeUserRepo{
+		user: domain.User{ID: 1, Email: "user@example.com", Role: "user"},
+		hash: string(hash),
+	}
+	s := newLoginTestService(repo, bcrypt.MinCost)
+	// A warm cache must not let revoked tokens through
+	s.cache = newMapCache()
+	return s, repo
+}
+
+func TestPasswordChangeRevokesTokens(t *testing.T) {
+	const password = "Correct-Horse-9"
+	s, _ := newRevocationTestService(t, password)
+	ctx := context.Background()
+
+	// Two sessions, e.g. a laptop and a phone
+	_, laptop, _, err := s.Login(ctx, "user@example.com", password, false)
+	require.NoError(t, err)
+	_, phone, _, err := s.Login(ctx, "user@example.com", password, false)
+	require.NoError(t, err)
+
+	_, err = s.ValidateToken(ctx, phone)
+	require.NoError(t, err)
+
+	require.NoError(t, s.ChangePassword(ctx, 1, password, "Brand-New-Pass-2"))
+
+	for _, token := range []string{laptop, phone} {
+		_, err = s.ValidateToken(ctx, token)
+		assert.ErrorIs(t, err, domain.ErrInvalidToken)
+
+		_, _, _, _, err = s.RefreshToken(ctx, token)
+		assert.ErrorIs(t, err, domain.ErrInvalidToken, "revoked tokens can't be refreshed")
+	}
+
+	// Logging in with the new password works again
+	_, token, _, err := s.Login(ctx, "user@example.com", "Brand-New-Pass-2", false)
+	require.NoError(t, err)
+	_, err = s.ValidateToken(ctx, token)
+	assert.NoError(t, err)
+}
+
+func TestDeactivateUserRevokesTokens(t *testing.T) {
+	const password = "Correct-Horse-9"
+	s, _ := newRevocationTestService(t, password)
+	ctx := context.Background()
+
+	_, token, _, err := s.Login(ctx, "user@example.com", password, false)
+	require.NoError(t, err)
+	_, err = s.ValidateToken(ctx, token)
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeactivateUser(ctx, 1))
+
+	_, err = s.ValidateToken(ctx, token)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+
+	assert.ErrorIs(t, s.DeactivateUser(ctx, 1), domain.ErrUserNotFound)
+}
+
+func TestUpdateUserRoleRevokesTokens(t *testing.T) {
+	const password = "Correct-Horse-9"
+	s, _ := newRevocationTestService(t, password)
+	sink := audit.NewMemorySink()
+	s.auditSink = sink
+	ctx := context.Background()
+
+	_, token, _, err := s.Login(ctx, "user@example.com", password, false)
+	require.NoError(t, err)
+	_, err = s.ValidateToken(ctx, token)
+	require.NoError(t, err)
+
+	require.NoError(t, s.UpdateUserRole(ctx, 9, 1, domain.RoleAdmin))
+
+	events := sink.Events()
+	require.NotEmpty(t, events)
+	changed := events[len(events)-1]
+	assert.Equal(t, domain.AuditRoleChanged, changed.Type)
+	assert.Equal(t, map[string]string{"role": domain.RoleAdmin, "previous_role": "user", "admin_id": "9"}, changed.Details)
+
+	_, err = s.ValidateToken(ctx, token)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken, "the token carries the old role")
+
+	// The next login carries the new role
+	_, token, _, err = s.Login(ctx, "user@example.com", password, false)
+	require.NoError(t, err)
+	claims, err := s.ValidateToken(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleAdmin, claims.Role)
+
+	assert.ErrorIs(t, s.UpdateUserRole(ctx, 9, 2, domain.RoleAdmin), domain.ErrUserNotFound)
+}
@@ -0,0 +1,56 @@
+// Package service implements the JWT claims of user tokens
+package service
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// UserClaims is the payload of the tokens issued to users, as signed and
+// parsed. ValidateToken turns it into TokenClaims once the token checks
+// out. Claims missing from tokens issued before they existed decode to
+// zero values.
+type UserClaims struct {
+	UserID   int32  `json:"user_id"`
+	TenantID string `json:"tenant_id,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// Permissions is nil in tokens issued before permissions existed
+	Permissions []string `json:"permissions,omitempty"`
+	// Binding ties the token to the client it was issued to
+	Binding string `json:"bnd,omitempty"`
+	// Version must match the user's token version
+	Version   int32  `json:"ver"`
+	SessionID string `json:"sid,omitempty"`
+	// RememberMe marks tokens of sessions that outlive the browser
+	RememberMe bool `json:"rem,omitempty"`
+
+	// Audience shadows the embedded one so aud stays a single string, as
+	// in tokens issued before; jwt encodes a lone audience as an array
+	Audience string `json:"aud,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// newUserClaims returns claims valid from now until expiresAt
+func newUserClaims(issuer, audience string, expiresAt time.Time) *UserClaims {
+	now := jwt.NewNumericDate(time.Now())
+	return &UserClaims{
+		Audience: audience,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  now,
+			NotBefore: now,
+		},
+	}
+}
+
+// GetAudience implements jwt.Claims for the shadowed audience
+func (c UserClaims) GetAudience() (jwt.ClaimStrings, error) {
+	if c.Audience == "" {
+		return nil, nil
+	}
+	return jwt.ClaimStrings{c.Audience}, nil
+}
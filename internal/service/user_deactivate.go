@@ -0,0 +1,85 @@
+// Package service implements bulk user deactivation
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+)
+
+// DeactivateUsers deactivates users in bulk in one transaction. Each user
+// gets the same treatment as DeactivateUser once it commits, and the batch
+// as a whole is recorded against the admin who ran it.
+func (s *authService) DeactivateUsers(ctx context.Context, adminID int32, userIDs []int32) ([]domain.UserDeactivationResult, error) {
+	results := make([]domain.UserDeactivationResult, 0, len(userIDs))
+	seen := make(map[int32]bool, len(userIDs))
+	for _, id := range userIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		results = append(results, domain.UserDeactivationResult{UserID: id})
+	}
+
+	err := s.repo.WithTx(ctx, func(tx repository.UserRepository) error {
+		for i := range results {
+			err := tx.DeactivateUser(ctx, results[i].UserID)
+			if errors.Is(err, domain.ErrUserNotFound) {
+				results[i].Err = err
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Int32("admin_id", adminID).Msg("Failed to deactivate users")
+		return nil, fmt.Errorf("deactivate users: %w", err)
+	}
+
+	admin := strconv.FormatInt(int64(adminID), 10)
+	deactivated := make([]string, 0, len(results))
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+		userID := results[i].UserID
+		deactivated = append(deactivated, strconv.FormatInt(int64(userID), 10))
+
+		s.invalidateUserCache(ctx, userID)
+		s.invalidateTokenVersion(ctx, userID)
+		s.endSessions(ctx, userID)
+
+		s.recordAudit(ctx, domain.AuditEvent{
+			Type:    domain.AuditUserDeactivated,
+			UserID:  &userID,
+			Success: true,
+			Details: map[string]string{"source": "bulk", "admin_id": admin},
+		})
+	}
+
+	s.recordAudit(ctx, domain.AuditEvent{
+		Type:    domain.AuditUsersDeactivated,
+		UserID:  &adminID,
+		Success: true,
+		Details: map[string]string{
+			"user_ids": strings.Join(deactivated, ","),
+			"count":    strconv.Itoa(len(deactivated)),
+		},
+	})
+
+	s.logger.Info().
+		Int32("admin_id", adminID).
+		Int("deactivated", len(deactivated)).
+		Int("not_found", len(results)-len(deactivated)).
+		Msg("Users deactivated")
+
+	return results, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deactivateRepo tracks which users are active. WithTx works on a copy and
+// only keeps it if fn succeeds; deactivating failID fails the query.
+type deactivateRepo struct {
+	repository.UserRepository
+	active map[int32]bool
+	failID int32
+}
+
+func (r *deactivateRepo) DeactivateUser(ctx context.Context, userID int32) error {
+	if userID == r.failID {
+		return errors.New("connection reset")
+	}
+	if !r.active[userID] {
+		return domain.ErrUserNotFound
+	}
+	r.active[userID] = false
+	return nil
+}
+
+func (r *deactivateRepo) WithTx(ctx context.Context, fn func(repository.UserRepository) error) error {
+	tx := &deactivateRepo{active: maps.Clone(r.active), failID: r.failID}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	r.active = tx.active
+	return nil
+}
+
+func TestDeactivateUsers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		repo := &deactivateRepo{active: map[int32]bool{1: true, 2: true, 3: true}}
+		s := newHasherTestService(repo, nil)
+		sink := audit.NewMemorySink()
+		s.auditSink = sink
+		cache := newMapCache()
+		s.cache = cache
+		cache.values[userCacheKey(ctx, 2)] = []byte(`{}`)
+
+		results, err := s.DeactivateUsers(ctx, 9, []int32{2, 4, 2, 3})
+		require.NoError(t, err)
+
+		require.Equal(t, []int32{2, 4, 3}, []int32{results[0].UserID, results[1].UserID, results[2].UserID}, "duplicates are dropped")
+		assert.NoError(t, results[0].Err)
+		assert.ErrorIs(t, results[1].Err, domain.ErrUserNotFound)
+		assert.NoError(t, results[2].Err)
+		assert.Equal(t, map[int32]bool{1: true, 2: false, 3: false}, repo.active)
+		assert.NotContains(t, cache.values, userCacheKey(ctx, 2))
+
+		events := sink.Events()
+		require.Len(t, events, 3)
+		assert.Equal(t, domain.AuditUserDeactivated, events[0].Type)
+		assert.Equal(t, int32(2), *events[0].UserID)
+		assert.Equal(t, map[string]string{"source": "bulk", "admin_id": "9"}, events[0].Details)
+		assert.Equal(t, domain.AuditUsersDeactivated, events[2].Type)
+		assert.Equal(t, int32(9), *events[2].UserID)
+		assert.Equal(t, map[string]string{"user_ids": "2,3", "count": "2"}, events[2].Details)
+	})
+
+	t.Run("failure rolls back the batch", func(t *testing.T) {
+		repo := &deactivateRepo{active: map[int32]bool{1: true, 2: true}, failID: 2}
+		s := newHasherTestService(repo, nil)
+		sink := audit.NewMemorySink()
+		s.auditSink = sink
+
+		_, err := s.DeactivateUsers(ctx, 9, []int32{1, 2})
+		require.Error(t, err)
+
+		assert.Equal(t, map[int32]bool{1: true, 2: true}, repo.active, "nothing should be committed")
+		assert.Empty(t, sink.Events())
+	})
+}
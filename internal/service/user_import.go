@@ -0,0 +1,160 @@
+// Package service implements bulk user import
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/hashing"
+	"user-auth-app/internal/repository"
+	"user-auth-app/internal/validator"
+)
+
+// temporaryPasswordBytes of randomness give a 22 character password
+const temporaryPasswordBytes = 16
+
+// ImportUsers creates users in bulk. See AuthService for the semantics.
+func (s *authService) ImportUsers(ctx context.Context, users []domain.UserImport, atomic bool) ([]domain.UserImportResult, error) {
+	results := make([]domain.UserImportResult, len(users))
+	validateImportHashes(users, results)
+
+	if atomic {
+		for _, result := range results {
+			if result.Err != nil {
+				return abortImport(results), nil
+			}
+		}
+
+		err := s.repo.WithTx(ctx, func(tx repository.UserRepository) error {
+			for i := range users {
+				if err := s.importUser(ctx, tx, users[i], &results[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			for _, result := range results {
+				if result.Err != nil {
+					return abortImport(results), nil
+				}
+			}
+			// Begin or commit failed; no single user is to blame
+			return nil, fmt.Errorf("import users: %w", err)
+		}
+	} else {
+		for i := range users {
+			if results[i].Err == nil {
+				s.importUser(ctx, s.repo, users[i], &results[i])
+			}
+		}
+	}
+
+	created := 0
+	for i, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		created++
+		s.invalidateUserCache(ctx, result.User.ID)
+		s.recordAudit(ctx, domain.AuditEvent{
+			Type:    domain.AuditUserCreated,
+			UserID:  &result.User.ID,
+			Success: true,
+			Details: map[string]string{"source": "import"},
+		})
+		if users[i].PasswordHash == "" {
+			results[i].PasswordSetupQueued = s.requestPasswordSetup(ctx, result.User)
+		}
+	}
+
+	s.logger.Info().
+		Int("created", created).
+		Int("failed", len(users)-created).
+		Bool("atomic", atomic).
+		Msg("Users imported")
+
+	return results, nil
+}
+
+// importUser creates one user, recording the outcome in result. Users
+// without a password hash are stored without a usable password.
+func (s *authService) importUser(ctx context.Context, repo repository.UserRepository, user domain.UserImport, result *domain.UserImportResult) error {
+	role := user.Role
+	if role == "" {
+		role = "user"
+	}
+
+	created, err := repo.CreateUser(ctx, domain.User{
+		Username: validator.NormalizeUsername(user.Username),
+		Email:    validator.NormalizeEmail(user.Email),
+		Role:     role,
+	}, user.PasswordHash)
+	if err != nil {
+		if !errors.Is(err, domain.ErrDuplicateEmail) && !errors.Is(err, domain.ErrDuplicateUsername) {
+			s.logger.Error().Err(err).Msg("Failed to import user")
+		}
+		*result = domain.UserImportResult{Err: err}
+		return err
+	}
+
+	result.User = created
+	return nil
+}
+
+// validateImportHashes fails the users whose password hash isn't one the
+// hasher can verify
+func validateImportHashes(users []domain.UserImport, results []domain.UserImportResult) {
+	for i, user := range users {
+		if user.PasswordHash != "" && hashing.Detect(user.PasswordHash) == "" {
+			results[i].Err = domain.NewValidationError(map[string]string{
+				"password_hash": "must be a bcrypt or Argon2id hash",
+			})
+		}
+	}
+}
+
+// requestPasswordSetup publishes domain.EventUserPasswordSetup for a user
+// created without a password, reporting whether it was published
+func (s *authService) requestPasswordSetup(ctx context.Context, user domain.User) bool {
+	logger := s.loggerFromCtx(ctx)
+	if s.broker == nil || !s.broker.IsAvailable() {
+		logger.Warn().Int32("user_id", user.ID).Msg("Message broker unavailable, password setup not requested")
+		return false
+	}
+	if err := s.broker.PublishJSON(domain.EventUserPasswordSetup, map[string]interface{}{
+		"user_id":   user.ID,
+		"tenant_id": user.TenantID,
+		"email":     user.Email,
+		"username":  user.Username,
+		"timestamp": time.Now().UTC(),
+	}); err != nil {
+		logger.Error().Err(err).Int32("user_id", user.ID).Msg("Failed to publish password setup event")
+		return false
+	}
+	return true
+}
+
+// abortImport marks every user without an error of its own as not
+// imported and drops users created before the failure was rolled back
+func abortImport(results []domain.UserImportResult) []domain.UserImportResult {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i] = domain.UserImportResult{Err: domain.ErrNotImported}
+		}
+	}
+	return results
+}
+
+func generateTemporaryPassword() (string, error) {
+	b := make([]byte, temporaryPasswordBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
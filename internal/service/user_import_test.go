@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/hashing"
+	"user-auth-app/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// importRepo keeps users in memory with unique emails. WithTx stages
+// users and only keeps them if fn succeeds.
+type importRepo struct {
+	repository.UserRepository
+	users  []domain.User
+	hashes map[string]string
+	staged *[]domain.User
+}
+
+func newImportRepo() *importRepo {
+	return &importRepo{hashes: make(map[string]string)}
+}
+
+func (r *importRepo) CreateUser(ctx context.Context, user domain.User, passwordHash string) (domain.User, error) {
+	all := r.users
+	if r.staged != nil {
+		all = append(append([]domain.User{}, r.users...), *r.staged...)
+	}
+	for _, existing := range all {
+		if existing.Email == user.Email {
+			return domain.User{}, domain.ErrDuplicateEmail
+		}
+	}
+
+	user.ID = int32(len(all) + 1)
+	r.hashes[user.Email] = passwordHash
+	if r.staged != nil {
+		*r.staged = append(*r.staged, user)
+	} else {
+		r.users = append(r.users, user)
+	}
+	return user, nil
+}
+
+func (r *importRepo) WithTx(ctx context.Context, fn func(repository.UserRepository) error) error {
+	var staged []domain.User
+	if err := fn(&importRepo{users: r.users, hashes: r.hashes, staged: &staged}); err != nil {
+		return err
+	}
+	r.users = append(r.users, staged...)
+	return nil
+}
+
+func TestImportUsersAtomic(t *testing.T) {
+	ctx := context.Background()
+	existingHash, err := bcrypt.GenerateFromPassword([]byte("Secret123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		repo := newImportRepo()
+		s := newHasherTestService(repo, hashing.NewBcryptHasher(bcrypt.MinCost))
+		broker := &recordingBroker{}
+		s.broker = broker
+
+		results, err := s.ImportUsers(ctx, []domain.UserImport{
+			{Username: "alice", Email: "alice@example.com"},
+			{Username: "bob", Email: "bob@example.com", Role: "admin", PasswordHash: string(existingHash)},
+		}, true)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		require.Len(t, repo.users, 2)
+
+		// No password: none is usable, and setting one is requested
+		alice := results[0]
+		require.NoError(t, alice.Err)
+		assert.True(t, alice.PasswordSetupQueued)
+		assert.Equal(t, "user", alice.User.Role)
+		assert.Empty(t, repo.hashes["alice@example.com"])
+		assert.Error(t, s.hasher.Compare(repo.hashes["alice@example.com"], ""))
+		assert.Equal(t, []string{domain.EventUserPasswordSetup}, broker.published, "only alice needs to set a password")
+
+		// Pre-hashed password is stored as-is
+		bob := results[1]
+		require.NoError(t, bob.Err)
+		assert.False(t, bob.PasswordSetupQueued)
+		assert.False(t, bob.User.MustChangePassword)
+		assert.Equal(t, string(existingHash), repo.hashes["bob@example.com"])
+	})
+
+	t.Run("one failure rolls back the batch", func(t *testing.T) {
+		repo := newImportRepo()
+		repo.users = []domain.User{{ID: 1, Email: "taken@example.com"}}
+		s := newHasherTestService(repo, hashing.NewBcryptHasher(bcrypt.MinCost))
+		broker := &recordingBroker{}
+		s.broker = broker
+
+		results, err := s.ImportUsers(ctx, []domain.UserImport{
+			{Username: "alice", Email: "alice@example.com"},
+			{Username: "taken", Email: "taken@example.com"},
+			{Username: "carol", Email: "carol@example.com"},
+		}, true)
+		require.NoError(t, err)
+
+		assert.Len(t, repo.users, 1, "nothing should be committed")
+		assert.ErrorIs(t, results[0].Err, domain.ErrNotImported)
+		assert.Empty(t, broker.published, "rolled back users aren't asked to set a password")
+		assert.ErrorIs(t, results[1].Err, domain.ErrDuplicateEmail)
+		assert.ErrorIs(t, results[2].Err, domain.ErrNotImported)
+	})
+
+	t.Run("invalid hash fails before the transaction", func(t *testing.T) {
+		repo := newImportRepo()
+		s := newHasherTestService(repo, hashing.NewBcryptHasher(bcrypt.MinCost))
+
+		results, err := s.ImportUsers(ctx, []domain.UserImport{
+			{Username: "alice", Email: "alice@example.com"},
+			{Username: "bob", Email: "bob@example.com", PasswordHash: "plaintext"},
+		}, true)
+		require.NoError(t, err)
+
+		assert.Empty(t, repo.users)
+		assert.ErrorIs(t, results[0].Err, domain.ErrNotImported)
+		assert.ErrorIs(t, results[1].Err, domain.ErrValidation)
+	})
+}
+
+func TestImportUsersBestEffort(t *testing.T) {
+	ctx := context.Background()
+	repo := newImportRepo()
+	repo.users = []domain.User{{ID: 1, Email: "taken@example.com"}}
+	s := newHasherTestService(repo, hashing.NewBcryptHasher(bcrypt.MinCost))
+
+	results, err := s.ImportUsers(ctx, []domain.UserImport{
+		{Username: "alice", Email: "alice@example.com"},
+		{Username: "taken", Email: "taken@example.com"},
+		{Username: "carol", Email: "carol@example.com"},
+	}, false)
+	require.NoError(t, err)
+
+	assert.Len(t, repo.users, 3)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, domain.ErrDuplicateEmail)
+	assert.False(t, results[1].PasswordSetupQueued)
+	assert.NoError(t, results[2].Err)
+	assert.False(t, results[2].PasswordSetupQueued, "no broker to publish to")
+}
@@ -3,67 +3,282 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
+	"user-auth-app/internal/audit"
 	"user-auth-app/internal/cache"
 	"user-auth-app/internal/domain"
 	"user-auth-app/internal/repository"
+	"user-auth-app/internal/tenant"
 
 	"github.com/rs/zerolog"
+	"golang.org/x/sync/singleflight"
 )
 
 type userService struct {
 	repo   repository.UserRepository
 	cache  cache.Service
 	logger *zerolog.Logger
+
+	// profileReads records who read whose profile; nil disables auditing
+	// of profile reads. It must not block, since reads are served from
+	// cache.
+	profileReads audit.Sink
+
+	// warmUsers is how many of the most recently active users WarmCache
+	// preloads, unless warmUserIDs names the users to preload instead
+	warmUsers   int
+	warmUserIDs []int32
+
+	// negativeTTL is how long a "not found" result is cached; 0 disables
+	// negative caching
+	negativeTTL time.Duration
+
+	// loads coalesces concurrent cache misses for the same user into one
+	// database fetch
+	loads singleflight.Group
 }
 
-// NewUserService creates a new user service
+// NewUserService creates a new user service. Lookups of missing users
+// are cached for negativeTTL so repeated probes don't reach the database.
+// Profile reads are audited to profileReads unless it is nil. WarmCache
+// preloads the users in warmUserIDs or, if there are none, the warmUsers
+// most recently active users.
 func NewUserService(
 	repo repository.UserRepository,
 	cache cache.Service,
 	logger *zerolog.Logger,
+	negativeTTL time.Duration,
+	profileReads audit.Sink,
+	warmUsers int,
+	warmUserIDs []int32,
 ) UserService {
 	return &userService{
-		repo:   repo,
-		cache:  cache,
-		logger: logger,
+		repo:         repo,
+		cache:        cache,
+		logger:       logger,
+		negativeTTL:  negativeTTL,
+		profileReads: profileReads,
+		warmUsers:    warmUsers,
+		warmUserIDs:  warmUserIDs,
 	}
 }
 
-func (s *userService) GetProfile(ctx context.Context, userID int32) (domain.User, error) {
-	return s.GetUserByID(ctx, userID)
+// userCacheKey is the cache key of a user. User IDs are unique across
+// tenants, but the key is still scoped so a cached user can only be served
+// to requests for its own tenant.
+func userCacheKey(ctx context.Context, userID int32) string {
+	return fmt.Sprintf("user:%s:%d", tenant.ID(ctx), userID)
+}
+
+// missingUser is the tombstone cached under a user's key when the user
+// doesn't exist. Real users always have a non-zero ID.
+var missingUser = domain.User{}
+
+func isMissingUser(user domain.User) bool {
+	return user.ID == 0
+}
+
+// cacheMissingUser records that userID doesn't exist, if negative caching
+// is enabled
+func (s *userService) cacheMissingUser(ctx context.Context, userID int32) {
+	logger := s.loggerFromCtx(ctx)
+	if s.negativeTTL <= 0 {
+		return
+	}
+	if err := s.cache.Set(ctx, userCacheKey(ctx, userID), missingUser, s.negativeTTL); err != nil {
+		logger.Warn().Err(err).Msg("Failed to cache missing user")
+	}
+}
+
+func (s *userService) GetProfile(ctx context.Context, readerID, userID int32) (domain.User, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	// Users reading their own profile aren't worth an event
+	if s.profileReads != nil && readerID != userID {
+		recordAuditEvent(ctx, s.profileReads, s.logger, domain.AuditEvent{
+			Type:    domain.AuditProfileRead,
+			UserID:  &readerID,
+			Success: true,
+			Details: map[string]string{"target_id": strconv.FormatInt(int64(userID), 10)},
+		})
+	}
+
+	return user, nil
 }
 
 func (s *userService) GetUserByID(ctx context.Context, userID int32) (domain.User, error) {
-	cacheKey := fmt.Sprintf("user:%d", userID)
+	logger := s.loggerFromCtx(ctx)
+	cacheKey := userCacheKey(ctx, userID)
 
 	// Try cache first
 	var user domain.User
 	if err := s.cache.Get(ctx, cacheKey, &user); err == nil {
-		s.logger.Debug().Int32("user_id", userID).Msg("User retrieved from cache")
+		authCacheHits.Inc()
+		if isMissingUser(user) {
+			return domain.User{}, domain.ErrUserNotFound
+		}
+		logger.Debug().Int32("user_id", userID).Msg("User retrieved from cache")
 		return user, nil
 	}
+	authCacheMisses.Inc()
+
+	// Fetch from database. When a hot entry expires, only one request
+	// loads it and the others wait for its result.
+	result := s.loads.DoChan(cacheKey, func() (interface{}, error) {
+		return s.loadUser(ctx, userID, cacheKey)
+	})
+
+	select {
+	case res := <-result:
+		if res.Shared {
+			authCacheCoalesced.Inc()
+		}
+		if res.Err != nil {
+			return domain.User{}, res.Err
+		}
+		return res.Val.(domain.User), nil
+	case <-ctx.Done():
+		return domain.User{}, ctx.Err()
+	}
+}
 
-	// Fetch from database
-	user, err := s.repo.GetUserByID(ctx, userID)
+// loadUser fetches a user from the database and caches it. The fetch
+// ignores the cancellation of the request that started it, since other
+// requests may be waiting for it, but keeps that request's deadline.
+func (s *userService) loadUser(ctx context.Context, userID int32, cacheKey string) (domain.User, error) {
+	logger := s.loggerFromCtx(ctx)
+	loadCtx := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		loadCtx, cancel = context.WithDeadline(loadCtx, deadline)
+		defer cancel()
+	}
+
+	user, err := s.repo.GetUserByID(loadCtx, userID)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		s.cacheMissingUser(loadCtx, userID)
+		return domain.User{}, err
+	}
 	if err != nil {
-		s.logger.Error().Err(err).Int32("user_id", userID).Msg("Failed to get user")
+		logger.Error().Err(err).Int32("user_id", userID).Msg("Failed to get user")
 		return domain.User{}, err
 	}
 
 	// Cache the result
-	if err := s.cache.Set(ctx, cacheKey, user, 0); err != nil {
-		s.logger.Warn().Err(err).Msg("Failed to cache user")
+	if err := s.cache.Set(loadCtx, cacheKey, user, 0); err != nil {
+		logger.Warn().Err(err).Msg("Failed to cache user")
 		// Don't fail the request if caching fails
 	}
 
 	return user, nil
 }
 
+func (s *userService) WarmCache(ctx context.Context) error {
+	logger := s.loggerFromCtx(ctx)
+	limit := s.warmUsers
+	if len(s.warmUserIDs) > 0 {
+		limit = len(s.warmUserIDs)
+	}
+	if limit <= 0 {
+		return nil
+	}
+	start := time.Now()
+
+	users, err := s.repo.ListUsersToWarm(ctx, s.warmUserIDs, limit)
+	if err != nil {
+		return fmt.Errorf("list users to warm: %w", err)
+	}
+
+	warmed := 0
+	for _, user := range users {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("warm cache after %d users: %w", warmed, err)
+		}
+
+		// Users of every tenant are warmed, each under its own tenant's key.
+		// A live request may have cached a fresher copy meanwhile, which
+		// is kept.
+		userCtx := tenant.WithID(ctx, user.TenantID)
+		stored, err := s.cache.SetNX(userCtx, userCacheKey(userCtx, user.ID), user, 0)
+		if err != nil {
+			return fmt.Errorf("warm cache after %d users: %w", warmed, err)
+		}
+		if stored {
+			warmed++
+		}
+	}
+
+	logger.Info().
+		Int("users", warmed).
+		Dur("duration", time.Since(start)).
+		Msg("User cache warmed")
+	return nil
+}
+
+func (s *userService) GetUsersByIDs(ctx context.Context, ids []int32) ([]domain.User, error) {
+	found := make(map[int32]domain.User, len(ids))
+	seen := make(map[int32]bool, len(ids))
+	var misses []int32
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		var user domain.User
+		if err := s.cache.Get(ctx, userCacheKey(ctx, id), &user); err == nil {
+			authCacheHits.Inc()
+			if !isMissingUser(user) {
+				found[id] = user
+			}
+			continue
+		}
+		authCacheMisses.Inc()
+		misses = append(misses, id)
+	}
+
+	if len(misses) > 0 {
+		fetched, err := s.repo.GetUsersByIDs(ctx, misses)
+		if err != nil {
+			s.logger.Error().Err(err).Int("count", len(misses)).Msg("Failed to get users")
+			return nil, err
+		}
+
+		for _, user := range fetched {
+			found[user.ID] = user
+			if err := s.cache.Set(ctx, userCacheKey(ctx, user.ID), user, 0); err != nil {
+				s.logger.Warn().Err(err).Msg("Failed to cache user")
+			}
+		}
+		for _, id := range misses {
+			if _, ok := found[id]; !ok {
+				s.cacheMissingUser(ctx, id)
+			}
+		}
+	}
+
+	users := make([]domain.User, 0, len(found))
+	for _, id := range ids {
+		if user, ok := found[id]; ok {
+			users = append(users, user)
+			delete(found, id) // keep duplicates out of the response
+		}
+	}
+
+	return users, nil
+}
+
 func (s *userService) UpdateProfile(ctx context.Context, userID int32, updates map[string]interface{}) error {
 	// Invalidate cache
-	cacheKey := fmt.Sprintf("user:%d", userID)
+	cacheKey := userCacheKey(ctx, userID)
 	if err := s.cache.Delete(ctx, cacheKey); err != nil {
 		s.logger.Warn().Err(err).Msg("Failed to invalidate cache")
 	}
@@ -74,7 +289,7 @@ func (s *userService) UpdateProfile(ctx context.Context, userID int32, updates m
 
 func (s *userService) DeleteProfile(ctx context.Context, userID int32) error {
 	// Invalidate cache
-	cacheKey := fmt.Sprintf("user:%d", userID)
+	cacheKey := userCacheKey(ctx, userID)
 	if err := s.cache.Delete(ctx, cacheKey); err != nil {
 		s.logger.Warn().Err(err).Msg("Failed to invalidate cache")
 	}
@@ -96,4 +311,24 @@ func (s *userService) ListUsers(ctx context.Context, limit, offset int) ([]domai
 	}
 
 	return users, nil
+}
+
+func (s *userService) SearchUsers(ctx context.Context, query string, limit, offset int) ([]domain.User, int64, error) {
+	users, total, err := s.repo.SearchUsers(ctx, query, limit, offset)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to search users")
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (s *userService) GetCollectionStats(ctx context.Context) (domain.UserCollectionStats, error) {
+	stats, err := s.repo.GetCollectionStats(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to get user collection stats")
+		return domain.UserCollectionStats{}, err
+	}
+
+	return stats, nil
 }
\ No newline at end of file
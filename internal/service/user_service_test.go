@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/cache"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+	"user-auth-app/internal/tenant"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// mapCache is an in-memory cache.Service that round-trips values through
+// JSON like the real cache does
+type mapCache struct {
+	cache.Service
+	values map[string][]byte
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{values: make(map[string][]byte)}
+}
+
+func (c *mapCache) Get(ctx context.Context, key string, dest interface{}) error {
+	data, ok := c.values[key]
+	if !ok {
+		return errors.New("cache miss")
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func (c *mapCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.values[key] = data
+	return nil
+}
+
+func (c *mapCache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	if _, ok := c.values[key]; ok {
+		return false, nil
+	}
+	return true, c.Set(ctx, key, value, ttl)
+}
+
+func (c *mapCache) Delete(ctx context.Context, key string) error {
+	delete(c.values, key)
+	return nil
+}
+
+// batchUserRepo serves users by ID and records which IDs were queried
+type batchUserRepo struct {
+	repository.UserRepository
+	users   map[int32]domain.User
+	queried [][]int32
+}
+
+func (r *batchUserRepo) GetUsersByIDs(ctx context.Context, ids []int32) ([]domain.User, error) {
+	r.queried = append(r.queried, ids)
+	var users []domain.User
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func TestGetUsersByIDsOnlyFetchesMisses(t *testing.T) {
+	logger := zerolog.Nop()
+	c := newMapCache()
+	repo := &batchUserRepo{users: map[int32]domain.User{
+		1: {ID: 1, Username: "alice"},
+		2: {ID: 2, Username: "bob"},
+		3: {ID: 3, Username: "carol"},
+	}}
+	s := NewUserService(repo, c, &logger, 0, nil, 0, nil)
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, userCacheKey(ctx, 2), domain.User{ID: 2, Username: "bob"}, 0))
+
+	users, err := s.GetUsersByIDs(ctx, []int32{3, 2, 99, 1, 3})
+	require.NoError(t, err)
+
+	var names []string
+	for _, user := range users {
+		names = append(names, user.Username)
+	}
+	assert.Equal(t, []string{"carol", "bob", "alice"}, names, "request order, no duplicates, unknown IDs skipped")
+	assert.Equal(t, [][]int32{{3, 99, 1}}, repo.queried, "one query for the misses only")
+
+	for _, id := range []int32{1, 3} {
+		assert.Contains(t, c.values, userCacheKey(ctx, id))
+	}
+
+	// Everything known is now cached
+	repo.queried = nil
+	_, err = s.GetUsersByIDs(ctx, []int32{1, 2, 3})
+	require.NoError(t, err)
+	assert.Empty(t, repo.queried)
+}
+
+func TestCachedUsersAreScopedToTenant(t *testing.T) {
+	logger := zerolog.Nop()
+	c := newMapCache()
+	repo := &batchUserRepo{users: map[int32]domain.User{}}
+	s := NewUserService(repo, c, &logger, 0, nil, 0, nil)
+
+	acme := tenant.WithID(context.Background(), "acme")
+	require.NoError(t, c.Set(acme, userCacheKey(acme, 1), domain.User{ID: 1, TenantID: "acme"}, 0))
+
+	users, err := s.GetUsersByIDs(tenant.WithID(context.Background(), "globex"), []int32{1})
+	require.NoError(t, err)
+	assert.Empty(t, users, "another tenant's cached user must not be served")
+	assert.Equal(t, [][]int32{{1}}, repo.queried)
+}
+
+func TestGetProfileAuditsReadsOfOtherUsers(t *testing.T) {
+	logger := zerolog.Nop()
+	sink := audit.NewMemorySink()
+	repo := &fakeUserRepo{user: domain.User{ID: 2, Username: "bob"}}
+	s := NewUserService(repo, newMapCache(), &logger, 0, sink, 0, nil)
+	ctx := audit.WithClient(context.Background(), audit.Client{IPAddress: "203.0.113.5"})
+
+	// The second read is served from cache and audited all the same
+	for range 2 {
+		_, err := s.GetProfile(ctx, 1, 2)
+		require.NoError(t, err)
+	}
+	_, err := s.GetProfile(ctx, 2, 2)
+	require.NoError(t, err)
+	_, err = s.GetProfile(ctx, 1, 99)
+	require.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	events := sink.Events()
+	require.Len(t, events, 2, "own profile and missing users aren't audited")
+	for _, event := range events {
+		assert.Equal(t, domain.AuditProfileRead, event.Type)
+		require.NotNil(t, event.UserID)
+		assert.Equal(t, int32(1), *event.UserID)
+		assert.Equal(t, map[string]string{"target_id": "2"}, event.Details)
+		assert.Equal(t, "203.0.113.5", event.IPAddress)
+		assert.False(t, event.Timestamp.IsZero())
+	}
+}
+
+// warmUserRepo serves ListUsersToWarm and records its arguments
+type warmUserRepo struct {
+	repository.UserRepository
+	users []domain.User
+	ids   []int32
+	limit int
+}
+
+func (r *warmUserRepo) ListUsersToWarm(ctx context.Context, ids []int32, limit int) ([]domain.User, error) {
+	r.ids, r.limit = ids, limit
+	return r.users[:min(limit, len(r.users))], nil
+}
+
+func TestWarmCache(t *testing.T) {
+	logger := zerolog.Nop()
+	acme := tenant.WithID(context.Background(), "acme")
+	globex := tenant.WithID(context.Background(), "globex")
+
+	t.Run("most recently active users", func(t *testing.T) {
+		c := newMapCache()
+		repo := &warmUserRepo{users: []domain.User{
+			{ID: 1, TenantID: "acme", Username: "alice"},
+			{ID: 2, TenantID: "globex", Username: "bob"},
+			{ID: 3, TenantID: "acme", Username: "carol"},
+		}}
+		s := NewUserService(repo, c, &logger, 0, nil, 2, nil)
+
+		// A live request cached a fresher alice first
+		require.NoError(t, c.Set(acme, userCacheKey(acme, 1), domain.User{ID: 1, Username: "alice2"}, 0))
+
+		require.NoError(t, s.WarmCache(context.Background()))
+		assert.Empty(t, repo.ids)
+		assert.Equal(t, 2, repo.limit)
+
+		var user domain.User
+		require.NoError(t, c.Get(acme, userCacheKey(acme, 1), &user))
+		assert.Equal(t, "alice2", user.Username, "fresher entries are kept")
+		require.NoError(t, c.Get(globex, userCacheKey(globex, 2), &user))
+		assert.Equal(t, "bob", user.Username, "each user is cached under its tenant")
+		assert.NotContains(t, c.values, userCacheKey(acme, 3))
+
+		// Warmed users are served without the database
+		user, err := s.GetProfile(globex, 2, 2)
+		require.NoError(t, err)
+		assert.Equal(t, "bob", user.Username)
+	})
+
+	t.Run("configured users", func(t *testing.T) {
+		repo := &warmUserRepo{}
+		s := NewUserService(repo, newMapCache(), &logger, 0, nil, 100, []int32{7, 9})
+
+		require.NoError(t, s.WarmCache(context.Background()))
+		assert.Equal(t, []int32{7, 9}, repo.ids)
+		assert.Equal(t, 2, repo.limit)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := NewUserService(&warmUserRepo{}, newMapCache(), &logger, 0, nil, 0, nil)
+		assert.NoError(t, s.WarmCache(context.Background()), "the repository isn't queried")
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		c := newMapCache()
+		repo := &warmUserRepo{users: []domain.User{{ID: 1, TenantID: "acme"}}}
+		s := NewUserService(repo, c, &logger, 0, nil, 10, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, s.WarmCache(ctx), context.Canceled)
+		assert.Empty(t, c.values)
+	})
+}
+
+// notFoundRepo has no users and counts lookups
+type notFoundRepo struct {
+	repository.UserRepository
+	calls int
+}
+
+func (r *notFoundRepo) GetUserByID(ctx context.Context, id int32) (domain.User, error) {
+	r.calls++
+	return domain.User{}, domain.ErrUserNotFound
+}
+
+func TestGetProfileCachesMissingUsers(t *testing.T) {
+	logger := zerolog.Nop()
+	ctx := context.Background()
+
+	t.Run("enabled", func(t *testing.T) {
+		repo := &notFoundRepo{}
+		s := NewUserService(repo, newMapCache(), &logger, time.Minute, nil, 0, nil)
+
+		for i := 0; i < 3; i++ {
+			_, err := s.GetProfile(ctx, 99, 99)
+			assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		}
+		assert.Equal(t, 1, repo.calls, "repeated probes are served from cache")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		repo := &notFoundRepo{}
+		s := NewUserService(repo, newMapCache(), &logger, 0, nil, 0, nil)
+
+		for i := 0; i < 3; i++ {
+			_, err := s.GetProfile(ctx, 99, 99)
+			assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		}
+		assert.Equal(t, 3, repo.calls)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		repo := &batchUserRepo{users: map[int32]domain.User{1: {ID: 1, Username: "alice"}}}
+		s := NewUserService(repo, newMapCache(), &logger, time.Minute, nil, 0, nil)
+
+		for i := 0; i < 2; i++ {
+			users, err := s.GetUsersByIDs(ctx, []int32{1, 99})
+			require.NoError(t, err)
+			assert.Len(t, users, 1)
+		}
+		assert.Equal(t, [][]int32{{1, 99}}, repo.queried)
+
+		_, err := s.GetProfile(ctx, 99, 99)
+		assert.ErrorIs(t, err, domain.ErrUserNotFound, "tombstone is shared with single lookups")
+	})
+}
+
+func TestCreatingUserClearsTombstone(t *testing.T) {
+	logger := zerolog.Nop()
+	ctx := context.Background()
+	c := newMapCache()
+	repo := &fakeUserRepo{}
+	users := NewUserService(repo, c, &logger, time.Minute, nil, 0, nil)
+
+	// The next user will get ID 1; probe it before it exists
+	_, err := users.GetProfile(ctx, 1, 1)
+	require.ErrorIs(t, err, domain.ErrUserNotFound)
+	require.Contains(t, c.values, userCacheKey(ctx, 1))
+
+	auth := newLoginTestService(repo, bcrypt.MinCost)
+	auth.cache = c
+	created, err := auth.Register(ctx, "alice", "alice@example.com", "Correct-Horse-9", "", "")
+	require.NoError(t, err)
+	require.Equal(t, int32(1), created.ID)
+
+	user, err := users.GetProfile(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+}
+
+// missCache never holds anything, like a cache whose entry just expired
+type missCache struct {
+	cache.Service
+}
+
+func (missCache) Get(ctx context.Context, key string, dest interface{}) error {
+	return cache.ErrCacheMiss
+}
+
+func (missCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return nil
+}
+
+// slowUserRepo counts GetUserByID calls, each taking delay
+type slowUserRepo struct {
+	repository.UserRepository
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (r *slowUserRepo) GetUserByID(ctx context.Context, id int32) (domain.User, error) {
+	r.calls.Add(1)
+	time.Sleep(r.delay)
+	return domain.User{ID: id, Username: "popular"}, nil
+}
+
+func TestGetProfileCoalescesConcurrentMisses(t *testing.T) {
+	logger := zerolog.Nop()
+	repo := &slowUserRepo{delay: 100 * time.Millisecond}
+	s := NewUserService(repo, missCache{}, &logger, 0, nil, 0, nil)
+
+	const callers = 50
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := s.GetProfile(context.Background(), 7, 7)
+			assert.NoError(t, err)
+			assert.Equal(t, "popular", user.Username)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), repo.calls.Load(), "one database fetch for all concurrent misses")
+
+	// Coalescing only lasts while a fetch is in flight
+	_, err := s.GetProfile(context.Background(), 7, 7)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), repo.calls.Load())
+}
+
+func TestGetProfileWaiterCanGiveUp(t *testing.T) {
+	logger := zerolog.Nop()
+	repo := &slowUserRepo{delay: 200 * time.Millisecond}
+	s := NewUserService(repo, missCache{}, &logger, 0, nil, 0, nil)
+
+	leader := make(chan error)
+	go func() {
+		_, err := s.GetProfile(context.Background(), 7, 7)
+		leader <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// A waiter whose request ends stops waiting; the fetch carries on
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := s.GetProfile(ctx, 7, 7)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.NoError(t, <-leader)
+	assert.Equal(t, int32(1), repo.calls.Load())
+}
+
+// BenchmarkGetProfileHotKeyMiss loads one expired hot key from many
+// goroutines. db_calls/op well below 1 shows concurrent misses sharing a
+// fetch; without coalescing it would be 1.
+func BenchmarkGetProfileHotKeyMiss(b *testing.B) {
+	logger := zerolog.Nop()
+	repo := &slowUserRepo{delay: time.Millisecond}
+	s := NewUserService(repo, missCache{}, &logger, 0, nil, 0, nil)
+	ctx := context.Background()
+
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := s.GetProfile(ctx, 7, 7); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+
+	b.ReportMetric(float64(repo.calls.Load())/float64(b.N), "db_calls/op")
+}
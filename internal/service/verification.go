@@ -0,0 +1,77 @@
+// Package service implements resending email verification
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/tenant"
+	"user-auth-app/internal/validator"
+)
+
+// verificationResendCacheKey marks an email whose verification was resent
+// within the cooldown
+func verificationResendCacheKey(ctx context.Context, email string) string {
+	return fmt.Sprintf("verify_resend:%s:%s", tenant.ID(ctx), email)
+}
+
+// ResendVerification publishes the verification event for an unverified
+// account. The cooldown is taken before the account is looked up, so
+// unknown and verified emails are throttled exactly like real ones and
+// repeated requests never reach the database.
+func (s *authService) ResendVerification(ctx context.Context, email string) error {
+	logger := s.loggerFromCtx(ctx)
+	email = validator.NormalizeEmail(email)
+
+	if s.cache != nil {
+		first, err := s.cache.SetNX(ctx, verificationResendCacheKey(ctx, email), true, s.verificationCooldown)
+		if err != nil {
+			// Without the cooldown the endpoint could be used to spam, so
+			// skip the resend rather than risk it
+			logger.Warn().Err(err).Msg("Failed to check verification resend cooldown")
+			authVerificationResends.WithLabelValues("error").Inc()
+			return nil
+		}
+		if !first {
+			authVerificationResends.WithLabelValues("cooldown").Inc()
+			return nil
+		}
+	}
+
+	user, _, err := s.repo.GetUserByEmail(ctx, email)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		authVerificationResends.WithLabelValues("unknown_email").Inc()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("verification resend failed: %w", err)
+	}
+	if user.EmailVerified {
+		authVerificationResends.WithLabelValues("already_verified").Inc()
+		return nil
+	}
+
+	if s.broker == nil || !s.broker.IsAvailable() {
+		logger.Warn().Int32("user_id", user.ID).Msg("Message broker unavailable, verification email not resent")
+		authVerificationResends.WithLabelValues("error").Inc()
+		return nil
+	}
+	if err := s.broker.PublishJSON(domain.EventUserVerify, map[string]interface{}{
+		"user_id":   user.ID,
+		"tenant_id": user.TenantID,
+		"email":     user.Email,
+		"username":  user.Username,
+		"timestamp": time.Now().UTC(),
+	}); err != nil {
+		logger.Error().Err(err).Int32("user_id", user.ID).Msg("Failed to publish verification event")
+		authVerificationResends.WithLabelValues("error").Inc()
+		return nil
+	}
+
+	authVerificationResends.WithLabelValues("resent").Inc()
+	logger.Info().Int32("user_id", user.ID).Msg("Verification email resend requested")
+	return nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/messaging"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// recordingBroker records the subjects of published messages
+type recordingBroker struct {
+	messaging.Broker
+	published []string
+}
+
+func (b *recordingBroker) IsAvailable() bool { return true }
+
+func (b *recordingBroker) PublishJSON(subject string, data interface{}) error {
+	b.published = append(b.published, subject)
+	return nil
+}
+
+func newVerificationTestService(verified bool) (*authService, *recordingBroker) {
+	repo := &fakeUserRepo{user: domain.User{ID: 1, Email: "user@example.com", EmailVerified: verified}}
+	broker := &recordingBroker{}
+	s := newLoginTestService(repo, bcrypt.MinCost)
+	s.cache = newMapCache()
+	s.broker = broker
+	return s, broker
+}
+
+func TestResendVerification(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unverified account", func(t *testing.T) {
+		s, broker := newVerificationTestService(false)
+		require.NoError(t, s.ResendVerification(ctx, "User@Example.com"))
+		assert.Equal(t, []string{domain.EventUserVerify}, broker.published)
+	})
+
+	t.Run("cooldown", func(t *testing.T) {
+		s, broker := newVerificationTestService(false)
+		require.NoError(t, s.ResendVerification(ctx, "user@example.com"))
+		require.NoError(t, s.ResendVerification(ctx, "USER@example.com"))
+		assert.Len(t, broker.published, 1, "the second request falls within the cooldown")
+	})
+
+	t.Run("verified account", func(t *testing.T) {
+		s, broker := newVerificationTestService(true)
+		require.NoError(t, s.ResendVerification(ctx, "user@example.com"))
+		assert.Empty(t, broker.published)
+	})
+
+	t.Run("unknown email", func(t *testing.T) {
+		s, broker := newVerificationTestService(false)
+		require.NoError(t, s.ResendVerification(ctx, "nobody@example.com"))
+		assert.Empty(t, broker.published)
+	})
+}
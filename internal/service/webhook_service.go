@@ -0,0 +1,138 @@
+// Package service implements webhook management
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"user-auth-app/internal/audit"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// webhookSecretPrefix marks webhook signing secrets so they are
+	// recognisable by secret scanners
+	webhookSecretPrefix = "whsec_"
+	// webhookSecretBytes is the entropy of a signing secret
+	webhookSecretBytes = 32
+	// webhookURLMaxLen bounds the stored endpoint URL
+	webhookURLMaxLen = 2048
+)
+
+type webhookService struct {
+	repo      repository.WebhookRepository
+	auditSink audit.Sink
+	logger    *zerolog.Logger
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(repo repository.WebhookRepository, auditSink audit.Sink, logger *zerolog.Logger) WebhookService {
+	return &webhookService{
+		repo:      repo,
+		auditSink: auditSink,
+		logger:    logger,
+	}
+}
+
+func (s *webhookService) CreateWebhook(ctx context.Context, endpoint string, events []string) (domain.Webhook, string, error) {
+	endpoint = strings.TrimSpace(endpoint)
+	fields := make(map[string]string)
+	if endpoint == "" {
+		fields["url"] = "is required"
+	} else if len(endpoint) > webhookURLMaxLen {
+		fields["url"] = fmt.Sprintf("must be at most %d characters", webhookURLMaxLen)
+	} else if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		fields["url"] = "must be an absolute http or https URL"
+	}
+
+	var subscribed []string
+	for _, event := range events {
+		if !slices.Contains(domain.WebhookEvents, event) {
+			fields["events"] = fmt.Sprintf("unknown event %q (must be one of: %s)", event, strings.Join(domain.WebhookEvents, ", "))
+			break
+		}
+		if !slices.Contains(subscribed, event) {
+			subscribed = append(subscribed, event)
+		}
+	}
+	if len(events) == 0 {
+		fields["events"] = "at least one event is required"
+	}
+	if len(fields) > 0 {
+		return domain.Webhook{}, "", domain.NewValidationError(fields)
+	}
+
+	raw := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return domain.Webhook{}, "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+	secret := webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	webhook, err := s.repo.CreateWebhook(ctx, domain.Webhook{
+		URL:    endpoint,
+		Secret: secret,
+		Events: subscribed,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to create webhook")
+		return domain.Webhook{}, "", err
+	}
+
+	recordAuditEvent(ctx, s.auditSink, s.logger, domain.AuditEvent{
+		Type:    domain.AuditWebhookCreated,
+		Success: true,
+		Details: map[string]string{"webhook_id": fmt.Sprint(webhook.ID), "events": strings.Join(subscribed, ",")},
+	})
+
+	s.logger.Info().
+		Int32("webhook_id", webhook.ID).
+		Strs("events", subscribed).
+		Msg("Webhook created")
+
+	return webhook, secret, nil
+}
+
+func (s *webhookService) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	webhooks, err := s.repo.ListWebhooks(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to list webhooks")
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, webhookID int32) error {
+	if err := s.repo.DeleteWebhook(ctx, webhookID); err != nil {
+		if !errors.Is(err, domain.ErrWebhookNotFound) {
+			s.logger.Error().Err(err).Int32("webhook_id", webhookID).Msg("Failed to delete webhook")
+		}
+		return err
+	}
+
+	recordAuditEvent(ctx, s.auditSink, s.logger, domain.AuditEvent{
+		Type:    domain.AuditWebhookDeleted,
+		Success: true,
+		Details: map[string]string{"webhook_id": fmt.Sprint(webhookID)},
+	})
+
+	s.logger.Info().Int32("webhook_id", webhookID).Msg("Webhook deleted")
+	return nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, webhookID int32, limit int) ([]domain.WebhookDelivery, error) {
+	deliveries, err := s.repo.ListWebhookDeliveries(ctx, webhookID, limit)
+	if err != nil {
+		s.logger.Error().Err(err).Int32("webhook_id", webhookID).Msg("Failed to list webhook deliveries")
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memWebhookRepo keeps webhooks in memory
+type memWebhookRepo struct {
+	webhooks []domain.Webhook
+}
+
+func (r *memWebhookRepo) CreateWebhook(ctx context.Context, webhook domain.Webhook) (domain.Webhook, error) {
+	webhook.ID = int32(len(r.webhooks) + 1)
+	r.webhooks = append(r.webhooks, webhook)
+	return webhook, nil
+}
+
+func (r *memWebhookRepo) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	return r.webhooks, nil
+}
+
+func (r *memWebhookRepo) ListWebhooksForEvent(ctx context.Context, event string) ([]domain.Webhook, error) {
+	return r.webhooks, nil
+}
+
+func (r *memWebhookRepo) DeleteWebhook(ctx context.Context, webhookID int32) error {
+	for i, webhook := range r.webhooks {
+		if webhook.ID == webhookID {
+			r.webhooks = append(r.webhooks[:i], r.webhooks[i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrWebhookNotFound
+}
+
+func (r *memWebhookRepo) RecordWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	return nil
+}
+
+func (r *memWebhookRepo) ListWebhookDeliveries(ctx context.Context, webhookID int32, limit int) ([]domain.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (r *memWebhookRepo) PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// recordingNotifier collects the events it is notified of
+type recordingNotifier struct {
+	events []string
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, event string, data interface{}) {
+	n.events = append(n.events, event)
+}
+
+func TestCreateWebhook(t *testing.T) {
+	logger := zerolog.Nop()
+	repo := &memWebhookRepo{}
+	svc := NewWebhookService(repo, nil, &logger)
+
+	webhook, secret, err := svc.CreateWebhook(context.Background(), " https://hooks.example.com/auth ",
+		[]string{domain.EventUserRegistered, domain.EventUserRegistered, domain.EventUserPasswordChanged})
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(secret, webhookSecretPrefix))
+	assert.Equal(t, secret, repo.webhooks[0].Secret)
+	assert.Equal(t, "https://hooks.example.com/auth", webhook.URL)
+	assert.Equal(t, []string{domain.EventUserRegistered, domain.EventUserPasswordChanged}, webhook.Events)
+
+	_, other, err := svc.CreateWebhook(context.Background(), "https://hooks.example.com/other", []string{domain.EventUserRegistered})
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+}
+
+func TestCreateWebhookValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		events []string
+		field  string
+	}{
+		{name: "missing url", url: "", events: []string{domain.EventUserRegistered}, field: "url"},
+		{name: "relative url", url: "/hooks", events: []string{domain.EventUserRegistered}, field: "url"},
+		{name: "other scheme", url: "ftp://hooks.example.com", events: []string{domain.EventUserRegistered}, field: "url"},
+		{name: "no events", url: "https://hooks.example.com", field: "events"},
+		{name: "unknown event", url: "https://hooks.example.com", events: []string{"user.deleted"}, field: "events"},
+	}
+
+	logger := zerolog.Nop()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memWebhookRepo{}
+			svc := NewWebhookService(repo, nil, &logger)
+
+			_, _, err := svc.CreateWebhook(context.Background(), tt.url, tt.events)
+
+			var appErr *domain.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Contains(t, appErr.Fields, tt.field)
+			assert.Empty(t, repo.webhooks)
+		})
+	}
+}
+
+func TestPublishEventNotifiesWebhooks(t *testing.T) {
+	logger := zerolog.Nop()
+	notifier := &recordingNotifier{}
+	s := &authService{webhooks: notifier, logger: &logger}
+
+	s.publishEvent(context.Background(), domain.EventUserPasswordChanged, map[string]interface{}{"user_id": int32(1)})
+
+	assert.Equal(t, []string{domain.EventUserPasswordChanged}, notifier.events)
+}
@@ -0,0 +1,54 @@
+// Package signing renders public keys as a JSON Web Key Set
+package signing
+
+import (
+	"math/big"
+	"sort"
+)
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys in the set, current key first. Symmetric
+// keys are never included.
+func (s *KeySet) JWKS() JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jwks := JWKS{Keys: []JWK{}}
+	for id, key := range s.keys {
+		if key.public == nil || s.activeKey(id) == nil {
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, JWK{
+			KeyType:   "RSA",
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+			KeyID:     key.ID,
+			N:         base64URL(key.public.N.Bytes()),
+			E:         base64URL(big.NewInt(int64(key.public.E)).Bytes()),
+		})
+	}
+
+	currentID := s.current.ID
+	sort.SliceStable(jwks.Keys, func(i, j int) bool {
+		if jwks.Keys[i].KeyID == currentID || jwks.Keys[j].KeyID == currentID {
+			return jwks.Keys[i].KeyID == currentID
+		}
+		return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID
+	})
+
+	return jwks
+}
@@ -0,0 +1,125 @@
+// Package signing manages the keys used to sign and verify JWTs
+package signing
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a JWT signing key identified by its key ID (kid)
+type Key struct {
+	// ID is stamped in the kid header of tokens signed with this key
+	ID     string
+	Method jwt.SigningMethod
+
+	signKey   interface{}
+	verifyKey interface{}
+	public    *rsa.PublicKey
+}
+
+// MinHMACSecretBytes is the shortest accepted HS256 secret
+const MinHMACSecretBytes = 32
+
+// NewHMACKey creates a symmetric HS256 key. Its ID is derived from a
+// hash of the secret, so every replica sharing the secret agrees on it.
+func NewHMACKey(secret []byte) *Key {
+	sum := sha256.Sum256(secret)
+	return &Key{
+		ID:        "hs-" + base64URL(sum[:12]),
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// NewRSAKey creates an RS256 signing key. Its ID is the RFC 7638
+// thumbprint of the public key, so it is stable across restarts.
+func NewRSAKey(private *rsa.PrivateKey) *Key {
+	key := NewRSAPublicKey(&private.PublicKey)
+	key.signKey = private
+	return key
+}
+
+// NewRSAPublicKey creates a verification-only RS256 key, e.g. a retired
+// key whose tokens haven't expired yet
+func NewRSAPublicKey(public *rsa.PublicKey) *Key {
+	return &Key{
+		ID:        rsaThumbprint(public),
+		Method:    jwt.SigningMethodRS256,
+		verifyKey: public,
+		public:    public,
+	}
+}
+
+// CanSign reports whether the key holds private material
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// LoadHMACKey reads an HS256 secret from a file. Surrounding whitespace,
+// such as a trailing newline, is ignored.
+func LoadHMACKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read secret: %w", err)
+	}
+
+	secret := bytes.TrimSpace(data)
+	if len(secret) < MinHMACSecretBytes {
+		return nil, fmt.Errorf("secret in %s must be at least %d bytes long", path, MinHMACSecretBytes)
+	}
+
+	return NewHMACKey(secret), nil
+}
+
+// LoadRSAPrivateKey reads a PEM-encoded RSA private key (PKCS#1 or PKCS#8)
+func LoadRSAPrivateKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+
+	private, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key %s: %w", path, err)
+	}
+
+	return NewRSAKey(private), nil
+}
+
+// LoadRSAPublicKey reads a PEM-encoded RSA public key or certificate
+func LoadRSAPublicKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read public key: %w", err)
+	}
+
+	public, err := jwt.ParseRSAPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key %s: %w", path, err)
+	}
+
+	return NewRSAPublicKey(public), nil
+}
+
+// rsaThumbprint computes the RFC 7638 JWK thumbprint of an RSA key
+func rsaThumbprint(public *rsa.PublicKey) string {
+	// Members in lexicographic order, no whitespace
+	canonical := fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`,
+		base64URL(big.NewInt(int64(public.E)).Bytes()),
+		base64URL(public.N.Bytes()),
+	)
+	sum := sha256.Sum256([]byte(canonical))
+	return base64URL(sum[:])
+}
+
+func base64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,215 @@
+// Package signing implements a set of JWT keys for signing and verification
+package signing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrUnknownKey is returned when a token's kid doesn't match any key
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySet holds the current signing key and the keys still accepted for
+// verification. Tokens are signed with the current key; any key in the
+// set verifies tokens carrying its kid.
+//
+// Rotating to a new key keeps the old one for verification until the
+// tokens it signed have expired, after which it is retired automatically.
+// Other verification keys are kept until replaced with SetPrevious.
+type KeySet struct {
+	mu       sync.RWMutex
+	current  *Key
+	keys     map[string]*Key
+	retireAt map[string]time.Time
+	now      func() time.Time
+}
+
+// KeyInfo describes a key in the set
+type KeyInfo struct {
+	ID        string
+	Algorithm string
+	Current   bool
+	// RetiresAt is zero for keys kept until they are no longer configured
+	RetiresAt time.Time
+}
+
+// NewKeySet creates a key set that signs with current and also verifies
+// tokens signed by previous
+func NewKeySet(current *Key, previous ...*Key) (*KeySet, error) {
+	if current == nil || !current.CanSign() {
+		return nil, errors.New("current signing key must include private material")
+	}
+
+	s := &KeySet{
+		current:  current,
+		keys:     map[string]*Key{current.ID: current},
+		retireAt: make(map[string]time.Time),
+		now:      time.Now,
+	}
+	for _, key := range previous {
+		if _, exists := s.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		s.keys[key.ID] = key
+	}
+
+	return s, nil
+}
+
+// Sign signs claims with the current key, stamping its kid in the header
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	s.mu.RLock()
+	key := s.current
+	s.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.signKey)
+}
+
+// Keyfunc selects the verification key for a token by its kid header. It
+// is meant to be passed to jwt.Parse. Tokens without a kid, issued before
+// key IDs existed, are checked against the current key.
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	s.mu.RLock()
+	key := s.current
+	if kid != "" {
+		key = s.activeKey(kid)
+	}
+	s.mu.RUnlock()
+	if key == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+
+	// The key, not the token, decides the algorithm
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.verifyKey, nil
+}
+
+// ValidMethods lists the algorithms of all keys in the set
+func (s *KeySet) ValidMethods() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var methods []string
+	for _, key := range s.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// CurrentID returns the kid of the signing key
+func (s *KeySet) CurrentID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.ID
+}
+
+// Rotate makes next the signing key. The previous signing key keeps
+// verifying tokens for grace, which should be the token lifetime, and is
+// then retired. Rotating to the current key is a no-op.
+func (s *KeySet) Rotate(next *Key, grace time.Duration) error {
+	if next == nil || !next.CanSign() {
+		return errors.New("signing key must include private material")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune()
+	if next.ID == s.current.ID {
+		return nil
+	}
+
+	s.retireAt[s.current.ID] = s.now().Add(grace)
+	s.current = next
+	s.keys[next.ID] = next
+	delete(s.retireAt, next.ID)
+
+	return nil
+}
+
+// SetPrevious replaces the keys accepted only for verification with
+// previous. Keys left out stop verifying tokens at once. A previous
+// signing key within its grace period after Rotate is kept either way.
+func (s *KeySet) SetPrevious(previous ...*Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.keys {
+		if _, retiring := s.retireAt[id]; !retiring && id != s.current.ID {
+			delete(s.keys, id)
+		}
+	}
+	for _, key := range previous {
+		if _, exists := s.keys[key.ID]; !exists {
+			s.keys[key.ID] = key
+		}
+	}
+}
+
+// Keys describes the keys currently accepted, signing key first
+func (s *KeySet) Keys() []KeyInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var infos []KeyInfo
+	for id, key := range s.keys {
+		if s.activeKey(id) == nil {
+			continue
+		}
+		infos = append(infos, KeyInfo{
+			ID:        id,
+			Algorithm: key.Method.Alg(),
+			Current:   key == s.current,
+			RetiresAt: s.retireAt[id],
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Current != infos[j].Current {
+			return infos[i].Current
+		}
+		return infos[i].ID < infos[j].ID
+	})
+
+	return infos
+}
+
+// activeKey returns the key with the given ID unless it is missing or
+// past its retirement time. Callers must hold s.mu.
+func (s *KeySet) activeKey(id string) *Key {
+	key, ok := s.keys[id]
+	if !ok {
+		return nil
+	}
+	if at, retiring := s.retireAt[id]; retiring && !s.now().Before(at) {
+		return nil
+	}
+	return key
+}
+
+// prune drops retired keys. Callers must hold s.mu for writing.
+func (s *KeySet) prune() {
+	for id := range s.retireAt {
+		if s.activeKey(id) == nil {
+			delete(s.keys, id)
+			delete(s.retireAt, id)
+		}
+	}
+}
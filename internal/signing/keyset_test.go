@@ -0,0 +1,212 @@
+package signing
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func TestRS256TokenCarriesJWKSKeyID(t *testing.T) {
+	keys, err := NewKeySet(NewRSAKey(generateRSAKey(t)))
+	require.NoError(t, err)
+
+	signed, err := keys.Sign(jwt.MapClaims{"user_id": 1})
+	require.NoError(t, err)
+
+	token, err := jwt.Parse(signed, keys.Keyfunc)
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", token.Header["alg"])
+
+	jwks := keys.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, token.Header["kid"], jwks.Keys[0].KeyID)
+	assert.Equal(t, "RSA", jwks.Keys[0].KeyType)
+	assert.Equal(t, "sig", jwks.Keys[0].Use)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+}
+
+func TestPreviousKeysStillVerify(t *testing.T) {
+	oldPrivate := generateRSAKey(t)
+	oldKeys, err := NewKeySet(NewRSAKey(oldPrivate))
+	require.NoError(t, err)
+	oldToken, err := oldKeys.Sign(jwt.MapClaims{"user_id": 1})
+	require.NoError(t, err)
+
+	current := NewRSAKey(generateRSAKey(t))
+	keys, err := NewKeySet(current, NewRSAPublicKey(&oldPrivate.PublicKey))
+	require.NoError(t, err)
+
+	_, err = jwt.Parse(oldToken, keys.Keyfunc)
+	assert.NoError(t, err)
+
+	jwks := keys.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, current.ID, jwks.Keys[0].KeyID, "current key is listed first")
+
+	// Once the old key is dropped its tokens are rejected
+	keys, err = NewKeySet(current)
+	require.NoError(t, err)
+	_, err = jwt.Parse(oldToken, keys.Keyfunc)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyfuncRejectsAlgorithmMismatch(t *testing.T) {
+	private := generateRSAKey(t)
+	rsaKey := NewRSAKey(private)
+	keys, err := NewKeySet(rsaKey)
+	require.NoError(t, err)
+
+	// Classic confusion attack: HS256 keyed with the public key bytes
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1})
+	forged.Header["kid"] = rsaKey.ID
+	signed, err := forged.SignedString(x509.MarshalPKCS1PublicKey(&private.PublicKey))
+	require.NoError(t, err)
+
+	_, err = jwt.Parse(signed, keys.Keyfunc)
+	assert.Error(t, err)
+}
+
+func TestHMACKeysAreNeverPublished(t *testing.T) {
+	keys, err := NewKeySet(NewHMACKey([]byte("test-secret-key-min-32-characters-long")))
+	require.NoError(t, err)
+
+	signed, err := keys.Sign(jwt.MapClaims{"user_id": 1})
+	require.NoError(t, err)
+
+	token, err := jwt.Parse(signed, keys.Keyfunc)
+	require.NoError(t, err)
+	assert.Equal(t, keys.CurrentID(), token.Header["kid"])
+
+	assert.Empty(t, keys.JWKS().Keys)
+}
+
+func TestTokensWithoutKeyIDUseCurrentKey(t *testing.T) {
+	secret := []byte("test-secret-key-min-32-characters-long")
+	keys, err := NewKeySet(NewHMACKey(secret))
+	require.NoError(t, err)
+
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1}).SignedString(secret)
+	require.NoError(t, err)
+
+	_, err = jwt.Parse(legacy, keys.Keyfunc)
+	assert.NoError(t, err)
+}
+
+func TestRotateKeepsOldKeyForGracePeriod(t *testing.T) {
+	now := time.Now()
+	oldKey := NewHMACKey([]byte("old-secret-key-min-32-characters-long"))
+	keys, err := NewKeySet(oldKey)
+	require.NoError(t, err)
+	keys.now = func() time.Time { return now }
+
+	oldToken, err := keys.Sign(jwt.MapClaims{"user_id": 1})
+	require.NoError(t, err)
+
+	newKey := NewHMACKey([]byte("new-secret-key-min-32-characters-long"))
+	require.NoError(t, keys.Rotate(newKey, time.Hour))
+	assert.Equal(t, newKey.ID, keys.CurrentID())
+
+	newToken, err := keys.Sign(jwt.MapClaims{"user_id": 1})
+	require.NoError(t, err)
+	token, err := jwt.Parse(newToken, keys.Keyfunc)
+	require.NoError(t, err)
+	assert.Equal(t, newKey.ID, token.Header["kid"])
+
+	_, err = jwt.Parse(oldToken, keys.Keyfunc)
+	assert.NoError(t, err, "old tokens verify during the grace period")
+
+	infos := keys.Keys()
+	require.Len(t, infos, 2)
+	assert.True(t, infos[0].Current)
+	assert.Equal(t, now.Add(time.Hour), infos[1].RetiresAt)
+
+	// Rotating to the same key changes nothing
+	require.NoError(t, keys.Rotate(newKey, time.Hour))
+	assert.Len(t, keys.Keys(), 2)
+
+	now = now.Add(time.Hour)
+	_, err = jwt.Parse(oldToken, keys.Keyfunc)
+	assert.ErrorIs(t, err, ErrUnknownKey, "old key is retired after the grace period")
+	assert.Len(t, keys.Keys(), 1)
+}
+
+func TestSetPrevious(t *testing.T) {
+	now := time.Now()
+	first := NewHMACKey([]byte("first-secret-key-min-32-characters-long"))
+	extraSecret := []byte("extra-secret-key-min-32-characters-long")
+	extra := NewHMACKey(extraSecret)
+	keys, err := NewKeySet(first, extra)
+	require.NoError(t, err)
+	keys.now = func() time.Time { return now }
+
+	extraToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1})
+	extraToken.Header["kid"] = extra.ID
+	signed, err := extraToken.SignedString(extraSecret)
+	require.NoError(t, err)
+	_, err = jwt.Parse(signed, keys.Keyfunc)
+	require.NoError(t, err)
+
+	second := NewHMACKey([]byte("second-secret-key-min-32-characters-long"))
+	require.NoError(t, keys.Rotate(second, time.Hour))
+
+	// A key no longer configured stops verifying at once, while the
+	// rotated-out signing key keeps its grace period
+	keys.SetPrevious()
+	infos := keys.Keys()
+	require.Len(t, infos, 2)
+	assert.Equal(t, second.ID, infos[0].ID)
+	assert.Equal(t, first.ID, infos[1].ID)
+
+	_, err = jwt.Parse(signed, keys.Keyfunc)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	keys.SetPrevious(extra)
+	assert.Len(t, keys.Keys(), 3)
+}
+
+func TestLoadRSAKeys(t *testing.T) {
+	private := generateRSAKey(t)
+	dir := t.TempDir()
+
+	privatePath := filepath.Join(dir, "private.pem")
+	require.NoError(t, os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(private),
+	}), 0o600))
+
+	publicDER, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	require.NoError(t, err)
+	publicPath := filepath.Join(dir, "public.pem")
+	require.NoError(t, os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicDER,
+	}), 0o644))
+
+	signer, err := LoadRSAPrivateKey(privatePath)
+	require.NoError(t, err)
+	assert.True(t, signer.CanSign())
+
+	verifier, err := LoadRSAPublicKey(publicPath)
+	require.NoError(t, err)
+	assert.False(t, verifier.CanSign())
+	assert.Equal(t, signer.ID, verifier.ID, "kid depends only on the public key")
+
+	_, err = NewKeySet(verifier)
+	assert.Error(t, err, "a public key can't be the signing key")
+}
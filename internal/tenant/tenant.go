@@ -0,0 +1,43 @@
+// Package tenant carries the tenant a request belongs to through the
+// context
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// Default is the tenant used when a request names none. Single-tenant
+// deployments keep every user in it.
+const Default = "default"
+
+// idPattern limits tenant IDs to a DNS label so any tenant can also be
+// addressed by subdomain
+var idPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+type contextKey struct{}
+
+// Valid reports whether id is a well-formed tenant ID: 1-63 lowercase
+// letters, digits or hyphens, not starting or ending with a hyphen
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// WithID returns a context carrying the tenant ID
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant ID stored by WithID, if any
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// ID returns the context's tenant ID, or Default if none is set
+func ID(ctx context.Context) string {
+	if id, ok := FromContext(ctx); ok {
+		return id
+	}
+	return Default
+}
@@ -0,0 +1,78 @@
+// Package validator implements password policy checks
+package validator
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// commonPasswords holds the embedded list, lowercased
+var commonPasswords = parseCommonPasswords(commonPasswordsFile)
+
+func parseCommonPasswords(data string) map[string]struct{} {
+	passwords := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[strings.ToLower(line)] = struct{}{}
+	}
+	return passwords
+}
+
+// isCommonPassword reports whether password appears in the embedded list,
+// ignoring case
+func isCommonPassword(password string) bool {
+	_, ok := commonPasswords[strings.ToLower(password)]
+	return ok
+}
+
+// Character classes a policy can require
+const (
+	ClassUppercase = "uppercase"
+	ClassLowercase = "lowercase"
+	ClassDigit     = "digit"
+	ClassSpecial   = "special"
+)
+
+// RequiredClasses lists the character classes the policy requires
+func (p PasswordPolicy) RequiredClasses() []string {
+	classes := []string{}
+	if p.RequireUpper {
+		classes = append(classes, ClassUppercase)
+	}
+	if p.RequireLower {
+		classes = append(classes, ClassLowercase)
+	}
+	if p.RequireDigit {
+		classes = append(classes, ClassDigit)
+	}
+	if p.RequireSpecial {
+		classes = append(classes, ClassSpecial)
+	}
+	return classes
+}
+
+// passwordClasses reports which character classes password contains
+func passwordClasses(password string) (upper, lower, digit, special bool) {
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			special = true
+		}
+	}
+	return upper, lower, digit, special
+}
@@ -5,18 +5,82 @@ import (
 	"fmt"
 	"net/mail"
 	"regexp"
+	"slices"
 	"strings"
+
+	"user-auth-app/internal/domain"
 )
 
 var (
-	// Username: 3-50 chars, alphanumeric + underscore
-	usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_]{3,50}$`)
-	// Password: min 8 chars
-	minPasswordLength = 8
+	// Username: alphanumeric + underscore, length bounded by Rules
+	usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+)
+
+// Rules holds configurable validation limits
+type Rules struct {
+	UsernameMinLen int
+	UsernameMaxLen int
+	// ReservedUsernames are rejected by ValidateUsername, whatever their
+	// case
+	ReservedUsernames []string
+	// AllowedRoles are the roles ValidateRole accepts
+	AllowedRoles []string
+	Password     PasswordPolicy
+}
+
+// PasswordPolicy describes the requirements a password must meet
+type PasswordPolicy struct {
+	MinLength      int
+	MaxLength      int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSpecial bool
+	// RejectCommon rejects passwords on the embedded common-password list
+	RejectCommon bool
+	// BreachCheck reports whether passwords are checked against known
+	// breaches. It is enforced by the auth service, not the validator.
+	BreachCheck bool
+}
+
+// DefaultRules returns the built-in validation limits
+func DefaultRules() Rules {
+	return Rules{
+		UsernameMinLen:    domain.DefaultUsernameMinLen,
+		UsernameMaxLen:    domain.DefaultUsernameMaxLen,
+		ReservedUsernames: domain.DefaultReservedUsernames,
+		AllowedRoles:      domain.DefaultRoles,
+		Password: PasswordPolicy{
+			MinLength:      domain.DefaultPasswordMinLen,
+			MaxLength:      domain.DefaultPasswordMaxLen,
+			RequireUpper:   true,
+			RequireLower:   true,
+			RequireDigit:   true,
+			RequireSpecial: true,
+			RejectCommon:   true,
+		},
+	}
+}
+
+// Validation failure codes. Codes are stable, low-cardinality identifiers
+// that are safe to log and use as metric labels, unlike messages and the
+// submitted values.
+const (
+	CodeRequired       = "required"
+	CodeInvalidFormat  = "invalid_format"
+	CodeTooShort       = "too_short"
+	CodeTooLong        = "too_long"
+	CodeMissingUpper   = "missing_uppercase"
+	CodeMissingLower   = "missing_lowercase"
+	CodeMissingDigit   = "missing_digit"
+	CodeMissingSpecial = "missing_special"
+	CodeTooCommon      = "too_common"
+	CodeNotAllowed     = "not_allowed"
 )
 
 type ValidationError struct {
 	Field   string
+	Code    string
 	Message string
 }
 
@@ -26,14 +90,19 @@ func (e ValidationError) Error() string {
 
 type Validator struct {
 	errors []ValidationError
+	rules  Rules
 }
 
 func New() *Validator {
-	return &Validator{errors: []ValidationError{}}
+	return NewWithRules(DefaultRules())
+}
+
+func NewWithRules(rules Rules) *Validator {
+	return &Validator{errors: []ValidationError{}, rules: rules}
 }
 
-func (v *Validator) AddError(field, message string) {
-	v.errors = append(v.errors, ValidationError{Field: field, Message: message})
+func (v *Validator) AddError(field, code, message string) {
+	v.errors = append(v.errors, ValidationError{Field: field, Code: code, Message: message})
 }
 
 func (v *Validator) Valid() bool {
@@ -47,44 +116,118 @@ func (v *Validator) Errors() []ValidationError {
 func (v *Validator) ValidateEmail(field, email string) {
 	email = strings.TrimSpace(email)
 	if email == "" {
-		v.AddError(field, "is required")
+		v.AddError(field, CodeRequired, "is required")
 		return
 	}
 	if _, err := mail.ParseAddress(email); err != nil {
-		v.AddError(field, "is not a valid email address")
+		v.AddError(field, CodeInvalidFormat, "is not a valid email address")
 	}
 }
 
+// NormalizeEmail returns the canonical stored form of an email address:
+// surrounding whitespace removed and lowercased. Emails are compared
+// case-insensitively everywhere, so every lookup and write goes through it.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IsEmail reports whether s is a bare email address (no display name)
+func IsEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// NormalizeUsername returns the canonical stored form of a username:
+// surrounding whitespace removed and lowercased. Like emails, usernames
+// are unique regardless of case, so every lookup and write goes through
+// it.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// ValidateUsername checks the username's length and characters, and that
+// it isn't reserved
 func (v *Validator) ValidateUsername(field, username string) {
 	username = strings.TrimSpace(username)
 	if username == "" {
-		v.AddError(field, "is required")
+		v.AddError(field, CodeRequired, "is required")
 		return
 	}
-	if !usernameRegex.MatchString(username) {
-		v.AddError(field, "must be 3-50 characters and contain only letters, numbers, and underscores")
+	if len(username) < v.rules.UsernameMinLen || len(username) > v.rules.UsernameMaxLen || !usernameRegex.MatchString(username) {
+		v.AddError(field, CodeInvalidFormat, fmt.Sprintf("must be %d-%d characters and contain only letters, numbers, and underscores",
+			v.rules.UsernameMinLen, v.rules.UsernameMaxLen))
+		return
+	}
+	if IsReservedUsername(username, v.rules.ReservedUsernames) {
+		v.AddError(field, CodeNotAllowed, "is reserved")
+	}
+}
+
+// IsReservedUsername reports whether username matches one of reserved,
+// whatever its case
+func IsReservedUsername(username string, reserved []string) bool {
+	normalized := NormalizeUsername(username)
+	for _, name := range reserved {
+		if NormalizeUsername(name) == normalized {
+			return true
+		}
 	}
+	return false
 }
 
+// ValidatePassword checks password against the configured policy, adding
+// one error per failed rule
 func (v *Validator) ValidatePassword(field, password string) {
-	if len(password) < minPasswordLength {
-		v.AddError(field, fmt.Sprintf("must be at least %d characters long", minPasswordLength))
+	policy := v.rules.Password
+	if len(password) < policy.MinLength {
+		v.AddError(field, CodeTooShort, fmt.Sprintf("must be at least %d characters long", policy.MinLength))
+	}
+	if len(password) > policy.MaxLength {
+		v.AddError(field, CodeTooLong, fmt.Sprintf("must be at most %d characters long", policy.MaxLength))
+	}
+
+	upper, lower, digit, special := passwordClasses(password)
+	if policy.RequireUpper && !upper {
+		v.AddError(field, CodeMissingUpper, "must contain at least one uppercase letter")
+	}
+	if policy.RequireLower && !lower {
+		v.AddError(field, CodeMissingLower, "must contain at least one lowercase letter")
+	}
+	if policy.RequireDigit && !digit {
+		v.AddError(field, CodeMissingDigit, "must contain at least one digit")
+	}
+	if policy.RequireSpecial && !special {
+		v.AddError(field, CodeMissingSpecial, "must contain at least one special character")
+	}
+
+	if policy.RejectCommon && isCommonPassword(password) {
+		v.AddError(field, CodeTooCommon, "is too common")
 	}
 }
 
+// ValidateRole checks role against the allowed roles. An empty role is
+// valid; the user gets the default role.
 func (v *Validator) ValidateRole(field, role string) {
-	validRoles := map[string]bool{"user": true, "admin": true, "moderator": true}
 	if role == "" {
-		role = "user" // default
 		return
 	}
-	if !validRoles[role] {
-		v.AddError(field, "must be one of: user, admin, moderator")
+	if !slices.Contains(v.rules.AllowedRoles, role) {
+		v.AddError(field, CodeNotAllowed, "must be one of: "+strings.Join(v.rules.AllowedRoles, ", "))
+	}
+}
+
+// ValidateSelfAssignedRole checks a role users pick for themselves. Only
+// admins can make someone an admin, so admin is never accepted here.
+func (v *Validator) ValidateSelfAssignedRole(field, role string) {
+	if role == domain.RoleAdmin {
+		v.AddError(field, CodeNotAllowed, "can only be assigned by an administrator")
+		return
 	}
+	v.ValidateRole(field, role)
 }
 
 func (v *Validator) ValidateRequired(field, value string) {
 	if strings.TrimSpace(value) == "" {
-		v.AddError(field, "is required")
+		v.AddError(field, CodeRequired, "is required")
 	}
 }
@@ -0,0 +1,181 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateUsernameLengthBoundaries(t *testing.T) {
+	rules := Rules{UsernameMinLen: 4, UsernameMaxLen: 10}
+
+	tests := []struct {
+		name     string
+		username string
+		valid    bool
+	}{
+		{"min-1", strings.Repeat("a", 3), false},
+		{"min", strings.Repeat("a", 4), true},
+		{"max", strings.Repeat("a", 10), true},
+		{"max+1", strings.Repeat("a", 11), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewWithRules(rules)
+			v.ValidateUsername("username", tt.username)
+			assert.Equal(t, tt.valid, v.Valid())
+		})
+	}
+}
+
+func TestValidateUsernameDefaultRules(t *testing.T) {
+	v := New()
+	v.ValidateUsername("username", "ab")
+	assert.False(t, v.Valid())
+	assert.Equal(t, "must be 3-50 characters and contain only letters, numbers, and underscores", v.Errors()[0].Message)
+}
+
+func TestValidateUsernameRejectsInvalidCharacters(t *testing.T) {
+	v := New()
+	v.ValidateUsername("username", "bad-name")
+	assert.False(t, v.Valid())
+}
+
+func TestValidateUsernameRejectsReservedNames(t *testing.T) {
+	for username, valid := range map[string]bool{"admin": false, "Admin": false, " ROOT ": false, "support": false, "admins": true, "alice": true} {
+		v := New()
+		v.ValidateUsername("username", username)
+		if assert.Equal(t, valid, v.Valid(), username) && !valid {
+			assert.Equal(t, CodeNotAllowed, v.Errors()[0].Code)
+			assert.Equal(t, "is reserved", v.Errors()[0].Message)
+		}
+	}
+
+	// The list is configurable
+	rules := DefaultRules()
+	rules.ReservedUsernames = []string{"Ops"}
+	for username, valid := range map[string]bool{"ops": false, "admin": true} {
+		v := NewWithRules(rules)
+		v.ValidateUsername("username", username)
+		assert.Equal(t, valid, v.Valid(), username)
+	}
+}
+
+func TestValidatePasswordLengthBoundaries(t *testing.T) {
+	rules := DefaultRules()
+	rules.Password = PasswordPolicy{MinLength: 10, MaxLength: 20}
+
+	tests := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{"min-1", strings.Repeat("a", 9), false},
+		{"min", strings.Repeat("a", 10), true},
+		{"max", strings.Repeat("a", 20), true},
+		{"max+1", strings.Repeat("a", 21), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewWithRules(rules)
+			v.ValidatePassword("password", tt.password)
+			assert.Equal(t, tt.valid, v.Valid())
+		})
+	}
+}
+
+func TestValidatePasswordReportsEachFailedRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		messages []string
+	}{
+		{"valid", "Correct-Horse-9", nil},
+		{"no uppercase", "correct-horse-9", []string{"must contain at least one uppercase letter"}},
+		{"no lowercase", "CORRECT-HORSE-9", []string{"must contain at least one lowercase letter"}},
+		{"no digit", "Correct-Horse", []string{"must contain at least one digit"}},
+		{"no special", "CorrectHorse9", []string{"must contain at least one special character"}},
+		{"short and plain", "abc", []string{
+			"must be at least 8 characters long",
+			"must contain at least one uppercase letter",
+			"must contain at least one digit",
+			"must contain at least one special character",
+		}},
+		{"common", "P@ssw0rd1", []string{"is too common"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			v.ValidatePassword("password", tt.password)
+
+			var messages []string
+			for _, err := range v.Errors() {
+				assert.Equal(t, "password", err.Field)
+				messages = append(messages, err.Message)
+			}
+			assert.Equal(t, tt.messages, messages)
+		})
+	}
+}
+
+func TestValidatePasswordRelaxedPolicy(t *testing.T) {
+	rules := DefaultRules()
+	rules.Password = PasswordPolicy{MinLength: 8, MaxLength: 72}
+
+	v := NewWithRules(rules)
+	v.ValidatePassword("password", "password123")
+	assert.True(t, v.Valid())
+}
+
+func TestRequiredClasses(t *testing.T) {
+	assert.Equal(t, []string{ClassUppercase, ClassLowercase, ClassDigit, ClassSpecial}, DefaultRules().Password.RequiredClasses())
+	assert.Equal(t, []string{}, PasswordPolicy{}.RequiredClasses())
+}
+
+func TestIsEmail(t *testing.T) {
+	assert.True(t, IsEmail("alice@example.com"))
+	assert.False(t, IsEmail("alice"))
+	assert.False(t, IsEmail("Alice <alice@example.com>"))
+	assert.False(t, IsEmail(""))
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	assert.Equal(t, "alice@example.com", NormalizeEmail("Alice@Example.COM"))
+	assert.Equal(t, "alice@example.com", NormalizeEmail("  alice@example.com\t"))
+	assert.Equal(t, "", NormalizeEmail("   "))
+}
+
+func TestNormalizeUsername(t *testing.T) {
+	assert.Equal(t, "alice_b", NormalizeUsername("Alice_B"))
+	assert.Equal(t, "alice", NormalizeUsername("  ALICE\t"))
+}
+
+func TestValidateRoleUsesAllowlist(t *testing.T) {
+	rules := DefaultRules()
+	rules.AllowedRoles = []string{"user", "editor", "admin"}
+
+	for role, valid := range map[string]bool{"": true, "user": true, "editor": true, "admin": true, "moderator": false} {
+		v := NewWithRules(rules)
+		v.ValidateRole("role", role)
+		assert.Equal(t, valid, v.Valid(), role)
+	}
+
+	v := NewWithRules(rules)
+	v.ValidateRole("role", "moderator")
+	assert.Equal(t, "must be one of: user, editor, admin", v.Errors()[0].Message)
+}
+
+func TestValidateSelfAssignedRoleRejectsAdmin(t *testing.T) {
+	v := New()
+	v.ValidateSelfAssignedRole("role", "admin")
+	assert.False(t, v.Valid())
+	assert.Equal(t, CodeNotAllowed, v.Errors()[0].Code)
+
+	v = New()
+	v.ValidateSelfAssignedRole("role", "moderator")
+	assert.True(t, v.Valid())
+}
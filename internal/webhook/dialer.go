@@ -0,0 +1,63 @@
+// Package webhook implements the address checks of webhook delivery
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrAddressNotAllowed is returned for deliveries to an internal address
+// that isn't allowed. Retrying can't help, so such deliveries fail at once.
+var ErrAddressNotAllowed = errors.New("webhook address not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which
+// netip doesn't count as private
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// dialControl refuses connections to loopback, private, link-local and
+// other non-public addresses unless allowed contains them. It runs on the
+// address being dialled, after DNS resolution, so a public hostname that
+// resolves to an internal address is refused as well.
+func dialControl(allowed []netip.Prefix) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrAddressNotAllowed, address)
+		}
+		addr, err := netip.ParseAddr(host)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrAddressNotAllowed, address)
+		}
+		if !addressAllowed(addr.Unmap(), allowed) {
+			return fmt.Errorf("%w: %s", ErrAddressNotAllowed, addr)
+		}
+		return nil
+	}
+}
+
+// addressAllowed reports whether deliveries may connect to addr
+func addressAllowed(addr netip.Addr, allowed []netip.Prefix) bool {
+	for _, network := range allowed {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return !internalAddress(addr)
+}
+
+// internalAddress reports whether addr belongs to this host or a private
+// network rather than the public internet. Link-local covers cloud
+// metadata endpoints such as 169.254.169.254.
+func internalAddress(addr netip.Addr) bool {
+	return addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() ||
+		sharedAddressSpace.Contains(addr)
+}
@@ -0,0 +1,358 @@
+// Package webhook implements asynchronous, retried webhook delivery
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/tenant"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// queueSize bounds the events waiting for delivery. Events beyond it
+	// are dropped rather than slowing down the requests that raised them.
+	queueSize = 1000
+	// workers is how many events are delivered concurrently
+	workers = 4
+	// retryBackoff is the pause before the second attempt, doubling after
+	// that
+	retryBackoff = time.Second
+	// storeTimeout bounds looking up webhooks and recording attempts
+	storeTimeout = 5 * time.Second
+	// maxResponseBytes is how much of a response is read, so connections
+	// can be reused without trusting the receiver's response size
+	maxResponseBytes = 64 << 10
+	// purgeInterval is how often delivery attempts past the retention
+	// period are deleted
+	purgeInterval = time.Hour
+
+	userAgent = "user-auth-app-webhooks"
+)
+
+// Notifier accepts events to deliver to the webhooks subscribed to them
+type Notifier interface {
+	// Notify queues an event of the tenant in ctx without blocking
+	Notify(ctx context.Context, event string, data interface{})
+}
+
+// Store persists webhooks and their delivery attempts
+type Store interface {
+	ListWebhooksForEvent(ctx context.Context, event string) ([]domain.Webhook, error)
+	RecordWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+	PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Payload is the JSON body of every delivery
+type Payload struct {
+	// ID is the delivery ID, also sent in DeliveryHeader
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	TenantID  string      `json:"tenant_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// event is a notification waiting in the queue
+type event struct {
+	tenantID  string
+	name      string
+	data      interface{}
+	createdAt time.Time
+}
+
+// Dispatcher delivers events to webhooks in the background. Each
+// delivery is a signed POST, retried with exponential backoff until the
+// receiver answers 2xx, answers with a status that won't change on retry,
+// or maxAttempts is reached. Every attempt is recorded in the store and
+// kept for the retention period.
+type Dispatcher struct {
+	store       Store
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	retention   time.Duration
+	logger      *zerolog.Logger
+
+	queue  chan event
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher starts a dispatcher whose requests time out after
+// timeout and are attempted up to maxAttempts times. Requests only
+// connect to public addresses and to the allowed networks. Attempts older
+// than retention are purged; zero keeps them forever.
+func NewDispatcher(store Store, timeout time.Duration, maxAttempts int, retention time.Duration, allowed []netip.Prefix, logger *zerolog.Logger) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the connection on our behalf, out of reach of
+	// the address check
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl(allowed),
+	}).DialContext
+
+	d := &Dispatcher{
+		store: store,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// A redirect could point the signed payload anywhere; the
+			// configured URL must answer itself
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxAttempts: max(maxAttempts, 1),
+		backoff:     retryBackoff,
+		retention:   retention,
+		logger:      logger,
+		queue:       make(chan event, queueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+
+	d.wg.Add(workers)
+	for range workers {
+		go d.run()
+	}
+
+	if retention > 0 {
+		d.wg.Add(1)
+		go d.purge()
+	}
+
+	return d
+}
+
+// Notify queues an event for delivery, dropping it if the queue is full
+func (d *Dispatcher) Notify(ctx context.Context, name string, data interface{}) {
+	if d.ctx.Err() != nil {
+		return
+	}
+
+	select {
+	case d.queue <- event{tenantID: tenant.ID(ctx), name: name, data: data, createdAt: time.Now().UTC()}:
+	default:
+		eventsDropped.WithLabelValues(name).Inc()
+		d.logger.Warn().Str("event", name).Msg("Webhook queue full, dropping event")
+	}
+}
+
+// Close stops delivery and purging, abandoning retries in progress and
+// discarding queued events
+func (d *Dispatcher) Close() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) run() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case e := <-d.queue:
+			d.dispatch(e)
+		}
+	}
+}
+
+// purge deletes delivery attempts past the retention period at startup
+// and every purgeInterval
+func (d *Dispatcher) purge() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(d.ctx, storeTimeout)
+		deleted, err := d.store.PurgeWebhookDeliveries(ctx, time.Now().Add(-d.retention))
+		cancel()
+		switch {
+		case err != nil && d.ctx.Err() == nil:
+			d.logger.Warn().Err(err).Msg("Failed to purge webhook deliveries")
+		case deleted > 0:
+			d.logger.Info().Int64("deleted", deleted).Msg("Purged old webhook deliveries")
+		}
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch delivers an event to each webhook subscribed to it. Webhooks
+// are delivered to concurrently so a failing endpoint doesn't hold up the
+// others.
+func (d *Dispatcher) dispatch(e event) {
+	ctx := tenant.WithID(d.ctx, e.tenantID)
+
+	lookupCtx, cancel := context.WithTimeout(ctx, storeTimeout)
+	webhooks, err := d.store.ListWebhooksForEvent(lookupCtx, e.name)
+	cancel()
+	if err != nil {
+		d.logger.Error().Err(err).Str("event", e.name).Msg("Failed to look up webhooks")
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, webhook := range webhooks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, webhook, e)
+		}()
+	}
+	wg.Wait()
+}
+
+// deliver sends an event to one webhook, retrying with backoff
+func (d *Dispatcher) deliver(ctx context.Context, webhook domain.Webhook, e event) {
+	deliveryID, err := newDeliveryID()
+	if err != nil {
+		d.logger.Error().Err(err).Msg("Failed to generate webhook delivery ID")
+		return
+	}
+	body, err := json.Marshal(Payload{
+		ID:        deliveryID,
+		Event:     e.name,
+		TenantID:  e.tenantID,
+		CreatedAt: e.createdAt,
+		Data:      e.data,
+	})
+	if err != nil {
+		d.logger.Error().Err(err).Str("event", e.name).Msg("Failed to encode webhook payload")
+		return
+	}
+
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		status, err := d.send(ctx, webhook, e.name, deliveryID, body)
+		if ctx.Err() != nil {
+			// Shutting down; the attempt didn't fail on the receiver's side
+			return
+		}
+
+		retry := err != nil && retryable(status) && !errors.Is(err, ErrAddressNotAllowed) && attempt < d.maxAttempts
+		d.record(ctx, domain.WebhookDelivery{
+			WebhookID:  webhook.ID,
+			DeliveryID: deliveryID,
+			Event:      e.name,
+			Attempt:    int32(attempt),
+			StatusCode: int32(status),
+			Error:      errorString(err),
+			Succeeded:  err == nil,
+		})
+
+		switch {
+		case err == nil:
+			deliveryAttempts.WithLabelValues(e.name, "success").Inc()
+			return
+		case retry:
+			deliveryAttempts.WithLabelValues(e.name, "retry").Inc()
+		default:
+			deliveryAttempts.WithLabelValues(e.name, "failed").Inc()
+			d.logger.Warn().
+				Err(err).
+				Int32("webhook_id", webhook.ID).
+				Str("event", e.name).
+				Int("attempt", attempt).
+				Msg("Webhook delivery failed")
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.backoff << (attempt - 1)):
+		}
+	}
+}
+
+// send makes one delivery attempt, returning the response status (zero
+// if there was no response) and an error unless the receiver answered 2xx
+func (d *Dispatcher) send(ctx context.Context, webhook domain.Webhook, name, deliveryID string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("create webhook request: %w", err)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, name)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	deliveryDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// record stores a delivery attempt. The log is best effort; failing to
+// write it doesn't affect delivery.
+func (d *Dispatcher) record(ctx context.Context, delivery domain.WebhookDelivery) {
+	ctx, cancel := context.WithTimeout(ctx, storeTimeout)
+	defer cancel()
+	if err := d.store.RecordWebhookDelivery(ctx, delivery); err != nil && !errors.Is(err, context.Canceled) {
+		d.logger.Warn().Err(err).Int32("webhook_id", delivery.WebhookID).Msg("Failed to record webhook delivery")
+	}
+}
+
+// retryable reports whether an attempt that got status may succeed if
+// repeated: no response, a server error, a timeout or rate limiting.
+// Other client errors mean the receiver rejects the request as such.
+func retryable(status int) bool {
+	return status == 0 ||
+		status >= http.StatusInternalServerError ||
+		status == http.StatusRequestTimeout ||
+		status == http.StatusTooManyRequests
+}
+
+// newDeliveryID returns a random delivery ID
+func newDeliveryID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
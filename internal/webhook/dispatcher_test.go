@@ -0,0 +1,268 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/tenant"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore serves fixed webhooks and collects delivery attempts
+type memStore struct {
+	webhooks []domain.Webhook
+
+	mu           sync.Mutex
+	tenants      []string
+	deliveries   []domain.WebhookDelivery
+	purgedBefore []time.Time
+}
+
+func (s *memStore) ListWebhooksForEvent(ctx context.Context, event string) ([]domain.Webhook, error) {
+	s.mu.Lock()
+	s.tenants = append(s.tenants, tenant.ID(ctx))
+	s.mu.Unlock()
+
+	var subscribed []domain.Webhook
+	for _, webhook := range s.webhooks {
+		for _, e := range webhook.Events {
+			if e == event {
+				subscribed = append(subscribed, webhook)
+			}
+		}
+	}
+	return subscribed, nil
+}
+
+func (s *memStore) RecordWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, delivery)
+	return nil
+}
+
+func (s *memStore) PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purgedBefore = append(s.purgedBefore, before)
+	return 0, nil
+}
+
+func (s *memStore) recorded() []domain.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]domain.WebhookDelivery(nil), s.deliveries...)
+}
+
+// loopback lets test dispatchers deliver to httptest servers
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+
+func newTestDispatcher(store Store, maxAttempts int) *Dispatcher {
+	logger := zerolog.Nop()
+	d := NewDispatcher(store, time.Second, maxAttempts, 0, loopback, &logger)
+	d.backoff = time.Millisecond
+	return d
+}
+
+func TestSignatureRoundTrip(t *testing.T) {
+	body := []byte(`{"event":"user.registered"}`)
+	signature := Sign("secret", 1700000000, body)
+
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, signature)
+	assert.True(t, Verify("secret", signature, 1700000000, body))
+	assert.False(t, Verify("other", signature, 1700000000, body))
+	assert.False(t, Verify("secret", signature, 1700000001, body))
+	assert.False(t, Verify("secret", signature, 1700000000, []byte(`{}`)))
+}
+
+func TestDispatcherDeliversSignedPayload(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header, body: body}
+	}))
+	defer srv.Close()
+
+	store := &memStore{webhooks: []domain.Webhook{
+		{ID: 1, URL: srv.URL, Secret: "whsec_test", Events: []string{domain.EventUserRegistered}},
+		{ID: 2, URL: srv.URL, Secret: "whsec_other", Events: []string{domain.EventUserPasswordChanged}},
+	}}
+	d := newTestDispatcher(store, 3)
+	defer d.Close()
+
+	d.Notify(tenant.WithID(context.Background(), "acme"), domain.EventUserRegistered, map[string]interface{}{"user_id": 7})
+
+	var req received
+	select {
+	case req = <-requests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+
+	timestamp, err := strconv.ParseInt(req.header.Get(TimestampHeader), 10, 64)
+	require.NoError(t, err)
+	assert.True(t, Verify("whsec_test", req.header.Get(SignatureHeader), timestamp, req.body))
+	assert.Equal(t, domain.EventUserRegistered, req.header.Get(EventHeader))
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+
+	var payload Payload
+	require.NoError(t, json.Unmarshal(req.body, &payload))
+	assert.Equal(t, req.header.Get(DeliveryHeader), payload.ID)
+	assert.Equal(t, domain.EventUserRegistered, payload.Event)
+	assert.Equal(t, "acme", payload.TenantID)
+	assert.Equal(t, map[string]interface{}{"user_id": float64(7)}, payload.Data)
+
+	require.Eventually(t, func() bool { return len(store.recorded()) == 1 }, time.Second, 5*time.Millisecond)
+	delivery := store.recorded()[0]
+	assert.Equal(t, int32(1), delivery.WebhookID)
+	assert.Equal(t, payload.ID, delivery.DeliveryID)
+	assert.Equal(t, int32(http.StatusOK), delivery.StatusCode)
+	assert.True(t, delivery.Succeeded)
+	assert.Equal(t, []string{"acme"}, store.tenants)
+}
+
+func TestDispatcherRetriesFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		attempts  int
+		succeeded bool
+	}{
+		{name: "server errors are retried", statuses: []int{500, 503, 200}, attempts: 3, succeeded: true},
+		{name: "rate limiting is retried", statuses: []int{429, 204}, attempts: 2, succeeded: true},
+		{name: "gives up after max attempts", statuses: []int{500, 500, 500, 500}, attempts: 3},
+		{name: "client errors are not retried", statuses: []int{400, 200}, attempts: 1},
+		{name: "redirects are not followed", statuses: []int{302, 200}, attempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			deliveryIDs := make(chan string, len(tt.statuses))
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deliveryIDs <- r.Header.Get(DeliveryHeader)
+				n := calls.Add(1)
+				if tt.statuses[n-1] == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			store := &memStore{webhooks: []domain.Webhook{
+				{ID: 1, URL: srv.URL, Secret: "whsec_test", Events: []string{domain.EventUserRegistered}},
+			}}
+			d := newTestDispatcher(store, 3)
+			defer d.Close()
+
+			d.Notify(context.Background(), domain.EventUserRegistered, nil)
+
+			require.Eventually(t, func() bool {
+				return len(store.recorded()) == tt.attempts
+			}, 5*time.Second, 5*time.Millisecond)
+			// Give a wrongly scheduled retry the chance to show up
+			time.Sleep(20 * time.Millisecond)
+
+			deliveries := store.recorded()
+			require.Len(t, deliveries, tt.attempts)
+			assert.Equal(t, int32(tt.attempts), calls.Load())
+			for i, delivery := range deliveries {
+				assert.Equal(t, int32(i+1), delivery.Attempt)
+				assert.Equal(t, int32(tt.statuses[i]), delivery.StatusCode)
+				assert.Equal(t, deliveries[0].DeliveryID, delivery.DeliveryID)
+				assert.Equal(t, <-deliveryIDs, delivery.DeliveryID)
+			}
+			assert.Equal(t, tt.succeeded, deliveries[len(deliveries)-1].Succeeded)
+		})
+	}
+}
+
+func TestDispatcherRefusesInternalAddresses(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	store := &memStore{webhooks: []domain.Webhook{
+		{ID: 1, URL: srv.URL, Secret: "whsec_test", Events: []string{domain.EventUserRegistered}},
+	}}
+	logger := zerolog.Nop()
+	d := NewDispatcher(store, time.Second, 3, 0, nil, &logger)
+	d.backoff = time.Millisecond
+	defer d.Close()
+
+	d.Notify(context.Background(), domain.EventUserRegistered, nil)
+
+	require.Eventually(t, func() bool {
+		return len(store.recorded()) == 1
+	}, 5*time.Second, 5*time.Millisecond)
+	// Give a wrongly scheduled retry the chance to show up
+	time.Sleep(20 * time.Millisecond)
+
+	deliveries := store.recorded()
+	require.Len(t, deliveries, 1, "refused addresses aren't retried")
+	assert.False(t, deliveries[0].Succeeded)
+	assert.Contains(t, deliveries[0].Error, ErrAddressNotAllowed.Error())
+	assert.Zero(t, calls.Load())
+}
+
+func TestAddressAllowed(t *testing.T) {
+	internal := netip.MustParsePrefix("10.1.0.0/16")
+	tests := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.0.0.5":        false,
+		"172.16.3.4":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00:ec2::254":   false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"10.1.2.3":        true,
+	}
+
+	for addr, want := range tests {
+		assert.Equal(t, want, addressAllowed(netip.MustParseAddr(addr), []netip.Prefix{internal}), addr)
+	}
+
+	control := dialControl(nil)
+	assert.ErrorIs(t, control("tcp4", "169.254.169.254:80", nil), ErrAddressNotAllowed)
+	assert.ErrorIs(t, control("tcp6", "[::ffff:127.0.0.1]:443", nil), ErrAddressNotAllowed, "mapped IPv4 addresses are unmapped")
+	assert.NoError(t, control("tcp4", "93.184.216.34:443", nil))
+}
+
+func TestDispatcherPurgesOldDeliveries(t *testing.T) {
+	store := &memStore{}
+	logger := zerolog.Nop()
+	d := NewDispatcher(store, time.Second, 1, 24*time.Hour, nil, &logger)
+
+	require.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return len(store.purgedBefore) == 1
+	}, 5*time.Second, 5*time.Millisecond)
+	d.Close()
+
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), store.purgedBefore[0], time.Minute)
+}
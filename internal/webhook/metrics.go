@@ -0,0 +1,33 @@
+// Package webhook defines delivery metrics
+package webhook
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	deliveryAttempts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_delivery_attempts_total",
+			Help: "Total number of webhook delivery attempts by event and result (success, retry or failed)",
+		},
+		[]string{"event", "result"},
+	)
+
+	deliveryDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "webhook_delivery_duration_seconds",
+			Help:    "Duration of webhook delivery requests",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	eventsDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_events_dropped_total",
+			Help: "Total number of events not delivered to webhooks because the queue was full",
+		},
+		[]string{"event"},
+	)
+)
@@ -0,0 +1,43 @@
+// Package webhook delivers signed event notifications to HTTP endpoints
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Headers sent with every delivery
+const (
+	// SignatureHeader carries Sign's signature of the request body
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader carries the Unix time the request was signed at.
+	// Receivers should reject old timestamps to stop replays.
+	TimestampHeader = "X-Webhook-Timestamp"
+	// EventHeader names the event, e.g. user.registered
+	EventHeader = "X-Webhook-Event"
+	// DeliveryHeader identifies the delivery; retries of an event repeat
+	// it, so receivers can drop duplicates
+	DeliveryHeader = "X-Webhook-Delivery"
+)
+
+// signaturePrefix names the signing algorithm, leaving room for others
+const signaturePrefix = "sha256="
+
+// Sign returns the signature of a body sent at timestamp: "sha256="
+// followed by the hex HMAC-SHA256, keyed with secret, of the timestamp, a
+// dot and the body
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is Sign's signature of body and
+// timestamp, comparing in constant time
+func Verify(secret, signature string, timestamp int64, body []byte) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
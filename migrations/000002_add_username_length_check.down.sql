@@ -0,0 +1,7 @@
+-- Remove username length constraint
+
+BEGIN;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS check_username_length;
+
+COMMIT;
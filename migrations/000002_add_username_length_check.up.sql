@@ -0,0 +1,11 @@
+-- Bound username length at the database level.
+-- The upper bound must match domain.UsernameMaxLenLimit; USERNAME_MAX_LEN
+-- is validated against it at startup so validation never accepts a
+-- username the insert would reject.
+
+BEGIN;
+
+ALTER TABLE users
+    ADD CONSTRAINT check_username_length CHECK (char_length(username) BETWEEN 1 AND 64);
+
+COMMIT;
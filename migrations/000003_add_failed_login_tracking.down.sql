@@ -0,0 +1,9 @@
+-- Remove failed login tracking
+
+BEGIN;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS failed_login_attempts;
+
+COMMIT;
@@ -0,0 +1,9 @@
+-- Track failed login attempts for account lockout
+
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN failed_login_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN locked_until TIMESTAMP;
+
+COMMIT;
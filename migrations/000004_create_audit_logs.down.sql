@@ -0,0 +1,7 @@
+-- Remove audit trail
+
+BEGIN;
+
+DROP TABLE IF EXISTS audit_logs;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Remove user collection ETag index
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_users_active_updated_at;
+
+COMMIT;
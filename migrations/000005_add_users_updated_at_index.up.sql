@@ -0,0 +1,7 @@
+-- Index for the user collection ETag (MAX(updated_at) over active users)
+
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_users_active_updated_at ON users(updated_at DESC) WHERE is_active = TRUE;
+
+COMMIT;
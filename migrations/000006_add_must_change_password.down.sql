@@ -0,0 +1,8 @@
+-- Remove forced password change flag
+
+BEGIN;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS must_change_password;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Force admin-provisioned users to replace their temporary password
+
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMIT;
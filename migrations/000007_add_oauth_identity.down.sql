@@ -0,0 +1,11 @@
+-- Remove external identity columns
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_users_provider_identity;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS provider_id,
+    DROP COLUMN IF EXISTS provider;
+
+COMMIT;
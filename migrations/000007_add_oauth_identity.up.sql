@@ -0,0 +1,14 @@
+-- Link users to external identity providers (e.g. Google sign-in)
+
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN provider TEXT,
+    ADD COLUMN provider_id TEXT;
+
+-- An external identity belongs to at most one user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_provider_identity
+    ON users(provider, provider_id)
+    WHERE provider IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Remove API keys
+
+BEGIN;
+
+DROP TABLE IF EXISTS api_keys;
+
+COMMIT;
@@ -0,0 +1,19 @@
+-- Remove tenant scoping. Fails if two tenants share a username or email.
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_users_provider_identity;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_provider_identity
+    ON users(provider, provider_id)
+    WHERE provider IS NOT NULL;
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_tenant_email_key,
+    DROP CONSTRAINT IF EXISTS users_tenant_username_key,
+    ADD CONSTRAINT users_username_key UNIQUE (username),
+    ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS tenant_id;
+
+COMMIT;
@@ -0,0 +1,21 @@
+-- Scope users to a tenant. Existing users move to the 'default' tenant,
+-- and usernames and emails become unique per tenant instead of globally.
+
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_username_key,
+    DROP CONSTRAINT IF EXISTS users_email_key,
+    ADD CONSTRAINT users_tenant_username_key UNIQUE (tenant_id, username),
+    ADD CONSTRAINT users_tenant_email_key UNIQUE (tenant_id, email);
+
+-- An external identity may sign in to several tenants, once each
+DROP INDEX IF EXISTS idx_users_provider_identity;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_provider_identity
+    ON users(tenant_id, provider, provider_id)
+    WHERE provider IS NOT NULL;
+
+COMMIT;
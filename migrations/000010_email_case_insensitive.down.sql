@@ -0,0 +1,10 @@
+-- Restore case-sensitive email uniqueness. Emails stay lowercased.
+
+BEGIN;
+
+DROP INDEX IF EXISTS users_tenant_email_lower_key;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_tenant_email_key UNIQUE (tenant_id, email);
+
+COMMIT;
@@ -0,0 +1,19 @@
+-- Treat emails case-insensitively. Stored emails are trimmed and
+-- lowercased, and uniqueness is enforced on lower(email) so "Bob@x.com"
+-- and "bob@x.com" can no longer both register. Fails if a tenant already
+-- holds two accounts whose emails differ only in case; merge or rename
+-- those before migrating.
+
+BEGIN;
+
+UPDATE users
+SET email = lower(btrim(email))
+WHERE email <> lower(btrim(email));
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_tenant_email_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_email_lower_key
+    ON users(tenant_id, lower(email));
+
+COMMIT;
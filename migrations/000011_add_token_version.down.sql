@@ -0,0 +1,8 @@
+-- Remove token version
+
+BEGIN;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS token_version;
+
+COMMIT;
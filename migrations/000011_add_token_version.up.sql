@@ -0,0 +1,8 @@
+-- Version embedded in issued tokens; bumping it revokes them all
+
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Remove sessions
+
+BEGIN;
+
+DROP TABLE IF EXISTS sessions;
+
+COMMIT;
@@ -0,0 +1,22 @@
+-- One row per login, kept alive by token refreshes, so users can see and
+-- revoke where they're signed in
+
+BEGIN;
+
+-- Replaces the unused placeholder table of the original schema
+DROP TABLE IF EXISTS sessions;
+
+CREATE TABLE sessions (
+    id TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ip_address TEXT,
+    user_agent TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
+
+COMMIT;
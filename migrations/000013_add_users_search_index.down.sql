@@ -0,0 +1,9 @@
+-- Remove user search indexes. The pg_trgm extension is left installed
+-- in case anything else uses it.
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_users_email_trgm;
+DROP INDEX IF EXISTS idx_users_username_trgm;
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Trigram indexes so admin user search (ILIKE '%term%') doesn't scan
+-- the whole table
+
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING GIN (username gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING GIN (email gin_trgm_ops);
+
+COMMIT;
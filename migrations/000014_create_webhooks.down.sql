@@ -0,0 +1,8 @@
+-- Remove webhooks and their delivery log
+
+BEGIN;
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Remove password history
+
+BEGIN;
+
+DROP TABLE IF EXISTS password_history;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Remove password age tracking
+
+BEGIN;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS password_changed_at;
+
+COMMIT;
@@ -0,0 +1,9 @@
+-- Track when each password was set, so passwords can expire. Existing
+-- users count from the migration.
+
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN password_changed_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+COMMIT;
@@ -0,0 +1,10 @@
+-- Restore case-sensitive username uniqueness. Usernames stay lowercased.
+
+BEGIN;
+
+DROP INDEX IF EXISTS users_tenant_username_lower_key;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_tenant_username_key UNIQUE (tenant_id, username);
+
+COMMIT;
@@ -0,0 +1,19 @@
+-- Treat usernames case-insensitively, like emails. Stored usernames are
+-- trimmed and lowercased, and uniqueness is enforced on lower(username)
+-- so "Bob" and "bob" can no longer both register. Fails if a tenant
+-- already holds two accounts whose usernames differ only in case; rename
+-- those before migrating.
+
+BEGIN;
+
+UPDATE users
+SET username = lower(btrim(username))
+WHERE username <> lower(btrim(username));
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_tenant_username_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_username_lower_key
+    ON users(tenant_id, lower(username));
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Remove invite codes
+
+BEGIN;
+
+DROP TABLE IF EXISTS invites;
+
+COMMIT;
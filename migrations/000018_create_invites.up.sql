@@ -0,0 +1,17 @@
+-- Single-use invite codes for invite-only registration. Only a hash of
+-- each code is stored; the code is shown once, when the invite is created.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS invites (
+    id SERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL DEFAULT 'default',
+    code_hash TEXT NOT NULL UNIQUE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    used_by INTEGER REFERENCES users(id) ON DELETE SET NULL
+);
+
+COMMIT;
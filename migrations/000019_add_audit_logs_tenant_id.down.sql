@@ -0,0 +1,10 @@
+-- Remove tenant scoping from audit events
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_audit_logs_tenant_created_at;
+
+ALTER TABLE audit_logs
+    DROP COLUMN IF EXISTS tenant_id;
+
+COMMIT;
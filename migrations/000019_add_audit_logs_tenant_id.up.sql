@@ -0,0 +1,18 @@
+-- Scope audit events to a tenant. Events of a user move to the user's
+-- tenant; events without a user, or whose user was deleted, stay in the
+-- 'default' tenant.
+
+BEGIN;
+
+ALTER TABLE audit_logs
+    ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+
+UPDATE audit_logs
+SET tenant_id = users.tenant_id
+FROM users
+WHERE audit_logs.user_id = users.id AND users.tenant_id <> 'default';
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_created_at
+    ON audit_logs(tenant_id, created_at DESC);
+
+COMMIT;
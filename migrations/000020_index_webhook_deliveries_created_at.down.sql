@@ -0,0 +1,7 @@
+-- Remove the delivery age index
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_created_at;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Index delivery attempts by age, so purging those past the retention
+-- period doesn't scan the whole log
+
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created_at ON webhook_deliveries(created_at);
+
+COMMIT;
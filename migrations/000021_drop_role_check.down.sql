@@ -0,0 +1,9 @@
+-- Restrict roles to the built-in three again. Fails if a user has a
+-- configured role.
+
+BEGIN;
+
+ALTER TABLE users
+    ADD CONSTRAINT check_role CHECK (role IN ('user', 'admin', 'moderator'));
+
+COMMIT;
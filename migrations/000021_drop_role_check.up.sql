@@ -0,0 +1,9 @@
+-- Roles are configured with ALLOWED_ROLES and validated by the service,
+-- so the database no longer restricts them to the built-in three
+
+BEGIN;
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS check_role;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Drop the dead letters
+
+BEGIN;
+
+DROP TABLE IF EXISTS dead_letters;
+
+COMMIT;
@@ -0,0 +1,17 @@
+-- Keep messages whose subscriber failed every attempt, so admins can
+-- inspect and replay them
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id SERIAL PRIMARY KEY,
+    subject TEXT NOT NULL,
+    data BYTEA NOT NULL,
+    attempts INTEGER NOT NULL,
+    error TEXT NOT NULL,
+    failed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letters_failed_at ON dead_letters(failed_at DESC);
+
+COMMIT;
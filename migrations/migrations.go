@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migrations, so the binary can apply
+// them without the files being deployed alongside it
+package migrations
+
+import "embed"
+
+// FS holds the numbered up and down migrations
+//
+//go:embed *.sql
+var FS embed.FS
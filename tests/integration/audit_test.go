@@ -0,0 +1,48 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+	"user-auth-app/internal/tenant"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditExportIsScopedToTenant(t *testing.T) {
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, testDBURL)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	const action = "test.export_tenant"
+	defer pool.Exec(ctx, "DELETE FROM audit_logs WHERE action = $1", action)
+
+	repo := repository.NewAuditRepository(pool)
+	acme := tenant.WithID(ctx, "acme")
+	globex := tenant.WithID(ctx, "globex")
+
+	from := time.Now().Add(-time.Minute)
+	require.NoError(t, repo.RecordAuditEvent(acme, domain.AuditEvent{Type: action, Details: map[string]string{"tenant": "acme"}}))
+	require.NoError(t, repo.RecordAuditEvent(globex, domain.AuditEvent{Type: action, Details: map[string]string{"tenant": "globex"}}))
+	to := time.Now().Add(time.Minute)
+
+	var exported []domain.AuditEvent
+	require.NoError(t, repo.ExportAuditEvents(acme, from, to, func(event domain.AuditEvent) error {
+		if event.Type == action {
+			exported = append(exported, event)
+		}
+		return nil
+	}))
+
+	require.Len(t, exported, 1)
+	assert.Equal(t, "acme", exported[0].Details["tenant"])
+}
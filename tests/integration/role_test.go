@@ -0,0 +1,36 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfiguredRolesAreStored(t *testing.T) {
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, testDBURL)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	repo := repository.NewUserRepository(pool, nil, repository.LockoutPolicy{})
+
+	user, err := repo.CreateUser(ctx, domain.User{
+		Username: "roleuser",
+		Email:    "roleuser@example.com",
+		Role:     "editor",
+	}, "hash")
+	require.NoError(t, err)
+	defer pool.Exec(ctx, "DELETE FROM users WHERE id = $1", user.ID)
+	assert.Equal(t, "editor", user.Role)
+
+	require.NoError(t, repo.UpdateUserRole(ctx, user.ID, "auditor"))
+}
@@ -0,0 +1,55 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+// BenchmarkGetUserByEmail compares login lookups with each query exec
+// mode. cache_statement should beat exec and simple_protocol, which parse
+// and plan the query on every call:
+//
+//	go test -tags integration -run '^$' -bench GetUserByEmail ./tests/integration
+func BenchmarkGetUserByEmail(b *testing.B) {
+	ctx := context.Background()
+
+	for _, name := range []string{"cache_statement", "cache_describe", "exec", "simple_protocol"} {
+		b.Run(name, func(b *testing.B) {
+			poolConfig, err := pgxpool.ParseConfig(testDBURL)
+			require.NoError(b, err)
+			poolConfig.ConnConfig.DefaultQueryExecMode = repository.QueryExecModes[name]
+
+			pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+			require.NoError(b, err)
+			defer pool.Close()
+
+			repo := repository.NewUserRepository(pool, nil, repository.LockoutPolicy{MaxAttempts: 5, Duration: time.Minute})
+			user, err := repo.CreateUser(ctx, domain.User{
+				Username: "bench_" + name,
+				Email:    "bench_" + name + "@example.com",
+				Role:     domain.RoleUser,
+			}, "hash")
+			require.NoError(b, err)
+			defer pool.Exec(ctx, "DELETE FROM users WHERE id = $1", user.ID)
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, _, err := repo.GetUserByEmail(ctx, user.Email); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,44 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"user-auth-app/internal/domain"
+	"user-auth-app/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsernamesAreUniqueRegardlessOfCase(t *testing.T) {
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, testDBURL)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	repo := repository.NewUserRepository(pool, nil, repository.LockoutPolicy{})
+
+	user, err := repo.CreateUser(ctx, domain.User{
+		Username: "caseuser",
+		Email:    "caseuser@example.com",
+		Role:     "user",
+	}, "hash")
+	require.NoError(t, err)
+	defer pool.Exec(ctx, "DELETE FROM users WHERE id = $1", user.ID)
+
+	_, err = repo.CreateUser(ctx, domain.User{
+		Username: "CaseUser",
+		Email:    "caseuser2@example.com",
+		Role:     "user",
+	}, "hash")
+	assert.ErrorIs(t, err, domain.ErrDuplicateUsername)
+
+	found, _, err := repo.GetUserByUsername(ctx, "CASEUSER")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+}